@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
+
+	"github.com/netbirdio/netbird/client/proto"
+)
+
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "inspect the internal state of the NetBird client",
+}
+
+var debugFirewallCmd = &cobra.Command{
+	Use:   "firewall",
+	Short: "show the firewall rules applied from the management with their counters",
+	Long: "Shows the firewall rules applied from the management with the packets and bytes they matched, " +
+		"and the traffic dropped because no rule allowed it. " +
+		"Set NB_FIREWALL_LOG_DROPPED=true on the daemon to log the dropped packets.",
+	RunE: debugFirewallFunc,
+}
+
+func init() {
+	debugCmd.AddCommand(debugFirewallCmd)
+}
+
+func debugFirewallFunc(cmd *cobra.Command, _ []string) error {
+	client, closeConn, err := daemonClient(cmd)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	resp, err := client.GetFirewallRules(cmd.Context(), &proto.GetFirewallRulesRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to get firewall rules: %v", status.Convert(err).Message())
+	}
+
+	cmd.Print(parseFirewallRules(resp))
+	return nil
+}
+
+func parseFirewallRules(resp *proto.GetFirewallRulesResponse) string {
+	var builder strings.Builder
+	if len(resp.GetRules()) == 0 {
+		builder.WriteString("No firewall rules applied\n")
+	} else {
+		builder.WriteString(fmt.Sprintf("%-9s %-7s %-8s %-18s %-6s %12s %14s\n", "DIRECTION", "ACTION", "PROTOCOL", "PEER", "PORT", "PACKETS", "BYTES"))
+		for _, rule := range resp.GetRules() {
+			port := rule.GetPort()
+			if port == "" {
+				port = "-"
+			}
+			peerIP := rule.GetPeerIP()
+			if peerIP == "0.0.0.0" {
+				peerIP = "any"
+			}
+			builder.WriteString(fmt.Sprintf("%-9s %-7s %-8s %-18s %-6s %12d %14d\n",
+				strings.ToLower(rule.GetDirection()),
+				strings.ToLower(rule.GetAction()),
+				strings.ToLower(rule.GetProtocol()),
+				peerIP,
+				port,
+				rule.GetPackets(),
+				rule.GetBytes(),
+			))
+		}
+	}
+
+	builder.WriteString(fmt.Sprintf("\nDropped by default: in %d packets (%d bytes), out %d packets (%d bytes)\n",
+		resp.GetDroppedInPackets(), resp.GetDroppedInBytes(), resp.GetDroppedOutPackets(), resp.GetDroppedOutBytes()))
+	return builder.String()
+}
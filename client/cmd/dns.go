@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
+
+	"github.com/netbirdio/netbird/client/proto"
+	"github.com/netbirdio/netbird/util"
+)
+
+var (
+	dnsLogLimit   uint32
+	dnsLogEnable  bool
+	dnsLogDisable bool
+	dnsLogClear   bool
+)
+
+var dnsCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "inspect the NetBird local DNS resolver",
+}
+
+var dnsLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: "show the queries served by the local DNS resolver",
+	Long: "Shows the latest queries served by the local DNS resolver with the handler that answered them. " +
+		"Recording is disabled by default, use --enable to start it or set NB_DNS_QUERY_LOG=true on the daemon.",
+	RunE: dnsLogFunc,
+}
+
+var dnsStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "show per-domain statistics of the local DNS resolver",
+	RunE:  dnsStatsFunc,
+}
+
+func init() {
+	dnsLogCmd.Flags().Uint32Var(&dnsLogLimit, "limit", 100, "number of latest queries to display, 0 displays all recorded queries")
+	dnsLogCmd.Flags().BoolVar(&dnsLogEnable, "enable", false, "start recording queries")
+	dnsLogCmd.Flags().BoolVar(&dnsLogDisable, "disable", false, "stop recording queries")
+	dnsLogCmd.Flags().BoolVar(&dnsLogClear, "clear", false, "remove the recorded queries and statistics")
+	dnsLogCmd.MarkFlagsMutuallyExclusive("enable", "disable")
+	dnsCmd.AddCommand(dnsLogCmd, dnsStatsCmd)
+}
+
+func dnsLogFunc(cmd *cobra.Command, _ []string) error {
+	client, closeConn, err := daemonClient(cmd)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	if dnsLogEnable || dnsLogDisable || dnsLogClear {
+		req := &proto.SetDNSQueryLogRequest{Clear: dnsLogClear}
+		if dnsLogEnable || dnsLogDisable {
+			enabled := dnsLogEnable
+			req.Enabled = &enabled
+		}
+		if _, err := client.SetDNSQueryLog(cmd.Context(), req); err != nil {
+			return fmt.Errorf("failed to update DNS query log: %v", status.Convert(err).Message())
+		}
+	}
+
+	resp, err := client.GetDNSQueryLog(cmd.Context(), &proto.GetDNSQueryLogRequest{Limit: dnsLogLimit})
+	if err != nil {
+		return fmt.Errorf("failed to get DNS query log: %v", status.Convert(err).Message())
+	}
+
+	cmd.Print(parseDNSQueryLog(resp))
+	return nil
+}
+
+func dnsStatsFunc(cmd *cobra.Command, _ []string) error {
+	client, closeConn, err := daemonClient(cmd)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	resp, err := client.GetDNSStats(cmd.Context(), &proto.GetDNSStatsRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to get DNS statistics: %v", status.Convert(err).Message())
+	}
+
+	cmd.Print(parseDNSStats(resp))
+	return nil
+}
+
+func daemonClient(cmd *cobra.Command) (proto.DaemonServiceClient, func(), error) {
+	SetFlagsFromEnvVars(rootCmd)
+
+	cmd.SetOut(cmd.OutOrStdout())
+
+	err := util.InitLog(logLevel, "console")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed initializing log %v", err)
+	}
+
+	conn, err := DialClientGRPCServer(context.Background(), daemonAddr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to daemon error: %v\n"+
+			"If the daemon is not running please run: "+
+			"\nnetbird service install \nnetbird service start\n", err)
+	}
+
+	return proto.NewDaemonServiceClient(conn), func() { _ = conn.Close() }, nil
+}
+
+func parseDNSQueryLog(resp *proto.GetDNSQueryLogResponse) string {
+	var builder strings.Builder
+	if !resp.GetEnabled() {
+		builder.WriteString("DNS query log is disabled, run: netbird dns log --enable\n")
+	}
+
+	if len(resp.GetEntries()) == 0 {
+		builder.WriteString("No queries recorded\n")
+		return builder.String()
+	}
+
+	builder.WriteString(fmt.Sprintf("%-23s %-40s %-6s %-10s %-10s %s\n", "TIME", "NAME", "TYPE", "RCODE", "LATENCY", "HANDLER"))
+	for _, entry := range resp.GetEntries() {
+		builder.WriteString(fmt.Sprintf("%-23s %-40s %-6s %-10s %-10s %s\n",
+			entry.GetTime().AsTime().Local().Format("2006-01-02 15:04:05.000"),
+			entry.GetName(),
+			entry.GetType(),
+			entry.GetRcode(),
+			entry.GetLatency().AsDuration().Round(time.Microsecond),
+			entry.GetHandler(),
+		))
+	}
+	return builder.String()
+}
+
+func parseDNSStats(resp *proto.GetDNSStatsResponse) string {
+	var builder strings.Builder
+	if !resp.GetEnabled() {
+		builder.WriteString("DNS query log is disabled, run: netbird dns log --enable\n")
+	}
+
+	if len(resp.GetStats()) == 0 {
+		builder.WriteString("No queries recorded\n")
+		return builder.String()
+	}
+
+	builder.WriteString(fmt.Sprintf("%-40s %-8s %-8s %-12s %-19s %s\n", "DOMAIN", "QUERIES", "FAILED", "AVG LATENCY", "LAST SEEN", "LAST HANDLER"))
+	for _, stats := range resp.GetStats() {
+		builder.WriteString(fmt.Sprintf("%-40s %-8d %-8d %-12s %-19s %s\n",
+			stats.GetDomain(),
+			stats.GetQueries(),
+			stats.GetFailures(),
+			stats.GetAverageLatency().AsDuration().Round(time.Microsecond),
+			stats.GetLastSeen().AsTime().Local().Format("2006-01-02 15:04:05"),
+			stats.GetLastHandler(),
+		))
+	}
+	return builder.String()
+}
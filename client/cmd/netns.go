@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/netbirdio/netbird/iface/netns"
+	"github.com/netbirdio/netbird/iface/netstack"
+)
+
+const netNSFlag = "netns"
+
+var netNSName string
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&netNSName, netNSFlag, "",
+		`Places the WireGuard interface with its routes and firewall rules in a Linux network namespace, `+
+			`the connections to the Management, Signal and the peers are kept in the namespace of the host. `+
+			`Either the name of a namespace managed by "ip netns", created if it doesn't exist, `+
+			`or a path like /proc/<pid>/ns/net`)
+}
+
+// setNetNS opens the namespace the interfaces created by the engine are placed in, from the flag
+func setNetNS() error {
+	if netNSName == "" {
+		return nil
+	}
+	if netstack.IsEnabled() {
+		return fmt.Errorf("--%s can't be used with --%s, the interface isn't visible to the host", netNSFlag, useNetstackModeFlag)
+	}
+
+	ns, err := netns.Open(netNSName)
+	if err != nil {
+		return err
+	}
+	log.Infof("the WireGuard interface is placed in network namespace %s", ns)
+	netns.SetDefault(ns)
+	return nil
+}
+
+// netNSServiceArguments returns the namespace flag set by the user to pass it to the installed service
+func netNSServiceArguments() []string {
+	if !rootCmd.PersistentFlags().Changed(netNSFlag) {
+		return nil
+	}
+	return []string{"--" + netNSFlag + "=" + netNSName}
+}
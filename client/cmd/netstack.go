@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/netbirdio/netbird/iface/netstack"
+)
+
+const (
+	useNetstackModeFlag          = "use-netstack-mode"
+	socks5ListenerPortFlag       = "socks5-listener-port"
+	httpProxyListenerPortFlag    = "http-proxy-listener-port"
+	proxyListenerAddressFlag     = "proxy-listener-address"
+	proxyUsernameFlag            = "proxy-username"
+	proxyPasswordFlag            = "proxy-password"
+	proxyPasswordFileFlag        = "proxy-password-file"
+	proxyAllowedDestinationsFlag = "proxy-allowed-destinations"
+	portForwardsFlag             = "port-forwards"
+
+	// proxyPasswordFileName is the file next to the config the password of the proxies is saved in for the service
+	proxyPasswordFileName = "proxy_password"
+)
+
+var (
+	useNetstackMode          bool
+	socks5ListenerPort       int
+	httpProxyListenerPort    int
+	proxyListenerAddress     string
+	proxyUsername            string
+	proxyPassword            string
+	proxyPasswordFile        string
+	proxyAllowedDestinations []string
+	portForwards             []string
+)
+
+func init() {
+	// the flag names match the environment variables configuring the netstack mode before these flags existed
+	rootCmd.PersistentFlags().BoolVar(&useNetstackMode, useNetstackModeFlag, false,
+		"Runs the WireGuard interface in a userspace network stack without a kernel interface. "+
+			"The peers are reached through the SOCKS5 and HTTP proxies")
+	rootCmd.PersistentFlags().IntVar(&socks5ListenerPort, socks5ListenerPortFlag, netstack.DefaultSocks5Port,
+		"Port of the SOCKS5 proxy into the network in netstack mode")
+	rootCmd.PersistentFlags().IntVar(&httpProxyListenerPort, httpProxyListenerPortFlag, 0,
+		"Port of the HTTP CONNECT proxy into the network in netstack mode, the proxy is disabled when it is 0")
+	rootCmd.PersistentFlags().StringVar(&proxyListenerAddress, proxyListenerAddressFlag, "127.0.0.1",
+		"Address the proxies listen on in netstack mode, e.g. 0.0.0.0 to accept remote clients. "+
+			"A non-loopback address requires --"+proxyUsernameFlag+" and a password")
+	rootCmd.PersistentFlags().StringVar(&proxyUsername, proxyUsernameFlag, "",
+		"Username the clients of the proxies authenticate with in netstack mode. No authentication is required if empty")
+	rootCmd.PersistentFlags().StringVar(&proxyPassword, proxyPasswordFlag, "",
+		"Password the clients of the proxies authenticate with in netstack mode. "+
+			"Prefer the NB_PROXY_PASSWORD environment variable or --"+proxyPasswordFileFlag+
+			", the flags are visible to the other users of the host")
+	rootCmd.PersistentFlags().StringVar(&proxyPasswordFile, proxyPasswordFileFlag, "",
+		"File containing the password the clients of the proxies authenticate with in netstack mode, "+
+			"used if the password isn't set otherwise. The file should only be readable by the user running the client")
+	rootCmd.PersistentFlags().StringSliceVar(&proxyAllowedDestinations, proxyAllowedDestinationsFlag, nil,
+		`Destinations the proxies may connect to in netstack mode, all are allowed if empty. `+
+			`A destination is a prefix or address optionally followed by a port or port range, `+
+			`e.g. --proxy-allowed-destinations 100.64.0.0/10,10.0.0.0/8:443,10.1.0.5:8000-8100`)
+	rootCmd.PersistentFlags().StringSliceVar(&portForwards, portForwardsFlag, nil,
+		`Forwards ports of the peer address to services reachable from the host in netstack mode. `+
+			`A forward is port[/tcp|udp]:host:port, the protocol defaults to tcp, `+
+			`e.g. --port-forwards 8080:127.0.0.1:80,5353/udp:127.0.0.1:53`)
+}
+
+// setNetstackOptions configures the netstack mode of the interface created by the engine from the flags
+func setNetstackOptions() error {
+	opts, err := netstackOptions()
+	if err != nil {
+		return err
+	}
+	netstack.SetOptions(opts)
+	return nil
+}
+
+func netstackOptions() (netstack.Options, error) {
+	opts := netstack.Options{Enabled: useNetstackMode}
+
+	if net.ParseIP(proxyListenerAddress) == nil {
+		return opts, fmt.Errorf("invalid proxy listener address %s", proxyListenerAddress)
+	}
+	if socks5ListenerPort < 1 || socks5ListenerPort > 65535 {
+		return opts, fmt.Errorf("invalid socks5 listener port %d, it should be in the range 1-65535", socks5ListenerPort)
+	}
+	opts.Proxy.ListenAddr = net.JoinHostPort(proxyListenerAddress, strconv.Itoa(socks5ListenerPort))
+
+	if httpProxyListenerPort < 0 || httpProxyListenerPort > 65535 {
+		return opts, fmt.Errorf("invalid http proxy listener port %d, it should be in the range 1-65535", httpProxyListenerPort)
+	}
+	if httpProxyListenerPort != 0 {
+		opts.Proxy.HTTPListenAddr = net.JoinHostPort(proxyListenerAddress, strconv.Itoa(httpProxyListenerPort))
+	}
+
+	password := proxyPassword
+	if password == "" && proxyPasswordFile != "" {
+		var err error
+		password, err = readProxyPassword(proxyPasswordFile)
+		if err != nil {
+			return opts, err
+		}
+	}
+	if (proxyUsername == "") != (password == "") {
+		return opts, fmt.Errorf("both --%s and --%s must be set to authenticate the proxy clients", proxyUsernameFlag, proxyPasswordFlag)
+	}
+	opts.Proxy.Username = proxyUsername
+	opts.Proxy.Password = password
+
+	for _, d := range proxyAllowedDestinations {
+		if d == "" {
+			continue
+		}
+		destination, err := netstack.ParseDestination(d)
+		if err != nil {
+			return opts, err
+		}
+		opts.Proxy.AllowedDestinations = append(opts.Proxy.AllowedDestinations, destination)
+	}
+
+	for _, f := range portForwards {
+		if f == "" {
+			continue
+		}
+		forward, err := netstack.ParsePortForward(f)
+		if err != nil {
+			return opts, err
+		}
+		opts.PortForwards = append(opts.PortForwards, forward)
+	}
+
+	if useNetstackMode {
+		if err := opts.Proxy.Validate(); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// netstackServiceArguments returns the netstack flags set by the user to pass them to the installed service.
+// The password of the proxies is never passed as an argument, see proxyPasswordServiceArguments
+func netstackServiceArguments() []string {
+	var args []string
+	flags := rootCmd.PersistentFlags()
+	for _, name := range []string{
+		useNetstackModeFlag,
+		socks5ListenerPortFlag,
+		httpProxyListenerPortFlag,
+		proxyListenerAddressFlag,
+		proxyUsernameFlag,
+		proxyPasswordFileFlag,
+	} {
+		if flags.Changed(name) {
+			args = append(args, "--"+name+"="+flags.Lookup(name).Value.String())
+		}
+	}
+	if flags.Changed(proxyAllowedDestinationsFlag) {
+		args = append(args, "--"+proxyAllowedDestinationsFlag+"="+strings.Join(proxyAllowedDestinations, ","))
+	}
+	if flags.Changed(portForwardsFlag) {
+		args = append(args, "--"+portForwardsFlag+"="+strings.Join(portForwards, ","))
+	}
+	return args
+}
+
+// proxyPasswordServiceArguments saves the password of the proxies set by the user, from the flag or the environment,
+// to a file only readable by its owner next to the config and returns the flag reading it in the installed service.
+// The arguments of the service are visible to the other users of the host
+func proxyPasswordServiceArguments() ([]string, error) {
+	if proxyPassword == "" {
+		return nil, nil
+	}
+
+	path := filepath.Join(filepath.Dir(configPath), proxyPasswordFileName)
+	if err := writeProxyPassword(path, proxyPassword); err != nil {
+		return nil, err
+	}
+	return []string{"--" + proxyPasswordFileFlag + "=" + path}, nil
+}
+
+// removeProxyPasswordFile removes the password of the proxies saved for the installed service
+func removeProxyPasswordFile() error {
+	path := filepath.Join(filepath.Dir(configPath), proxyPasswordFileName)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove proxy password file: %w", err)
+	}
+	return nil
+}
+
+func writeProxyPassword(path, password string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("create proxy password directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(password), 0600); err != nil {
+		return fmt.Errorf("write proxy password file: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("set proxy password file mode: %w", err)
+	}
+	return nil
+}
+
+func readProxyPassword(path string) (string, error) {
+	password, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read proxy password file: %w", err)
+	}
+	return strings.TrimRight(string(password), "\r\n"), nil
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyPasswordFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netbird", proxyPasswordFileName)
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0644))
+
+	require.NoError(t, writeProxyPassword(path, "secret"))
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "password file should only be readable by its owner")
+	}
+
+	password, err := readProxyPassword(path)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", password)
+
+	require.NoError(t, os.WriteFile(path, []byte("secret\r\n"), 0600))
+	password, err = readProxyPassword(path)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", password, "trailing line break should be ignored")
+}
+
+func TestNetstackServiceArguments_NoPassword(t *testing.T) {
+	require.NoError(t, rootCmd.PersistentFlags().Set(proxyPasswordFlag, "secret"))
+	t.Cleanup(func() {
+		proxyPassword = ""
+		rootCmd.PersistentFlags().Lookup(proxyPasswordFlag).Changed = false
+	})
+
+	for _, arg := range netstackServiceArguments() {
+		assert.NotContains(t, arg, "secret", "password shouldn't be an argument of the service")
+	}
+}
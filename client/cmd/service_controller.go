@@ -98,6 +98,14 @@ var runCmd = &cobra.Command{
 			return fmt.Errorf("failed initializing log %v", err)
 		}
 
+		if err := setNetstackOptions(); err != nil {
+			return err
+		}
+
+		if err := setNetNS(); err != nil {
+			return err
+		}
+
 		ctx, cancel := context.WithCancel(cmd.Context())
 		SetupCloseHandler(ctx, cancel)
 
@@ -41,6 +41,15 @@ var installCmd = &cobra.Command{
 			svcConfig.Arguments = append(svcConfig.Arguments, "--log-file", logFile)
 		}
 
+		svcConfig.Arguments = append(svcConfig.Arguments, netstackServiceArguments()...)
+		passwordArgs, err := proxyPasswordServiceArguments()
+		if err != nil {
+			cmd.PrintErrln(err)
+			return err
+		}
+		svcConfig.Arguments = append(svcConfig.Arguments, passwordArgs...)
+		svcConfig.Arguments = append(svcConfig.Arguments, netNSServiceArguments()...)
+
 		if runtime.GOOS == "linux" {
 			// Respected only by systemd systems
 			svcConfig.Dependencies = []string{"After=network.target syslog.target"}
@@ -106,6 +115,9 @@ var uninstallCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if err := removeProxyPasswordFile(); err != nil {
+			cmd.PrintErrln(err)
+		}
 		cmd.Println("Netbird has been uninstalled")
 		return nil
 	},
@@ -11,8 +11,10 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
 
 	"github.com/netbirdio/netbird/client/internal"
+	"github.com/netbirdio/netbird/client/proto"
 	nbssh "github.com/netbirdio/netbird/client/ssh"
 	"github.com/netbirdio/netbird/util"
 )
@@ -89,8 +91,24 @@ var sshCmd = &cobra.Command{
 	},
 }
 
+// sftpServerCmd serves SFTP over stdin and stdout. It is started by the SSH server as the session's local user
+var sftpServerCmd = &cobra.Command{
+	Use:    nbssh.SFTPServerCommand,
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return nbssh.ServeSFTP()
+	},
+}
+
 func runSSH(ctx context.Context, addr string, pemKey []byte, cmd *cobra.Command) error {
-	c, err := nbssh.DialWithKey(fmt.Sprintf("%s:%d", addr, port), user, pemKey)
+	hostKey, err := getPeerSSHHostKey(ctx, addr)
+	if err != nil {
+		cmd.Printf("Error: %v\n", err)
+		return err
+	}
+
+	c, err := nbssh.DialWithKey(fmt.Sprintf("%s:%d", addr, port), user, pemKey, hostKey)
 	if err != nil {
 		cmd.Printf("Error: %v\n", err)
 		cmd.Printf("Couldn't connect. Please check the connection status or if the ssh server is enabled on the other peer" +
@@ -114,6 +132,45 @@ func runSSH(ctx context.Context, addr string, pemKey []byte, cmd *cobra.Command)
 	return nil
 }
 
+// getPeerSSHHostKey returns the SSH host key of the peer with the given NetBird IP, FQDN or short name
+// received by the daemon from the management service
+func getPeerSSHHostKey(ctx context.Context, addr string) ([]byte, error) {
+	conn, err := DialClientGRPCServer(ctx, daemonAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to daemon error: %v\n"+
+			"If the daemon is not running please run: "+
+			"\nnetbird service install \nnetbird service start\n", err)
+	}
+	defer conn.Close()
+
+	resp, err := proto.NewDaemonServiceClient(conn).Status(ctx, &proto.StatusRequest{GetFullPeerStatus: true})
+	if err != nil {
+		return nil, fmt.Errorf("status failed: %v", status.Convert(err).Message())
+	}
+
+	for _, peerState := range resp.GetFullStatus().GetPeers() {
+		if !matchesPeer(peerState, addr) {
+			continue
+		}
+		if peerState.GetSshHostKey() == "" {
+			return nil, fmt.Errorf("no SSH host key known for peer %s", addr)
+		}
+		return []byte(peerState.GetSshHostKey()), nil
+	}
+
+	return nil, fmt.Errorf("peer %s not found, the host has to be the NetBird IP or domain name of a peer", addr)
+}
+
+func matchesPeer(peerState *proto.PeerState, addr string) bool {
+	addr = strings.ToLower(strings.TrimSuffix(addr, "."))
+	fqdn := strings.ToLower(strings.TrimSuffix(peerState.GetFqdn(), "."))
+	if addr == peerState.GetIP() || (fqdn != "" && addr == fqdn) {
+		return true
+	}
+	name, _, _ := strings.Cut(fqdn, ".")
+	return name != "" && addr == name
+}
+
 func init() {
 	sshCmd.PersistentFlags().IntVarP(&port, "port", "p", nbssh.DefaultSSHPort, "Sets remote SSH port. Defaults to "+fmt.Sprint(nbssh.DefaultSSHPort))
 }
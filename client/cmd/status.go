@@ -22,14 +22,25 @@ import (
 )
 
 type peerStateDetailOutput struct {
-	FQDN             string           `json:"fqdn" yaml:"fqdn"`
-	IP               string           `json:"netbirdIp" yaml:"netbirdIp"`
-	PubKey           string           `json:"publicKey" yaml:"publicKey"`
-	Status           string           `json:"status" yaml:"status"`
-	LastStatusUpdate time.Time        `json:"lastStatusUpdate" yaml:"lastStatusUpdate"`
-	ConnType         string           `json:"connectionType" yaml:"connectionType"`
-	Direct           bool             `json:"direct" yaml:"direct"`
-	IceCandidateType iceCandidateType `json:"iceCandidateType" yaml:"iceCandidateType"`
+	FQDN                   string               `json:"fqdn" yaml:"fqdn"`
+	IP                     string               `json:"netbirdIp" yaml:"netbirdIp"`
+	PubKey                 string               `json:"publicKey" yaml:"publicKey"`
+	Status                 string               `json:"status" yaml:"status"`
+	LastStatusUpdate       time.Time            `json:"lastStatusUpdate" yaml:"lastStatusUpdate"`
+	ConnType               string               `json:"connectionType" yaml:"connectionType"`
+	Direct                 bool                 `json:"direct" yaml:"direct"`
+	IceCandidateType       iceCandidateType     `json:"iceCandidateType" yaml:"iceCandidateType"`
+	IceCandidateEndpoint   iceCandidateEndpoint `json:"iceCandidateEndpoint" yaml:"iceCandidateEndpoint"`
+	LastWireguardHandshake time.Time            `json:"lastWireguardHandshake" yaml:"lastWireguardHandshake"`
+	TransferReceived       int64                `json:"transferReceived" yaml:"transferReceived"`
+	TransferSent           int64                `json:"transferSent" yaml:"transferSent"`
+	Latency                time.Duration        `json:"latency" yaml:"latency"`
+	RelayUpgrades          relayUpgradesOutput  `json:"relayUpgrades" yaml:"relayUpgrades"`
+}
+
+type relayUpgradesOutput struct {
+	Attempts  int64 `json:"attempts" yaml:"attempts"`
+	Succeeded int64 `json:"succeeded" yaml:"succeeded"`
 }
 
 type peersStateOutput struct {
@@ -53,6 +64,11 @@ type iceCandidateType struct {
 	Remote string `json:"remote" yaml:"remote"`
 }
 
+type iceCandidateEndpoint struct {
+	Local  string `json:"local" yaml:"local"`
+	Remote string `json:"remote" yaml:"remote"`
+}
+
 type statusOutputOverview struct {
 	Peers           peersStateOutput      `json:"peers" yaml:"peers"`
 	CliVersion      string                `json:"cliVersion" yaml:"cliVersion"`
@@ -93,7 +109,7 @@ func init() {
 	statusCmd.MarkFlagsMutuallyExclusive("detail", "json", "yaml", "ipv4")
 	statusCmd.PersistentFlags().StringSliceVar(&ipsFilter, "filter-by-ips", []string{}, "filters the detailed output by a list of one or more IPs, e.g., --filter-by-ips 100.64.0.100,100.64.0.200")
 	statusCmd.PersistentFlags().StringSliceVar(&prefixNamesFilter, "filter-by-names", []string{}, "filters the detailed output by a list of one or more peer FQDN or hostnames, e.g., --filter-by-names peer-a,peer-b.netbird.cloud")
-	statusCmd.PersistentFlags().StringVar(&statusFilter, "filter-by-status", "", "filters the detailed output by connection status(connected|disconnected), e.g., --filter-by-status connected")
+	statusCmd.PersistentFlags().StringVar(&statusFilter, "filter-by-status", "", "filters the detailed output by connection status(connected|disconnected|idle), e.g., --filter-by-status connected")
 }
 
 func statusFunc(cmd *cobra.Command, args []string) error {
@@ -178,12 +194,12 @@ func getStatus(ctx context.Context, cmd *cobra.Command) (*proto.StatusResponse,
 func parseFilters() error {
 
 	switch strings.ToLower(statusFilter) {
-	case "", "disconnected", "connected":
+	case "", "disconnected", "connected", "idle":
 		if strings.ToLower(statusFilter) != "" {
 			enableDetailFlagWhenFilterFlag()
 		}
 	default:
-		return fmt.Errorf("wrong status filter, should be one of connected|disconnected, got: %s", statusFilter)
+		return fmt.Errorf("wrong status filter, should be one of connected|disconnected|idle, got: %s", statusFilter)
 	}
 
 	if len(ipsFilter) > 0 {
@@ -249,6 +265,8 @@ func mapPeers(peers []*proto.PeerState) peersStateOutput {
 	var peersStateDetail []peerStateDetailOutput
 	localICE := ""
 	remoteICE := ""
+	localICEEndpoint := ""
+	remoteICEEndpoint := ""
 	connType := ""
 	peersConnected := 0
 	for _, pbPeerState := range peers {
@@ -261,6 +279,8 @@ func mapPeers(peers []*proto.PeerState) peersStateOutput {
 
 			localICE = pbPeerState.GetLocalIceCandidateType()
 			remoteICE = pbPeerState.GetRemoteIceCandidateType()
+			localICEEndpoint = pbPeerState.GetLocalIceCandidateEndpoint()
+			remoteICEEndpoint = pbPeerState.GetRemoteIceCandidateEndpoint()
 			connType = "P2P"
 			if pbPeerState.Relayed {
 				connType = "Relayed"
@@ -279,7 +299,21 @@ func mapPeers(peers []*proto.PeerState) peersStateOutput {
 				Local:  localICE,
 				Remote: remoteICE,
 			},
-			FQDN: pbPeerState.GetFqdn(),
+			IceCandidateEndpoint: iceCandidateEndpoint{
+				Local:  localICEEndpoint,
+				Remote: remoteICEEndpoint,
+			},
+			FQDN:             pbPeerState.GetFqdn(),
+			TransferReceived: pbPeerState.GetBytesRx(),
+			TransferSent:     pbPeerState.GetBytesTx(),
+			Latency:          pbPeerState.GetLatency().AsDuration(),
+			RelayUpgrades: relayUpgradesOutput{
+				Attempts:  pbPeerState.GetRelayUpgradeAttempts(),
+				Succeeded: pbPeerState.GetRelayUpgrades(),
+			},
+		}
+		if pbPeerState.GetLastWireguardHandshake() != nil {
+			peerState.LastWireguardHandshake = pbPeerState.GetLastWireguardHandshake().AsTime().Local()
 		}
 
 		peersStateDetail = append(peersStateDetail, peerState)
@@ -409,6 +443,26 @@ func parsePeers(peers peersStateOutput) string {
 			remoteICE = peerState.IceCandidateType.Remote
 		}
 
+		localICEEndpoint := "-"
+		if peerState.IceCandidateEndpoint.Local != "" {
+			localICEEndpoint = peerState.IceCandidateEndpoint.Local
+		}
+
+		remoteICEEndpoint := "-"
+		if peerState.IceCandidateEndpoint.Remote != "" {
+			remoteICEEndpoint = peerState.IceCandidateEndpoint.Remote
+		}
+
+		lastWireguardHandshake := "-"
+		if !peerState.LastWireguardHandshake.IsZero() {
+			lastWireguardHandshake = peerState.LastWireguardHandshake.Format("2006-01-02 15:04:05")
+		}
+
+		latency := "-"
+		if peerState.Latency > 0 {
+			latency = peerState.Latency.String()
+		}
+
 		peerString := fmt.Sprintf(
 			"\n %s:\n"+
 				"  NetBird IP: %s\n"+
@@ -418,7 +472,11 @@ func parsePeers(peers peersStateOutput) string {
 				"  Connection type: %s\n"+
 				"  Direct: %t\n"+
 				"  ICE candidate (Local/Remote): %s/%s\n"+
-				"  Last connection update: %s\n",
+				"  ICE candidate endpoints (Local/Remote): %s/%s\n"+
+				"  Last connection update: %s\n"+
+				"  Last WireGuard handshake: %s\n"+
+				"  Transfer status (received/sent): %s/%s\n"+
+				"  Latency: %s\n",
 			peerState.FQDN,
 			peerState.IP,
 			peerState.PubKey,
@@ -427,7 +485,13 @@ func parsePeers(peers peersStateOutput) string {
 			peerState.Direct,
 			localICE,
 			remoteICE,
+			localICEEndpoint,
+			remoteICEEndpoint,
 			peerState.LastStatusUpdate.Format("2006-01-02 15:04:05"),
+			lastWireguardHandshake,
+			toIEC(peerState.TransferReceived),
+			toIEC(peerState.TransferSent),
+			latency,
 		)
 
 		peersString += peerString
@@ -446,6 +510,8 @@ func skipDetailByFilters(peerState *proto.PeerState, isConnected bool) bool {
 			statusEval = true
 		} else if lowerStatusFilter == "connected" && !isConnected {
 			statusEval = true
+		} else if lowerStatusFilter == "idle" && peerState.ConnStatus != peer.StatusIdle.String() {
+			statusEval = true
 		}
 	}
 
@@ -467,3 +533,17 @@ func skipDetailByFilters(peerState *proto.PeerState, isConnected bool) bool {
 
 	return statusEval || ipEval || nameEval
 }
+
+// toIEC formats a byte count with binary prefixes
+func toIEC(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
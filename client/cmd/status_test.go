@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/netbirdio/netbird/client/proto"
@@ -25,26 +26,41 @@ var resp = &proto.StatusResponse{
 	FullStatus: &proto.FullStatus{
 		Peers: []*proto.PeerState{
 			{
-				IP:                     "192.168.178.101",
-				PubKey:                 "Pubkey1",
-				Fqdn:                   "peer-1.awesome-domain.com",
-				ConnStatus:             "Connected",
-				ConnStatusUpdate:       timestamppb.New(time.Date(2001, time.Month(1), 1, 1, 1, 1, 0, time.UTC)),
-				Relayed:                false,
-				Direct:                 true,
-				LocalIceCandidateType:  "",
-				RemoteIceCandidateType: "",
+				IP:                         "192.168.178.101",
+				PubKey:                     "Pubkey1",
+				Fqdn:                       "peer-1.awesome-domain.com",
+				ConnStatus:                 "Connected",
+				ConnStatusUpdate:           timestamppb.New(time.Date(2001, time.Month(1), 1, 1, 1, 1, 0, time.UTC)),
+				Relayed:                    false,
+				Direct:                     true,
+				LocalIceCandidateType:      "",
+				RemoteIceCandidateType:     "",
+				LocalIceCandidateEndpoint:  "",
+				RemoteIceCandidateEndpoint: "",
+				LastWireguardHandshake:     timestamppb.New(time.Date(2001, 1, 1, 1, 1, 2, 0, time.UTC)),
+				BytesRx:                    200,
+				BytesTx:                    100,
+				Latency:                    durationpb.New(time.Duration(10000000)),
+				RelayUpgradeAttempts:       1,
+				RelayUpgrades:              1,
 			},
 			{
-				IP:                     "192.168.178.102",
-				PubKey:                 "Pubkey2",
-				Fqdn:                   "peer-2.awesome-domain.com",
-				ConnStatus:             "Connected",
-				ConnStatusUpdate:       timestamppb.New(time.Date(2002, time.Month(2), 2, 2, 2, 2, 0, time.UTC)),
-				Relayed:                true,
-				Direct:                 false,
-				LocalIceCandidateType:  "relay",
-				RemoteIceCandidateType: "prflx",
+				IP:                         "192.168.178.102",
+				PubKey:                     "Pubkey2",
+				Fqdn:                       "peer-2.awesome-domain.com",
+				ConnStatus:                 "Connected",
+				ConnStatusUpdate:           timestamppb.New(time.Date(2002, time.Month(2), 2, 2, 2, 2, 0, time.UTC)),
+				Relayed:                    true,
+				Direct:                     false,
+				LocalIceCandidateType:      "relay",
+				RemoteIceCandidateType:     "prflx",
+				LocalIceCandidateEndpoint:  "10.0.0.1:10001",
+				RemoteIceCandidateEndpoint: "10.0.10.1:10002",
+				LastWireguardHandshake:     timestamppb.New(time.Date(2002, 2, 2, 2, 2, 3, 0, time.UTC)),
+				BytesRx:                    2000,
+				BytesTx:                    1000,
+				Latency:                    durationpb.New(time.Duration(10000000)),
+				RelayUpgradeAttempts:       3,
 			},
 		},
 		ManagementState: &proto.ManagementState{
@@ -82,6 +98,18 @@ var overview = statusOutputOverview{
 					Local:  "",
 					Remote: "",
 				},
+				IceCandidateEndpoint: iceCandidateEndpoint{
+					Local:  "",
+					Remote: "",
+				},
+				LastWireguardHandshake: time.Date(2001, 1, 1, 1, 1, 2, 0, time.UTC),
+				TransferReceived:       200,
+				TransferSent:           100,
+				Latency:                time.Duration(10000000),
+				RelayUpgrades: relayUpgradesOutput{
+					Attempts:  1,
+					Succeeded: 1,
+				},
 			},
 			{
 				IP:               "192.168.178.102",
@@ -95,6 +123,18 @@ var overview = statusOutputOverview{
 					Local:  "relay",
 					Remote: "prflx",
 				},
+				IceCandidateEndpoint: iceCandidateEndpoint{
+					Local:  "10.0.0.1:10001",
+					Remote: "10.0.10.1:10002",
+				},
+				LastWireguardHandshake: time.Date(2002, 2, 2, 2, 2, 3, 0, time.UTC),
+				TransferReceived:       2000,
+				TransferSent:           1000,
+				Latency:                time.Duration(10000000),
+				RelayUpgrades: relayUpgradesOutput{
+					Attempts:  3,
+					Succeeded: 0,
+				},
 			},
 		},
 	},
@@ -167,6 +207,20 @@ func TestParsingToJSON(t *testing.T) {
 		"{" +
 		"\"local\":\"\"," +
 		"\"remote\":\"\"" +
+		"}," +
+		"\"iceCandidateEndpoint\":" +
+		"{" +
+		"\"local\":\"\"," +
+		"\"remote\":\"\"" +
+		"}," +
+		"\"lastWireguardHandshake\":\"2001-01-01T01:01:02Z\"," +
+		"\"transferReceived\":200," +
+		"\"transferSent\":100," +
+		"\"latency\":10000000," +
+		"\"relayUpgrades\":" +
+		"{" +
+		"\"attempts\":1," +
+		"\"succeeded\":1" +
 		"}" +
 		"}," +
 		"{" +
@@ -181,6 +235,20 @@ func TestParsingToJSON(t *testing.T) {
 		"{" +
 		"\"local\":\"relay\"," +
 		"\"remote\":\"prflx\"" +
+		"}," +
+		"\"iceCandidateEndpoint\":" +
+		"{" +
+		"\"local\":\"10.0.0.1:10001\"," +
+		"\"remote\":\"10.0.10.1:10002\"" +
+		"}," +
+		"\"lastWireguardHandshake\":\"2002-02-02T02:02:03Z\"," +
+		"\"transferReceived\":2000," +
+		"\"transferSent\":1000," +
+		"\"latency\":10000000," +
+		"\"relayUpgrades\":" +
+		"{" +
+		"\"attempts\":3," +
+		"\"succeeded\":0" +
 		"}" +
 		"}" +
 		"]" +
@@ -224,6 +292,16 @@ func TestParsingToYAML(t *testing.T) {
 		"          iceCandidateType:\n" +
 		"            local: \"\"\n" +
 		"            remote: \"\"\n" +
+		"          iceCandidateEndpoint:\n" +
+		"            local: \"\"\n" +
+		"            remote: \"\"\n" +
+		"          lastWireguardHandshake: 2001-01-01T01:01:02Z\n" +
+		"          transferReceived: 200\n" +
+		"          transferSent: 100\n" +
+		"          latency: 10ms\n" +
+		"          relayUpgrades:\n" +
+		"            attempts: 1\n" +
+		"            succeeded: 1\n" +
 		"        - fqdn: peer-2.awesome-domain.com\n" +
 		"          netbirdIp: 192.168.178.102\n" +
 		"          publicKey: Pubkey2\n" +
@@ -234,6 +312,16 @@ func TestParsingToYAML(t *testing.T) {
 		"          iceCandidateType:\n" +
 		"            local: relay\n" +
 		"            remote: prflx\n" +
+		"          iceCandidateEndpoint:\n" +
+		"            local: 10.0.0.1:10001\n" +
+		"            remote: 10.0.10.1:10002\n" +
+		"          lastWireguardHandshake: 2002-02-02T02:02:03Z\n" +
+		"          transferReceived: 2000\n" +
+		"          transferSent: 1000\n" +
+		"          latency: 10ms\n" +
+		"          relayUpgrades:\n" +
+		"            attempts: 3\n" +
+		"            succeeded: 0\n" +
 		"cliVersion: development\n" +
 		"daemonVersion: 0.14.1\n" +
 		"management:\n" +
@@ -262,7 +350,11 @@ func TestParsingToDetail(t *testing.T) {
 		"  Connection type: P2P\n" +
 		"  Direct: true\n" +
 		"  ICE candidate (Local/Remote): -/-\n" +
+		"  ICE candidate endpoints (Local/Remote): -/-\n" +
 		"  Last connection update: 2001-01-01 01:01:01\n" +
+		"  Last WireGuard handshake: 2001-01-01 01:01:02\n" +
+		"  Transfer status (received/sent): 200 B/100 B\n" +
+		"  Latency: 10ms\n" +
 		"\n" +
 		" peer-2.awesome-domain.com:\n" +
 		"  NetBird IP: 192.168.178.102\n" +
@@ -272,7 +364,11 @@ func TestParsingToDetail(t *testing.T) {
 		"  Connection type: Relayed\n" +
 		"  Direct: false\n" +
 		"  ICE candidate (Local/Remote): relay/prflx\n" +
+		"  ICE candidate endpoints (Local/Remote): 10.0.0.1:10001/10.0.10.1:10002\n" +
 		"  Last connection update: 2002-02-02 02:02:02\n" +
+		"  Last WireGuard handshake: 2002-02-02 02:02:03\n" +
+		"  Transfer status (received/sent): 2.0 KiB/1000 B\n" +
+		"  Latency: 10ms\n" +
 		"\n" +
 		"Daemon version: 0.14.1\n" +
 		"CLI version: development\n" +
@@ -101,6 +101,14 @@ func runInForegroundMode(ctx context.Context, cmd *cobra.Command) error {
 
 	config, _ = internal.UpdateOldManagementURL(ctx, config, configPath)
 
+	if err := setNetstackOptions(); err != nil {
+		return err
+	}
+
+	if err := setNetNS(); err != nil {
+		return err
+	}
+
 	err = foregroundLogin(ctx, cmd, config, setupKey)
 	if err != nil {
 		return fmt.Errorf("foreground login failed: %v", err)
@@ -113,6 +121,12 @@ func runInForegroundMode(ctx context.Context, cmd *cobra.Command) error {
 }
 
 func runInDaemonMode(ctx context.Context, cmd *cobra.Command) error {
+	if len(netstackServiceArguments()) > 0 || proxyPassword != "" {
+		log.Warn("the netstack flags are ignored by the daemon, pass them to the service instead")
+	}
+	if len(netNSServiceArguments()) > 0 {
+		log.Warnf("the --%s flag is ignored by the daemon, pass it to the service instead", netNSFlag)
+	}
 
 	customDNSAddressConverted, err := parseCustomDNSAddress(cmd.Flag(dnsResolverAddress).Changed)
 	if err != nil {
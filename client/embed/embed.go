@@ -0,0 +1,350 @@
+// Package embed runs a NetBird peer inside a Go application, without the daemon and without privileges. The
+// interface of the peer runs in a userspace network stack: the application reaches the other peers with Dial and
+// accepts their connections with Listen and ListenUDP, the traffic of the host isn't routed through the network.
+package embed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/netbirdio/netbird/client/internal"
+	"github.com/netbirdio/netbird/client/internal/peer"
+	"github.com/netbirdio/netbird/client/system"
+	"github.com/netbirdio/netbird/iface/netstack"
+)
+
+const startPollInterval = 100 * time.Millisecond
+
+var (
+	// ErrClientNotStarted is returned when the network is used before the client is started
+	ErrClientNotStarted = errors.New("client not started")
+	// ErrClientAlreadyStarted is returned when a running client is started again
+	ErrClientAlreadyStarted = errors.New("client already started")
+)
+
+// Options configure an embedded client
+type Options struct {
+	// DeviceName is the name the peer is registered with, the hostname is used if empty
+	DeviceName string
+	// SetupKey registers the peer, it isn't needed anymore once the peer is registered
+	SetupKey string
+	// ManagementURL is the URL of the Management service, the NetBird cloud is used if empty
+	ManagementURL string
+	// PreSharedKey is the WireGuard pre-shared key, only peers with the same key can communicate
+	PreSharedKey string
+	// ConfigPath is the file the identity of the peer is stored in. Without it, a new peer is registered each time
+	// a client is created
+	ConfigPath string
+	// WireguardPort is the UDP port of WireGuard on the host, the default port is used if nil
+	WireguardPort *int
+	// PortForwards expose services of the host to the other peers on the peer address
+	PortForwards []netstack.PortForward
+}
+
+// Client is a NetBird peer running in the application
+type Client struct {
+	deviceName string
+	setupKey   string
+	config     *internal.Config
+	opts       netstack.Options
+
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	connect  *internal.ConnectClient
+	recorder *peer.Status
+	done     chan error
+}
+
+// New creates a client, the peer is registered and connected by Start
+func New(opts Options) (*Client, error) {
+	input := internal.ConfigInput{
+		ConfigPath:    opts.ConfigPath,
+		ManagementURL: opts.ManagementURL,
+	}
+	if opts.PreSharedKey != "" {
+		input.PreSharedKey = &opts.PreSharedKey
+	}
+
+	var (
+		config *internal.Config
+		err    error
+	)
+	if opts.ConfigPath != "" {
+		config, err = internal.UpdateOrCreateConfig(input)
+	} else {
+		config, err = internal.CreateInMemoryConfig(input)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create config: %w", err)
+	}
+	if opts.WireguardPort != nil {
+		config.WgPort = *opts.WireguardPort
+	}
+
+	return &Client{
+		deviceName: opts.DeviceName,
+		setupKey:   opts.SetupKey,
+		config:     config,
+		// the proxies of the netstack mode aren't served, the application uses the network directly
+		opts: netstack.Options{Enabled: true, PortForwards: opts.PortForwards},
+	}, nil
+}
+
+// Start registers the peer if needed and connects it, it returns once the network can be used. The client keeps
+// running after the context is done, it is stopped by Stop
+func (c *Client) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel != nil {
+		return ErrClientAlreadyStarted
+	}
+
+	runCtx := internal.CtxInitState(context.Background())
+	if c.deviceName != "" {
+		// nolint
+		runCtx = context.WithValue(runCtx, system.DeviceNameCtxKey, c.deviceName)
+		// nolint
+		ctx = context.WithValue(ctx, system.DeviceNameCtxKey, c.deviceName)
+	}
+
+	if err := internal.Login(ctx, c.config, c.setupKey, ""); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(runCtx)
+	recorder := peer.NewRecorder(c.config.ManagementURL.String())
+	connect := internal.NewConnectClient(runCtx, c.config, recorder)
+	done := make(chan error, 1)
+	go func() {
+		done <- connect.RunWithNetstack(c.opts)
+	}()
+
+	ticker := time.NewTicker(startPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-done
+			return ctx.Err()
+		case err := <-done:
+			cancel()
+			if err == nil {
+				err = errors.New("client stopped")
+			}
+			return fmt.Errorf("run client: %w", err)
+		case <-ticker.C:
+			if engine := connect.Engine(); engine == nil || engine.GetNet() == nil {
+				continue
+			}
+			c.cancel = cancel
+			c.connect = connect
+			c.recorder = recorder
+			c.done = done
+			return nil
+		}
+	}
+}
+
+// Stop disconnects the peer and waits until the client is stopped or the context is done
+func (c *Client) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel == nil {
+		return ErrClientNotStarted
+	}
+	c.cancel()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-c.done:
+		c.cancel = nil
+		c.connect = nil
+		c.recorder = nil
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("stop client: %w", err)
+		}
+		return nil
+	}
+}
+
+// Address returns the address of the peer in the network
+func (c *Client) Address() (netip.Addr, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.recorder == nil {
+		return netip.Addr{}, ErrClientNotStarted
+	}
+	prefix, err := netip.ParsePrefix(c.recorder.GetFullStatus().LocalPeerState.IP)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("parse peer address: %w", err)
+	}
+	return prefix.Addr(), nil
+}
+
+// Dial connects to the address through the network, only TCP and UDP networks are supported. Host names are
+// resolved by the DNS server of the peer like on the other peers, so the names of the peers resolve. The names it
+// doesn't resolve are resolved by the resolver of the host
+func (c *Client) Dial(ctx context.Context, network, address string) (net.Conn, error) {
+	n, err := c.getNet()
+	if err != nil {
+		return nil, err
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := netip.ParseAddr(host); err == nil || host == "" {
+		return n.DialContext(ctx, network, address)
+	}
+
+	addrs, err := c.lookupHost(ctx, n, lookupNetwork(network), host)
+	if err != nil {
+		return nil, err
+	}
+	var dialErr error
+	for _, addr := range addrs {
+		conn, err := n.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		dialErr = err
+	}
+	return nil, dialErr
+}
+
+// lookupHost resolves the host name with the DNS server of the peer through the network, and with the resolver of
+// the host if the server doesn't resolve it, e.g. without nameservers configured for the peer
+func (c *Client) lookupHost(ctx context.Context, n *netstack.Net, network, host string) ([]netip.Addr, error) {
+	if server := c.dnsServerAddress(); server != "" {
+		resolver := &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return n.DialContext(ctx, network, server)
+			},
+		}
+		addrs, err := resolver.LookupNetIP(ctx, network, host)
+		if err == nil {
+			return addrs, nil
+		}
+		log.Debugf("resolve %s with the DNS server of the peer: %v", host, err)
+	}
+	return net.DefaultResolver.LookupNetIP(ctx, network, host)
+}
+
+func (c *Client) dnsServerAddress() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.connect == nil {
+		return ""
+	}
+	engine := c.connect.Engine()
+	if engine == nil {
+		return ""
+	}
+	return engine.GetDNSServerAddress()
+}
+
+// lookupNetwork returns the network of the addresses to look up for a dial network, e.g. ip4 for tcp4
+func lookupNetwork(network string) string {
+	switch {
+	case strings.HasSuffix(network, "4"):
+		return "ip4"
+	case strings.HasSuffix(network, "6"):
+		return "ip6"
+	default:
+		return "ip"
+	}
+}
+
+// Listen accepts the TCP connections of the other peers on the address, e.g. ":8080". An empty or unspecified host
+// is the address of the peer. The listener is closed when the peer reconnects and its interface is recreated
+func (c *Client) Listen(network, address string) (net.Listener, error) {
+	switch network {
+	case "tcp", "tcp4":
+	default:
+		return nil, &net.OpError{Op: "listen", Net: network, Err: net.UnknownNetworkError(network)}
+	}
+
+	n, addrPort, err := c.listenAddr(address)
+	if err != nil {
+		return nil, err
+	}
+	return n.ListenTCP(addrPort)
+}
+
+// ListenUDP receives the UDP datagrams of the other peers on the address, e.g. ":5353". An empty or unspecified
+// host is the address of the peer. The connection is closed when the peer reconnects
+func (c *Client) ListenUDP(address string) (net.PacketConn, error) {
+	n, addrPort, err := c.listenAddr(address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := n.ListenUDP(addrPort)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (c *Client) listenAddr(address string) (*netstack.Net, netip.AddrPort, error) {
+	n, err := c.getNet()
+	if err != nil {
+		return nil, netip.AddrPort{}, err
+	}
+
+	host, sPort, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, netip.AddrPort{}, err
+	}
+	port, err := strconv.ParseUint(sPort, 10, 16)
+	if err != nil {
+		return nil, netip.AddrPort{}, fmt.Errorf("invalid port %s", sPort)
+	}
+
+	var addr netip.Addr
+	if host != "" {
+		if addr, err = netip.ParseAddr(host); err != nil {
+			return nil, netip.AddrPort{}, fmt.Errorf("invalid listen address %s: %w", host, err)
+		}
+	}
+	if !addr.IsValid() || addr.IsUnspecified() {
+		if addr, err = c.Address(); err != nil {
+			return nil, netip.AddrPort{}, err
+		}
+	}
+	return n, netip.AddrPortFrom(addr, uint16(port)), nil
+}
+
+func (c *Client) getNet() (*netstack.Net, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.connect == nil {
+		return nil, ErrClientNotStarted
+	}
+	engine := c.connect.Engine()
+	if engine == nil {
+		return nil, errors.New("client is reconnecting")
+	}
+	n := engine.GetNet()
+	if n == nil {
+		return nil, errors.New("network not ready")
+	}
+	return n, nil
+}
@@ -0,0 +1,63 @@
+package embed
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientNotStarted(t *testing.T) {
+	client, err := New(Options{ManagementURL: "https://localhost:33073"})
+	require.NoError(t, err)
+
+	_, err = client.Dial(context.Background(), "tcp", "100.64.0.1:80")
+	assert.ErrorIs(t, err, ErrClientNotStarted)
+
+	_, err = client.Listen("tcp", ":80")
+	assert.ErrorIs(t, err, ErrClientNotStarted)
+
+	_, err = client.ListenUDP(":53")
+	assert.ErrorIs(t, err, ErrClientNotStarted)
+
+	_, err = client.Address()
+	assert.ErrorIs(t, err, ErrClientNotStarted)
+
+	assert.ErrorIs(t, client.Stop(context.Background()), ErrClientNotStarted)
+}
+
+func TestClientListenUnsupportedNetwork(t *testing.T) {
+	client, err := New(Options{ManagementURL: "https://localhost:33073"})
+	require.NoError(t, err)
+
+	_, err = client.Listen("unix", "/tmp/netbird.sock")
+	assert.Error(t, err)
+}
+
+func TestNewWithConfigPath(t *testing.T) {
+	opts := Options{
+		ManagementURL: "https://localhost:33073",
+		ConfigPath:    t.TempDir() + "/config.json",
+	}
+
+	first, err := New(opts)
+	require.NoError(t, err)
+	second, err := New(opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.config.PrivateKey, second.config.PrivateKey, "the identity of the peer should be kept")
+}
+
+func TestLookupNetwork(t *testing.T) {
+	for network, expected := range map[string]string{
+		"tcp":  "ip",
+		"tcp4": "ip4",
+		"tcp6": "ip6",
+		"udp":  "ip",
+		"udp4": "ip4",
+		"udp6": "ip6",
+	} {
+		assert.Equal(t, expected, lookupNetwork(network), "network %s", network)
+	}
+}
@@ -15,6 +15,7 @@ import (
 	firewall "github.com/netbirdio/netbird/client/firewall/manager"
 	nbnftables "github.com/netbirdio/netbird/client/firewall/nftables"
 	"github.com/netbirdio/netbird/client/firewall/uspfilter"
+	"github.com/netbirdio/netbird/iface/netns"
 )
 
 const (
@@ -32,6 +33,11 @@ const SKIP_NFTABLES_ENV = "NB_SKIP_NFTABLES_CHECK"
 // FWType is the type for the firewall type
 type FWType int
 
+// netNSIFace is implemented by the interfaces which may be placed in a network namespace
+type netNSIFace interface {
+	NetNS() *netns.NetNS
+}
+
 func NewFirewall(context context.Context, iface IFaceMapper) (firewall.Manager, error) {
 	// on the linux system we try to user nftables or iptables
 	// in any case, because we need to allow netbird interface traffic
@@ -40,22 +46,10 @@ func NewFirewall(context context.Context, iface IFaceMapper) (firewall.Manager,
 	var fm firewall.Manager
 	var errFw error
 
-	switch check() {
-	case IPTABLES:
-		log.Debug("creating an iptables firewall manager")
-		fm, errFw = nbiptables.Create(context, iface)
-		if errFw != nil {
-			log.Errorf("failed to create iptables manager: %s", errFw)
-		}
-	case NFTABLES:
-		log.Debug("creating an nftables firewall manager")
-		fm, errFw = nbnftables.Create(context, iface)
-		if errFw != nil {
-			log.Errorf("failed to create nftables manager: %s", errFw)
-		}
-	default:
-		errFw = fmt.Errorf("no firewall manager found")
-		log.Debug("no firewall manager found, try to use userspace packet filtering firewall")
+	if nsIface, ok := iface.(netNSIFace); ok && nsIface.NetNS() != nil {
+		fm, errFw = newNativeFirewallInNetNS(context, iface, nsIface.NetNS())
+	} else {
+		fm, errFw = newNativeFirewall(context, iface)
 	}
 
 	if iface.IsUserspaceBind() {
@@ -83,6 +77,45 @@ func NewFirewall(context context.Context, iface IFaceMapper) (firewall.Manager,
 	return fm, nil
 }
 
+// newNativeFirewall creates the iptables or nftables manager of the host
+func newNativeFirewall(context context.Context, iface IFaceMapper) (firewall.Manager, error) {
+	var fm firewall.Manager
+	var errFw error
+
+	switch check() {
+	case IPTABLES:
+		log.Debug("creating an iptables firewall manager")
+		fm, errFw = nbiptables.Create(context, iface)
+		if errFw != nil {
+			log.Errorf("failed to create iptables manager: %s", errFw)
+		}
+	case NFTABLES:
+		log.Debug("creating an nftables firewall manager")
+		fm, errFw = nbnftables.Create(context, iface)
+		if errFw != nil {
+			log.Errorf("failed to create nftables manager: %s", errFw)
+		}
+	default:
+		errFw = fmt.Errorf("no firewall manager found")
+		log.Debug("no firewall manager found, try to use userspace packet filtering firewall")
+	}
+	return fm, errFw
+}
+
+// newNativeFirewallInNetNS creates the iptables or nftables manager of the namespace of the interface
+func newNativeFirewallInNetNS(context context.Context, iface IFaceMapper, ns *netns.NetNS) (firewall.Manager, error) {
+	var fm firewall.Manager
+	err := ns.Do(func() error {
+		var err error
+		fm, err = newNativeFirewall(context, iface)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &netNSManager{manager: fm, netNS: ns}, nil
+}
+
 // check returns the firewall type based on common lib checks. It returns UNKNOWN if no firewall is found.
 func check() FWType {
 	nf := nftables.Conn{}
@@ -21,6 +21,9 @@ const (
 	chainNameOutputRules = "NETBIRD-ACL-OUTPUT"
 
 	postRoutingMark = "0x000007e4"
+
+	dropInLogPrefix  = "netbird-drop-in: "
+	dropOutLogPrefix = "netbird-drop-out: "
 )
 
 type aclManager struct {
@@ -30,6 +33,14 @@ type aclManager struct {
 
 	entries    map[string][][]string
 	ipsetStore *ipsetStore
+	logDropped bool
+
+	// pendingIpsetAdds, pendingChanges and pendingIpsetOps are applied in this order on the next flush
+	pendingIpsetAdds []ipsetAdd
+	pendingChanges   []ruleChange
+	pendingIpsetOps  []func()
+	// appliedIpsets are the ipsets of the last successful flush, restored when a flush fails
+	appliedIpsets *ipsetStore
 }
 
 func newAclManager(iptablesClient *iptables.IPTables, wgIface iFaceMapper, routeingFwChainName string) (*aclManager, error) {
@@ -40,6 +51,7 @@ func newAclManager(iptablesClient *iptables.IPTables, wgIface iFaceMapper, route
 
 		entries:    make(map[string][][]string),
 		ipsetStore: newIpsetStore(),
+		logDropped: firewall.LogDroppedEnabled(),
 	}
 
 	err := ipset.Init()
@@ -72,11 +84,10 @@ func (m *aclManager) AddFiltering(
 ) ([]firewall.Rule, error) {
 	var dPortVal, sPortVal string
 	if dPort != nil && dPort.Values != nil {
-		// TODO: we support only one port per rule in current implementation of ACLs
-		dPortVal = strconv.Itoa(dPort.Values[0])
+		dPortVal = dPort.String()
 	}
 	if sPort != nil && sPort.Values != nil {
-		sPortVal = strconv.Itoa(sPort.Values[0])
+		sPortVal = sPort.String()
 	}
 
 	var chain string
@@ -87,17 +98,20 @@ func (m *aclManager) AddFiltering(
 	}
 
 	ipsetName = transformIPsetName(ipsetName, sPortVal, dPortVal)
-	specs := filterRuleSpecs(ip, string(protocol), sPortVal, dPortVal, direction, action, ipsetName)
+	specs := filterRuleSpecs(ip, string(protocol), sPort, dPort, direction, action, ipsetName)
+	ruleID := filterRuleID(chain, specs)
+	specs = withComment(specs, ruleID)
 	if ipsetName != "" {
+		m.saveAppliedState()
 		if ipList, ipsetExists := m.ipsetStore.ipset(ipsetName); ipsetExists {
-			if err := ipset.Add(ipsetName, ip.String()); err != nil {
-				return nil, fmt.Errorf("failed to add IP to ipset: %w", err)
+			if _, ok := ipList.ips[ip.String()]; !ok {
+				m.pendingIpsetAdds = append(m.pendingIpsetAdds, ipsetAdd{name: ipsetName, ip: ip.String()})
 			}
 			// if ruleset already exists it means we already have the firewall rule
 			// so we need to update IPs in the ruleset and return new fw.Rule object for ACL manager.
 			ipList.addIP(ip.String())
 			return []firewall.Rule{&Rule{
-				ruleID:    uuid.New().String(),
+				ruleID:    ruleID,
 				ipsetName: ipsetName,
 				ip:        ip.String(),
 				chain:     chain,
@@ -105,16 +119,7 @@ func (m *aclManager) AddFiltering(
 			}}, nil
 		}
 
-		if err := ipset.Flush(ipsetName); err != nil {
-			log.Errorf("flush ipset %s before use it: %s", ipsetName, err)
-		}
-		if err := ipset.Create(ipsetName); err != nil {
-			return nil, fmt.Errorf("failed to create ipset: %w", err)
-		}
-		if err := ipset.Add(ipsetName, ip.String()); err != nil {
-			return nil, fmt.Errorf("failed to add IP to ipset: %w", err)
-		}
-
+		m.pendingIpsetAdds = append(m.pendingIpsetAdds, ipsetAdd{name: ipsetName, ip: ip.String(), create: true})
 		ipList := newIpList(ip.String())
 		m.ipsetStore.addIpList(ipsetName, ipList)
 	}
@@ -127,12 +132,10 @@ func (m *aclManager) AddFiltering(
 		return nil, fmt.Errorf("rule already exists")
 	}
 
-	if err := m.iptablesClient.Insert("filter", chain, 1, specs...); err != nil {
-		return nil, err
-	}
+	m.queueInsert(tableName, chain, specs)
 
 	rule := &Rule{
-		ruleID:    uuid.New().String(),
+		ruleID:    ruleID,
 		specs:     specs,
 		ipsetName: ipsetName,
 		ip:        ip.String(),
@@ -143,14 +146,14 @@ func (m *aclManager) AddFiltering(
 		return []firewall.Rule{rule}, nil
 	}
 
-	rulePrerouting, err := m.addPreroutingFilter(ipsetName, string(protocol), dPortVal, ip)
+	rulePrerouting, err := m.addPreroutingFilter(ipsetName, string(protocol), dPort, ip)
 	if err != nil {
 		return []firewall.Rule{rule}, err
 	}
 	return []firewall.Rule{rule, rulePrerouting}, nil
 }
 
-// DeleteRule from the firewall by rule definition
+// DeleteRule from the firewall by rule definition. The rule is removed on the next flush
 func (m *aclManager) DeleteRule(rule firewall.Rule) error {
 	r, ok := rule.(*Rule)
 	if !ok {
@@ -158,15 +161,20 @@ func (m *aclManager) DeleteRule(rule firewall.Rule) error {
 	}
 
 	if r.chain == "PREROUTING" {
-		goto DELETERULE
+		m.queueDelete("mangle", r.chain, r.specs)
+		return nil
 	}
 
+	m.saveAppliedState()
 	if ipsetList, ok := m.ipsetStore.ipset(r.ipsetName); ok {
 		// delete IP from ruleset IPs list and ipset
 		if _, ok := ipsetList.ips[r.ip]; ok {
-			if err := ipset.Del(r.ipsetName, r.ip); err != nil {
-				return fmt.Errorf("failed to delete ip from ipset: %w", err)
-			}
+			ipsetName, ip := r.ipsetName, r.ip
+			m.pendingIpsetOps = append(m.pendingIpsetOps, func() {
+				if err := ipset.Del(ipsetName, ip); err != nil {
+					log.Errorf("failed to delete ip from ipset: %v", err)
+				}
+			})
 			delete(ipsetList.ips, r.ip)
 		}
 
@@ -180,44 +188,33 @@ func (m *aclManager) DeleteRule(rule firewall.Rule) error {
 		// set itself and associated firewall rule too
 		m.ipsetStore.deleteIpset(r.ipsetName)
 
-		if err := ipset.Destroy(r.ipsetName); err != nil {
-			log.Errorf("delete empty ipset: %v", err)
-		}
+		ipsetName := r.ipsetName
+		m.pendingIpsetOps = append(m.pendingIpsetOps, func() {
+			if err := ipset.Destroy(ipsetName); err != nil {
+				log.Errorf("delete empty ipset: %v", err)
+			}
+		})
 	}
 
-DELETERULE:
-	var table string
-	if r.chain == "PREROUTING" {
-		table = "mangle"
-	} else {
-		table = "filter"
-	}
-	err := m.iptablesClient.Delete(table, r.chain, r.specs...)
-	if err != nil {
-		log.Debugf("failed to delete rule, %s, %v: %s", r.chain, r.specs, err)
-	}
-	return err
+	m.queueDelete(tableName, r.chain, r.specs)
+	return nil
 }
 
 func (m *aclManager) Reset() error {
+	m.pendingIpsetAdds, m.pendingChanges, m.pendingIpsetOps, m.appliedIpsets = nil, nil, nil, nil
 	return m.cleanChains()
 }
 
-func (m *aclManager) addPreroutingFilter(ipsetName string, protocol string, port string, ip net.IP) (*Rule, error) {
-	var src []string
+func (m *aclManager) addPreroutingFilter(ipsetName string, protocol string, port *firewall.Port, ip net.IP) (*Rule, error) {
+	var specs []string
 	if ipsetName != "" {
-		src = []string{"-m", "set", "--set", ipsetName, "src"}
+		specs = []string{"-m", "set", "--set", ipsetName, "src"}
 	} else {
-		src = []string{"-s", ip.String()}
+		specs = []string{"-s", ip.String()}
 	}
-	specs := []string{
-		"-d", m.wgIface.Address().IP.String(),
-		"-p", protocol,
-		"--dport", port,
-		"-j", "MARK", "--set-mark", postRoutingMark,
-	}
-
-	specs = append(src, specs...)
+	specs = append(specs, "-d", m.wgIface.Address().IP.String(), "-p", protocol)
+	specs = append(specs, portSpecs("--dport", port)...)
+	specs = append(specs, "-j", "MARK", "--set-mark", postRoutingMark)
 
 	ok, err := m.iptablesClient.Exists("mangle", "PREROUTING", specs...)
 	if err != nil {
@@ -227,9 +224,7 @@ func (m *aclManager) addPreroutingFilter(ipsetName string, protocol string, port
 		return nil, fmt.Errorf("rule already exists")
 	}
 
-	if err := m.iptablesClient.Insert("mangle", "PREROUTING", 1, specs...); err != nil {
-		return nil, err
-	}
+	m.queueInsert("mangle", "PREROUTING", specs)
 
 	rule := &Rule{
 		ruleID:    uuid.New().String(),
@@ -366,6 +361,9 @@ func (m *aclManager) seedInitialEntries() {
 	m.appendToEntries("INPUT",
 		[]string{"-i", m.wgIface.Name(), "-s", m.wgIface.Address().String(), "-d", m.wgIface.Address().String(), "-j", chainNameInputRules})
 
+	if m.logDropped {
+		m.appendToEntries("INPUT", m.dropLogSpecs("-i", dropInLogPrefix))
+	}
 	m.appendToEntries("INPUT", []string{"-i", m.wgIface.Name(), "-j", "DROP"})
 
 	m.appendToEntries("OUTPUT",
@@ -377,9 +375,16 @@ func (m *aclManager) seedInitialEntries() {
 	m.appendToEntries("OUTPUT",
 		[]string{"-o", m.wgIface.Name(), "-s", m.wgIface.Address().String(), "-d", m.wgIface.Address().String(), "-j", chainNameOutputRules})
 
+	if m.logDropped {
+		m.appendToEntries("OUTPUT", m.dropLogSpecs("-o", dropOutLogPrefix))
+	}
 	m.appendToEntries("OUTPUT", []string{"-o", m.wgIface.Name(), "-j", "DROP"})
 
 	m.appendToEntries("FORWARD", []string{"-i", m.wgIface.Name(), "-j", "DROP"})
+	if m.logDropped {
+		// the FORWARD entries are inserted in reverse order, the log rule ends up before the drop rule
+		m.appendToEntries("FORWARD", m.dropLogSpecs("-i", dropInLogPrefix))
+	}
 	m.appendToEntries("FORWARD", []string{"-i", m.wgIface.Name(), "-j", chainNameInputRules})
 	m.appendToEntries("FORWARD",
 		[]string{"-o", m.wgIface.Name(), "-m", "mark", "--mark", postRoutingMark, "-j", "ACCEPT"})
@@ -396,9 +401,20 @@ func (m *aclManager) appendToEntries(chainName string, spec []string) {
 	m.entries[chainName] = append(m.entries[chainName], spec)
 }
 
+// dropLogSpecs returns the specs of the rate-limited rule logging the packets of the interface before they are dropped
+func (m *aclManager) dropLogSpecs(ifaceFlag, prefix string) []string {
+	rate := strconv.Itoa(firewall.DropLogRate)
+	return []string{
+		ifaceFlag, m.wgIface.Name(),
+		"-m", "limit", "--limit", rate + "/sec", "--limit-burst", rate,
+		"-j", "LOG", "--log-prefix", prefix,
+	}
+}
+
 // filterRuleSpecs returns the specs of a filtering rule
 func filterRuleSpecs(
-	ip net.IP, protocol string, sPort, dPort string, direction firewall.RuleDirection, action firewall.Action, ipsetName string,
+	ip net.IP, protocol string, sPort, dPort *firewall.Port, direction firewall.RuleDirection, action firewall.Action,
+	ipsetName string,
 ) (specs []string) {
 	matchByIP := true
 	// don't use IP matching if IP is ip 0.0.0.0
@@ -426,15 +442,26 @@ func filterRuleSpecs(
 	if protocol != "all" {
 		specs = append(specs, "-p", protocol)
 	}
-	if sPort != "" {
-		specs = append(specs, "--sport", sPort)
-	}
-	if dPort != "" {
-		specs = append(specs, "--dport", dPort)
-	}
+	specs = append(specs, portSpecs("--sport", sPort)...)
+	specs = append(specs, portSpecs("--dport", dPort)...)
 	return append(specs, "-j", actionToStr(action))
 }
 
+// portSpecs returns the match of the port flag, --sport or --dport, for a port, a range or a list of ports.
+// The lists are matched with the multiport module
+func portSpecs(flag string, port *firewall.Port) []string {
+	switch {
+	case port == nil || len(port.Values) == 0:
+		return nil
+	case port.IsRange && len(port.Values) == 2:
+		return []string{flag, fmt.Sprintf("%d:%d", port.Values[0], port.Values[1])}
+	case len(port.Values) == 1:
+		return []string{flag, strconv.Itoa(port.Values[0])}
+	default:
+		return []string{"-m", "multiport", flag + "s", port.String()}
+	}
+}
+
 func actionToStr(action firewall.Action) string {
 	if action == firewall.ActionAccept {
 		return "ACCEPT"
@@ -0,0 +1,28 @@
+package iptables
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	fw "github.com/netbirdio/netbird/client/firewall/manager"
+	"github.com/netbirdio/netbird/client/firewall/test"
+)
+
+func TestIptablesConformance(t *testing.T) {
+	test.RunConformance(t, test.Backend{
+		Setup: func(t *testing.T) (fw.Manager, test.Network) {
+			network := test.NewNetnsNetwork(t)
+
+			manager, err := Create(context.Background(), network)
+			if err != nil {
+				t.Skipf("iptables is not available: %v", err)
+			}
+			t.Cleanup(func() {
+				require.NoError(t, manager.Reset(), "reset manager")
+			})
+			return manager, network
+		},
+	})
+}
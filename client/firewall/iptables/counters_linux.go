@@ -0,0 +1,116 @@
+package iptables
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	firewall "github.com/netbirdio/netbird/client/firewall/manager"
+)
+
+// GetRuleCounters returns the counters of the filtering rules and of the default drop rules
+func (m *aclManager) GetRuleCounters() (*firewall.RuleCounters, error) {
+	counters := &firewall.RuleCounters{
+		Rules: make(map[string]firewall.Counters),
+	}
+
+	for _, chain := range []string{chainNameInputRules, chainNameOutputRules} {
+		lines, err := m.iptablesClient.ListWithCounters(tableName, chain)
+		if err != nil {
+			return nil, fmt.Errorf("list rules of chain %s: %w", chain, err)
+		}
+		for _, line := range lines {
+			lineChain, specs, ruleCounters, ok := parseCounterLine(line)
+			if !ok || lineChain != chain {
+				continue
+			}
+			ruleID, ok := ruleComment(specs)
+			if !ok {
+				continue
+			}
+			c := counters.Rules[ruleID]
+			c.Add(ruleCounters)
+			counters.Rules[ruleID] = c
+		}
+	}
+
+	dropIn := strings.Join([]string{"-i", m.wgIface.Name(), "-j", "DROP"}, " ")
+	dropOut := strings.Join([]string{"-o", m.wgIface.Name(), "-j", "DROP"}, " ")
+	for _, chain := range []string{"INPUT", "OUTPUT", "FORWARD"} {
+		lines, err := m.iptablesClient.ListWithCounters(tableName, chain)
+		if err != nil {
+			return nil, fmt.Errorf("list rules of chain %s: %w", chain, err)
+		}
+		for _, line := range lines {
+			_, specs, ruleCounters, ok := parseCounterLine(line)
+			if !ok {
+				continue
+			}
+			switch specs {
+			case dropIn:
+				counters.DroppedIn.Add(ruleCounters)
+			case dropOut:
+				counters.DroppedOut.Add(ruleCounters)
+			}
+		}
+	}
+
+	return counters, nil
+}
+
+// parseCounterLine parses a rule listed by iptables -v -S, e.g. "-A INPUT -i wt0 -c 10 840 -j DROP", and returns
+// its chain, its specs without the counters and its counters
+func parseCounterLine(line string) (string, string, firewall.Counters, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "-A" {
+		return "", "", firewall.Counters{}, false
+	}
+
+	var (
+		counters firewall.Counters
+		found    bool
+		specs    = make([]string, 0, len(fields))
+	)
+	for i := 2; i < len(fields); i++ {
+		if fields[i] != "-c" || i+2 >= len(fields) {
+			specs = append(specs, fields[i])
+			continue
+		}
+
+		packets, errPackets := strconv.ParseUint(fields[i+1], 10, 64)
+		bytes, errBytes := strconv.ParseUint(fields[i+2], 10, 64)
+		if errPackets != nil || errBytes != nil {
+			return "", "", firewall.Counters{}, false
+		}
+		counters = firewall.Counters{Packets: packets, Bytes: bytes}
+		found = true
+		i += 2
+	}
+
+	return fields[1], strings.Join(specs, " "), counters, found
+}
+
+// filterRuleID returns the ID of a filtering rule, derived from its chain and its specs. The ID is the comment of the
+// rule, the counters of the rule are found by it. The rules sharing an ipset share the same ID
+func filterRuleID(chain string, specs []string) string {
+	hash := sha256.Sum256([]byte(chain + " " + strings.Join(specs, " ")))
+	return "nb-" + hex.EncodeToString(hash[:8])
+}
+
+// withComment returns the specs of a rule tagged with a comment, in the format of genRuleSpec
+func withComment(specs []string, comment string) []string {
+	return append(specs, "-m", "comment", "--comment", comment)
+}
+
+// ruleComment returns the comment of a rule from its specs listed by iptables -S
+func ruleComment(specs string) (string, bool) {
+	fields := strings.Fields(specs)
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "--comment" {
+			return strings.Trim(fields[i+1], `"`), true
+		}
+	}
+	return "", false
+}
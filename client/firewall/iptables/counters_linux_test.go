@@ -0,0 +1,67 @@
+package iptables
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	fw "github.com/netbirdio/netbird/client/firewall/manager"
+)
+
+func TestFilterRuleID(t *testing.T) {
+	sshSpecs := filterRuleSpecs(net.ParseIP("100.64.0.1"), "tcp", nil, &fw.Port{Values: []int{22}},
+		fw.RuleDirectionIN, fw.ActionAccept, "")
+	ruleID := filterRuleID(chainNameInputRules, sshSpecs)
+
+	require.Regexp(t, `^nb-[0-9a-f]{16}$`, ruleID, "ID should be a valid comment without quoting")
+	require.Equal(t, ruleID, filterRuleID(chainNameInputRules, sshSpecs), "ID should be stable")
+	require.NotEqual(t, ruleID, filterRuleID(chainNameOutputRules, sshSpecs), "chain should be part of the ID")
+
+	httpSpecs := filterRuleSpecs(net.ParseIP("100.64.0.1"), "tcp", nil, &fw.Port{Values: []int{80}},
+		fw.RuleDirectionIN, fw.ActionAccept, "")
+	require.NotEqual(t, ruleID, filterRuleID(chainNameInputRules, httpSpecs), "specs should be part of the ID")
+
+	first := filterRuleSpecs(net.ParseIP("100.64.0.1"), "udp", nil, &fw.Port{Values: []int{53}},
+		fw.RuleDirectionIN, fw.ActionAccept, "nb0000001-dport")
+	second := filterRuleSpecs(net.ParseIP("100.64.0.2"), "udp", nil, &fw.Port{Values: []int{53}},
+		fw.RuleDirectionIN, fw.ActionAccept, "nb0000001-dport")
+	require.Equal(t, filterRuleID(chainNameInputRules, first), filterRuleID(chainNameInputRules, second),
+		"rules sharing an ipset should share the ID")
+}
+
+func TestRuleComment(t *testing.T) {
+	specs := withComment([]string{"-s", "100.64.0.1", "-j", "ACCEPT"}, "nb-0123456789abcdef")
+	require.Equal(t, []string{"-s", "100.64.0.1", "-j", "ACCEPT", "-m", "comment", "--comment", "nb-0123456789abcdef"}, specs)
+
+	// iptables lists the matches in its own order, the comment is found wherever it is
+	comment, ok := ruleComment("-s 100.64.0.1/32 -p tcp -m tcp --dport 22 -m comment --comment nb-0123456789abcdef -j ACCEPT")
+	require.True(t, ok)
+	require.Equal(t, "nb-0123456789abcdef", comment)
+
+	comment, ok = ruleComment(`-m comment --comment "nb-0123456789abcdef" -j ACCEPT`)
+	require.True(t, ok)
+	require.Equal(t, "nb-0123456789abcdef", comment)
+
+	_, ok = ruleComment("-i wt0 -j DROP")
+	require.False(t, ok, "rules without comment have no ID")
+}
+
+func TestParseCounterLine(t *testing.T) {
+	chain, specs, counters, ok := parseCounterLine("-A NETBIRD-ACL-INPUT -s 100.64.0.1/32 -p tcp -m tcp --dport 22 -c 12 720 -j ACCEPT")
+	require.True(t, ok)
+	require.Equal(t, chainNameInputRules, chain)
+	require.Equal(t, "-s 100.64.0.1/32 -p tcp -m tcp --dport 22 -j ACCEPT", specs)
+	require.Equal(t, fw.Counters{Packets: 12, Bytes: 720}, counters)
+
+	_, specs, counters, ok = parseCounterLine("-A INPUT -i wt0 -c 3 252 -j DROP")
+	require.True(t, ok)
+	require.Equal(t, "-i wt0 -j DROP", specs)
+	require.Equal(t, fw.Counters{Packets: 3, Bytes: 252}, counters)
+
+	_, _, _, ok = parseCounterLine("-P INPUT ACCEPT -c 100 2000")
+	require.False(t, ok, "policies aren't rules")
+
+	_, _, _, ok = parseCounterLine("-N NETBIRD-ACL-INPUT")
+	require.False(t, ok, "chains aren't rules")
+}
@@ -88,6 +88,11 @@ func (m *Manager) IsServerRouteSupported() bool {
 	return true
 }
 
+// IsStateful returns false, the replies are accepted by the inverted rules
+func (m *Manager) IsStateful() bool {
+	return false
+}
+
 func (m *Manager) InsertRoutingRules(pair firewall.RouterPair) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -102,6 +107,14 @@ func (m *Manager) RemoveRoutingRules(pair firewall.RouterPair) error {
 	return m.router.RemoveRoutingRules(pair)
 }
 
+// GetRuleCounters returns the traffic matched by the filtering rules and dropped by the default policy
+func (m *Manager) GetRuleCounters() (*firewall.RuleCounters, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.aclMgr.GetRuleCounters()
+}
+
 // Reset firewall to the default state
 func (m *Manager) Reset() error {
 	m.mutex.Lock()
@@ -148,8 +161,16 @@ func (m *Manager) AllowNetbird() error {
 		"",
 		"",
 	)
-	return err
+	if err != nil {
+		return fmt.Errorf("failed to allow netbird interface traffic: %w", err)
+	}
+	return m.Flush()
 }
 
-// Flush doesn't need to be implemented for this manager
-func (m *Manager) Flush() error { return nil }
+// Flush applies the rules added and deleted since the last flush
+func (m *Manager) Flush() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.aclMgr.Flush()
+}
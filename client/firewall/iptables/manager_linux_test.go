@@ -74,6 +74,7 @@ func TestIptablesManager(t *testing.T) {
 		port := &fw.Port{Values: []int{8080}}
 		rule1, err = manager.AddFiltering(ip, "tcp", nil, port, fw.RuleDirectionOUT, fw.ActionAccept, "", "accept HTTP traffic")
 		require.NoError(t, err, "failed to add rule")
+		require.NoError(t, manager.Flush(), "failed to flush")
 
 		for _, r := range rule1 {
 			checkRuleSpecs(t, ipv4Client, chainNameOutputRules, true, r.(*Rule).specs...)
@@ -90,6 +91,7 @@ func TestIptablesManager(t *testing.T) {
 		rule2, err = manager.AddFiltering(
 			ip, "tcp", port, nil, fw.RuleDirectionIN, fw.ActionAccept, "", "accept HTTPS traffic from ports range")
 		require.NoError(t, err, "failed to add rule")
+		require.NoError(t, manager.Flush(), "failed to flush")
 
 		for _, r := range rule2 {
 			rr := r.(*Rule)
@@ -101,6 +103,7 @@ func TestIptablesManager(t *testing.T) {
 		for _, r := range rule1 {
 			err := manager.DeleteRule(r)
 			require.NoError(t, err, "failed to delete rule")
+			require.NoError(t, manager.Flush(), "failed to flush")
 
 			checkRuleSpecs(t, ipv4Client, chainNameOutputRules, false, r.(*Rule).specs...)
 		}
@@ -276,6 +279,7 @@ func TestIptablesCreatePerformance(t *testing.T) {
 
 				require.NoError(t, err, "failed to add rule")
 			}
+			require.NoError(t, manager.Flush(), "failed to flush")
 			t.Logf("execution avg per rule: %s", time.Since(start)/time.Duration(testMax))
 		})
 	}
@@ -0,0 +1,220 @@
+package iptables
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/nadoo/ipset"
+	log "github.com/sirupsen/logrus"
+)
+
+const restoreBinary = "iptables-restore"
+
+// ruleChange is a rule inserted at the top of its chain or deleted on the next flush
+type ruleChange struct {
+	table  string
+	chain  string
+	specs  []string
+	insert bool
+}
+
+// queueInsert schedules the insertion of a rule at the top of the chain
+func (m *aclManager) queueInsert(table, chain string, specs []string) {
+	m.pendingChanges = append(m.pendingChanges, ruleChange{table: table, chain: chain, specs: specs, insert: true})
+}
+
+// queueDelete schedules the deletion of a rule, a rule not inserted yet is dropped from the pending changes
+func (m *aclManager) queueDelete(table, chain string, specs []string) {
+	for i, change := range m.pendingChanges {
+		if change.insert && change.table == table && change.chain == chain && slices.Equal(change.specs, specs) {
+			m.pendingChanges = append(m.pendingChanges[:i], m.pendingChanges[i+1:]...)
+			return
+		}
+	}
+	m.pendingChanges = append(m.pendingChanges, ruleChange{table: table, chain: chain, specs: specs})
+}
+
+// ipsetAdd is an IP added to an ipset on the next flush, before the rules using it are inserted
+type ipsetAdd struct {
+	name   string
+	ip     string
+	create bool
+}
+
+// saveAppliedState keeps the ipsets of the last flush before their first change, to restore them if the next
+// flush fails
+func (m *aclManager) saveAppliedState() {
+	if m.appliedIpsets == nil {
+		m.appliedIpsets = m.ipsetStore.clone()
+	}
+}
+
+// Flush commits the pending rule changes with iptables-restore, each table is replaced in one transaction.
+// The ipset entries of the new rules are added before and the ones of the deleted rules are removed once the rules
+// don't reference them anymore. When the flush fails nothing is applied, the pending changes are dropped and the
+// manager goes back to the ipsets of the last successful flush
+func (m *aclManager) Flush() error {
+	adds, changes, ipsetOps, applied := m.pendingIpsetAdds, m.pendingChanges, m.pendingIpsetOps, m.appliedIpsets
+	m.pendingIpsetAdds, m.pendingChanges, m.pendingIpsetOps, m.appliedIpsets = nil, nil, nil, nil
+
+	added, err := addIpsetEntries(adds)
+	if err == nil {
+		err = m.restoreTables(changes)
+	}
+	if err != nil {
+		removeIpsetEntries(added)
+		if applied != nil {
+			m.ipsetStore = applied
+		}
+		return err
+	}
+
+	for _, op := range ipsetOps {
+		op()
+	}
+	return nil
+}
+
+// addIpsetEntries creates the ipsets and adds their entries, it returns the entries added until an error occurred
+func addIpsetEntries(adds []ipsetAdd) ([]ipsetAdd, error) {
+	for i, add := range adds {
+		if add.create {
+			if err := ipset.Flush(add.name); err != nil {
+				log.Errorf("flush ipset %s before use it: %s", add.name, err)
+			}
+			if err := ipset.Create(add.name); err != nil {
+				return adds[:i], fmt.Errorf("failed to create ipset: %w", err)
+			}
+		}
+		if err := ipset.Add(add.name, add.ip); err != nil {
+			if add.create {
+				// the ipset was created, it has to be destroyed with the others
+				i++
+			}
+			return adds[:i], fmt.Errorf("failed to add IP to ipset: %w", err)
+		}
+	}
+	return adds, nil
+}
+
+// removeIpsetEntries reverts addIpsetEntries
+func removeIpsetEntries(added []ipsetAdd) {
+	for i := len(added) - 1; i >= 0; i-- {
+		add := added[i]
+		if add.create {
+			if err := ipset.Destroy(add.name); err != nil {
+				log.Errorf("delete ipset %s of failed flush: %v", add.name, err)
+			}
+			continue
+		}
+		if err := ipset.Del(add.name, add.ip); err != nil {
+			log.Errorf("delete ip from ipset %s of failed flush: %v", add.name, err)
+		}
+	}
+}
+
+// restoreTables commits the changes table by table, the filter table last. When a table fails the tables committed
+// before it are reverted, the filter table whose rule order matters is never reverted
+func (m *aclManager) restoreTables(changes []ruleChange) error {
+	var tables []string
+	byTable := make(map[string][]ruleChange)
+	for _, change := range changes {
+		if _, ok := byTable[change.table]; !ok {
+			tables = append(tables, change.table)
+		}
+		byTable[change.table] = append(byTable[change.table], change)
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		return tables[i] != tableName && tables[j] == tableName
+	})
+
+	for i, table := range tables {
+		err := m.restore(restoreInput(byTable[table]))
+		if err == nil {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if revertErr := m.restore(restoreInput(revertChanges(byTable[tables[j]]))); revertErr != nil {
+				log.Errorf("failed to revert the %s table: %v", tables[j], revertErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// revertChanges returns the changes undoing the given ones
+func revertChanges(changes []ruleChange) []ruleChange {
+	reverted := make([]ruleChange, 0, len(changes))
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		change.insert = !change.insert
+		reverted = append(reverted, change)
+	}
+	return reverted
+}
+
+func (m *aclManager) restore(input string) error {
+	path, err := exec.LookPath(restoreBinary)
+	if err != nil {
+		return fmt.Errorf("find %s: %w", restoreBinary, err)
+	}
+
+	args := []string{"--noflush"}
+	// the wait flag is supported since iptables 1.6.2
+	if v1, v2, v3 := m.iptablesClient.GetIptablesVersion(); v1 > 1 || v1 == 1 && (v2 > 6 || v2 == 6 && v3 >= 2) {
+		args = append(args, "--wait")
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = strings.NewReader(input)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		log.Debugf("failed iptables-restore input:\n%s", input)
+		return fmt.Errorf("%s: %w: %s", restoreBinary, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// restoreInput returns the iptables-restore input applying the changes, grouped by table in the order of
+// their first change
+func restoreInput(changes []ruleChange) string {
+	var tables []string
+	lines := make(map[string][]string)
+	for _, change := range changes {
+		if _, ok := lines[change.table]; !ok {
+			tables = append(tables, change.table)
+		}
+
+		var line string
+		if change.insert {
+			line = fmt.Sprintf("-I %s 1 %s", change.chain, quoteSpecs(change.specs))
+		} else {
+			line = fmt.Sprintf("-D %s %s", change.chain, quoteSpecs(change.specs))
+		}
+		lines[change.table] = append(lines[change.table], line)
+	}
+
+	var builder strings.Builder
+	for _, table := range tables {
+		builder.WriteString("*" + table + "\n")
+		for _, line := range lines[table] {
+			builder.WriteString(line + "\n")
+		}
+		builder.WriteString("COMMIT\n")
+	}
+	return builder.String()
+}
+
+func quoteSpecs(specs []string) string {
+	quoted := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		if spec == "" || strings.ContainsAny(spec, " \t\"") {
+			spec = `"` + strings.ReplaceAll(spec, `"`, `\"`) + `"`
+		}
+		quoted = append(quoted, spec)
+	}
+	return strings.Join(quoted, " ")
+}
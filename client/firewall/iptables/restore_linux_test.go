@@ -0,0 +1,39 @@
+package iptables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreInput(t *testing.T) {
+	m := &aclManager{}
+	m.queueInsert(tableName, chainNameInputRules, []string{"-s", "100.64.0.1/32", "-j", "ACCEPT"})
+	m.queueInsert("mangle", "PREROUTING", []string{"-s", "100.64.0.1", "-j", "MARK", "--set-mark", postRoutingMark})
+	m.queueInsert(tableName, chainNameOutputRules, []string{"-d", "100.64.0.2/32", "-j", "ACCEPT"})
+	m.queueDelete(tableName, chainNameOutputRules, []string{"-d", "100.64.0.3/32", "-j", "ACCEPT"})
+	m.queueDelete(tableName, chainNameOutputRules, []string{"-d", "100.64.0.2/32", "-j", "ACCEPT"})
+	m.queueInsert(tableName, chainNameInputRules, []string{"-j", "LOG", "--log-prefix", "netbird: "})
+
+	expected := "*filter\n" +
+		"-I " + chainNameInputRules + " 1 -s 100.64.0.1/32 -j ACCEPT\n" +
+		"-D " + chainNameOutputRules + " -d 100.64.0.3/32 -j ACCEPT\n" +
+		"-I " + chainNameInputRules + " 1 -j LOG --log-prefix \"netbird: \"\n" +
+		"COMMIT\n" +
+		"*mangle\n" +
+		"-I PREROUTING 1 -s 100.64.0.1 -j MARK --set-mark " + postRoutingMark + "\n" +
+		"COMMIT\n"
+	require.Equal(t, expected, restoreInput(m.pendingChanges), "deleting a pending rule should drop its insertion")
+}
+
+func TestRevertChanges(t *testing.T) {
+	m := &aclManager{}
+	m.queueInsert("mangle", "PREROUTING", []string{"-s", "100.64.0.1", "-j", "MARK", "--set-mark", postRoutingMark})
+	m.queueDelete("mangle", "PREROUTING", []string{"-s", "100.64.0.2", "-j", "MARK", "--set-mark", postRoutingMark})
+
+	expected := "*mangle\n" +
+		"-I PREROUTING 1 -s 100.64.0.2 -j MARK --set-mark " + postRoutingMark + "\n" +
+		"-D PREROUTING -s 100.64.0.1 -j MARK --set-mark " + postRoutingMark + "\n" +
+		"COMMIT\n"
+	require.Equal(t, expected, restoreInput(revertChanges(m.pendingChanges)), "the changes should be undone in reverse order")
+}
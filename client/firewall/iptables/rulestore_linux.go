@@ -27,6 +27,19 @@ func newIpsetStore() *ipsetStore {
 	}
 }
 
+// clone returns a copy of the store which isn't changed by the changes of the store
+func (s *ipsetStore) clone() *ipsetStore {
+	c := newIpsetStore()
+	for name, list := range s.ipsets {
+		ips := make(map[string]struct{}, len(list.ips))
+		for ip := range list.ips {
+			ips[ip] = struct{}{}
+		}
+		c.ipsets[name] = ipList{ips: ips}
+	}
+	return c
+}
+
 func (s *ipsetStore) ipset(ipsetName string) (ipList, bool) {
 	r, ok := s.ipsets[ipsetName]
 	return r, ok
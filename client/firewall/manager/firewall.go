@@ -3,8 +3,16 @@ package manager
 import (
 	"fmt"
 	"net"
+	"os"
+	"strconv"
 )
 
+// EnvLogDropped is the environment variable that enables the rate-limited logging of the packets dropped by the firewall
+const EnvLogDropped = "NB_FIREWALL_LOG_DROPPED"
+
+// DropLogRate is the maximum number of dropped packets logged per second
+const DropLogRate = 10
+
 const (
 	NatFormat          = "netbird-nat-%s"
 	ForwardingFormat   = "netbird-fwd-%s"
@@ -52,7 +60,7 @@ type Manager interface {
 	// AddFiltering rule to the firewall
 	//
 	// If comment argument is empty firewall manager should set
-	// rule ID as comment for the rule
+	// rule ID as comment for the rule. The rule is applied on the next Flush
 	AddFiltering(
 		ip net.IP,
 		proto Protocol,
@@ -64,12 +72,16 @@ type Manager interface {
 		comment string,
 	) ([]Rule, error)
 
-	// DeleteRule from the firewall by rule definition
+	// DeleteRule from the firewall by rule definition, the rule is removed on the next Flush
 	DeleteRule(rule Rule) error
 
 	// IsServerRouteSupported returns true if the firewall supports server side routing operations
 	IsServerRouteSupported() bool
 
+	// IsStateful returns true if the firewall accepts the replies of the connections allowed by the rules, the
+	// rules allowing the replies don't have to be added then
+	IsStateful() bool
+
 	// InsertRoutingRules inserts a routing firewall rule
 	InsertRoutingRules(pair RouterPair) error
 
@@ -79,10 +91,43 @@ type Manager interface {
 	// Reset firewall to the default state
 	Reset() error
 
-	// Flush the changes to firewall controller
+	// GetRuleCounters returns the traffic matched by the filtering rules and dropped by the default policy
+	GetRuleCounters() (*RuleCounters, error)
+
+	// Flush applies the pending rule changes to the firewall controller at once. When it fails none of the changes
+	// is applied, they are dropped and the rules of the last successful flush stay in place
 	Flush() error
 }
 
 func GenKey(format string, input string) string {
 	return fmt.Sprintf(format, input)
 }
+
+// Counters contains the number of packets and bytes matched by a rule
+type Counters struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// Add adds the counters of another rule
+func (c *Counters) Add(other Counters) {
+	c.Packets += other.Packets
+	c.Bytes += other.Bytes
+}
+
+// RuleCounters contains the counters of the filtering rules and of the packets dropped by the default policy
+type RuleCounters struct {
+	// Rules contains the counters of the filtering rules indexed by rule ID.
+	// Rules sharing the same firewall entry, e.g. through an ipset, share the same counters
+	Rules map[string]Counters
+	// DroppedIn are the incoming packets that matched no rule
+	DroppedIn Counters
+	// DroppedOut are the outgoing packets that matched no rule
+	DroppedOut Counters
+}
+
+// LogDroppedEnabled returns true if the dropped packets have to be logged
+func LogDroppedEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(EnvLogDropped))
+	return enabled
+}
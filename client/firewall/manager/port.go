@@ -35,6 +35,10 @@ type Port struct {
 
 // String interface implementation
 func (p *Port) String() string {
+	if p.IsRange && len(p.Values) == 2 {
+		return strconv.Itoa(p.Values[0]) + "-" + strconv.Itoa(p.Values[1])
+	}
+
 	var ports string
 	for _, port := range p.Values {
 		if ports != "" {
@@ -44,3 +48,16 @@ func (p *Port) String() string {
 	}
 	return ports
 }
+
+// Match returns true if the port is one of the values or in the range
+func (p *Port) Match(port int) bool {
+	if p.IsRange && len(p.Values) == 2 {
+		return port >= p.Values[0] && port <= p.Values[1]
+	}
+	for _, value := range p.Values {
+		if value == port {
+			return true
+		}
+	}
+	return false
+}
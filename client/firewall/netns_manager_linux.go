@@ -0,0 +1,83 @@
+//go:build !android
+
+package firewall
+
+import (
+	"net"
+
+	firewall "github.com/netbirdio/netbird/client/firewall/manager"
+	"github.com/netbirdio/netbird/iface/netns"
+)
+
+// netNSManager applies the rules of a native firewall manager in the network namespace of the interface
+type netNSManager struct {
+	manager firewall.Manager
+	netNS   *netns.NetNS
+}
+
+func (m *netNSManager) AllowNetbird() error {
+	return m.netNS.Do(m.manager.AllowNetbird)
+}
+
+func (m *netNSManager) AddFiltering(
+	ip net.IP,
+	proto firewall.Protocol,
+	sPort *firewall.Port,
+	dPort *firewall.Port,
+	direction firewall.RuleDirection,
+	action firewall.Action,
+	ipsetName string,
+	comment string,
+) ([]firewall.Rule, error) {
+	var rules []firewall.Rule
+	err := m.netNS.Do(func() error {
+		var err error
+		rules, err = m.manager.AddFiltering(ip, proto, sPort, dPort, direction, action, ipsetName, comment)
+		return err
+	})
+	return rules, err
+}
+
+func (m *netNSManager) DeleteRule(rule firewall.Rule) error {
+	return m.netNS.Do(func() error {
+		return m.manager.DeleteRule(rule)
+	})
+}
+
+func (m *netNSManager) IsServerRouteSupported() bool {
+	return m.manager.IsServerRouteSupported()
+}
+
+func (m *netNSManager) IsStateful() bool {
+	return m.manager.IsStateful()
+}
+
+func (m *netNSManager) InsertRoutingRules(pair firewall.RouterPair) error {
+	return m.netNS.Do(func() error {
+		return m.manager.InsertRoutingRules(pair)
+	})
+}
+
+func (m *netNSManager) RemoveRoutingRules(pair firewall.RouterPair) error {
+	return m.netNS.Do(func() error {
+		return m.manager.RemoveRoutingRules(pair)
+	})
+}
+
+func (m *netNSManager) Reset() error {
+	return m.netNS.Do(m.manager.Reset)
+}
+
+func (m *netNSManager) GetRuleCounters() (*firewall.RuleCounters, error) {
+	var counters *firewall.RuleCounters
+	err := m.netNS.Do(func() error {
+		var err error
+		counters, err = m.manager.GetRuleCounters()
+		return err
+	})
+	return counters, err
+}
+
+func (m *netNSManager) Flush() error {
+	return m.netNS.Do(m.manager.Flush)
+}
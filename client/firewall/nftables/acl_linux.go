@@ -31,6 +31,10 @@ const (
 	chainNameForwardFilter = "netbird-acl-forward-filter"
 
 	allowNetbirdInputRuleID = "allow Netbird incoming traffic"
+
+	// user data of the default drop rules, used to read their counters
+	dropInRuleID  = "netbird-drop-in"
+	dropOutRuleID = "netbird-drop-out"
 )
 
 var (
@@ -40,18 +44,39 @@ var (
 
 type AclManager struct {
 	rConn               *nftables.Conn
-	sConn               *nftables.Conn
+	bConn               *nftables.Conn // sends the pending changes of a flush as one batch
 	wgIface             iFaceMapper
 	routeingFwChainName string
 
 	workTable        *nftables.Table
 	chainInputRules  *nftables.Chain
 	chainOutputRules *nftables.Chain
+	chainInFilter    *nftables.Chain
+	chainOutFilter   *nftables.Chain
 	chainFwFilter    *nftables.Chain
 	chainPrerouting  *nftables.Chain
 
 	ipsetStore *ipsetStore
 	rules      map[string]*Rule
+	// pendingChanges are applied in order on the next flush
+	pendingChanges []pendingChange
+	// portSets are the anonymous sets of the port lists matched by the pending rules, created with the rules
+	portSets map[*nftables.Rule][]portSet
+	// applied is the state of the last successful flush, restored when a flush fails
+	applied    *aclState
+	logDropped bool
+}
+
+// pendingChange is either the insertion of a rule or another operation of the next flush
+type pendingChange struct {
+	insert *nftables.Rule
+	apply  func()
+}
+
+// aclState is the bookkeeping of the rules and sets applied by a flush
+type aclState struct {
+	rules      map[string]*Rule
+	ipsetStore *ipsetStore
 }
 
 // iFaceMapper defines subset methods of interface required for manager
@@ -62,28 +87,20 @@ type iFaceMapper interface {
 }
 
 func newAclManager(table *nftables.Table, wgIface iFaceMapper, routeingFwChainName string) (*AclManager, error) {
-	// sConn is used for creating sets and adding/removing elements from them
-	// it's differ then rConn (which does create new conn for each flush operation)
-	// and is permanent. Using same connection for booth type of operations
-	// overloads netlink with high amount of rules ( > 10000)
-	sConn, err := nftables.New(nftables.AsLasting())
-	if err != nil {
-		return nil, err
-	}
-
 	m := &AclManager{
 		rConn:               &nftables.Conn{},
-		sConn:               sConn,
+		bConn:               newBatchConn(),
 		wgIface:             wgIface,
 		workTable:           table,
 		routeingFwChainName: routeingFwChainName,
 
 		ipsetStore: newIpsetStore(),
 		rules:      make(map[string]*Rule),
+		portSets:   make(map[*nftables.Rule][]portSet),
+		logDropped: firewall.LogDroppedEnabled(),
 	}
 
-	err = m.createDefaultChains()
-	if err != nil {
+	if err := m.createDefaultChains(); err != nil {
 		return nil, err
 	}
 
@@ -104,6 +121,8 @@ func (m *AclManager) AddFiltering(
 	ipsetName string,
 	comment string,
 ) ([]firewall.Rule, error) {
+	m.saveAppliedState()
+
 	var ipset *nftables.Set
 	if ipsetName != "" {
 		var err error
@@ -132,40 +151,34 @@ func (m *AclManager) AddFiltering(
 	return newRules, nil
 }
 
-// DeleteRule from the firewall by rule definition
+// DeleteRule from the firewall by rule definition. The rule is removed on the next flush
 func (m *AclManager) DeleteRule(rule firewall.Rule) error {
 	r, ok := rule.(*Rule)
 	if !ok {
 		return fmt.Errorf("invalid rule type")
 	}
+	m.saveAppliedState()
 
 	if r.nftSet == nil {
-		err := m.rConn.DelRule(r.nftRule)
-		if err != nil {
-			log.Errorf("failed to delete rule: %v", err)
-		}
+		m.deleteNftRule(r.nftRule)
 		delete(m.rules, r.GetRuleID())
-		return m.rConn.Flush()
+		return nil
 	}
 
 	ips, ok := m.ipsetStore.ips(r.nftSet.Name)
 	if !ok {
-		err := m.rConn.DelRule(r.nftRule)
-		if err != nil {
-			log.Errorf("failed to delete rule: %v", err)
-		}
+		m.deleteNftRule(r.nftRule)
 		delete(m.rules, r.GetRuleID())
-		return m.rConn.Flush()
+		return nil
 	}
 	if _, ok := ips[r.ip.String()]; ok {
-		err := m.sConn.SetDeleteElements(r.nftSet, []nftables.SetElement{{Key: r.ip.To4()}})
-		if err != nil {
-			log.Errorf("delete elements for set %q: %v", r.nftSet.Name, err)
-		}
-		if err := m.sConn.Flush(); err != nil {
-			log.Debugf("flush error of set delete element, %s", r.nftSet.Name)
-			return err
-		}
+		// the element is removed in the same transaction as the rules
+		nftSet, key := r.nftSet, r.ip.To4()
+		m.queueChange(func() {
+			if err := m.bConn.SetDeleteElements(nftSet, []nftables.SetElement{{Key: key}}); err != nil {
+				log.Errorf("delete elements for set %q: %v", nftSet.Name, err)
+			}
+		})
 		m.ipsetStore.DeleteIpFromSet(r.nftSet.Name, r.ip)
 	}
 
@@ -175,15 +188,7 @@ func (m *AclManager) DeleteRule(rule firewall.Rule) error {
 		return nil
 	}
 
-	err := m.rConn.DelRule(r.nftRule)
-	if err != nil {
-		log.Errorf("failed to delete rule: %v", err)
-	}
-	err = m.rConn.Flush()
-	if err != nil {
-		return err
-	}
-
+	m.deleteNftRule(r.nftRule)
 	delete(m.rules, r.GetRuleID())
 	m.ipsetStore.DeleteReferenceFromIpSet(r.nftSet.Name)
 
@@ -193,12 +198,32 @@ func (m *AclManager) DeleteRule(rule firewall.Rule) error {
 
 	// we delete last IP from the set, that means we need to delete
 	// set itself and associated firewall rule too
-	m.rConn.FlushSet(r.nftSet)
-	m.rConn.DelSet(r.nftSet)
+	nftSet := r.nftSet
+	m.queueChange(func() {
+		m.bConn.FlushSet(nftSet)
+		m.bConn.DelSet(nftSet)
+	})
 	m.ipsetStore.deleteIpset(r.nftSet.Name)
 	return nil
 }
 
+// deleteNftRule schedules the deletion of an applied rule or drops a rule not applied yet
+func (m *AclManager) deleteNftRule(nftRule *nftables.Rule) {
+	for i, pending := range m.pendingChanges {
+		if pending.insert == nftRule {
+			m.pendingChanges = append(m.pendingChanges[:i], m.pendingChanges[i+1:]...)
+			delete(m.portSets, nftRule)
+			return
+		}
+	}
+
+	m.queueChange(func() {
+		if err := m.bConn.DelRule(nftRule); err != nil {
+			log.Errorf("failed to delete rule: %v", err)
+		}
+	})
+}
+
 // createDefaultAllowRules In case if the USP firewall manager can use the native firewall manager we must to create allow rules for
 // input and output chains
 func (m *AclManager) createDefaultAllowRules() error {
@@ -276,9 +301,35 @@ func (m *AclManager) createDefaultAllowRules() error {
 
 // Flush rule/chain/set operations from the buffer
 //
+// The pending changes, with the sets and set elements they use, are sent in a single netlink batch which the kernel
+// applies as one transaction. When the flush fails nothing is applied, the pending changes are dropped and the
+// manager goes back to the rules of the last successful flush.
 // Method also get all rules after flush and refreshes handle values in the rulesets
 func (m *AclManager) Flush() error {
-	if err := m.flushWithBackoff(); err != nil {
+	changes, portSets, applied := m.pendingChanges, m.portSets, m.applied
+	m.pendingChanges, m.portSets, m.applied = nil, make(map[*nftables.Rule][]portSet), nil
+
+	err := m.flushWithBackoff(func() error {
+		for _, change := range changes {
+			if change.insert == nil {
+				change.apply()
+				continue
+			}
+			// anonymous sets have to be bound to their rule in the same transaction
+			for _, set := range portSets[change.insert] {
+				if err := m.bConn.AddSet(set.set, set.elements); err != nil {
+					return fmt.Errorf("add port set: %w", err)
+				}
+				set.lookup.SetName, set.lookup.SetID = set.set.Name, set.set.ID
+			}
+			m.bConn.InsertRule(change.insert)
+		}
+		return nil
+	})
+	if err != nil {
+		if applied != nil {
+			m.rules, m.ipsetStore = applied.rules, applied.ipsetStore
+		}
 		return err
 	}
 
@@ -385,38 +436,21 @@ func (m *AclManager) addIOFiltering(ip net.IP, proto firewall.Protocol, sPort *f
 		}
 	}
 
+	var sets []portSet
 	if sPort != nil && len(sPort.Values) != 0 {
-		expressions = append(expressions,
-			&expr.Payload{
-				DestRegister: 1,
-				Base:         expr.PayloadBaseTransportHeader,
-				Offset:       0,
-				Len:          2,
-			},
-			&expr.Cmp{
-				Op:       expr.CmpOpEq,
-				Register: 1,
-				Data:     encodePort(*sPort),
-			},
-		)
+		portExprs, set := m.portExpressions(*sPort, 0)
+		expressions = append(expressions, portExprs...)
+		sets = appendPortSet(sets, set)
 	}
 
 	if dPort != nil && len(dPort.Values) != 0 {
-		expressions = append(expressions,
-			&expr.Payload{
-				DestRegister: 1,
-				Base:         expr.PayloadBaseTransportHeader,
-				Offset:       2,
-				Len:          2,
-			},
-			&expr.Cmp{
-				Op:       expr.CmpOpEq,
-				Register: 1,
-				Data:     encodePort(*dPort),
-			},
-		)
+		portExprs, set := m.portExpressions(*dPort, 2)
+		expressions = append(expressions, portExprs...)
+		sets = appendPortSet(sets, set)
 	}
 
+	expressions = append(expressions, &expr.Counter{})
+
 	switch action {
 	case firewall.ActionAccept:
 		expressions = append(expressions, &expr.Verdict{Kind: expr.VerdictAccept})
@@ -432,13 +466,17 @@ func (m *AclManager) addIOFiltering(ip net.IP, proto firewall.Protocol, sPort *f
 	} else {
 		chain = m.chainOutputRules
 	}
-	nftRule := m.rConn.InsertRule(&nftables.Rule{
+	nftRule := &nftables.Rule{
 		Table:    m.workTable,
 		Chain:    chain,
 		Position: 0,
 		Exprs:    expressions,
 		UserData: userData,
-	})
+	}
+	m.pendingChanges = append(m.pendingChanges, pendingChange{insert: nftRule})
+	if len(sets) > 0 {
+		m.portSets[nftRule] = sets
+	}
 
 	rule := &Rule{
 		nftRule: nftRule,
@@ -525,20 +563,11 @@ func (m *AclManager) addPreroutingFiltering(ipset *nftables.Set, proto firewall.
 		},
 	}
 
+	var set *portSet
 	if port != nil {
-		expressions = append(expressions,
-			&expr.Payload{
-				DestRegister: 1,
-				Base:         expr.PayloadBaseTransportHeader,
-				Offset:       2,
-				Len:          2,
-			},
-			&expr.Cmp{
-				Op:       expr.CmpOpEq,
-				Register: 1,
-				Data:     encodePort(*port),
-			},
-		)
+		var portExprs []expr.Any
+		portExprs, set = m.portExpressions(*port, 2)
+		expressions = append(expressions, portExprs...)
 	}
 
 	expressions = append(expressions,
@@ -553,16 +582,16 @@ func (m *AclManager) addPreroutingFiltering(ipset *nftables.Set, proto firewall.
 		},
 	)
 
-	nftRule := m.rConn.InsertRule(&nftables.Rule{
+	nftRule := &nftables.Rule{
 		Table:    m.workTable,
 		Chain:    m.chainPrerouting,
 		Position: 0,
 		Exprs:    expressions,
 		UserData: []byte(ruleId),
-	})
-
-	if err := m.rConn.Flush(); err != nil {
-		return nil, fmt.Errorf("flush insert rule: %v", err)
+	}
+	m.pendingChanges = append(m.pendingChanges, pendingChange{insert: nftRule})
+	if set != nil {
+		m.portSets[nftRule] = []portSet{*set}
 	}
 
 	rule := &Rule{
@@ -605,12 +634,13 @@ func (m *AclManager) createDefaultChains() (err error) {
 	m.addRouteAllowRule(chain, expr.MetaKeyIIFNAME)
 	m.addFwdAllow(chain, expr.MetaKeyIIFNAME)
 	m.addJumpRule(chain, m.chainInputRules.Name, expr.MetaKeyIIFNAME) // to netbird-acl-input-rules
-	m.addDropExpressions(chain, expr.MetaKeyIIFNAME)
+	m.addDropExpressions(chain, expr.MetaKeyIIFNAME, dropInRuleID)
 	err = m.rConn.Flush()
 	if err != nil {
 		log.Debugf("failed to create chain (%s): %s", chain.Name, err)
 		return err
 	}
+	m.chainInFilter = chain
 
 	// netbird-acl-output-filter
 	// type filter hook output priority filter; policy accept;
@@ -618,19 +648,20 @@ func (m *AclManager) createDefaultChains() (err error) {
 	m.addRouteAllowRule(chain, expr.MetaKeyOIFNAME)
 	m.addFwdAllow(chain, expr.MetaKeyOIFNAME)
 	m.addJumpRule(chain, m.chainOutputRules.Name, expr.MetaKeyOIFNAME) // to netbird-acl-output-rules
-	m.addDropExpressions(chain, expr.MetaKeyOIFNAME)
+	m.addDropExpressions(chain, expr.MetaKeyOIFNAME, dropOutRuleID)
 	err = m.rConn.Flush()
 	if err != nil {
 		log.Debugf("failed to create chain (%s): %s", chainNameOutputFilter, err)
 		return err
 	}
+	m.chainOutFilter = chain
 
 	// netbird-acl-forward-filter
 	m.chainFwFilter = m.createFilterChainWithHook(chainNameForwardFilter, nftables.ChainHookForward)
 	m.addJumpRulesToRtForward() // to
 	m.addMarkAccept()
 	m.addJumpRuleToInputChain() // to netbird-acl-input-rules
-	m.addDropExpressions(m.chainFwFilter, expr.MetaKeyIIFNAME)
+	m.addDropExpressions(m.chainFwFilter, expr.MetaKeyIIFNAME, dropInRuleID)
 	err = m.rConn.Flush()
 	if err != nil {
 		log.Debugf("failed to create chain (%s): %s", chainNameForwardFilter, err)
@@ -814,20 +845,46 @@ func (m *AclManager) createPreroutingMangle() *nftables.Chain {
 	return chain
 }
 
-func (m *AclManager) addDropExpressions(chain *nftables.Chain, ifaceKey expr.MetaKey) []expr.Any {
-	expressions := []expr.Any{
+func (m *AclManager) addDropExpressions(chain *nftables.Chain, ifaceKey expr.MetaKey, ruleID string) []expr.Any {
+	matchIface := []expr.Any{
 		&expr.Meta{Key: ifaceKey, Register: 1},
 		&expr.Cmp{
 			Op:       expr.CmpOpEq,
 			Register: 1,
 			Data:     ifname(m.wgIface.Name()),
 		},
-		&expr.Verdict{Kind: expr.VerdictDrop},
 	}
+
+	if m.logDropped {
+		// the log rule doesn't have a verdict, the packets continue to the drop rule
+		logExpressions := append(matchIface[:len(matchIface):len(matchIface)],
+			&expr.Limit{
+				Type:  expr.LimitTypePkts,
+				Rate:  firewall.DropLogRate,
+				Unit:  expr.LimitTimeSecond,
+				Burst: firewall.DropLogRate,
+			},
+			&expr.Log{
+				Key:  1 << unix.NFTA_LOG_PREFIX,
+				Data: []byte(ruleID + ": "),
+			},
+		)
+		_ = m.rConn.AddRule(&nftables.Rule{
+			Table: m.workTable,
+			Chain: chain,
+			Exprs: logExpressions,
+		})
+	}
+
+	expressions := append(matchIface,
+		&expr.Counter{},
+		&expr.Verdict{Kind: expr.VerdictDrop},
+	)
 	_ = m.rConn.AddRule(&nftables.Rule{
-		Table: m.workTable,
-		Chain: chain,
-		Exprs: expressions,
+		Table:    m.workTable,
+		Chain:    chain,
+		Exprs:    expressions,
+		UserData: []byte(ruleID),
 	})
 	return nil
 }
@@ -1038,59 +1095,70 @@ func (m *AclManager) addJumpRule(chain *nftables.Chain, to string, ifaceKey expr
 	})
 }
 
+// addIpToSet adds the IP to the named set on the next flush, the set is created with its first IP
 func (m *AclManager) addIpToSet(ipsetName string, ip net.IP) (*nftables.Set, error) {
-	ipset, err := m.rConn.GetSetByName(m.workTable, ipsetName)
-	rawIP := ip.To4()
-	if err != nil {
-		if ipset, err = m.createSet(m.workTable, ipsetName); err != nil {
-			return nil, fmt.Errorf("get set name: %v", err)
+	ipset, ok := m.ipsetStore.set(ipsetName)
+	if !ok {
+		ipset = &nftables.Set{
+			Name:    ipsetName,
+			Table:   m.workTable,
+			Dynamic: true,
+			KeyType: nftables.TypeIPAddr,
 		}
-
-		m.ipsetStore.newIpset(ipset.Name)
+		m.ipsetStore.newIpset(ipset)
+		m.queueChange(func() {
+			if err := m.bConn.AddSet(ipset, nil); err != nil {
+				log.Errorf("create set %q: %v", ipset.Name, err)
+			}
+		})
 	}
 
 	if m.ipsetStore.IsIpInSet(ipset.Name, ip) {
 		return ipset, nil
 	}
 
-	if err := m.sConn.SetAddElements(ipset, []nftables.SetElement{{Key: rawIP}}); err != nil {
-		return nil, fmt.Errorf("add set element for the first time: %v", err)
+	key := ip.To4()
+	if key == nil {
+		return nil, fmt.Errorf("add %s to set %s: not an IPv4 address", ip, ipsetName)
 	}
-
+	m.queueChange(func() {
+		if err := m.bConn.SetAddElements(ipset, []nftables.SetElement{{Key: key}}); err != nil {
+			log.Errorf("add elements to set %q: %v", ipset.Name, err)
+		}
+	})
 	m.ipsetStore.AddIpToSet(ipset.Name, ip)
 
-	if err := m.sConn.Flush(); err != nil {
-		return nil, fmt.Errorf("flush add elements: %v", err)
-	}
-
 	return ipset, nil
 }
 
-// createSet in given table by name
-func (m *AclManager) createSet(table *nftables.Table, name string) (*nftables.Set, error) {
-	ipset := &nftables.Set{
-		Name:    name,
-		Table:   table,
-		Dynamic: true,
-		KeyType: nftables.TypeIPAddr,
-	}
+// queueChange schedules an operation for the next flush, after the changes queued before it
+func (m *AclManager) queueChange(apply func()) {
+	m.pendingChanges = append(m.pendingChanges, pendingChange{apply: apply})
+}
 
-	if err := m.rConn.AddSet(ipset, nil); err != nil {
-		return nil, fmt.Errorf("create set: %v", err)
+// saveAppliedState keeps the bookkeeping of the last flush before its first change, to restore it if the next
+// flush fails
+func (m *AclManager) saveAppliedState() {
+	if m.applied != nil {
+		return
 	}
-
-	if err := m.rConn.Flush(); err != nil {
-		return nil, fmt.Errorf("flush created set: %v", err)
+	rules := make(map[string]*Rule, len(m.rules))
+	for id, rule := range m.rules {
+		rules[id] = rule
 	}
-
-	return ipset, nil
+	m.applied = &aclState{rules: rules, ipsetStore: m.ipsetStore.clone()}
 }
 
-func (m *AclManager) flushWithBackoff() (err error) {
+// flushWithBackoff queues the changes and sends them, the changes are queued again when the kernel is busy as a
+// failed flush drops the buffered messages
+func (m *AclManager) flushWithBackoff(queue func() error) (err error) {
 	backoff := 4
 	backoffTime := 1000 * time.Millisecond
 	for i := 0; ; i++ {
-		err = m.rConn.Flush()
+		if err = queue(); err != nil {
+			return err
+		}
+		err = m.bConn.Flush()
 		if err != nil {
 			if !strings.Contains(err.Error(), "busy") {
 				return
@@ -1132,6 +1200,57 @@ func (m *AclManager) refreshRuleHandles(chain *nftables.Chain) error {
 	return nil
 }
 
+// GetRuleCounters returns the counters of the filtering rules and of the default drop rules
+func (m *AclManager) GetRuleCounters() (*firewall.RuleCounters, error) {
+	counters := &firewall.RuleCounters{
+		Rules: make(map[string]firewall.Counters),
+	}
+
+	chains := []*nftables.Chain{m.chainInputRules, m.chainOutputRules, m.chainInFilter, m.chainOutFilter, m.chainFwFilter}
+	for _, chain := range chains {
+		if chain == nil {
+			continue
+		}
+
+		rules, err := m.rConn.GetRules(m.workTable, chain)
+		if err != nil {
+			return nil, fmt.Errorf("get rules of chain %s: %w", chain.Name, err)
+		}
+
+		for _, rule := range rules {
+			if len(rule.UserData) == 0 {
+				continue
+			}
+			ruleCounter, ok := getCounter(rule)
+			if !ok {
+				continue
+			}
+
+			switch ruleID := string(bytes.Split(rule.UserData, []byte(" "))[0]); ruleID {
+			case dropInRuleID:
+				counters.DroppedIn.Add(ruleCounter)
+			case dropOutRuleID:
+				counters.DroppedOut.Add(ruleCounter)
+			default:
+				c := counters.Rules[ruleID]
+				c.Add(ruleCounter)
+				counters.Rules[ruleID] = c
+			}
+		}
+	}
+
+	return counters, nil
+}
+
+func getCounter(rule *nftables.Rule) (firewall.Counters, bool) {
+	for _, e := range rule.Exprs {
+		if c, ok := e.(*expr.Counter); ok {
+			return firewall.Counters{Packets: c.Packets, Bytes: c.Bytes}, true
+		}
+	}
+	return firewall.Counters{}, false
+}
+
 func generateRuleId(
 	ip net.IP,
 	sPort *firewall.Port,
@@ -1183,9 +1302,66 @@ func shouldAddToPrerouting(proto firewall.Protocol, dPort *firewall.Port, direct
 	return true
 }
 
-func encodePort(port firewall.Port) []byte {
+// portSet is an anonymous set of ports with its elements and the lookup of the rule matching it
+type portSet struct {
+	set      *nftables.Set
+	elements []nftables.SetElement
+	lookup   *expr.Lookup
+}
+
+// portExpressions returns the expressions matching the transport header port at the offset against a single port,
+// a range or a list of ports. A list is matched with an anonymous set, which is returned to be created with the rule
+func (m *AclManager) portExpressions(port firewall.Port, offset uint32) ([]expr.Any, *portSet) {
+	expressions := []expr.Any{
+		&expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseTransportHeader,
+			Offset:       offset,
+			Len:          2,
+		},
+	}
+
+	switch {
+	case port.IsRange && len(port.Values) == 2:
+		return append(expressions, &expr.Range{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			FromData: encodePort(port.Values[0]),
+			ToData:   encodePort(port.Values[1]),
+		}), nil
+	case len(port.Values) == 1:
+		return append(expressions, &expr.Cmp{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			Data:     encodePort(port.Values[0]),
+		}), nil
+	}
+
+	set := &nftables.Set{
+		Table:     m.workTable,
+		Anonymous: true,
+		Constant:  true,
+		KeyType:   nftables.TypeInetService,
+	}
+	elements := make([]nftables.SetElement, 0, len(port.Values))
+	for _, value := range port.Values {
+		elements = append(elements, nftables.SetElement{Key: encodePort(value)})
+	}
+	// the set gets its name and ID when it's added, the lookup is resolved when the rule is inserted
+	lookup := &expr.Lookup{SourceRegister: 1}
+	return append(expressions, lookup), &portSet{set: set, elements: elements, lookup: lookup}
+}
+
+func appendPortSet(sets []portSet, set *portSet) []portSet {
+	if set == nil {
+		return sets
+	}
+	return append(sets, *set)
+}
+
+func encodePort(port int) []byte {
 	bs := make([]byte, 2)
-	binary.BigEndian.PutUint16(bs, uint16(port.Values[0]))
+	binary.BigEndian.PutUint16(bs, uint16(port))
 	return bs
 }
 
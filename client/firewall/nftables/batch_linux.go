@@ -0,0 +1,91 @@
+package nftables
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/google/nftables"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// batchReplySize is the receive buffer reserved for the answers to a message of a batch, the kernel echoes the
+// inserted rules on top of the acknowledgements
+const batchReplySize = 4096
+
+// newBatchConn returns a connection sending each flush as one netlink batch through sendBatch.
+// The library reads the acknowledgements only after the whole batch is sent, while the kernel answers all the
+// messages of the batch at once: with the default receive buffer of the socket big batches fail with ENOBUFS.
+// The dial hook is the only way the library lets us size the buffer of the socket.
+func newBatchConn() *nftables.Conn {
+	return &nftables.Conn{TestDial: sendBatch}
+}
+
+// sendBatch sends the messages of a batch and reads all their acknowledgements, the receive buffer of the socket
+// fits the answers of the whole batch
+func sendBatch(req []netlink.Message) ([]netlink.Message, error) {
+	if req == nil {
+		// everything has been read already
+		return nil, io.EOF
+	}
+
+	conn, err := netlink.Dial(unix.NETLINK_NETFILTER, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dial netlink: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err := setReceiveBuffer(conn, len(req)*batchReplySize); err != nil {
+		return nil, err
+	}
+
+	acks := 0
+	for i := range req {
+		// the sequence numbers and the port ID are the ones of the socket of the library
+		req[i].Header.Sequence, req[i].Header.PID = 0, 0
+		if req[i].Header.Flags&netlink.Acknowledge != 0 {
+			acks++
+		}
+	}
+
+	if _, err := conn.SendMessages(req); err != nil {
+		return nil, fmt.Errorf("send batch: %w", err)
+	}
+
+	for acks > 0 {
+		replies, err := conn.Receive()
+		if err != nil {
+			return nil, err
+		}
+		for _, reply := range replies {
+			if reply.Header.Type == netlink.Error {
+				acks--
+			}
+		}
+	}
+	return nil, nil
+}
+
+// setReceiveBuffer sets the receive buffer of the socket, above the system limit when the process is allowed to
+func setReceiveBuffer(conn *netlink.Conn, size int) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("get netlink socket: %w", err)
+	}
+
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUFFORCE, size)
+		if sockErr != nil {
+			// only privileged processes can exceed net.core.rmem_max
+			sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF, size)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("control netlink socket: %w", err)
+	}
+	if sockErr != nil {
+		return fmt.Errorf("set netlink receive buffer: %w", sockErr)
+	}
+	return nil
+}
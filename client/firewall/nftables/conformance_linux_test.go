@@ -0,0 +1,26 @@
+package nftables
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	fw "github.com/netbirdio/netbird/client/firewall/manager"
+	"github.com/netbirdio/netbird/client/firewall/test"
+)
+
+func TestNftablesConformance(t *testing.T) {
+	test.RunConformance(t, test.Backend{
+		Setup: func(t *testing.T) (fw.Manager, test.Network) {
+			network := test.NewNetnsNetwork(t)
+
+			manager, err := Create(context.Background(), network)
+			require.NoError(t, err, "create manager")
+			t.Cleanup(func() {
+				require.NoError(t, manager.Reset(), "reset manager")
+			})
+			return manager, network
+		},
+	})
+}
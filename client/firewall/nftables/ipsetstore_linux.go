@@ -2,18 +2,41 @@ package nftables
 
 import (
 	"net"
+
+	"github.com/google/nftables"
 )
 
 type ipsetStore struct {
 	ipsetReference map[string]int
 	ipsets         map[string]map[string]struct{} // ipsetName -> list of ips
+	sets           map[string]*nftables.Set
 }
 
 func newIpsetStore() *ipsetStore {
 	return &ipsetStore{
 		ipsetReference: make(map[string]int),
 		ipsets:         make(map[string]map[string]struct{}),
+		sets:           make(map[string]*nftables.Set),
+	}
+}
+
+// clone returns a copy of the store which isn't changed by the changes of the store
+func (s *ipsetStore) clone() *ipsetStore {
+	c := newIpsetStore()
+	for name, references := range s.ipsetReference {
+		c.ipsetReference[name] = references
 	}
+	for name, ips := range s.ipsets {
+		ipsCopy := make(map[string]struct{}, len(ips))
+		for ip := range ips {
+			ipsCopy[ip] = struct{}{}
+		}
+		c.ipsets[name] = ipsCopy
+	}
+	for name, set := range s.sets {
+		c.sets[name] = set
+	}
+	return c
 }
 
 func (s *ipsetStore) ips(ipsetName string) (map[string]struct{}, bool) {
@@ -21,16 +44,23 @@ func (s *ipsetStore) ips(ipsetName string) (map[string]struct{}, bool) {
 	return r, ok
 }
 
-func (s *ipsetStore) newIpset(ipsetName string) map[string]struct{} {
-	s.ipsetReference[ipsetName] = 0
+func (s *ipsetStore) set(ipsetName string) (*nftables.Set, bool) {
+	set, ok := s.sets[ipsetName]
+	return set, ok
+}
+
+func (s *ipsetStore) newIpset(set *nftables.Set) map[string]struct{} {
+	s.ipsetReference[set.Name] = 0
 	ipList := make(map[string]struct{})
-	s.ipsets[ipsetName] = ipList
+	s.ipsets[set.Name] = ipList
+	s.sets[set.Name] = set
 	return ipList
 }
 
 func (s *ipsetStore) deleteIpset(ipsetName string) {
 	delete(s.ipsetReference, ipsetName)
 	delete(s.ipsets, ipsetName)
+	delete(s.sets, ipsetName)
 }
 
 func (s *ipsetStore) DeleteIpFromSet(ipsetName string, ip net.IP) {
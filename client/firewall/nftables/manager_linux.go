@@ -87,10 +87,23 @@ func (m *Manager) DeleteRule(rule firewall.Rule) error {
 	return m.aclManager.DeleteRule(rule)
 }
 
+// GetRuleCounters returns the traffic matched by the filtering rules and dropped by the default policy
+func (m *Manager) GetRuleCounters() (*firewall.RuleCounters, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.aclManager.GetRuleCounters()
+}
+
 func (m *Manager) IsServerRouteSupported() bool {
 	return true
 }
 
+// IsStateful returns false, the replies are accepted by the inverted rules
+func (m *Manager) IsStateful() bool {
+	return false
+}
+
 func (m *Manager) InsertRoutingRules(pair firewall.RouterPair) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -187,6 +200,10 @@ func (m *Manager) Reset() error {
 
 	m.router.ResetForwardRules()
 
+	// the pending changes target the table deleted below
+	m.aclManager.pendingChanges, m.aclManager.applied = nil, nil
+	m.aclManager.portSets = make(map[*nftables.Rule][]portSet)
+
 	tables, err := m.rConn.ListTables()
 	if err != nil {
 		return fmt.Errorf("list of tables: %w", err)
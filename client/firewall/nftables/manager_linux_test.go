@@ -132,10 +132,15 @@ func TestNftablesManager(t *testing.T) {
 			Register: 1,
 			Data:     []byte{0, 53},
 		},
+		&expr.Counter{},
 		&expr.Verdict{Kind: expr.VerdictDrop},
 	}
 	require.ElementsMatch(t, rules[0].Exprs, expectedExprs, "expected the same expressions")
 
+	counters, err := manager.GetRuleCounters()
+	require.NoError(t, err, "failed to get counters")
+	require.Contains(t, counters.Rules, rule[0].GetRuleID(), "expected the counters of the rule")
+
 	for _, r := range rule {
 		err = manager.DeleteRule(r)
 		require.NoError(t, err, "failed to delete rule")
@@ -152,6 +157,79 @@ func TestNftablesManager(t *testing.T) {
 	require.NoError(t, err, "failed to reset")
 }
 
+func TestNftablesManager_FailedFlush(t *testing.T) {
+	mock := &iFaceMock{
+		NameFunc: func() string {
+			return "lo"
+		},
+		AddressFunc: func() iface.WGAddress {
+			return iface.WGAddress{
+				IP: net.ParseIP("100.96.0.1"),
+				Network: &net.IPNet{
+					IP:   net.ParseIP("100.96.0.0"),
+					Mask: net.IPv4Mask(255, 255, 255, 0),
+				},
+			}
+		},
+	}
+
+	manager, err := Create(context.Background(), mock)
+	require.NoError(t, err)
+
+	defer func() {
+		err = manager.Reset()
+		require.NoError(t, err, "failed to reset")
+	}()
+
+	port := &fw.Port{Values: []int{80}}
+	applied, err := manager.AddFiltering(net.ParseIP("100.96.0.2"), fw.ProtocolTCP, nil, port, fw.RuleDirectionIN, fw.ActionAccept, "web", "")
+	require.NoError(t, err, "failed to add rule")
+	require.NoError(t, manager.Flush(), "failed to flush")
+
+	_, err = manager.AddFiltering(net.ParseIP("100.96.0.3"), fw.ProtocolTCP, nil, port, fw.RuleDirectionIN, fw.ActionAccept, "web", "")
+	require.NoError(t, err, "failed to add rule")
+	_, err = manager.AddFiltering(net.ParseIP("100.96.0.4"), fw.ProtocolUDP, nil, port, fw.RuleDirectionIN, fw.ActionAccept, "dns", "")
+	require.NoError(t, err, "failed to add rule")
+	for _, r := range applied {
+		require.NoError(t, manager.DeleteRule(r), "failed to delete rule")
+	}
+
+	// deleting a set which doesn't exist makes the kernel reject the whole batch
+	manager.aclManager.queueChange(func() {
+		manager.aclManager.bConn.DelSet(&nftables.Set{Name: "missing", Table: manager.aclManager.workTable})
+	})
+	require.Error(t, manager.Flush(), "the flush should fail")
+
+	testClient := &nftables.Conn{}
+	rules, err := testClient.GetRules(manager.aclManager.workTable, manager.aclManager.chainInputRules)
+	require.NoError(t, err, "failed to get rules")
+	require.Len(t, rules, 1, "the rule of the last flush should be kept")
+
+	set, err := testClient.GetSetByName(manager.aclManager.workTable, "web")
+	require.NoError(t, err, "failed to get set")
+	elements, err := testClient.GetSetElements(set)
+	require.NoError(t, err, "failed to get set elements")
+	require.Len(t, elements, 1, "the set should only contain the IP of the last flush")
+	_, err = testClient.GetSetByName(manager.aclManager.workTable, "dns")
+	require.Error(t, err, "the set of the failed flush should not be created")
+
+	require.Len(t, manager.aclManager.rules, 2, "the bookkeeping should be the one of the last flush")
+	require.True(t, manager.aclManager.ipsetStore.IsIpInSet("web", net.ParseIP("100.96.0.2")))
+	require.False(t, manager.aclManager.ipsetStore.IsIpInSet("web", net.ParseIP("100.96.0.3")))
+	_, ok := manager.aclManager.ipsetStore.set("dns")
+	require.False(t, ok, "the set of the failed flush should be dropped")
+
+	// the rules of the last flush can still be deleted
+	for _, r := range applied {
+		require.NoError(t, manager.DeleteRule(r), "failed to delete rule")
+	}
+	require.NoError(t, manager.Flush(), "failed to flush")
+
+	rules, err = testClient.GetRules(manager.aclManager.workTable, manager.aclManager.chainInputRules)
+	require.NoError(t, err, "failed to get rules")
+	require.Len(t, rules, 0, "expected 0 rules after deletion")
+}
+
 func TestNFtablesCreatePerformance(t *testing.T) {
 	mock := &iFaceMock{
 		NameFunc: func() string {
@@ -0,0 +1,419 @@
+package test
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/require"
+
+	firewall "github.com/netbirdio/netbird/client/firewall/manager"
+)
+
+// Addresses of the conformance network
+const (
+	LocalIP       = "100.96.0.1"
+	PeerIP        = "100.96.0.2"
+	OtherPeerIP   = "100.96.0.3"
+	ThirdPeerIP   = "100.96.0.4"
+	RoutedIP      = "10.10.0.2"
+	WgNetwork     = "100.96.0.0/24"
+	RoutedNetwork = "10.10.0.0/24"
+)
+
+// Packet is a packet sent through the firewall under test
+type Packet struct {
+	// Direction is IN for the packets of the peer to the local address and OUT for the packets to the peer
+	Direction firewall.RuleDirection
+	Protocol  firewall.Protocol
+	Peer      string
+	// Destination is the routed address the packet of the peer is sent to instead of the local address
+	Destination string
+	SrcPort     uint16
+	DstPort     uint16
+}
+
+// SrcIP returns the source address of the packet
+func (p Packet) SrcIP() net.IP {
+	if p.Direction == firewall.RuleDirectionOUT {
+		return net.ParseIP(LocalIP).To4()
+	}
+	return net.ParseIP(p.Peer).To4()
+}
+
+// DstIP returns the destination address of the packet
+func (p Packet) DstIP() net.IP {
+	switch {
+	case p.Direction == firewall.RuleDirectionOUT:
+		return net.ParseIP(p.Peer).To4()
+	case p.Destination != "":
+		return net.ParseIP(p.Destination).To4()
+	default:
+		return net.ParseIP(LocalIP).To4()
+	}
+}
+
+// Serialize returns the IPv4 packet carrying the payload, TCP packets are SYN packets and ICMP packets are echo
+// requests
+func (p Packet) Serialize(payload []byte) ([]byte, error) {
+	ipv4 := &layers.IPv4{Version: 4, TTL: 64, SrcIP: p.SrcIP(), DstIP: p.DstIP()}
+
+	var transport gopacket.SerializableLayer
+	switch p.Protocol {
+	case firewall.ProtocolTCP:
+		ipv4.Protocol = layers.IPProtocolTCP
+		tcp := &layers.TCP{SrcPort: layers.TCPPort(p.SrcPort), DstPort: layers.TCPPort(p.DstPort), SYN: true, Window: 1024}
+		if err := tcp.SetNetworkLayerForChecksum(ipv4); err != nil {
+			return nil, err
+		}
+		transport = tcp
+	case firewall.ProtocolUDP:
+		ipv4.Protocol = layers.IPProtocolUDP
+		udp := &layers.UDP{SrcPort: layers.UDPPort(p.SrcPort), DstPort: layers.UDPPort(p.DstPort)}
+		if err := udp.SetNetworkLayerForChecksum(ipv4); err != nil {
+			return nil, err
+		}
+		transport = udp
+	case firewall.ProtocolICMP:
+		ipv4.Protocol = layers.IPProtocolICMPv4
+		transport = &layers.ICMPv4{TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0), Id: p.SrcPort, Seq: 1}
+	default:
+		return nil, fmt.Errorf("unsupported packet protocol: %s", p.Protocol)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+	if err := gopacket.SerializeLayers(buf, opts, ipv4, transport, gopacket.Payload(payload)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (p Packet) String() string {
+	return fmt.Sprintf("%s %s:%d -> %s:%d", p.Protocol, p.SrcIP(), p.SrcPort, p.DstIP(), p.DstPort)
+}
+
+// Network sends the packets through the firewall under test
+type Network interface {
+	// Passes sends the packet and reports whether the firewall let it through
+	Passes(t *testing.T, packet Packet) bool
+}
+
+// Rule is a filtering rule added to the firewall under test
+type Rule struct {
+	Peer      string
+	Protocol  firewall.Protocol
+	SPort     *firewall.Port
+	DPort     *firewall.Port
+	Direction firewall.RuleDirection
+	Action    firewall.Action
+	IPSet     string
+}
+
+// Case is a conformance scenario, the rules and routes are applied before the packets are sent
+type Case struct {
+	Name  string
+	Rules []Rule
+	// Deleted are the indexes of the rules deleted again before the packets are sent
+	Deleted []int
+	Routes  []firewall.RouterPair
+	// RemoveRoutes removes the routes again before the packets are sent
+	RemoveRoutes bool
+	Accepted     []Packet
+	Dropped      []Packet
+}
+
+// Backend is a firewall manager implementation checked by the conformance suite
+type Backend struct {
+	// Setup returns a new firewall manager and the network checking its verdicts
+	Setup func(t *testing.T) (firewall.Manager, Network)
+	// Unsupported maps the names of the cases the backend doesn't pass yet to the reason
+	Unsupported map[string]string
+}
+
+var (
+	routeToRoutedNetwork = firewall.RouterPair{
+		ID:          "conformance-route",
+		Source:      WgNetwork,
+		Destination: RoutedNetwork,
+		Masquerade:  true,
+	}
+
+	// ConformanceCases are the scenarios every firewall manager has to pass
+	ConformanceCases = []Case{
+		{
+			Name: "Drop Everything Without Rules",
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40000, DstPort: 80},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolUDP, Peer: PeerIP, SrcPort: 40000, DstPort: 53},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolICMP, Peer: PeerIP, SrcPort: 1},
+				{Direction: firewall.RuleDirectionOUT, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40000, DstPort: 80},
+			},
+		},
+		{
+			Name: "Accept TCP Destination Port",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolTCP, DPort: &firewall.Port{Values: []int{80}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept},
+			},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40000, DstPort: 80},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40001, DstPort: 81},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: OtherPeerIP, SrcPort: 40002, DstPort: 80},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolUDP, Peer: PeerIP, SrcPort: 40003, DstPort: 80},
+			},
+		},
+		{
+			Name: "Accept UDP Destination Port",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolUDP, DPort: &firewall.Port{Values: []int{53}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept},
+			},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolUDP, Peer: PeerIP, SrcPort: 40000, DstPort: 53},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolUDP, Peer: PeerIP, SrcPort: 40001, DstPort: 54},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolUDP, Peer: OtherPeerIP, SrcPort: 40002, DstPort: 53},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40003, DstPort: 53},
+			},
+		},
+		{
+			Name: "Accept Source Port",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolTCP, SPort: &firewall.Port{Values: []int{22}}, Direction: firewall.RuleDirectionOUT, Action: firewall.ActionAccept},
+			},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionOUT, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 22, DstPort: 40000},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionOUT, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 23, DstPort: 40001},
+				{Direction: firewall.RuleDirectionOUT, Protocol: firewall.ProtocolTCP, Peer: OtherPeerIP, SrcPort: 22, DstPort: 40002},
+			},
+		},
+		{
+			Name: "Accept ICMP",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolICMP, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept},
+			},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolICMP, Peer: PeerIP, SrcPort: 1},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolICMP, Peer: OtherPeerIP, SrcPort: 2},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40000, DstPort: 80},
+			},
+		},
+		{
+			Name: "Accept All Protocols",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolALL, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept},
+			},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40000, DstPort: 80},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolUDP, Peer: PeerIP, SrcPort: 40001, DstPort: 53},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolICMP, Peer: PeerIP, SrcPort: 1},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: OtherPeerIP, SrcPort: 40002, DstPort: 80},
+				{Direction: firewall.RuleDirectionOUT, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40003, DstPort: 80},
+			},
+		},
+		{
+			Name: "Accept Any Peer",
+			Rules: []Rule{
+				{Peer: "0.0.0.0", Protocol: firewall.ProtocolTCP, DPort: &firewall.Port{Values: []int{80}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept},
+			},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40000, DstPort: 80},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: ThirdPeerIP, SrcPort: 40001, DstPort: 80},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40002, DstPort: 81},
+			},
+		},
+		{
+			Name: "Accept Outgoing",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolTCP, DPort: &firewall.Port{Values: []int{80}}, Direction: firewall.RuleDirectionOUT, Action: firewall.ActionAccept},
+			},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionOUT, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40000, DstPort: 80},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionOUT, Protocol: firewall.ProtocolTCP, Peer: OtherPeerIP, SrcPort: 40001, DstPort: 80},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40002, DstPort: 80},
+			},
+		},
+		{
+			Name: "Drop Rule",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolTCP, DPort: &firewall.Port{Values: []int{80}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept},
+				{Peer: PeerIP, Protocol: firewall.ProtocolTCP, DPort: &firewall.Port{Values: []int{22}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionDrop},
+			},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40000, DstPort: 80},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40001, DstPort: 22},
+			},
+		},
+		{
+			Name: "Deleted Rule",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolTCP, DPort: &firewall.Port{Values: []int{80}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept},
+				{Peer: PeerIP, Protocol: firewall.ProtocolUDP, DPort: &firewall.Port{Values: []int{53}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept},
+			},
+			Deleted: []int{0},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolUDP, Peer: PeerIP, SrcPort: 40000, DstPort: 53},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40001, DstPort: 80},
+			},
+		},
+		{
+			Name: "Accept IP Set",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolTCP, DPort: &firewall.Port{Values: []int{80}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept, IPSet: "conformance-set"},
+				{Peer: OtherPeerIP, Protocol: firewall.ProtocolTCP, DPort: &firewall.Port{Values: []int{80}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept, IPSet: "conformance-set"},
+			},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40000, DstPort: 80},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: OtherPeerIP, SrcPort: 40001, DstPort: 80},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: ThirdPeerIP, SrcPort: 40002, DstPort: 80},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40003, DstPort: 81},
+			},
+		},
+		{
+			Name: "Deleted IP Set Member",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolTCP, DPort: &firewall.Port{Values: []int{80}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept, IPSet: "conformance-set"},
+				{Peer: OtherPeerIP, Protocol: firewall.ProtocolTCP, DPort: &firewall.Port{Values: []int{80}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept, IPSet: "conformance-set"},
+			},
+			Deleted: []int{0},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: OtherPeerIP, SrcPort: 40000, DstPort: 80},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40001, DstPort: 80},
+			},
+		},
+		{
+			Name: "Accept Port Range",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolTCP, DPort: &firewall.Port{IsRange: true, Values: []int{8000, 8100}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept},
+			},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40000, DstPort: 8000},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40001, DstPort: 8050},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40002, DstPort: 8100},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40003, DstPort: 7999},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 40004, DstPort: 8101},
+			},
+		},
+		{
+			Name: "Accept Port List",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolUDP, DPort: &firewall.Port{Values: []int{53, 853, 5353}}, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept},
+			},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolUDP, Peer: PeerIP, SrcPort: 40000, DstPort: 53},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolUDP, Peer: PeerIP, SrcPort: 40001, DstPort: 5353},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolUDP, Peer: PeerIP, SrcPort: 40002, DstPort: 54},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolUDP, Peer: OtherPeerIP, SrcPort: 40003, DstPort: 853},
+			},
+		},
+		{
+			Name: "Accept Source Port Range",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolTCP, SPort: &firewall.Port{IsRange: true, Values: []int{8000, 8100}}, Direction: firewall.RuleDirectionOUT, Action: firewall.ActionAccept},
+			},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionOUT, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 8080, DstPort: 40000},
+			},
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionOUT, Protocol: firewall.ProtocolTCP, Peer: PeerIP, SrcPort: 8101, DstPort: 40001},
+			},
+		},
+		{
+			Name: "Accept Routed Traffic",
+			Rules: []Rule{
+				{Peer: PeerIP, Protocol: firewall.ProtocolALL, Direction: firewall.RuleDirectionIN, Action: firewall.ActionAccept},
+			},
+			Routes: []firewall.RouterPair{routeToRoutedNetwork},
+			Accepted: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, Destination: RoutedIP, SrcPort: 40000, DstPort: 80},
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolUDP, Peer: PeerIP, Destination: RoutedIP, SrcPort: 40001, DstPort: 53},
+			},
+		},
+		{
+			Name:         "Drop Traffic Of Removed Route",
+			Routes:       []firewall.RouterPair{routeToRoutedNetwork},
+			RemoveRoutes: true,
+			Dropped: []Packet{
+				{Direction: firewall.RuleDirectionIN, Protocol: firewall.ProtocolTCP, Peer: PeerIP, Destination: RoutedIP, SrcPort: 40000, DstPort: 80},
+			},
+		},
+	}
+)
+
+// RunConformance runs the conformance cases against the backend, every case gets a new firewall manager
+func RunConformance(t *testing.T, backend Backend) {
+	t.Helper()
+
+	for _, c := range ConformanceCases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			if reason, ok := backend.Unsupported[c.Name]; ok {
+				t.Skipf("not supported by the backend: %s", reason)
+			}
+
+			manager, network := backend.Setup(t)
+			applyCase(t, manager, c)
+
+			for _, packet := range c.Accepted {
+				require.True(t, network.Passes(t, packet), "packet %s should be accepted", packet)
+			}
+			for _, packet := range c.Dropped {
+				require.False(t, network.Passes(t, packet), "packet %s should be dropped", packet)
+			}
+		})
+	}
+}
+
+func applyCase(t *testing.T, manager firewall.Manager, c Case) {
+	t.Helper()
+
+	var added [][]firewall.Rule
+	for _, r := range c.Rules {
+		rules, err := manager.AddFiltering(net.ParseIP(r.Peer), r.Protocol, r.SPort, r.DPort, r.Direction, r.Action, r.IPSet, "")
+		require.NoError(t, err, "add rule")
+		added = append(added, rules)
+	}
+	require.NoError(t, manager.Flush(), "flush added rules")
+
+	for _, i := range c.Deleted {
+		for _, rule := range added[i] {
+			require.NoError(t, manager.DeleteRule(rule), "delete rule")
+		}
+	}
+	require.NoError(t, manager.Flush(), "flush deleted rules")
+
+	for _, pair := range c.Routes {
+		require.NoError(t, manager.InsertRoutingRules(pair), "insert routing rules")
+	}
+	if c.RemoveRoutes {
+		for _, pair := range c.Routes {
+			require.NoError(t, manager.RemoveRoutingRules(pair), "remove routing rules")
+		}
+	}
+}
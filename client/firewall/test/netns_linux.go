@@ -0,0 +1,290 @@
+//go:build !android
+
+package test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+
+	firewall "github.com/netbirdio/netbird/client/firewall/manager"
+	"github.com/netbirdio/netbird/iface"
+)
+
+const (
+	// IfaceName is the name of the interface filtered by the kernel firewall, it connects the peer namespace
+	IfaceName   = "nbconf0"
+	peerIface   = "nbconf1"
+	routerIface = "nbconf2"
+	routedIface = "nbconf3"
+	routerIP    = "10.10.0.1"
+
+	// dropTimeout is the time a packet has to arrive before it is considered dropped
+	dropTimeout = 100 * time.Millisecond
+)
+
+// NetnsNetwork checks the verdicts of a kernel firewall through network namespaces. The firewall is applied in a
+// new namespace entered by the calling thread, which reaches the peers through IfaceName and forwards the routed
+// traffic to another namespace. It implements the interface mapper of the kernel firewall managers
+type NetnsNetwork struct {
+	hostNS   netns.NsHandle
+	peerNS   netns.NsHandle
+	routedNS netns.NsHandle
+
+	hostSend int
+	peerSend int
+	// receivers are the raw sockets receiving the packets delivered in a namespace, by namespace and protocol
+	receivers map[netns.NsHandle]map[firewall.Protocol]int
+}
+
+// NewNetnsNetwork creates the namespaces of the network and moves the calling goroutine into the one of the
+// firewall. The goroutine stays locked to its thread, which exits with the goroutine, so the test must not start
+// goroutines using the firewall
+func NewNetnsNetwork(t *testing.T) *NetnsNetwork {
+	t.Helper()
+
+	if os.Geteuid() != 0 {
+		t.Skip("the kernel firewall conformance tests need root privileges")
+	}
+
+	// never unlocked, the thread in the test namespaces is terminated with the goroutine
+	runtime.LockOSThread()
+
+	n := &NetnsNetwork{
+		hostNS:    netns.None(),
+		peerNS:    netns.None(),
+		routedNS:  netns.None(),
+		receivers: make(map[netns.NsHandle]map[firewall.Protocol]int),
+	}
+	t.Cleanup(n.close)
+
+	var err error
+	n.hostNS, err = netns.New()
+	require.NoError(t, err, "create host namespace")
+	n.peerNS, err = netns.New()
+	require.NoError(t, err, "create peer namespace")
+	n.routedNS, err = netns.New()
+	require.NoError(t, err, "create routed namespace")
+	require.NoError(t, netns.Set(n.hostNS), "enter host namespace")
+
+	require.NoError(t, n.setupLinks(), "set up links")
+	require.NoError(t, os.WriteFile("/proc/sys/net/ipv4/ip_forward", []byte("1"), 0644), "enable forwarding")
+
+	n.hostSend, err = n.socket(n.hostNS, unix.IPPROTO_RAW)
+	require.NoError(t, err)
+	n.peerSend, err = n.socket(n.peerNS, unix.IPPROTO_RAW)
+	require.NoError(t, err)
+	for _, ns := range []netns.NsHandle{n.hostNS, n.peerNS, n.routedNS} {
+		n.receivers[ns] = make(map[firewall.Protocol]int)
+		for proto, number := range map[firewall.Protocol]int{
+			firewall.ProtocolTCP:  unix.IPPROTO_TCP,
+			firewall.ProtocolUDP:  unix.IPPROTO_UDP,
+			firewall.ProtocolICMP: unix.IPPROTO_ICMP,
+		} {
+			fd, err := n.socket(ns, number)
+			require.NoError(t, err)
+			n.receivers[ns][proto] = fd
+		}
+	}
+	return n
+}
+
+// Name returns the name of the filtered interface
+func (n *NetnsNetwork) Name() string {
+	return IfaceName
+}
+
+// Address returns the address of the filtered interface
+func (n *NetnsNetwork) Address() iface.WGAddress {
+	_, network, _ := net.ParseCIDR(WgNetwork)
+	return iface.WGAddress{IP: net.ParseIP(LocalIP), Network: network}
+}
+
+// IsUserspaceBind returns false, the kernel firewall filters the traffic
+func (n *NetnsNetwork) IsUserspaceBind() bool {
+	return false
+}
+
+// Passes sends the packet from its source namespace and waits for it in the namespace of its destination
+func (n *NetnsNetwork) Passes(t *testing.T, packet Packet) bool {
+	t.Helper()
+
+	// the payload identifies the packet, its addresses and ports may be translated
+	marker := make([]byte, 16)
+	_, err := rand.Read(marker)
+	require.NoError(t, err)
+	data, err := packet.Serialize(marker)
+	require.NoError(t, err)
+
+	sender, receiverNS := n.peerSend, n.hostNS
+	switch {
+	case packet.Direction == firewall.RuleDirectionOUT:
+		sender, receiverNS = n.hostSend, n.peerNS
+	case packet.Destination != "":
+		receiverNS = n.routedNS
+	}
+	receiver := n.receivers[receiverNS][packet.Protocol]
+
+	dst := unix.SockaddrInet4{}
+	copy(dst.Addr[:], packet.DstIP())
+	err = unix.Sendto(sender, data, 0, &dst)
+	if errors.Is(err, unix.EPERM) {
+		// the packets dropped by the output hook are reported to the sender
+		return false
+	}
+	require.NoError(t, err, "send packet %s", packet)
+
+	buf := make([]byte, 65535)
+	deadline := time.Now().Add(dropTimeout)
+	for time.Now().Before(deadline) {
+		size, _, err := unix.Recvfrom(receiver, buf, 0)
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			continue
+		}
+		require.NoError(t, err, "receive packet %s", packet)
+		if bytes.Contains(buf[:size], marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *NetnsNetwork) setupLinks() error {
+	_, wgNetwork, _ := net.ParseCIDR(WgNetwork)
+	_, routedNetwork, _ := net.ParseCIDR(RoutedNetwork)
+
+	links := []struct {
+		name, peer string
+		ns         netns.NsHandle
+		addrs      []string
+		peerAddrs  []string
+		peerRoute  *netlink.Route
+	}{
+		{
+			name:      IfaceName,
+			peer:      peerIface,
+			ns:        n.peerNS,
+			addrs:     []string{LocalIP + "/24"},
+			peerAddrs: []string{PeerIP + "/24", OtherPeerIP + "/32", ThirdPeerIP + "/32"},
+			peerRoute: &netlink.Route{Dst: routedNetwork, Gw: net.ParseIP(LocalIP)},
+		},
+		{
+			name:      routerIface,
+			peer:      routedIface,
+			ns:        n.routedNS,
+			addrs:     []string{routerIP + "/24"},
+			peerAddrs: []string{RoutedIP + "/24"},
+			peerRoute: &netlink.Route{Dst: wgNetwork, Gw: net.ParseIP(routerIP)},
+		},
+	}
+
+	host, err := netlink.NewHandleAt(n.hostNS)
+	if err != nil {
+		return err
+	}
+	defer host.Delete()
+
+	for _, l := range links {
+		if err := host.LinkAdd(&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: l.name}, PeerName: l.peer}); err != nil {
+			return fmt.Errorf("add veth %s: %w", l.name, err)
+		}
+		peer, err := host.LinkByName(l.peer)
+		if err != nil {
+			return err
+		}
+		if err := host.LinkSetNsFd(peer, int(l.ns)); err != nil {
+			return fmt.Errorf("move %s: %w", l.peer, err)
+		}
+		if err := configureLink(host, l.name, l.addrs, nil); err != nil {
+			return err
+		}
+
+		handle, err := netlink.NewHandleAt(l.ns)
+		if err != nil {
+			return err
+		}
+		err = configureLink(handle, l.peer, l.peerAddrs, l.peerRoute)
+		handle.Delete()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func configureLink(handle *netlink.Handle, name string, addrs []string, route *netlink.Route) error {
+	link, err := handle.LinkByName(name)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		parsed, err := netlink.ParseAddr(addr)
+		if err != nil {
+			return err
+		}
+		if err := handle.AddrAdd(link, parsed); err != nil {
+			return fmt.Errorf("add address %s to %s: %w", addr, name, err)
+		}
+	}
+	if err := handle.LinkSetUp(link); err != nil {
+		return fmt.Errorf("set %s up: %w", name, err)
+	}
+	if route == nil {
+		return nil
+	}
+	route.LinkIndex = link.Attrs().Index
+	if err := handle.RouteAdd(route); err != nil {
+		return fmt.Errorf("add route to %s: %w", route.Dst, err)
+	}
+	return nil
+}
+
+// socket opens a raw socket in the namespace, the sockets stay in the namespace they were created in
+func (n *NetnsNetwork) socket(ns netns.NsHandle, proto int) (int, error) {
+	if err := netns.Set(ns); err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = netns.Set(n.hostNS)
+	}()
+
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_RAW, proto)
+	if err != nil {
+		return 0, fmt.Errorf("open raw socket: %w", err)
+	}
+	timeout := unix.NsecToTimeval((10 * time.Millisecond).Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+		_ = unix.Close(fd)
+		return 0, fmt.Errorf("set receive timeout: %w", err)
+	}
+	return fd, nil
+}
+
+func (n *NetnsNetwork) close() {
+	for _, fd := range []int{n.hostSend, n.peerSend} {
+		if fd > 0 {
+			_ = unix.Close(fd)
+		}
+	}
+	for _, receivers := range n.receivers {
+		for _, fd := range receivers {
+			_ = unix.Close(fd)
+		}
+	}
+	for _, ns := range []netns.NsHandle{n.hostNS, n.peerNS, n.routedNS} {
+		if ns.IsOpen() {
+			_ = ns.Close()
+		}
+	}
+}
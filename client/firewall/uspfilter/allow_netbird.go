@@ -9,6 +9,13 @@ func (m *Manager) Reset() error {
 
 	m.outgoingRules = make(map[string]RuleSet)
 	m.incomingRules = make(map[string]RuleSet)
+	m.pendingAdd = make(map[string]Rule)
+	m.pendingDelete = make(map[string]Rule)
+	m.connTracker.Close()
+
+	if err := m.resetRouting(); err != nil {
+		return err
+	}
 
 	if m.nativeFirewall != nil {
 		return m.nativeFirewall.Reset()
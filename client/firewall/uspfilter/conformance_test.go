@@ -0,0 +1,51 @@
+package uspfilter
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	fw "github.com/netbirdio/netbird/client/firewall/manager"
+	"github.com/netbirdio/netbird/client/firewall/test"
+	"github.com/netbirdio/netbird/iface"
+)
+
+// conformanceNetwork passes the conformance packets to the filter directly
+type conformanceNetwork struct {
+	manager *Manager
+}
+
+func (n *conformanceNetwork) Passes(t *testing.T, packet test.Packet) bool {
+	data, err := packet.Serialize(nil)
+	require.NoError(t, err)
+
+	if packet.Direction == fw.RuleDirectionOUT {
+		return !n.manager.DropOutgoing(data)
+	}
+	return !n.manager.DropIncoming(data)
+}
+
+func TestUSPFilterConformance(t *testing.T) {
+	_, wgNetwork, err := net.ParseCIDR(test.WgNetwork)
+	require.NoError(t, err)
+
+	test.RunConformance(t, test.Backend{
+		Setup: func(t *testing.T) (fw.Manager, test.Network) {
+			ifaceMock := &forwardingIFaceMock{
+				IFaceMock: IFaceMock{
+					SetFilterFunc: func(iface.PacketFilter) error { return nil },
+				},
+				forwarder: &forwarderMock{},
+			}
+
+			manager, err := Create(ifaceMock)
+			require.NoError(t, err, "create manager")
+			manager.SetNetwork(wgNetwork)
+			t.Cleanup(func() {
+				require.NoError(t, manager.Reset(), "reset manager")
+			})
+			return manager, &conformanceNetwork{manager: manager}
+		},
+	})
+}
@@ -0,0 +1,211 @@
+// Package conntrack tracks the flows allowed by the userspace packet filter, so that the packets of established flows
+// can be accepted in both directions without evaluating the filtering rules again.
+package conntrack
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxEntries is the default maximum number of flows tracked at the same time
+	DefaultMaxEntries = 65536
+
+	// gcInterval is the interval of the removal of the expired flows
+	gcInterval = 10 * time.Second
+)
+
+// Protocol is the IP protocol number of a flow
+type Protocol uint8
+
+const (
+	ProtocolICMP   Protocol = 1
+	ProtocolTCP    Protocol = 6
+	ProtocolUDP    Protocol = 17
+	ProtocolICMPv6 Protocol = 58
+)
+
+// ConnKey identifies a flow by the address and the port of its initiator (Src) and of its responder (Dst).
+// ICMP echo flows use the echo identifier as both ports
+type ConnKey struct {
+	Proto   Protocol
+	SrcIP   netip.Addr
+	DstIP   netip.Addr
+	SrcPort uint16
+	DstPort uint16
+}
+
+// NewConnKey returns the key of the flow of a packet from srcIP:srcPort to dstIP:dstPort
+func NewConnKey(proto Protocol, srcIP, dstIP net.IP, srcPort, dstPort uint16) ConnKey {
+	return ConnKey{
+		Proto:   proto,
+		SrcIP:   toAddr(srcIP),
+		DstIP:   toAddr(dstIP),
+		SrcPort: srcPort,
+		DstPort: dstPort,
+	}
+}
+
+// Reverse returns the key of the packets sent in the opposite direction of the flow
+func (k ConnKey) Reverse() ConnKey {
+	return ConnKey{
+		Proto:   k.Proto,
+		SrcIP:   k.DstIP,
+		DstIP:   k.SrcIP,
+		SrcPort: k.DstPort,
+		DstPort: k.SrcPort,
+	}
+}
+
+// conn is the state of a tracked flow
+type conn struct {
+	tcpState TCPState
+	// finSent records the FIN packets seen from the initiator (index 0) and from the responder (index 1)
+	finSent [2]bool
+	// replied is true once a packet of the responder has been seen
+	replied bool
+	// incoming is true if the initiator is the remote peer, the flow was allowed by the incoming rules
+	incoming bool
+	lastSeen time.Time
+}
+
+// Tracker is a bounded table of the flows allowed by the packet filter
+type Tracker struct {
+	mu         sync.Mutex
+	conns      map[ConnKey]*conn
+	maxEntries int
+
+	now       func() time.Time
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTracker returns a tracker of at most maxEntries flows and starts the removal of the expired flows
+func NewTracker(maxEntries int) *Tracker {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+
+	t := &Tracker{
+		conns:      make(map[ConnKey]*conn),
+		maxEntries: maxEntries,
+		now:        time.Now,
+		done:       make(chan struct{}),
+	}
+	go t.gcLoop()
+
+	return t
+}
+
+// Len returns the number of flows in the table, including the expired flows that have not been removed yet
+func (t *Tracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.conns)
+}
+
+// Close stops the removal of the expired flows and clears the table.
+// Expired flows are still ignored if the tracker is used afterward
+func (t *Tracker) Close() {
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+
+	t.mu.Lock()
+	t.conns = make(map[ConnKey]*conn)
+	t.mu.Unlock()
+}
+
+// lookup returns the live flow of a packet with the given key and whether the packet was sent by the responder.
+// It has to be called with t.mu held
+func (t *Tracker) lookup(key ConnKey, now time.Time) (*conn, bool) {
+	if c, ok := t.conns[key]; ok && !c.expired(key.Proto, now) {
+		return c, false
+	}
+	if c, ok := t.conns[key.Reverse()]; ok && !c.expired(key.Proto, now) {
+		return c, true
+	}
+	return nil, false
+}
+
+// add inserts a new flow if the table has room for it. It has to be called with t.mu held
+func (t *Tracker) add(key ConnKey, c *conn) bool {
+	if existing, ok := t.conns[key]; !ok || existing.expired(key.Proto, c.lastSeen) {
+		if len(t.conns) >= t.maxEntries {
+			t.removeExpired(c.lastSeen)
+			if len(t.conns) >= t.maxEntries {
+				return false
+			}
+		}
+	}
+
+	delete(t.conns, key.Reverse())
+	t.conns[key] = c
+	return true
+}
+
+// Retain removes the flows for which keep returns false, keep is called with the key of the flow and whether it
+// was started by an incoming packet. It returns the number of removed flows
+func (t *Tracker) Retain(keep func(key ConnKey, incoming bool) bool) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	removed := 0
+	for key, c := range t.conns {
+		if !keep(key, c.incoming) {
+			delete(t.conns, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (t *Tracker) gcLoop() {
+	ticker := time.NewTicker(gcInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			t.mu.Lock()
+			t.removeExpired(t.now())
+			t.mu.Unlock()
+		}
+	}
+}
+
+// removeExpired removes the expired flows from the table. It has to be called with t.mu held
+func (t *Tracker) removeExpired(now time.Time) {
+	for key, c := range t.conns {
+		if c.expired(key.Proto, now) {
+			delete(t.conns, key)
+		}
+	}
+}
+
+func (c *conn) expired(proto Protocol, now time.Time) bool {
+	var timeout time.Duration
+	switch proto {
+	case ProtocolTCP:
+		timeout = c.tcpState.timeout()
+	case ProtocolUDP:
+		timeout = UDPTimeout
+		if c.replied {
+			timeout = UDPStreamTimeout
+		}
+	default:
+		timeout = ICMPTimeout
+	}
+
+	return now.Sub(c.lastSeen) > timeout
+}
+
+func toAddr(ip net.IP) netip.Addr {
+	addr, _ := netip.AddrFromSlice(ip)
+	return addr.Unmap()
+}
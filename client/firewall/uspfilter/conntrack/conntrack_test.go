@@ -0,0 +1,160 @@
+package conntrack
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	initiatorIP = net.ParseIP("100.64.0.1")
+	responderIP = net.ParseIP("100.64.0.2")
+)
+
+// newTestTracker returns a tracker whose clock is advanced manually
+func newTestTracker(t *testing.T, maxEntries int) (*Tracker, *time.Time) {
+	t.Helper()
+
+	tracker := NewTracker(maxEntries)
+	t.Cleanup(tracker.Close)
+
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+
+	return tracker, &now
+}
+
+func TestTracker_TCPHandshakeAndClose(t *testing.T) {
+	tracker, _ := newTestTracker(t, 0)
+
+	out := NewConnKey(ProtocolTCP, initiatorIP, responderIP, 40000, 80)
+	in := out.Reverse()
+
+	assert.False(t, tracker.MatchTCP(out, TCPSyn), "untracked flow shouldn't match")
+	require.True(t, tracker.TrackTCP(out, TCPSyn, false))
+
+	assert.False(t, tracker.MatchTCP(in, TCPAck), "responder should answer the SYN with a SYN-ACK")
+	assert.True(t, tracker.MatchTCP(in, TCPSyn|TCPAck))
+	assert.True(t, tracker.MatchTCP(out, TCPAck))
+	assertTCPState(t, tracker, out, TCPStateEstablished)
+
+	assert.True(t, tracker.MatchTCP(in, TCPAck))
+	assert.False(t, tracker.MatchTCP(in, TCPSyn), "SYN isn't valid on an established flow")
+
+	assert.True(t, tracker.MatchTCP(out, TCPFin|TCPAck))
+	assertTCPState(t, tracker, out, TCPStateFinWait)
+	assert.True(t, tracker.MatchTCP(in, TCPFin|TCPAck))
+	assertTCPState(t, tracker, out, TCPStateTimeWait)
+	assert.True(t, tracker.MatchTCP(out, TCPAck), "late ACKs should be accepted")
+}
+
+func TestTracker_TCPReset(t *testing.T) {
+	tracker, now := newTestTracker(t, 0)
+
+	out := NewConnKey(ProtocolTCP, initiatorIP, responderIP, 40000, 80)
+	require.True(t, tracker.TrackTCP(out, TCPSyn, false))
+
+	assert.True(t, tracker.MatchTCP(out.Reverse(), TCPRst|TCPAck), "responder should be able to reset the flow")
+	assertTCPState(t, tracker, out, TCPStateClosed)
+	assert.False(t, tracker.MatchTCP(out.Reverse(), TCPAck), "reset flow shouldn't match")
+
+	assert.True(t, tracker.MatchTCP(out, TCPSyn), "initiator should be able to reopen the flow")
+	assertTCPState(t, tracker, out, TCPStateSynSent)
+
+	assert.True(t, tracker.MatchTCP(out, TCPRst))
+	*now = now.Add(TCPClosedTimeout + time.Second)
+	_, found := tracker.TCPState(out)
+	assert.False(t, found, "closed flow should expire")
+}
+
+func TestTracker_TCPMidStreamPickup(t *testing.T) {
+	tracker, _ := newTestTracker(t, 0)
+
+	out := NewConnKey(ProtocolTCP, initiatorIP, responderIP, 40000, 80)
+	require.True(t, tracker.TrackTCP(out, TCPAck, false))
+	assertTCPState(t, tracker, out, TCPStateEstablished)
+	assert.True(t, tracker.MatchTCP(out.Reverse(), TCPAck))
+
+	other := NewConnKey(ProtocolTCP, initiatorIP, responderIP, 40001, 80)
+	require.True(t, tracker.TrackTCP(other, TCPRst, false))
+	_, found := tracker.TCPState(other)
+	assert.False(t, found, "resets shouldn't start flows")
+}
+
+func TestTracker_UDP(t *testing.T) {
+	tracker, now := newTestTracker(t, 0)
+
+	out := NewConnKey(ProtocolUDP, initiatorIP, responderIP, 40000, 53)
+	require.True(t, tracker.TrackUDP(out, false))
+
+	other := NewConnKey(ProtocolUDP, responderIP, initiatorIP, 53, 40001)
+	assert.False(t, tracker.MatchUDP(other), "packets of other flows shouldn't match")
+
+	*now = now.Add(UDPTimeout - time.Second)
+	assert.True(t, tracker.MatchUDP(out.Reverse()), "reply should match")
+
+	*now = now.Add(UDPTimeout + time.Second)
+	assert.True(t, tracker.MatchUDP(out), "flows with replies should live longer")
+
+	*now = now.Add(UDPStreamTimeout + time.Second)
+	assert.False(t, tracker.MatchUDP(out.Reverse()), "flow should expire")
+}
+
+func TestTracker_ICMPEcho(t *testing.T) {
+	tracker, now := newTestTracker(t, 0)
+
+	request := NewConnKey(ProtocolICMP, initiatorIP, responderIP, 7, 7)
+	require.True(t, tracker.TrackICMPEcho(request, false))
+
+	assert.True(t, tracker.MatchICMPEcho(request.Reverse(), false))
+	assert.True(t, tracker.MatchICMPEcho(request, true), "next requests should match")
+	assert.False(t, tracker.MatchICMPEcho(request.Reverse(), true), "requests of the responder shouldn't match")
+	assert.False(t, tracker.MatchICMPEcho(NewConnKey(ProtocolICMP, responderIP, initiatorIP, 8, 8), false),
+		"replies with another identifier shouldn't match")
+
+	*now = now.Add(ICMPTimeout + time.Second)
+	assert.False(t, tracker.MatchICMPEcho(request.Reverse(), false), "flow should expire")
+}
+
+func TestTracker_Bounded(t *testing.T) {
+	tracker, now := newTestTracker(t, 2)
+
+	require.True(t, tracker.TrackUDP(NewConnKey(ProtocolUDP, initiatorIP, responderIP, 1, 53), false))
+	require.True(t, tracker.TrackUDP(NewConnKey(ProtocolUDP, initiatorIP, responderIP, 2, 53), false))
+	assert.False(t, tracker.TrackUDP(NewConnKey(ProtocolUDP, initiatorIP, responderIP, 3, 53), false), "table should be full")
+	assert.True(t, tracker.TrackUDP(NewConnKey(ProtocolUDP, initiatorIP, responderIP, 1, 53), false),
+		"tracked flows should be refreshed when the table is full")
+
+	*now = now.Add(UDPTimeout + time.Second)
+	assert.True(t, tracker.TrackUDP(NewConnKey(ProtocolUDP, initiatorIP, responderIP, 3, 53), false),
+		"expired flows should make room for new ones")
+	assert.Equal(t, 1, tracker.Len())
+}
+
+func TestTracker_Retain(t *testing.T) {
+	tracker, _ := newTestTracker(t, 0)
+
+	out := NewConnKey(ProtocolTCP, initiatorIP, responderIP, 40000, 80)
+	in := NewConnKey(ProtocolUDP, responderIP, initiatorIP, 40000, 53)
+	require.True(t, tracker.TrackTCP(out, TCPSyn, false))
+	require.True(t, tracker.TrackUDP(in, true))
+
+	removed := tracker.Retain(func(key ConnKey, incoming bool) bool {
+		assert.Equal(t, key == in, incoming, "flow %v should keep its direction", key)
+		return key.Proto == ProtocolTCP
+	})
+	assert.Equal(t, 1, removed)
+	assert.True(t, tracker.MatchTCP(out.Reverse(), TCPSyn|TCPAck), "retained flow should still match")
+	assert.False(t, tracker.MatchUDP(in.Reverse()), "removed flow shouldn't match")
+}
+
+func assertTCPState(t *testing.T, tracker *Tracker, key ConnKey, expected TCPState) {
+	t.Helper()
+
+	state, found := tracker.TCPState(key)
+	require.True(t, found, "flow should be tracked")
+	assert.Equal(t, expected, state, "expected state %s, got %s", expected, state)
+}
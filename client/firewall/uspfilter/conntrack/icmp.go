@@ -0,0 +1,32 @@
+package conntrack
+
+import (
+	"time"
+)
+
+// ICMPTimeout is the timeout of the ICMP echo flows
+const ICMPTimeout = 30 * time.Second
+
+// MatchICMPEcho returns true if the ICMP echo request of the initiator or the echo reply of the responder belongs to a
+// tracked flow. The key of echo packets uses the echo identifier as both ports
+func (t *Tracker) MatchICMPEcho(key ConnKey, request bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	c, reply := t.lookup(key, now)
+	if c == nil || reply == request {
+		return false
+	}
+
+	c.replied = c.replied || reply
+	c.lastSeen = now
+
+	return true
+}
+
+// TrackICMPEcho starts tracking the flow of an ICMP echo request allowed by the filtering rules, so that its replies
+// are accepted. It returns false if the table is full
+func (t *Tracker) TrackICMPEcho(key ConnKey, incoming bool) bool {
+	return t.trackPseudoFlow(key, incoming)
+}
@@ -0,0 +1,198 @@
+package conntrack
+
+import (
+	"time"
+)
+
+// TCP flags of the packets as encoded in the TCP header
+const (
+	TCPFin uint8 = 0x01
+	TCPSyn uint8 = 0x02
+	TCPRst uint8 = 0x04
+	TCPAck uint8 = 0x10
+)
+
+// Timeouts of the TCP flows by state. They follow the defaults of the Linux connection tracker, except for the
+// established flows that expire after a day instead of five days
+const (
+	TCPSynSentTimeout     = 2 * time.Minute
+	TCPSynReceivedTimeout = time.Minute
+	TCPEstablishedTimeout = 24 * time.Hour
+	TCPFinWaitTimeout     = 2 * time.Minute
+	TCPTimeWaitTimeout    = 2 * time.Minute
+	TCPClosedTimeout      = 10 * time.Second
+)
+
+// TCPState is the state of a tracked TCP flow
+type TCPState uint8
+
+const (
+	// TCPStateSynSent is the state of a flow after the SYN of the initiator
+	TCPStateSynSent TCPState = iota
+	// TCPStateSynReceived is the state of a flow after the SYN-ACK of the responder
+	TCPStateSynReceived
+	// TCPStateEstablished is the state of a flow after the handshake or of a flow picked up mid-stream
+	TCPStateEstablished
+	// TCPStateFinWait is the state of a flow closed by one of the sides
+	TCPStateFinWait
+	// TCPStateTimeWait is the state of a flow closed by both sides
+	TCPStateTimeWait
+	// TCPStateClosed is the state of a reset flow
+	TCPStateClosed
+)
+
+func (s TCPState) String() string {
+	switch s {
+	case TCPStateSynSent:
+		return "SYN_SENT"
+	case TCPStateSynReceived:
+		return "SYN_RECEIVED"
+	case TCPStateEstablished:
+		return "ESTABLISHED"
+	case TCPStateFinWait:
+		return "FIN_WAIT"
+	case TCPStateTimeWait:
+		return "TIME_WAIT"
+	case TCPStateClosed:
+		return "CLOSED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+func (s TCPState) timeout() time.Duration {
+	switch s {
+	case TCPStateSynSent:
+		return TCPSynSentTimeout
+	case TCPStateSynReceived:
+		return TCPSynReceivedTimeout
+	case TCPStateEstablished:
+		return TCPEstablishedTimeout
+	case TCPStateFinWait:
+		return TCPFinWaitTimeout
+	case TCPStateTimeWait:
+		return TCPTimeWaitTimeout
+	default:
+		return TCPClosedTimeout
+	}
+}
+
+// MatchTCP returns true if the TCP packet belongs to a tracked flow and is valid in its state.
+// The state of the flow is updated with the packet
+func (t *Tracker) MatchTCP(key ConnKey, flags uint8) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	c, reply := t.lookup(key, now)
+	if c == nil {
+		return false
+	}
+
+	if !c.updateTCP(reply, flags) {
+		return false
+	}
+	c.lastSeen = now
+
+	return true
+}
+
+// TrackTCP starts tracking the flow of a TCP packet allowed by the filtering rules. A SYN starts the handshake,
+// other packets are picked up as established flows. Resets and FINs don't start flows.
+// It returns false if the table is full
+func (t *Tracker) TrackTCP(key ConnKey, flags uint8, incoming bool) bool {
+	if flags&(TCPRst|TCPFin) != 0 {
+		return true
+	}
+
+	state := TCPStateEstablished
+	if flags&(TCPSyn|TCPAck) == TCPSyn {
+		state = TCPStateSynSent
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.add(key, &conn{tcpState: state, incoming: incoming, lastSeen: t.now()})
+}
+
+// updateTCP moves the flow to its next state and returns false if the packet isn't valid in the current state
+func (c *conn) updateTCP(reply bool, flags uint8) bool {
+	if flags&TCPRst != 0 {
+		if c.tcpState == TCPStateClosed {
+			return false
+		}
+		c.tcpState = TCPStateClosed
+		return true
+	}
+
+	side := 0
+	if reply {
+		side = 1
+	}
+
+	switch c.tcpState {
+	case TCPStateSynSent:
+		if !reply {
+			// retransmitted SYN
+			return flags&TCPSyn != 0
+		}
+		if flags&(TCPSyn|TCPAck) != TCPSyn|TCPAck {
+			return false
+		}
+		c.tcpState = TCPStateSynReceived
+		c.replied = true
+	case TCPStateSynReceived:
+		if reply {
+			// retransmitted SYN-ACK
+			return flags&TCPAck != 0
+		}
+		if flags&TCPAck == 0 {
+			return false
+		}
+		c.tcpState = TCPStateEstablished
+		c.updateFin(side, flags)
+	case TCPStateEstablished, TCPStateFinWait:
+		if flags&TCPSyn != 0 {
+			return false
+		}
+		c.replied = c.replied || reply
+		c.updateFin(side, flags)
+	case TCPStateTimeWait:
+		// late ACKs and retransmitted FINs
+		return flags&TCPSyn == 0
+	case TCPStateClosed:
+		if reply || flags&(TCPSyn|TCPAck) != TCPSyn {
+			return false
+		}
+		// the initiator reopens the flow with the same ports
+		*c = conn{tcpState: TCPStateSynSent, incoming: c.incoming}
+	}
+
+	return true
+}
+
+func (c *conn) updateFin(side int, flags uint8) {
+	if flags&TCPFin == 0 {
+		return
+	}
+
+	c.finSent[side] = true
+	if c.finSent[0] && c.finSent[1] {
+		c.tcpState = TCPStateTimeWait
+	} else {
+		c.tcpState = TCPStateFinWait
+	}
+}
+
+// TCPState returns the state of the tracked flow of the packet with the given key
+func (t *Tracker) TCPState(key ConnKey) (TCPState, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, _ := t.lookup(key, t.now())
+	if c == nil {
+		return 0, false
+	}
+	return c.tcpState, true
+}
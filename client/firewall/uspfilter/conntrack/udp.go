@@ -0,0 +1,47 @@
+package conntrack
+
+import (
+	"time"
+)
+
+const (
+	// UDPTimeout is the timeout of the UDP flows without replies
+	UDPTimeout = 30 * time.Second
+	// UDPStreamTimeout is the timeout of the UDP flows once the responder replied
+	UDPStreamTimeout = 3 * time.Minute
+)
+
+// MatchUDP returns true if the UDP packet belongs to a tracked flow
+func (t *Tracker) MatchUDP(key ConnKey) bool {
+	return t.matchPseudoFlow(key)
+}
+
+// TrackUDP starts tracking the flow of a UDP packet allowed by the filtering rules.
+// It returns false if the table is full
+func (t *Tracker) TrackUDP(key ConnKey, incoming bool) bool {
+	return t.trackPseudoFlow(key, incoming)
+}
+
+// matchPseudoFlow matches the packets of the connectionless protocols, whose flows only expire
+func (t *Tracker) matchPseudoFlow(key ConnKey) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	c, reply := t.lookup(key, now)
+	if c == nil {
+		return false
+	}
+
+	c.replied = c.replied || reply
+	c.lastSeen = now
+
+	return true
+}
+
+func (t *Tracker) trackPseudoFlow(key ConnKey, incoming bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.add(key, &conn{incoming: incoming, lastSeen: t.now()})
+}
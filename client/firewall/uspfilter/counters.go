@@ -0,0 +1,96 @@
+package uspfilter
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	firewall "github.com/netbirdio/netbird/client/firewall/manager"
+)
+
+// ruleCounters counts the packets matched by a rule. It is shared by the copies of the rule
+type ruleCounters struct {
+	packets atomic.Uint64
+	bytes   atomic.Uint64
+}
+
+func (c *ruleCounters) add(size int) {
+	c.packets.Add(1)
+	c.bytes.Add(uint64(size))
+}
+
+func (c *ruleCounters) get() firewall.Counters {
+	return firewall.Counters{
+		Packets: c.packets.Load(),
+		Bytes:   c.bytes.Load(),
+	}
+}
+
+// dropLogger logs the dropped packets, at most firewall.DropLogRate per second
+type dropLogger struct {
+	mu          sync.Mutex
+	windowStart time.Time
+	logged      int
+}
+
+func (l *dropLogger) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.windowStart) >= time.Second {
+		l.windowStart = now
+		l.logged = 0
+	}
+	if l.logged >= firewall.DropLogRate {
+		return false
+	}
+	l.logged++
+	return true
+}
+
+// countDropped counts a packet dropped by the default policy and logs it if enabled
+func (m *Manager) countDropped(d *decoder, srcIP, dstIP net.IP, size int, isIncomingPacket bool) {
+	direction := "out"
+	if isIncomingPacket {
+		m.droppedIn.add(size)
+		direction = "in"
+	} else {
+		m.droppedOut.add(size)
+	}
+
+	if m.dropLog == nil || !m.dropLog.allow() {
+		return
+	}
+	log.Infof("firewall dropped %s packet: proto %s, src %s, dst %s, length %d",
+		direction, d.decoded[1], srcIP, dstIP, size)
+}
+
+// GetRuleCounters returns the traffic matched by the filtering rules and dropped by the default policy.
+// Only the packets evaluated against the rules are counted, not the ones accepted by the connection tracker.
+// Packet hooks aren't reported
+func (m *Manager) GetRuleCounters() (*firewall.RuleCounters, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	counters := &firewall.RuleCounters{
+		Rules:      make(map[string]firewall.Counters),
+		DroppedIn:  m.droppedIn.get(),
+		DroppedOut: m.droppedOut.get(),
+	}
+	for _, rules := range []map[string]RuleSet{m.incomingRules, m.outgoingRules} {
+		for _, ruleSet := range rules {
+			for id, rule := range ruleSet {
+				if rule.counters == nil {
+					continue
+				}
+				counters.Rules[id] = rule.counters.get()
+			}
+		}
+	}
+
+	return counters, nil
+}
@@ -0,0 +1,132 @@
+package uspfilter
+
+import (
+	"fmt"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	firewall "github.com/netbirdio/netbird/client/firewall/manager"
+	"github.com/netbirdio/netbird/iface"
+)
+
+// forwardingIFace is implemented by the interfaces able to forward the routed traffic in userspace
+type forwardingIFace interface {
+	GetForwarder() iface.Forwarder
+}
+
+// IsServerRouteSupported returns true if the routed traffic is forwarded in userspace or by the native firewall
+func (m *Manager) IsServerRouteSupported() bool {
+	return m.forwarder != nil || m.nativeFirewall != nil
+}
+
+// InsertRoutingRules allows forwarding the traffic of the peers to the destination of the pair. The userspace
+// forwarder masquerades all the routed traffic
+func (m *Manager) InsertRoutingRules(pair firewall.RouterPair) error {
+	if m.forwarder == nil {
+		if m.nativeFirewall == nil {
+			return errRouteNotSupported
+		}
+		return m.nativeFirewall.InsertRoutingRules(pair)
+	}
+
+	_, destination, err := net.ParseCIDR(pair.Destination)
+	if err != nil {
+		return fmt.Errorf("parse route destination %s: %w", pair.Destination, err)
+	}
+	if !pair.Masquerade {
+		log.Warnf("route %s to %s isn't masqueraded, but the userspace forwarder always masquerades the routed traffic",
+			pair.ID, pair.Destination)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.routes) == 0 {
+		if err := m.forwarder.Enable(); err != nil {
+			return fmt.Errorf("enable forwarding: %w", err)
+		}
+	}
+	m.routes[pair.ID] = destination
+	return nil
+}
+
+// RemoveRoutingRules removes a routing firewall rule
+func (m *Manager) RemoveRoutingRules(pair firewall.RouterPair) error {
+	if m.forwarder == nil {
+		if m.nativeFirewall == nil {
+			return errRouteNotSupported
+		}
+		return m.nativeFirewall.RemoveRoutingRules(pair)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.routes[pair.ID]; !ok {
+		return nil
+	}
+	delete(m.routes, pair.ID)
+
+	if len(m.routes) == 0 {
+		if err := m.forwarder.Disable(); err != nil {
+			return fmt.Errorf("disable forwarding: %w", err)
+		}
+	}
+	return nil
+}
+
+// resetRouting removes the routes and stops forwarding, the caller has to hold the lock
+func (m *Manager) resetRouting() error {
+	if m.forwarder == nil || len(m.routes) == 0 {
+		return nil
+	}
+
+	m.routes = make(map[string]*net.IPNet)
+	if err := m.forwarder.Disable(); err != nil {
+		return fmt.Errorf("disable forwarding: %w", err)
+	}
+	return nil
+}
+
+// filterRouted decides on the packets not addressed between the peers. It returns true if the packet is routed
+// by this peer and has to be evaluated against the rules, and whether the packet has to be dropped otherwise
+func (m *Manager) filterRouted(d *decoder, srcIP, dstIP net.IP, size int, isIncomingPacket bool) (bool, bool) {
+	switch {
+	case m.forwarder == nil:
+		// routed traffic is handled by the kernel
+		return false, false
+	case isIncomingPacket && !m.wgNetwork.Contains(dstIP):
+		// traffic of the peers to the routed networks, filtered by the rules of the source peer
+		if m.wgNetwork.Contains(srcIP) && m.isRouted(dstIP) {
+			return true, false
+		}
+		m.countDropped(d, srcIP, dstIP, size, isIncomingPacket)
+		return false, true
+	case !isIncomingPacket && !m.wgNetwork.Contains(srcIP):
+		// replies from the routed networks, accepted only for the flows allowed earlier
+		if m.isTrackedConnection(d, srcIP, dstIP) {
+			return false, false
+		}
+		m.countDropped(d, srcIP, dstIP, size, isIncomingPacket)
+		return false, true
+	default:
+		// traffic of the networks routed by other peers
+		return false, false
+	}
+}
+
+// isRouted returns true if the address is in a routed network. The host-local addresses are never routed, like the
+// kernel drops the routed packets to them, even if a route like 0.0.0.0/0 contains them
+func (m *Manager) isRouted(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, destination := range m.routes {
+		if destination.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
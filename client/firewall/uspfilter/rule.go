@@ -16,10 +16,13 @@ type Rule struct {
 	matchByIP  bool
 	protoLayer gopacket.LayerType
 	direction  firewall.RuleDirection
-	sPort      uint16
-	dPort      uint16
-	drop       bool
-	comment    string
+	// sPort and dPort are nil if the rule matches any port
+	sPort   *firewall.Port
+	dPort   *firewall.Port
+	drop    bool
+	comment string
+	// counters is nil for the packet hooks
+	counters *ruleCounters
 
 	udpHook func([]byte) bool
 }
@@ -28,3 +31,8 @@ type Rule struct {
 func (r *Rule) GetRuleID() string {
 	return r.id
 }
+
+// matchPorts returns true if the source and destination ports of the packet match the ports of the rule
+func (r *Rule) matchPorts(srcPort, dstPort uint16) bool {
+	return (r.sPort == nil || r.sPort.Match(int(srcPort))) && (r.dPort == nil || r.dPort.Match(int(dstPort)))
+}
@@ -0,0 +1,162 @@
+package uspfilter
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/netbirdio/netbird/client/firewall/uspfilter/conntrack"
+)
+
+// isTrackedConnection returns true if the packet belongs to a flow previously allowed by the rules in any direction
+func (m *Manager) isTrackedConnection(d *decoder, srcIP, dstIP net.IP) bool {
+	switch d.decoded[1] {
+	case layers.LayerTypeTCP:
+		key := conntrack.NewConnKey(conntrack.ProtocolTCP, srcIP, dstIP, uint16(d.tcp.SrcPort), uint16(d.tcp.DstPort))
+		return m.connTracker.MatchTCP(key, tcpFlags(&d.tcp))
+	case layers.LayerTypeUDP:
+		key := conntrack.NewConnKey(conntrack.ProtocolUDP, srcIP, dstIP, uint16(d.udp.SrcPort), uint16(d.udp.DstPort))
+		return m.connTracker.MatchUDP(key)
+	case layers.LayerTypeICMPv4, layers.LayerTypeICMPv6:
+		key, request, ok := icmpEchoKey(d, srcIP, dstIP)
+		if !ok {
+			return false
+		}
+		return m.connTracker.MatchICMPEcho(key, request)
+	}
+
+	return false
+}
+
+// trackConnection starts tracking the flow of a packet allowed by the rules, so that the replies are accepted
+func (m *Manager) trackConnection(d *decoder, srcIP, dstIP net.IP, isIncomingPacket bool) {
+	tracked := true
+	switch d.decoded[1] {
+	case layers.LayerTypeTCP:
+		key := conntrack.NewConnKey(conntrack.ProtocolTCP, srcIP, dstIP, uint16(d.tcp.SrcPort), uint16(d.tcp.DstPort))
+		tracked = m.connTracker.TrackTCP(key, tcpFlags(&d.tcp), isIncomingPacket)
+	case layers.LayerTypeUDP:
+		key := conntrack.NewConnKey(conntrack.ProtocolUDP, srcIP, dstIP, uint16(d.udp.SrcPort), uint16(d.udp.DstPort))
+		tracked = m.connTracker.TrackUDP(key, isIncomingPacket)
+	case layers.LayerTypeICMPv4, layers.LayerTypeICMPv6:
+		// only echo requests start flows, other ICMP messages are filtered by the rules only
+		if key, request, ok := icmpEchoKey(d, srcIP, dstIP); ok && request {
+			tracked = m.connTracker.TrackICMPEcho(key, isIncomingPacket)
+		}
+	}
+
+	if !tracked {
+		log.Tracef("connection tracking table is full, not tracking packet from %s to %s", srcIP, dstIP)
+	}
+}
+
+// removeDeniedFlows stops tracking the flows whose first packet isn't allowed by the active rules anymore, so that
+// deleting a rule also cuts the flows it allowed. The caller has to hold the lock
+func (m *Manager) removeDeniedFlows() {
+	removed := m.connTracker.Retain(func(key conntrack.ConnKey, incoming bool) bool {
+		if incoming {
+			return flowAllowed(net.IP(key.SrcIP.AsSlice()), key, m.incomingRules)
+		}
+		return flowAllowed(net.IP(key.DstIP.AsSlice()), key, m.outgoingRules)
+	})
+	if removed > 0 {
+		log.Debugf("stopped tracking %d flows not allowed by the rules anymore", removed)
+	}
+}
+
+// flowAllowed returns true if the rules allow the first packet of the flow like matchRules would. The rules with
+// packet hooks are skipped, the flows of their packets aren't tracked
+func flowAllowed(ip net.IP, key conntrack.ConnKey, rules map[string]RuleSet) bool {
+	var protoLayer gopacket.LayerType
+	switch key.Proto {
+	case conntrack.ProtocolTCP:
+		protoLayer = layers.LayerTypeTCP
+	case conntrack.ProtocolUDP:
+		protoLayer = layers.LayerTypeUDP
+	case conntrack.ProtocolICMP:
+		protoLayer = layers.LayerTypeICMPv4
+	case conntrack.ProtocolICMPv6:
+		protoLayer = layers.LayerTypeICMPv6
+	}
+
+	for _, setKey := range []string{ip.String(), "0.0.0.0", "::"} {
+		for _, rule := range rules[setKey] {
+			if rule.udpHook != nil || rule.matchByIP && !ip.Equal(rule.ip) {
+				continue
+			}
+			if rule.protoLayer != layerTypeAll {
+				if rule.protoLayer != protoLayer {
+					continue
+				}
+				// the echo identifier of the ICMP flows isn't a port
+				if key.Proto == conntrack.ProtocolTCP || key.Proto == conntrack.ProtocolUDP {
+					if !rule.matchPorts(key.SrcPort, key.DstPort) {
+						continue
+					}
+				}
+			}
+			return !rule.drop
+		}
+	}
+	return false
+}
+
+// icmpEchoKey returns the flow key of an ICMP echo request or reply and whether it is a request
+func icmpEchoKey(d *decoder, srcIP, dstIP net.IP) (conntrack.ConnKey, bool, bool) {
+	var (
+		proto   conntrack.Protocol
+		id      uint16
+		request bool
+	)
+
+	switch d.decoded[1] {
+	case layers.LayerTypeICMPv4:
+		switch d.icmp4.TypeCode.Type() {
+		case layers.ICMPv4TypeEchoRequest:
+			request = true
+		case layers.ICMPv4TypeEchoReply:
+		default:
+			return conntrack.ConnKey{}, false, false
+		}
+		proto = conntrack.ProtocolICMP
+		id = d.icmp4.Id
+	case layers.LayerTypeICMPv6:
+		switch d.icmp6.TypeCode.Type() {
+		case layers.ICMPv6TypeEchoRequest:
+			request = true
+		case layers.ICMPv6TypeEchoReply:
+		default:
+			return conntrack.ConnKey{}, false, false
+		}
+		// the echo identifier is the beginning of the ICMPv6 payload
+		if len(d.icmp6.Payload) < 2 {
+			return conntrack.ConnKey{}, false, false
+		}
+		proto = conntrack.ProtocolICMPv6
+		id = binary.BigEndian.Uint16(d.icmp6.Payload[:2])
+	default:
+		return conntrack.ConnKey{}, false, false
+	}
+
+	return conntrack.NewConnKey(proto, srcIP, dstIP, id, id), request, true
+}
+
+func tcpFlags(tcp *layers.TCP) uint8 {
+	var flags uint8
+	if tcp.FIN {
+		flags |= conntrack.TCPFin
+	}
+	if tcp.SYN {
+		flags |= conntrack.TCPSyn
+	}
+	if tcp.RST {
+		flags |= conntrack.TCPRst
+	}
+	if tcp.ACK {
+		flags |= conntrack.TCPAck
+	}
+	return flags
+}
@@ -11,6 +11,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	firewall "github.com/netbirdio/netbird/client/firewall/manager"
+	"github.com/netbirdio/netbird/client/firewall/uspfilter/conntrack"
 	"github.com/netbirdio/netbird/iface"
 )
 
@@ -31,12 +32,23 @@ type RuleSet map[string]Rule
 
 // Manager userspace firewall manager
 type Manager struct {
-	outgoingRules  map[string]RuleSet
-	incomingRules  map[string]RuleSet
+	outgoingRules map[string]RuleSet
+	incomingRules map[string]RuleSet
+	// pendingAdd and pendingDelete contain the rules changed since the last flush, indexed by rule ID
+	pendingAdd     map[string]Rule
+	pendingDelete  map[string]Rule
 	wgNetwork      *net.IPNet
 	decoders       sync.Pool
 	wgIface        IFaceMapper
 	nativeFirewall firewall.Manager
+	// forwarder is nil unless the interface forwards the routed traffic in userspace
+	forwarder   iface.Forwarder
+	routes      map[string]*net.IPNet
+	connTracker *conntrack.Tracker
+	droppedIn   ruleCounters
+	droppedOut  ruleCounters
+	// dropLog is nil unless the logging of the dropped packets is enabled
+	dropLog *dropLogger
 
 	mutex sync.RWMutex
 }
@@ -86,42 +98,30 @@ func create(iface IFaceMapper) (*Manager, error) {
 		},
 		outgoingRules: make(map[string]RuleSet),
 		incomingRules: make(map[string]RuleSet),
+		pendingAdd:    make(map[string]Rule),
+		pendingDelete: make(map[string]Rule),
 		wgIface:       iface,
+		routes:        make(map[string]*net.IPNet),
+		connTracker:   conntrack.NewTracker(conntrack.DefaultMaxEntries),
+	}
+	if fwdIface, ok := iface.(forwardingIFace); ok {
+		m.forwarder = fwdIface.GetForwarder()
+	}
+	if firewall.LogDroppedEnabled() {
+		m.dropLog = &dropLogger{}
 	}
 
 	if err := iface.SetFilter(m); err != nil {
+		m.connTracker.Close()
 		return nil, err
 	}
 	return m, nil
 }
 
-func (m *Manager) IsServerRouteSupported() bool {
-	if m.nativeFirewall == nil {
-		return false
-	} else {
-		return true
-	}
-}
-
-func (m *Manager) InsertRoutingRules(pair firewall.RouterPair) error {
-	if m.nativeFirewall == nil {
-		return errRouteNotSupported
-	}
-	return m.nativeFirewall.InsertRoutingRules(pair)
-}
-
-// RemoveRoutingRules removes a routing firewall rule
-func (m *Manager) RemoveRoutingRules(pair firewall.RouterPair) error {
-	if m.nativeFirewall == nil {
-		return errRouteNotSupported
-	}
-	return m.nativeFirewall.RemoveRoutingRules(pair)
-}
-
 // AddFiltering rule to the firewall
 //
 // If comment argument is empty firewall manager should set
-// rule ID as comment for the rule
+// rule ID as comment for the rule. The rule is applied on the next Flush
 func (m *Manager) AddFiltering(
 	ip net.IP,
 	proto firewall.Protocol,
@@ -140,6 +140,7 @@ func (m *Manager) AddFiltering(
 		direction: direction,
 		drop:      action == firewall.ActionDrop,
 		comment:   comment,
+		counters:  &ruleCounters{},
 	}
 	if ipNormalized := ip.To4(); ipNormalized != nil {
 		r.ipLayer = layers.LayerTypeIPv4
@@ -150,12 +151,12 @@ func (m *Manager) AddFiltering(
 		r.matchByIP = false
 	}
 
-	if sPort != nil && len(sPort.Values) == 1 {
-		r.sPort = uint16(sPort.Values[0])
+	if sPort != nil && len(sPort.Values) != 0 {
+		r.sPort = sPort
 	}
 
-	if dPort != nil && len(dPort.Values) == 1 {
-		r.dPort = uint16(dPort.Values[0])
+	if dPort != nil && len(dPort.Values) != 0 {
+		r.dPort = dPort
 	}
 
 	switch proto {
@@ -173,22 +174,12 @@ func (m *Manager) AddFiltering(
 	}
 
 	m.mutex.Lock()
-	if direction == firewall.RuleDirectionIN {
-		if _, ok := m.incomingRules[r.ip.String()]; !ok {
-			m.incomingRules[r.ip.String()] = make(RuleSet)
-		}
-		m.incomingRules[r.ip.String()][r.id] = r
-	} else {
-		if _, ok := m.outgoingRules[r.ip.String()]; !ok {
-			m.outgoingRules[r.ip.String()] = make(RuleSet)
-		}
-		m.outgoingRules[r.ip.String()][r.id] = r
-	}
+	m.pendingAdd[r.id] = r
 	m.mutex.Unlock()
 	return []firewall.Rule{&r}, nil
 }
 
-// DeleteRule from the firewall by rule definition
+// DeleteRule from the firewall by rule definition. The rule is removed on the next Flush
 func (m *Manager) DeleteRule(rule firewall.Rule) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -198,25 +189,67 @@ func (m *Manager) DeleteRule(rule firewall.Rule) error {
 		return fmt.Errorf("delete rule: invalid rule type: %T", rule)
 	}
 
+	if _, ok := m.pendingAdd[r.id]; ok {
+		delete(m.pendingAdd, r.id)
+		return nil
+	}
+
+	rules := m.outgoingRules
 	if r.direction == firewall.RuleDirectionIN {
-		_, ok := m.incomingRules[r.ip.String()][r.id]
-		if !ok {
-			return fmt.Errorf("delete rule: no rule with such id: %v", r.id)
-		}
-		delete(m.incomingRules[r.ip.String()], r.id)
-	} else {
-		_, ok := m.outgoingRules[r.ip.String()][r.id]
-		if !ok {
-			return fmt.Errorf("delete rule: no rule with such id: %v", r.id)
-		}
-		delete(m.outgoingRules[r.ip.String()], r.id)
+		rules = m.incomingRules
+	}
+	if _, ok := rules[r.ip.String()][r.id]; !ok {
+		return fmt.Errorf("delete rule: no rule with such id: %v", r.id)
 	}
+	m.pendingDelete[r.id] = *r
+	return nil
+}
 
+// Flush applies the rules added and deleted since the last flush at once. The tracked flows the new rules don't
+// allow anymore are dropped with their next packet
+func (m *Manager) Flush() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, r := range m.pendingDelete {
+		m.removeRule(r)
+	}
+	for _, r := range m.pendingAdd {
+		m.insertRule(r)
+	}
+	if len(m.pendingDelete) > 0 || len(m.pendingAdd) > 0 {
+		m.removeDeniedFlows()
+	}
+	m.pendingAdd = make(map[string]Rule)
+	m.pendingDelete = make(map[string]Rule)
 	return nil
 }
 
-// Flush doesn't need to be implemented for this manager
-func (m *Manager) Flush() error { return nil }
+// insertRule adds the rule to the active rules, the caller has to hold the lock
+func (m *Manager) insertRule(r Rule) {
+	rules := m.outgoingRules
+	if r.direction == firewall.RuleDirectionIN {
+		rules = m.incomingRules
+	}
+	if _, ok := rules[r.ip.String()]; !ok {
+		rules[r.ip.String()] = make(RuleSet)
+	}
+	rules[r.ip.String()][r.id] = r
+}
+
+// removeRule removes the rule from the active rules, the caller has to hold the lock
+func (m *Manager) removeRule(r Rule) {
+	rules := m.outgoingRules
+	if r.direction == firewall.RuleDirectionIN {
+		rules = m.incomingRules
+	}
+	delete(rules[r.ip.String()], r.id)
+}
+
+// IsStateful returns true, the replies of the allowed flows are accepted by the connection tracker
+func (m *Manager) IsStateful() bool {
+	return true
+}
 
 // DropOutgoing filter outgoing packets
 func (m *Manager) DropOutgoing(packetData []byte) bool {
@@ -246,64 +279,72 @@ func (m *Manager) dropFilter(packetData []byte, rules map[string]RuleSet, isInco
 		return true
 	}
 
-	ipLayer := d.decoded[0]
-
-	switch ipLayer {
+	var srcIP, dstIP net.IP
+	switch d.decoded[0] {
 	case layers.LayerTypeIPv4:
-		if !m.wgNetwork.Contains(d.ip4.SrcIP) || !m.wgNetwork.Contains(d.ip4.DstIP) {
-			return false
-		}
+		srcIP, dstIP = d.ip4.SrcIP, d.ip4.DstIP
 	case layers.LayerTypeIPv6:
-		if !m.wgNetwork.Contains(d.ip6.SrcIP) || !m.wgNetwork.Contains(d.ip6.DstIP) {
-			return false
-		}
+		srcIP, dstIP = d.ip6.SrcIP, d.ip6.DstIP
 	default:
 		log.Errorf("unknown layer: %v", d.decoded[0])
 		return true
 	}
 
-	var ip net.IP
-	switch ipLayer {
-	case layers.LayerTypeIPv4:
-		if isIncomingPacket {
-			ip = d.ip4.SrcIP
-		} else {
-			ip = d.ip4.DstIP
-		}
-	case layers.LayerTypeIPv6:
-		if isIncomingPacket {
-			ip = d.ip6.SrcIP
-		} else {
-			ip = d.ip6.DstIP
+	if !m.wgNetwork.Contains(srcIP) || !m.wgNetwork.Contains(dstIP) {
+		routed, drop := m.filterRouted(d, srcIP, dstIP, len(packetData), isIncomingPacket)
+		if !routed {
+			return drop
 		}
 	}
 
-	filter, ok := validateRule(ip, packetData, rules[ip.String()], d)
-	if ok {
-		return filter
+	// packets of the flows allowed earlier are accepted in both directions, like established connections
+	// in the kernel firewalls
+	if m.isTrackedConnection(d, srcIP, dstIP) {
+		return false
 	}
-	filter, ok = validateRule(ip, packetData, rules["0.0.0.0"], d)
-	if ok {
-		return filter
+
+	ip := dstIP
+	if isIncomingPacket {
+		ip = srcIP
 	}
-	filter, ok = validateRule(ip, packetData, rules["::"], d)
-	if ok {
-		return filter
+
+	rule, drop := m.matchRules(ip, packetData, rules, d)
+	switch {
+	case rule == nil:
+		m.countDropped(d, srcIP, dstIP, len(packetData), isIncomingPacket)
+	case rule.counters != nil:
+		rule.counters.add(len(packetData))
+	}
+	// the flows of the packets passed by hooks aren't tracked, the hooks have to see all their packets
+	if !drop && rule != nil && rule.udpHook == nil {
+		m.trackConnection(d, srcIP, dstIP, isIncomingPacket)
+	}
+
+	return drop
+}
+
+// matchRules returns the rule matching the packet and whether the packet has to be dropped
+func (m *Manager) matchRules(ip net.IP, packetData []byte, rules map[string]RuleSet, d *decoder) (*Rule, bool) {
+	for _, key := range []string{ip.String(), "0.0.0.0", "::"} {
+		if rule, drop, ok := validateRule(ip, packetData, rules[key], d); ok {
+			return rule, drop
+		}
 	}
 
 	// default policy is DROP ALL
-	return true
+	return nil, true
 }
 
-func validateRule(ip net.IP, packetData []byte, rules map[string]Rule, d *decoder) (bool, bool) {
+func validateRule(ip net.IP, packetData []byte, rules map[string]Rule, d *decoder) (*Rule, bool, bool) {
 	payloadLayer := d.decoded[1]
 	for _, rule := range rules {
+		rule := rule
 		if rule.matchByIP && !ip.Equal(rule.ip) {
 			continue
 		}
 
 		if rule.protoLayer == layerTypeAll {
-			return rule.drop, true
+			return &rule, rule.drop, true
 		}
 
 		if payloadLayer != rule.protoLayer {
@@ -312,37 +353,25 @@ func validateRule(ip net.IP, packetData []byte, rules map[string]Rule, d *decode
 
 		switch payloadLayer {
 		case layers.LayerTypeTCP:
-			if rule.sPort == 0 && rule.dPort == 0 {
-				return rule.drop, true
-			}
-			if rule.sPort != 0 && rule.sPort == uint16(d.tcp.SrcPort) {
-				return rule.drop, true
-			}
-			if rule.dPort != 0 && rule.dPort == uint16(d.tcp.DstPort) {
-				return rule.drop, true
+			if rule.matchPorts(uint16(d.tcp.SrcPort), uint16(d.tcp.DstPort)) {
+				return &rule, rule.drop, true
 			}
 		case layers.LayerTypeUDP:
 			// if rule has UDP hook (and if we are here we match this rule)
 			// we ignore rule.drop and call this hook
 			if rule.udpHook != nil {
-				return rule.udpHook(packetData), true
+				return &rule, rule.udpHook(packetData), true
 			}
 
-			if rule.sPort == 0 && rule.dPort == 0 {
-				return rule.drop, true
+			// like the TCP rules, the UDP rules with ports only match these ports
+			if rule.matchPorts(uint16(d.udp.SrcPort), uint16(d.udp.DstPort)) {
+				return &rule, rule.drop, true
 			}
-			if rule.sPort != 0 && rule.sPort == uint16(d.udp.SrcPort) {
-				return rule.drop, true
-			}
-			if rule.dPort != 0 && rule.dPort == uint16(d.udp.DstPort) {
-				return rule.drop, true
-			}
-			return rule.drop, true
 		case layers.LayerTypeICMPv4, layers.LayerTypeICMPv6:
-			return rule.drop, true
+			return &rule, rule.drop, true
 		}
 	}
-	return false, false
+	return nil, false, false
 }
 
 // SetNetwork of the wireguard interface to which filtering applied
@@ -360,7 +389,7 @@ func (m *Manager) AddUDPPacketHook(
 		id:         uuid.New().String(),
 		ip:         ip,
 		protoLayer: layers.LayerTypeUDP,
-		dPort:      dPort,
+		dPort:      &firewall.Port{Values: []int{int(dPort)}},
 		ipLayer:    layers.LayerTypeIPv6,
 		direction:  firewall.RuleDirectionOUT,
 		comment:    fmt.Sprintf("UDP Hook direction: %v, ip:%v, dport:%d", in, ip, dPort),
@@ -371,20 +400,13 @@ func (m *Manager) AddUDPPacketHook(
 		r.ipLayer = layers.LayerTypeIPv4
 	}
 
-	m.mutex.Lock()
 	if in {
 		r.direction = firewall.RuleDirectionIN
-		if _, ok := m.incomingRules[r.ip.String()]; !ok {
-			m.incomingRules[r.ip.String()] = make(map[string]Rule)
-		}
-		m.incomingRules[r.ip.String()][r.id] = r
-	} else {
-		if _, ok := m.outgoingRules[r.ip.String()]; !ok {
-			m.outgoingRules[r.ip.String()] = make(map[string]Rule)
-		}
-		m.outgoingRules[r.ip.String()][r.id] = r
 	}
 
+	// the hooks are applied immediately, they aren't part of the rules flushed by the ACL manager
+	m.mutex.Lock()
+	m.insertRule(r)
 	m.mutex.Unlock()
 
 	return r.id
@@ -392,19 +414,14 @@ func (m *Manager) AddUDPPacketHook(
 
 // RemovePacketHook removes packet hook by given ID
 func (m *Manager) RemovePacketHook(hookID string) error {
-	for _, arr := range m.incomingRules {
-		for _, r := range arr {
-			if r.id == hookID {
-				rule := r
-				return m.DeleteRule(&rule)
-			}
-		}
-	}
-	for _, arr := range m.outgoingRules {
-		for _, r := range arr {
-			if r.id == hookID {
-				rule := r
-				return m.DeleteRule(&rule)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, rules := range []map[string]RuleSet{m.incomingRules, m.outgoingRules} {
+		for _, ruleSet := range rules {
+			if r, ok := ruleSet[hookID]; ok && r.udpHook != nil {
+				m.removeRule(r)
+				return nil
 			}
 		}
 	}
@@ -33,6 +33,29 @@ func (i *IFaceMock) Address() iface.WGAddress {
 	return i.AddressFunc()
 }
 
+type forwarderMock struct {
+	enabled bool
+}
+
+func (f *forwarderMock) Enable() error {
+	f.enabled = true
+	return nil
+}
+
+func (f *forwarderMock) Disable() error {
+	f.enabled = false
+	return nil
+}
+
+type forwardingIFaceMock struct {
+	IFaceMock
+	forwarder *forwarderMock
+}
+
+func (i *forwardingIFaceMock) GetForwarder() iface.Forwarder {
+	return i.forwarder
+}
+
 func TestManagerCreate(t *testing.T) {
 	ifaceMock := &IFaceMock{
 		SetFilterFunc: func(iface.PacketFilter) error { return nil },
@@ -125,6 +148,11 @@ func TestManagerDeleteRule(t *testing.T) {
 		return
 	}
 
+	if err := m.Flush(); err != nil {
+		t.Errorf("failed to flush rules: %v", err)
+		return
+	}
+
 	for _, r := range rule {
 		err = m.DeleteRule(r)
 		if err != nil {
@@ -133,6 +161,11 @@ func TestManagerDeleteRule(t *testing.T) {
 		}
 	}
 
+	if err := m.Flush(); err != nil {
+		t.Errorf("failed to flush rules: %v", err)
+		return
+	}
+
 	for _, r := range rule2 {
 		if _, ok := m.incomingRules[ip.String()][r.GetRuleID()]; !ok {
 			t.Errorf("rule2 is not in the incomingRules")
@@ -147,6 +180,11 @@ func TestManagerDeleteRule(t *testing.T) {
 		}
 	}
 
+	if err := m.Flush(); err != nil {
+		t.Errorf("failed to flush rules: %v", err)
+		return
+	}
+
 	for _, r := range rule2 {
 		if _, ok := m.incomingRules[ip.String()][r.GetRuleID()]; ok {
 			t.Errorf("rule2 is not in the incomingRules")
@@ -154,6 +192,35 @@ func TestManagerDeleteRule(t *testing.T) {
 	}
 }
 
+func TestManagerFlush(t *testing.T) {
+	ifaceMock := &IFaceMock{
+		SetFilterFunc: func(iface.PacketFilter) error { return nil },
+	}
+
+	m, err := Create(ifaceMock)
+	require.NoError(t, err)
+
+	ip := net.ParseIP("192.168.1.1")
+	port := &fw.Port{Values: []int{80}}
+	rules, err := m.AddFiltering(ip, fw.ProtocolTCP, nil, port, fw.RuleDirectionIN, fw.ActionAccept, "", "")
+	require.NoError(t, err)
+	require.Empty(t, m.incomingRules[ip.String()], "rules should be applied on flush")
+
+	require.NoError(t, m.Flush())
+	require.Len(t, m.incomingRules[ip.String()], 1)
+
+	require.NoError(t, m.DeleteRule(rules[0]))
+	require.Len(t, m.incomingRules[ip.String()], 1, "rules should be deleted on flush")
+
+	pending, err := m.AddFiltering(ip, fw.ProtocolTCP, nil, port, fw.RuleDirectionOUT, fw.ActionAccept, "", "")
+	require.NoError(t, err)
+	require.NoError(t, m.DeleteRule(pending[0]), "deleting a pending rule should drop it")
+
+	require.NoError(t, m.Flush())
+	require.Empty(t, m.incomingRules[ip.String()])
+	require.Empty(t, m.outgoingRules[ip.String()])
+}
+
 func TestAddUDPPacketHook(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -214,8 +281,8 @@ func TestAddUDPPacketHook(t *testing.T) {
 				t.Errorf("expected ip %s, got %s", tt.ip, addedRule.ip)
 				return
 			}
-			if tt.dPort != addedRule.dPort {
-				t.Errorf("expected dPort %d, got %d", tt.dPort, addedRule.dPort)
+			if !addedRule.dPort.Match(int(tt.dPort)) {
+				t.Errorf("expected dPort %d, got %s", tt.dPort, addedRule.dPort)
 				return
 			}
 			if layers.LayerTypeUDP != addedRule.protoLayer {
@@ -296,6 +363,11 @@ func TestNotMatchByIP(t *testing.T) {
 		return
 	}
 
+	if err := m.Flush(); err != nil {
+		t.Errorf("failed to flush rules: %v", err)
+		return
+	}
+
 	ipv4 := &layers.IPv4{
 		TTL:      64,
 		Version:  4,
@@ -336,6 +408,46 @@ func TestNotMatchByIP(t *testing.T) {
 }
 
 // TestRemovePacketHook tests the functionality of the RemovePacketHook method
+// TestUDPRulePorts covers the UDP rules with ports, which matched any UDP port before they were checked like the
+// TCP rules
+func TestUDPRulePorts(t *testing.T) {
+	ifaceMock := &IFaceMock{
+		SetFilterFunc: func(iface.PacketFilter) error { return nil },
+	}
+
+	m, err := Create(ifaceMock)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, m.Reset())
+	}()
+	m.wgNetwork = &net.IPNet{
+		IP:   net.ParseIP("100.10.0.0"),
+		Mask: net.CIDRMask(16, 32),
+	}
+
+	localIP := net.ParseIP("100.10.0.1")
+	peerIP := net.ParseIP("100.10.0.100")
+	otherPeerIP := net.ParseIP("100.10.0.101")
+
+	_, err = m.AddFiltering(peerIP, fw.ProtocolUDP, nil, &fw.Port{Values: []int{53}}, fw.RuleDirectionIN, fw.ActionAccept, "", "")
+	require.NoError(t, err)
+	_, err = m.AddFiltering(otherPeerIP, fw.ProtocolUDP, nil, nil, fw.RuleDirectionIN, fw.ActionAccept, "", "")
+	require.NoError(t, err)
+	require.NoError(t, m.Flush())
+
+	udpPacket := func(src, dst net.IP, sPort, dPort layers.UDPPort) []byte {
+		ipv4 := &layers.IPv4{Version: 4, TTL: 64, SrcIP: src, DstIP: dst, Protocol: layers.IPProtocolUDP}
+		udp := &layers.UDP{SrcPort: sPort, DstPort: dPort}
+		require.NoError(t, udp.SetNetworkLayerForChecksum(ipv4))
+		return serializePacket(t, ipv4, udp)
+	}
+
+	require.False(t, m.DropIncoming(udpPacket(peerIP, localIP, 40000, 53)), "port of the rule should be allowed")
+	require.True(t, m.DropIncoming(udpPacket(peerIP, localIP, 40000, 5353)), "other ports should be dropped")
+	require.True(t, m.DropIncoming(udpPacket(peerIP, localIP, 53, 5353)), "source port shouldn't match the destination port")
+	require.False(t, m.DropIncoming(udpPacket(otherPeerIP, localIP, 40000, 5353)), "rule without ports should match any port")
+}
+
 func TestRemovePacketHook(t *testing.T) {
 	// creating mock iface
 	iface := &IFaceMock{
@@ -417,3 +529,263 @@ func TestUSPFilterCreatePerformance(t *testing.T) {
 		})
 	}
 }
+
+func TestStatefulFilter(t *testing.T) {
+	ifaceMock := &IFaceMock{
+		SetFilterFunc: func(iface.PacketFilter) error { return nil },
+	}
+
+	m, err := Create(ifaceMock)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, m.Reset())
+	}()
+	m.wgNetwork = &net.IPNet{
+		IP:   net.ParseIP("100.10.0.0"),
+		Mask: net.CIDRMask(16, 32),
+	}
+
+	localIP := net.ParseIP("100.10.0.1")
+	peerIP := net.ParseIP("100.10.0.100")
+
+	// the peer may connect to the local HTTP server and the local peer may ping any peer
+	_, err = m.AddFiltering(peerIP, fw.ProtocolTCP, nil, &fw.Port{Values: []int{80}}, fw.RuleDirectionIN, fw.ActionAccept, "", "")
+	require.NoError(t, err)
+	_, err = m.AddFiltering(net.ParseIP("0.0.0.0"), fw.ProtocolICMP, nil, nil, fw.RuleDirectionOUT, fw.ActionAccept, "", "")
+	require.NoError(t, err)
+	require.NoError(t, m.Flush())
+
+	tcpPacket := func(src, dst net.IP, sPort, dPort layers.TCPPort, syn, ack bool) []byte {
+		ipv4 := &layers.IPv4{Version: 4, TTL: 64, SrcIP: src, DstIP: dst, Protocol: layers.IPProtocolTCP}
+		tcp := &layers.TCP{SrcPort: sPort, DstPort: dPort, SYN: syn, ACK: ack}
+		require.NoError(t, tcp.SetNetworkLayerForChecksum(ipv4))
+		return serializePacket(t, ipv4, tcp)
+	}
+	icmpPacket := func(src, dst net.IP, typeCode layers.ICMPv4TypeCode) []byte {
+		ipv4 := &layers.IPv4{Version: 4, TTL: 64, SrcIP: src, DstIP: dst, Protocol: layers.IPProtocolICMPv4}
+		return serializePacket(t, ipv4, &layers.ICMPv4{TypeCode: typeCode, Id: 7, Seq: 1})
+	}
+
+	require.False(t, m.DropIncoming(tcpPacket(peerIP, localIP, 40000, 80, true, false)), "SYN of the peer should be allowed")
+	require.False(t, m.DropOutgoing(tcpPacket(localIP, peerIP, 80, 40000, true, true)), "SYN-ACK reply should be allowed")
+	require.False(t, m.DropIncoming(tcpPacket(peerIP, localIP, 40000, 80, false, true)), "ACK of the peer should be allowed")
+	require.False(t, m.DropOutgoing(tcpPacket(localIP, peerIP, 80, 40000, false, true)), "data of the local server should be allowed")
+	require.True(t, m.DropOutgoing(tcpPacket(localIP, peerIP, 80, 40001, true, false)),
+		"local peer shouldn't open connections to the peer without an outgoing rule")
+
+	echoRequest := layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0)
+	echoReply := layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoReply, 0)
+	require.True(t, m.DropIncoming(icmpPacket(peerIP, localIP, echoReply)), "unsolicited echo reply should be dropped")
+	require.False(t, m.DropOutgoing(icmpPacket(localIP, peerIP, echoRequest)), "echo request should be allowed")
+	require.False(t, m.DropIncoming(icmpPacket(peerIP, localIP, echoReply)), "echo reply should be allowed")
+	require.True(t, m.DropIncoming(icmpPacket(peerIP, localIP, echoRequest)), "echo request of the peer should be dropped")
+}
+
+func TestStatefulFilter_HooksNotTracked(t *testing.T) {
+	ifaceMock := &IFaceMock{
+		SetFilterFunc: func(iface.PacketFilter) error { return nil },
+	}
+
+	m, err := Create(ifaceMock)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, m.Reset())
+	}()
+	m.wgNetwork = &net.IPNet{
+		IP:   net.ParseIP("100.10.0.0"),
+		Mask: net.CIDRMask(16, 32),
+	}
+
+	hookCalls := 0
+	m.AddUDPPacketHook(false, net.ParseIP("100.10.0.100"), 53, func([]byte) bool {
+		hookCalls++
+		return false
+	})
+
+	ipv4 := &layers.IPv4{Version: 4, TTL: 64, SrcIP: net.ParseIP("100.10.0.1"), DstIP: net.ParseIP("100.10.0.100"), Protocol: layers.IPProtocolUDP}
+	udp := &layers.UDP{SrcPort: 40000, DstPort: 53}
+	require.NoError(t, udp.SetNetworkLayerForChecksum(ipv4))
+	packet := serializePacket(t, ipv4, udp, gopacket.Payload("query"))
+
+	require.False(t, m.DropOutgoing(packet))
+	require.False(t, m.DropOutgoing(packet))
+	require.Equal(t, 2, hookCalls, "hook should see every packet of the flow")
+}
+
+func TestStatefulFilter_FlushRemovesDeniedFlows(t *testing.T) {
+	ifaceMock := &IFaceMock{
+		SetFilterFunc: func(iface.PacketFilter) error { return nil },
+	}
+
+	m, err := Create(ifaceMock)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, m.Reset())
+	}()
+	m.wgNetwork = &net.IPNet{
+		IP:   net.ParseIP("100.10.0.0"),
+		Mask: net.CIDRMask(16, 32),
+	}
+
+	localIP := net.ParseIP("100.10.0.1")
+	peerIP := net.ParseIP("100.10.0.100")
+
+	httpRules, err := m.AddFiltering(peerIP, fw.ProtocolTCP, nil, &fw.Port{Values: []int{80}}, fw.RuleDirectionIN, fw.ActionAccept, "", "")
+	require.NoError(t, err)
+	_, err = m.AddFiltering(peerIP, fw.ProtocolTCP, nil, &fw.Port{Values: []int{443}}, fw.RuleDirectionOUT, fw.ActionAccept, "", "")
+	require.NoError(t, err)
+	require.NoError(t, m.Flush())
+
+	tcpPacket := func(src, dst net.IP, sPort, dPort layers.TCPPort, syn, ack bool) []byte {
+		ipv4 := &layers.IPv4{Version: 4, TTL: 64, SrcIP: src, DstIP: dst, Protocol: layers.IPProtocolTCP}
+		tcp := &layers.TCP{SrcPort: sPort, DstPort: dPort, SYN: syn, ACK: ack}
+		require.NoError(t, tcp.SetNetworkLayerForChecksum(ipv4))
+		return serializePacket(t, ipv4, tcp)
+	}
+
+	require.False(t, m.DropIncoming(tcpPacket(peerIP, localIP, 40000, 80, true, false)))
+	require.False(t, m.DropOutgoing(tcpPacket(localIP, peerIP, 80, 40000, true, true)))
+	require.False(t, m.DropOutgoing(tcpPacket(localIP, peerIP, 40001, 443, true, false)))
+	require.False(t, m.DropIncoming(tcpPacket(peerIP, localIP, 443, 40001, true, true)))
+	require.Equal(t, 2, m.connTracker.Len())
+
+	require.NoError(t, m.DeleteRule(httpRules[0]))
+	require.False(t, m.DropIncoming(tcpPacket(peerIP, localIP, 40000, 80, false, true)),
+		"deleted rule should still apply until the flush")
+	require.NoError(t, m.Flush())
+
+	require.True(t, m.DropIncoming(tcpPacket(peerIP, localIP, 40000, 80, false, true)),
+		"flow of the deleted rule should be dropped")
+	require.True(t, m.DropOutgoing(tcpPacket(localIP, peerIP, 80, 40000, false, true)),
+		"replies of the flow of the deleted rule should be dropped")
+	require.False(t, m.DropOutgoing(tcpPacket(localIP, peerIP, 40001, 443, false, true)),
+		"flow still allowed by the rules should be kept")
+	require.Equal(t, 1, m.connTracker.Len())
+}
+
+func TestGetRuleCounters(t *testing.T) {
+	ifaceMock := &IFaceMock{
+		SetFilterFunc: func(iface.PacketFilter) error { return nil },
+	}
+
+	m, err := Create(ifaceMock)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, m.Reset())
+	}()
+	m.wgNetwork = &net.IPNet{
+		IP:   net.ParseIP("100.10.0.0"),
+		Mask: net.CIDRMask(16, 32),
+	}
+
+	localIP := net.ParseIP("100.10.0.1")
+	peerIP := net.ParseIP("100.10.0.100")
+
+	rules, err := m.AddFiltering(peerIP, fw.ProtocolUDP, nil, &fw.Port{Values: []int{53}}, fw.RuleDirectionIN, fw.ActionAccept, "", "")
+	require.NoError(t, err)
+	require.NoError(t, m.Flush())
+	m.AddUDPPacketHook(false, peerIP, 5353, func([]byte) bool { return false })
+
+	udpPacket := func(src, dst net.IP, sPort, dPort layers.UDPPort) []byte {
+		ipv4 := &layers.IPv4{Version: 4, TTL: 64, SrcIP: src, DstIP: dst, Protocol: layers.IPProtocolUDP}
+		udp := &layers.UDP{SrcPort: sPort, DstPort: dPort}
+		require.NoError(t, udp.SetNetworkLayerForChecksum(ipv4))
+		return serializePacket(t, ipv4, udp)
+	}
+
+	allowed := udpPacket(peerIP, localIP, 40000, 53)
+	require.False(t, m.DropIncoming(allowed))
+	// the packets of the tracked flow are accepted by the connection tracker and aren't counted by the rule
+	require.False(t, m.DropOutgoing(udpPacket(localIP, peerIP, 53, 40000)))
+	require.False(t, m.DropIncoming(allowed))
+	require.False(t, m.DropIncoming(udpPacket(peerIP, localIP, 40001, 53)))
+
+	dropped := udpPacket(localIP, net.ParseIP("100.10.0.101"), 40000, 8080)
+	require.True(t, m.DropOutgoing(dropped))
+	require.False(t, m.DropOutgoing(udpPacket(localIP, peerIP, 40000, 5353)))
+
+	counters, err := m.GetRuleCounters()
+	require.NoError(t, err)
+	require.Len(t, counters.Rules, 1, "packet hooks shouldn't be reported")
+	require.Equal(t, fw.Counters{Packets: 2, Bytes: uint64(2 * len(allowed))}, counters.Rules[rules[0].GetRuleID()])
+	require.Equal(t, fw.Counters{}, counters.DroppedIn)
+	require.Equal(t, fw.Counters{Packets: 1, Bytes: uint64(len(dropped))}, counters.DroppedOut)
+}
+
+func TestRoutedFilter(t *testing.T) {
+	ifaceMock := &forwardingIFaceMock{
+		IFaceMock: IFaceMock{
+			SetFilterFunc: func(iface.PacketFilter) error { return nil },
+		},
+		forwarder: &forwarderMock{},
+	}
+
+	m, err := Create(ifaceMock)
+	require.NoError(t, err)
+	require.True(t, m.IsServerRouteSupported())
+	m.wgNetwork = &net.IPNet{
+		IP:   net.ParseIP("100.10.0.0"),
+		Mask: net.CIDRMask(16, 32),
+	}
+
+	peerIP := net.ParseIP("100.10.0.100")
+	routedIP := net.ParseIP("192.168.1.10")
+	otherIP := net.ParseIP("10.0.0.1")
+
+	pair := fw.RouterPair{ID: "route1", Source: "100.10.0.0/16", Destination: "192.168.1.0/24", Masquerade: true}
+	require.NoError(t, m.InsertRoutingRules(pair))
+	require.True(t, ifaceMock.forwarder.enabled, "forwarding should be enabled with the first route")
+
+	_, err = m.AddFiltering(peerIP, fw.ProtocolTCP, nil, &fw.Port{Values: []int{443}}, fw.RuleDirectionIN, fw.ActionAccept, "", "")
+	require.NoError(t, err)
+	require.NoError(t, m.Flush())
+
+	tcpPacket := func(src, dst net.IP, sPort, dPort layers.TCPPort, syn, ack bool) []byte {
+		ipv4 := &layers.IPv4{Version: 4, TTL: 64, SrcIP: src, DstIP: dst, Protocol: layers.IPProtocolTCP}
+		tcp := &layers.TCP{SrcPort: sPort, DstPort: dPort, SYN: syn, ACK: ack}
+		require.NoError(t, tcp.SetNetworkLayerForChecksum(ipv4))
+		return serializePacket(t, ipv4, tcp)
+	}
+
+	require.True(t, m.DropOutgoing(tcpPacket(routedIP, peerIP, 443, 40000, true, true)),
+		"replies of untracked routed flows should be dropped")
+	require.False(t, m.DropIncoming(tcpPacket(peerIP, routedIP, 40000, 443, true, false)),
+		"routed traffic allowed by the rules of the peer should be accepted")
+	require.False(t, m.DropOutgoing(tcpPacket(routedIP, peerIP, 443, 40000, true, true)),
+		"replies of the routed flows should be accepted")
+	require.True(t, m.DropIncoming(tcpPacket(peerIP, routedIP, 40001, 22, true, false)),
+		"routed traffic not allowed by the rules of the peer should be dropped")
+	require.True(t, m.DropIncoming(tcpPacket(peerIP, otherIP, 40002, 443, true, false)),
+		"traffic to networks not routed by this peer should be dropped")
+
+	defaultPair := fw.RouterPair{ID: "route2", Source: "100.10.0.0/16", Destination: "0.0.0.0/0", Masquerade: true}
+	require.NoError(t, m.InsertRoutingRules(defaultPair))
+	require.False(t, m.DropIncoming(tcpPacket(peerIP, otherIP, 40004, 443, true, false)),
+		"traffic to networks of the default route should be accepted")
+	for _, hostLocalIP := range []string{"127.0.0.1", "0.0.0.0", "169.254.1.1", "224.0.0.1"} {
+		require.True(t, m.DropIncoming(tcpPacket(peerIP, net.ParseIP(hostLocalIP), 40005, 443, true, false)),
+			"traffic to host-local address %s shouldn't be routed", hostLocalIP)
+	}
+	require.NoError(t, m.RemoveRoutingRules(defaultPair))
+
+	require.NoError(t, m.RemoveRoutingRules(pair))
+	require.False(t, ifaceMock.forwarder.enabled, "forwarding should be disabled without routes")
+	require.True(t, m.DropIncoming(tcpPacket(peerIP, routedIP, 40003, 443, true, false)),
+		"traffic of removed routes should be dropped")
+
+	require.NoError(t, m.InsertRoutingRules(pair))
+	require.NoError(t, m.Reset())
+	require.False(t, ifaceMock.forwarder.enabled, "forwarding should be disabled on reset")
+}
+
+func serializePacket(t *testing.T, packetLayers ...gopacket.SerializableLayer) []byte {
+	t.Helper()
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{
+		ComputeChecksums: true,
+		FixLengths:       true,
+	}
+	require.NoError(t, gopacket.SerializeLayers(buf, opts, packetLayers...))
+	return buf.Bytes()
+}
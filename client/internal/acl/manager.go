@@ -4,6 +4,7 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"net"
 	"strconv"
 	"sync"
@@ -19,6 +20,7 @@ import (
 // Manager is a ACL rules manager
 type Manager interface {
 	ApplyFiltering(networkMap *mgmProto.NetworkMap)
+	GetStats() (*Stats, error)
 }
 
 // DefaultManager uses firewall manager to handle
@@ -26,19 +28,24 @@ type DefaultManager struct {
 	firewall     firewall.Manager
 	ipsetCounter int
 	rulesPairs   map[string][]firewall.Rule
+	// appliedRules contains the rule received from the management for each pair of rules
+	appliedRules map[string]*mgmProto.FirewallRule
 	mutex        sync.Mutex
 }
 
 func NewDefaultManager(fm firewall.Manager) *DefaultManager {
 	return &DefaultManager{
-		firewall:   fm,
-		rulesPairs: make(map[string][]firewall.Rule),
+		firewall:     fm,
+		rulesPairs:   make(map[string][]firewall.Rule),
+		appliedRules: make(map[string]*mgmProto.FirewallRule),
 	}
 }
 
 // ApplyFiltering firewall rules to the local firewall manager processed by ACL policy.
 //
 // If allowByDefault is true it appends allow ALL traffic rules to input and output chains.
+// The new rules and the removal of the stale ones are flushed to the firewall at once. If any rule
+// can't be applied, the previous ruleset is kept.
 func (d *DefaultManager) ApplyFiltering(networkMap *mgmProto.NetworkMap) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
@@ -59,12 +66,6 @@ func (d *DefaultManager) ApplyFiltering(networkMap *mgmProto.NetworkMap) {
 		return
 	}
 
-	defer func() {
-		if err := d.firewall.Flush(); err != nil {
-			log.Error("failed to flush firewall rules: ", err)
-		}
-	}()
-
 	rules, squashedProtocols := d.squashAcceptRules(networkMap)
 
 	enableSSH := (networkMap.PeerConfig != nil &&
@@ -110,7 +111,8 @@ func (d *DefaultManager) ApplyFiltering(networkMap *mgmProto.NetworkMap) {
 		)
 	}
 
-	newRulePairs := make(map[string][]firewall.Rule)
+	specs := make(map[string]ruleSpec)
+	var order []string
 	ipsetByRuleSelectors := make(map[string]string)
 
 	for _, r := range rules {
@@ -123,82 +125,132 @@ func (d *DefaultManager) ApplyFiltering(networkMap *mgmProto.NetworkMap) {
 			ipsetName = fmt.Sprintf("nb%07d", d.ipsetCounter)
 			ipsetByRuleSelectors[selector] = ipsetName
 		}
-		pairID, rulePair, err := d.protoRuleToFirewallRule(r, ipsetName)
+		pairID, spec, err := d.protoRuleToSpec(r, ipsetName)
 		if err != nil {
 			log.Errorf("failed to apply firewall rule: %+v, %v", r, err)
-			d.rollBack(newRulePairs)
-			break
+			continue
 		}
-		if len(rules) > 0 {
-			d.rulesPairs[pairID] = rulePair
+		if _, ok := specs[pairID]; !ok {
+			order = append(order, pairID)
+		}
+		specs[pairID] = spec
+	}
+
+	newAppliedRules := make(map[string]*mgmProto.FirewallRule, len(specs))
+	for pairID, spec := range specs {
+		newAppliedRules[pairID] = spec.rule
+	}
+
+	if d.rulesUnchanged(specs) {
+		log.Debugf("firewall rules haven't changed")
+		d.appliedRules = newAppliedRules
+		return
+	}
+
+	newRulePairs := make(map[string][]firewall.Rule, len(specs))
+	addedRulePairs := make(map[string][]firewall.Rule)
+	for _, pairID := range order {
+		if rulePair, ok := d.rulesPairs[pairID]; ok {
 			newRulePairs[pairID] = rulePair
+			continue
 		}
+
+		rulePair, err := d.addRules(specs[pairID])
+		if err != nil {
+			log.Errorf("failed to apply firewall rule: %+v, %v", specs[pairID].rule, err)
+			addedRulePairs[pairID] = rulePair
+			d.rollBack(addedRulePairs)
+			return
+		}
+		newRulePairs[pairID] = rulePair
+		addedRulePairs[pairID] = rulePair
 	}
 
 	for pairID, rules := range d.rulesPairs {
-		if _, ok := newRulePairs[pairID]; !ok {
-			for _, rule := range rules {
-				if err := d.firewall.DeleteRule(rule); err != nil {
-					log.Errorf("failed to delete firewall rule: %v", err)
-					continue
-				}
+		if _, ok := newRulePairs[pairID]; ok {
+			continue
+		}
+		for _, rule := range rules {
+			if err := d.firewall.DeleteRule(rule); err != nil {
+				log.Errorf("failed to delete firewall rule: %v", err)
 			}
-			delete(d.rulesPairs, pairID)
 		}
 	}
+
+	// a failed flush drops the added rules and keeps the deleted ones, the next network map applies the rules again
+	if err := d.firewall.Flush(); err != nil {
+		log.Errorf("failed to flush firewall rules, keeping the previous firewall rules: %v", err)
+		return
+	}
 	d.rulesPairs = newRulePairs
+	d.appliedRules = newAppliedRules
 }
 
-func (d *DefaultManager) protoRuleToFirewallRule(
-	r *mgmProto.FirewallRule,
-	ipsetName string,
-) (string, []firewall.Rule, error) {
+// ruleSpec is a validated firewall rule received from the management
+type ruleSpec struct {
+	rule      *mgmProto.FirewallRule
+	ip        net.IP
+	protocol  firewall.Protocol
+	port      *firewall.Port
+	action    firewall.Action
+	ipsetName string
+}
+
+// rulesUnchanged returns true if the rules are the ones already applied
+func (d *DefaultManager) rulesUnchanged(specs map[string]ruleSpec) bool {
+	if len(specs) != len(d.rulesPairs) {
+		return false
+	}
+	for pairID := range specs {
+		if _, ok := d.rulesPairs[pairID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *DefaultManager) protoRuleToSpec(r *mgmProto.FirewallRule, ipsetName string) (string, ruleSpec, error) {
 	ip := net.ParseIP(r.PeerIP)
 	if ip == nil {
-		return "", nil, fmt.Errorf("invalid IP address, skipping firewall rule")
+		return "", ruleSpec{}, fmt.Errorf("invalid IP address, skipping firewall rule")
 	}
 
 	protocol, err := convertToFirewallProtocol(r.Protocol)
 	if err != nil {
-		return "", nil, fmt.Errorf("skipping firewall rule: %s", err)
+		return "", ruleSpec{}, fmt.Errorf("skipping firewall rule: %s", err)
 	}
 
 	action, err := convertFirewallAction(r.Action)
 	if err != nil {
-		return "", nil, fmt.Errorf("skipping firewall rule: %s", err)
+		return "", ruleSpec{}, fmt.Errorf("skipping firewall rule: %s", err)
 	}
 
-	var port *firewall.Port
-	if r.Port != "" {
-		value, err := strconv.Atoi(r.Port)
-		if err != nil {
-			return "", nil, fmt.Errorf("invalid port, skipping firewall rule")
-		}
-		port = &firewall.Port{
-			Values: []int{value},
-		}
+	port, err := convertToFirewallPort(r)
+	if err != nil {
+		return "", ruleSpec{}, fmt.Errorf("skipping firewall rule: %s", err)
 	}
 
-	ruleID := d.getRuleID(ip, protocol, int(r.Direction), port, action, "")
-	if rulesPair, ok := d.rulesPairs[ruleID]; ok {
-		return ruleID, rulesPair, nil
+	if r.Direction != mgmProto.FirewallRule_IN && r.Direction != mgmProto.FirewallRule_OUT {
+		return "", ruleSpec{}, fmt.Errorf("invalid direction, skipping firewall rule")
 	}
 
-	var rules []firewall.Rule
-	switch r.Direction {
-	case mgmProto.FirewallRule_IN:
-		rules, err = d.addInRules(ip, protocol, port, action, ipsetName, "")
-	case mgmProto.FirewallRule_OUT:
-		rules, err = d.addOutRules(ip, protocol, port, action, ipsetName, "")
-	default:
-		return "", nil, fmt.Errorf("invalid direction, skipping firewall rule")
+	spec := ruleSpec{
+		rule:      r,
+		ip:        ip,
+		protocol:  protocol,
+		port:      port,
+		action:    action,
+		ipsetName: ipsetName,
 	}
+	return d.getRuleID(ip, protocol, int(r.Direction), port, action, ""), spec, nil
+}
 
-	if err != nil {
-		return "", nil, err
+// addRules adds the firewall rules of a spec. On error, it returns the rules added before the failure
+func (d *DefaultManager) addRules(spec ruleSpec) ([]firewall.Rule, error) {
+	if spec.rule.Direction == mgmProto.FirewallRule_IN {
+		return d.addInRules(spec.ip, spec.protocol, spec.port, spec.action, spec.ipsetName, "")
 	}
-
-	return ruleID, rules, nil
+	return d.addOutRules(spec.ip, spec.protocol, spec.port, spec.action, spec.ipsetName, "")
 }
 
 func (d *DefaultManager) addInRules(
@@ -217,14 +269,14 @@ func (d *DefaultManager) addInRules(
 	}
 	rules = append(rules, rule...)
 
-	if shouldSkipInvertedRule(protocol, port) {
+	if d.shouldSkipInvertedRule(protocol, port) {
 		return rules, nil
 	}
 
 	rule, err = d.firewall.AddFiltering(
 		ip, protocol, port, nil, firewall.RuleDirectionOUT, action, ipsetName, comment)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add firewall rule: %v", err)
+		return append(rules, rule...), fmt.Errorf("failed to add firewall rule: %v", err)
 	}
 
 	return append(rules, rule...), nil
@@ -246,14 +298,14 @@ func (d *DefaultManager) addOutRules(
 	}
 	rules = append(rules, rule...)
 
-	if shouldSkipInvertedRule(protocol, port) {
+	if d.shouldSkipInvertedRule(protocol, port) {
 		return rules, nil
 	}
 
 	rule, err = d.firewall.AddFiltering(
 		ip, protocol, port, nil, firewall.RuleDirectionIN, action, ipsetName, comment)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add firewall rule: %v", err)
+		return append(rules, rule...), fmt.Errorf("failed to add firewall rule: %v", err)
 	}
 
 	return append(rules, rule...), nil
@@ -308,7 +360,7 @@ func (d *DefaultManager) squashAcceptRules(
 	//
 	// We zeroed this to notify squash function that this protocol can't be squashed.
 	addRuleToCalculationMap := func(i int, r *mgmProto.FirewallRule, protocols protoMatch) {
-		drop := r.Action == mgmProto.FirewallRule_DROP || r.Port != ""
+		drop := r.Action == mgmProto.FirewallRule_DROP || r.Port != "" || r.PortInfo != nil
 		if drop {
 			protocols[r.Protocol] = map[string]int{}
 			return
@@ -407,11 +459,17 @@ func (d *DefaultManager) squashAcceptRules(
 	return append(rules, squashedRules...), squashedProtocols
 }
 
-// getRuleGroupingSelector takes all rule properties except IP address to build selector
+// getRuleGroupingSelector takes all rule properties except IP address to build selector. The port string only carries
+// the first port of the ranges and lists, the selector has all of them
 func (d *DefaultManager) getRuleGroupingSelector(rule *mgmProto.FirewallRule) string {
-	return fmt.Sprintf("%v:%v:%v:%s", strconv.Itoa(int(rule.Direction)), rule.Action, rule.Protocol, rule.Port)
+	var ports string
+	if port, err := convertToFirewallPort(rule); err == nil && port != nil {
+		ports = port.String()
+	}
+	return fmt.Sprintf("%v:%v:%v:%s", strconv.Itoa(int(rule.Direction)), rule.Action, rule.Protocol, ports)
 }
 
+// rollBack drops the rules added by the current transaction, the previous rules stay in place
 func (d *DefaultManager) rollBack(newRulePairs map[string][]firewall.Rule) {
 	log.Debugf("rollback ACL to previous state")
 	for _, rules := range newRulePairs {
@@ -421,6 +479,10 @@ func (d *DefaultManager) rollBack(newRulePairs map[string][]firewall.Rule) {
 			}
 		}
 	}
+
+	if err := d.firewall.Flush(); err != nil {
+		log.Error("failed to flush firewall rules after rollback: ", err)
+	}
 }
 
 func convertToFirewallProtocol(protocol mgmProto.FirewallRuleProtocol) (firewall.Protocol, error) {
@@ -438,7 +500,52 @@ func convertToFirewallProtocol(protocol mgmProto.FirewallRuleProtocol) (firewall
 	}
 }
 
-func shouldSkipInvertedRule(protocol firewall.Protocol, port *firewall.Port) bool {
+// convertToFirewallPort returns the port, the range or the list of ports of the rule, nil if the rule applies to all
+// ports. The structured port info is preferred, the port string is sent by older management services
+func convertToFirewallPort(r *mgmProto.FirewallRule) (*firewall.Port, error) {
+	switch {
+	case r.GetPortInfo().GetRange() != nil:
+		start, end := r.GetPortInfo().GetRange().GetStart(), r.GetPortInfo().GetRange().GetEnd()
+		if start == 0 || start > end || end > math.MaxUint16 {
+			return nil, fmt.Errorf("invalid port range %d-%d", start, end)
+		}
+		return &firewall.Port{IsRange: true, Values: []int{int(start), int(end)}}, nil
+	case r.GetPortInfo().GetList() != nil:
+		ports := r.GetPortInfo().GetList().GetPorts()
+		if len(ports) == 0 {
+			return nil, fmt.Errorf("empty port list")
+		}
+		values := make([]int, 0, len(ports))
+		for _, port := range ports {
+			if port == 0 || port > math.MaxUint16 {
+				return nil, fmt.Errorf("invalid port %d", port)
+			}
+			values = append(values, int(port))
+		}
+		return &firewall.Port{Values: values}, nil
+	case r.GetPortInfo().GetPort() != 0:
+		port := r.GetPortInfo().GetPort()
+		if port > math.MaxUint16 {
+			return nil, fmt.Errorf("invalid port %d", port)
+		}
+		return &firewall.Port{Values: []int{int(port)}}, nil
+	case r.Port != "":
+		value, err := strconv.Atoi(r.Port)
+		if err != nil || value < 1 || value > math.MaxUint16 {
+			return nil, fmt.Errorf("invalid port %s", r.Port)
+		}
+		return &firewall.Port{Values: []int{value}}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// shouldSkipInvertedRule returns true if the rule allowing the replies of a rule isn't needed: the firewall tracks
+// the connections, or the rule doesn't match ports
+func (d *DefaultManager) shouldSkipInvertedRule(protocol firewall.Protocol, port *firewall.Port) bool {
+	if d.firewall.IsStateful() {
+		return true
+	}
 	return protocol == firewall.ProtocolALL || protocol == firewall.ProtocolICMP || port == nil
 }
 
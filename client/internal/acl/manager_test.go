@@ -2,13 +2,17 @@ package acl
 
 import (
 	"context"
+	"errors"
 	"net"
+	"reflect"
+	"strconv"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 
 	"github.com/netbirdio/netbird/client/firewall"
 	"github.com/netbirdio/netbird/client/firewall/manager"
+	"github.com/netbirdio/netbird/client/firewall/uspfilter"
 	"github.com/netbirdio/netbird/client/internal/acl/mocks"
 	"github.com/netbirdio/netbird/iface"
 	mgmProto "github.com/netbirdio/netbird/management/proto"
@@ -71,6 +75,25 @@ func TestDefaultManager(t *testing.T) {
 		}
 	})
 
+	t.Run("get stats of the applied rules", func(t *testing.T) {
+		stats, err := acl.GetStats()
+		if err != nil {
+			t.Errorf("get stats: %v", err)
+			return
+		}
+
+		if len(stats.Rules) != 2 {
+			t.Errorf("expected the stats of 2 rules, got: %d", len(stats.Rules))
+			return
+		}
+		// the rules are sorted by direction and peer IP
+		for i, rule := range stats.Rules {
+			if rule.Rule != networkMap.FirewallRules[i] {
+				t.Errorf("expected the stats of rule %v, got: %v", networkMap.FirewallRules[i], rule.Rule)
+			}
+		}
+	})
+
 	t.Run("add extra rules", func(t *testing.T) {
 		existedPairs := map[string]struct{}{}
 		for id := range acl.rulesPairs {
@@ -109,6 +132,86 @@ func TestDefaultManager(t *testing.T) {
 		}
 	})
 
+	t.Run("skip unchanged rules", func(t *testing.T) {
+		existedPairs := make(map[string][]manager.Rule)
+		for id, pair := range acl.rulesPairs {
+			existedPairs[id] = pair
+		}
+
+		acl.ApplyFiltering(networkMap)
+
+		if len(acl.rulesPairs) != len(existedPairs) {
+			t.Errorf("expected %d rules, got: %d", len(existedPairs), len(acl.rulesPairs))
+			return
+		}
+		for id, pair := range acl.rulesPairs {
+			if len(existedPairs[id]) == 0 || existedPairs[id][0] != pair[0] {
+				t.Errorf("rule %s should have been kept", id)
+			}
+		}
+	})
+
+	t.Run("skip invalid rule", func(t *testing.T) {
+		existedPairs := map[string]struct{}{}
+		for id := range acl.rulesPairs {
+			existedPairs[id] = struct{}{}
+		}
+
+		invalidMap := &mgmProto.NetworkMap{
+			FirewallRules: []*mgmProto.FirewallRule{
+				{
+					PeerIP:    "10.93.0.4",
+					Direction: mgmProto.FirewallRule_IN,
+					Action:    mgmProto.FirewallRule_ACCEPT,
+					Protocol:  mgmProto.FirewallRule_TCP,
+					Port:      "22",
+				},
+				{
+					PeerIP:    "invalid",
+					Direction: mgmProto.FirewallRule_IN,
+					Action:    mgmProto.FirewallRule_ACCEPT,
+					Protocol:  mgmProto.FirewallRule_TCP,
+				},
+			},
+		}
+		acl.ApplyFiltering(invalidMap)
+
+		if len(acl.rulesPairs) != 1 {
+			t.Errorf("expected only the valid rule, got: %d rules", len(acl.rulesPairs))
+			return
+		}
+		for id := range acl.rulesPairs {
+			if _, ok := existedPairs[id]; ok {
+				t.Errorf("rule %s should have been removed", id)
+			}
+		}
+	})
+
+	t.Run("keep previous rules on failed flush", func(t *testing.T) {
+		existedPairs := make(map[string][]manager.Rule)
+		for id, pair := range acl.rulesPairs {
+			existedPairs[id] = pair
+		}
+
+		failing := &failingFlushFirewall{Manager: fw, fail: true}
+		acl.firewall = failing
+		defer func() {
+			acl.firewall = fw
+		}()
+
+		acl.ApplyFiltering(networkMap)
+		if !reflect.DeepEqual(acl.rulesPairs, existedPairs) {
+			t.Errorf("expected the previous rules after a failed flush, got: %v", acl.rulesPairs)
+			return
+		}
+
+		failing.fail = false
+		acl.ApplyFiltering(networkMap)
+		if len(acl.rulesPairs) != len(networkMap.FirewallRules) {
+			t.Errorf("expected the rules to be applied again, got: %v", acl.rulesPairs)
+		}
+	})
+
 	t.Run("handle default rules", func(t *testing.T) {
 		networkMap.FirewallRules = networkMap.FirewallRules[:0]
 
@@ -127,6 +230,19 @@ func TestDefaultManager(t *testing.T) {
 	})
 }
 
+// failingFlushFirewall fails the flushes while fail is set
+type failingFlushFirewall struct {
+	manager.Manager
+	fail bool
+}
+
+func (f *failingFlushFirewall) Flush() error {
+	if f.fail {
+		return errors.New("flush failed")
+	}
+	return f.Manager.Flush()
+}
+
 func TestDefaultManagerSquashRules(t *testing.T) {
 	networkMap := &mgmProto.NetworkMap{
 		RemotePeers: []*mgmProto.RemotePeerConfig{
@@ -362,3 +478,184 @@ func TestDefaultManagerEnableSSHRules(t *testing.T) {
 		return
 	}
 }
+
+// directionRecorder records the directions of the rules added to the firewall
+type directionRecorder struct {
+	manager.Manager
+	directions []manager.RuleDirection
+}
+
+func (r *directionRecorder) AddFiltering(
+	ip net.IP,
+	proto manager.Protocol,
+	sPort *manager.Port,
+	dPort *manager.Port,
+	direction manager.RuleDirection,
+	action manager.Action,
+	ipsetName string,
+	comment string,
+) ([]manager.Rule, error) {
+	r.directions = append(r.directions, direction)
+	return r.Manager.AddFiltering(ip, proto, sPort, dPort, direction, action, ipsetName, comment)
+}
+
+func TestDefaultManagerStatefulFirewall(t *testing.T) {
+	networkMap := &mgmProto.NetworkMap{
+		FirewallRules: []*mgmProto.FirewallRule{
+			{
+				PeerIP:    "10.93.0.1",
+				Direction: mgmProto.FirewallRule_OUT,
+				Action:    mgmProto.FirewallRule_ACCEPT,
+				Protocol:  mgmProto.FirewallRule_TCP,
+				Port:      "80",
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ifaceMock := mocks.NewMockIFaceMapper(ctrl)
+	ifaceMock.EXPECT().SetFilter(gomock.Any())
+	ip, network, err := net.ParseCIDR("10.93.0.100/16")
+	if err != nil {
+		t.Fatalf("failed to parse IP address: %v", err)
+	}
+	ifaceMock.EXPECT().Address().Return(iface.WGAddress{
+		IP:      ip,
+		Network: network,
+	}).AnyTimes()
+
+	fw, err := uspfilter.Create(ifaceMock)
+	if err != nil {
+		t.Fatalf("create firewall: %v", err)
+	}
+	defer func() {
+		_ = fw.Reset()
+	}()
+	if !fw.IsStateful() {
+		t.Fatalf("userspace firewall should be stateful")
+	}
+
+	recorder := &directionRecorder{Manager: fw}
+	acl := NewDefaultManager(recorder)
+	acl.ApplyFiltering(networkMap)
+
+	if len(acl.rulesPairs) != 1 {
+		t.Fatalf("firewall rules not applied: %v", acl.rulesPairs)
+	}
+	for _, direction := range recorder.directions {
+		if direction == manager.RuleDirectionIN {
+			t.Errorf("no inbound rule should be added for an outbound policy with a stateful firewall")
+		}
+	}
+	if len(recorder.directions) != 1 {
+		t.Errorf("expected only the outbound rule, got the directions %v", recorder.directions)
+	}
+}
+
+func TestConvertToFirewallPort(t *testing.T) {
+	portRange := func(start, end uint32) *mgmProto.PortInfo {
+		return &mgmProto.PortInfo{PortSelection: &mgmProto.PortInfo_Range_{
+			Range: &mgmProto.PortInfo_Range{Start: start, End: end},
+		}}
+	}
+	portList := func(ports ...uint32) *mgmProto.PortInfo {
+		return &mgmProto.PortInfo{PortSelection: &mgmProto.PortInfo_List_{
+			List: &mgmProto.PortInfo_List{Ports: ports},
+		}}
+	}
+
+	tests := []struct {
+		name      string
+		rule      *mgmProto.FirewallRule
+		expected  *manager.Port
+		expectErr bool
+	}{
+		{
+			name: "no port",
+			rule: &mgmProto.FirewallRule{},
+		},
+		{
+			name:     "port string of older management",
+			rule:     &mgmProto.FirewallRule{Port: "22"},
+			expected: &manager.Port{Values: []int{22}},
+		},
+		{
+			name: "port info",
+			rule: &mgmProto.FirewallRule{
+				Port:     "443",
+				PortInfo: &mgmProto.PortInfo{PortSelection: &mgmProto.PortInfo_Port{Port: 443}},
+			},
+			expected: &manager.Port{Values: []int{443}},
+		},
+		{
+			name:     "port range",
+			rule:     &mgmProto.FirewallRule{Port: "8000", PortInfo: portRange(8000, 8100)},
+			expected: &manager.Port{IsRange: true, Values: []int{8000, 8100}},
+		},
+		{
+			name:     "port list",
+			rule:     &mgmProto.FirewallRule{Port: "80", PortInfo: portList(80, 443)},
+			expected: &manager.Port{Values: []int{80, 443}},
+		},
+		{
+			name:      "port list with invalid port",
+			rule:      &mgmProto.FirewallRule{PortInfo: portList(80, 70000)},
+			expectErr: true,
+		},
+		{
+			name:      "empty port list",
+			rule:      &mgmProto.FirewallRule{PortInfo: portList()},
+			expectErr: true,
+		},
+		{
+			name:      "inverted port range",
+			rule:      &mgmProto.FirewallRule{PortInfo: portRange(8100, 8000)},
+			expectErr: true,
+		},
+		{
+			name:      "port range string without port info",
+			rule:      &mgmProto.FirewallRule{Port: "8000-8100"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			port, err := convertToFirewallPort(tc.rule)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got port %v", port)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tc.expected, port) {
+				t.Errorf("expected port %v, got %v", tc.expected, port)
+			}
+		})
+	}
+}
+
+func TestGetRuleGroupingSelector(t *testing.T) {
+	d := &DefaultManager{}
+	rule := func(start, end uint32) *mgmProto.FirewallRule {
+		return &mgmProto.FirewallRule{
+			Protocol: mgmProto.FirewallRule_TCP,
+			Port:     strconv.Itoa(int(start)),
+			PortInfo: &mgmProto.PortInfo{PortSelection: &mgmProto.PortInfo_Range_{
+				Range: &mgmProto.PortInfo_Range{Start: start, End: end},
+			}},
+		}
+	}
+
+	if d.getRuleGroupingSelector(rule(8000, 8100)) == d.getRuleGroupingSelector(rule(8000, 9000)) {
+		t.Error("rules of different port ranges with the same first port shouldn't share an ipset")
+	}
+	if d.getRuleGroupingSelector(rule(8000, 8100)) != d.getRuleGroupingSelector(rule(8000, 8100)) {
+		t.Error("rules of the same port range should share an ipset")
+	}
+}
@@ -0,0 +1,75 @@
+package acl
+
+import (
+	"fmt"
+	"sort"
+
+	firewall "github.com/netbirdio/netbird/client/firewall/manager"
+	mgmProto "github.com/netbirdio/netbird/management/proto"
+)
+
+// RuleStats is a firewall rule applied from the management with the traffic matched by its firewall entries
+type RuleStats struct {
+	Rule     *mgmProto.FirewallRule
+	Counters firewall.Counters
+}
+
+// Stats contains the traffic matched by the applied rules and dropped by the default policy
+type Stats struct {
+	Rules      []RuleStats
+	DroppedIn  firewall.Counters
+	DroppedOut firewall.Counters
+}
+
+// GetStats returns the applied rules with their counters. The counters of a rule are the sum of the counters of its
+// firewall entries, including the inverted ones. The entries shared through an ipset count the traffic of all
+// the rules sharing them
+func (d *DefaultManager) GetStats() (*Stats, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.firewall == nil {
+		return nil, fmt.Errorf("firewall manager is not supported")
+	}
+
+	counters, err := d.firewall.GetRuleCounters()
+	if err != nil {
+		return nil, fmt.Errorf("get firewall rule counters: %w", err)
+	}
+
+	stats := &Stats{
+		Rules:      make([]RuleStats, 0, len(d.appliedRules)),
+		DroppedIn:  counters.DroppedIn,
+		DroppedOut: counters.DroppedOut,
+	}
+	for pairID, rule := range d.appliedRules {
+		ruleStats := RuleStats{Rule: rule}
+
+		seen := make(map[string]struct{})
+		for _, fwRule := range d.rulesPairs[pairID] {
+			id := fwRule.GetRuleID()
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			ruleStats.Counters.Add(counters.Rules[id])
+		}
+		stats.Rules = append(stats.Rules, ruleStats)
+	}
+
+	sort.Slice(stats.Rules, func(i, j int) bool {
+		a, b := stats.Rules[i].Rule, stats.Rules[j].Rule
+		if a.Direction != b.Direction {
+			return a.Direction < b.Direction
+		}
+		if a.PeerIP != b.PeerIP {
+			return a.PeerIP < b.PeerIP
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.Port < b.Port
+	})
+
+	return stats, nil
+}
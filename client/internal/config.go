@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"slices"
 
 	log "github.com/sirupsen/logrus"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
@@ -42,6 +43,10 @@ type ConfigInput struct {
 	NATExternalIPs   []string
 	CustomDNSAddress []byte
 	RosenpassEnabled *bool
+	InterfaceName    *string
+	WireguardPort    *int
+	// IFaceBlackList replaces the blacklisted interfaces if it isn't nil
+	IFaceBlackList []string
 }
 
 // Config Configuration type
@@ -189,7 +194,18 @@ func createNewConfig(input ConfigInput) (*Config, error) {
 		config.AdminURL = newURL
 	}
 
+	if input.InterfaceName != nil {
+		config.WgIface = *input.InterfaceName
+	}
+
+	if input.WireguardPort != nil {
+		config.WgPort = *input.WireguardPort
+	}
+
 	config.IFaceBlackList = defaultInterfaceBlacklist
+	if input.IFaceBlackList != nil {
+		config.IFaceBlackList = input.IFaceBlackList
+	}
 	return config, nil
 }
 
@@ -243,11 +259,28 @@ func update(input ConfigInput) (*Config, error) {
 		config.WgPort = iface.DefaultWgPort
 		refresh = true
 	}
-	if input.NATExternalIPs != nil && len(config.NATExternalIPs) != len(input.NATExternalIPs) {
+	if input.NATExternalIPs != nil && !slices.Equal(config.NATExternalIPs, input.NATExternalIPs) {
 		config.NATExternalIPs = input.NATExternalIPs
 		refresh = true
 	}
 
+	if input.InterfaceName != nil && config.WgIface != *input.InterfaceName {
+		log.Infof("new interface name provided, updated to %s (old value %s)", *input.InterfaceName, config.WgIface)
+		config.WgIface = *input.InterfaceName
+		refresh = true
+	}
+
+	if input.WireguardPort != nil && config.WgPort != *input.WireguardPort {
+		log.Infof("new WireGuard port provided, updated to %d (old value %d)", *input.WireguardPort, config.WgPort)
+		config.WgPort = *input.WireguardPort
+		refresh = true
+	}
+
+	if input.IFaceBlackList != nil && !slices.Equal(config.IFaceBlackList, input.IFaceBlackList) {
+		config.IFaceBlackList = input.IFaceBlackList
+		refresh = true
+	}
+
 	if input.CustomDNSAddress != nil {
 		config.CustomDNSAddress = string(input.CustomDNSAddress)
 		refresh = true
@@ -123,6 +123,41 @@ func TestHiddenPreSharedKey(t *testing.T) {
 	}
 }
 
+func TestUpdateInterfaceConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	_, err := UpdateOrCreateConfig(ConfigInput{ConfigPath: path})
+	require.NoError(t, err)
+
+	interfaceName := "wt5"
+	wireguardPort := 51830
+	config, err := UpdateOrCreateConfig(ConfigInput{
+		ConfigPath:     path,
+		InterfaceName:  &interfaceName,
+		WireguardPort:  &wireguardPort,
+		IFaceBlackList: []string{"eth1"},
+		NATExternalIPs: []string{"1.2.3.4"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, interfaceName, config.WgIface)
+	assert.Equal(t, wireguardPort, config.WgPort)
+	assert.Equal(t, []string{"eth1"}, config.IFaceBlackList)
+
+	// a mapping replacing another one of the same length
+	config, err = UpdateOrCreateConfig(ConfigInput{
+		ConfigPath:     path,
+		NATExternalIPs: []string{"5.6.7.8"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"5.6.7.8"}, config.NATExternalIPs)
+
+	readConf, err := ReadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, interfaceName, readConf.WgIface)
+	assert.Equal(t, wireguardPort, readConf.WgPort)
+	assert.Equal(t, []string{"eth1"}, readConf.IFaceBlackList)
+	assert.Equal(t, []string{"5.6.7.8"}, readConf.NATExternalIPs)
+}
+
 func TestUpdateOldManagementURL(t *testing.T) {
 	tests := []struct {
 		name                  string
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
@@ -19,15 +20,58 @@ import (
 	"github.com/netbirdio/netbird/client/ssh"
 	"github.com/netbirdio/netbird/client/system"
 	"github.com/netbirdio/netbird/iface"
+	"github.com/netbirdio/netbird/iface/netstack"
 	mgm "github.com/netbirdio/netbird/management/client"
 	mgmProto "github.com/netbirdio/netbird/management/proto"
 	signal "github.com/netbirdio/netbird/signal/client"
 	"github.com/netbirdio/netbird/version"
 )
 
+// ConnectClient connects to the Management and Signal services and runs the engine until its context is done
+type ConnectClient struct {
+	ctx            context.Context
+	config         *Config
+	statusRecorder *peer.Status
+
+	engine      *Engine
+	engineMutex sync.Mutex
+}
+
+// NewConnectClient creates a client for the config, the context must be initialized with CtxInitState
+func NewConnectClient(ctx context.Context, config *Config, statusRecorder *peer.Status) *ConnectClient {
+	return &ConnectClient{
+		ctx:            ctx,
+		config:         config,
+		statusRecorder: statusRecorder,
+	}
+}
+
+// Run runs the client with the interface of the platform until the context is done
+func (c *ConnectClient) Run() error {
+	return c.run(MobileDependency{}, nil)
+}
+
+// RunWithNetstack runs the client with an interface in a userspace network stack until the context is done
+func (c *ConnectClient) RunWithNetstack(opts netstack.Options) error {
+	return c.run(MobileDependency{}, &opts)
+}
+
+// Engine returns the running engine, nil while the client isn't connected
+func (c *ConnectClient) Engine() *Engine {
+	c.engineMutex.Lock()
+	defer c.engineMutex.Unlock()
+	return c.engine
+}
+
+func (c *ConnectClient) setEngine(engine *Engine) {
+	c.engineMutex.Lock()
+	defer c.engineMutex.Unlock()
+	c.engine = engine
+}
+
 // RunClient with main logic.
 func RunClient(ctx context.Context, config *Config, statusRecorder *peer.Status) error {
-	return runClient(ctx, config, statusRecorder, MobileDependency{})
+	return NewConnectClient(ctx, config, statusRecorder).Run()
 }
 
 // RunClientMobile with main logic on mobile system
@@ -40,7 +84,7 @@ func RunClientMobile(ctx context.Context, config *Config, statusRecorder *peer.S
 		HostDNSAddresses:      dnsAddresses,
 		DnsReadyListener:      dnsReadyListener,
 	}
-	return runClient(ctx, config, statusRecorder, mobileDependency)
+	return NewConnectClient(ctx, config, statusRecorder).run(mobileDependency, nil)
 }
 
 func RunClientiOS(ctx context.Context, config *Config, statusRecorder *peer.Status, fileDescriptor int32, networkChangeListener listener.NetworkChangeListener, dnsManager dns.IosDnsManager) error {
@@ -49,10 +93,11 @@ func RunClientiOS(ctx context.Context, config *Config, statusRecorder *peer.Stat
 		NetworkChangeListener: networkChangeListener,
 		DnsManager:            dnsManager,
 	}
-	return runClient(ctx, config, statusRecorder, mobileDependency)
+	return NewConnectClient(ctx, config, statusRecorder).run(mobileDependency, nil)
 }
 
-func runClient(ctx context.Context, config *Config, statusRecorder *peer.Status, mobileDependency MobileDependency) error {
+func (c *ConnectClient) run(mobileDependency MobileDependency, netstackOpts *netstack.Options) error {
+	ctx, config, statusRecorder := c.ctx, c.config, c.statusRecorder
 	log.Infof("starting NetBird client version %s", version.NetbirdVersion())
 
 	backOff := &backoff.ExponentialBackOff{
@@ -180,6 +225,7 @@ func runClient(ctx context.Context, config *Config, statusRecorder *peer.Status,
 			log.Error(err)
 			return wrapErr(err)
 		}
+		engineConfig.NetstackOptions = netstackOpts
 
 		engine := NewEngine(engineCtx, cancel, signalClient, mgmClient, engineConfig, mobileDependency, statusRecorder)
 		err = engine.Start()
@@ -187,12 +233,14 @@ func runClient(ctx context.Context, config *Config, statusRecorder *peer.Status,
 			log.Errorf("error while starting Netbird Connection Engine: %s", err)
 			return wrapErr(err)
 		}
+		c.setEngine(engine)
 
 		log.Print("Netbird engine started, my IP is: ", peerConfig.Address)
 		state.Set(StatusConnected)
 
 		<-engineCtx.Done()
 		statusRecorder.ClientTeardown()
+		c.setEngine(nil)
 
 		backOff.Reset()
 
@@ -233,6 +281,7 @@ func createEngineConfig(key wgtypes.Key, config *Config, peerConfig *mgmProto.Pe
 		WgPrivateKey:         key,
 		WgPort:               config.WgPort,
 		SSHKey:               []byte(config.SSHKey),
+		SSHKnownHostsFile:    ssh.KnownHostsFile(),
 		NATExternalIPs:       config.NATExternalIPs,
 		CustomDNSAddress:     config.CustomDNSAddress,
 		RosenpassEnabled:     config.RosenpassEnabled,
@@ -0,0 +1,213 @@
+package dns
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	nbdns "github.com/netbirdio/netbird/dns"
+)
+
+const (
+	// HandlerBlockList is the handler name used for queries answered by a block list
+	HandlerBlockList = "blocklist"
+
+	blockedRecordTTL = 60
+)
+
+// blockListMatcher matches query names against the block lists received from the management service
+type blockListMatcher struct {
+	mu sync.RWMutex
+	// blocked and wildcardBlocked map a domain to the response of its block list
+	blocked         map[string]nbdns.BlockResponse
+	wildcardBlocked map[string]nbdns.BlockResponse
+	allowed         map[string]struct{}
+	wildcardAllowed map[string]struct{}
+}
+
+func newBlockListMatcher() *blockListMatcher {
+	return &blockListMatcher{
+		blocked:         make(map[string]nbdns.BlockResponse),
+		wildcardBlocked: make(map[string]nbdns.BlockResponse),
+		allowed:         make(map[string]struct{}),
+		wildcardAllowed: make(map[string]struct{}),
+	}
+}
+
+// update replaces the matched domains with the domains of the block lists
+func (m *blockListMatcher) update(blockLists []*nbdns.BlockList) {
+	blocked := make(map[string]nbdns.BlockResponse)
+	wildcardBlocked := make(map[string]nbdns.BlockResponse)
+	allowed := make(map[string]struct{})
+	wildcardAllowed := make(map[string]struct{})
+
+	for _, blockList := range blockLists {
+		response := blockList.Response
+		if !response.IsValid() {
+			response = nbdns.BlockResponseNXDomain
+		}
+
+		for _, domain := range blockList.Domains {
+			domain = nbdns.NormalizeBlockListDomain(domain)
+			if wildcard, ok := strings.CutPrefix(domain, nbdns.WildcardPrefix); ok {
+				wildcardBlocked[wildcard] = response
+				continue
+			}
+			blocked[domain] = response
+		}
+
+		for _, domain := range blockList.AllowDomains {
+			domain = nbdns.NormalizeBlockListDomain(domain)
+			if wildcard, ok := strings.CutPrefix(domain, nbdns.WildcardPrefix); ok {
+				wildcardAllowed[wildcard] = struct{}{}
+				continue
+			}
+			allowed[domain] = struct{}{}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.blocked = blocked
+	m.wildcardBlocked = wildcardBlocked
+	m.allowed = allowed
+	m.wildcardAllowed = wildcardAllowed
+
+	if len(blockLists) > 0 {
+		log.Debugf("applied %d DNS block lists with %d domains and %d wildcard domains",
+			len(blockLists), len(blocked), len(wildcardBlocked))
+	}
+}
+
+// match returns the response for the query name and true if the name is blocked.
+// Allowed domains take precedence over blocked domains
+func (m *blockListMatcher) match(name string) (nbdns.BlockResponse, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.blocked) == 0 && len(m.wildcardBlocked) == 0 {
+		return "", false
+	}
+
+	name = nbdns.NormalizeBlockListDomain(name)
+
+	if _, ok := m.allowed[name]; ok {
+		return "", false
+	}
+	if hasParentDomain(name, m.wildcardAllowed) {
+		return "", false
+	}
+
+	if response, ok := m.blocked[name]; ok {
+		return response, true
+	}
+
+	for parent := parentDomain(name); parent != ""; parent = parentDomain(parent) {
+		if response, ok := m.wildcardBlocked[parent]; ok {
+			return response, true
+		}
+	}
+
+	return "", false
+}
+
+func hasParentDomain[T any](name string, domains map[string]T) bool {
+	if len(domains) == 0 {
+		return false
+	}
+	for parent := parentDomain(name); parent != ""; parent = parentDomain(parent) {
+		if _, ok := domains[parent]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func parentDomain(name string) string {
+	_, parent, found := strings.Cut(name, ".")
+	if !found {
+		return ""
+	}
+	return parent
+}
+
+// warnInactiveBlockLists logs the queries the block lists don't apply to. They only block the queries reaching the
+// handlers of the server, without a primary nameserver group the host resolves the other domains itself
+func (s *DefaultServer) warnInactiveBlockLists(update nbdns.Config, muxUpdates []muxUpdate) {
+	if !update.ServiceEnable {
+		log.Warnf("the DNS block lists are inactive, the DNS service of this peer is disabled")
+		return
+	}
+	if s.permanent {
+		return
+	}
+	for _, update := range muxUpdates {
+		if update.domain == nbdns.RootZone {
+			return
+		}
+	}
+	log.Warnf("the DNS block lists only apply to the domains of the custom zones and nameserver groups, " +
+		"configure a primary nameserver group to apply them to all the DNS queries of this peer")
+}
+
+// blockListHandler answers the queries for blocked domains before they reach the wrapped handler
+type blockListHandler struct {
+	handlerWithStop
+	blockLists *blockListMatcher
+	queryLog   *QueryLog
+}
+
+func (h *blockListHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	if len(r.Question) == 0 {
+		h.handlerWithStop.ServeDNS(w, r)
+		return
+	}
+
+	question := r.Question[0]
+	response, blocked := h.blockLists.match(question.Name)
+	if !blocked {
+		h.handlerWithStop.ServeDNS(w, r)
+		return
+	}
+
+	log.Tracef("blocked DNS query %s %s", question.Name, dns.TypeToString[question.Qtype])
+
+	reply := blockedReply(r, response)
+	if err := w.WriteMsg(reply); err != nil {
+		log.Errorf("failed to write DNS response for blocked domain %s: %v", question.Name, err)
+	}
+
+	if h.queryLog != nil {
+		h.queryLog.record(QueryLogEntry{
+			Time:    time.Now(),
+			Name:    strings.ToLower(question.Name),
+			Type:    dns.TypeToString[question.Qtype],
+			Handler: HandlerBlockList,
+			Rcode:   dns.RcodeToString[reply.Rcode],
+		})
+	}
+}
+
+// blockedReply builds the answer to a query for a blocked domain
+func blockedReply(r *dns.Msg, response nbdns.BlockResponse) *dns.Msg {
+	reply := new(dns.Msg)
+	if response != nbdns.BlockResponseZero {
+		return reply.SetRcode(r, dns.RcodeNameError)
+	}
+
+	reply.SetReply(r)
+	question := r.Question[0]
+	header := dns.RR_Header{Name: question.Name, Rrtype: question.Qtype, Class: dns.ClassINET, Ttl: blockedRecordTTL}
+	switch question.Qtype {
+	case dns.TypeA:
+		reply.Answer = append(reply.Answer, &dns.A{Hdr: header, A: make([]byte, 4)})
+	case dns.TypeAAAA:
+		reply.Answer = append(reply.Answer, &dns.AAAA{Hdr: header, AAAA: make([]byte, 16)})
+	}
+
+	return reply
+}
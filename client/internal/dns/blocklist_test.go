@@ -0,0 +1,144 @@
+package dns
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+
+	nbdns "github.com/netbirdio/netbird/dns"
+)
+
+func TestBlockListMatcher_Match(t *testing.T) {
+	matcher := newBlockListMatcher()
+	matcher.update([]*nbdns.BlockList{
+		{
+			Domains:      []string{"ads.example.com", "*.tracker.example.com", "Fetched.Example.com."},
+			AllowDomains: []string{"ok.tracker.example.com"},
+			Response:     nbdns.BlockResponseNXDomain,
+		},
+		{
+			Domains:      []string{"*.zero.example.com"},
+			AllowDomains: []string{"*.allowed.zero.example.com"},
+			Response:     nbdns.BlockResponseZero,
+		},
+	})
+
+	testCases := []struct {
+		name             string
+		queryName        string
+		expectedBlocked  bool
+		expectedResponse nbdns.BlockResponse
+	}{
+		{name: "exact domain", queryName: "ads.example.com.", expectedBlocked: true, expectedResponse: nbdns.BlockResponseNXDomain},
+		{name: "exact domain is case insensitive", queryName: "ADS.example.com.", expectedBlocked: true, expectedResponse: nbdns.BlockResponseNXDomain},
+		{name: "subdomain of exact domain", queryName: "sub.ads.example.com.", expectedBlocked: false},
+		{name: "fetched domain", queryName: "fetched.example.com.", expectedBlocked: true, expectedResponse: nbdns.BlockResponseNXDomain},
+		{name: "wildcard subdomain", queryName: "a.b.tracker.example.com.", expectedBlocked: true, expectedResponse: nbdns.BlockResponseNXDomain},
+		{name: "wildcard parent", queryName: "tracker.example.com.", expectedBlocked: false},
+		{name: "allowed domain", queryName: "ok.tracker.example.com.", expectedBlocked: false},
+		{name: "zero response", queryName: "a.zero.example.com.", expectedBlocked: true, expectedResponse: nbdns.BlockResponseZero},
+		{name: "wildcard allowed domain", queryName: "a.allowed.zero.example.com.", expectedBlocked: false},
+		{name: "not blocked", queryName: "netbird.io.", expectedBlocked: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, blocked := matcher.match(testCase.queryName)
+			if blocked != testCase.expectedBlocked {
+				t.Fatalf("expected blocked %t, got %t", testCase.expectedBlocked, blocked)
+			}
+			if response != testCase.expectedResponse {
+				t.Errorf("expected response %s, got %s", testCase.expectedResponse, response)
+			}
+		})
+	}
+
+	matcher.update(nil)
+	if _, blocked := matcher.match("ads.example.com."); blocked {
+		t.Errorf("domain shouldn't be blocked after the block lists were removed")
+	}
+}
+
+func TestBlockListHandler_ServeDNS(t *testing.T) {
+	matcher := newBlockListMatcher()
+	matcher.update([]*nbdns.BlockList{
+		{Domains: []string{"nx.example.com"}, Response: nbdns.BlockResponseNXDomain},
+		{Domains: []string{"zero.example.com"}, Response: nbdns.BlockResponseZero},
+	})
+
+	queryLog := NewQueryLog(10, true)
+	resolver := &localResolver{registeredMap: make(registrationMap)}
+	handler := &blockListHandler{handlerWithStop: resolver, blockLists: matcher, queryLog: queryLog}
+
+	testCases := []struct {
+		name           string
+		question       *dns.Msg
+		expectedRcode  int
+		expectedAnswer string
+	}{
+		{
+			name:          "nxdomain response",
+			question:      new(dns.Msg).SetQuestion("nx.example.com.", dns.TypeA),
+			expectedRcode: dns.RcodeNameError,
+		},
+		{
+			name:           "zero A response",
+			question:       new(dns.Msg).SetQuestion("zero.example.com.", dns.TypeA),
+			expectedRcode:  dns.RcodeSuccess,
+			expectedAnswer: "0.0.0.0",
+		},
+		{
+			name:           "zero AAAA response",
+			question:       new(dns.Msg).SetQuestion("zero.example.com.", dns.TypeAAAA),
+			expectedRcode:  dns.RcodeSuccess,
+			expectedAnswer: "::",
+		},
+		{
+			name:          "zero response without records for other types",
+			question:      new(dns.Msg).SetQuestion("zero.example.com.", dns.TypeTXT),
+			expectedRcode: dns.RcodeSuccess,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var reply *dns.Msg
+			writer := &mockResponseWriter{WriteMsgFunc: func(m *dns.Msg) error {
+				reply = m
+				return nil
+			}}
+
+			handler.ServeDNS(writer, testCase.question)
+
+			if reply == nil {
+				t.Fatalf("expected a reply for the blocked domain")
+			}
+			if reply.Rcode != testCase.expectedRcode {
+				t.Errorf("expected rcode %s, got %s", dns.RcodeToString[testCase.expectedRcode], dns.RcodeToString[reply.Rcode])
+			}
+
+			var answer string
+			switch rr := firstRR(reply.Answer).(type) {
+			case *dns.A:
+				answer = rr.A.String()
+			case *dns.AAAA:
+				answer = rr.AAAA.String()
+			}
+			if answer != testCase.expectedAnswer {
+				t.Errorf("expected answer %q, got %q", testCase.expectedAnswer, answer)
+			}
+		})
+	}
+
+	entries := queryLog.Entries(0)
+	if len(entries) != len(testCases) || entries[0].Handler != HandlerBlockList {
+		t.Errorf("expected blocked queries to be recorded with the %s handler, got %+v", HandlerBlockList, entries)
+	}
+}
+
+func firstRR(rrs []dns.RR) dns.RR {
+	if len(rrs) == 0 {
+		return nil
+	}
+	return rrs[0]
+}
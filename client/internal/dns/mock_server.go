@@ -8,9 +8,10 @@ import (
 
 // MockServer is the mock instance of a dns server
 type MockServer struct {
-	InitializeFunc      func() error
-	StopFunc            func()
-	UpdateDNSServerFunc func(serial uint64, update nbdns.Config) error
+	InitializeFunc       func() error
+	StopFunc             func()
+	UpdateDNSServerFunc  func(serial uint64, update nbdns.Config) error
+	UpdateBlockListsFunc func(blockLists []*nbdns.BlockList)
 }
 
 // Initialize mock implementation of Initialize from Server interface
@@ -45,6 +46,18 @@ func (m *MockServer) UpdateDNSServer(serial uint64, update nbdns.Config) error {
 	return fmt.Errorf("method UpdateDNSServer is not implemented")
 }
 
+// UpdateBlockLists mock implementation of UpdateBlockLists from Server interface
+func (m *MockServer) UpdateBlockLists(blockLists []*nbdns.BlockList) {
+	if m.UpdateBlockListsFunc != nil {
+		m.UpdateBlockListsFunc(blockLists)
+	}
+}
+
 func (m *MockServer) SearchDomains() []string {
 	return make([]string, 0)
 }
+
+// CurrentConfig mock implementation of CurrentConfig from Server interface
+func (m *MockServer) CurrentConfig() HostDNSConfig {
+	return HostDNSConfig{}
+}
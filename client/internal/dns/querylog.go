@@ -0,0 +1,231 @@
+package dns
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	envDNSQueryLog = "NB_DNS_QUERY_LOG"
+
+	defaultQueryLogSize = 1000
+	maxStatsDomains     = 5000
+
+	// HandlerLocal is the handler name used for queries answered by the local zone records
+	HandlerLocal = "local"
+	// HandlerHost is the handler name used for queries falling through to the host's original resolvers
+	HandlerHost = "host"
+	// HandlerUpstreamPrefix is the prefix of the handler name used for queries answered by a nameserver group
+	HandlerUpstreamPrefix = "upstream"
+
+	rcodeNoResponse = "NO_RESPONSE"
+)
+
+var defaultQueryLog = NewQueryLog(defaultQueryLogSize, os.Getenv(envDNSQueryLog) == "true")
+
+// QueryLogEntry holds a single query served by the DNS server
+type QueryLogEntry struct {
+	Time    time.Time
+	Name    string
+	Type    string
+	Handler string
+	Rcode   string
+	Latency time.Duration
+}
+
+// DomainStats holds aggregated query statistics of a single domain
+type DomainStats struct {
+	Domain       string
+	Queries      uint64
+	Failures     uint64
+	TotalLatency time.Duration
+	LastHandler  string
+	LastSeen     time.Time
+}
+
+// AverageLatency returns the average time spent to answer a query of the domain
+func (d DomainStats) AverageLatency() time.Duration {
+	if d.Queries == 0 {
+		return 0
+	}
+	return d.TotalLatency / time.Duration(d.Queries)
+}
+
+// QueryLog is a bounded in-memory log of the queries served by the DNS server. Recording is skipped while disabled
+type QueryLog struct {
+	mux     sync.Mutex
+	enabled bool
+	entries []QueryLogEntry
+	next    int
+	full    bool
+	stats   map[string]*DomainStats
+}
+
+// NewQueryLog returns a new query log that keeps up to size entries
+func NewQueryLog(size int, enabled bool) *QueryLog {
+	if size <= 0 {
+		size = defaultQueryLogSize
+	}
+	return &QueryLog{
+		enabled: enabled,
+		entries: make([]QueryLogEntry, size),
+		stats:   make(map[string]*DomainStats),
+	}
+}
+
+// GetQueryLog returns the query log shared by the DNS servers of this process
+func GetQueryLog() *QueryLog {
+	return defaultQueryLog
+}
+
+// Enabled returns true if queries are being recorded
+func (q *QueryLog) Enabled() bool {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return q.enabled
+}
+
+// SetEnabled starts or stops the recording of queries. Recorded data is kept
+func (q *QueryLog) SetEnabled(enabled bool) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.enabled = enabled
+}
+
+// Clear removes all recorded entries and statistics
+func (q *QueryLog) Clear() {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.entries = make([]QueryLogEntry, len(q.entries))
+	q.next = 0
+	q.full = false
+	q.stats = make(map[string]*DomainStats)
+}
+
+// Entries returns up to limit of the latest entries, oldest first. A limit of 0 returns all of them
+func (q *QueryLog) Entries(limit int) []QueryLogEntry {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	count := q.next
+	if q.full {
+		count = len(q.entries)
+	}
+	if limit <= 0 || limit > count {
+		limit = count
+	}
+
+	result := make([]QueryLogEntry, 0, limit)
+	for i := count - limit; i < count; i++ {
+		idx := i
+		if q.full {
+			idx = (q.next + i) % len(q.entries)
+		}
+		result = append(result, q.entries[idx])
+	}
+	return result
+}
+
+// Stats returns the per-domain statistics sorted by number of queries
+func (q *QueryLog) Stats() []DomainStats {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	result := make([]DomainStats, 0, len(q.stats))
+	for _, s := range q.stats {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Queries == result[j].Queries {
+			return result[i].Domain < result[j].Domain
+		}
+		return result[i].Queries > result[j].Queries
+	})
+	return result
+}
+
+func (q *QueryLog) record(entry QueryLogEntry) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	if !q.enabled {
+		return
+	}
+
+	q.entries[q.next] = entry
+	q.next++
+	if q.next == len(q.entries) {
+		q.next = 0
+		q.full = true
+	}
+
+	stats, ok := q.stats[entry.Name]
+	if !ok {
+		if len(q.stats) >= maxStatsDomains {
+			return
+		}
+		stats = &DomainStats{Domain: entry.Name}
+		q.stats[entry.Name] = stats
+	}
+	stats.Queries++
+	if entry.Rcode != dns.RcodeToString[dns.RcodeSuccess] {
+		stats.Failures++
+	}
+	stats.TotalLatency += entry.Latency
+	stats.LastHandler = entry.Handler
+	stats.LastSeen = entry.Time
+}
+
+// queryLogHandler records every query served by the wrapped handler in the query log
+type queryLogHandler struct {
+	handlerWithStop
+	name     string
+	queryLog *QueryLog
+}
+
+func (h *queryLogHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	if h.queryLog == nil || !h.queryLog.Enabled() || len(r.Question) == 0 {
+		h.handlerWithStop.ServeDNS(w, r)
+		return
+	}
+
+	start := time.Now()
+	rw := &queryLogResponseWriter{ResponseWriter: w}
+	h.handlerWithStop.ServeDNS(rw, r)
+
+	rcode := rcodeNoResponse
+	if rw.rcode != nil {
+		rcode = dns.RcodeToString[*rw.rcode]
+	}
+
+	question := r.Question[0]
+	h.queryLog.record(QueryLogEntry{
+		Time:    start,
+		Name:    strings.ToLower(question.Name),
+		Type:    dns.TypeToString[question.Qtype],
+		Handler: h.name,
+		Rcode:   rcode,
+		Latency: time.Since(start),
+	})
+}
+
+// queryLogResponseWriter keeps the response code of the message written by a handler
+type queryLogResponseWriter struct {
+	dns.ResponseWriter
+	rcode *int
+}
+
+func (w *queryLogResponseWriter) WriteMsg(m *dns.Msg) error {
+	rcode := m.Rcode
+	w.rcode = &rcode
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+func upstreamHandlerName(upstreamServers []string) string {
+	return HandlerUpstreamPrefix + " " + strings.Join(upstreamServers, ",")
+}
@@ -0,0 +1,96 @@
+package dns
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestQueryLog_Entries(t *testing.T) {
+	queryLog := NewQueryLog(3, true)
+
+	for i := 0; i < 5; i++ {
+		queryLog.record(QueryLogEntry{Name: fmt.Sprintf("peer%d.netbird.cloud.", i), Rcode: "NOERROR"})
+	}
+
+	entries := queryLog.Entries(0)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		expected := fmt.Sprintf("peer%d.netbird.cloud.", i+2)
+		if entry.Name != expected {
+			t.Errorf("expected entry %d to be %s, got %s", i, expected, entry.Name)
+		}
+	}
+
+	latest := queryLog.Entries(1)
+	if len(latest) != 1 || latest[0].Name != "peer4.netbird.cloud." {
+		t.Errorf("expected only the latest entry, got %v", latest)
+	}
+
+	queryLog.Clear()
+	if len(queryLog.Entries(0)) != 0 || len(queryLog.Stats()) != 0 {
+		t.Errorf("expected empty query log after clear")
+	}
+}
+
+func TestQueryLog_Disabled(t *testing.T) {
+	queryLog := NewQueryLog(10, false)
+	queryLog.record(QueryLogEntry{Name: "peera.netbird.cloud.", Rcode: "NOERROR"})
+
+	if len(queryLog.Entries(0)) != 0 {
+		t.Fatalf("disabled query log should not record entries")
+	}
+
+	queryLog.SetEnabled(true)
+	queryLog.record(QueryLogEntry{Name: "peera.netbird.cloud.", Rcode: "NOERROR"})
+
+	if len(queryLog.Entries(0)) != 1 {
+		t.Fatalf("enabled query log should record entries")
+	}
+}
+
+func TestQueryLog_Stats(t *testing.T) {
+	queryLog := NewQueryLog(10, true)
+	queryLog.record(QueryLogEntry{Name: "a.netbird.cloud.", Rcode: "NOERROR", Handler: HandlerLocal, Latency: time.Millisecond})
+	queryLog.record(QueryLogEntry{Name: "a.netbird.cloud.", Rcode: "NXDOMAIN", Handler: HandlerLocal, Latency: 3 * time.Millisecond})
+	queryLog.record(QueryLogEntry{Name: "b.netbird.cloud.", Rcode: "NOERROR", Handler: HandlerHost, Latency: time.Millisecond})
+
+	stats := queryLog.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("expected stats of 2 domains, got %d", len(stats))
+	}
+
+	if stats[0].Domain != "a.netbird.cloud." || stats[0].Queries != 2 || stats[0].Failures != 1 {
+		t.Errorf("unexpected stats for the most queried domain: %+v", stats[0])
+	}
+
+	if stats[0].AverageLatency() != 2*time.Millisecond {
+		t.Errorf("expected average latency of 2ms, got %s", stats[0].AverageLatency())
+	}
+
+	if stats[1].LastHandler != HandlerHost {
+		t.Errorf("expected last handler %s, got %s", HandlerHost, stats[1].LastHandler)
+	}
+}
+
+func TestQueryLogHandler_ServeDNS(t *testing.T) {
+	queryLog := NewQueryLog(10, true)
+	resolver := &localResolver{registeredMap: make(registrationMap)}
+	handler := &queryLogHandler{handlerWithStop: resolver, name: HandlerLocal, queryLog: queryLog}
+
+	handler.ServeDNS(&mockResponseWriter{}, new(dns.Msg).SetQuestion("Peera.Netbird.Cloud.", dns.TypeA))
+
+	entries := queryLog.Entries(0)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Name != "peera.netbird.cloud." || entry.Type != "A" || entry.Rcode != "NOERROR" || entry.Handler != HandlerLocal {
+		t.Errorf("unexpected query log entry: %+v", entry)
+	}
+}
@@ -30,8 +30,10 @@ type Server interface {
 	Stop()
 	DnsIP() string
 	UpdateDNSServer(serial uint64, update nbdns.Config) error
+	UpdateBlockLists(blockLists []*nbdns.BlockList)
 	OnUpdatedHostDNSServer(strings []string)
 	SearchDomains() []string
+	CurrentConfig() HostDNSConfig
 }
 
 type registeredHandlerMap map[string]handlerWithStop
@@ -49,6 +51,8 @@ type DefaultServer struct {
 	updateSerial       uint64
 	previousConfigHash uint64
 	currentConfig      HostDNSConfig
+	queryLog           *QueryLog
+	blockLists         *blockListMatcher
 
 	// permanent related properties
 	permanent        bool
@@ -88,7 +92,21 @@ func NewDefaultServer(ctx context.Context, wgInterface WGIface, customAddress st
 		dnsService = newServiceViaListener(wgInterface, addrPort)
 	}
 
-	return newDefaultServer(ctx, wgInterface, dnsService), nil
+	ds := newDefaultServer(ctx, wgInterface, dnsService)
+	if ns := interfaceNetNS(wgInterface); ns != nil {
+		// the resolver of the namespace is configured by its user, e.g. with /etc/netns/<name>/resolv.conf
+		log.Infof("the DNS configuration of the host is left unchanged, the interface is in network namespace %s", ns)
+		ds.hostManager = newNoopHostMocker()
+	}
+	return ds, nil
+}
+
+// NewDefaultServerWithoutHostDNS returns a new dns server which leaves the DNS configuration of the host unchanged.
+// It is used when the client is embedded into another application, whose interface isn't visible to the host
+func NewDefaultServerWithoutHostDNS(ctx context.Context, wgInterface WGIface) *DefaultServer {
+	ds := newDefaultServer(ctx, wgInterface, newServiceViaMemory(wgInterface))
+	ds.hostManager = newNoopHostMocker()
+	return ds
 }
 
 // NewDefaultServerPermanentUpstream returns a new dns server. It optimized for mobile systems
@@ -123,6 +141,8 @@ func newDefaultServer(ctx context.Context, wgInterface WGIface, dnsService servi
 			registeredMap: make(registrationMap),
 		},
 		wgInterface: wgInterface,
+		queryLog:    GetQueryLog(),
+		blockLists:  newBlockListMatcher(),
 	}
 
 	return defaultServer
@@ -233,6 +253,14 @@ func (s *DefaultServer) UpdateDNSServer(serial uint64, update nbdns.Config) erro
 	}
 }
 
+// UpdateBlockLists replaces the block lists of the applied configuration, e.g. once their domains are fetched
+func (s *DefaultServer) UpdateBlockLists(blockLists []*nbdns.BlockList) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.blockLists.update(blockLists)
+}
+
 func (s *DefaultServer) SearchDomains() []string {
 	var searchDomains []string
 
@@ -248,6 +276,17 @@ func (s *DefaultServer) SearchDomains() []string {
 	return searchDomains
 }
 
+// CurrentConfig returns the DNS configuration applied to the host
+func (s *DefaultServer) CurrentConfig() HostDNSConfig {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	config := s.currentConfig
+	config.Domains = make([]DomainConfig, len(s.currentConfig.Domains))
+	copy(config.Domains, s.currentConfig.Domains)
+	return config
+}
+
 func (s *DefaultServer) applyConfiguration(update nbdns.Config) error {
 	// is the service should be Disabled, we stop the listener or fake resolver
 	// and proceed with a regular update to clean up the handlers and records
@@ -269,6 +308,10 @@ func (s *DefaultServer) applyConfiguration(update nbdns.Config) error {
 
 	s.updateMux(muxUpdates)
 	s.updateLocalResolver(localRecords)
+	s.blockLists.update(update.BlockLists)
+	if len(update.BlockLists) > 0 {
+		s.warnInactiveBlockLists(update, muxUpdates)
+	}
 	s.currentConfig = dnsConfigToHostDNSConfig(update, s.service.RuntimeIP(), s.service.RuntimePort())
 
 	hostUpdate := s.currentConfig
@@ -301,7 +344,7 @@ func (s *DefaultServer) buildLocalHandlerUpdate(customZones []nbdns.CustomZone)
 
 		muxUpdates = append(muxUpdates, muxUpdate{
 			domain:  customZone.Domain,
-			handler: s.localResolver,
+			handler: s.wrapHandler(HandlerLocal, s.localResolver),
 		})
 
 		for _, record := range customZone.Records {
@@ -344,6 +387,8 @@ func (s *DefaultServer) buildUpstreamHandlerUpdate(nameServerGroups []*nbdns.Nam
 			continue
 		}
 
+		wrappedHandler := s.wrapHandler(upstreamHandlerName(handler.upstreamServers), handler)
+
 		// when upstream fails to resolve domain several times over all it servers
 		// it will calls this hook to exclude self from the configuration and
 		// reapply DNS settings, but it not touch the original configuration and serial number
@@ -352,12 +397,12 @@ func (s *DefaultServer) buildUpstreamHandlerUpdate(nameServerGroups []*nbdns.Nam
 		// after some period defined by upstream it tries to reactivate self by calling this hook
 		// everything we need here is just to re-apply current configuration because it already
 		// contains this upstream settings (temporal deactivation not removed it)
-		handler.deactivate, handler.reactivate = s.upstreamCallbacks(nsGroup, handler)
+		handler.deactivate, handler.reactivate = s.upstreamCallbacks(nsGroup, wrappedHandler)
 
 		if nsGroup.Primary {
 			muxUpdates = append(muxUpdates, muxUpdate{
 				domain:  nbdns.RootZone,
-				handler: handler,
+				handler: wrappedHandler,
 			})
 			continue
 		}
@@ -374,7 +419,7 @@ func (s *DefaultServer) buildUpstreamHandlerUpdate(nameServerGroups []*nbdns.Nam
 			}
 			muxUpdates = append(muxUpdates, muxUpdate{
 				domain:  domain,
-				handler: handler,
+				handler: wrappedHandler,
 			})
 		}
 	}
@@ -523,5 +568,19 @@ func (s *DefaultServer) addHostRootZone() {
 	}
 	handler.deactivate = func() {}
 	handler.reactivate = func() {}
-	s.service.RegisterMux(nbdns.RootZone, handler)
+	s.service.RegisterMux(nbdns.RootZone, s.wrapHandler(HandlerHost, handler))
+}
+
+// wrapHandler wraps the handler so that queries for blocked domains are answered before reaching it
+// and the queries it serves are recorded in the query log
+func (s *DefaultServer) wrapHandler(name string, handler handlerWithStop) handlerWithStop {
+	return &blockListHandler{
+		handlerWithStop: &queryLogHandler{
+			handlerWithStop: handler,
+			name:            name,
+			queryLog:        s.queryLog,
+		},
+		blockLists: s.blockLists,
+		queryLog:   s.queryLog,
+	}
 }
@@ -14,6 +14,7 @@ import (
 
 	"github.com/netbirdio/netbird/client/internal/ebpf"
 	ebpfMgr "github.com/netbirdio/netbird/client/internal/ebpf/manager"
+	"github.com/netbirdio/netbird/iface/netns"
 )
 
 const (
@@ -32,6 +33,20 @@ type serviceViaListener struct {
 	listenerIsRunning bool
 	listenerFlagLock  sync.Mutex
 	ebpfService       ebpfMgr.Manager
+	netNS             *netns.NetNS
+}
+
+// netNSIFace is implemented by the interfaces which may be placed in a network namespace
+type netNSIFace interface {
+	NetNS() *netns.NetNS
+}
+
+// interfaceNetNS returns the network namespace of the interface, nil if it is in the namespace of the process
+func interfaceNetNS(wgIface WGIface) *netns.NetNS {
+	if nsIface, ok := wgIface.(netNSIFace); ok {
+		return nsIface.NetNS()
+	}
+	return nil
 }
 
 func newServiceViaListener(wgIface WGIface, customAddr *netip.AddrPort) *serviceViaListener {
@@ -46,6 +61,7 @@ func newServiceViaListener(wgIface WGIface, customAddr *netip.AddrPort) *service
 			Handler: mux,
 			UDPSize: 65535,
 		},
+		netNS: interfaceNetNS(wgIface),
 	}
 
 	return s
@@ -75,12 +91,29 @@ func (s *serviceViaListener) Listen() error {
 			s.ebpfService = nil
 		}
 	}
+	if s.netNS != nil {
+		// the socket is opened in the namespace of the interface and keeps serving it
+		err = s.netNS.Do(func() error {
+			var err error
+			s.server.PacketConn, err = net.ListenPacket("udp", s.server.Addr)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("listen in network namespace %s: %w", s.netNS, err)
+		}
+	}
+
 	log.Debugf("starting dns on %s", s.server.Addr)
 	go func() {
 		s.setListenerStatus(true)
 		defer s.setListenerStatus(false)
 
-		err := s.server.ListenAndServe()
+		var err error
+		if s.server.PacketConn != nil {
+			err = s.server.ActivateAndServe()
+		} else {
+			err = s.server.ListenAndServe()
+		}
 		if err != nil {
 			log.Errorf("dns server running with %d port returned an error: %v. Will not retry", s.listenPort, err)
 		}
@@ -169,7 +202,16 @@ func (s *serviceViaListener) evalListenAddress() (string, int, error) {
 		return s.customAddr.Addr().String(), int(s.customAddr.Port()), nil
 	}
 
-	return s.getFirstListenerAvailable()
+	var (
+		ip   string
+		port int
+	)
+	err := s.netNS.Do(func() error {
+		var err error
+		ip, port, err = s.getFirstListenerAvailable()
+		return err
+	})
+	return ip, port, err
 }
 
 // shouldApplyPortFwd decides whether to apply eBPF program to capture DNS traffic on port 53.
@@ -186,6 +228,11 @@ func (s *serviceViaListener) shouldApplyPortFwd() bool {
 		return false
 	}
 
+	// the forwarder would capture the DNS traffic of the host instead of the one of the namespace
+	if s.netNS != nil {
+		return false
+	}
+
 	if s.listenPort == defaultPort {
 		return false
 	}
@@ -2,25 +2,33 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
 	"net/netip"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/pion/ice/v3"
 	"github.com/pion/stun/v2"
 	log "github.com/sirupsen/logrus"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/netbirdio/netbird/client/firewall"
 	"github.com/netbirdio/netbird/client/firewall/manager"
+	"github.com/netbirdio/netbird/client/firewall/uspfilter"
 	"github.com/netbirdio/netbird/client/internal/acl"
 	"github.com/netbirdio/netbird/client/internal/dns"
+	"github.com/netbirdio/netbird/client/internal/lazyconn"
 	"github.com/netbirdio/netbird/client/internal/peer"
 	"github.com/netbirdio/netbird/client/internal/rosenpass"
 	"github.com/netbirdio/netbird/client/internal/routemanager"
@@ -29,8 +37,10 @@ import (
 	nbdns "github.com/netbirdio/netbird/dns"
 	"github.com/netbirdio/netbird/iface"
 	"github.com/netbirdio/netbird/iface/bind"
+	"github.com/netbirdio/netbird/iface/netstack"
 	mgm "github.com/netbirdio/netbird/management/client"
 	mgmProto "github.com/netbirdio/netbird/management/proto"
+	relayClient "github.com/netbirdio/netbird/relay/client"
 	"github.com/netbirdio/netbird/route"
 	signal "github.com/netbirdio/netbird/signal/client"
 	sProto "github.com/netbirdio/netbird/signal/proto"
@@ -46,6 +56,16 @@ const (
 	PeerConnectionTimeoutMin = 30000 // ms
 )
 
+// offlinePeerBackoffInitial and offlinePeerBackoffMax bound the interval between the connection attempts to a peer
+// which isn't connected to the Signal server. An offer of the peer ends the wait early
+const (
+	offlinePeerBackoffInitial = 2 * time.Second
+	offlinePeerBackoffMax     = 2 * time.Minute
+)
+
+// sshSessionReportRetries is the number of retries to report an SSH session event to the management service
+const sshSessionReportRetries = 5
+
 var ErrResetConnection = fmt.Errorf("reset connection")
 
 // EngineConfig is a config for the Engine
@@ -74,11 +94,19 @@ type EngineConfig struct {
 	// SSHKey is a private SSH key in a PEM format
 	SSHKey []byte
 
+	// SSHKnownHostsFile is the path of the managed known_hosts file with the SSH host keys of the remote peers.
+	// The file isn't written if empty
+	SSHKnownHostsFile string
+
 	NATExternalIPs []string
 
 	CustomDNSAddress string
 
 	RosenpassEnabled bool
+
+	// NetstackOptions creates the interface in a userspace network stack with these options when set, regardless of
+	// the netstack mode of the process. The DNS of the host isn't configured then, the interface isn't visible to it
+	NetstackOptions *netstack.Options
 }
 
 // Engine is a mechanism responsible for reacting on Signal and Management stream events and managing connections to the remote peers.
@@ -89,6 +117,8 @@ type Engine struct {
 	mgmClient mgm.Client
 	// peerConns is a map that holds all the peers that are known to this peer
 	peerConns map[string]*peer.Conn
+	// lazyConnMgr connects to the peers only when there is traffic to them, it is nil if all the peers are connected
+	lazyConnMgr *lazyconn.Manager
 	// rpManager is a Rosenpass manager
 	rpManager *rosenpass.Manager
 
@@ -109,6 +139,8 @@ type Engine struct {
 
 	wgInterface    *iface.WGIface
 	wgProxyFactory *wgproxy.Factory
+	// relayManager connects to the relay servers the peer connections fall back to when ICE fails
+	relayManager *relayClient.Manager
 
 	udpMux *bind.UniversalUDPMuxDefault
 
@@ -125,6 +157,13 @@ type Engine struct {
 	acl          acl.Manager
 
 	dnsServer dns.Server
+	// blockListDomains holds the domains of the DNS block lists by block list ID, they are fetched apart from the
+	// network map
+	blockListDomains map[string]*mgmProto.DNSBlockListDomains
+	// blockLists are the block lists of the latest DNS config, blockListFetches the hashes of the domains being
+	// fetched by block list ID
+	blockLists       []*nbdns.BlockList
+	blockListFetches map[string]string
 }
 
 // Peer is an instance of the Connection Peer
@@ -155,6 +194,7 @@ func NewEngine(
 		sshServerFunc:  nbssh.DefaultSSHServer,
 		statusRecorder: statusRecorder,
 		wgProxyFactory: wgproxy.NewFactory(config.WgPort),
+		relayManager:   relayClient.NewManager(config.WgPrivateKey.PublicKey().String()),
 	}
 }
 
@@ -211,6 +251,7 @@ func (e *Engine) Start() error {
 
 	e.routeManager = routemanager.NewManager(e.ctx, e.config.WgPrivateKey.PublicKey().String(), e.wgInterface, e.statusRecorder, initialRoutes)
 	e.routeManager.SetRouteChangeListener(e.mobileDep.NetworkChangeListener)
+	e.statusRecorder.SetRoutesStateProvider(e.routeManager.RoutesState)
 
 	err = e.wgInterfaceCreate()
 	if err != nil {
@@ -219,7 +260,15 @@ func (e *Engine) Start() error {
 		return err
 	}
 
-	e.firewall, err = firewall.NewFirewall(e.ctx, e.wgInterface)
+	if e.config.NetstackOptions != nil {
+		// the interface isn't visible to the host, its traffic is only filtered in userspace
+		var fw *uspfilter.Manager
+		if fw, err = uspfilter.Create(e.wgInterface); err == nil {
+			e.firewall = fw
+		}
+	} else {
+		e.firewall, err = firewall.NewFirewall(e.ctx, e.wgInterface)
+	}
 	if err != nil {
 		log.Errorf("failed creating firewall manager: %s", err)
 	}
@@ -241,6 +290,7 @@ func (e *Engine) Start() error {
 
 	if e.firewall != nil {
 		e.acl = acl.NewDefaultManager(e.firewall)
+		e.statusRecorder.SetFirewallStateProvider(e.firewallState)
 	}
 
 	err = e.dnsServer.Initialize()
@@ -248,6 +298,9 @@ func (e *Engine) Start() error {
 		e.close()
 		return err
 	}
+	e.statusRecorder.SetDNSStateProvider(e.dnsState)
+
+	e.statusRecorder.SetPeerStatsRefresher(e.updatePeerStats)
 
 	e.receiveSignalEvents()
 	e.receiveManagementEvents()
@@ -343,6 +396,10 @@ func (e *Engine) removePeer(peerKey string) error {
 		}
 	}()
 
+	if e.lazyConnMgr != nil {
+		e.lazyConnMgr.RemovePeer(peerKey)
+	}
+
 	conn, exists := e.peerConns[peerKey]
 	if exists {
 		delete(e.peerConns, peerKey)
@@ -359,12 +416,12 @@ func (e *Engine) removePeer(peerKey string) error {
 	return nil
 }
 
-func signalCandidate(candidate ice.Candidate, myKey wgtypes.Key, remoteKey wgtypes.Key, s signal.Client) error {
+func signalCandidate(candidate ice.Candidate, myKey wgtypes.Key, remoteKey wgtypes.Key, s signal.Client, t sProto.Body_Type) error {
 	err := s.Send(&sProto.Message{
 		Key:       myKey.PublicKey().String(),
 		RemoteKey: remoteKey.String(),
 		Body: &sProto.Body{
-			Type:    sProto.Body_CANDIDATE,
+			Type:    t,
 			Payload: candidate.Marshal(),
 		},
 	})
@@ -389,10 +446,28 @@ func SignalOfferAnswer(offerAnswer peer.OfferAnswer, myKey wgtypes.Key, remoteKe
 		t = sProto.Body_OFFER
 	}
 
+	return signalOfferAnswer(offerAnswer, myKey, remoteKey, s, t)
+}
+
+// signalUpgradeOfferAnswer signals either an upgrade offer or an upgrade answer to remote peer
+func signalUpgradeOfferAnswer(offerAnswer peer.OfferAnswer, myKey wgtypes.Key, remoteKey wgtypes.Key, s signal.Client,
+	isAnswer bool) error {
+	var t sProto.Body_Type
+	if isAnswer {
+		t = sProto.Body_UPGRADE_ANSWER
+	} else {
+		t = sProto.Body_UPGRADE_OFFER
+	}
+
+	return signalOfferAnswer(offerAnswer, myKey, remoteKey, s, t)
+}
+
+func signalOfferAnswer(offerAnswer peer.OfferAnswer, myKey wgtypes.Key, remoteKey wgtypes.Key, s signal.Client,
+	t sProto.Body_Type) error {
 	msg, err := signal.MarshalCredential(myKey, offerAnswer.WgListenPort, remoteKey, &signal.Credential{
 		UFrag: offerAnswer.IceCredentials.UFrag,
 		Pwd:   offerAnswer.IceCredentials.Pwd,
-	}, t, offerAnswer.RosenpassPubKey, offerAnswer.RosenpassAddr)
+	}, t, offerAnswer.RosenpassPubKey, offerAnswer.RosenpassAddr, offerAnswer.RelayServerAddress)
 	if err != nil {
 		return err
 	}
@@ -420,6 +495,8 @@ func (e *Engine) handleSync(update *mgmProto.SyncResponse) error {
 			return err
 		}
 
+		e.updateRelay(update.GetWiretrusteeConfig().GetRelay())
+
 		// todo update signal
 	}
 
@@ -434,6 +511,104 @@ func (e *Engine) handleSync(update *mgmProto.SyncResponse) error {
 	return nil
 }
 
+// updateSSHHostKeys records the SSH host keys of the remote peers in the status recorder so that "netbird ssh" can
+// verify them, and writes the managed known_hosts file if it is enabled
+func (e *Engine) updateSSHHostKeys(remotePeers []*mgmProto.RemotePeerConfig) {
+	var knownHosts []nbssh.KnownHost
+	for _, p := range remotePeers {
+		hostKey := p.GetSshConfig().GetSshPubKey()
+		err := e.statusRecorder.UpdatePeerSSHHostKey(p.GetWgPubKey(), string(hostKey))
+		if err != nil {
+			log.Debugf("failed updating SSH host key of peer %s in the status recorder: %v", p.GetWgPubKey(), err)
+		}
+
+		if len(hostKey) == 0 {
+			continue
+		}
+		knownHosts = append(knownHosts, nbssh.KnownHost{
+			Hosts:   sshKnownHostNames(p),
+			HostKey: hostKey,
+		})
+	}
+
+	if e.config.SSHKnownHostsFile == "" {
+		return
+	}
+
+	err := nbssh.WriteKnownHosts(e.config.SSHKnownHostsFile, knownHosts, nbssh.DefaultSSHPort)
+	if err != nil {
+		log.Errorf("failed writing SSH known hosts file %s: %v", e.config.SSHKnownHostsFile, err)
+	}
+}
+
+// sshKnownHostNames returns the NetBird IP, the FQDN and the short name of the remote peer
+func sshKnownHostNames(p *mgmProto.RemotePeerConfig) []string {
+	var names []string
+	for _, allowedIP := range p.GetAllowedIps() {
+		prefix, err := netip.ParsePrefix(allowedIP)
+		if err != nil || !prefix.IsSingleIP() {
+			continue
+		}
+		names = append(names, prefix.Addr().String())
+	}
+
+	fqdn := strings.TrimSuffix(p.GetFqdn(), ".")
+	if fqdn != "" {
+		names = append(names, fqdn)
+		if name, _, found := strings.Cut(fqdn, "."); found {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// reportSSHSession reports the start or the end of a session of the SSH server to the management service for auditing
+func (e *Engine) reportSSHSession(event nbssh.SessionEvent) {
+	eventType := mgmProto.SSHSessionEvent_STARTED
+	if event.Type == nbssh.SessionEnded {
+		eventType = mgmProto.SSHSessionEvent_ENDED
+	}
+
+	sessionEvent := &mgmProto.SSHSessionEvent{
+		Type:            eventType,
+		SessionId:       event.ID,
+		RemotePeerKey:   event.PeerKey,
+		LocalUser:       event.LocalUser,
+		Timestamp:       timestamppb.New(event.Time),
+		DurationSeconds: int64(event.Duration.Seconds()),
+	}
+
+	go func() {
+		operation := func() error {
+			err := e.mgmClient.ReportSSHSessions([]*mgmProto.SSHSessionEvent{sessionEvent})
+			if isPermanentReportError(err) {
+				return backoff.Permanent(err)
+			}
+			return err
+		}
+		err := backoff.Retry(operation, backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), sshSessionReportRetries), e.ctx))
+		if err != nil {
+			log.Warnf("failed reporting SSH session %s to the management service: %v", event.ID, err)
+		}
+	}()
+}
+
+// isPermanentReportError returns true if the management service refused the reported SSH session, reporting it again
+// fails the same way
+func isPermanentReportError(err error) bool {
+	s, ok := gstatus.FromError(err)
+	if !ok {
+		return false
+	}
+	switch s.Code() {
+	case codes.NotFound, codes.InvalidArgument, codes.PermissionDenied:
+		return true
+	default:
+		return false
+	}
+}
+
 func isNil(server nbssh.Server) bool {
 	return server == nil || reflect.ValueOf(server).IsNil()
 }
@@ -453,6 +628,7 @@ func (e *Engine) updateSSH(sshConf *mgmProto.SSHConfig) error {
 			if err != nil {
 				return err
 			}
+			e.sshServer.SetSessionListener(e.reportSSHSession)
 			go func() {
 				// blocking
 				err = e.sshServer.Start()
@@ -468,6 +644,8 @@ func (e *Engine) updateSSH(sshConf *mgmProto.SSHConfig) error {
 		} else {
 			log.Debugf("SSH server is already running")
 		}
+		e.sshServer.SetPortForwardingEnabled(sshConf.GetPortForwardingEnabled())
+		e.sshServer.SetUserAuthorizationEnabled(sshConf.GetAuthorizeUsers())
 	} else if !isNil(e.sshServer) {
 		// Disable SSH server request, so stop it if it was running
 		err := e.sshServer.Stop()
@@ -498,6 +676,8 @@ func (e *Engine) updateConfig(conf *mgmProto.PeerConfig) error {
 		}
 	}
 
+	e.updateLazyConnection(conf.GetLazyConnectionEnabled())
+
 	e.statusRecorder.UpdateLocalPeerState(peer.LocalPeerState{
 		IP:              e.config.WgAddr,
 		PubKey:          e.config.WgPrivateKey.PublicKey().String(),
@@ -565,6 +745,16 @@ func (e *Engine) updateTURNs(turns []*mgmProto.ProtectedHostConfig) error {
 	return nil
 }
 
+// updateRelay sets the relay servers of the peer connections, the peers only connect to the servers of the
+// Management service and use the first one as their own
+func (e *Engine) updateRelay(relay *mgmProto.RelayConfig) {
+	if len(relay.GetUrls()) == 0 {
+		return
+	}
+	log.Debugf("got relay update from Management Service, updating")
+	e.relayManager.UpdateServers(relay.GetUrls(), relay.GetUser(), relay.GetPassword())
+}
+
 func (e *Engine) updateNetworkMap(networkMap *mgmProto.NetworkMap) error {
 
 	// intentionally leave it before checking serial because for now it can happen that peer IP changed but serial didn't
@@ -585,6 +775,8 @@ func (e *Engine) updateNetworkMap(networkMap *mgmProto.NetworkMap) error {
 
 	e.updateOfflinePeers(networkMap.GetOfflinePeers())
 
+	e.updateLazyConnectionExclusions(networkMap.GetRoutes())
+
 	// cleanup request, most likely our peer has been deleted
 	if networkMap.GetRemotePeersIsEmpty() {
 		err := e.removeAllPeers()
@@ -592,6 +784,8 @@ func (e *Engine) updateNetworkMap(networkMap *mgmProto.NetworkMap) error {
 		if err != nil {
 			return err
 		}
+
+		e.updateSSHHostKeys(nil)
 	} else {
 		err := e.removePeers(networkMap.GetRemotePeers())
 		if err != nil {
@@ -610,6 +804,8 @@ func (e *Engine) updateNetworkMap(networkMap *mgmProto.NetworkMap) error {
 
 		e.statusRecorder.FinishPeerListModifications()
 
+		e.updateSSHHostKeys(networkMap.GetRemotePeers())
+
 		// update SSHServer by adding remote peer SSH keys
 		if !isNil(e.sshServer) {
 			for _, config := range networkMap.GetRemotePeers() {
@@ -618,6 +814,7 @@ func (e *Engine) updateNetworkMap(networkMap *mgmProto.NetworkMap) error {
 					if err != nil {
 						log.Warnf("failed adding authorized key to SSH DefaultServer %v", err)
 					}
+					e.sshServer.SetAuthorizedUsers(config.WgPubKey, config.GetSshConfig().GetAuthorizedUsers())
 				}
 			}
 		}
@@ -636,7 +833,10 @@ func (e *Engine) updateNetworkMap(networkMap *mgmProto.NetworkMap) error {
 		protoDNSConfig = &mgmProto.DNSConfig{}
 	}
 
-	err = e.dnsServer.UpdateDNSServer(serial, toDNSConfig(protoDNSConfig))
+	dnsConfig := toDNSConfig(protoDNSConfig)
+	e.updateBlockListDomains(&dnsConfig)
+
+	err = e.dnsServer.UpdateDNSServer(serial, dnsConfig)
 	if err != nil {
 		log.Errorf("failed to update dns server, err: %v", err)
 	}
@@ -707,9 +907,81 @@ func toDNSConfig(protoDNSConfig *mgmProto.DNSConfig) nbdns.Config {
 		}
 		dnsUpdate.NameServerGroups = append(dnsUpdate.NameServerGroups, dnsNSGroup)
 	}
+
+	for _, blockList := range protoDNSConfig.GetBlockLists() {
+		dnsUpdate.BlockLists = append(dnsUpdate.BlockLists, &nbdns.BlockList{
+			ID:           blockList.GetID(),
+			DomainsHash:  blockList.GetDomainsHash(),
+			AllowDomains: blockList.GetAllowDomains(),
+			Response:     nbdns.BlockResponse(blockList.GetResponse()),
+		})
+	}
 	return dnsUpdate
 }
 
+// updateBlockListDomains sets the domains of the block lists of the DNS config. The network map only carries the
+// hash of the domains, they are fetched from the Management service in the background when the hash changes and
+// applied to the DNS server once fetched. Until then, and if they can't be fetched, a block list keeps its previous
+// domains
+func (e *Engine) updateBlockListDomains(dnsConfig *nbdns.Config) {
+	if e.blockListFetches == nil {
+		e.blockListFetches = make(map[string]string)
+	}
+
+	blockListDomains := make(map[string]*mgmProto.DNSBlockListDomains, len(dnsConfig.BlockLists))
+	for _, blockList := range dnsConfig.BlockLists {
+		domains := e.blockListDomains[blockList.ID]
+		if domains != nil {
+			blockList.Domains = domains.GetDomains()
+			blockListDomains[blockList.ID] = domains
+		}
+
+		if domains.GetDomainsHash() != blockList.DomainsHash && e.blockListFetches[blockList.ID] != blockList.DomainsHash {
+			e.blockListFetches[blockList.ID] = blockList.DomainsHash
+			go e.fetchBlockListDomains(blockList.ID, blockList.DomainsHash)
+		}
+	}
+	e.blockListDomains = blockListDomains
+	e.blockLists = dnsConfig.BlockLists
+}
+
+// fetchBlockListDomains fetches the domains of a block list and applies them to the DNS server if the block list is
+// still configured
+func (e *Engine) fetchBlockListDomains(blockListID, domainsHash string) {
+	fetched, err := e.mgmClient.GetDNSBlockList(blockListID)
+
+	e.syncMsgMux.Lock()
+	defer e.syncMsgMux.Unlock()
+
+	if e.blockListFetches[blockListID] == domainsHash {
+		delete(e.blockListFetches, blockListID)
+	}
+	if err != nil {
+		log.Errorf("failed fetching the domains of DNS block list %s: %v", blockListID, err)
+		return
+	}
+
+	blockLists := make([]*nbdns.BlockList, 0, len(e.blockLists))
+	found := false
+	for _, blockList := range e.blockLists {
+		if blockList.ID == blockListID {
+			blockList = blockList.Copy()
+			blockList.Domains = fetched.GetDomains()
+			found = true
+		}
+		blockLists = append(blockLists, blockList)
+	}
+	if !found {
+		return
+	}
+
+	e.blockListDomains[blockListID] = fetched
+	e.blockLists = blockLists
+	if e.dnsServer != nil {
+		e.dnsServer.UpdateBlockLists(blockLists)
+	}
+}
+
 func (e *Engine) updateOfflinePeers(offlinePeers []*mgmProto.RemotePeerConfig) {
 	replacement := make([]peer.State, len(offlinePeers))
 	for i, offlinePeer := range offlinePeers {
@@ -752,12 +1024,18 @@ func (e *Engine) addNewPeer(peerConfig *mgmProto.RemotePeerConfig) error {
 			log.Warnf("error adding peer %s to status recorder, got error: %v", peerKey, err)
 		}
 
+		if e.lazyConnMgr != nil && !e.lazyConnMgr.AddPeer(lazyPeerConfig(conn)) {
+			e.setPeerIdle(peerKey)
+			return nil
+		}
+
 		go e.connWorker(conn, peerKey)
 	}
 	return nil
 }
 
 func (e *Engine) connWorker(conn *peer.Conn, peerKey string) {
+	offlineBackOff := newOfflinePeerBackOff()
 	for {
 
 		// randomize starting time a bit
@@ -765,9 +1043,8 @@ func (e *Engine) connWorker(conn *peer.Conn, peerKey string) {
 		max := 2000
 		time.Sleep(time.Duration(rand.Intn(max-min)+min) * time.Millisecond)
 
-		// if peer has been removed -> give up
-		if !e.peerExists(peerKey) {
-			log.Debugf("peer %s doesn't exist anymore, won't retry connection", peerKey)
+		// if peer has been removed or its connection has been closed for inactivity -> give up
+		if !e.keepConnecting(conn, peerKey) {
 			return
 		}
 
@@ -784,21 +1061,185 @@ func (e *Engine) connWorker(conn *peer.Conn, peerKey string) {
 		err := conn.Open()
 		if err != nil {
 			log.Debugf("connection to peer %s failed: %v", peerKey, err)
-			switch err.(type) {
-			case *peer.ConnectionClosedError:
-				// conn has been forced to close, so we exit the loop
-				return
-			default:
-			}
 		}
+
+		var offlineErr *peer.ConnectionPeerOfflineError
+		if errors.As(err, &offlineErr) {
+			e.waitForOfflinePeer(conn, peerKey, offlineBackOff)
+		} else {
+			offlineBackOff.Reset()
+		}
+
+		// in lazy mode the connection is attempted again once there is traffic to the peer
+		e.syncMsgMux.Lock()
+		if e.lazyConnMgr != nil {
+			e.lazyConnMgr.DeactivatePeer(peerKey)
+		}
+		e.syncMsgMux.Unlock()
+	}
+}
+
+// waitForOfflinePeer delays the next connection attempt to a peer the Signal server reported as offline. The wait
+// ends early once the peer sends an offer, as it is online then
+func (e *Engine) waitForOfflinePeer(conn *peer.Conn, peerKey string, offlineBackOff backoff.BackOff) {
+	wait := offlineBackOff.NextBackOff()
+	log.Debugf("peer %s is offline, next connection attempt in %s", peerKey, wait)
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-conn.RemoteOfferSkipped():
+		log.Debugf("received an offer of offline peer %s, connecting to it", peerKey)
+		offlineBackOff.Reset()
+	case <-e.ctx.Done():
+	}
+}
+
+// newOfflinePeerBackOff creates the back-off of the connection attempts to an offline peer, it never stops
+func newOfflinePeerBackOff() backoff.BackOff {
+	b := &backoff.ExponentialBackOff{
+		InitialInterval:     offlinePeerBackoffInitial,
+		RandomizationFactor: backoff.DefaultRandomizationFactor,
+		Multiplier:          backoff.DefaultMultiplier,
+		MaxInterval:         offlinePeerBackoffMax,
+		MaxElapsedTime:      0,
+		Stop:                backoff.Stop,
+		Clock:               backoff.SystemClock,
+	}
+	b.Reset()
+	return b
+}
+
+// keepConnecting checks whether the worker of the connection keeps connecting to the peer. In lazy mode an inactive
+// peer waits for traffic instead
+func (e *Engine) keepConnecting(conn *peer.Conn, peerKey string) bool {
+	e.syncMsgMux.Lock()
+	defer e.syncMsgMux.Unlock()
+
+	if e.peerConns[peerKey] != conn {
+		log.Debugf("peer %s doesn't exist anymore, won't retry connection", peerKey)
+		return false
+	}
+
+	if e.lazyConnMgr == nil || e.lazyConnMgr.IsActive(peerKey) {
+		return true
+	}
+
+	if err := e.lazyConnMgr.WaitForActivity(peerKey); err != nil {
+		log.Warnf("failed to wait for traffic to peer %s, connecting to it: %v", peerKey, err)
+		e.lazyConnMgr.ActivatePeer(peerKey)
+		return true
+	}
+	e.setPeerIdle(peerKey)
+	return false
+}
+
+// updateLazyConnection switches between connecting to all the peers and connecting to them only when there is traffic
+func (e *Engine) updateLazyConnection(enabled bool) {
+	if enabled == (e.lazyConnMgr != nil) {
+		return
+	}
+
+	if enabled {
+		log.Infof("lazy connections enabled, the peers are connected when there is traffic to them")
+		e.lazyConnMgr = lazyconn.NewManager(e.wgInterface, e.onLazyPeerActivity, e.onLazyPeerInactivity)
+		e.lazyConnMgr.Start()
+		// the current connections are closed once they are idle
+		for _, conn := range e.peerConns {
+			e.lazyConnMgr.AddActivePeer(lazyPeerConfig(conn))
+		}
+		return
 	}
+
+	log.Infof("lazy connections disabled, connecting to all the peers")
+	lazyConnMgr := e.lazyConnMgr
+	e.lazyConnMgr = nil
+	for peerKey, conn := range e.peerConns {
+		if lazyConnMgr.ActivatePeer(peerKey) {
+			go e.connWorker(conn, peerKey)
+		}
+	}
+	lazyConnMgr.Close()
 }
 
-func (e *Engine) peerExists(peerKey string) bool {
+// updateLazyConnectionExclusions keeps the routing peers connected, the routes are only applied through a connected
+// peer so there is no traffic to detect
+func (e *Engine) updateLazyConnectionExclusions(routes []*mgmProto.Route) {
+	if e.lazyConnMgr == nil {
+		return
+	}
+
+	routingPeers := make(map[string]struct{})
+	for _, r := range routes {
+		routingPeers[r.GetPeer()] = struct{}{}
+	}
+
+	for _, peerKey := range e.lazyConnMgr.SetExcludedPeers(routingPeers) {
+		if conn, ok := e.peerConns[peerKey]; ok {
+			log.Debugf("connecting to routing peer %s", peerKey)
+			go e.connWorker(conn, peerKey)
+		}
+	}
+}
+
+// onLazyPeerActivity connects to an inactive peer once there is traffic to it
+func (e *Engine) onLazyPeerActivity(peerKey string) {
 	e.syncMsgMux.Lock()
 	defer e.syncMsgMux.Unlock()
-	_, ok := e.peerConns[peerKey]
-	return ok
+
+	e.activateLazyPeer(peerKey)
+}
+
+func (e *Engine) activateLazyPeer(peerKey string) {
+	if e.lazyConnMgr == nil {
+		return
+	}
+
+	conn, ok := e.peerConns[peerKey]
+	if !ok || !e.lazyConnMgr.ActivatePeer(peerKey) {
+		return
+	}
+
+	log.Debugf("connecting to inactive peer %s", peerKey)
+	go e.connWorker(conn, peerKey)
+}
+
+// onLazyPeerInactivity closes the connection to a peer that has been idle, its worker waits for traffic to the peer
+// then
+func (e *Engine) onLazyPeerInactivity(peerKey string) {
+	e.syncMsgMux.Lock()
+	defer e.syncMsgMux.Unlock()
+
+	conn, ok := e.peerConns[peerKey]
+	if !ok || e.lazyConnMgr == nil {
+		return
+	}
+
+	if err := conn.Close(); err != nil {
+		log.Debugf("failed to close the connection to idle peer %s: %v", peerKey, err)
+	}
+}
+
+func (e *Engine) setPeerIdle(peerKey string) {
+	err := e.statusRecorder.UpdatePeerState(peer.State{
+		PubKey:           peerKey,
+		ConnStatus:       peer.StatusIdle,
+		ConnStatusUpdate: time.Now(),
+	})
+	if err != nil {
+		log.Debugf("failed to update the state of idle peer %s: %v", peerKey, err)
+	}
+}
+
+func lazyPeerConfig(conn *peer.Conn) lazyconn.PeerConfig {
+	wgConfig := conn.WgConfig()
+	return lazyconn.PeerConfig{
+		PublicKey:    wgConfig.RemoteKey,
+		AllowedIPs:   wgConfig.AllowedIps,
+		PreSharedKey: wgConfig.PreSharedKey,
+	}
 }
 
 func (e *Engine) createPeerConn(pubKey string, allowedIPs string) (*peer.Conn, error) {
@@ -852,6 +1293,7 @@ func (e *Engine) createPeerConn(pubKey string, allowedIPs string) (*peer.Conn, e
 		UserspaceBind:        e.wgInterface.IsUserspaceBind(),
 		RosenpassPubKey:      e.getRosenpassPubKey(),
 		RosenpassAddr:        e.getRosenpassAddr(),
+		RelayManager:         e.relayManager,
 	}
 
 	peerConn, err := peer.NewConn(config, e.statusRecorder, e.wgProxyFactory, e.mobileDep.TunAdapter, e.mobileDep.IFaceDiscover)
@@ -868,8 +1310,12 @@ func (e *Engine) createPeerConn(pubKey string, allowedIPs string) (*peer.Conn, e
 		return SignalOfferAnswer(offerAnswer, e.config.WgPrivateKey, wgPubKey, e.signal, false)
 	}
 
+	signalUpgradeCandidate := func(candidate ice.Candidate) error {
+		return signalCandidate(candidate, e.config.WgPrivateKey, wgPubKey, e.signal, sProto.Body_UPGRADE_CANDIDATE)
+	}
+
 	signalCandidate := func(candidate ice.Candidate) error {
-		return signalCandidate(candidate, e.config.WgPrivateKey, wgPubKey, e.signal)
+		return signalCandidate(candidate, e.config.WgPrivateKey, wgPubKey, e.signal, sProto.Body_CANDIDATE)
 	}
 
 	signalAnswer := func(offerAnswer peer.OfferAnswer) error {
@@ -882,6 +1328,10 @@ func (e *Engine) createPeerConn(pubKey string, allowedIPs string) (*peer.Conn, e
 	peerConn.SetSendSignalMessage(func(message *sProto.Message) error {
 		return sendSignal(message, e.signal)
 	})
+	peerConn.SetSignalUpgrade(func(offerAnswer peer.OfferAnswer, isAnswer bool) error {
+		return signalUpgradeOfferAnswer(offerAnswer, e.config.WgPrivateKey, wgPubKey, e.signal, isAnswer)
+	})
+	peerConn.SetSignalUpgradeCandidate(signalUpgradeCandidate)
 
 	if e.rpManager != nil {
 
@@ -905,55 +1355,28 @@ func (e *Engine) receiveSignalEvents() {
 				return fmt.Errorf("wrongly addressed message %s", msg.Key)
 			}
 
+			// the remote peer has traffic to this peer
+			if msg.GetBody().Type == sProto.Body_OFFER {
+				e.activateLazyPeer(msg.Key)
+			}
+
 			switch msg.GetBody().Type {
 			case sProto.Body_OFFER:
-				remoteCred, err := signal.UnMarshalCredential(msg)
+				offer, err := offerAnswerFromMessage(msg)
 				if err != nil {
 					return err
 				}
 
 				conn.RegisterProtoSupportMeta(msg.Body.GetFeaturesSupported())
-
-				var rosenpassPubKey []byte
-				rosenpassAddr := ""
-				if msg.GetBody().GetRosenpassConfig() != nil {
-					rosenpassPubKey = msg.GetBody().GetRosenpassConfig().GetRosenpassPubKey()
-					rosenpassAddr = msg.GetBody().GetRosenpassConfig().GetRosenpassServerAddr()
-				}
-				conn.OnRemoteOffer(peer.OfferAnswer{
-					IceCredentials: peer.IceCredentials{
-						UFrag: remoteCred.UFrag,
-						Pwd:   remoteCred.Pwd,
-					},
-					WgListenPort:    int(msg.GetBody().GetWgListenPort()),
-					Version:         msg.GetBody().GetNetBirdVersion(),
-					RosenpassPubKey: rosenpassPubKey,
-					RosenpassAddr:   rosenpassAddr,
-				})
+				conn.OnRemoteOffer(offer)
 			case sProto.Body_ANSWER:
-				remoteCred, err := signal.UnMarshalCredential(msg)
+				answer, err := offerAnswerFromMessage(msg)
 				if err != nil {
 					return err
 				}
 
 				conn.RegisterProtoSupportMeta(msg.GetBody().GetFeaturesSupported())
-
-				var rosenpassPubKey []byte
-				rosenpassAddr := ""
-				if msg.GetBody().GetRosenpassConfig() != nil {
-					rosenpassPubKey = msg.GetBody().GetRosenpassConfig().GetRosenpassPubKey()
-					rosenpassAddr = msg.GetBody().GetRosenpassConfig().GetRosenpassServerAddr()
-				}
-				conn.OnRemoteAnswer(peer.OfferAnswer{
-					IceCredentials: peer.IceCredentials{
-						UFrag: remoteCred.UFrag,
-						Pwd:   remoteCred.Pwd,
-					},
-					WgListenPort:    int(msg.GetBody().GetWgListenPort()),
-					Version:         msg.GetBody().GetNetBirdVersion(),
-					RosenpassPubKey: rosenpassPubKey,
-					RosenpassAddr:   rosenpassAddr,
-				})
+				conn.OnRemoteAnswer(answer)
 			case sProto.Body_CANDIDATE:
 				candidate, err := ice.UnmarshalCandidate(msg.GetBody().Payload)
 				if err != nil {
@@ -961,6 +1384,25 @@ func (e *Engine) receiveSignalEvents() {
 					return err
 				}
 				conn.OnRemoteCandidate(candidate)
+			case sProto.Body_UPGRADE_OFFER:
+				offer, err := offerAnswerFromMessage(msg)
+				if err != nil {
+					return err
+				}
+				conn.OnRemoteUpgradeOffer(offer)
+			case sProto.Body_UPGRADE_ANSWER:
+				answer, err := offerAnswerFromMessage(msg)
+				if err != nil {
+					return err
+				}
+				conn.OnRemoteUpgradeAnswer(answer)
+			case sProto.Body_UPGRADE_CANDIDATE:
+				candidate, err := ice.UnmarshalCandidate(msg.GetBody().Payload)
+				if err != nil {
+					log.Errorf("failed on parsing remote upgrade candidate %s -> %s", candidate, err)
+					return err
+				}
+				conn.OnRemoteUpgradeCandidate(candidate)
 			case sProto.Body_MODE:
 			}
 
@@ -978,6 +1420,33 @@ func (e *Engine) receiveSignalEvents() {
 	e.signal.WaitStreamConnected()
 }
 
+// offerAnswerFromMessage parses the offer or answer of the remote peer from a signal message
+func offerAnswerFromMessage(msg *sProto.Message) (peer.OfferAnswer, error) {
+	remoteCred, err := signal.UnMarshalCredential(msg)
+	if err != nil {
+		return peer.OfferAnswer{}, err
+	}
+
+	var rosenpassPubKey []byte
+	rosenpassAddr := ""
+	if msg.GetBody().GetRosenpassConfig() != nil {
+		rosenpassPubKey = msg.GetBody().GetRosenpassConfig().GetRosenpassPubKey()
+		rosenpassAddr = msg.GetBody().GetRosenpassConfig().GetRosenpassServerAddr()
+	}
+
+	return peer.OfferAnswer{
+		IceCredentials: peer.IceCredentials{
+			UFrag: remoteCred.UFrag,
+			Pwd:   remoteCred.Pwd,
+		},
+		WgListenPort:       int(msg.GetBody().GetWgListenPort()),
+		Version:            msg.GetBody().GetNetBirdVersion(),
+		RosenpassPubKey:    rosenpassPubKey,
+		RosenpassAddr:      rosenpassAddr,
+		RelayServerAddress: msg.GetBody().GetRelayServerAddress(),
+	}, nil
+}
+
 func (e *Engine) parseNATExternalIPMappings() []string {
 	var mappedIPs []string
 	var ignoredIFaces = make(map[string]interface{})
@@ -1031,10 +1500,19 @@ func (e *Engine) parseNATExternalIPMappings() []string {
 }
 
 func (e *Engine) close() {
+	e.statusRecorder.SetPeerStatsRefresher(nil)
+
+	if e.lazyConnMgr != nil {
+		e.lazyConnMgr.Close()
+		e.lazyConnMgr = nil
+	}
+
 	if err := e.wgProxyFactory.Free(); err != nil {
 		log.Errorf("failed closing ebpf proxy: %s", err)
 	}
 
+	e.relayManager.Close()
+
 	log.Debugf("removing Netbird interface %s", e.config.WgIfaceName)
 	if e.wgInterface != nil {
 		if err := e.wgInterface.Close(); err != nil {
@@ -1050,14 +1528,17 @@ func (e *Engine) close() {
 	}
 
 	if e.routeManager != nil {
+		e.statusRecorder.SetRoutesStateProvider(nil)
 		e.routeManager.Stop()
 	}
 
 	if e.dnsServer != nil {
+		e.statusRecorder.SetDNSStateProvider(nil)
 		e.dnsServer.Stop()
 	}
 
 	if e.firewall != nil {
+		e.statusRecorder.SetFirewallStateProvider(nil)
 		err := e.firewall.Reset()
 		if err != nil {
 			log.Warnf("failed to reset firewall: %s", err)
@@ -1069,6 +1550,109 @@ func (e *Engine) close() {
 	}
 }
 
+// updatePeerStats reads the WireGuard statistics and the latency of the connected peers into the status recorder
+func (e *Engine) updatePeerStats() {
+	e.syncMsgMux.Lock()
+	defer e.syncMsgMux.Unlock()
+
+	if e.wgInterface == nil {
+		return
+	}
+	stats, err := e.wgInterface.GetStats()
+	if err != nil {
+		log.Debugf("failed to get WireGuard stats: %v", err)
+		return
+	}
+
+	for key, conn := range e.peerConns {
+		latency, _ := conn.Latency()
+		if err := e.statusRecorder.UpdateWireguardPeerState(key, stats[key], latency); err != nil {
+			log.Debugf("failed to update stats of peer %s: %v", key, err)
+		}
+	}
+}
+
+// firewallState returns the firewall rules applied from the management with their counters
+func (e *Engine) firewallState() (*peer.FirewallState, error) {
+	if e.acl == nil {
+		return nil, fmt.Errorf("firewall is not enabled")
+	}
+
+	stats, err := e.acl.GetStats()
+	if err != nil {
+		return nil, err
+	}
+
+	state := &peer.FirewallState{
+		Rules:             make([]peer.FirewallRuleState, 0, len(stats.Rules)),
+		DroppedInPackets:  stats.DroppedIn.Packets,
+		DroppedInBytes:    stats.DroppedIn.Bytes,
+		DroppedOutPackets: stats.DroppedOut.Packets,
+		DroppedOutBytes:   stats.DroppedOut.Bytes,
+	}
+	for _, ruleStats := range stats.Rules {
+		state.Rules = append(state.Rules, peer.FirewallRuleState{
+			PeerIP:    ruleStats.Rule.PeerIP,
+			Direction: ruleStats.Rule.Direction.String(),
+			Action:    ruleStats.Rule.Action.String(),
+			Protocol:  ruleStats.Rule.Protocol.String(),
+			Port:      ruleStats.Rule.Port,
+			Packets:   ruleStats.Counters.Packets,
+			Bytes:     ruleStats.Counters.Bytes,
+		})
+	}
+	return state, nil
+}
+
+// dnsState returns the DNS configuration the DNS server applied to the host
+func (e *Engine) dnsState() peer.DNSState {
+	config := e.dnsServer.CurrentConfig()
+
+	state := peer.DNSState{
+		RouteAll: config.RouteAll,
+		Domains:  make([]peer.DNSDomainState, 0, len(config.Domains)),
+	}
+	if config.ServerIP != "" {
+		state.ServerAddress = net.JoinHostPort(config.ServerIP, strconv.Itoa(config.ServerPort))
+	}
+	for _, domain := range config.Domains {
+		state.Domains = append(state.Domains, peer.DNSDomainState{
+			Domain:    domain.Domain,
+			MatchOnly: domain.MatchOnly,
+			Disabled:  domain.Disabled,
+		})
+	}
+	return state
+}
+
+// GetDNSServerAddress returns the address of the DNS server of the peer, which resolves the names of the peers.
+// It is empty until the server is configured
+func (e *Engine) GetDNSServerAddress() string {
+	e.syncMsgMux.Lock()
+	defer e.syncMsgMux.Unlock()
+
+	if e.dnsServer == nil {
+		return ""
+	}
+	config := e.dnsServer.CurrentConfig()
+	if config.ServerIP == "" {
+		return ""
+	}
+	return net.JoinHostPort(config.ServerIP, strconv.Itoa(config.ServerPort))
+}
+
+// GetNet returns the network to dial and listen on the peer address when the interface runs in a userspace
+// network stack, nil otherwise
+func (e *Engine) GetNet() *netstack.Net {
+	e.syncMsgMux.Lock()
+	defer e.syncMsgMux.Unlock()
+
+	if e.wgInterface == nil {
+		return nil
+	}
+	return e.wgInterface.GetNet()
+}
+
 func (e *Engine) readInitialSettings() ([]*route.Route, *nbdns.Config, error) {
 	netMap, err := e.mgmClient.GetNetworkMap()
 	if err != nil {
@@ -1076,6 +1660,7 @@ func (e *Engine) readInitialSettings() ([]*route.Route, *nbdns.Config, error) {
 	}
 	routes := toRoutes(netMap.GetRoutes())
 	dnsCfg := toDNSConfig(netMap.GetDNSConfig())
+	e.updateBlockListDomains(&dnsCfg)
 	return routes, &dnsCfg, nil
 }
 
@@ -1099,6 +1684,9 @@ func (e *Engine) newWgIface() (*iface.WGIface, error) {
 	default:
 	}
 
+	if e.config.NetstackOptions != nil {
+		return iface.NewWGIFaceNetstack(e.config.WgIfaceName, e.config.WgAddr, e.config.WgPort, e.config.WgPrivateKey.String(), iface.DefaultMTU, transportNet, *e.config.NetstackOptions)
+	}
 	return iface.NewWGIFace(e.config.WgIfaceName, e.config.WgAddr, e.config.WgPort, e.config.WgPrivateKey.String(), iface.DefaultMTU, transportNet, mArgs)
 }
 
@@ -1133,6 +1721,9 @@ func (e *Engine) newDnsServer() ([]*route.Route, dns.Server, error) {
 		dnsServer := dns.NewDefaultServerIos(e.ctx, e.wgInterface, e.mobileDep.DnsManager)
 		return nil, dnsServer, nil
 	default:
+		if e.config.NetstackOptions != nil {
+			return nil, dns.NewDefaultServerWithoutHostDNS(e.ctx, e.wgInterface), nil
+		}
 		dnsServer, err := dns.NewDefaultServer(e.ctx, e.wgInterface, e.config.CustomDNSAddress)
 		if err != nil {
 			return nil, nil, err
@@ -10,16 +10,20 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/pion/transport/v3/stdnet"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/keepalive"
+	gstatus "google.golang.org/grpc/status"
 
 	"github.com/netbirdio/netbird/client/internal/dns"
 	"github.com/netbirdio/netbird/client/internal/peer"
@@ -601,6 +605,84 @@ func TestEngine_UpdateNetworkMapWithRoutes(t *testing.T) {
 	}
 }
 
+func TestEngine_LazyConnection(t *testing.T) {
+	key, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wgAddr := "100.67.0.1/24"
+	engine := NewEngine(ctx, cancel, &signal.MockClient{}, &mgmt.MockClient{}, &EngineConfig{
+		WgIfaceName:  "utun110",
+		WgAddr:       wgAddr,
+		WgPrivateKey: key,
+		WgPort:       33110,
+	}, MobileDependency{}, peer.NewRecorder("https://mgm"))
+	newNet, err := stdnet.NewNet()
+	require.NoError(t, err)
+	engine.wgInterface, err = iface.NewWGIFace("utun110", wgAddr, engine.config.WgPort, key.String(), iface.DefaultMTU, newNet, nil)
+	require.NoError(t, err)
+	require.NoError(t, engine.wgInterface.Create())
+	defer func() {
+		_ = engine.wgInterface.Close()
+	}()
+
+	engine.routeManager = &routemanager.MockManager{}
+	engine.dnsServer = &dns.MockServer{}
+	conn, err := net.ListenUDP("udp4", nil)
+	require.NoError(t, err)
+	engine.udpMux = bind.NewUniversalUDPMuxDefault(bind.UniversalUDPMuxParams{UDPConn: conn})
+
+	idlePeer := &mgmtProto.RemotePeerConfig{
+		WgPubKey:   "RRHf3Ma6z6mdLbriAJbqhX7+nM/B71lgw2+91q3LfhU=",
+		AllowedIps: []string{"100.67.0.10/32"},
+	}
+	routingPeer := &mgmtProto.RemotePeerConfig{
+		WgPubKey:   "LLHf3Ma6z6mdLbriAJbqhX7+nM/B71lgw2+91q3LfhU=",
+		AllowedIps: []string{"100.67.0.11/32"},
+	}
+
+	networkMap := &mgmtProto.NetworkMap{
+		Serial:      1,
+		PeerConfig:  &mgmtProto.PeerConfig{Address: wgAddr, LazyConnectionEnabled: true},
+		RemotePeers: []*mgmtProto.RemotePeerConfig{idlePeer, routingPeer},
+		Routes: []*mgmtProto.Route{
+			{ID: "a", Network: "192.168.0.0/24", NetID: "n1", Peer: routingPeer.GetWgPubKey(), NetworkType: 1},
+		},
+	}
+
+	engine.syncMsgMux.Lock()
+	err = engine.updateNetworkMap(networkMap)
+	engine.syncMsgMux.Unlock()
+	require.NoError(t, err)
+	require.NotNil(t, engine.lazyConnMgr)
+
+	assert.False(t, engine.lazyConnMgr.IsActive(idlePeer.GetWgPubKey()), "the peer should wait for traffic")
+	assert.True(t, engine.lazyConnMgr.IsActive(routingPeer.GetWgPubKey()), "a routing peer should be connected")
+
+	state, err := engine.statusRecorder.GetPeer(idlePeer.GetWgPubKey())
+	require.NoError(t, err)
+	assert.Equal(t, peer.StatusIdle, state.ConnStatus)
+
+	stats, err := engine.wgInterface.GetStats()
+	require.NoError(t, err)
+	assert.Contains(t, stats, idlePeer.GetWgPubKey(), "the WireGuard peer of an idle peer should be configured")
+
+	networkMap.Serial = 2
+	networkMap.PeerConfig.LazyConnectionEnabled = false
+	engine.syncMsgMux.Lock()
+	err = engine.updateNetworkMap(networkMap)
+	engine.syncMsgMux.Unlock()
+	require.NoError(t, err)
+	assert.Nil(t, engine.lazyConnMgr, "all the peers should be connected")
+
+	engine.syncMsgMux.Lock()
+	err = engine.removeAllPeers()
+	engine.syncMsgMux.Unlock()
+	require.NoError(t, err)
+}
+
 func TestEngine_UpdateNetworkMapWithDNSUpdate(t *testing.T) {
 	testCases := []struct {
 		name                string
@@ -782,6 +864,85 @@ func TestEngine_UpdateNetworkMapWithDNSUpdate(t *testing.T) {
 	}
 }
 
+func TestEngine_updateBlockListDomains(t *testing.T) {
+	var fetches atomic.Int32
+	var failFetches atomic.Bool
+	applied := make(chan []*nbdns.BlockList, 1)
+	engine := &Engine{
+		syncMsgMux: &sync.Mutex{},
+		mgmClient: &mgmt.MockClient{
+			GetDNSBlockListFunc: func(blockListID string) (*mgmtProto.DNSBlockListDomains, error) {
+				n := fetches.Add(1)
+				if failFetches.Load() {
+					return nil, fmt.Errorf("unavailable")
+				}
+				return &mgmtProto.DNSBlockListDomains{
+					DomainsHash: fmt.Sprintf("hash%d", n),
+					Domains:     []string{fmt.Sprintf("ads%d.example.com", n)},
+				}, nil
+			},
+		},
+		dnsServer: &dns.MockServer{
+			UpdateBlockListsFunc: func(blockLists []*nbdns.BlockList) {
+				applied <- blockLists
+			},
+		},
+	}
+
+	dnsConfig := func(hash string) *nbdns.Config {
+		return &nbdns.Config{BlockLists: []*nbdns.BlockList{{ID: "blocklist", DomainsHash: hash}}}
+	}
+	update := func(config *nbdns.Config) {
+		engine.syncMsgMux.Lock()
+		defer engine.syncMsgMux.Unlock()
+		engine.updateBlockListDomains(config)
+	}
+	waitFetches := func() {
+		require.Eventually(t, func() bool {
+			engine.syncMsgMux.Lock()
+			defer engine.syncMsgMux.Unlock()
+			return len(engine.blockListFetches) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	config := dnsConfig("hash1")
+	update(config)
+	assert.Empty(t, config.BlockLists[0].Domains, "domains shouldn't be fetched synchronously")
+	select {
+	case blockLists := <-applied:
+		require.Len(t, blockLists, 1)
+		assert.Equal(t, []string{"ads1.example.com"}, blockLists[0].Domains)
+	case <-time.After(5 * time.Second):
+		t.Fatal("fetched domains weren't applied to the DNS server")
+	}
+
+	config = dnsConfig("hash1")
+	update(config)
+	waitFetches()
+	assert.Equal(t, int32(1), fetches.Load(), "domains with an unchanged hash shouldn't be fetched again")
+	assert.Equal(t, []string{"ads1.example.com"}, config.BlockLists[0].Domains)
+
+	failFetches.Store(true)
+	config = dnsConfig("hash2")
+	update(config)
+	waitFetches()
+	assert.Equal(t, int32(2), fetches.Load())
+	assert.Equal(t, []string{"ads1.example.com"}, config.BlockLists[0].Domains, "previous domains should be kept on failure")
+	assert.Empty(t, applied, "nothing should be applied on failure")
+
+	failFetches.Store(false)
+	update(dnsConfig("hash3"))
+	select {
+	case blockLists := <-applied:
+		assert.Equal(t, []string{"ads3.example.com"}, blockLists[0].Domains)
+	case <-time.After(5 * time.Second):
+		t.Fatal("fetched domains weren't applied to the DNS server")
+	}
+
+	update(&nbdns.Config{})
+	assert.Empty(t, engine.blockListDomains, "domains of removed block lists should be dropped")
+}
+
 func TestEngine_MultiplePeers(t *testing.T) {
 	// log.SetLevel(log.DebugLevel)
 
@@ -892,6 +1053,93 @@ loop:
 	}
 }
 
+func TestEngine_UpdateSSHHostKeys(t *testing.T) {
+	key, err := ssh.GeneratePrivateKey(ssh.ED25519)
+	require.NoError(t, err)
+	hostKey, err := ssh.GeneratePublicKey(key)
+	require.NoError(t, err)
+
+	knownHostsFile := filepath.Join(t.TempDir(), "ssh_known_hosts")
+	engine := &Engine{
+		config:         &EngineConfig{SSHKnownHostsFile: knownHostsFile},
+		statusRecorder: peer.NewRecorder("https://mgm"),
+	}
+	require.NoError(t, engine.statusRecorder.AddPeer("peerA", "peer-a.netbird.cloud"))
+
+	engine.updateSSHHostKeys([]*mgmtProto.RemotePeerConfig{
+		{
+			WgPubKey:   "peerA",
+			AllowedIps: []string{"100.64.0.10/32"},
+			SshConfig:  &mgmtProto.SSHConfig{SshPubKey: hostKey},
+			Fqdn:       "peer-a.netbird.cloud",
+		},
+	})
+
+	state, err := engine.statusRecorder.GetPeer("peerA")
+	require.NoError(t, err)
+	assert.Equal(t, string(hostKey), state.SSHHostKey)
+
+	content, err := os.ReadFile(knownHostsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "[100.64.0.10]:44338,[peer-a.netbird.cloud]:44338,[peer-a]:44338 ssh-ed25519 ")
+
+	engine.updateSSHHostKeys(nil)
+	content, err = os.ReadFile(knownHostsFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "peer-a", "removed peers should be removed from the known hosts file")
+}
+
+func TestEngine_waitForOfflinePeer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	engine := &Engine{ctx: ctx}
+
+	conn, err := peer.NewConn(peer.ConnConfig{Key: "LLHf3Ma6z6mdLbriAJbqhX7+nM/B71lgw2+91q3LfhU="}, nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		engine.waitForOfflinePeer(conn, conn.GetKey(), &backoff.ConstantBackOff{Interval: time.Minute})
+		close(done)
+	}()
+
+	// the offer of the peer coming online isn't awaited by a connection attempt
+	accepted := conn.OnRemoteOffer(peer.OfferAnswer{})
+	require.False(t, accepted)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the wait to end once the offline peer sends an offer")
+	}
+}
+
+func TestNewOfflinePeerBackOff(t *testing.T) {
+	b := newOfflinePeerBackOff()
+
+	previous := time.Duration(0)
+	for i := 0; i < 20; i++ {
+		wait := b.NextBackOff()
+		require.NotEqual(t, backoff.Stop, wait, "the back-off never stops")
+		require.LessOrEqual(t, wait, offlinePeerBackoffMax+offlinePeerBackoffMax/2)
+		previous = wait
+	}
+	assert.Greater(t, previous, offlinePeerBackoffInitial*2, "the back-off grows")
+
+	b.Reset()
+	assert.LessOrEqual(t, b.NextBackOff(), offlinePeerBackoffInitial+offlinePeerBackoffInitial/2)
+}
+
+func TestIsPermanentReportError(t *testing.T) {
+	assert.False(t, isPermanentReportError(nil))
+	assert.False(t, isPermanentReportError(fmt.Errorf("no connection to management")))
+	assert.False(t, isPermanentReportError(gstatus.Error(codes.Unavailable, "unavailable")))
+	assert.False(t, isPermanentReportError(gstatus.Error(codes.Internal, "failed handling request")))
+	assert.True(t, isPermanentReportError(gstatus.Error(codes.NotFound, "remote peer not found")))
+	assert.True(t, isPermanentReportError(gstatus.Error(codes.InvalidArgument, "invalid event")))
+	assert.True(t, isPermanentReportError(gstatus.Error(codes.PermissionDenied, "peer login has expired")))
+}
+
 func Test_ParseNATExternalIPMappings(t *testing.T) {
 	ifaceList, err := net.Interfaces()
 	if err != nil {
@@ -1054,7 +1302,7 @@ func startManagement(dataDir string) (*grpc.Server, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
-	turnManager := server.NewTimeBasedAuthSecretsManager(peersUpdateManager, config.TURNConfig)
+	turnManager := server.NewTimeBasedAuthSecretsManager(peersUpdateManager, config.TURNConfig, config.Relay)
 	mgmtServer, err := server.NewServer(config, accountManager, peersUpdateManager, turnManager, nil, nil)
 	if err != nil {
 		return nil, "", err
@@ -0,0 +1,51 @@
+package lazyconn
+
+import (
+	"net"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// activityListener is the WireGuard endpoint of an inactive peer. The interface sends a handshake initiation to it
+// once there is traffic to the peer
+type activityListener struct {
+	peerKey string
+	conn    *net.UDPConn
+}
+
+func newActivityListener(peerKey string, onActivity func(*activityListener)) (*activityListener, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return nil, err
+	}
+
+	l := &activityListener{
+		peerKey: peerKey,
+		conn:    conn,
+	}
+	go l.waitForActivity(onActivity)
+	return l, nil
+}
+
+func (l *activityListener) waitForActivity(onActivity func(*activityListener)) {
+	// large enough for a handshake initiation, a shorter buffer fails the read on some platforms
+	buf := make([]byte, 1500)
+	if _, _, err := l.conn.ReadFromUDP(buf); err != nil {
+		// the listener has been closed
+		return
+	}
+
+	log.Debugf("detected traffic to inactive peer %s", l.peerKey)
+	onActivity(l)
+}
+
+// addr returns the endpoint the WireGuard peer is configured with
+func (l *activityListener) addr() *net.UDPAddr {
+	return l.conn.LocalAddr().(*net.UDPAddr)
+}
+
+func (l *activityListener) close() {
+	if err := l.conn.Close(); err != nil {
+		log.Debugf("failed to close the activity listener of peer %s: %v", l.peerKey, err)
+	}
+}
@@ -0,0 +1,32 @@
+package lazyconn
+
+import (
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	envInactivityThreshold = "NB_LAZY_CONN_INACTIVITY_THRESHOLD"
+
+	// DefaultInactivityThreshold is the time without traffic after which the connection to a peer is closed
+	DefaultInactivityThreshold = 15 * time.Minute
+	minInactivityThreshold     = time.Minute
+)
+
+func inactivityThreshold() time.Duration {
+	thresholdEnv := os.Getenv(envInactivityThreshold)
+	if thresholdEnv == "" {
+		return DefaultInactivityThreshold
+	}
+
+	threshold, err := time.ParseDuration(thresholdEnv)
+	if err != nil || threshold < minInactivityThreshold {
+		log.Warnf("invalid value %s set for %s, using default %v", thresholdEnv, envInactivityThreshold, DefaultInactivityThreshold)
+		return DefaultInactivityThreshold
+	}
+
+	log.Debugf("setting the lazy connection inactivity threshold to %s", threshold)
+	return threshold
+}
@@ -0,0 +1,361 @@
+// Package lazyconn keeps the connections to the remote peers inactive until there is traffic to them.
+//
+// The WireGuard peer of an inactive connection is configured with a local endpoint, the first handshake initiation
+// the interface sends to it starts the connection. This works the same for the kernel and the userspace interfaces.
+// A connection is closed again once it has been idle for a while.
+package lazyconn
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/netbirdio/netbird/iface"
+)
+
+const (
+	inactivityCheckInterval = time.Minute
+
+	// an idle WireGuard connection still exchanges keep alive messages and a handshake every rekey interval
+	keepAliveBytes    = 32
+	keepAliveInterval = 25 * time.Second
+	handshakeBytes    = 148
+	rekeyInterval     = 2 * time.Minute
+)
+
+// WGIface configures the WireGuard peers of the inactive connections
+type WGIface interface {
+	UpdatePeer(peerKey string, allowedIps string, keepAlive time.Duration, endpoint *net.UDPAddr, preSharedKey *wgtypes.Key) error
+	RemovePeer(peerKey string) error
+	GetStats() (map[string]iface.WGStats, error)
+}
+
+// PeerConfig is the WireGuard configuration of a remote peer
+type PeerConfig struct {
+	PublicKey    string
+	AllowedIPs   string
+	PreSharedKey *wgtypes.Key
+}
+
+type managedPeer struct {
+	config PeerConfig
+	active bool
+	// listener is set while the peer is inactive and waits for traffic. An inactive peer without a listener is still
+	// being disconnected
+	listener *activityListener
+
+	lastActivity time.Time
+	rxBytes      int64
+	txBytes      int64
+}
+
+// Manager tracks the activity of the peers. It calls onActivity once there is traffic to an inactive peer and
+// onInactivity once an active peer has been idle for the inactivity threshold.
+// The callbacks are called without holding the lock of the Manager
+type Manager struct {
+	wgIface             WGIface
+	inactivityThreshold time.Duration
+	checkInterval       time.Duration
+	onActivity          func(peerKey string)
+	onInactivity        func(peerKey string)
+
+	mu       sync.Mutex
+	peers    map[string]*managedPeer
+	excluded map[string]struct{}
+	cancel   context.CancelFunc
+}
+
+// NewManager creates a Manager, the inactivity threshold can be set with the NB_LAZY_CONN_INACTIVITY_THRESHOLD
+// environment variable
+func NewManager(wgIface WGIface, onActivity, onInactivity func(peerKey string)) *Manager {
+	return &Manager{
+		wgIface:             wgIface,
+		inactivityThreshold: inactivityThreshold(),
+		checkInterval:       inactivityCheckInterval,
+		onActivity:          onActivity,
+		onInactivity:        onInactivity,
+		peers:               make(map[string]*managedPeer),
+		excluded:            make(map[string]struct{}),
+	}
+}
+
+// Start starts monitoring the traffic of the active peers
+func (m *Manager) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	go m.monitorInactivity(ctx)
+}
+
+// Close stops the monitoring and the listeners of the inactive peers. Their WireGuard peers are left as they are
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		m.cancel()
+	}
+	for _, p := range m.peers {
+		if p.listener != nil {
+			p.listener.close()
+			p.listener = nil
+		}
+	}
+}
+
+// AddPeer adds an inactive peer and waits for traffic to it. It returns true if the peer has to be connected right
+// away instead, because it is excluded or the traffic can't be detected
+func (m *Manager) AddPeer(config PeerConfig) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := &managedPeer{config: config}
+	m.peers[config.PublicKey] = p
+
+	if _, ok := m.excluded[config.PublicKey]; ok {
+		m.setActive(p)
+		return true
+	}
+
+	if err := m.listen(p); err != nil {
+		log.Warnf("failed to wait for traffic to peer %s, connecting to it: %v", config.PublicKey, err)
+		m.setActive(p)
+		return true
+	}
+	return false
+}
+
+// AddActivePeer adds a peer that is already being connected
+func (m *Manager) AddActivePeer(config PeerConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := &managedPeer{config: config}
+	m.setActive(p)
+	m.peers[config.PublicKey] = p
+}
+
+// RemovePeer stops managing the peer, its WireGuard peer is removed if it is inactive
+func (m *Manager) RemovePeer(peerKey string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.peers[peerKey]
+	if !ok {
+		return
+	}
+	delete(m.peers, peerKey)
+
+	if p.listener == nil {
+		return
+	}
+	p.listener.close()
+	if err := m.wgIface.RemovePeer(peerKey); err != nil {
+		log.Warnf("failed to remove the WireGuard peer of inactive peer %s: %v", peerKey, err)
+	}
+}
+
+// ActivatePeer marks the peer active. It returns true if the peer was waiting for traffic and has to be connected,
+// false if it is unknown, already active or its previous connection hasn't been closed yet
+func (m *Manager) ActivatePeer(peerKey string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.peers[peerKey]
+	if !ok || p.active {
+		return false
+	}
+
+	waiting := p.listener != nil
+	if waiting {
+		p.listener.close()
+		p.listener = nil
+	}
+	m.setActive(p)
+	return waiting
+}
+
+// DeactivatePeer marks the connection of the peer to be closed, unless the peer is excluded.
+// It returns true if the peer was active
+func (m *Manager) DeactivatePeer(peerKey string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.peers[peerKey]
+	if !ok || !p.active {
+		return false
+	}
+	if _, excluded := m.excluded[peerKey]; excluded {
+		return false
+	}
+
+	p.active = false
+	return true
+}
+
+// IsActive returns true if the peer is being connected or it is connected
+func (m *Manager) IsActive(peerKey string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.peers[peerKey]
+	return ok && p.active
+}
+
+// WaitForActivity configures the WireGuard peer of an inactive peer, whose connection has been closed, to detect the
+// traffic to it
+func (m *Manager) WaitForActivity(peerKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.peers[peerKey]
+	if !ok || p.active || p.listener != nil {
+		return nil
+	}
+	return m.listen(p)
+}
+
+// SetExcludedPeers sets the peers that are always connected, e.g. the routing peers. It returns the excluded peers
+// that were waiting for traffic and have to be connected
+func (m *Manager) SetExcludedPeers(peerKeys map[string]struct{}) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var toConnect []string
+	for peerKey := range peerKeys {
+		p, ok := m.peers[peerKey]
+		if !ok {
+			continue
+		}
+		if p.listener != nil {
+			p.listener.close()
+			p.listener = nil
+			toConnect = append(toConnect, peerKey)
+		}
+		m.setActive(p)
+	}
+
+	for peerKey := range m.excluded {
+		if _, ok := peerKeys[peerKey]; ok {
+			continue
+		}
+		// the inactivity of a peer which is not excluded anymore is measured from now on
+		if p, ok := m.peers[peerKey]; ok && p.active {
+			p.lastActivity = time.Now()
+		}
+	}
+
+	m.excluded = peerKeys
+	return toConnect
+}
+
+func (m *Manager) setActive(p *managedPeer) {
+	p.active = true
+	p.lastActivity = time.Now()
+}
+
+func (m *Manager) listen(p *managedPeer) error {
+	listener, err := newActivityListener(p.config.PublicKey, m.onListenerActivity)
+	if err != nil {
+		return err
+	}
+
+	err = m.wgIface.UpdatePeer(p.config.PublicKey, p.config.AllowedIPs, 0, listener.addr(), p.config.PreSharedKey)
+	if err != nil {
+		listener.close()
+		return err
+	}
+
+	p.listener = listener
+	return nil
+}
+
+func (m *Manager) onListenerActivity(listener *activityListener) {
+	m.mu.Lock()
+	p, ok := m.peers[listener.peerKey]
+	current := ok && p.listener == listener
+	m.mu.Unlock()
+
+	if current {
+		m.onActivity(listener.peerKey)
+	}
+}
+
+func (m *Manager) monitorInactivity(ctx context.Context) {
+	ticker := time.NewTicker(m.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkInactivity()
+		}
+	}
+}
+
+func (m *Manager) checkInactivity() {
+	stats, err := m.wgIface.GetStats()
+	if err != nil {
+		log.Debugf("failed to get the WireGuard stats to check the inactivity of the peers: %v", err)
+		return
+	}
+
+	var inactive []string
+	now := time.Now()
+	idleBytes := idleBytesPerInterval(m.checkInterval)
+
+	m.mu.Lock()
+	for peerKey, p := range m.peers {
+		if !p.active {
+			continue
+		}
+		if _, excluded := m.excluded[peerKey]; excluded {
+			continue
+		}
+
+		peerStats := stats[peerKey]
+		rx := bytesSince(p.rxBytes, peerStats.RxBytes)
+		tx := bytesSince(p.txBytes, peerStats.TxBytes)
+		p.rxBytes, p.txBytes = peerStats.RxBytes, peerStats.TxBytes
+
+		if rx > idleBytes || tx > idleBytes {
+			p.lastActivity = now
+			continue
+		}
+
+		if now.Sub(p.lastActivity) >= m.inactivityThreshold {
+			p.active = false
+			inactive = append(inactive, peerKey)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, peerKey := range inactive {
+		log.Infof("closing the connection to peer %s, it has been idle for %s", peerKey, m.inactivityThreshold)
+		m.onInactivity(peerKey)
+	}
+}
+
+// bytesSince returns the bytes transferred since the previous reading, the counters are reset when the WireGuard peer
+// is configured again
+func bytesSince(previous, current int64) int64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+// idleBytesPerInterval returns the bytes an idle connection transfers in one direction during the interval
+func idleBytesPerInterval(interval time.Duration) int64 {
+	keepAlives := int64(interval/keepAliveInterval) + 1
+	handshakes := int64(interval/rekeyInterval) + 2
+	return keepAlives*keepAliveBytes + handshakes*handshakeBytes
+}
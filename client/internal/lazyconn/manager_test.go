@@ -0,0 +1,167 @@
+package lazyconn
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/netbirdio/netbird/iface"
+)
+
+type mockWGIface struct {
+	mu        sync.Mutex
+	endpoints map[string]*net.UDPAddr
+	stats     map[string]iface.WGStats
+}
+
+func newMockWGIface() *mockWGIface {
+	return &mockWGIface{
+		endpoints: make(map[string]*net.UDPAddr),
+		stats:     make(map[string]iface.WGStats),
+	}
+}
+
+func (m *mockWGIface) UpdatePeer(peerKey string, _ string, _ time.Duration, endpoint *net.UDPAddr, _ *wgtypes.Key) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endpoints[peerKey] = endpoint
+	return nil
+}
+
+func (m *mockWGIface) RemovePeer(peerKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.endpoints, peerKey)
+	return nil
+}
+
+func (m *mockWGIface) GetStats() (map[string]iface.WGStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make(map[string]iface.WGStats, len(m.stats))
+	for k, v := range m.stats {
+		stats[k] = v
+	}
+	return stats, nil
+}
+
+func (m *mockWGIface) endpoint(peerKey string) *net.UDPAddr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.endpoints[peerKey]
+}
+
+func (m *mockWGIface) setStats(peerKey string, stats iface.WGStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats[peerKey] = stats
+}
+
+const testPeerKey = "RRHf3Ma6z6mdLbriAJbqhX7+nM/B71lgw2+91q3LfhU="
+
+func TestManager_ActivityOnTraffic(t *testing.T) {
+	wgIface := newMockWGIface()
+	activity := make(chan string, 1)
+	m := NewManager(wgIface, func(peerKey string) { activity <- peerKey }, func(string) {})
+	defer m.Close()
+
+	connect := m.AddPeer(PeerConfig{PublicKey: testPeerKey, AllowedIPs: "100.64.0.2/32"})
+	require.False(t, connect, "the peer should wait for traffic")
+	assert.False(t, m.IsActive(testPeerKey))
+
+	endpoint := wgIface.endpoint(testPeerKey)
+	require.NotNil(t, endpoint, "the WireGuard peer should be configured with the endpoint of the listener")
+	assert.True(t, endpoint.IP.IsLoopback())
+
+	conn, err := net.DialUDP("udp4", nil, endpoint)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("handshake initiation"))
+	require.NoError(t, err)
+
+	select {
+	case peerKey := <-activity:
+		assert.Equal(t, testPeerKey, peerKey)
+	case <-time.After(time.Second):
+		t.Fatal("the traffic to the peer wasn't detected")
+	}
+
+	assert.True(t, m.ActivatePeer(testPeerKey), "the waiting peer should be connected")
+	assert.True(t, m.IsActive(testPeerKey))
+	assert.False(t, m.ActivatePeer(testPeerKey), "an active peer is already connected")
+}
+
+func TestManager_WaitForActivityAfterClose(t *testing.T) {
+	wgIface := newMockWGIface()
+	m := NewManager(wgIface, func(string) {}, func(string) {})
+	defer m.Close()
+
+	m.AddActivePeer(PeerConfig{PublicKey: testPeerKey})
+	require.True(t, m.IsActive(testPeerKey))
+	require.Nil(t, wgIface.endpoint(testPeerKey), "an active peer is configured by its connection")
+
+	require.True(t, m.DeactivatePeer(testPeerKey))
+	assert.False(t, m.ActivatePeer(testPeerKey), "the connection of the peer is still being closed")
+	require.True(t, m.DeactivatePeer(testPeerKey))
+
+	require.NoError(t, m.WaitForActivity(testPeerKey))
+	assert.NotNil(t, wgIface.endpoint(testPeerKey))
+
+	m.RemovePeer(testPeerKey)
+	assert.Nil(t, wgIface.endpoint(testPeerKey), "the WireGuard peer of a removed inactive peer should be removed")
+	assert.False(t, m.IsActive(testPeerKey))
+}
+
+func TestManager_Inactivity(t *testing.T) {
+	wgIface := newMockWGIface()
+	var inactive []string
+	m := NewManager(wgIface, func(string) {}, func(peerKey string) { inactive = append(inactive, peerKey) })
+	defer m.Close()
+
+	m.AddActivePeer(PeerConfig{PublicKey: testPeerKey})
+	idleBytes := idleBytesPerInterval(m.checkInterval)
+
+	// traffic above the keep alive messages and handshakes keeps the peer active
+	m.peers[testPeerKey].lastActivity = time.Now().Add(-2 * m.inactivityThreshold)
+	wgIface.setStats(testPeerKey, iface.WGStats{RxBytes: idleBytes + 1})
+	m.checkInactivity()
+	assert.Empty(t, inactive)
+	assert.True(t, m.IsActive(testPeerKey))
+
+	// keep alive messages only
+	wgIface.setStats(testPeerKey, iface.WGStats{RxBytes: 2*idleBytes + 1, TxBytes: idleBytes})
+	m.checkInactivity()
+	assert.Empty(t, inactive, "the peer hasn't been idle for the threshold yet")
+
+	m.peers[testPeerKey].lastActivity = time.Now().Add(-m.inactivityThreshold)
+	m.checkInactivity()
+	assert.Equal(t, []string{testPeerKey}, inactive)
+	assert.False(t, m.IsActive(testPeerKey))
+}
+
+func TestManager_ExcludedPeers(t *testing.T) {
+	wgIface := newMockWGIface()
+	var inactive []string
+	m := NewManager(wgIface, func(string) {}, func(peerKey string) { inactive = append(inactive, peerKey) })
+	defer m.Close()
+
+	otherPeerKey := "LLHf3Ma6z6mdLbriAJbqhX7+nM/B71lgw2+91q3LfhU="
+	m.SetExcludedPeers(map[string]struct{}{otherPeerKey: {}})
+	assert.True(t, m.AddPeer(PeerConfig{PublicKey: otherPeerKey}), "an excluded peer should be connected right away")
+
+	require.False(t, m.AddPeer(PeerConfig{PublicKey: testPeerKey}))
+	toConnect := m.SetExcludedPeers(map[string]struct{}{testPeerKey: {}})
+	assert.Equal(t, []string{testPeerKey}, toConnect)
+	assert.True(t, m.IsActive(testPeerKey))
+	assert.False(t, m.DeactivatePeer(testPeerKey), "an excluded peer stays connected")
+
+	m.peers[testPeerKey].lastActivity = time.Now().Add(-2 * m.inactivityThreshold)
+	m.checkInactivity()
+	assert.NotContains(t, inactive, testPeerKey)
+	assert.True(t, m.DeactivatePeer(otherPeerKey), "a peer that isn't excluded anymore can be closed")
+}
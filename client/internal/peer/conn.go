@@ -2,9 +2,11 @@ package peer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +20,7 @@ import (
 	"github.com/netbirdio/netbird/client/internal/wgproxy"
 	"github.com/netbirdio/netbird/iface"
 	"github.com/netbirdio/netbird/iface/bind"
+	relayClient "github.com/netbirdio/netbird/relay/client"
 	signal "github.com/netbirdio/netbird/signal/client"
 	sProto "github.com/netbirdio/netbird/signal/proto"
 	"github.com/netbirdio/netbird/version"
@@ -72,6 +75,9 @@ type ConnConfig struct {
 	RosenpassPubKey []byte
 	// RosenpassPubKey is this peer's RosenpassAddr server address (IP:port)
 	RosenpassAddr string
+
+	// RelayManager connects to the relay servers the connection falls back to when ICE fails, nil disables the fallback
+	RelayManager *relayClient.Manager
 }
 
 // OfferAnswer represents a session establishment offer or answer
@@ -90,6 +96,9 @@ type OfferAnswer struct {
 	// RosenpassAddr is the Rosenpass server address (IP:port) of the remote peer when receiving this message
 	// This value is the local Rosenpass server address when sending the message
 	RosenpassAddr string
+	// RelayServerAddress is the URL of the relay server of the remote peer when receiving this message
+	// This value is the URL of the local relay server when sending the message
+	RelayServerAddress string
 }
 
 // IceCredentials ICE protocol credentials struct
@@ -111,16 +120,33 @@ type Conn struct {
 	onConnected       func(remoteWireGuardKey string, remoteRosenpassPubKey []byte, wireGuardIP string, remoteRosenpassAddr string)
 	onDisconnected    func(remotePeer string, wgIP string)
 
+	// signalUpgrade and signalUpgradeCandidate signal the negotiation of a direct path while the connection is relayed
+	signalUpgrade          func(offerAnswer OfferAnswer, isAnswer bool) error
+	signalUpgradeCandidate func(candidate ice.Candidate) error
+
 	// remoteOffersCh is a channel used to wait for remote credentials to proceed with the connection
 	remoteOffersCh chan OfferAnswer
 	// remoteAnswerCh is a channel used to wait for remote credentials answer (confirmation of our offer) to proceed with the connection
-	remoteAnswerCh     chan OfferAnswer
+	remoteAnswerCh chan OfferAnswer
+	// remoteOfferSkipped is notified when an offer arrives while the connection isn't waiting for one, e.g. when the
+	// remote peer comes online
+	remoteOfferSkipped chan struct{}
 	closeCh            chan struct{}
 	ctx                context.Context
 	notifyDisconnected context.CancelFunc
 
 	agent  *ice.Agent
 	status ConnStatus
+	// relayed is true if the selected candidate pair of the connected agent is relayed
+	relayed bool
+
+	// upgradeAgent probes a direct path while the connection is relayed, it replaces the agent once it connects
+	upgradeAgent *ice.Agent
+	// upgradeAnswerCh is used by the controlling peer to wait for the answer to its upgrade offer
+	upgradeAnswerCh chan OfferAnswer
+
+	// relayConn is the connection through the relay server when ICE has failed
+	relayConn *relayClient.Conn
 
 	statusRecorder *Status
 
@@ -164,17 +190,18 @@ func (conn *Conn) UpdateStunTurn(turnStun []*stun.URI) {
 // To establish a connection run Conn.Open
 func NewConn(config ConnConfig, statusRecorder *Status, wgProxyFactory *wgproxy.Factory, adapter iface.TunAdapter, iFaceDiscover stdnet.ExternalIFaceDiscover) (*Conn, error) {
 	return &Conn{
-		config:         config,
-		mu:             sync.Mutex{},
-		status:         StatusDisconnected,
-		closeCh:        make(chan struct{}),
-		remoteOffersCh: make(chan OfferAnswer),
-		remoteAnswerCh: make(chan OfferAnswer),
-		statusRecorder: statusRecorder,
-		remoteModeCh:   make(chan ModeMessage, 1),
-		wgProxyFactory: wgProxyFactory,
-		adapter:        adapter,
-		iFaceDiscover:  iFaceDiscover,
+		config:             config,
+		mu:                 sync.Mutex{},
+		status:             StatusDisconnected,
+		closeCh:            make(chan struct{}),
+		remoteOffersCh:     make(chan OfferAnswer),
+		remoteAnswerCh:     make(chan OfferAnswer),
+		remoteOfferSkipped: make(chan struct{}, 1),
+		statusRecorder:     statusRecorder,
+		remoteModeCh:       make(chan ModeMessage, 1),
+		wgProxyFactory:     wgProxyFactory,
+		adapter:            adapter,
+		iFaceDiscover:      iFaceDiscover,
 	}, nil
 }
 
@@ -182,6 +209,13 @@ func (conn *Conn) reCreateAgent() error {
 	conn.mu.Lock()
 	defer conn.mu.Unlock()
 
+	var err error
+	conn.agent, err = conn.newAgent(conn.candidateTypes(), conn.onICECandidate)
+	return err
+}
+
+// newAgent creates an ICE agent gathering the candidate types and signalling its local candidates with onCandidate
+func (conn *Conn) newAgent(candidateTypes []ice.CandidateType, onCandidate func(ice.Candidate)) (*ice.Agent, error) {
 	failedTimeout := 6 * time.Second
 
 	var err error
@@ -197,7 +231,7 @@ func (conn *Conn) reCreateAgent() error {
 		MulticastDNSMode:    ice.MulticastDNSModeDisabled,
 		NetworkTypes:        []ice.NetworkType{ice.NetworkTypeUDP4, ice.NetworkTypeUDP6},
 		Urls:                conn.config.StunTurn,
-		CandidateTypes:      conn.candidateTypes(),
+		CandidateTypes:      candidateTypes,
 		FailedTimeout:       &failedTimeout,
 		InterfaceFilter:     stdnet.InterfaceFilter(conn.config.InterfaceBlackList),
 		UDPMux:              conn.config.UDPMux,
@@ -212,28 +246,34 @@ func (conn *Conn) reCreateAgent() error {
 		agentConfig.NetworkTypes = []ice.NetworkType{ice.NetworkTypeUDP4}
 	}
 
-	conn.agent, err = ice.NewAgent(agentConfig)
-
+	agent, err := ice.NewAgent(agentConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = conn.agent.OnCandidate(conn.onICECandidate)
+	err = conn.registerAgentHandlers(agent, onCandidate)
 	if err != nil {
-		return err
+		_ = agent.Close()
+		return nil, err
 	}
 
-	err = conn.agent.OnConnectionStateChange(conn.onICEConnectionStateChange)
+	return agent, nil
+}
+
+func (conn *Conn) registerAgentHandlers(agent *ice.Agent, onCandidate func(ice.Candidate)) error {
+	err := agent.OnCandidate(onCandidate)
 	if err != nil {
 		return err
 	}
 
-	err = conn.agent.OnSelectedCandidatePairChange(conn.onICESelectedCandidatePair)
+	err = agent.OnConnectionStateChange(func(state ice.ConnectionState) {
+		conn.onICEConnectionStateChange(agent, state)
+	})
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return agent.OnSelectedCandidatePairChange(conn.onICESelectedCandidatePair)
 }
 
 func (conn *Conn) candidateTypes() []ice.CandidateType {
@@ -278,6 +318,9 @@ func (conn *Conn) Open() error {
 	}
 
 	err = conn.sendOffer()
+	if errors.Is(err, signal.ErrPeerNotConnected) {
+		return NewConnectionPeerOfflineError(conn.config.Key)
+	}
 	if err != nil {
 		return err
 	}
@@ -338,24 +381,43 @@ func (conn *Conn) Open() error {
 	} else {
 		remoteConn, err = conn.agent.Accept(conn.ctx, remoteOfferAnswer.IceCredentials.UFrag, remoteOfferAnswer.IceCredentials.Pwd)
 	}
-	if err != nil {
-		return err
-	}
 
 	// dynamically set remote WireGuard port is other side specified a different one from the default one
 	remoteWgPort := iface.DefaultWgPort
 	if remoteOfferAnswer.WgListenPort != 0 {
 		remoteWgPort = remoteOfferAnswer.WgListenPort
 	}
-	// the ice connection has been established successfully so we are ready to start the proxy
-	remoteAddr, err := conn.configureConnection(remoteConn, remoteWgPort, remoteOfferAnswer.RosenpassPubKey,
-		remoteOfferAnswer.RosenpassAddr)
+
+	var remoteAddr net.Addr
 	if err != nil {
-		return err
+		// ICE has failed, e.g. because UDP is blocked, the relay server is the last resort
+		relayServerURL := conn.relayServerURL(isControlling, remoteOfferAnswer)
+		if relayServerURL == "" {
+			return err
+		}
+		log.Infof("failed to connect to peer %s over ICE: %v, falling back to relay server %s", conn.config.Key, err, relayServerURL)
+
+		remoteAddr, err = conn.configureRelayConnection(relayServerURL, remoteOfferAnswer.RosenpassPubKey,
+			remoteOfferAnswer.RosenpassAddr)
+		if err != nil {
+			return err
+		}
+	} else {
+		// the ice connection has been established successfully so we are ready to start the proxy
+		remoteAddr, err = conn.configureConnection(remoteConn, remoteWgPort, remoteOfferAnswer.RosenpassPubKey,
+			remoteOfferAnswer.RosenpassAddr)
+		if err != nil {
+			return err
+		}
 	}
 
 	log.Infof("connected to peer %s, endpoint address: %s", conn.config.Key, remoteAddr.String())
 
+	// the controlling peer keeps probing for a direct path while the connection is relayed
+	if isControlling && conn.isUpgradeSupported() {
+		go conn.upgradeRelayedConn(conn.ctx, remoteWgPort)
+	}
+
 	// wait until connection disconnected or has been closed externally (upper layer, e.g. engine)
 	select {
 	case <-conn.closeCh:
@@ -406,17 +468,18 @@ func (conn *Conn) configureConnection(remoteConn net.Conn, remoteWgPort int, rem
 	}
 
 	conn.status = StatusConnected
+	conn.relayed = isRelayCandidate(pair.Local) || isRelayCandidate(pair.Remote)
 
 	peerState := State{
-		PubKey:                 conn.config.Key,
-		ConnStatus:             conn.status,
-		ConnStatusUpdate:       time.Now(),
-		LocalIceCandidateType:  pair.Local.Type().String(),
-		RemoteIceCandidateType: pair.Remote.Type().String(),
-		Direct:                 !isRelayCandidate(pair.Local),
-	}
-	if pair.Local.Type() == ice.CandidateTypeRelay || pair.Remote.Type() == ice.CandidateTypeRelay {
-		peerState.Relayed = true
+		PubKey:                     conn.config.Key,
+		ConnStatus:                 conn.status,
+		ConnStatusUpdate:           time.Now(),
+		LocalIceCandidateType:      pair.Local.Type().String(),
+		RemoteIceCandidateType:     pair.Remote.Type().String(),
+		LocalIceCandidateEndpoint:  candidateEndpoint(pair.Local),
+		RemoteIceCandidateEndpoint: candidateEndpoint(pair.Remote),
+		Direct:                     !isRelayCandidate(pair.Local),
+		Relayed:                    conn.relayed,
 	}
 
 	err = conn.statusRecorder.UpdatePeerState(peerState)
@@ -436,6 +499,30 @@ func (conn *Conn) configureConnection(remoteConn net.Conn, remoteWgPort int, rem
 	return endpoint, nil
 }
 
+// Latency returns the round trip time of the ICE connectivity checks on the selected candidate pair. It is unknown
+// if the local candidate is relayed, its checks don't go through the UDP mux
+func (conn *Conn) Latency() (time.Duration, bool) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.agent == nil || conn.status != StatusConnected {
+		return 0, false
+	}
+	pair, err := conn.agent.GetSelectedCandidatePair()
+	if err != nil || pair == nil {
+		return 0, false
+	}
+	mux, ok := conn.config.UDPMuxSrflx.(*bind.UniversalUDPMuxDefault)
+	if !ok {
+		return 0, false
+	}
+	return mux.RoundTripTime(candidateEndpoint(pair.Remote))
+}
+
+func candidateEndpoint(candidate ice.Candidate) string {
+	return net.JoinHostPort(candidate.Address(), strconv.Itoa(candidate.Port()))
+}
+
 func (conn *Conn) punchRemoteWGPort(pair *ice.CandidatePair, remoteWgPort int) {
 	// wait local endpoint configuration
 	time.Sleep(time.Second)
@@ -462,6 +549,20 @@ func (conn *Conn) cleanup() error {
 	conn.mu.Lock()
 	defer conn.mu.Unlock()
 
+	if conn.upgradeAgent != nil {
+		if err := conn.upgradeAgent.Close(); err != nil {
+			log.Debugf("failed to close the upgrade agent of peer %s: %v", conn.config.Key, err)
+		}
+		conn.upgradeAgent = nil
+		conn.upgradeAnswerCh = nil
+	}
+
+	if conn.relayConn != nil {
+		relayConn := conn.relayConn
+		conn.relayConn = nil
+		_ = relayConn.Close()
+	}
+
 	var err1, err2, err3 error
 	if conn.agent != nil {
 		err1 = conn.agent.Close()
@@ -488,6 +589,7 @@ func (conn *Conn) cleanup() error {
 	}
 
 	conn.status = StatusDisconnected
+	conn.relayed = false
 
 	peerState := State{
 		PubKey:           conn.config.Key,
@@ -561,12 +663,22 @@ func (conn *Conn) onICESelectedCandidatePair(c1 ice.Candidate, c2 ice.Candidate)
 		conn.config.Key)
 }
 
-// onICEConnectionStateChange registers callback of an ICE Agent to track connection state
-func (conn *Conn) onICEConnectionStateChange(state ice.ConnectionState) {
+// onICEConnectionStateChange registers callback of an ICE Agent to track connection state.
+// Only the agent of the connection disconnects it, the failure of an upgrade agent or of an agent replaced by the
+// upgrade agent is ignored
+func (conn *Conn) onICEConnectionStateChange(agent *ice.Agent, state ice.ConnectionState) {
 	log.Debugf("peer %s ICE ConnectionState has changed to %s", conn.config.Key, state.String())
-	if state == ice.ConnectionStateFailed || state == ice.ConnectionStateDisconnected {
-		conn.notifyDisconnected()
+	if state != ice.ConnectionStateFailed && state != ice.ConnectionStateDisconnected {
+		return
+	}
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if agent != conn.agent || conn.notifyDisconnected == nil {
+		return
 	}
+	conn.notifyDisconnected()
 }
 
 func (conn *Conn) sendAnswer() error {
@@ -579,13 +691,7 @@ func (conn *Conn) sendAnswer() error {
 	}
 
 	log.Debugf("sending answer to %s", conn.config.Key)
-	err = conn.signalAnswer(OfferAnswer{
-		IceCredentials:  IceCredentials{localUFrag, localPwd},
-		WgListenPort:    conn.config.LocalWgPort,
-		Version:         version.NetbirdVersion(),
-		RosenpassPubKey: conn.config.RosenpassPubKey,
-		RosenpassAddr:   conn.config.RosenpassAddr,
-	})
+	err = conn.signalAnswer(conn.localOfferAnswer(localUFrag, localPwd))
 	if err != nil {
 		return err
 	}
@@ -602,17 +708,26 @@ func (conn *Conn) sendOffer() error {
 	if err != nil {
 		return err
 	}
-	err = conn.signalOffer(OfferAnswer{
-		IceCredentials:  IceCredentials{localUFrag, localPwd},
+	err = conn.signalOffer(conn.localOfferAnswer(localUFrag, localPwd))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// localOfferAnswer returns the offer or answer of this peer for the ICE credentials
+func (conn *Conn) localOfferAnswer(uFrag, pwd string) OfferAnswer {
+	offerAnswer := OfferAnswer{
+		IceCredentials:  IceCredentials{uFrag, pwd},
 		WgListenPort:    conn.config.LocalWgPort,
 		Version:         version.NetbirdVersion(),
 		RosenpassPubKey: conn.config.RosenpassPubKey,
 		RosenpassAddr:   conn.config.RosenpassAddr,
-	})
-	if err != nil {
-		return err
 	}
-	return nil
+	if conn.config.RelayManager != nil {
+		offerAnswer.RelayServerAddress = conn.config.RelayManager.ServerURL()
+	}
+	return offerAnswer
 }
 
 // Close closes this peer Conn issuing a close event to the Conn closeCh
@@ -658,10 +773,20 @@ func (conn *Conn) OnRemoteOffer(offer OfferAnswer) bool {
 	default:
 		log.Debugf("OnRemoteOffer skipping message from peer %s on status %s because is not ready", conn.config.Key, conn.status.String())
 		// connection might not be ready yet to receive so we ignore the message
+		select {
+		case conn.remoteOfferSkipped <- struct{}{}:
+		default:
+		}
 		return false
 	}
 }
 
+// RemoteOfferSkipped returns a channel notified when an offer of the remote peer was skipped because the connection
+// wasn't waiting for one
+func (conn *Conn) RemoteOfferSkipped() <-chan struct{} {
+	return conn.remoteOfferSkipped
+}
+
 // OnRemoteAnswer handles an offer from the remote peer and returns true if the message was accepted, false otherwise
 // doesn't block, discards the message if connection wasn't ready
 func (conn *Conn) OnRemoteAnswer(answer OfferAnswer) bool {
@@ -703,5 +828,8 @@ func (conn *Conn) GetKey() string {
 // RegisterProtoSupportMeta register supported proto message in the connection metadata
 func (conn *Conn) RegisterProtoSupportMeta(support []uint32) {
 	protoSupport := signal.ParseFeaturesSupported(support)
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
 	conn.meta.protoSupport = protoSupport
 }
@@ -0,0 +1,143 @@
+package peer
+
+import (
+	"context"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	relayClient "github.com/netbirdio/netbird/relay/client"
+)
+
+const (
+	// relayCandidateType is reported as the candidate type of connections through a relay server
+	relayCandidateType = "relay-server"
+	// relayConnCloseDelay keeps the connection through the relay server open after the upgrade to a direct one, until
+	// the remote peer has switched to the direct path as well. Closing it earlier disconnects the remote peer
+	relayConnCloseDelay = 5 * time.Second
+)
+
+// relayServerURL returns the relay server both peers connect to when ICE fails, the one of the controlling peer.
+// It is empty if either peer has no relay server or if the server of the remote peer isn't one of the servers of the
+// Management service
+func (conn *Conn) relayServerURL(isControlling bool, remoteOfferAnswer OfferAnswer) string {
+	if conn.config.RelayManager == nil {
+		return ""
+	}
+
+	localServerURL := conn.config.RelayManager.ServerURL()
+	if localServerURL == "" || remoteOfferAnswer.RelayServerAddress == "" {
+		return ""
+	}
+
+	if isControlling {
+		return localServerURL
+	}
+	if !conn.config.RelayManager.IsAllowed(remoteOfferAnswer.RelayServerAddress) {
+		log.Warnf("ignoring relay server %s of peer %s, it isn't provided by the Management service",
+			remoteOfferAnswer.RelayServerAddress, conn.config.Key)
+		return ""
+	}
+	return remoteOfferAnswer.RelayServerAddress
+}
+
+// configureRelayConnection replaces the failed ICE agent with a connection through the relay server, starts proxying
+// traffic from/to local Wireguard over it and sets connection status to StatusConnected
+func (conn *Conn) configureRelayConnection(serverURL string, remoteRosenpassPubKey []byte, remoteRosenpassAddr string) (net.Addr, error) {
+	relayConn, err := conn.config.RelayManager.OpenConn(serverURL, conn.config.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	conn.relayConn = relayConn
+
+	if conn.agent != nil {
+		if err := conn.agent.Close(); err != nil {
+			log.Debugf("failed to close the failed agent of peer %s: %v", conn.config.Key, err)
+		}
+		conn.agent = nil
+	}
+
+	// the failed agent has cancelled the context, the connection through the relay server gets a new one
+	conn.ctx, conn.notifyDisconnected = context.WithCancel(context.Background())
+
+	conn.wgProxy = conn.wgProxyFactory.GetProxy()
+	endpoint, err := conn.wgProxy.AddTurnConn(relayConn)
+	if err != nil {
+		return nil, err
+	}
+
+	endpointUdpAddr, _ := net.ResolveUDPAddr(endpoint.Network(), endpoint.String())
+
+	err = conn.config.WgConfig.WgInterface.UpdatePeer(conn.config.WgConfig.RemoteKey, conn.config.WgConfig.AllowedIps, defaultWgKeepAlive, endpointUdpAddr, conn.config.WgConfig.PreSharedKey)
+	if err != nil {
+		_ = conn.wgProxy.CloseConn()
+		return nil, err
+	}
+
+	conn.status = StatusConnected
+	conn.relayed = true
+
+	peerState := State{
+		PubKey:                     conn.config.Key,
+		ConnStatus:                 conn.status,
+		ConnStatusUpdate:           time.Now(),
+		LocalIceCandidateType:      relayCandidateType,
+		RemoteIceCandidateType:     relayCandidateType,
+		LocalIceCandidateEndpoint:  serverURL,
+		RemoteIceCandidateEndpoint: serverURL,
+		Direct:                     false,
+		Relayed:                    true,
+	}
+
+	err = conn.statusRecorder.UpdatePeerState(peerState)
+	if err != nil {
+		log.Warnf("unable to save peer's state, got error: %v", err)
+	}
+
+	go conn.watchRelayConn(relayConn)
+
+	_, ipNet, err := net.ParseCIDR(conn.config.WgConfig.AllowedIps)
+	if err != nil {
+		return nil, err
+	}
+
+	if conn.onConnected != nil {
+		conn.onConnected(conn.config.Key, remoteRosenpassPubKey, ipNet.IP.String(), remoteRosenpassAddr)
+	}
+
+	return relayConn.RemoteAddr(), nil
+}
+
+// watchRelayConn disconnects the connection once the remote peer or the relay server has closed the connection
+// through the relay server
+func (conn *Conn) watchRelayConn(relayConn *relayClient.Conn) {
+	<-relayConn.Closed()
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.relayConn != relayConn || conn.notifyDisconnected == nil {
+		return
+	}
+	log.Infof("connection to peer %s through the relay server has been closed", conn.config.Key)
+	conn.notifyDisconnected()
+}
+
+// closeRelayConnDelayed closes the connection through the relay server after the upgrade to a direct one, has to be
+// called with the lock held
+func (conn *Conn) closeRelayConnDelayed() {
+	if conn.relayConn == nil {
+		return
+	}
+
+	relayConn := conn.relayConn
+	conn.relayConn = nil
+	time.AfterFunc(relayConnCloseDelay, func() {
+		_ = relayConn.Close()
+	})
+}
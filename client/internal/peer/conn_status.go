@@ -9,6 +9,8 @@ const (
 	StatusConnecting
 	// StatusDisconnected indicate the peer is in disconnected state
 	StatusDisconnected
+	// StatusIdle indicate the peer isn't connected until there is traffic to it
+	StatusIdle
 )
 
 // ConnStatus describe the status of a peer's connection
@@ -22,6 +24,8 @@ func (s ConnStatus) String() string {
 		return "Connected"
 	case StatusDisconnected:
 		return "Disconnected"
+	case StatusIdle:
+		return "Idle"
 	default:
 		log.Errorf("unknown status: %d", s)
 		return "INVALID_PEER_CONNECTION_STATUS"
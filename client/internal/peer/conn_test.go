@@ -11,6 +11,7 @@ import (
 	"github.com/netbirdio/netbird/client/internal/stdnet"
 	"github.com/netbirdio/netbird/client/internal/wgproxy"
 	"github.com/netbirdio/netbird/iface"
+	relayClient "github.com/netbirdio/netbird/relay/client"
 )
 
 var connConf = ConnConfig{
@@ -182,3 +183,94 @@ func TestConn_Close(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestConn_relayServerURL(t *testing.T) {
+	localServerURL := "wss://relay-a.netbird.io:443/relay"
+	remoteServerURL := "wss://relay-b.netbird.io:443/relay"
+
+	testCases := []struct {
+		name           string
+		localServerURL string
+		remote         OfferAnswer
+		isControlling  bool
+		expected       string
+	}{
+		{
+			name:           "controlling peer uses its own relay server",
+			localServerURL: localServerURL,
+			remote:         OfferAnswer{RelayServerAddress: remoteServerURL},
+			isControlling:  true,
+			expected:       localServerURL,
+		},
+		{
+			name:           "controlled peer uses the relay server of the remote peer",
+			localServerURL: localServerURL,
+			remote:         OfferAnswer{RelayServerAddress: remoteServerURL},
+			isControlling:  false,
+			expected:       remoteServerURL,
+		},
+		{
+			name:           "controlled peer ignores a relay server the management didn't provide",
+			localServerURL: localServerURL,
+			remote:         OfferAnswer{RelayServerAddress: "wss://relay.example.com:443/relay"},
+			isControlling:  false,
+			expected:       "",
+		},
+		{
+			name:           "remote peer without relay server",
+			localServerURL: localServerURL,
+			remote:         OfferAnswer{},
+			isControlling:  true,
+			expected:       "",
+		},
+		{
+			name:          "local peer without relay server",
+			remote:        OfferAnswer{RelayServerAddress: remoteServerURL},
+			isControlling: false,
+			expected:      "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			relayManager := relayClient.NewManager(connConf.LocalKey)
+			var serverURLs []string
+			if testCase.localServerURL != "" {
+				serverURLs = []string{testCase.localServerURL, remoteServerURL}
+			}
+			relayManager.UpdateServers(serverURLs, "user", "password")
+
+			config := connConf
+			config.RelayManager = relayManager
+			conn := &Conn{config: config}
+
+			assert.Equal(t, conn.relayServerURL(testCase.isControlling, testCase.remote), testCase.expected)
+			assert.Equal(t, conn.localOfferAnswer("ufrag", "pwd").RelayServerAddress, testCase.localServerURL)
+		})
+	}
+
+	conn := &Conn{config: connConf}
+	assert.Equal(t, conn.relayServerURL(true, OfferAnswer{RelayServerAddress: remoteServerURL}), "",
+		"no relay manager disables the fallback")
+}
+
+func TestConn_RemoteOfferSkipped(t *testing.T) {
+	wgProxyFactory := wgproxy.NewFactory(connConf.LocalWgPort)
+	defer func() {
+		_ = wgProxyFactory.Free()
+	}()
+	conn, err := NewConn(connConf, NewRecorder("https://mgm"), wgProxyFactory, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing waits for the offer
+	accepted := conn.OnRemoteOffer(OfferAnswer{IceCredentials: IceCredentials{UFrag: "test", Pwd: "test"}})
+	assert.Equal(t, accepted, false)
+
+	select {
+	case <-conn.RemoteOfferSkipped():
+	default:
+		t.Fatal("expected the skipped offer to be signaled")
+	}
+}
@@ -0,0 +1,337 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pion/ice/v3"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/netbirdio/netbird/iface"
+)
+
+const (
+	relayUpgradeIntervalDefault = time.Minute
+	// relayUpgradeTimeout is the time a probe has to exchange the credentials and connect the direct path
+	relayUpgradeTimeout = 15 * time.Second
+)
+
+// relayUpgradeCandidateTypes are the candidate types of the direct path probed while the connection is relayed
+func relayUpgradeCandidateTypes() []ice.CandidateType {
+	return []ice.CandidateType{ice.CandidateTypeHost, ice.CandidateTypeServerReflexive}
+}
+
+// SetSignalUpgrade sets a handler function to be triggered by Conn when an upgrade offer or answer has to be
+// signalled to the remote peer
+func (conn *Conn) SetSignalUpgrade(handler func(offerAnswer OfferAnswer, isAnswer bool) error) {
+	conn.signalUpgrade = handler
+}
+
+// SetSignalUpgradeCandidate sets a handler function to be triggered by Conn when a local candidate of the upgrade
+// agent has to be signalled to the remote peer
+func (conn *Conn) SetSignalUpgradeCandidate(handler func(candidate ice.Candidate) error) {
+	conn.signalUpgradeCandidate = handler
+}
+
+// isUpgradeSupported returns true if the connection is relayed and both peers can upgrade it to a direct one
+func (conn *Conn) isUpgradeSupported() bool {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	return conn.isUpgradable()
+}
+
+// isUpgradable has to be called with the lock held
+func (conn *Conn) isUpgradable() bool {
+	return conn.status == StatusConnected && conn.relayed && conn.meta.protoSupport.RelayUpgrade &&
+		conn.signalUpgrade != nil && conn.signalUpgradeCandidate != nil && !hasICEForceRelayConn()
+}
+
+// upgradeRelayedConn probes a direct path every relay upgrade interval until the connection has been upgraded or
+// the context is done
+func (conn *Conn) upgradeRelayedConn(ctx context.Context, remoteWgPort int) {
+	interval := relayUpgradeInterval()
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !conn.isUpgradeSupported() {
+			return
+		}
+
+		err := conn.probeDirectPath(ctx, remoteWgPort)
+		conn.recordRelayUpgrade(err == nil)
+		if err == nil {
+			return
+		}
+		log.Debugf("no direct path to peer %s yet: %v", conn.config.Key, err)
+	}
+}
+
+// probeDirectPath offers the remote peer to negotiate a direct path and connects it as the controlling peer
+func (conn *Conn) probeDirectPath(ctx context.Context, remoteWgPort int) error {
+	conn.mu.Lock()
+	if !conn.isUpgradable() || conn.upgradeAgent != nil {
+		conn.mu.Unlock()
+		return fmt.Errorf("connection can't be upgraded")
+	}
+	agent, err := conn.newAgent(relayUpgradeCandidateTypes(), conn.onUpgradeCandidate)
+	if err != nil {
+		conn.mu.Unlock()
+		return err
+	}
+	answerCh := make(chan OfferAnswer, 1)
+	conn.upgradeAgent = agent
+	conn.upgradeAnswerCh = answerCh
+	conn.mu.Unlock()
+
+	defer conn.closeUpgradeAgent(agent)
+
+	probeCtx, cancel := context.WithTimeout(ctx, relayUpgradeTimeout)
+	defer cancel()
+
+	localUFrag, localPwd, err := agent.GetLocalUserCredentials()
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("probing a direct path to relayed peer %s", conn.config.Key)
+	err = conn.signalUpgrade(conn.localOfferAnswer(localUFrag, localPwd), false)
+	if err != nil {
+		return err
+	}
+
+	var answer OfferAnswer
+	select {
+	case answer = <-answerCh:
+	case <-probeCtx.Done():
+		return fmt.Errorf("no upgrade answer received: %w", probeCtx.Err())
+	}
+
+	err = agent.GatherCandidates()
+	if err != nil {
+		return err
+	}
+
+	remoteConn, err := agent.Dial(probeCtx, answer.IceCredentials.UFrag, answer.IceCredentials.Pwd)
+	if err != nil {
+		return err
+	}
+
+	if answer.WgListenPort != 0 {
+		remoteWgPort = answer.WgListenPort
+	}
+	return conn.promoteUpgradeAgent(agent, remoteConn, remoteWgPort)
+}
+
+// acceptDirectPath answers the upgrade offer of the remote peer and waits for it to connect the direct path
+func (conn *Conn) acceptDirectPath(ctx context.Context, agent *ice.Agent, offer OfferAnswer) {
+	defer conn.closeUpgradeAgent(agent)
+
+	err := conn.connectDirectPath(ctx, agent, offer)
+	conn.recordRelayUpgrade(err == nil)
+	if err != nil {
+		log.Debugf("no direct path to peer %s yet: %v", conn.config.Key, err)
+	}
+}
+
+func (conn *Conn) connectDirectPath(ctx context.Context, agent *ice.Agent, offer OfferAnswer) error {
+	probeCtx, cancel := context.WithTimeout(ctx, relayUpgradeTimeout)
+	defer cancel()
+
+	localUFrag, localPwd, err := agent.GetLocalUserCredentials()
+	if err != nil {
+		return err
+	}
+
+	err = conn.signalUpgrade(conn.localOfferAnswer(localUFrag, localPwd), true)
+	if err != nil {
+		return err
+	}
+
+	err = agent.GatherCandidates()
+	if err != nil {
+		return err
+	}
+
+	remoteConn, err := agent.Accept(probeCtx, offer.IceCredentials.UFrag, offer.IceCredentials.Pwd)
+	if err != nil {
+		return err
+	}
+
+	return conn.promoteUpgradeAgent(agent, remoteConn, offer.WgListenPort)
+}
+
+// promoteUpgradeAgent switches the WireGuard endpoint to the direct path of the upgrade agent and replaces the relayed
+// agent and its proxy with it
+func (conn *Conn) promoteUpgradeAgent(agent *ice.Agent, remoteConn *ice.Conn, remoteWgPort int) error {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.upgradeAgent != agent || conn.status != StatusConnected {
+		return fmt.Errorf("connection has changed while probing a direct path")
+	}
+
+	pair, err := agent.GetSelectedCandidatePair()
+	if err != nil {
+		return err
+	}
+	if isRelayCandidate(pair.Local) || isRelayCandidate(pair.Remote) {
+		return fmt.Errorf("selected candidate pair is relayed")
+	}
+
+	endpoint := remoteConn.RemoteAddr()
+	endpointUdpAddr, _ := net.ResolveUDPAddr(endpoint.Network(), endpoint.String())
+	err = conn.config.WgConfig.WgInterface.UpdatePeer(conn.config.WgConfig.RemoteKey, conn.config.WgConfig.AllowedIps, defaultWgKeepAlive, endpointUdpAddr, conn.config.WgConfig.PreSharedKey)
+	if err != nil {
+		return err
+	}
+
+	if remoteWgPort == 0 {
+		remoteWgPort = iface.DefaultWgPort
+	}
+	go conn.punchRemoteWGPort(pair, remoteWgPort)
+
+	relayedAgent, relayedProxy := conn.agent, conn.wgProxy
+	conn.agent = agent
+	conn.wgProxy = nil
+	conn.upgradeAgent = nil
+	conn.upgradeAnswerCh = nil
+	conn.relayed = false
+	conn.closeRelayConnDelayed()
+
+	if relayedProxy != nil {
+		if err := relayedProxy.CloseConn(); err != nil {
+			log.Debugf("failed to close the relay proxy of peer %s: %v", conn.config.Key, err)
+		}
+	}
+	if relayedAgent != nil {
+		if err := relayedAgent.Close(); err != nil {
+			log.Debugf("failed to close the relayed agent of peer %s: %v", conn.config.Key, err)
+		}
+	}
+
+	err = conn.statusRecorder.UpdatePeerICEState(State{
+		PubKey:                     conn.config.Key,
+		LocalIceCandidateType:      pair.Local.Type().String(),
+		RemoteIceCandidateType:     pair.Remote.Type().String(),
+		LocalIceCandidateEndpoint:  candidateEndpoint(pair.Local),
+		RemoteIceCandidateEndpoint: candidateEndpoint(pair.Remote),
+		Direct:                     true,
+		Relayed:                    false,
+	})
+	if err != nil {
+		log.Warnf("unable to save peer's state, got error: %v", err)
+	}
+
+	log.Infof("upgraded the relayed connection to peer %s to a direct one, endpoint address: %s", conn.config.Key, endpoint.String())
+	return nil
+}
+
+// closeUpgradeAgent closes the upgrade agent unless it has been promoted or the connection has been cleaned up
+func (conn *Conn) closeUpgradeAgent(agent *ice.Agent) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.upgradeAgent != agent {
+		return
+	}
+	conn.upgradeAgent = nil
+	conn.upgradeAnswerCh = nil
+
+	if err := agent.Close(); err != nil {
+		log.Debugf("failed to close the upgrade agent of peer %s: %v", conn.config.Key, err)
+	}
+}
+
+func (conn *Conn) recordRelayUpgrade(upgraded bool) {
+	err := conn.statusRecorder.RecordRelayUpgrade(conn.config.Key, upgraded)
+	if err != nil {
+		log.Debugf("failed to record the relay upgrade of peer %s: %v", conn.config.Key, err)
+	}
+}
+
+// OnRemoteUpgradeOffer handles an upgrade offer from the remote peer and returns true if the message was accepted.
+// The offer is discarded if the connection isn't relayed or a direct path is already being probed
+func (conn *Conn) OnRemoteUpgradeOffer(offer OfferAnswer) bool {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if !conn.isUpgradable() || conn.upgradeAgent != nil {
+		log.Debugf("OnRemoteUpgradeOffer skipping message from peer %s on status %s because it can't be upgraded", conn.config.Key, conn.status.String())
+		return false
+	}
+
+	agent, err := conn.newAgent(relayUpgradeCandidateTypes(), conn.onUpgradeCandidate)
+	if err != nil {
+		log.Errorf("failed to create the upgrade agent of peer %s: %v", conn.config.Key, err)
+		return false
+	}
+	conn.upgradeAgent = agent
+
+	go conn.acceptDirectPath(conn.ctx, agent, offer)
+	return true
+}
+
+// OnRemoteUpgradeAnswer handles the answer to an upgrade offer and returns true if the message was accepted.
+// The answer is discarded if no direct path is being probed
+func (conn *Conn) OnRemoteUpgradeAnswer(answer OfferAnswer) bool {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.upgradeAnswerCh == nil {
+		return false
+	}
+
+	select {
+	case conn.upgradeAnswerCh <- answer:
+		return true
+	default:
+		return false
+	}
+}
+
+// OnRemoteUpgradeCandidate handles a candidate of the upgrade agent of the remote peer
+func (conn *Conn) OnRemoteUpgradeCandidate(candidate ice.Candidate) {
+	log.Debugf("OnRemoteUpgradeCandidate from peer %s -> %s", conn.config.Key, candidate.String())
+	go func() {
+		conn.mu.Lock()
+		defer conn.mu.Unlock()
+
+		if conn.upgradeAgent == nil {
+			return
+		}
+
+		err := conn.upgradeAgent.AddRemoteCandidate(candidate)
+		if err != nil {
+			log.Errorf("error while handling remote upgrade candidate from peer %s", conn.config.Key)
+		}
+	}()
+}
+
+// onUpgradeCandidate signals the local candidates of the upgrade agent to the remote peer
+func (conn *Conn) onUpgradeCandidate(candidate ice.Candidate) {
+	if candidate == nil {
+		return
+	}
+
+	log.Debugf("discovered local upgrade candidate %s", candidate.String())
+	go func() {
+		err := conn.signalUpgradeCandidate(candidate)
+		if err != nil {
+			log.Errorf("failed signaling upgrade candidate to the remote peer %s %s", conn.config.Key, err)
+		}
+	}()
+}
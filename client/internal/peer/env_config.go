@@ -13,6 +13,7 @@ const (
 	envICEKeepAliveIntervalSec   = "NB_ICE_KEEP_ALIVE_INTERVAL_SEC"
 	envICEDisconnectedTimeoutSec = "NB_ICE_DISCONNECTED_TIMEOUT_SEC"
 	envICEForceRelayConn         = "NB_ICE_FORCE_RELAY_CONN"
+	envRelayUpgradeIntervalSec   = "NB_RELAY_UPGRADE_INTERVAL_SEC"
 )
 
 func iceKeepAlive() time.Duration {
@@ -51,3 +52,20 @@ func hasICEForceRelayConn() bool {
 	disconnectedTimeoutEnv := os.Getenv(envICEForceRelayConn)
 	return strings.ToLower(disconnectedTimeoutEnv) == "true"
 }
+
+// relayUpgradeInterval returns the interval of probing a direct path while the connection is relayed, zero disables it
+func relayUpgradeInterval() time.Duration {
+	intervalEnv := os.Getenv(envRelayUpgradeIntervalSec)
+	if intervalEnv == "" {
+		return relayUpgradeIntervalDefault
+	}
+
+	log.Debugf("setting relay upgrade interval to %s seconds", intervalEnv)
+	intervalSec, err := strconv.Atoi(intervalEnv)
+	if err != nil || intervalSec < 0 {
+		log.Warnf("invalid value %s set for %s, using default %v", intervalEnv, envRelayUpgradeIntervalSec, relayUpgradeIntervalDefault)
+		return relayUpgradeIntervalDefault
+	}
+
+	return time.Duration(intervalSec) * time.Second
+}
@@ -70,3 +70,19 @@ func NewConnectionAlreadyClosed(peer string) error {
 		peer: peer,
 	}
 }
+
+// ConnectionPeerOfflineError is an error indicating that the remote peer isn't connected to the Signal server
+type ConnectionPeerOfflineError struct {
+	peer string
+}
+
+func (e *ConnectionPeerOfflineError) Error() string {
+	return fmt.Sprintf("peer %s is offline", e.peer)
+}
+
+// NewConnectionPeerOfflineError creates a new ConnectionPeerOfflineError error
+func NewConnectionPeerOfflineError(peer string) error {
+	return &ConnectionPeerOfflineError{
+		peer: peer,
+	}
+}
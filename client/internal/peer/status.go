@@ -4,6 +4,8 @@ import (
 	"errors"
 	"sync"
 	"time"
+
+	"github.com/netbirdio/netbird/iface"
 )
 
 // State contains the latest state of a peer
@@ -17,6 +19,21 @@ type State struct {
 	Direct                 bool
 	LocalIceCandidateType  string
 	RemoteIceCandidateType string
+	// LocalIceCandidateEndpoint and RemoteIceCandidateEndpoint are the addresses of the selected candidate pair
+	LocalIceCandidateEndpoint  string
+	RemoteIceCandidateEndpoint string
+	// SSHHostKey is the public SSH host key of the peer in the authorized_keys format
+	SSHHostKey string
+	// LastWireguardHandshake is zero if no handshake has been performed yet
+	LastWireguardHandshake time.Time
+	BytesRx                int64
+	BytesTx                int64
+	// Latency is the round trip time of the ICE connectivity checks on the selected candidate pair, zero if unknown
+	Latency time.Duration
+	// RelayUpgradeAttempts counts the probes of a direct path while the connection was relayed, RelayUpgrades the
+	// probes that upgraded the connection
+	RelayUpgradeAttempts int64
+	RelayUpgrades        int64
 }
 
 // LocalPeerState contains the latest state of the local peer
@@ -39,6 +56,56 @@ type ManagementState struct {
 	Connected bool
 }
 
+// FirewallRuleState contains a firewall rule applied from the management and the traffic it matched
+type FirewallRuleState struct {
+	PeerIP    string
+	Direction string
+	Action    string
+	Protocol  string
+	Port      string
+	Packets   uint64
+	Bytes     uint64
+}
+
+// FirewallState contains the applied firewall rules and the traffic dropped by the default policy
+type FirewallState struct {
+	Rules             []FirewallRuleState
+	DroppedInPackets  uint64
+	DroppedInBytes    uint64
+	DroppedOutPackets uint64
+	DroppedOutBytes   uint64
+}
+
+// RouteState contains a route to the network of another peer
+type RouteState struct {
+	ID string
+	// NetworkID identifies the routed network, the routes with the same one are highly available alternatives
+	NetworkID string
+	NetID     string
+	Network   string
+	// Peer is the public key of the routing peer
+	Peer       string
+	Metric     int
+	Masquerade bool
+	// Selected is true for the route chosen to reach its network
+	Selected bool
+}
+
+// DNSDomainState contains a domain resolved by the local DNS server
+type DNSDomainState struct {
+	Domain    string
+	MatchOnly bool
+	// Disabled is true while the nameservers of the domain are deactivated because they don't respond
+	Disabled bool
+}
+
+// DNSState contains the configuration the local DNS server applied to the host
+type DNSState struct {
+	ServerAddress string
+	RouteAll      bool
+	Domains       []DNSDomainState
+}
+
 // FullStatus contains the full state held by the Status instance
 type FullStatus struct {
 	Peers           []State
@@ -59,6 +126,13 @@ type Status struct {
 	mgmAddress      string
 	signalAddress   string
 	notifier        *notifier
+	firewallState   func() (*FirewallState, error)
+	routesState     func() []RouteState
+	dnsState        func() DNSState
+	// stateSubscribers are notified about the changes of the peers and of the connections
+	stateSubscribers map[chan struct{}]struct{}
+	// peerStatsRefresher reads the statistics of the peers of the running engine
+	peerStatsRefresher func()
 
 	// To reduce the number of notification invocation this bool will be true when need to call the notification
 	// Some Peer actions mostly used by in a batch when the network map has been synchronized. In these type of events
@@ -69,11 +143,12 @@ type Status struct {
 // NewRecorder returns a new Status instance
 func NewRecorder(mgmAddress string) *Status {
 	return &Status{
-		peers:        make(map[string]State),
-		changeNotify: make(map[string]chan struct{}),
-		offlinePeers: make([]State, 0),
-		notifier:     newNotifier(),
-		mgmAddress:   mgmAddress,
+		peers:            make(map[string]State),
+		changeNotify:     make(map[string]chan struct{}),
+		offlinePeers:     make([]State, 0),
+		notifier:         newNotifier(),
+		mgmAddress:       mgmAddress,
+		stateSubscribers: make(map[chan struct{}]struct{}),
 	}
 }
 
@@ -86,6 +161,7 @@ func (d *Status) ReplaceOfflinePeers(replacement []State) {
 
 	// todo we should set to true in case if the list changed only
 	d.peerListChangedForNotification = true
+	d.notifyStateSubscribers()
 }
 
 // AddPeer adds peer to Daemon status map
@@ -103,6 +179,7 @@ func (d *Status) AddPeer(peerPubKey string, fqdn string) error {
 		FQDN:       fqdn,
 	}
 	d.peerListChangedForNotification = true
+	d.notifyStateSubscribers()
 	return nil
 }
 
@@ -130,6 +207,7 @@ func (d *Status) RemovePeer(peerPubKey string) error {
 
 	delete(d.peers, peerPubKey)
 	d.peerListChangedForNotification = true
+	d.notifyStateSubscribers()
 	return nil
 }
 
@@ -156,9 +234,13 @@ func (d *Status) UpdatePeerState(receivedState State) error {
 		peerState.Relayed = receivedState.Relayed
 		peerState.LocalIceCandidateType = receivedState.LocalIceCandidateType
 		peerState.RemoteIceCandidateType = receivedState.RemoteIceCandidateType
+		peerState.LocalIceCandidateEndpoint = receivedState.LocalIceCandidateEndpoint
+		peerState.RemoteIceCandidateEndpoint = receivedState.RemoteIceCandidateEndpoint
+		peerState.Latency = receivedState.Latency
 	}
 
 	d.peers[receivedState.PubKey] = peerState
+	d.notifyStateSubscribers()
 
 	if skipNotification {
 		return nil
@@ -188,6 +270,48 @@ func shouldSkipNotify(received, curr State) bool {
 }
 
 // UpdatePeerFQDN update peer's state fqdn only
+// UpdatePeerICEState updates the selected candidate pair of a connected peer whose connection has switched paths
+func (d *Status) UpdatePeerICEState(receivedState State) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	peerState, ok := d.peers[receivedState.PubKey]
+	if !ok {
+		return errors.New("peer doesn't exist")
+	}
+
+	peerState.Direct = receivedState.Direct
+	peerState.Relayed = receivedState.Relayed
+	peerState.LocalIceCandidateType = receivedState.LocalIceCandidateType
+	peerState.RemoteIceCandidateType = receivedState.RemoteIceCandidateType
+	peerState.LocalIceCandidateEndpoint = receivedState.LocalIceCandidateEndpoint
+	peerState.RemoteIceCandidateEndpoint = receivedState.RemoteIceCandidateEndpoint
+	d.peers[receivedState.PubKey] = peerState
+
+	d.notifyStateSubscribers()
+	d.notifyPeerListChanged()
+	return nil
+}
+
+// RecordRelayUpgrade counts a probe of a direct path to a relayed peer and whether it upgraded the connection
+func (d *Status) RecordRelayUpgrade(peerPubKey string, upgraded bool) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	peerState, ok := d.peers[peerPubKey]
+	if !ok {
+		return errors.New("peer doesn't exist")
+	}
+
+	peerState.RelayUpgradeAttempts++
+	if upgraded {
+		peerState.RelayUpgrades++
+	}
+	d.peers[peerPubKey] = peerState
+
+	return nil
+}
+
 func (d *Status) UpdatePeerFQDN(peerPubKey, fqdn string) error {
 	d.mux.Lock()
 	defer d.mux.Unlock()
@@ -203,6 +327,41 @@ func (d *Status) UpdatePeerFQDN(peerPubKey, fqdn string) error {
 	return nil
 }
 
+// UpdatePeerSSHHostKey update peer's state SSH host key only
+func (d *Status) UpdatePeerSSHHostKey(peerPubKey, sshHostKey string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	peerState, ok := d.peers[peerPubKey]
+	if !ok {
+		return errors.New("peer doesn't exist")
+	}
+
+	peerState.SSHHostKey = sshHostKey
+	d.peers[peerPubKey] = peerState
+
+	return nil
+}
+
+// UpdateWireguardPeerState updates the WireGuard statistics and the latency of the peer
+func (d *Status) UpdateWireguardPeerState(peerPubKey string, wgStats iface.WGStats, latency time.Duration) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	peerState, ok := d.peers[peerPubKey]
+	if !ok {
+		return errors.New("peer doesn't exist")
+	}
+
+	peerState.LastWireguardHandshake = wgStats.LastHandshake
+	peerState.BytesRx = wgStats.RxBytes
+	peerState.BytesTx = wgStats.TxBytes
+	peerState.Latency = latency
+	d.peers[peerPubKey] = peerState
+
+	return nil
+}
+
 // FinishPeerListModifications this event invoke the notification
 func (d *Status) FinishPeerListModifications() {
 	d.mux.Lock()
@@ -236,6 +395,7 @@ func (d *Status) UpdateLocalPeerState(localPeerState LocalPeerState) {
 
 	d.localPeer = localPeerState
 	d.notifyAddressChanged()
+	d.notifyStateSubscribers()
 }
 
 // CleanLocalPeerState cleans local peer status
@@ -245,6 +405,7 @@ func (d *Status) CleanLocalPeerState() {
 
 	d.localPeer = LocalPeerState{}
 	d.notifyAddressChanged()
+	d.notifyStateSubscribers()
 }
 
 // MarkManagementDisconnected sets ManagementState to disconnected
@@ -323,6 +484,106 @@ func (d *Status) GetFullStatus() FullStatus {
 	return fullStatus
 }
 
+// SetFirewallStateProvider sets the function returning the state of the firewall of the running engine.
+// A nil provider marks the firewall state as unavailable
+func (d *Status) SetFirewallStateProvider(provider func() (*FirewallState, error)) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.firewallState = provider
+}
+
+// GetFirewallState returns the state of the firewall of the running engine
+func (d *Status) GetFirewallState() (*FirewallState, error) {
+	d.mux.Lock()
+	provider := d.firewallState
+	d.mux.Unlock()
+
+	if provider == nil {
+		return nil, errors.New("firewall state is not available")
+	}
+	return provider()
+}
+
+// SetRoutesStateProvider sets the function returning the routes of the running engine.
+// A nil provider marks the routes as unavailable
+func (d *Status) SetRoutesStateProvider(provider func() []RouteState) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.routesState = provider
+}
+
+// GetRoutesState returns the routes to the networks of the other peers of the running engine
+func (d *Status) GetRoutesState() ([]RouteState, error) {
+	d.mux.Lock()
+	provider := d.routesState
+	d.mux.Unlock()
+
+	if provider == nil {
+		return nil, errors.New("routes are not available")
+	}
+	return provider(), nil
+}
+
+// SetDNSStateProvider sets the function returning the state of the DNS server of the running engine.
+// A nil provider marks the DNS state as unavailable
+func (d *Status) SetDNSStateProvider(provider func() DNSState) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.dnsState = provider
+}
+
+// GetDNSState returns the state of the DNS server of the running engine
+func (d *Status) GetDNSState() (*DNSState, error) {
+	d.mux.Lock()
+	provider := d.dnsState
+	d.mux.Unlock()
+
+	if provider == nil {
+		return nil, errors.New("DNS state is not available")
+	}
+	state := provider()
+	return &state, nil
+}
+
+// SubscribeToStateChanges returns a channel receiving a value whenever the state of the peers or of the connections
+// changes. The changes happening before the value is received are merged into it. The returned function ends the
+// subscription
+func (d *Status) SubscribeToStateChanges() (<-chan struct{}, func()) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	ch := make(chan struct{}, 1)
+	d.stateSubscribers[ch] = struct{}{}
+	return ch, func() {
+		d.mux.Lock()
+		defer d.mux.Unlock()
+		delete(d.stateSubscribers, ch)
+	}
+}
+
+// SetPeerStatsRefresher sets the function reading the WireGuard statistics and the latency of the peers of the
+// running engine into the recorder. A nil refresher leaves the statistics as they are
+func (d *Status) SetPeerStatsRefresher(refresher func()) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.peerStatsRefresher = refresher
+}
+
+// RefreshPeerStats updates the WireGuard statistics and the latency of the peers, before they are shown
+func (d *Status) RefreshPeerStats() {
+	d.mux.Lock()
+	refresher := d.peerStatsRefresher
+	d.mux.Unlock()
+
+	if refresher != nil {
+		refresher()
+	}
+}
+
 // ClientStart will notify all listeners about the new service state
 func (d *Status) ClientStart() {
 	d.notifier.clientStart()
@@ -350,6 +611,17 @@ func (d *Status) RemoveConnectionListener() {
 
 func (d *Status) onConnectionChanged() {
 	d.notifier.updateServerStates(d.managementState, d.signalState)
+	d.notifyStateSubscribers()
+}
+
+// notifyStateSubscribers has to be called with the mutex held
+func (d *Status) notifyStateSubscribers() {
+	for ch := range d.stateSubscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
 }
 
 func (d *Status) notifyPeerListChanged() {
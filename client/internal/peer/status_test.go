@@ -73,6 +73,51 @@ func TestStatus_UpdatePeerFQDN(t *testing.T) {
 	assert.Equal(t, fqdn, state.FQDN, "fqdn should be equal")
 }
 
+func TestStatus_UpdatePeerICEState(t *testing.T) {
+	key := "abc"
+	status := NewRecorder("https://mgm")
+	status.peers[key] = State{
+		PubKey:                key,
+		ConnStatus:            StatusConnected,
+		Relayed:               true,
+		LocalIceCandidateType: "relay",
+	}
+
+	err := status.UpdatePeerICEState(State{
+		PubKey:                     key,
+		Direct:                     true,
+		LocalIceCandidateType:      "host",
+		RemoteIceCandidateType:     "srflx",
+		LocalIceCandidateEndpoint:  "10.0.0.1:51820",
+		RemoteIceCandidateEndpoint: "198.51.100.1:51820",
+	})
+	assert.NoError(t, err, "shouldn't return error")
+
+	state := status.peers[key]
+	assert.Equal(t, StatusConnected, state.ConnStatus, "connection status shouldn't change")
+	assert.False(t, state.Relayed, "connection shouldn't be relayed")
+	assert.True(t, state.Direct, "connection should be direct")
+	assert.Equal(t, "host", state.LocalIceCandidateType)
+	assert.Equal(t, "198.51.100.1:51820", state.RemoteIceCandidateEndpoint)
+
+	assert.Error(t, status.UpdatePeerICEState(State{PubKey: "non_existing_key"}), "should return error when peer doesn't exist")
+}
+
+func TestStatus_RecordRelayUpgrade(t *testing.T) {
+	key := "abc"
+	status := NewRecorder("https://mgm")
+	status.peers[key] = State{PubKey: key}
+
+	assert.NoError(t, status.RecordRelayUpgrade(key, false), "shouldn't return error")
+	assert.NoError(t, status.RecordRelayUpgrade(key, true), "shouldn't return error")
+
+	state := status.peers[key]
+	assert.Equal(t, int64(2), state.RelayUpgradeAttempts, "attempts should be counted")
+	assert.Equal(t, int64(1), state.RelayUpgrades, "upgrades should be counted")
+
+	assert.Error(t, status.RecordRelayUpgrade("non_existing_key", true), "should return error when peer doesn't exist")
+}
+
 func TestGetPeerStateChangeNotifierLogic(t *testing.T) {
 	key := "abc"
 	ip := "10.10.10.10"
@@ -231,3 +276,65 @@ func TestGetFullStatus(t *testing.T) {
 	assert.Equal(t, signalState, fullStatus.SignalState, "signal status should be equal")
 	assert.ElementsMatch(t, []State{peerState1, peerState2}, fullStatus.Peers, "peers states should match")
 }
+
+func TestStatus_SubscribeToStateChanges(t *testing.T) {
+	key := "abc"
+	status := NewRecorder("https://mgm")
+	changes, unsubscribe := status.SubscribeToStateChanges()
+
+	err := status.AddPeer(key, "abc.netbird")
+	assert.NoError(t, err, "shouldn't return error")
+	err = status.UpdatePeerState(State{PubKey: key, ConnStatus: StatusConnecting})
+	assert.NoError(t, err, "shouldn't return error")
+
+	select {
+	case <-changes:
+	default:
+		t.Fatal("expected a change notification")
+	}
+
+	select {
+	case <-changes:
+		t.Fatal("expected the changes to be merged into a single notification")
+	default:
+	}
+
+	unsubscribe()
+	status.MarkSignalConnected()
+
+	select {
+	case <-changes:
+		t.Fatal("expected no notification after unsubscribing")
+	default:
+	}
+}
+
+func TestStatus_StateProviders(t *testing.T) {
+	status := NewRecorder("https://mgm")
+
+	_, err := status.GetRoutesState()
+	assert.Error(t, err, "routes should be unavailable without provider")
+	_, err = status.GetDNSState()
+	assert.Error(t, err, "DNS state should be unavailable without provider")
+
+	routes := []RouteState{{ID: "route", NetworkID: "network", Selected: true}}
+	status.SetRoutesStateProvider(func() []RouteState {
+		return routes
+	})
+	status.SetDNSStateProvider(func() DNSState {
+		return DNSState{ServerAddress: "100.64.0.1:53", RouteAll: true}
+	})
+
+	routesState, err := status.GetRoutesState()
+	assert.NoError(t, err, "shouldn't return error")
+	assert.Equal(t, routes, routesState)
+
+	dnsState, err := status.GetDNSState()
+	assert.NoError(t, err, "shouldn't return error")
+	assert.Equal(t, "100.64.0.1:53", dnsState.ServerAddress)
+	assert.True(t, dnsState.RouteAll)
+
+	status.SetRoutesStateProvider(nil)
+	_, err = status.GetRoutesState()
+	assert.Error(t, err, "routes should be unavailable after the engine stopped")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/netip"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -35,8 +36,10 @@ type clientNetwork struct {
 	peerStateUpdate     chan struct{}
 	routePeersNotifiers map[string]chan struct{}
 	chosenRoute         *route.Route
-	network             netip.Prefix
-	updateSerial        uint64
+	// chosenMux guards the changes of chosenRoute, which is read outside of the watcher for the routes state
+	chosenMux    sync.Mutex
+	network      netip.Prefix
+	updateSerial uint64
 }
 
 func newClientNetworkWatcher(ctx context.Context, wgInterface *iface.WGIface, statusRecorder *peer.Status, network netip.Prefix) *clientNetwork {
@@ -178,7 +181,9 @@ func (c *clientNetwork) removeRouteFromPeerAndSystem() error {
 		if err != nil {
 			return err
 		}
-		err = removeFromRouteTableIfNonSystem(c.network, c.wgInterface.Address().IP.String())
+		err = c.wgInterface.NetNS().Do(func() error {
+			return removeFromRouteTableIfNonSystem(c.network, c.wgInterface.Address().IP.String())
+		})
 		if err != nil {
 			return fmt.Errorf("couldn't remove route %s from system, err: %v",
 				c.network, err)
@@ -200,7 +205,7 @@ func (c *clientNetwork) recalculateRouteAndUpdatePeerAndSystem() error {
 			return err
 		}
 
-		c.chosenRoute = nil
+		c.setChosenRoute(nil)
 
 		return nil
 	}
@@ -217,14 +222,17 @@ func (c *clientNetwork) recalculateRouteAndUpdatePeerAndSystem() error {
 			return err
 		}
 	} else {
-		err = addToRouteTableIfNoExists(c.network, c.wgInterface.Address().IP.String())
+		// the routes are set in the namespace of the interface, if it has been placed in one
+		err = c.wgInterface.NetNS().Do(func() error {
+			return addToRouteTableIfNoExists(c.network, c.wgInterface.Address().IP.String())
+		})
 		if err != nil {
 			return fmt.Errorf("route %s couldn't be added for peer %s, err: %v",
 				c.network.String(), c.wgInterface.Address().IP.String(), err)
 		}
 	}
 
-	c.chosenRoute = c.routes[chosen]
+	c.setChosenRoute(c.routes[chosen])
 	err = c.wgInterface.AddAllowedIP(c.chosenRoute.Peer, c.network.String())
 	if err != nil {
 		log.Errorf("couldn't add allowed IP %s added for peer %s, err: %v",
@@ -234,6 +242,22 @@ func (c *clientNetwork) recalculateRouteAndUpdatePeerAndSystem() error {
 	return nil
 }
 
+func (c *clientNetwork) setChosenRoute(r *route.Route) {
+	c.chosenMux.Lock()
+	defer c.chosenMux.Unlock()
+	c.chosenRoute = r
+}
+
+// chosenRouteID returns the ID of the route chosen to reach the network, empty if none has been chosen
+func (c *clientNetwork) chosenRouteID() string {
+	c.chosenMux.Lock()
+	defer c.chosenMux.Unlock()
+	if c.chosenRoute == nil {
+		return ""
+	}
+	return c.chosenRoute.ID
+}
+
 func (c *clientNetwork) sendUpdateToClientNetworkWatcher(update routesUpdate) {
 	go func() {
 		c.routeUpdate <- update
@@ -3,6 +3,7 @@ package routemanager
 import (
 	"context"
 	"runtime"
+	"sort"
 	"sync"
 
 	log "github.com/sirupsen/logrus"
@@ -21,6 +22,7 @@ type Manager interface {
 	SetRouteChangeListener(listener listener.NetworkChangeListener)
 	InitialRouteRange() []string
 	EnableServerRouter(firewall firewall.Manager) error
+	RoutesState() []peer.RouteState
 	Stop()
 }
 
@@ -30,6 +32,8 @@ type DefaultManager struct {
 	stop           context.CancelFunc
 	mux            sync.Mutex
 	clientNetworks map[string]*clientNetwork
+	// networkRoutes are the routes of the client networks by network ID
+	networkRoutes  map[string][]*route.Route
 	serverRouter   serverRouter
 	statusRecorder *peer.Status
 	wgInterface    *iface.WGIface
@@ -43,6 +47,7 @@ func NewManager(ctx context.Context, pubKey string, wgInterface *iface.WGIface,
 		ctx:            mCTX,
 		stop:           cancel,
 		clientNetworks: make(map[string]*clientNetwork),
+		networkRoutes:  make(map[string][]*route.Route),
 		statusRecorder: statusRecorder,
 		wgInterface:    wgInterface,
 		pubKey:         pubKey,
@@ -87,6 +92,7 @@ func (m *DefaultManager) UpdateRoutes(updateSerial uint64, newRoutes []*route.Ro
 		newServerRoutesMap, newClientRoutesIDMap := m.classifiesRoutes(newRoutes)
 
 		m.updateClientNetworks(updateSerial, newClientRoutesIDMap)
+		m.networkRoutes = newClientRoutesIDMap
 		m.notifier.onNewRoutes(newClientRoutesIDMap)
 
 		if m.serverRouter != nil {
@@ -110,6 +116,40 @@ func (m *DefaultManager) InitialRouteRange() []string {
 	return m.notifier.initialRouteRanges()
 }
 
+// RoutesState returns the routes to the networks of the other peers, sorted by network
+func (m *DefaultManager) RoutesState() []peer.RouteState {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	var states []peer.RouteState
+	for networkID, routes := range m.networkRoutes {
+		chosen := ""
+		if clientNetwork, found := m.clientNetworks[networkID]; found {
+			chosen = clientNetwork.chosenRouteID()
+		}
+		for _, r := range routes {
+			states = append(states, peer.RouteState{
+				ID:         r.ID,
+				NetworkID:  networkID,
+				NetID:      r.NetID,
+				Network:    r.Network.String(),
+				Peer:       r.Peer,
+				Metric:     r.Metric,
+				Masquerade: r.Masquerade,
+				Selected:   r.ID == chosen,
+			})
+		}
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].NetworkID != states[j].NetworkID {
+			return states[i].NetworkID < states[j].NetworkID
+		}
+		return states[i].ID < states[j].ID
+	})
+	return states
+}
+
 func (m *DefaultManager) updateClientNetworks(updateSerial uint64, networks map[string][]*route.Route) {
 	// removing routes that do not exist as per the update from the Management service.
 	for id, client := range m.clientNetworks {
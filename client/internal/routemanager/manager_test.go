@@ -438,3 +438,48 @@ func TestManagerUpdateRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestManagerRoutesState(t *testing.T) {
+	routeA := &route.Route{
+		ID:      "a",
+		NetID:   "routeA",
+		Peer:    remotePeerKey1,
+		Network: netip.MustParsePrefix("100.64.251.250/30"),
+		Metric:  9999,
+	}
+	// highly available alternative of route a
+	routeB := &route.Route{
+		ID:      "b",
+		NetID:   "routeA",
+		Peer:    "remote2",
+		Network: netip.MustParsePrefix("100.64.251.250/30"),
+		Metric:  9999,
+	}
+	routeC := &route.Route{
+		ID:      "c",
+		NetID:   "routeC",
+		Peer:    remotePeerKey1,
+		Network: netip.MustParsePrefix("8.8.8.8/32"),
+		Metric:  100,
+	}
+	networkA := route.GetHAUniqueID(routeA)
+	networkC := route.GetHAUniqueID(routeC)
+
+	routeManager := &DefaultManager{
+		clientNetworks: map[string]*clientNetwork{
+			networkA: {chosenRoute: routeB},
+			networkC: {},
+		},
+		networkRoutes: map[string][]*route.Route{
+			networkA: {routeA, routeB},
+			networkC: {routeC},
+		},
+	}
+
+	states := routeManager.RoutesState()
+	require.Equal(t, []peer.RouteState{
+		{ID: "a", NetworkID: networkA, NetID: "routeA", Network: "100.64.251.250/30", Peer: remotePeerKey1, Metric: 9999},
+		{ID: "b", NetworkID: networkA, NetID: "routeA", Network: "100.64.251.250/30", Peer: "remote2", Metric: 9999, Selected: true},
+		{ID: "c", NetworkID: networkC, NetID: "routeC", Network: "8.8.8.8/32", Peer: remotePeerKey1, Metric: 100},
+	}, states, "routes should be sorted by network with the chosen route selected")
+}
@@ -65,8 +65,9 @@ func (m *defaultServerRouter) updateRoutes(routesMap map[string]*route.Route) er
 		m.routes[id] = newRoute
 	}
 
-	if len(m.routes) > 0 {
-		err := enableIPForwarding()
+	// the routed traffic forwarded in userspace doesn't need the forwarding of the kernel
+	if len(m.routes) > 0 && m.wgInterface.GetForwarder() == nil {
+		err := m.wgInterface.NetNS().Do(enableIPForwarding)
 		if err != nil {
 			return err
 		}
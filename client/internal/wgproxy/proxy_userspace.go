@@ -2,7 +2,9 @@ package wgproxy
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 
 	log "github.com/sirupsen/logrus"
@@ -94,6 +96,10 @@ func (p *WGUserSpaceProxy) proxyToLocal() {
 		default:
 			n, err := p.remoteConn.Read(buf)
 			if err != nil {
+				// the remote conn won't deliver anything anymore once it has been closed
+				if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+					return
+				}
 				continue
 			}
 
@@ -31,6 +31,24 @@ type DaemonServiceClient interface {
 	Down(ctx context.Context, in *DownRequest, opts ...grpc.CallOption) (*DownResponse, error)
 	// GetConfig of the daemon.
 	GetConfig(ctx context.Context, in *GetConfigRequest, opts ...grpc.CallOption) (*GetConfigResponse, error)
+	// GetDNSQueryLog returns the latest queries served by the local DNS server.
+	GetDNSQueryLog(ctx context.Context, in *GetDNSQueryLogRequest, opts ...grpc.CallOption) (*GetDNSQueryLogResponse, error)
+	// GetDNSStats returns per-domain statistics of the queries served by the local DNS server.
+	GetDNSStats(ctx context.Context, in *GetDNSStatsRequest, opts ...grpc.CallOption) (*GetDNSStatsResponse, error)
+	// SetDNSQueryLog enables, disables or clears the DNS query log.
+	SetDNSQueryLog(ctx context.Context, in *SetDNSQueryLogRequest, opts ...grpc.CallOption) (*SetDNSQueryLogResponse, error)
+	// GetFirewallRules returns the firewall rules applied from the management with their packet and byte counters.
+	GetFirewallRules(ctx context.Context, in *GetFirewallRulesRequest, opts ...grpc.CallOption) (*GetFirewallRulesResponse, error)
+	// SetConfig validates and stores the settings of the daemon, they are applied when the client connects the next time.
+	SetConfig(ctx context.Context, in *SetConfigRequest, opts ...grpc.CallOption) (*SetConfigResponse, error)
+	// ListRoutes returns the routes to the networks of the other peers.
+	ListRoutes(ctx context.Context, in *ListRoutesRequest, opts ...grpc.CallOption) (*ListRoutesResponse, error)
+	// ListNetworks returns the networks routed by the other peers with their chosen routing peer.
+	ListNetworks(ctx context.Context, in *ListNetworksRequest, opts ...grpc.CallOption) (*ListNetworksResponse, error)
+	// GetDNSState returns the configuration the local DNS server applied to the host.
+	GetDNSState(ctx context.Context, in *GetDNSStateRequest, opts ...grpc.CallOption) (*GetDNSStateResponse, error)
+	// SubscribeStatus streams the status of the service, a new one is sent whenever the state of the peers changes.
+	SubscribeStatus(ctx context.Context, in *SubscribeStatusRequest, opts ...grpc.CallOption) (DaemonService_SubscribeStatusClient, error)
 }
 
 type daemonServiceClient struct {
@@ -95,6 +113,110 @@ func (c *daemonServiceClient) GetConfig(ctx context.Context, in *GetConfigReques
 	return out, nil
 }
 
+func (c *daemonServiceClient) GetDNSQueryLog(ctx context.Context, in *GetDNSQueryLogRequest, opts ...grpc.CallOption) (*GetDNSQueryLogResponse, error) {
+	out := new(GetDNSQueryLogResponse)
+	err := c.cc.Invoke(ctx, "/daemon.DaemonService/GetDNSQueryLog", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) GetDNSStats(ctx context.Context, in *GetDNSStatsRequest, opts ...grpc.CallOption) (*GetDNSStatsResponse, error) {
+	out := new(GetDNSStatsResponse)
+	err := c.cc.Invoke(ctx, "/daemon.DaemonService/GetDNSStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) SetDNSQueryLog(ctx context.Context, in *SetDNSQueryLogRequest, opts ...grpc.CallOption) (*SetDNSQueryLogResponse, error) {
+	out := new(SetDNSQueryLogResponse)
+	err := c.cc.Invoke(ctx, "/daemon.DaemonService/SetDNSQueryLog", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) GetFirewallRules(ctx context.Context, in *GetFirewallRulesRequest, opts ...grpc.CallOption) (*GetFirewallRulesResponse, error) {
+	out := new(GetFirewallRulesResponse)
+	err := c.cc.Invoke(ctx, "/daemon.DaemonService/GetFirewallRules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) SetConfig(ctx context.Context, in *SetConfigRequest, opts ...grpc.CallOption) (*SetConfigResponse, error) {
+	out := new(SetConfigResponse)
+	err := c.cc.Invoke(ctx, "/daemon.DaemonService/SetConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) ListRoutes(ctx context.Context, in *ListRoutesRequest, opts ...grpc.CallOption) (*ListRoutesResponse, error) {
+	out := new(ListRoutesResponse)
+	err := c.cc.Invoke(ctx, "/daemon.DaemonService/ListRoutes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) ListNetworks(ctx context.Context, in *ListNetworksRequest, opts ...grpc.CallOption) (*ListNetworksResponse, error) {
+	out := new(ListNetworksResponse)
+	err := c.cc.Invoke(ctx, "/daemon.DaemonService/ListNetworks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) GetDNSState(ctx context.Context, in *GetDNSStateRequest, opts ...grpc.CallOption) (*GetDNSStateResponse, error) {
+	out := new(GetDNSStateResponse)
+	err := c.cc.Invoke(ctx, "/daemon.DaemonService/GetDNSState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) SubscribeStatus(ctx context.Context, in *SubscribeStatusRequest, opts ...grpc.CallOption) (DaemonService_SubscribeStatusClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DaemonService_ServiceDesc.Streams[0], "/daemon.DaemonService/SubscribeStatus", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &daemonServiceSubscribeStatusClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DaemonService_SubscribeStatusClient interface {
+	Recv() (*StatusResponse, error)
+	grpc.ClientStream
+}
+
+type daemonServiceSubscribeStatusClient struct {
+	grpc.ClientStream
+}
+
+func (x *daemonServiceSubscribeStatusClient) Recv() (*StatusResponse, error) {
+	m := new(StatusResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // DaemonServiceServer is the server API for DaemonService service.
 // All implementations must embed UnimplementedDaemonServiceServer
 // for forward compatibility
@@ -112,6 +234,24 @@ type DaemonServiceServer interface {
 	Down(context.Context, *DownRequest) (*DownResponse, error)
 	// GetConfig of the daemon.
 	GetConfig(context.Context, *GetConfigRequest) (*GetConfigResponse, error)
+	// GetDNSQueryLog returns the latest queries served by the local DNS server.
+	GetDNSQueryLog(context.Context, *GetDNSQueryLogRequest) (*GetDNSQueryLogResponse, error)
+	// GetDNSStats returns per-domain statistics of the queries served by the local DNS server.
+	GetDNSStats(context.Context, *GetDNSStatsRequest) (*GetDNSStatsResponse, error)
+	// SetDNSQueryLog enables, disables or clears the DNS query log.
+	SetDNSQueryLog(context.Context, *SetDNSQueryLogRequest) (*SetDNSQueryLogResponse, error)
+	// GetFirewallRules returns the firewall rules applied from the management with their packet and byte counters.
+	GetFirewallRules(context.Context, *GetFirewallRulesRequest) (*GetFirewallRulesResponse, error)
+	// SetConfig validates and stores the settings of the daemon, they are applied when the client connects the next time.
+	SetConfig(context.Context, *SetConfigRequest) (*SetConfigResponse, error)
+	// ListRoutes returns the routes to the networks of the other peers.
+	ListRoutes(context.Context, *ListRoutesRequest) (*ListRoutesResponse, error)
+	// ListNetworks returns the networks routed by the other peers with their chosen routing peer.
+	ListNetworks(context.Context, *ListNetworksRequest) (*ListNetworksResponse, error)
+	// GetDNSState returns the configuration the local DNS server applied to the host.
+	GetDNSState(context.Context, *GetDNSStateRequest) (*GetDNSStateResponse, error)
+	// SubscribeStatus streams the status of the service, a new one is sent whenever the state of the peers changes.
+	SubscribeStatus(*SubscribeStatusRequest, DaemonService_SubscribeStatusServer) error
 	mustEmbedUnimplementedDaemonServiceServer()
 }
 
@@ -137,6 +277,33 @@ func (UnimplementedDaemonServiceServer) Down(context.Context, *DownRequest) (*Do
 func (UnimplementedDaemonServiceServer) GetConfig(context.Context, *GetConfigRequest) (*GetConfigResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetConfig not implemented")
 }
+func (UnimplementedDaemonServiceServer) GetDNSQueryLog(context.Context, *GetDNSQueryLogRequest) (*GetDNSQueryLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDNSQueryLog not implemented")
+}
+func (UnimplementedDaemonServiceServer) GetDNSStats(context.Context, *GetDNSStatsRequest) (*GetDNSStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDNSStats not implemented")
+}
+func (UnimplementedDaemonServiceServer) SetDNSQueryLog(context.Context, *SetDNSQueryLogRequest) (*SetDNSQueryLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDNSQueryLog not implemented")
+}
+func (UnimplementedDaemonServiceServer) GetFirewallRules(context.Context, *GetFirewallRulesRequest) (*GetFirewallRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFirewallRules not implemented")
+}
+func (UnimplementedDaemonServiceServer) SetConfig(context.Context, *SetConfigRequest) (*SetConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetConfig not implemented")
+}
+func (UnimplementedDaemonServiceServer) ListRoutes(context.Context, *ListRoutesRequest) (*ListRoutesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRoutes not implemented")
+}
+func (UnimplementedDaemonServiceServer) ListNetworks(context.Context, *ListNetworksRequest) (*ListNetworksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNetworks not implemented")
+}
+func (UnimplementedDaemonServiceServer) GetDNSState(context.Context, *GetDNSStateRequest) (*GetDNSStateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDNSState not implemented")
+}
+func (UnimplementedDaemonServiceServer) SubscribeStatus(*SubscribeStatusRequest, DaemonService_SubscribeStatusServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeStatus not implemented")
+}
 func (UnimplementedDaemonServiceServer) mustEmbedUnimplementedDaemonServiceServer() {}
 
 // UnsafeDaemonServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -258,6 +425,171 @@ func _DaemonService_GetConfig_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DaemonService_GetDNSQueryLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDNSQueryLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).GetDNSQueryLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/daemon.DaemonService/GetDNSQueryLog",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).GetDNSQueryLog(ctx, req.(*GetDNSQueryLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_GetDNSStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDNSStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).GetDNSStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/daemon.DaemonService/GetDNSStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).GetDNSStats(ctx, req.(*GetDNSStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_SetDNSQueryLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDNSQueryLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).SetDNSQueryLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/daemon.DaemonService/SetDNSQueryLog",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).SetDNSQueryLog(ctx, req.(*SetDNSQueryLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_GetFirewallRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFirewallRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).GetFirewallRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/daemon.DaemonService/GetFirewallRules",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).GetFirewallRules(ctx, req.(*GetFirewallRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_SetConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).SetConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/daemon.DaemonService/SetConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).SetConfig(ctx, req.(*SetConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_ListRoutes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRoutesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).ListRoutes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/daemon.DaemonService/ListRoutes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).ListRoutes(ctx, req.(*ListRoutesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_ListNetworks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNetworksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).ListNetworks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/daemon.DaemonService/ListNetworks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).ListNetworks(ctx, req.(*ListNetworksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_GetDNSState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDNSStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).GetDNSState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/daemon.DaemonService/GetDNSState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).GetDNSState(ctx, req.(*GetDNSStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_SubscribeStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DaemonServiceServer).SubscribeStatus(m, &daemonServiceSubscribeStatusServer{stream})
+}
+
+type DaemonService_SubscribeStatusServer interface {
+	Send(*StatusResponse) error
+	grpc.ServerStream
+}
+
+type daemonServiceSubscribeStatusServer struct {
+	grpc.ServerStream
+}
+
+func (x *daemonServiceSubscribeStatusServer) Send(m *StatusResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // DaemonService_ServiceDesc is the grpc.ServiceDesc for DaemonService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -289,7 +621,45 @@ var DaemonService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetConfig",
 			Handler:    _DaemonService_GetConfig_Handler,
 		},
+		{
+			MethodName: "GetDNSQueryLog",
+			Handler:    _DaemonService_GetDNSQueryLog_Handler,
+		},
+		{
+			MethodName: "GetDNSStats",
+			Handler:    _DaemonService_GetDNSStats_Handler,
+		},
+		{
+			MethodName: "SetDNSQueryLog",
+			Handler:    _DaemonService_SetDNSQueryLog_Handler,
+		},
+		{
+			MethodName: "GetFirewallRules",
+			Handler:    _DaemonService_GetFirewallRules_Handler,
+		},
+		{
+			MethodName: "SetConfig",
+			Handler:    _DaemonService_SetConfig_Handler,
+		},
+		{
+			MethodName: "ListRoutes",
+			Handler:    _DaemonService_ListRoutes_Handler,
+		},
+		{
+			MethodName: "ListNetworks",
+			Handler:    _DaemonService_ListNetworks_Handler,
+		},
+		{
+			MethodName: "GetDNSState",
+			Handler:    _DaemonService_GetDNSState_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeStatus",
+			Handler:       _DaemonService_SubscribeStatus_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "daemon.proto",
 }
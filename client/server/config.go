@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"runtime"
+	"strings"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+
+	"github.com/netbirdio/netbird/client/internal"
+	"github.com/netbirdio/netbird/client/proto"
+)
+
+const (
+	// maxInterfaceNameLength is the length limit of the interface names on Linux
+	maxInterfaceNameLength = 15
+	// hiddenPreSharedKey is the pre-shared key returned by GetConfig, setting it leaves the key unchanged
+	hiddenPreSharedKey = "**********"
+)
+
+// SetConfig validates and stores the settings of the daemon, they are applied when the client connects the next time.
+func (s *Server) SetConfig(_ context.Context, msg *proto.SetConfigRequest) (*proto.SetConfigResponse, error) {
+	if err := validateSetConfigRequest(msg); err != nil {
+		return nil, gstatus.Errorf(codes.InvalidArgument, "invalid config: %v", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	inputConfig := s.latestConfigInput
+
+	if msg.ManagementUrl != "" {
+		inputConfig.ManagementURL = msg.ManagementUrl
+		s.latestConfigInput.ManagementURL = msg.ManagementUrl
+	}
+
+	if msg.AdminURL != "" {
+		inputConfig.AdminURL = msg.AdminURL
+		s.latestConfigInput.AdminURL = msg.AdminURL
+	}
+
+	if msg.CleanNATExternalIPs {
+		inputConfig.NATExternalIPs = make([]string, 0)
+		s.latestConfigInput.NATExternalIPs = nil
+	} else if msg.NatExternalIPs != nil {
+		inputConfig.NATExternalIPs = msg.NatExternalIPs
+		s.latestConfigInput.NATExternalIPs = msg.NatExternalIPs
+	}
+
+	if msg.CustomDNSAddress != nil {
+		// an empty address has to be set to restore the default one, nil leaves it unchanged
+		inputConfig.CustomDNSAddress = []byte{}
+		if address := msg.GetCustomDNSAddress(); address != "" {
+			inputConfig.CustomDNSAddress = []byte(address)
+		}
+		s.latestConfigInput.CustomDNSAddress = inputConfig.CustomDNSAddress
+	}
+
+	if msg.RosenpassEnabled != nil {
+		inputConfig.RosenpassEnabled = msg.RosenpassEnabled
+		s.latestConfigInput.RosenpassEnabled = msg.RosenpassEnabled
+	}
+
+	inputConfig.PreSharedKey = msg.PreSharedKey
+	inputConfig.InterfaceName = msg.InterfaceName
+
+	if msg.WireguardPort != nil {
+		port := int(msg.GetWireguardPort())
+		inputConfig.WireguardPort = &port
+	}
+
+	if msg.CleanInterfaceBlacklist {
+		inputConfig.IFaceBlackList = make([]string, 0)
+	} else if msg.InterfaceBlacklist != nil {
+		inputConfig.IFaceBlackList = msg.InterfaceBlacklist
+	}
+
+	config, err := internal.UpdateOrCreateConfig(inputConfig)
+	if err != nil {
+		return nil, err
+	}
+	s.config = config
+
+	return &proto.SetConfigResponse{}, nil
+}
+
+func validateSetConfigRequest(msg *proto.SetConfigRequest) error {
+	if msg.ManagementUrl != "" {
+		if err := validateURL(msg.ManagementUrl); err != nil {
+			return fmt.Errorf("management URL: %w", err)
+		}
+	}
+
+	if msg.AdminURL != "" {
+		if err := validateURL(msg.AdminURL); err != nil {
+			return fmt.Errorf("admin URL: %w", err)
+		}
+	}
+
+	if msg.InterfaceName != nil {
+		if err := validateInterfaceName(msg.GetInterfaceName()); err != nil {
+			return err
+		}
+	}
+
+	if msg.WireguardPort != nil && (msg.GetWireguardPort() < 1 || msg.GetWireguardPort() > 65535) {
+		return fmt.Errorf("WireGuard port %d is out of range", msg.GetWireguardPort())
+	}
+
+	if key := msg.GetPreSharedKey(); key != "" && key != hiddenPreSharedKey {
+		if _, err := wgtypes.ParseKey(key); err != nil {
+			return fmt.Errorf("pre-shared key: %w", err)
+		}
+	}
+
+	if address := msg.GetCustomDNSAddress(); address != "" {
+		if _, err := netip.ParseAddrPort(address); err != nil {
+			return fmt.Errorf("custom DNS address %s should be formatted as IP:Port: %w", address, err)
+		}
+	}
+
+	for _, name := range msg.InterfaceBlacklist {
+		if name == "" {
+			return fmt.Errorf("empty interface name in the interface blacklist")
+		}
+	}
+
+	for _, mapping := range msg.NatExternalIPs {
+		if err := validateNATExternalIP(mapping); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateURL(rawURL string) error {
+	parsedURL, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return err
+	}
+	if parsedURL.Scheme != "https" && parsedURL.Scheme != "http" {
+		return fmt.Errorf("%s should be formatted as [http|https]://[host]:[port]", rawURL)
+	}
+	return nil
+}
+
+// validateInterfaceName checks the name of the WireGuard interface against the rules of the host
+func validateInterfaceName(name string) error {
+	if name == "" {
+		return fmt.Errorf("interface name is empty")
+	}
+	if strings.ContainsAny(name, "/: \t\n") {
+		return fmt.Errorf("interface name %q contains invalid characters", name)
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		if len(name) > maxInterfaceNameLength {
+			return fmt.Errorf("interface name %s is longer than %d characters", name, maxInterfaceNameLength)
+		}
+	case "darwin":
+		if !strings.HasPrefix(name, "utun") {
+			return fmt.Errorf("interface name %s should start with utun", name)
+		}
+	}
+	return nil
+}
+
+// validateNATExternalIP checks an external IP mapping formatted as external[/internal], the internal part being an
+// IP address or the name of an interface of the host
+func validateNATExternalIP(mapping string) error {
+	external, internal, hasInternal := strings.Cut(mapping, "/")
+	if net.ParseIP(external) == nil {
+		return fmt.Errorf("external IP mapping %s should start with an IP address", mapping)
+	}
+	if !hasInternal || net.ParseIP(internal) != nil {
+		return nil
+	}
+
+	if _, err := net.InterfaceByName(internal); err != nil {
+		return fmt.Errorf("external IP mapping %s should end with an IP address or an interface name: %w", mapping, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/netbirdio/netbird/client/internal/dns"
+	"github.com/netbirdio/netbird/client/proto"
+)
+
+// GetDNSQueryLog returns the latest queries served by the local DNS server.
+func (s *Server) GetDNSQueryLog(_ context.Context, msg *proto.GetDNSQueryLogRequest) (*proto.GetDNSQueryLogResponse, error) {
+	queryLog := dns.GetQueryLog()
+
+	entries := queryLog.Entries(int(msg.GetLimit()))
+	pbEntries := make([]*proto.DNSQueryLogEntry, 0, len(entries))
+	for _, entry := range entries {
+		pbEntries = append(pbEntries, &proto.DNSQueryLogEntry{
+			Time:    timestamppb.New(entry.Time),
+			Name:    entry.Name,
+			Type:    entry.Type,
+			Handler: entry.Handler,
+			Rcode:   entry.Rcode,
+			Latency: durationpb.New(entry.Latency),
+		})
+	}
+
+	return &proto.GetDNSQueryLogResponse{
+		Enabled: queryLog.Enabled(),
+		Entries: pbEntries,
+	}, nil
+}
+
+// GetDNSStats returns per-domain statistics of the queries served by the local DNS server.
+func (s *Server) GetDNSStats(_ context.Context, _ *proto.GetDNSStatsRequest) (*proto.GetDNSStatsResponse, error) {
+	queryLog := dns.GetQueryLog()
+
+	stats := queryLog.Stats()
+	pbStats := make([]*proto.DNSDomainStats, 0, len(stats))
+	for _, domainStats := range stats {
+		pbStats = append(pbStats, &proto.DNSDomainStats{
+			Domain:         domainStats.Domain,
+			Queries:        domainStats.Queries,
+			Failures:       domainStats.Failures,
+			AverageLatency: durationpb.New(domainStats.AverageLatency()),
+			LastHandler:    domainStats.LastHandler,
+			LastSeen:       timestamppb.New(domainStats.LastSeen),
+		})
+	}
+
+	return &proto.GetDNSStatsResponse{
+		Enabled: queryLog.Enabled(),
+		Stats:   pbStats,
+	}, nil
+}
+
+// SetDNSQueryLog enables, disables or clears the DNS query log.
+func (s *Server) SetDNSQueryLog(_ context.Context, msg *proto.SetDNSQueryLogRequest) (*proto.SetDNSQueryLogResponse, error) {
+	queryLog := dns.GetQueryLog()
+
+	if msg.Enabled != nil {
+		queryLog.SetEnabled(msg.GetEnabled())
+	}
+
+	if msg.GetClear() {
+		queryLog.Clear()
+	}
+
+	return &proto.SetDNSQueryLogResponse{}, nil
+}
+
+// GetDNSState returns the configuration the local DNS server applied to the host.
+func (s *Server) GetDNSState(_ context.Context, _ *proto.GetDNSStateRequest) (*proto.GetDNSStateResponse, error) {
+	s.mutex.Lock()
+	statusRecorder := s.statusRecorder
+	s.mutex.Unlock()
+
+	if statusRecorder == nil {
+		return nil, gstatus.Errorf(codes.FailedPrecondition, "client is not running")
+	}
+
+	state, err := statusRecorder.GetDNSState()
+	if err != nil {
+		return nil, gstatus.Errorf(codes.FailedPrecondition, "get DNS state: %v", err)
+	}
+
+	pbDomains := make([]*proto.DNSDomain, 0, len(state.Domains))
+	for _, domain := range state.Domains {
+		pbDomains = append(pbDomains, &proto.DNSDomain{
+			Domain:    domain.Domain,
+			MatchOnly: domain.MatchOnly,
+			Disabled:  domain.Disabled,
+		})
+	}
+
+	return &proto.GetDNSStateResponse{
+		ServerAddress: state.ServerAddress,
+		RouteAll:      state.RouteAll,
+		Domains:       pbDomains,
+	}, nil
+}
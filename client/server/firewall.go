@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+
+	"github.com/netbirdio/netbird/client/proto"
+)
+
+// GetFirewallRules returns the firewall rules applied from the management with their packet and byte counters.
+func (s *Server) GetFirewallRules(_ context.Context, _ *proto.GetFirewallRulesRequest) (*proto.GetFirewallRulesResponse, error) {
+	s.mutex.Lock()
+	statusRecorder := s.statusRecorder
+	s.mutex.Unlock()
+
+	if statusRecorder == nil {
+		return nil, gstatus.Errorf(codes.FailedPrecondition, "client is not running")
+	}
+
+	state, err := statusRecorder.GetFirewallState()
+	if err != nil {
+		return nil, gstatus.Errorf(codes.FailedPrecondition, "get firewall state: %v", err)
+	}
+
+	pbRules := make([]*proto.FirewallRuleCounters, 0, len(state.Rules))
+	for _, rule := range state.Rules {
+		pbRules = append(pbRules, &proto.FirewallRuleCounters{
+			PeerIP:    rule.PeerIP,
+			Direction: rule.Direction,
+			Action:    rule.Action,
+			Protocol:  rule.Protocol,
+			Port:      rule.Port,
+			Packets:   rule.Packets,
+			Bytes:     rule.Bytes,
+		})
+	}
+
+	return &proto.GetFirewallRulesResponse{
+		Rules:             pbRules,
+		DroppedInPackets:  state.DroppedInPackets,
+		DroppedInBytes:    state.DroppedInBytes,
+		DroppedOutPackets: state.DroppedOutPackets,
+		DroppedOutBytes:   state.DroppedOutBytes,
+	}, nil
+}
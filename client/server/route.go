@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+
+	"github.com/netbirdio/netbird/client/internal/peer"
+	"github.com/netbirdio/netbird/client/proto"
+)
+
+// ListRoutes returns the routes to the networks of the other peers.
+func (s *Server) ListRoutes(_ context.Context, _ *proto.ListRoutesRequest) (*proto.ListRoutesResponse, error) {
+	routes, err := s.routesState()
+	if err != nil {
+		return nil, err
+	}
+
+	pbRoutes := make([]*proto.Route, 0, len(routes))
+	for _, r := range routes {
+		pbRoutes = append(pbRoutes, &proto.Route{
+			ID:         r.ID,
+			NetworkID:  r.NetworkID,
+			NetID:      r.NetID,
+			Network:    r.Network,
+			Peer:       r.Peer,
+			Metric:     int64(r.Metric),
+			Masquerade: r.Masquerade,
+			Selected:   r.Selected,
+		})
+	}
+
+	return &proto.ListRoutesResponse{Routes: pbRoutes}, nil
+}
+
+// ListNetworks returns the networks routed by the other peers with their chosen routing peer.
+func (s *Server) ListNetworks(_ context.Context, _ *proto.ListNetworksRequest) (*proto.ListNetworksResponse, error) {
+	routes, err := s.routesState()
+	if err != nil {
+		return nil, err
+	}
+
+	// the routes are sorted by network
+	var pbNetworks []*proto.Network
+	for _, r := range routes {
+		if len(pbNetworks) == 0 || pbNetworks[len(pbNetworks)-1].ID != r.NetworkID {
+			pbNetworks = append(pbNetworks, &proto.Network{
+				ID:      r.NetworkID,
+				NetID:   r.NetID,
+				Network: r.Network,
+			})
+		}
+
+		pbNetwork := pbNetworks[len(pbNetworks)-1]
+		pbNetwork.Routes++
+		if r.Selected {
+			pbNetwork.RoutingPeer = r.Peer
+		}
+	}
+
+	return &proto.ListNetworksResponse{Networks: pbNetworks}, nil
+}
+
+func (s *Server) routesState() ([]peer.RouteState, error) {
+	s.mutex.Lock()
+	statusRecorder := s.statusRecorder
+	s.mutex.Unlock()
+
+	if statusRecorder == nil {
+		return nil, gstatus.Errorf(codes.FailedPrecondition, "client is not running")
+	}
+
+	routes, err := statusRecorder.GetRoutesState()
+	if err != nil {
+		return nil, gstatus.Errorf(codes.FailedPrecondition, "get routes: %v", err)
+	}
+	return routes, nil
+}
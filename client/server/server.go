@@ -13,6 +13,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	gstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/netbirdio/netbird/client/internal"
@@ -428,6 +429,44 @@ func (s *Server) Status(
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	return s.statusResponse(msg.GetFullPeerStatus)
+}
+
+// SubscribeStatus streams the status of the service, a new one is sent whenever the state of the peers changes.
+func (s *Server) SubscribeStatus(msg *proto.SubscribeStatusRequest, stream proto.DaemonService_SubscribeStatusServer) error {
+	s.mutex.Lock()
+	statusResponse, err := s.statusResponse(msg.GetFullPeerStatus)
+	if err != nil {
+		s.mutex.Unlock()
+		return err
+	}
+	// subscribing before the first status is sent so that no change gets lost
+	changes, unsubscribe := s.statusRecorder.SubscribeToStateChanges()
+	s.mutex.Unlock()
+	defer unsubscribe()
+
+	for {
+		if err := stream.Send(statusResponse); err != nil {
+			return err
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-changes:
+		}
+
+		s.mutex.Lock()
+		statusResponse, err = s.statusResponse(msg.GetFullPeerStatus)
+		s.mutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// statusResponse returns the status of the service, it has to be called with the mutex held
+func (s *Server) statusResponse(getFullPeerStatus bool) (*proto.StatusResponse, error) {
 	status, err := internal.CtxGetState(s.rootCtx).Status()
 	if err != nil {
 		return nil, err
@@ -441,7 +480,8 @@ func (s *Server) Status(
 		s.statusRecorder.UpdateManagementAddress(s.config.ManagementURL.String())
 	}
 
-	if msg.GetFullPeerStatus {
+	if getFullPeerStatus {
+		s.statusRecorder.RefreshPeerStats()
 		fullStatus := s.statusRecorder.GetFullStatus()
 		pbFullStatus := toProtoFullStatus(fullStatus)
 		statusResponse.FullStatus = pbFullStatus
@@ -458,6 +498,10 @@ func (s *Server) GetConfig(_ context.Context, _ *proto.GetConfigRequest) (*proto
 	managementURL := s.latestConfigInput.ManagementURL
 	adminURL := s.latestConfigInput.AdminURL
 	preSharedKey := ""
+	response := &proto.GetConfigResponse{
+		ConfigFile: s.latestConfigInput.ConfigPath,
+		LogFile:    s.logFile,
+	}
 
 	if s.config != nil {
 		if managementURL == "" && s.config.ManagementURL != nil {
@@ -470,18 +514,21 @@ func (s *Server) GetConfig(_ context.Context, _ *proto.GetConfigRequest) (*proto
 
 		preSharedKey = s.config.PreSharedKey
 		if preSharedKey != "" {
-			preSharedKey = "**********"
+			preSharedKey = hiddenPreSharedKey
 		}
 
+		response.InterfaceName = s.config.WgIface
+		response.WireguardPort = int64(s.config.WgPort)
+		response.RosenpassEnabled = s.config.RosenpassEnabled
+		response.CustomDNSAddress = s.config.CustomDNSAddress
+		response.InterfaceBlacklist = s.config.IFaceBlackList
+		response.NatExternalIPs = s.config.NATExternalIPs
 	}
 
-	return &proto.GetConfigResponse{
-		ManagementUrl: managementURL,
-		AdminURL:      adminURL,
-		ConfigFile:    s.latestConfigInput.ConfigPath,
-		LogFile:       s.logFile,
-		PreSharedKey:  preSharedKey,
-	}, nil
+	response.ManagementUrl = managementURL
+	response.AdminURL = adminURL
+	response.PreSharedKey = preSharedKey
+	return response, nil
 }
 
 func toProtoFullStatus(fullStatus peer.FullStatus) *proto.FullStatus {
@@ -505,15 +552,28 @@ func toProtoFullStatus(fullStatus peer.FullStatus) *proto.FullStatus {
 
 	for _, peerState := range fullStatus.Peers {
 		pbPeerState := &proto.PeerState{
-			IP:                     peerState.IP,
-			PubKey:                 peerState.PubKey,
-			ConnStatus:             peerState.ConnStatus.String(),
-			ConnStatusUpdate:       timestamppb.New(peerState.ConnStatusUpdate),
-			Relayed:                peerState.Relayed,
-			Direct:                 peerState.Direct,
-			LocalIceCandidateType:  peerState.LocalIceCandidateType,
-			RemoteIceCandidateType: peerState.RemoteIceCandidateType,
-			Fqdn:                   peerState.FQDN,
+			IP:                         peerState.IP,
+			PubKey:                     peerState.PubKey,
+			ConnStatus:                 peerState.ConnStatus.String(),
+			ConnStatusUpdate:           timestamppb.New(peerState.ConnStatusUpdate),
+			Relayed:                    peerState.Relayed,
+			Direct:                     peerState.Direct,
+			LocalIceCandidateType:      peerState.LocalIceCandidateType,
+			RemoteIceCandidateType:     peerState.RemoteIceCandidateType,
+			Fqdn:                       peerState.FQDN,
+			SshHostKey:                 peerState.SSHHostKey,
+			BytesRx:                    peerState.BytesRx,
+			BytesTx:                    peerState.BytesTx,
+			LocalIceCandidateEndpoint:  peerState.LocalIceCandidateEndpoint,
+			RemoteIceCandidateEndpoint: peerState.RemoteIceCandidateEndpoint,
+			RelayUpgradeAttempts:       peerState.RelayUpgradeAttempts,
+			RelayUpgrades:              peerState.RelayUpgrades,
+		}
+		if !peerState.LastWireguardHandshake.IsZero() {
+			pbPeerState.LastWireguardHandshake = timestamppb.New(peerState.LastWireguardHandshake)
+		}
+		if peerState.Latency > 0 {
+			pbPeerState.Latency = durationpb.New(peerState.Latency)
 		}
 		pbFullStatus.Peers = append(pbFullStatus.Peers, pbPeerState)
 	}
@@ -2,7 +2,6 @@ package ssh
 
 import (
 	"fmt"
-	"net"
 	"os"
 	"time"
 
@@ -85,20 +84,26 @@ func (c *Client) OpenTerminal() error {
 }
 
 // DialWithKey connects to the remote SSH server with a provided private key file (PEM).
-func DialWithKey(addr, user string, privateKey []byte) (*Client, error) {
+// The server has to present the provided host key (authorized_keys format) distributed by the management service.
+func DialWithKey(addr, user string, privateKey, hostKey []byte) (*Client, error) {
 
 	signer, err := ssh.ParsePrivateKey(privateKey)
 	if err != nil {
 		return nil, err
 	}
 
+	hostKeyCallback, err := HostKeyCallback(hostKey)
+	if err != nil {
+		return nil, err
+	}
+
 	config := &ssh.ClientConfig{
 		User:    user,
 		Timeout: 5 * time.Second,
 		Auth: []ssh.AuthMethod{
 			ssh.PublicKeys(signer),
 		},
-		HostKeyCallback: ssh.HostKeyCallback(func(hostname string, remote net.Addr, key ssh.PublicKey) error { return nil }),
+		HostKeyCallback: hostKeyCallback,
 	}
 
 	return Dial("tcp", addr, config)
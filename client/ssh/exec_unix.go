@@ -0,0 +1,51 @@
+//go:build !windows
+
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// userSysProcAttr returns the process attributes to run a command as the local user.
+// It returns nil if the command can run with the credentials of the current process
+func userSysProcAttr(localUser *user.User) (*syscall.SysProcAttr, error) {
+	uid, err := strconv.ParseUint(localUser.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("parse uid %s of user %s: %w", localUser.Uid, localUser.Username, err)
+	}
+
+	if os.Geteuid() != 0 || uint32(uid) == uint32(os.Geteuid()) {
+		return nil, nil
+	}
+
+	gid, err := strconv.ParseUint(localUser.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("parse gid %s of user %s: %w", localUser.Gid, localUser.Username, err)
+	}
+
+	var groups []uint32
+	groupIDs, err := localUser.GroupIds()
+	if err != nil {
+		return nil, fmt.Errorf("lookup groups of user %s: %w", localUser.Username, err)
+	}
+	for _, groupID := range groupIDs {
+		parsed, err := strconv.ParseUint(groupID, 10, 32)
+		if err != nil {
+			continue
+		}
+		groups = append(groups, uint32(parsed))
+	}
+
+	return &syscall.SysProcAttr{
+		Credential: &syscall.Credential{
+			Uid:    uint32(uid),
+			Gid:    uint32(gid),
+			Groups: groups,
+		},
+		Setsid: true,
+	}, nil
+}
@@ -0,0 +1,10 @@
+package ssh
+
+import (
+	"os/user"
+	"syscall"
+)
+
+func userSysProcAttr(_ *user.User) (*syscall.SysProcAttr, error) {
+	return nil, nil
+}
@@ -0,0 +1,121 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// EnvKnownHostsFile is the environment variable that enables writing a managed known_hosts file with the host keys of the
+// remote peers to the given path, e.g. to be used with the GlobalKnownHostsFile option of OpenSSH clients
+const EnvKnownHostsFile = "NB_SSH_KNOWN_HOSTS_FILE"
+
+const knownHostsHeader = "# This file is managed by NetBird. Manual changes will be overwritten.\n"
+
+// KnownHost is a remote peer and the public host key of its SSH server
+type KnownHost struct {
+	// Hosts are the addresses and names of the peer, e.g. its NetBird IP and FQDN
+	Hosts []string
+	// HostKey is the public host key in the authorized_keys format
+	HostKey []byte
+}
+
+// KnownHostsFile returns the path of the managed known_hosts file or an empty string if writing it is disabled
+func KnownHostsFile() string {
+	return os.Getenv(EnvKnownHostsFile)
+}
+
+// HostKeyCallback returns a ssh.HostKeyCallback that only accepts the given host key (authorized_keys format)
+func HostKeyCallback(hostKey []byte) (ssh.HostKeyCallback, error) {
+	if len(hostKey) == 0 {
+		return nil, fmt.Errorf("no host key provided")
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey(hostKey)
+	if err != nil {
+		return nil, fmt.Errorf("parse host key: %w", err)
+	}
+
+	return ssh.FixedHostKey(key), nil
+}
+
+// MarshalKnownHosts returns the known hosts in the OpenSSH known_hosts format.
+// Hosts are written with the SSH port so that they don't conflict with other SSH servers running on the peers
+func MarshalKnownHosts(hosts []KnownHost, port int) ([]byte, error) {
+	lines := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if len(host.Hosts) == 0 {
+			continue
+		}
+
+		key, _, _, _, err := ssh.ParseAuthorizedKey(host.HostKey)
+		if err != nil {
+			return nil, fmt.Errorf("parse host key of %s: %w", host.Hosts[0], err)
+		}
+
+		addresses := make([]string, 0, len(host.Hosts))
+		for _, h := range host.Hosts {
+			addresses = append(addresses, net.JoinHostPort(h, strconv.Itoa(port)))
+		}
+		lines = append(lines, knownhosts.Line(addresses, key))
+	}
+	sort.Strings(lines)
+
+	buf := bytes.NewBufferString(knownHostsHeader)
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+// WriteKnownHosts atomically replaces the file at path with the known hosts
+func WriteKnownHosts(path string, hosts []KnownHost, port int) error {
+	content, err := MarshalKnownHosts(hosts, port)
+	if err != nil {
+		return err
+	}
+
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write known hosts: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("set known hosts permissions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close known hosts: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace known hosts: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package ssh
+
+import (
+	"net"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestWriteKnownHosts(t *testing.T) {
+	key, err := GeneratePrivateKey(ED25519)
+	require.NoError(t, err)
+	hostKey, err := GeneratePublicKey(key)
+	require.NoError(t, err)
+
+	otherKey, err := GeneratePrivateKey(ED25519)
+	require.NoError(t, err)
+	otherHostKey, err := GeneratePublicKey(otherKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "ssh_known_hosts")
+	err = WriteKnownHosts(path, []KnownHost{
+		{Hosts: []string{"100.64.0.10", "peer-a.netbird.cloud", "peer-a"}, HostKey: hostKey},
+		{Hosts: nil, HostKey: otherHostKey},
+	}, DefaultSSHPort)
+	require.NoError(t, err)
+
+	callback, err := knownhosts.New(path)
+	require.NoError(t, err)
+
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey(hostKey)
+	require.NoError(t, err)
+	parsedOther, _, _, _, err := ssh.ParseAuthorizedKey(otherHostKey)
+	require.NoError(t, err)
+
+	remote := &net.TCPAddr{IP: net.ParseIP("100.64.0.10"), Port: DefaultSSHPort}
+	for _, host := range []string{"100.64.0.10:44338", "peer-a.netbird.cloud:44338", "peer-a:44338"} {
+		assert.NoError(t, callback(host, remote, parsed), "host key of %s should be known", host)
+		assert.Error(t, callback(host, remote, parsedOther), "other key of %s shouldn't be accepted", host)
+	}
+
+	// the NetBird host key must not be used for other SSH servers of the peer
+	var keyErr *knownhosts.KeyError
+	err = callback("100.64.0.10:22", &net.TCPAddr{IP: net.ParseIP("100.64.0.10"), Port: 22}, parsed)
+	require.ErrorAs(t, err, &keyErr)
+	assert.Empty(t, keyErr.Want)
+}
+
+func TestMarshalKnownHosts_InvalidKey(t *testing.T) {
+	_, err := MarshalKnownHosts([]KnownHost{{Hosts: []string{"100.64.0.10"}, HostKey: []byte("invalid")}}, DefaultSSHPort)
+	assert.Error(t, err)
+}
+
+func TestDialWithKey_VerifiesHostKey(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SSH server is not supported on Windows")
+	}
+
+	hostPrivateKey, err := GeneratePrivateKey(ED25519)
+	require.NoError(t, err)
+	hostKey, err := GeneratePublicKey(hostPrivateKey)
+	require.NoError(t, err)
+	server, err := newDefaultServer(hostPrivateKey, "127.0.0.1:0")
+	require.NoError(t, err)
+
+	clientKey, err := GeneratePrivateKey(ED25519)
+	require.NoError(t, err)
+	clientPubKey, err := GeneratePublicKey(clientKey)
+	require.NoError(t, err)
+	require.NoError(t, server.AddAuthorizedKey("remotePeer", string(clientPubKey)))
+
+	go func() {
+		_ = server.Start()
+	}()
+	t.Cleanup(func() {
+		_ = server.Stop()
+	})
+
+	currentUser, err := user.Current()
+	require.NoError(t, err)
+
+	client, err := DialWithKey(server.listener.Addr().String(), currentUser.Username, clientKey, hostKey)
+	require.NoError(t, err, "client should accept the expected host key")
+	_ = client.Close()
+
+	otherKey, err := GeneratePrivateKey(ED25519)
+	require.NoError(t, err)
+	otherHostKey, err := GeneratePublicKey(otherKey)
+	require.NoError(t, err)
+
+	_, err = DialWithKey(server.listener.Addr().String(), currentUser.Username, clientKey, otherHostKey)
+	assert.ErrorContains(t, err, "host key mismatch", "client should reject an unexpected host key")
+
+	_, err = DialWithKey(server.listener.Addr().String(), currentUser.Username, clientKey, nil)
+	assert.Error(t, err, "client should refuse to connect without a host key")
+}
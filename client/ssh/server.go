@@ -1,9 +1,11 @@
 package ssh
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"net/netip"
 	"os"
 	"os/exec"
 	"os/user"
@@ -26,6 +28,13 @@ const TerminalTimeout = 10 * time.Second
 // TerminalBackoffDelay is the delay between terminal session readiness checks
 const TerminalBackoffDelay = 500 * time.Millisecond
 
+// defaultUserPath is the PATH of the commands executed without a login session
+const defaultUserPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// minReverseForwardingPort is the lowest port a client can ask the server to listen on for remote port forwarding.
+// Port 0 lets the server pick a port
+const minReverseForwardingPort = 1024
+
 // DefaultSSHServer is a function that creates DefaultServer
 func DefaultSSHServer(hostKeyPEM []byte, addr string) (Server, error) {
 	return newDefaultServer(hostKeyPEM, addr)
@@ -41,6 +50,14 @@ type Server interface {
 	RemoveAuthorizedKey(peer string)
 	// AddAuthorizedKey add a given peer key to server authorized keys
 	AddAuthorizedKey(peer, newKey string) error
+	// SetPortForwardingEnabled allows or denies local and remote port forwarding
+	SetPortForwardingEnabled(enabled bool)
+	// SetAuthorizedUsers sets the local users a given peer is allowed to log in as if the user authorization is enabled
+	SetAuthorizedUsers(peer string, users []string)
+	// SetUserAuthorizationEnabled restricts the local users of the remote peers to their authorized users when enabled
+	SetUserAuthorizationEnabled(enabled bool)
+	// SetSessionListener sets the listener notified about the start and the end of the sessions
+	SetSessionListener(listener func(SessionEvent))
 }
 
 // DefaultServer is the embedded NetBird SSH server
@@ -51,6 +68,15 @@ type DefaultServer struct {
 	mu             sync.Mutex
 	hostKeyPEM     []byte
 	sessions       []ssh.Session
+	// portForwarding indicates whether local and remote port forwarding is allowed
+	portForwarding bool
+	// authorizedUsers are the local users indexed by peer WireGuard public key the peer is allowed to log in as
+	authorizedUsers map[string][]string
+	// authorizeUsers indicates whether the local users of the peers are restricted to their authorized users
+	authorizeUsers  bool
+	sessionListener func(SessionEvent)
+	// trackedSessions are the IDs of the connections with started sessions
+	trackedSessions map[string]struct{}
 }
 
 // newDefaultServer creates new server with provided host key
@@ -60,7 +86,15 @@ func newDefaultServer(hostKeyPEM []byte, addr string) (*DefaultServer, error) {
 		return nil, err
 	}
 	allowedKeys := make(map[string]ssh.PublicKey)
-	return &DefaultServer{listener: ln, mu: sync.Mutex{}, hostKeyPEM: hostKeyPEM, authorizedKeys: allowedKeys, sessions: make([]ssh.Session, 0)}, nil
+	return &DefaultServer{
+		listener:        ln,
+		mu:              sync.Mutex{},
+		hostKeyPEM:      hostKeyPEM,
+		authorizedKeys:  allowedKeys,
+		authorizedUsers: make(map[string][]string),
+		sessions:        make([]ssh.Session, 0),
+		trackedSessions: make(map[string]struct{}),
+	}, nil
 }
 
 // RemoveAuthorizedKey removes SSH key of a given peer from the authorized keys
@@ -69,6 +103,7 @@ func (srv *DefaultServer) RemoveAuthorizedKey(peer string) {
 	defer srv.mu.Unlock()
 
 	delete(srv.authorizedKeys, peer)
+	delete(srv.authorizedUsers, peer)
 }
 
 // AddAuthorizedKey add a given peer key to server authorized keys
@@ -85,6 +120,83 @@ func (srv *DefaultServer) AddAuthorizedKey(peer, newKey string) error {
 	return nil
 }
 
+// SetPortForwardingEnabled allows or denies local and remote port forwarding
+func (srv *DefaultServer) SetPortForwardingEnabled(enabled bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.portForwarding = enabled
+}
+
+func (srv *DefaultServer) isPortForwardingEnabled() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	return srv.portForwarding
+}
+
+// localPortForwardingHandler allows the client to open connections from the server to the destination
+func (srv *DefaultServer) localPortForwardingHandler(ctx ssh.Context, destinationHost string, destinationPort uint32) bool {
+	if !srv.isPortForwardingEnabled() {
+		log.Warnf("denied SSH local port forwarding to %s:%d for %s from %s: port forwarding is disabled",
+			destinationHost, destinationPort, ctx.User(), ctx.RemoteAddr())
+		return false
+	}
+
+	log.Infof("SSH local port forwarding to %s:%d for %s from %s", destinationHost, destinationPort, ctx.User(), ctx.RemoteAddr())
+	srv.trackSession(ctx)
+	return true
+}
+
+// reversePortForwardingHandler allows the client to listen on the server and receive the accepted connections
+func (srv *DefaultServer) reversePortForwardingHandler(ctx ssh.Context, bindHost string, bindPort uint32) bool {
+	if !srv.isPortForwardingEnabled() {
+		log.Warnf("denied SSH remote port forwarding from %s:%d for %s from %s: port forwarding is disabled",
+			bindHost, bindPort, ctx.User(), ctx.RemoteAddr())
+		return false
+	}
+
+	if !srv.isReverseForwardingHostAllowed(bindHost) {
+		log.Warnf("denied SSH remote port forwarding from %s:%d for %s from %s: only loopback and NetBird addresses are allowed",
+			bindHost, bindPort, ctx.User(), ctx.RemoteAddr())
+		return false
+	}
+
+	if bindPort != 0 && bindPort < minReverseForwardingPort {
+		log.Warnf("denied SSH remote port forwarding from %s:%d for %s from %s: privileged ports are not allowed",
+			bindHost, bindPort, ctx.User(), ctx.RemoteAddr())
+		return false
+	}
+
+	log.Infof("SSH remote port forwarding from %s:%d for %s from %s", bindHost, bindPort, ctx.User(), ctx.RemoteAddr())
+	srv.trackSession(ctx)
+	return true
+}
+
+// isReverseForwardingHostAllowed returns true if the client asks to listen on a loopback address or on the address
+// of the SSH server, which is the NetBird address of the peer. Listening on other addresses, the wildcard addresses
+// included, would expose the forwarded port to the networks of the host
+func (srv *DefaultServer) isReverseForwardingHostAllowed(bindHost string) bool {
+	if bindHost == "localhost" {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(bindHost)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	if addr.IsLoopback() {
+		return true
+	}
+
+	serverAddr, err := netip.ParseAddrPort(srv.listener.Addr().String())
+	if err != nil || serverAddr.Addr().IsUnspecified() {
+		return false
+	}
+	return serverAddr.Addr().Unmap() == addr
+}
+
 // Stop stops SSH server.
 func (srv *DefaultServer) Stop() error {
 	srv.mu.Lock()
@@ -107,10 +219,16 @@ func (srv *DefaultServer) publicKeyHandler(ctx ssh.Context, key ssh.PublicKey) b
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
 
-	for _, allowed := range srv.authorizedKeys {
-		if ssh.KeysEqual(allowed, key) {
-			return true
+	for peer, allowed := range srv.authorizedKeys {
+		if !ssh.KeysEqual(allowed, key) {
+			continue
 		}
+
+		if srv.authorizeUsers && !srv.isUserAuthorized(peer, ctx.User()) {
+			log.Warnf("denied SSH login of peer %s as user %s from %s: the user isn't authorized", peer, ctx.User(), ctx.RemoteAddr())
+			return false
+		}
+		return true
 	}
 
 	return false
@@ -146,6 +264,7 @@ func (srv *DefaultServer) sessionHandler(session ssh.Session) {
 	}()
 
 	log.Infof("Establishing SSH session for %s from host %s", session.User(), session.RemoteAddr().String())
+	srv.trackSession(session.Context())
 
 	localUser, err := userNameLookup(session.User())
 	if err != nil {
@@ -201,18 +320,95 @@ func (srv *DefaultServer) sessionHandler(session ssh.Session) {
 			return
 		}
 	} else {
-		_, err := io.WriteString(session, "only PTY is supported.\n")
-		if err != nil {
-			return
-		}
-		err = session.Exit(1)
-		if err != nil {
-			return
-		}
+		srv.execHandler(session, localUser)
 	}
 	log.Debugf("SSH session ended")
 }
 
+// execHandler runs the requested command, or the user's shell if no command was requested, without a PTY.
+// The command's exit code is returned to the client
+func (srv *DefaultServer) execHandler(session ssh.Session, localUser *user.User) {
+	shell := getUserShell(localUser.Uid)
+
+	args := []string{}
+	if session.RawCommand() != "" {
+		args = append(args, "-c", session.RawCommand())
+	}
+
+	cmd, err := newUserCommand(session, localUser, shell, args...)
+	if err != nil {
+		log.Warnf("failed preparing SSH command for user %s from %s: %v", localUser.Username, session.RemoteAddr(), err)
+		_, _ = fmt.Fprintf(session.Stderr(), "remote SSH server couldn't run the command as user %s\n", localUser.Username)
+		_ = session.Exit(1)
+		return
+	}
+
+	log.Debugf("SSH exec command for user %s: %s", localUser.Username, cmd.String())
+	_ = session.Exit(runSessionCommand(session, cmd))
+}
+
+// newUserCommand creates a command that runs as the local user in its home directory
+func newUserCommand(session ssh.Session, localUser *user.User, name string, args ...string) (*exec.Cmd, error) {
+	sysProcAttr, err := userSysProcAttr(localUser)
+	if err != nil {
+		return nil, err
+	}
+
+	shell := getUserShell(localUser.Uid)
+
+	cmd := exec.CommandContext(session.Context(), name, args...)
+	cmd.Dir = localUser.HomeDir
+	cmd.SysProcAttr = sysProcAttr
+	cmd.Env = append(cmd.Env, prepareUserEnv(localUser, shell)...)
+	cmd.Env = append(cmd.Env, "LOGNAME="+localUser.Username, "PATH="+defaultUserPath)
+	for _, v := range session.Environ() {
+		if acceptEnv(v) {
+			cmd.Env = append(cmd.Env, v)
+		}
+	}
+
+	return cmd, nil
+}
+
+// runSessionCommand runs the command connected to the session's stdin, stdout and stderr and returns its exit code
+func runSessionCommand(session ssh.Session, cmd *exec.Cmd) int {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		log.Errorf("failed creating stdin pipe of SSH command: %v", err)
+		return 1
+	}
+	cmd.Stdout = session
+	cmd.Stderr = session.Stderr()
+
+	if err := cmd.Start(); err != nil {
+		log.Warnf("failed starting SSH command %s: %v", cmd.String(), err)
+		_, _ = fmt.Fprintf(session.Stderr(), "failed starting command: %v\n", err)
+		return 1
+	}
+
+	// the client may keep stdin open after the command exits, so it is copied outside of exec.Cmd.Wait
+	go func() {
+		_, _ = io.Copy(stdin, session)
+		_ = stdin.Close()
+	}()
+
+	return exitCode(cmd.Wait())
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+		return exitErr.ExitCode()
+	}
+
+	log.Debugf("SSH command failed: %v", err)
+	return 255
+}
+
 func (srv *DefaultServer) stdInOut(file *os.File, session ssh.Session) {
 	go func() {
 		// stdin
@@ -247,9 +443,33 @@ func (srv *DefaultServer) stdInOut(file *os.File, session ssh.Session) {
 func (srv *DefaultServer) Start() error {
 	log.Infof("starting SSH server on addr: %s", srv.listener.Addr().String())
 
+	forwardHandler := &ssh.ForwardedTCPHandler{}
+	server := &ssh.Server{
+		Handler:                       srv.sessionHandler,
+		LocalPortForwardingCallback:   srv.localPortForwardingHandler,
+		ReversePortForwardingCallback: srv.reversePortForwardingHandler,
+		ChannelHandlers: map[string]ssh.ChannelHandler{
+			"session":      ssh.DefaultSessionHandler,
+			"direct-tcpip": ssh.DirectTCPIPHandler,
+		},
+		RequestHandlers: map[string]ssh.RequestHandler{
+			"tcpip-forward":        forwardHandler.HandleSSHRequest,
+			"cancel-tcpip-forward": forwardHandler.HandleSSHRequest,
+		},
+		SubsystemHandlers: map[string]ssh.SubsystemHandler{
+			"sftp": srv.sftpHandler,
+		},
+	}
+
 	publicKeyOption := ssh.PublicKeyAuth(srv.publicKeyHandler)
 	hostKeyPEM := ssh.HostKeyPEM(srv.hostKeyPEM)
-	err := ssh.Serve(srv.listener, srv.sessionHandler, publicKeyOption, hostKeyPEM)
+	for _, option := range []ssh.Option{publicKeyOption, hostKeyPEM} {
+		if err := server.SetOption(option); err != nil {
+			return err
+		}
+	}
+
+	err := server.Serve(srv.listener)
 	if err != nil {
 		return err
 	}
@@ -4,11 +4,15 @@ import "context"
 
 // MockServer mocks ssh.Server
 type MockServer struct {
-	Ctx                     context.Context
-	StopFunc                func() error
-	StartFunc               func() error
-	AddAuthorizedKeyFunc    func(peer, newKey string) error
-	RemoveAuthorizedKeyFunc func(peer string)
+	Ctx                      context.Context
+	StopFunc                 func() error
+	StartFunc                func() error
+	AddAuthorizedKeyFunc     func(peer, newKey string) error
+	RemoveAuthorizedKeyFunc  func(peer string)
+	SetPortForwardingFunc    func(enabled bool)
+	SetAuthorizedUsersFunc   func(peer string, users []string)
+	SetUserAuthorizationFunc func(enabled bool)
+	SetSessionListenerFunc   func(listener func(SessionEvent))
 }
 
 // RemoveAuthorizedKey removes SSH key of a given peer from the authorized keys
@@ -42,3 +46,35 @@ func (srv *MockServer) Start() error {
 	}
 	return srv.StartFunc()
 }
+
+// SetPortForwardingEnabled allows or denies local and remote port forwarding
+func (srv *MockServer) SetPortForwardingEnabled(enabled bool) {
+	if srv.SetPortForwardingFunc == nil {
+		return
+	}
+	srv.SetPortForwardingFunc(enabled)
+}
+
+// SetAuthorizedUsers sets the local users a given peer is allowed to log in as
+func (srv *MockServer) SetAuthorizedUsers(peer string, users []string) {
+	if srv.SetAuthorizedUsersFunc == nil {
+		return
+	}
+	srv.SetAuthorizedUsersFunc(peer, users)
+}
+
+// SetUserAuthorizationEnabled restricts the local users of the remote peers to their authorized users when enabled
+func (srv *MockServer) SetUserAuthorizationEnabled(enabled bool) {
+	if srv.SetUserAuthorizationFunc == nil {
+		return
+	}
+	srv.SetUserAuthorizationFunc(enabled)
+}
+
+// SetSessionListener sets the listener notified about the start and the end of the sessions
+func (srv *MockServer) SetSessionListener(listener func(SessionEvent)) {
+	if srv.SetSessionListenerFunc == nil {
+		return
+	}
+	srv.SetSessionListenerFunc(listener)
+}
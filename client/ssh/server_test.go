@@ -1,11 +1,22 @@
 package ssh
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"github.com/stretchr/testify/assert"
-	"golang.org/x/crypto/ssh"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestServer_AddAuthorizedKey(t *testing.T) {
@@ -119,3 +130,209 @@ func TestServer_PubKeyHandler(t *testing.T) {
 	}
 
 }
+
+// startTestServer starts a server on localhost and returns a client connected as the current user
+func startTestServer(t *testing.T) (*DefaultServer, *ssh.Client) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("SSH server is not supported on Windows")
+	}
+
+	hostKey, err := GeneratePrivateKey(ED25519)
+	require.NoError(t, err)
+	server, err := newDefaultServer(hostKey, "127.0.0.1:0")
+	require.NoError(t, err)
+
+	clientKey, err := GeneratePrivateKey(ED25519)
+	require.NoError(t, err)
+	clientPubKey, err := GeneratePublicKey(clientKey)
+	require.NoError(t, err)
+	require.NoError(t, server.AddAuthorizedKey("remotePeer", string(clientPubKey)))
+
+	go func() {
+		_ = server.Start()
+	}()
+	t.Cleanup(func() {
+		_ = server.Stop()
+	})
+
+	currentUser, err := user.Current()
+	require.NoError(t, err)
+
+	client, err := dialTestServer(t, server, clientKey, currentUser.Username)
+	require.NoError(t, err)
+
+	return server, client
+}
+
+// dialTestServer connects to the server as the given local user
+func dialTestServer(t *testing.T, server *DefaultServer, clientKey []byte, localUser string) (*ssh.Client, error) {
+	t.Helper()
+
+	signer, err := ssh.ParsePrivateKey(clientKey)
+	require.NoError(t, err)
+	client, err := ssh.Dial("tcp", server.listener.Addr().String(), &ssh.ClientConfig{
+		User:            localUser,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint:gosec
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return client, nil
+}
+
+func TestServer_Exec(t *testing.T) {
+	_, client := startTestServer(t)
+
+	session, err := client.NewSession()
+	require.NoError(t, err)
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	session.Stdin = strings.NewReader("from stdin")
+
+	err = session.Run("cat; echo; echo out; echo err >&2; exit 3")
+
+	var exitErr *ssh.ExitError
+	require.True(t, errors.As(err, &exitErr), "expecting an exit error, got %v", err)
+	assert.Equal(t, 3, exitErr.ExitStatus())
+	assert.Equal(t, "from stdin\nout\n", stdout.String())
+	assert.Equal(t, "err\n", stderr.String())
+}
+
+func TestServer_SFTP(t *testing.T) {
+	_, client := startTestServer(t)
+
+	sftpClient, err := sftp.NewClient(client)
+	require.NoError(t, err)
+	defer sftpClient.Close()
+
+	path := filepath.Join(t.TempDir(), "file")
+	file, err := sftpClient.Create(path)
+	require.NoError(t, err)
+	_, err = file.Write([]byte("netbird"))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "netbird", string(content))
+}
+
+func TestServer_LocalPortForwarding(t *testing.T) {
+	server, client := startTestServer(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("forwarded"))
+			_ = conn.Close()
+		}
+	}()
+
+	_, err = client.Dial("tcp", listener.Addr().String())
+	require.Error(t, err, "port forwarding should be denied by default")
+
+	server.SetPortForwardingEnabled(true)
+
+	conn, err := client.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	content, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Equal(t, "forwarded", string(content))
+}
+
+func TestServer_ReversePortForwarding(t *testing.T) {
+	server, client := startTestServer(t)
+
+	_, err := client.Listen("tcp", "127.0.0.1:0")
+	require.Error(t, err, "port forwarding should be denied by default")
+
+	server.SetPortForwardingEnabled(true)
+
+	_, err = client.Listen("tcp", "127.0.0.1:80")
+	require.Error(t, err, "privileged ports should be denied")
+
+	_, err = client.Listen("tcp", "0.0.0.0:0")
+	require.Error(t, err, "wildcard addresses should be denied")
+
+	_, err = client.Listen("tcp", "192.0.2.1:0")
+	require.Error(t, err, "addresses other than the loopback and the server addresses should be denied")
+
+	listener, err := client.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte("reversed"))
+		_ = conn.Close()
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	content, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Equal(t, "reversed", string(content))
+}
+
+// addrListener is a listener that only reports its address
+type addrListener struct {
+	net.Listener
+	addr net.Addr
+}
+
+func (l addrListener) Addr() net.Addr {
+	return l.addr
+}
+
+func TestServer_isReverseForwardingHostAllowed(t *testing.T) {
+	server := &DefaultServer{
+		listener: addrListener{addr: &net.TCPAddr{IP: net.ParseIP("100.64.0.1"), Port: DefaultSSHPort}},
+	}
+
+	testCases := []struct {
+		bindHost string
+		expected bool
+	}{
+		{bindHost: "localhost", expected: true},
+		{bindHost: "127.0.0.1", expected: true},
+		{bindHost: "::1", expected: true},
+		{bindHost: "100.64.0.1", expected: true},
+		{bindHost: "", expected: false},
+		{bindHost: "*", expected: false},
+		{bindHost: "0.0.0.0", expected: false},
+		{bindHost: "::", expected: false},
+		{bindHost: "100.64.0.2", expected: false},
+		{bindHost: "192.168.1.1", expected: false},
+		{bindHost: "example.com", expected: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.bindHost, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, server.isReverseForwardingHostAllowed(testCase.bindHost))
+		})
+	}
+}
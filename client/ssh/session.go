@@ -0,0 +1,144 @@
+package ssh
+
+import (
+	"slices"
+	"time"
+
+	"github.com/gliderlabs/ssh"
+	log "github.com/sirupsen/logrus"
+)
+
+// AnyUser is the authorized user that allows a remote peer to log in as any local user
+const AnyUser = "*"
+
+const (
+	// SessionStarted is the type of the event emitted when a remote peer opens its first session or port forwarding
+	// on an authenticated connection
+	SessionStarted SessionEventType = iota
+	// SessionEnded is the type of the event emitted when the connection of a started session is closed
+	SessionEnded
+)
+
+// SessionEventType is the type of a SessionEvent
+type SessionEventType int
+
+// SessionEvent is the start or the end of an SSH session of a remote peer
+type SessionEvent struct {
+	Type SessionEventType
+	// ID identifies the session in the events of its start and its end
+	ID string
+	// PeerKey is the WireGuard public key of the remote peer
+	PeerKey string
+	// LocalUser is the local user the remote peer logged in as
+	LocalUser string
+	// Time of the event
+	Time time.Time
+	// Duration of the session. It is only set for SessionEnded events
+	Duration time.Duration
+}
+
+// SetAuthorizedUsers sets the local users a given peer is allowed to log in as if the user authorization is enabled
+func (srv *DefaultServer) SetAuthorizedUsers(peer string, users []string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if len(users) == 0 {
+		delete(srv.authorizedUsers, peer)
+		return
+	}
+	srv.authorizedUsers[peer] = slices.Clone(users)
+}
+
+// SetUserAuthorizationEnabled restricts the local users of the remote peers to their authorized users when enabled.
+// Otherwise, the remote peers may log in as any local user
+func (srv *DefaultServer) SetUserAuthorizationEnabled(enabled bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.authorizeUsers = enabled
+}
+
+// SetSessionListener sets the listener notified about the start and the end of the sessions
+func (srv *DefaultServer) SetSessionListener(listener func(SessionEvent)) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.sessionListener = listener
+}
+
+// isUserAuthorized returns true if the local user is one of the authorized users of the peer. It has to be called with
+// srv.mu held
+func (srv *DefaultServer) isUserAuthorized(peer, localUser string) bool {
+	users := srv.authorizedUsers[peer]
+	return slices.Contains(users, AnyUser) || slices.Contains(users, localUser)
+}
+
+// authenticatedPeer returns the peer whose key the client authenticated with. It has to be called with srv.mu held
+func (srv *DefaultServer) authenticatedPeer(ctx ssh.Context) string {
+	key, ok := ctx.Value(ssh.ContextKeyPublicKey).(ssh.PublicKey)
+	if !ok {
+		return ""
+	}
+
+	for peer, allowed := range srv.authorizedKeys {
+		if ssh.KeysEqual(allowed, key) {
+			return peer
+		}
+	}
+
+	return ""
+}
+
+// trackSession notifies the session listener about the start of the session of the connection, and about its end once the
+// connection is closed. Connections are tracked once, on their first session or port forwarding
+func (srv *DefaultServer) trackSession(ctx ssh.Context) {
+	sessionID := ctx.SessionID()
+
+	srv.mu.Lock()
+	if _, tracked := srv.trackedSessions[sessionID]; tracked {
+		srv.mu.Unlock()
+		return
+	}
+	srv.trackedSessions[sessionID] = struct{}{}
+	peer := srv.authenticatedPeer(ctx)
+	srv.mu.Unlock()
+
+	started := time.Now()
+	log.Infof("started SSH session %s of peer %s as user %s from %s", sessionID, peer, ctx.User(), ctx.RemoteAddr())
+	srv.notifySession(SessionEvent{
+		Type:      SessionStarted,
+		ID:        sessionID,
+		PeerKey:   peer,
+		LocalUser: ctx.User(),
+		Time:      started,
+	})
+
+	go func() {
+		<-ctx.Done()
+
+		srv.mu.Lock()
+		delete(srv.trackedSessions, sessionID)
+		srv.mu.Unlock()
+
+		ended := time.Now()
+		log.Infof("ended SSH session %s of peer %s as user %s after %s", sessionID, peer, ctx.User(), ended.Sub(started).Round(time.Second))
+		srv.notifySession(SessionEvent{
+			Type:      SessionEnded,
+			ID:        sessionID,
+			PeerKey:   peer,
+			LocalUser: ctx.User(),
+			Time:      ended,
+			Duration:  ended.Sub(started),
+		})
+	}()
+}
+
+func (srv *DefaultServer) notifySession(event SessionEvent) {
+	srv.mu.Lock()
+	listener := srv.sessionListener
+	srv.mu.Unlock()
+
+	if listener != nil {
+		listener(event)
+	}
+}
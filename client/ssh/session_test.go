@@ -0,0 +1,89 @@
+package ssh
+
+import (
+	"os/user"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_UserAuthorization(t *testing.T) {
+	server, _ := startTestServer(t)
+
+	clientKey, err := GeneratePrivateKey(ED25519)
+	require.NoError(t, err)
+	clientPubKey, err := GeneratePublicKey(clientKey)
+	require.NoError(t, err)
+	require.NoError(t, server.AddAuthorizedKey("otherPeer", string(clientPubKey)))
+
+	currentUser, err := user.Current()
+	require.NoError(t, err)
+
+	server.SetUserAuthorizationEnabled(true)
+
+	_, err = dialTestServer(t, server, clientKey, currentUser.Username)
+	assert.Error(t, err, "peer without authorized users shouldn't be allowed to log in")
+
+	server.SetAuthorizedUsers("otherPeer", []string{currentUser.Username + "-other"})
+	_, err = dialTestServer(t, server, clientKey, currentUser.Username)
+	assert.Error(t, err, "peer shouldn't be allowed to log in as a user that isn't authorized")
+
+	server.SetAuthorizedUsers("otherPeer", []string{currentUser.Username})
+	_, err = dialTestServer(t, server, clientKey, currentUser.Username)
+	assert.NoError(t, err, "peer should be allowed to log in as an authorized user")
+
+	server.SetAuthorizedUsers("otherPeer", []string{AnyUser})
+	_, err = dialTestServer(t, server, clientKey, currentUser.Username)
+	assert.NoError(t, err, "peer should be allowed to log in as any user")
+
+	server.SetUserAuthorizationEnabled(false)
+	server.SetAuthorizedUsers("otherPeer", nil)
+	_, err = dialTestServer(t, server, clientKey, currentUser.Username)
+	assert.NoError(t, err, "peer should be allowed to log in as any user when the authorization is disabled")
+}
+
+func TestServer_SessionListener(t *testing.T) {
+	server, client := startTestServer(t)
+
+	events := make(chan SessionEvent, 10)
+	server.SetSessionListener(func(event SessionEvent) {
+		events <- event
+	})
+
+	currentUser, err := user.Current()
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		session, err := client.NewSession()
+		require.NoError(t, err)
+		require.NoError(t, session.Run("true"))
+	}
+
+	started := waitSessionEvent(t, events)
+	assert.Equal(t, SessionStarted, started.Type)
+	assert.Equal(t, "remotePeer", started.PeerKey)
+	assert.Equal(t, currentUser.Username, started.LocalUser)
+	assert.NotEmpty(t, started.ID)
+
+	require.NoError(t, client.Close())
+
+	ended := waitSessionEvent(t, events)
+	assert.Equal(t, SessionEnded, ended.Type, "sessions of a connection should be reported once")
+	assert.Equal(t, started.ID, ended.ID)
+	assert.Equal(t, "remotePeer", ended.PeerKey)
+	assert.Positive(t, ended.Duration)
+}
+
+func waitSessionEvent(t *testing.T, events chan SessionEvent) SessionEvent {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a session event")
+		return SessionEvent{}
+	}
+}
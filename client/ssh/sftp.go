@@ -0,0 +1,107 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/pkg/sftp"
+	log "github.com/sirupsen/logrus"
+)
+
+// SFTPServerCommand is the hidden command of the NetBird binary that serves SFTP over its standard input and output.
+// The SSH server runs it as the session's local user when it has to switch users to serve files
+const SFTPServerCommand = "sftp-server"
+
+// sftpHandler serves the SFTP subsystem with the permissions of the session's local user
+func (srv *DefaultServer) sftpHandler(session ssh.Session) {
+	localUser, err := userNameLookup(session.User())
+	if err != nil {
+		log.Warnf("failed SFTP session from %v, user %s: %v", session.RemoteAddr(), session.User(), err)
+		_ = session.Exit(1)
+		return
+	}
+
+	log.Infof("establishing SFTP session for %s from host %s", localUser.Username, session.RemoteAddr().String())
+	srv.trackSession(session.Context())
+
+	sysProcAttr, err := userSysProcAttr(localUser)
+	if err != nil {
+		log.Warnf("failed SFTP session from %v, user %s: %v", session.RemoteAddr(), session.User(), err)
+		_ = session.Exit(1)
+		return
+	}
+
+	if sysProcAttr == nil {
+		_ = session.Exit(serveSFTP(session, localUser.HomeDir))
+		return
+	}
+
+	_ = session.Exit(runSFTPServerProcess(session, localUser))
+}
+
+// runSFTPServerProcess serves SFTP from a child process running as the local user
+func runSFTPServerProcess(session ssh.Session, localUser *user.User) int {
+	executable, err := os.Executable()
+	if err != nil {
+		log.Errorf("failed to get the executable path for the SFTP server: %v", err)
+		return 1
+	}
+
+	cmd, err := newUserCommand(session, localUser, executable, SFTPServerCommand)
+	if err != nil {
+		log.Warnf("failed preparing SFTP server for user %s: %v", localUser.Username, err)
+		return 1
+	}
+
+	return runSessionCommand(session, cmd)
+}
+
+// ServeSFTP serves SFTP over the standard input and output of the process, starting in the working directory
+func ServeSFTP() error {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get working directory: %w", err)
+	}
+
+	if code := serveSFTP(stdioConn{}, workDir); code != 0 {
+		return fmt.Errorf("SFTP server exited with code %d", code)
+	}
+	return nil
+}
+
+func serveSFTP(rwc io.ReadWriteCloser, workDir string) int {
+	server, err := sftp.NewServer(rwc, sftp.WithServerWorkingDirectory(workDir))
+	if err != nil {
+		log.Errorf("failed creating SFTP server: %v", err)
+		return 1
+	}
+	defer func() {
+		_ = server.Close()
+	}()
+
+	if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) {
+		log.Warnf("SFTP server stopped with error: %v", err)
+		return 1
+	}
+
+	return 0
+}
+
+// stdioConn is the connection of the SFTP server process over its standard input and output
+type stdioConn struct{}
+
+func (stdioConn) Read(p []byte) (int, error) {
+	return os.Stdin.Read(p)
+}
+
+func (stdioConn) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+func (stdioConn) Close() error {
+	return errors.Join(os.Stdin.Close(), os.Stdout.Close())
+}
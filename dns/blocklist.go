@@ -0,0 +1,115 @@
+package dns
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// BlockResponseNXDomain answers blocked queries with an NXDOMAIN error
+	BlockResponseNXDomain BlockResponse = "nxdomain"
+	// BlockResponseZero answers blocked queries with an unspecified address, 0.0.0.0 or ::
+	BlockResponseZero BlockResponse = "zero"
+
+	// WildcardPrefix is the prefix of a block list entry matching all subdomains of a domain
+	WildcardPrefix = "*."
+
+	// MaxBlockListDomainsSize is the maximum size in bytes of the domains of a block list sent to a peer,
+	// counted with the encoding overhead of each domain
+	MaxBlockListDomainsSize = 16 << 20
+	// blockListDomainOverhead is the maximum encoding overhead of a domain in a block list message
+	blockListDomainOverhead = 3
+)
+
+// BlockResponse defines the answer returned for a blocked domain
+type BlockResponse string
+
+// IsValid returns true if the block response is one of the supported responses
+func (r BlockResponse) IsValid() bool {
+	return r == BlockResponseNXDomain || r == BlockResponseZero
+}
+
+// BlockList is a list of domains that the peers of its groups refuse to resolve
+type BlockList struct {
+	// ID identifier of the block list
+	ID string `gorm:"primaryKey"`
+	// AccountID is a reference to Account that this object belongs
+	AccountID string `gorm:"index"`
+	// Name block list name
+	Name string
+	// Description block list description
+	Description string
+	// Domains inline list of blocked domains. A leading "*." matches all subdomains of the domain.
+	// They aren't hashed with the DNS config of the peers, DomainsHash changes with them
+	Domains []string `gorm:"serializer:json" hash:"ignore"`
+	// URLs of hosted block lists fetched by the management service
+	URLs []string `gorm:"serializer:json"`
+	// AllowDomains domains that are never blocked, even if they match a blocked domain
+	AllowDomains []string `gorm:"serializer:json"`
+	// Response answer returned for blocked domains
+	Response BlockResponse
+	// Groups list of peer group IDs to distribute the block list
+	Groups []string `gorm:"serializer:json"`
+	// Enabled block list status
+	Enabled bool
+	// FetchedDomainsCount number of domains fetched from the URLs. The fetched domains are stored apart from the block list
+	FetchedDomainsCount int
+	// DomainsHash hash of the inline and fetched domains, the peers fetch the domains again when it changes
+	DomainsHash string
+	// LastFetched last time the URLs were successfully fetched
+	LastFetched time.Time
+	// InactivePeers IDs of the peers of the groups which resolve the domains outside of their custom zones and
+	// nameserver groups through the host resolver, the block list doesn't apply to these queries. It is computed when
+	// the block list is read
+	InactivePeers []string `gorm:"-" json:"-" hash:"ignore"`
+}
+
+// EventMeta returns activity event meta related to the block list
+func (b *BlockList) EventMeta() map[string]any {
+	return map[string]any{"name": b.Name}
+}
+
+// Copy copies a block list object
+func (b *BlockList) Copy() *BlockList {
+	blockList := &BlockList{
+		ID:                  b.ID,
+		AccountID:           b.AccountID,
+		Name:                b.Name,
+		Description:         b.Description,
+		Domains:             make([]string, len(b.Domains)),
+		URLs:                make([]string, len(b.URLs)),
+		AllowDomains:        make([]string, len(b.AllowDomains)),
+		Response:            b.Response,
+		Groups:              make([]string, len(b.Groups)),
+		Enabled:             b.Enabled,
+		FetchedDomainsCount: b.FetchedDomainsCount,
+		DomainsHash:         b.DomainsHash,
+		LastFetched:         b.LastFetched,
+	}
+
+	if b.InactivePeers != nil {
+		blockList.InactivePeers = make([]string, len(b.InactivePeers))
+		copy(blockList.InactivePeers, b.InactivePeers)
+	}
+
+	copy(blockList.Domains, b.Domains)
+	copy(blockList.URLs, b.URLs)
+	copy(blockList.AllowDomains, b.AllowDomains)
+	copy(blockList.Groups, b.Groups)
+
+	return blockList
+}
+
+// BlockListDomainsSize returns the size of the domains counted against MaxBlockListDomainsSize
+func BlockListDomainsSize(domains []string) int {
+	size := 0
+	for _, domain := range domains {
+		size += len(domain) + blockListDomainOverhead
+	}
+	return size
+}
+
+// NormalizeBlockListDomain lowercases a block list entry and removes its trailing dot
+func NormalizeBlockListDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
@@ -31,6 +31,8 @@ type Config struct {
 	NameServerGroups []*NameServerGroup
 	// CustomZones contains a list of custom zone
 	CustomZones []CustomZone
+	// BlockLists contains a list of block lists enforced by the peer
+	BlockLists []*BlockList
 }
 
 // CustomZone represents a custom zone to be resolved by the dns server
@@ -0,0 +1,109 @@
+package bind
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/stun/v2"
+)
+
+const (
+	// maxPendingChecks limits the connectivity checks waiting for a response
+	maxPendingChecks = 1024
+	// pendingCheckTimeout is the time after which a connectivity check is considered lost
+	pendingCheckTimeout = 10 * time.Second
+	// rttExpiry is the time after which a measurement is outdated, the agent checks the selected pair every few
+	// seconds
+	rttExpiry = time.Minute
+)
+
+type pendingCheck struct {
+	addr string
+	sent time.Time
+}
+
+type measurement struct {
+	rtt      time.Duration
+	measured time.Time
+}
+
+// rttTracker measures the round trip time of the ICE connectivity checks sent through the mux, the agent keeps
+// sending them on the selected candidate pair while it is connected
+type rttTracker struct {
+	mu      sync.Mutex
+	pending map[[stun.TransactionIDSize]byte]pendingCheck
+	rtts    map[string]measurement
+}
+
+func newRTTTracker() *rttTracker {
+	return &rttTracker{
+		pending: make(map[[stun.TransactionIDSize]byte]pendingCheck),
+		rtts:    make(map[string]measurement),
+	}
+}
+
+// onSend records the binding requests sent to the address
+func (t *rttTracker) onSend(buf []byte, addr net.Addr) {
+	if !stun.IsMessage(buf) {
+		return
+	}
+	msg := &stun.Message{Raw: buf}
+	if err := msg.Decode(); err != nil || msg.Type != stun.BindingRequest {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if len(t.pending) >= maxPendingChecks {
+		for id, check := range t.pending {
+			if now.Sub(check.sent) > pendingCheckTimeout {
+				delete(t.pending, id)
+			}
+		}
+		if len(t.pending) >= maxPendingChecks {
+			return
+		}
+	}
+	t.pending[msg.TransactionID] = pendingCheck{addr: addr.String(), sent: now}
+}
+
+// onReceive measures the round trip time of the request answered by the success response
+func (t *rttTracker) onReceive(msg *stun.Message, addr net.Addr) {
+	if msg.Type != stun.BindingSuccess {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	check, ok := t.pending[msg.TransactionID]
+	if !ok || check.addr != addr.String() {
+		return
+	}
+	delete(t.pending, msg.TransactionID)
+
+	now := time.Now()
+	t.rtts[check.addr] = measurement{rtt: now.Sub(check.sent), measured: now}
+	if len(t.rtts) > maxPendingChecks {
+		for addr, m := range t.rtts {
+			if now.Sub(m.measured) > rttExpiry {
+				delete(t.rtts, addr)
+			}
+		}
+	}
+}
+
+// get returns the last round trip time measured to the address, if it isn't outdated
+func (t *rttTracker) get(addr string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m, ok := t.rtts[addr]
+	if !ok || time.Since(m.measured) > rttExpiry {
+		return 0, false
+	}
+	return m.rtt, true
+}
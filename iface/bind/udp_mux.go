@@ -6,6 +6,7 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pion/ice/v3"
 	"github.com/pion/logging"
@@ -41,6 +42,8 @@ type UDPMuxDefault struct {
 
 	// for UDP connection listen at unspecified address
 	localAddrsForUnspecified []net.Addr
+
+	rtt *rttTracker
 }
 
 const maxAddrSize = 512
@@ -203,6 +206,7 @@ func NewUDPMuxDefault(params UDPMuxParams) *UDPMuxDefault {
 			},
 		},
 		localAddrsForUnspecified: localAddrsForUnspecified,
+		rtt:                      newRTTTracker(),
 	}
 }
 
@@ -325,9 +329,16 @@ func (m *UDPMuxDefault) Close() error {
 }
 
 func (m *UDPMuxDefault) writeTo(buf []byte, rAddr net.Addr) (n int, err error) {
+	m.rtt.onSend(buf, rAddr)
 	return m.params.UDPConn.WriteTo(buf, rAddr)
 }
 
+// RoundTripTime returns the round trip time of the last ICE connectivity check answered by the remote address.
+// The checks of the candidates gathered through a TURN server don't go through the mux
+func (m *UDPMuxDefault) RoundTripTime(remoteAddr string) (time.Duration, bool) {
+	return m.rtt.get(remoteAddr)
+}
+
 func (m *UDPMuxDefault) registerConnForAddress(conn *udpMuxedConn, addr string) {
 	if m.IsClosed() {
 		return
@@ -365,6 +376,8 @@ func (m *UDPMuxDefault) HandleSTUNMessage(msg *stun.Message, addr net.Addr) erro
 		return fmt.Errorf("underlying PacketConn did not return a UDPAddr")
 	}
 
+	m.rtt.onReceive(msg, addr)
+
 	// If we have already seen this address dispatch to the appropriate destination
 	// If you are using the same socket for the Host and SRFLX candidates, it might be that there are more than one
 	// muxed connection - one for the SRFLX candidate and the other one for the HOST one.
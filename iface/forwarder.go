@@ -0,0 +1,16 @@
+//go:build !android
+// +build !android
+
+package iface
+
+// GetForwarder returns the forwarder of the routed traffic if the device forwards it in userspace, nil otherwise
+func (w *WGIface) GetForwarder() Forwarder {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	fwd, ok := w.tun.(interface{ Forwarder() Forwarder })
+	if !ok {
+		return nil
+	}
+	return fwd.Forwarder()
+}
@@ -10,6 +10,7 @@ import (
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 
 	"github.com/netbirdio/netbird/iface/bind"
+	"github.com/netbirdio/netbird/iface/netns"
 )
 
 const (
@@ -17,10 +18,17 @@ const (
 	DefaultWgPort = 51820
 )
 
+// Forwarder forwards the traffic routed through an interface whose device doesn't route it in the kernel
+type Forwarder interface {
+	Enable() error
+	Disable() error
+}
+
 // WGIface represents a interface instance
 type WGIface struct {
 	tun           wgTunDevice
 	userspaceBind bool
+	netNS         *netns.NetNS
 	mu            sync.Mutex
 
 	configurer wgConfigurer
@@ -32,6 +40,11 @@ func (w *WGIface) IsUserspaceBind() bool {
 	return w.userspaceBind
 }
 
+// NetNS returns the network namespace the interface is placed in, nil if it is in the namespace of the process
+func (w *WGIface) NetNS() *netns.NetNS {
+	return w.netNS
+}
+
 // Name returns the interface name
 func (w *WGIface) Name() string {
 	return w.tun.DeviceName()
@@ -101,6 +114,14 @@ func (w *WGIface) RemoveAllowedIP(peerKey string, allowedIP string) error {
 	return w.configurer.removeAllowedIP(peerKey, allowedIP)
 }
 
+// GetStats returns the WireGuard statistics of the peers keyed by their public key
+func (w *WGIface) GetStats() (map[string]WGStats, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.configurer.getStats()
+}
+
 // Close closes the tunnel interface
 func (w *WGIface) Close() error {
 	w.mu.Lock()
@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/pion/transport/v3"
+
+	"github.com/netbirdio/netbird/iface/netstack"
 )
 
 // NewWGIFace Creates a new WireGuard interface instance
@@ -38,3 +40,18 @@ func (w *WGIface) CreateOnAndroid(routes []string, dns string, searchDomains []s
 func (w *WGIface) Create() error {
 	return fmt.Errorf("this function has not implemented on this platform")
 }
+
+// GetForwarder returns nil, the routed traffic isn't forwarded in userspace on Android
+func (w *WGIface) GetForwarder() Forwarder {
+	return nil
+}
+
+// NewWGIFaceNetstack is not supported on Android, the interface is always created by the VPN service
+func NewWGIFaceNetstack(string, string, int, string, int, transport.Net, netstack.Options) (*WGIface, error) {
+	return nil, fmt.Errorf("netstack interfaces are not supported on this platform")
+}
+
+// GetNet returns nil, the interface never runs in a userspace network stack on Android
+func (w *WGIface) GetNet() *netstack.Net {
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/pion/transport/v3"
 
+	"github.com/netbirdio/netbird/iface/netns"
 	"github.com/netbirdio/netbird/iface/netstack"
 )
 
@@ -22,13 +23,16 @@ func NewWGIFace(iFaceName string, address string, wgPort int, wgPrivKey string,
 
 	// move the kernel/usp/netstack preference evaluation to upper layer
 	if netstack.IsEnabled() {
-		wgIFace.tun = newTunNetstackDevice(iFaceName, wgAddress, wgPort, wgPrivKey, mtu, transportNet, netstack.ListenAddr())
+		wgIFace.tun = newTunNetstackDevice(iFaceName, wgAddress, wgPort, wgPrivKey, mtu, transportNet, netstack.GetOptions())
 		wgIFace.userspaceBind = true
 		return wgIFace, nil
 	}
 
+	// the interface is moved into the namespace, its routes and firewall rules are set in the namespace as well
+	wgIFace.netNS = netns.Default()
+
 	if WireGuardModuleIsLoaded() {
-		wgIFace.tun = newTunDevice(iFaceName, wgAddress, wgPort, wgPrivKey, mtu, transportNet)
+		wgIFace.tun = newTunDevice(iFaceName, wgAddress, wgPort, wgPrivKey, mtu, transportNet, wgIFace.netNS)
 		wgIFace.userspaceBind = false
 		return wgIFace, nil
 	}
@@ -36,7 +40,7 @@ func NewWGIFace(iFaceName string, address string, wgPort int, wgPrivKey string,
 	if !tunModuleIsLoaded() {
 		return nil, fmt.Errorf("couldn't check or load tun module")
 	}
-	wgIFace.tun = newTunUSPDevice(iFaceName, wgAddress, wgPort, wgPrivKey, mtu, transportNet)
+	wgIFace.tun = newTunUSPDevice(iFaceName, wgAddress, wgPort, wgPrivKey, mtu, transportNet, wgIFace.netNS)
 	wgIFace.userspaceBind = true
 	return wgIFace, nil
 
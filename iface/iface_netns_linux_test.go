@@ -0,0 +1,74 @@
+//go:build linux && !android
+
+package iface
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/v3/stdnet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	vnetns "github.com/vishvananda/netns"
+
+	"github.com/netbirdio/netbird/iface/netns"
+)
+
+func TestWGIface_NetNS(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating a network namespace requires root")
+	}
+
+	nsName := fmt.Sprintf("nbtest%d", time.Now().UnixNano()%100000)
+	ns, err := netns.Open(nsName)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = ns.Close()
+		_ = vnetns.DeleteNamed(nsName)
+	})
+
+	netns.SetDefault(ns)
+	defer netns.SetDefault(nil)
+
+	ifaceName := fmt.Sprintf("utun%d", WgIntNumber+5)
+	addr := "100.64.0.1/8"
+	newNet, err := stdnet.NewNet()
+	require.NoError(t, err)
+
+	iface, err := NewWGIFace(ifaceName, addr, 33105, key, DefaultMTU, newNet, nil)
+	require.NoError(t, err)
+	require.NoError(t, iface.Create())
+	defer func() {
+		assert.NoError(t, iface.Close())
+	}()
+	_, err = iface.Up()
+	require.NoError(t, err)
+
+	_, err = net.InterfaceByName(ifaceName)
+	assert.Error(t, err, "the interface shouldn't be visible in the namespace of the process")
+
+	var addrs []net.Addr
+	err = ns.Do(func() error {
+		var err error
+		addrs, err = getIfaceAddrs(ifaceName)
+		return err
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, addrs)
+	assert.Equal(t, addr, addrs[0].String())
+
+	// the address is updated in the namespace as well
+	addr = "100.64.0.2/8"
+	require.NoError(t, iface.UpdateAddr(addr))
+	err = ns.Do(func() error {
+		var err error
+		addrs, err = getIfaceAddrs(ifaceName)
+		return err
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, addrs)
+	assert.Equal(t, addr, addrs[0].String())
+}
@@ -0,0 +1,37 @@
+//go:build !android
+// +build !android
+
+package iface
+
+import (
+	"github.com/pion/transport/v3"
+
+	"github.com/netbirdio/netbird/iface/netstack"
+)
+
+// NewWGIFaceNetstack creates a new WireGuard interface running in a userspace network stack, regardless of the
+// netstack mode of the process. It is used to embed the client into other applications
+func NewWGIFaceNetstack(iFaceName string, address string, wgPort int, wgPrivKey string, mtu int, transportNet transport.Net, opts netstack.Options) (*WGIface, error) {
+	wgAddress, err := parseWGAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WGIface{
+		tun:           newTunNetstackDevice(iFaceName, wgAddress, wgPort, wgPrivKey, mtu, transportNet, opts),
+		userspaceBind: true,
+	}, nil
+}
+
+// GetNet returns the network to dial and listen on the interface address if the interface runs in a userspace
+// network stack, nil otherwise
+func (w *WGIface) GetNet() *netstack.Net {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n, ok := w.tun.(interface{ Net() *netstack.Net })
+	if !ok {
+		return nil
+	}
+	return n.Net()
+}
@@ -0,0 +1,26 @@
+// Package netns places the WireGuard interface in a Linux network namespace. Only the interface, its routes and
+// its firewall rules live in the namespace, the sockets of the control plane (management, signal, ICE and the
+// WireGuard socket itself) stay in the namespace of the process.
+package netns
+
+import "sync"
+
+var (
+	defaultMu sync.Mutex
+	defaultNS *NetNS
+)
+
+// SetDefault sets the namespace the interfaces are created in, nil creates them in the namespace of the process
+func SetDefault(ns *NetNS) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultNS = ns
+}
+
+// Default returns the namespace the interfaces are created in, nil if they are created in the namespace of the
+// process
+func Default() *NetNS {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	return defaultNS
+}
@@ -0,0 +1,134 @@
+//go:build linux && !android
+
+package netns
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+const namedNetNSPath = "/var/run/netns"
+
+// NetNS is an open network namespace
+type NetNS struct {
+	name   string
+	handle netns.NsHandle
+}
+
+// Open opens the namespace at the path, e.g. /proc/<pid>/ns/net of a container, or the named namespace managed by
+// "ip netns". A named namespace is created if it doesn't exist yet, it isn't deleted when it is closed
+func Open(nameOrPath string) (*NetNS, error) {
+	if strings.ContainsRune(nameOrPath, filepath.Separator) {
+		handle, err := netns.GetFromPath(nameOrPath)
+		if err != nil {
+			return nil, fmt.Errorf("open network namespace %s: %w", nameOrPath, err)
+		}
+		return &NetNS{name: nameOrPath, handle: handle}, nil
+	}
+
+	handle, err := netns.GetFromName(nameOrPath)
+	if err == nil {
+		return &NetNS{name: nameOrPath, handle: handle}, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("open network namespace %s: %w", nameOrPath, err)
+	}
+	return create(nameOrPath)
+}
+
+// create adds a named namespace like "ip netns add" and brings its loopback interface up
+func create(name string) (*NetNS, error) {
+	if err := os.MkdirAll(namedNetNSPath, 0755); err != nil {
+		return nil, fmt.Errorf("create network namespace directory: %w", err)
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origin, err := netns.Get()
+	if err != nil {
+		return nil, fmt.Errorf("get current network namespace: %w", err)
+	}
+	defer origin.Close()
+
+	// NewNamed moves the thread into the new namespace
+	handle, err := netns.NewNamed(name)
+	if restoreErr := netns.Set(origin); restoreErr != nil {
+		// the thread is discarded, it can't be used by other goroutines anymore
+		runtime.LockOSThread()
+		return nil, fmt.Errorf("restore network namespace: %w", restoreErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create network namespace %s: %w", name, err)
+	}
+	log.Infof("created network namespace %s", name)
+
+	ns := &NetNS{name: name, handle: handle}
+	if err := ns.Do(setLoopbackUp); err != nil {
+		_ = ns.Close()
+		return nil, fmt.Errorf("set up loopback interface in network namespace %s: %w", name, err)
+	}
+	return ns, nil
+}
+
+func setLoopbackUp() error {
+	lo, err := netlink.LinkByName("lo")
+	if err != nil {
+		return err
+	}
+	return netlink.LinkSetUp(lo)
+}
+
+// String returns the name or the path the namespace was opened with
+func (n *NetNS) String() string {
+	return n.name
+}
+
+// Fd returns the file descriptor of the namespace, e.g. to move a link into it
+func (n *NetNS) Fd() int {
+	return int(n.handle)
+}
+
+// Do runs fn in the namespace. The goroutines started by fn run in the namespace of the process, the sockets opened
+// by fn stay in the namespace. A nil namespace runs fn in the namespace of the process
+func (n *NetNS) Do(fn func() error) error {
+	if n == nil {
+		return fn()
+	}
+
+	runtime.LockOSThread()
+
+	origin, err := netns.Get()
+	if err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("get current network namespace: %w", err)
+	}
+	defer origin.Close()
+
+	if err := netns.Set(n.handle); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("enter network namespace %s: %w", n.name, err)
+	}
+
+	fnErr := fn()
+
+	if err := netns.Set(origin); err != nil {
+		// the thread stays locked so that it is discarded when the goroutine exits
+		return errors.Join(fnErr, fmt.Errorf("restore network namespace: %w", err))
+	}
+	runtime.UnlockOSThread()
+	return fnErr
+}
+
+// Close releases the namespace, the namespace itself is kept
+func (n *NetNS) Close() error {
+	return n.handle.Close()
+}
@@ -0,0 +1,106 @@
+//go:build linux && !android
+
+package netns
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+func TestNilNetNSDo(t *testing.T) {
+	var ns *NetNS
+	called := false
+	err := ns.Do(func() error {
+		called = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, called, "the function should run in the namespace of the process")
+}
+
+func TestOpenNamed(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating a network namespace requires root")
+	}
+
+	name := fmt.Sprintf("nbtest%d", time.Now().UnixNano()%100000)
+	ns, err := Open(name)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = ns.Close()
+		_ = netns.DeleteNamed(name)
+	})
+
+	hostIfaces, err := net.Interfaces()
+	require.NoError(t, err)
+
+	var nsIfaces []net.Interface
+	err = ns.Do(func() error {
+		var err error
+		nsIfaces, err = net.Interfaces()
+		return err
+	})
+	require.NoError(t, err)
+	require.Len(t, nsIfaces, 1, "a new namespace only has a loopback interface")
+	assert.Equal(t, "lo", nsIfaces[0].Name)
+	assert.NotZero(t, nsIfaces[0].Flags&net.FlagUp, "the loopback interface should be up")
+
+	ifaces, err := net.Interfaces()
+	require.NoError(t, err)
+	assert.Len(t, ifaces, len(hostIfaces), "the namespace of the process should be restored")
+
+	reopened, err := Open(name)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.True(t, netns.NsHandle(reopened.Fd()).Equal(netns.NsHandle(ns.Fd())), "the existing namespace should be opened")
+
+	byPath, err := Open(filepath.Join(namedNetNSPath, name))
+	require.NoError(t, err)
+	defer byPath.Close()
+	assert.True(t, netns.NsHandle(byPath.Fd()).Equal(netns.NsHandle(ns.Fd())), "the namespace should be opened by path")
+}
+
+func TestOpenMissingPath(t *testing.T) {
+	_, err := Open("/proc/0/ns/net")
+	assert.Error(t, err)
+}
+
+// TestDoLink checks that the links created in the namespace aren't visible to the namespace of the process
+func TestDoLink(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating a network namespace requires root")
+	}
+
+	name := fmt.Sprintf("nbtest%d", time.Now().UnixNano()%100000)
+	ns, err := Open(name)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = ns.Close()
+		_ = netns.DeleteNamed(name)
+	})
+
+	attrs := netlink.NewLinkAttrs()
+	attrs.Name = "nbtestveth0"
+	err = ns.Do(func() error {
+		return netlink.LinkAdd(&netlink.Veth{LinkAttrs: attrs, PeerName: "nbtestveth1"})
+	})
+	require.NoError(t, err)
+
+	_, err = netlink.LinkByName(attrs.Name)
+	assert.Error(t, err, "the link should only exist in the namespace")
+
+	err = ns.Do(func() error {
+		_, err := netlink.LinkByName(attrs.Name)
+		return err
+	})
+	assert.NoError(t, err)
+}
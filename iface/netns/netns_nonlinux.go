@@ -0,0 +1,35 @@
+//go:build !linux || android
+
+package netns
+
+import (
+	"errors"
+)
+
+// NetNS is an open network namespace
+type NetNS struct{}
+
+// Open fails, network namespaces are only supported on Linux
+func Open(string) (*NetNS, error) {
+	return nil, errors.New("network namespaces are only supported on Linux")
+}
+
+// String returns the name or the path the namespace was opened with
+func (n *NetNS) String() string {
+	return ""
+}
+
+// Fd returns the file descriptor of the namespace
+func (n *NetNS) Fd() int {
+	return -1
+}
+
+// Do runs fn in the namespace of the process
+func (n *NetNS) Do(fn func() error) error {
+	return fn()
+}
+
+// Close releases the namespace
+func (n *NetNS) Close() error {
+	return nil
+}
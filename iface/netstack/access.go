@@ -0,0 +1,164 @@
+package netstack
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/things-go/go-socks5"
+	"github.com/things-go/go-socks5/statute"
+)
+
+// Destination is a network the proxies may connect to, optionally restricted to a port range
+type Destination struct {
+	Prefix netip.Prefix
+	// StartPort and EndPort limit the allowed ports, any port is allowed when they are zero
+	StartPort uint16
+	EndPort   uint16
+}
+
+// ParseDestination parses a destination in the format prefix[:port[-port]], e.g. 100.64.0.0/10, 10.0.0.0/8:443
+// or fd00::/8:8000-8100. A single address is allowed instead of the prefix, IPv6 addresses with ports are enclosed
+// in brackets
+func ParseDestination(s string) (Destination, error) {
+	addr, ports := splitDestination(s)
+
+	var d Destination
+	if strings.Contains(addr, "/") {
+		prefix, err := netip.ParsePrefix(addr)
+		if err != nil {
+			return d, fmt.Errorf("invalid destination %s: %w", s, err)
+		}
+		d.Prefix = prefix.Masked()
+	} else {
+		ip, err := netip.ParseAddr(addr)
+		if err != nil {
+			return d, fmt.Errorf("invalid destination %s: %w", s, err)
+		}
+		d.Prefix = netip.PrefixFrom(ip, ip.BitLen())
+	}
+
+	if ports == "" {
+		return d, nil
+	}
+	start, end, isRange := strings.Cut(ports, "-")
+	startPort, err := parsePort(start)
+	if err != nil {
+		return d, fmt.Errorf("invalid destination %s: %w", s, err)
+	}
+	endPort := startPort
+	if isRange {
+		if endPort, err = parsePort(end); err != nil {
+			return d, fmt.Errorf("invalid destination %s: %w", s, err)
+		}
+	}
+	if startPort > endPort {
+		return d, fmt.Errorf("invalid destination %s: port range start is greater than its end", s)
+	}
+	d.StartPort, d.EndPort = startPort, endPort
+	return d, nil
+}
+
+// splitDestination separates the address or prefix of a destination from its ports
+func splitDestination(s string) (string, string) {
+	if i := strings.Index(s, "/"); i >= 0 {
+		bits, ports, _ := strings.Cut(s[i+1:], ":")
+		return s[:i+1] + bits, ports
+	}
+	if strings.HasPrefix(s, "[") {
+		addr, ports, _ := strings.Cut(strings.TrimPrefix(s, "["), "]")
+		return addr, strings.TrimPrefix(ports, ":")
+	}
+	if strings.Count(s, ":") == 1 {
+		addr, ports, _ := strings.Cut(s, ":")
+		return addr, ports
+	}
+	return s, ""
+}
+
+func parsePort(s string) (uint16, error) {
+	port, err := strconv.ParseUint(s, 10, 16)
+	if err != nil || port == 0 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return uint16(port), nil
+}
+
+// Match returns whether the address and port belong to the destination
+func (d Destination) Match(addr netip.Addr, port uint16) bool {
+	if !d.Prefix.Contains(addr.Unmap()) {
+		return false
+	}
+	return d.StartPort == 0 || port >= d.StartPort && port <= d.EndPort
+}
+
+func (d Destination) String() string {
+	switch {
+	case d.StartPort == 0:
+		return d.Prefix.String()
+	case d.StartPort == d.EndPort:
+		return fmt.Sprintf("%s:%d", d.Prefix, d.StartPort)
+	default:
+		return fmt.Sprintf("%s:%d-%d", d.Prefix, d.StartPort, d.EndPort)
+	}
+}
+
+// accessControl authenticates the proxy clients and restricts the destinations they connect to
+type accessControl struct {
+	username     string
+	password     string
+	destinations []Destination
+}
+
+func newAccessControl(config ProxyConfig) *accessControl {
+	return &accessControl{
+		username:     config.Username,
+		password:     config.Password,
+		destinations: config.AllowedDestinations,
+	}
+}
+
+func (a *accessControl) authRequired() bool {
+	return a.username != ""
+}
+
+// Valid checks the credentials of a client, it implements the socks5.CredentialStore
+func (a *accessControl) Valid(username, password, _ string) bool {
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(a.username))
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(a.password))
+	return userOK&passOK == 1
+}
+
+// allowed returns whether the clients may connect to the address and port
+func (a *accessControl) allowed(addr netip.Addr, port uint16) bool {
+	if len(a.destinations) == 0 {
+		return true
+	}
+	for _, d := range a.destinations {
+		if d.Match(addr, port) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allow permits the connect requests to the allowed destinations, it implements the socks5.RuleSet.
+// The bind and associate commands would open sockets on the host instead of the netstack and are refused
+func (a *accessControl) Allow(ctx context.Context, req *socks5.Request) (context.Context, bool) {
+	if req.Command != statute.CommandConnect {
+		return ctx, false
+	}
+	return ctx, a.allowedAddr(req.DestAddr.IP, req.DestAddr.Port)
+}
+
+func (a *accessControl) allowedAddr(ip net.IP, port int) bool {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok || port < 1 || port > 65535 {
+		return false
+	}
+	return a.allowed(addr.Unmap(), uint16(port))
+}
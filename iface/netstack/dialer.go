@@ -5,7 +5,6 @@ import (
 	"net"
 
 	log "github.com/sirupsen/logrus"
-	"golang.zx2c4.com/wireguard/tun/netstack"
 )
 
 type Dialer interface {
@@ -13,10 +12,10 @@ type Dialer interface {
 }
 
 type NSDialer struct {
-	net *netstack.Net
+	net *Net
 }
 
-func NewNSDialer(net *netstack.Net) *NSDialer {
+func NewNSDialer(net *Net) *NSDialer {
 	return &NSDialer{
 		net: net,
 	}
@@ -24,7 +23,7 @@ func NewNSDialer(net *netstack.Net) *NSDialer {
 
 func (d *NSDialer) Dial(ctx context.Context, network, addr string) (net.Conn, error) {
 	log.Debugf("dialing %s %s", network, addr)
-	conn, err := d.net.Dial(network, addr)
+	conn, err := d.net.DialContext(ctx, network, addr)
 	if err != nil {
 		log.Debugf("failed to deal connection: %s", err)
 	}
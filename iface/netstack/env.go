@@ -4,15 +4,59 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 )
 
-// IsEnabled todo: move these function to cmd layer
+// Options configure the netstack mode, without them the mode is configured by the environment
+type Options struct {
+	Enabled bool
+	// Proxy configures the proxies into the netstack, no SOCKS5 proxy is served without its ListenAddr
+	Proxy ProxyConfig
+	// PortForwards expose services of the host to the peers
+	PortForwards []PortForward
+}
+
+var (
+	optionsMu sync.Mutex
+	options   *Options
+)
+
+// SetOptions configures the netstack mode of the interfaces created afterwards, the command line sets them
+// from its flags
+func SetOptions(opts Options) {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	options = &opts
+}
+
+func getOptions() *Options {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	return options
+}
+
+// IsEnabled returns whether the interfaces are created in netstack mode
 func IsEnabled() bool {
+	if opts := getOptions(); opts != nil {
+		return opts.Enabled
+	}
 	return os.Getenv("NB_USE_NETSTACK_MODE") == "true"
 }
 
+// GetOptions returns the options of the netstack mode. Without options set, only an unauthenticated SOCKS5 proxy
+// listening on the loopback address and the port of NB_SOCKS5_LISTENER_PORT is served
+func GetOptions() Options {
+	if opts := getOptions(); opts != nil {
+		return *opts
+	}
+	return Options{
+		Enabled: IsEnabled(),
+		Proxy:   ProxyConfig{ListenAddr: ListenAddr()},
+	}
+}
+
 func ListenAddr() string {
 	sPort := os.Getenv("NB_SOCKS5_LISTENER_PORT")
 	port, err := strconv.Atoi(sPort)
@@ -29,5 +73,5 @@ func ListenAddr() string {
 }
 
 func listenAddr(port int) string {
-	return fmt.Sprintf("0.0.0.0:%d", port)
+	return fmt.Sprintf("127.0.0.1:%d", port)
 }
@@ -0,0 +1,278 @@
+package netstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	// maxInFlightTCP is the maximum number of TCP connections being established at the same time
+	maxInFlightTCP = 1024
+	dialTimeout    = 10 * time.Second
+	// udpIdleTimeout closes the UDP flows without traffic in any direction
+	udpIdleTimeout = 2 * time.Minute
+)
+
+// Forwarder forwards the TCP and UDP flows routed through the netstack to their destinations. The flows are
+// terminated in the netstack and opened again from the host, so the routed traffic is always masqueraded
+// with the host address. Other protocols aren't forwarded
+type Forwarder struct {
+	stack     *stack.Stack
+	localAddr tcpip.Address
+	// dial opens the flows from the host
+	dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	mu      sync.Mutex
+	enabled bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func newForwarder(s *stack.Stack, localAddr netip.Addr) *Forwarder {
+	return &Forwarder{
+		stack:     s,
+		localAddr: tcpip.AddrFromSlice(localAddr.AsSlice()),
+		dial:      (&net.Dialer{Timeout: dialTimeout}).DialContext,
+	}
+}
+
+// Enable starts accepting the packets of any destination and forwarding their flows
+func (f *Forwarder) Enable() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.enabled {
+		return nil
+	}
+
+	if err := f.stack.SetPromiscuousMode(nicID, true); err != nil {
+		return fmt.Errorf("enable promiscuous mode: %v", err)
+	}
+	// the replies are sent from the addresses of the routed destinations
+	if err := f.stack.SetSpoofing(nicID, true); err != nil {
+		_ = f.stack.SetPromiscuousMode(nicID, false)
+		return fmt.Errorf("enable spoofing: %v", err)
+	}
+
+	f.ctx, f.cancel = context.WithCancel(context.Background())
+	tcpForwarder := tcp.NewForwarder(f.stack, 0, maxInFlightTCP, f.handleTCP)
+	udpForwarder := udp.NewForwarder(f.stack, f.handleUDP)
+	f.stack.SetTransportProtocolHandler(tcp.ProtocolNumber, tcpForwarder.HandlePacket)
+	f.stack.SetTransportProtocolHandler(udp.ProtocolNumber, udpForwarder.HandlePacket)
+	f.enabled = true
+
+	log.Info("enabled forwarding of the routed traffic in the netstack")
+	return nil
+}
+
+// Disable stops forwarding and closes the forwarded flows
+func (f *Forwarder) Disable() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.enabled {
+		return nil
+	}
+
+	f.stack.SetTransportProtocolHandler(tcp.ProtocolNumber, nil)
+	f.stack.SetTransportProtocolHandler(udp.ProtocolNumber, nil)
+	f.cancel()
+
+	var merr error
+	if err := f.stack.SetSpoofing(nicID, false); err != nil {
+		merr = errors.Join(merr, fmt.Errorf("disable spoofing: %v", err))
+	}
+	if err := f.stack.SetPromiscuousMode(nicID, false); err != nil {
+		merr = errors.Join(merr, fmt.Errorf("disable promiscuous mode: %v", err))
+	}
+	f.enabled = false
+
+	log.Info("disabled forwarding of the routed traffic in the netstack")
+	return merr
+}
+
+func (f *Forwarder) handleTCP(r *tcp.ForwarderRequest) {
+	id := r.ID()
+	// connections to the local address without listener are refused
+	if id.LocalAddress == f.localAddr {
+		r.Complete(true)
+		return
+	}
+	if isHostLocal(id.LocalAddress) {
+		log.Debugf("refused to forward TCP connection from %s to host-local address %s", id.RemoteAddress, id.LocalAddress)
+		r.Complete(true)
+		return
+	}
+
+	ctx := f.context()
+	dst := net.JoinHostPort(id.LocalAddress.String(), strconv.Itoa(int(id.LocalPort)))
+	outConn, err := f.dial(ctx, "tcp", dst)
+	if err != nil {
+		log.Debugf("failed to forward TCP connection from %s to %s: %v", id.RemoteAddress, dst, err)
+		r.Complete(true)
+		return
+	}
+
+	var wq waiter.Queue
+	ep, tcpErr := r.CreateEndpoint(&wq)
+	if tcpErr != nil {
+		log.Debugf("failed to create endpoint for TCP connection to %s: %v", dst, tcpErr)
+		r.Complete(true)
+		_ = outConn.Close()
+		return
+	}
+	r.Complete(false)
+
+	inConn := gonet.NewTCPConn(&wq, ep)
+	go relay(ctx, inConn, outConn, 0)
+}
+
+func (f *Forwarder) handleUDP(r *udp.ForwarderRequest) {
+	id := r.ID()
+	if id.LocalAddress == f.localAddr {
+		return
+	}
+	if isHostLocal(id.LocalAddress) {
+		log.Debugf("refused to forward UDP flow from %s to host-local address %s", id.RemoteAddress, id.LocalAddress)
+		return
+	}
+
+	ctx := f.context()
+	dst := net.JoinHostPort(id.LocalAddress.String(), strconv.Itoa(int(id.LocalPort)))
+
+	var wq waiter.Queue
+	ep, tcpErr := r.CreateEndpoint(&wq)
+	if tcpErr != nil {
+		log.Debugf("failed to create endpoint for UDP flow to %s: %v", dst, tcpErr)
+		return
+	}
+	inConn := gonet.NewUDPConn(f.stack, &wq, ep)
+
+	go func() {
+		outConn, err := f.dial(ctx, "udp", dst)
+		if err != nil {
+			log.Debugf("failed to forward UDP flow from %s to %s: %v", id.RemoteAddress, dst, err)
+			_ = inConn.Close()
+			return
+		}
+		relay(ctx, inConn, outConn, udpIdleTimeout)
+	}()
+}
+
+// isHostLocal returns true if the address only reaches the host itself: the loopback, unspecified, link-local and
+// multicast addresses and the addresses of the host interfaces. The flows to them aren't forwarded, like the kernel
+// drops the routed packets to them, so the peers can't reach the services only listening on the host
+func isHostLocal(address tcpip.Address) bool {
+	addr, ok := netip.AddrFromSlice(address.AsSlice())
+	if !ok {
+		return true
+	}
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsUnspecified() || addr.IsLinkLocalUnicast() || addr.IsMulticast() {
+		return true
+	}
+
+	hostAddrs, err := net.InterfaceAddrs()
+	if err != nil {
+		log.Warnf("failed to list the host addresses, refusing to forward to %s: %v", addr, err)
+		return true
+	}
+	for _, hostAddr := range hostAddrs {
+		var ip net.IP
+		switch hostAddr := hostAddr.(type) {
+		case *net.IPNet:
+			ip = hostAddr.IP
+		case *net.IPAddr:
+			ip = hostAddr.IP
+		}
+		if hostIP, ok := netip.AddrFromSlice(ip); ok && hostIP.Unmap() == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Forwarder) context() context.Context {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.ctx == nil {
+		return context.Background()
+	}
+	return f.ctx
+}
+
+// relay copies the data between both connections until one of them is closed, the context is done or,
+// if idleTimeout is set, no data is received for this duration
+func relay(ctx context.Context, inConn, outConn net.Conn, idleTimeout time.Duration) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		_ = inConn.Close()
+		_ = outConn.Close()
+	}()
+
+	var (
+		wg           sync.WaitGroup
+		lastActivity atomic.Int64
+	)
+	lastActivity.Store(time.Now().UnixNano())
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		copyWithIdleTimeout(outConn, inConn, idleTimeout, &lastActivity)
+	}()
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		copyWithIdleTimeout(inConn, outConn, idleTimeout, &lastActivity)
+	}()
+	wg.Wait()
+}
+
+// copyWithIdleTimeout copies the data from src to dst. If idleTimeout is set, the copy stops once no data has been
+// copied in any direction for this duration
+func copyWithIdleTimeout(dst, src net.Conn, idleTimeout time.Duration, lastActivity *atomic.Int64) {
+	if idleTimeout == 0 {
+		_, _ = io.Copy(dst, src)
+		return
+	}
+
+	buf := make([]byte, 65535)
+	for {
+		_ = src.SetReadDeadline(time.Unix(0, lastActivity.Load()).Add(idleTimeout))
+		n, err := src.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			// the other direction may have been active in the meantime
+			if errors.As(err, &netErr) && netErr.Timeout() &&
+				time.Since(time.Unix(0, lastActivity.Load())) < idleTimeout {
+				continue
+			}
+			return
+		}
+		lastActivity.Store(time.Now().UnixNano())
+		if _, err := dst.Write(buf[:n]); err != nil {
+			return
+		}
+	}
+}
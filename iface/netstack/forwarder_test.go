@@ -0,0 +1,108 @@
+package netstack
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sendSYN injects a TCP SYN of the peer to the destination into the stack, like WireGuard does for the received packets
+func sendSYN(t *testing.T, dev *netTun, src netip.Addr, dst netip.AddrPort) {
+	t.Helper()
+
+	ipv4 := &layers.IPv4{Version: 4, TTL: 64, SrcIP: src.AsSlice(), DstIP: dst.Addr().AsSlice(), Protocol: layers.IPProtocolTCP}
+	tcp := &layers.TCP{SrcPort: 40000, DstPort: layers.TCPPort(dst.Port()), SYN: true, Seq: 1, Window: 65535}
+	require.NoError(t, tcp.SetNetworkLayerForChecksum(ipv4))
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+	require.NoError(t, gopacket.SerializeLayers(buf, opts, ipv4, tcp))
+
+	_, err := dev.Write([][]byte{buf.Bytes()}, 0)
+	require.NoError(t, err)
+}
+
+// discardOutbound reads the packets the stack sends to the peers until the device is closed
+func discardOutbound(dev *netTun) {
+	packet := make([]byte, 1500)
+	sizes := make([]int, 1)
+	for {
+		if _, err := dev.Read([][]byte{packet}, sizes, 0); err != nil {
+			return
+		}
+	}
+}
+
+func TestForwarder_HostLocalDestinations(t *testing.T) {
+	addr := netip.MustParseAddr("100.64.0.1")
+	peerAddr := netip.MustParseAddr("100.64.0.2")
+	dev, _, err := createNetTUN(addr, 1280)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = dev.Close() })
+	go discardOutbound(dev)
+
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	port := uint16(listener.Addr().(*net.TCPAddr).Port)
+
+	// the routed destination is dialed on the host listener instead, as if a 0.0.0.0/0 route allowed any destination
+	routedAddr := netip.AddrPortFrom(netip.MustParseAddr("198.51.100.1"), port)
+	dialed := make(chan string, 10)
+	forwarder := newForwarder(dev.stack, addr)
+	forwarder.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialed <- address
+		if address == routedAddr.String() {
+			address = netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), port).String()
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, address)
+	}
+	require.NoError(t, forwarder.Enable())
+	t.Cleanup(func() { _ = forwarder.Disable() })
+
+	accepted := make(chan struct{}, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+			accepted <- struct{}{}
+		}
+	}()
+
+	sendSYN(t, dev, peerAddr, routedAddr)
+	select {
+	case <-accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the routed destination should be forwarded")
+	}
+	assert.Equal(t, routedAddr.String(), <-dialed)
+
+	hostLocal := []string{"127.0.0.1", "0.0.0.0", "169.254.1.1"}
+	hostAddrs, err := net.InterfaceAddrs()
+	require.NoError(t, err)
+	for _, hostAddr := range hostAddrs {
+		if ipNet, ok := hostAddr.(*net.IPNet); ok && ipNet.IP.To4() != nil && !ipNet.IP.IsLoopback() {
+			hostLocal = append(hostLocal, ipNet.IP.String())
+		}
+	}
+	for _, dst := range hostLocal {
+		sendSYN(t, dev, peerAddr, netip.AddrPortFrom(netip.MustParseAddr(dst), port))
+	}
+
+	select {
+	case address := <-dialed:
+		t.Fatalf("host-local destination %s shouldn't be dialed", address)
+	case <-accepted:
+		t.Fatal("host-local destinations shouldn't reach the host listener")
+	case <-time.After(time.Second):
+	}
+}
@@ -0,0 +1,202 @@
+package netstack
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	proxyAuthRealm    = "netbird"
+	readHeaderTimeout = 10 * time.Second
+)
+
+// HTTPProxy serves HTTP CONNECT tunnels into the netstack, other methods are refused
+type HTTPProxy struct {
+	dialer   Dialer
+	access   *accessControl
+	resolver *net.Resolver
+
+	mu     sync.Mutex
+	server *http.Server
+	closed bool
+}
+
+// NewHTTPProxy creates an HTTP CONNECT proxy dialing the tunnels with the dialer
+func NewHTTPProxy(dialer Dialer, config ProxyConfig) *HTTPProxy {
+	return &HTTPProxy{
+		dialer:   dialer,
+		access:   newAccessControl(config),
+		resolver: net.DefaultResolver,
+	}
+}
+
+func (p *HTTPProxy) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Errorf("failed to create listener for http proxy: %s", err)
+		return err
+	}
+	return p.Serve(listener)
+}
+
+// Serve accepts the connections of the listener until the proxy is closed
+func (p *HTTPProxy) Serve(listener net.Listener) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return listener.Close()
+	}
+	p.server = &http.Server{
+		Handler:           p,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	server := p.server
+	p.mu.Unlock()
+
+	err := server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (p *HTTPProxy) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	if p.server == nil {
+		return nil
+	}
+	// the hijacked tunnels aren't tracked by the server and are closed by their peers
+	return p.server.Close()
+}
+
+func (p *HTTPProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		w.Header().Set("Allow", http.MethodConnect)
+		http.Error(w, "only CONNECT is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if p.access.authRequired() {
+		username, password, ok := proxyBasicAuth(r)
+		if !ok || !p.access.Valid(username, password, r.RemoteAddr) {
+			w.Header().Set("Proxy-Authenticate", fmt.Sprintf("Basic realm=%q", proxyAuthRealm))
+			http.Error(w, "proxy authentication required", http.StatusProxyAuthRequired)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dialTimeout)
+	defer cancel()
+
+	dst, err := p.resolve(ctx, r.Host)
+	if err != nil {
+		log.Debugf("failed to resolve http proxy destination %s: %v", r.Host, err)
+		http.Error(w, "invalid destination", http.StatusBadGateway)
+		return
+	}
+	if !p.access.allowed(dst.Addr(), dst.Port()) {
+		log.Debugf("http proxy connection from %s to %s isn't allowed", r.RemoteAddr, dst)
+		http.Error(w, "destination not allowed", http.StatusForbidden)
+		return
+	}
+
+	outConn, err := p.dialer.Dial(ctx, "tcp", dst.String())
+	if err != nil {
+		http.Error(w, "failed to connect to the destination", http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		_ = outConn.Close()
+		http.Error(w, "tunneling isn't supported", http.StatusInternalServerError)
+		return
+	}
+	inConn, buf, err := hijacker.Hijack()
+	if err != nil {
+		_ = outConn.Close()
+		log.Debugf("failed to hijack http proxy connection from %s: %v", r.RemoteAddr, err)
+		return
+	}
+
+	if _, err := inConn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		_ = inConn.Close()
+		_ = outConn.Close()
+		return
+	}
+
+	// the client may have sent data after the request, it is buffered by the server
+	var inReader io.Reader = inConn
+	if buffered := buf.Reader.Buffered(); buffered > 0 {
+		data, _ := buf.Reader.Peek(buffered)
+		inReader = io.MultiReader(bytes.NewReader(data), inConn)
+	}
+	go tunnel(inConn, inReader, outConn)
+}
+
+// resolve returns the address and port of the host of a CONNECT request, names are resolved by the host
+// like the SOCKS5 proxy does
+func (p *HTTPProxy) resolve(ctx context.Context, hostPort string) (netip.AddrPort, error) {
+	host, sPort, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	port, err := strconv.ParseUint(sPort, 10, 16)
+	if err != nil || port == 0 {
+		return netip.AddrPort{}, fmt.Errorf("invalid port %s", sPort)
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return netip.AddrPortFrom(addr.Unmap(), uint16(port)), nil
+	}
+	addrs, err := p.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	if len(addrs) == 0 {
+		return netip.AddrPort{}, fmt.Errorf("no addresses found for %s", host)
+	}
+	return netip.AddrPortFrom(addrs[0].Unmap(), uint16(port)), nil
+}
+
+// proxyBasicAuth returns the credentials of the Proxy-Authorization header
+func proxyBasicAuth(r *http.Request) (string, string, bool) {
+	auth := r.Header.Get("Proxy-Authorization")
+	if auth == "" {
+		return "", "", false
+	}
+	// reuse the parsing of the Authorization header
+	req := http.Request{Header: http.Header{"Authorization": []string{auth}}}
+	return req.BasicAuth()
+}
+
+// tunnel copies the data between both connections until one direction is finished
+func tunnel(inConn net.Conn, inReader io.Reader, outConn net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(outConn, inReader)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(inConn, outConn)
+		done <- struct{}{}
+	}()
+	<-done
+	_ = inConn.Close()
+	_ = outConn.Close()
+	<-done
+}
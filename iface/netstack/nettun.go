@@ -0,0 +1,277 @@
+package netstack
+
+// The TUN device is based on golang.zx2c4.com/wireguard/tun/netstack (MIT licensed, Copyright (C) 2017-2023
+// WireGuard LLC), it exposes the gVisor stack so that the routed traffic can be forwarded.
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/tun"
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/channel"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/icmp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+)
+
+const nicID tcpip.NICID = 1
+
+// netTun is a TUN device backed by a gVisor network stack
+type netTun struct {
+	ep             *channel.Endpoint
+	stack          *stack.Stack
+	events         chan tun.Event
+	incomingPacket chan *buffer.View
+	// closed is closed with the device, the stack may still write packets while it is closed
+	closed    chan struct{}
+	mtu       int
+	localAddr tcpip.Address
+}
+
+// Net dials connections from the address of a netstack TUN device
+type Net struct {
+	stack *stack.Stack
+}
+
+func createNetTUN(localAddress netip.Addr, mtu int) (*netTun, *Net, error) {
+	opts := stack.Options{
+		NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol, ipv6.NewProtocol},
+		TransportProtocols: []stack.TransportProtocolFactory{tcp.NewProtocol, udp.NewProtocol, icmp.NewProtocol6, icmp.NewProtocol4},
+		// the packets to the local address are delivered back by WriteNotify. The stack would drop all the packets of
+		// the peers in promiscuous mode if it handled them itself, it takes their source for a local address
+		HandleLocal: false,
+	}
+	dev := &netTun{
+		ep:             channel.New(1024, uint32(mtu), ""),
+		stack:          stack.New(opts),
+		events:         make(chan tun.Event, 10),
+		incomingPacket: make(chan *buffer.View),
+		closed:         make(chan struct{}),
+		mtu:            mtu,
+		localAddr:      tcpip.AddrFromSlice(localAddress.AsSlice()),
+	}
+
+	sackEnabledOpt := tcpip.TCPSACKEnabled(true) // TCP SACK is disabled by default
+	if err := dev.stack.SetTransportProtocolOption(tcp.ProtocolNumber, &sackEnabledOpt); err != nil {
+		return nil, nil, fmt.Errorf("enable TCP SACK: %v", err)
+	}
+	dev.ep.AddNotify(dev)
+	if err := dev.stack.CreateNIC(nicID, dev.ep); err != nil {
+		return nil, nil, fmt.Errorf("create NIC: %v", err)
+	}
+
+	protoNumber := ipv4.ProtocolNumber
+	subnet := header.IPv4EmptySubnet
+	if localAddress.Is6() {
+		protoNumber = ipv6.ProtocolNumber
+		subnet = header.IPv6EmptySubnet
+	}
+	protoAddr := tcpip.ProtocolAddress{
+		Protocol:          protoNumber,
+		AddressWithPrefix: dev.localAddr.WithPrefix(),
+	}
+	if err := dev.stack.AddProtocolAddress(nicID, protoAddr, stack.AddressProperties{}); err != nil {
+		return nil, nil, fmt.Errorf("add protocol address %s: %v", localAddress, err)
+	}
+	dev.stack.AddRoute(tcpip.Route{Destination: subnet, NIC: nicID})
+
+	dev.events <- tun.EventUp
+	return dev, &Net{stack: dev.stack}, nil
+}
+
+func (t *netTun) Name() (string, error) {
+	return "go", nil
+}
+
+func (t *netTun) File() *os.File {
+	return nil
+}
+
+func (t *netTun) Events() <-chan tun.Event {
+	return t.events
+}
+
+func (t *netTun) Read(buf [][]byte, sizes []int, offset int) (int, error) {
+	var view *buffer.View
+	select {
+	case view = <-t.incomingPacket:
+	case <-t.closed:
+		return 0, os.ErrClosed
+	}
+
+	n, err := view.Read(buf[0][offset:])
+	if err != nil {
+		return 0, err
+	}
+	sizes[0] = n
+	return 1, nil
+}
+
+func (t *netTun) Write(buf [][]byte, offset int) (int, error) {
+	for _, buf := range buf {
+		packet := buf[offset:]
+		if len(packet) == 0 {
+			continue
+		}
+
+		if err := t.inject(packet); err != nil {
+			return 0, err
+		}
+	}
+	return len(buf), nil
+}
+
+// inject delivers the packet to the stack
+func (t *netTun) inject(packet []byte) error {
+	pkb := stack.NewPacketBuffer(stack.PacketBufferOptions{Payload: buffer.MakeWithData(packet)})
+
+	switch packet[0] >> 4 {
+	case 4:
+		t.ep.InjectInbound(header.IPv4ProtocolNumber, pkb)
+	case 6:
+		t.ep.InjectInbound(header.IPv6ProtocolNumber, pkb)
+	default:
+		return syscall.EAFNOSUPPORT
+	}
+	return nil
+}
+
+// WriteNotify is called by the channel endpoint when the stack has written a packet. The packets to the local address
+// are delivered back to the stack, the other ones are read by WireGuard
+func (t *netTun) WriteNotify() {
+	pkt := t.ep.Read()
+	if pkt.IsNil() {
+		return
+	}
+
+	view := pkt.ToView()
+	pkt.DecRef()
+
+	if t.isLocal(view.AsSlice()) {
+		if err := t.inject(view.AsSlice()); err != nil {
+			log.Debugf("failed to deliver a local packet: %v", err)
+		}
+		view.Release()
+		return
+	}
+	select {
+	case t.incomingPacket <- view:
+	case <-t.closed:
+		view.Release()
+	}
+}
+
+// isLocal returns true if the packet is addressed to the local address
+func (t *netTun) isLocal(packet []byte) bool {
+	switch {
+	case len(packet) >= header.IPv4MinimumSize && packet[0]>>4 == 4:
+		return header.IPv4(packet).DestinationAddress() == t.localAddr
+	case len(packet) >= header.IPv6MinimumSize && packet[0]>>4 == 6:
+		return header.IPv6(packet).DestinationAddress() == t.localAddr
+	default:
+		return false
+	}
+}
+
+func (t *netTun) Close() error {
+	t.stack.RemoveNIC(nicID)
+
+	if t.events != nil {
+		close(t.events)
+	}
+
+	t.ep.Close()
+
+	close(t.closed)
+
+	return nil
+}
+
+func (t *netTun) MTU() (int, error) {
+	return t.mtu, nil
+}
+
+func (t *netTun) BatchSize() int {
+	return 1
+}
+
+// DialContext connects to the address on the named network, only TCP and UDP networks are supported
+func (n *Net) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	addrPort, err := resolveAddrPort(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	fa, pn := toFullAddr(addrPort)
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return gonet.DialContextTCP(ctx, n.stack, fa, pn)
+	case "udp", "udp4", "udp6":
+		return gonet.DialUDP(n.stack, nil, &fa, pn)
+	default:
+		return nil, &net.OpError{Op: "dial", Net: network, Err: net.UnknownNetworkError(network)}
+	}
+}
+
+// ListenTCP accepts the TCP connections to the address
+func (n *Net) ListenTCP(addrPort netip.AddrPort) (net.Listener, error) {
+	fa, pn := toFullAddr(addrPort)
+	listener, err := gonet.ListenTCP(n.stack, fa, pn)
+	if err != nil {
+		return nil, err
+	}
+	return listener, nil
+}
+
+// ListenUDP receives the UDP datagrams sent to the address
+func (n *Net) ListenUDP(addrPort netip.AddrPort) (*gonet.UDPConn, error) {
+	fa, pn := toFullAddr(addrPort)
+	return gonet.DialUDP(n.stack, &fa, nil, pn)
+}
+
+func resolveAddrPort(ctx context.Context, network, address string) (netip.AddrPort, error) {
+	if addrPort, err := netip.ParseAddrPort(address); err == nil {
+		return addrPort, nil
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	portNum, err := net.DefaultResolver.LookupPort(ctx, network, port)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip4", host)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	if len(addrs) == 0 {
+		return netip.AddrPort{}, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return netip.AddrPortFrom(addrs[0].Unmap(), uint16(portNum)), nil
+}
+
+func toFullAddr(addrPort netip.AddrPort) (tcpip.FullAddress, tcpip.NetworkProtocolNumber) {
+	protoNumber := ipv4.ProtocolNumber
+	if addrPort.Addr().Is6() {
+		protoNumber = ipv6.ProtocolNumber
+	}
+	return tcpip.FullAddress{
+		NIC:  nicID,
+		Addr: tcpip.AddrFromSlice(addrPort.Addr().AsSlice()),
+		Port: addrPort.Port(),
+	}, protoNumber
+}
@@ -0,0 +1,347 @@
+package netstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+)
+
+const (
+	// maxUDPSessions limits the number of peers using a UDP forward at the same time
+	maxUDPSessions = 1024
+	udpBufferSize  = 65535
+)
+
+// PortForward exposes a service reachable from the host to the peers on a port of the netstack address
+type PortForward struct {
+	// Protocol is either tcp or udp
+	Protocol string
+	// Port is the port of the netstack address the peers connect to
+	Port uint16
+	// Target is the host:port address the connections are forwarded to from the host
+	Target string
+}
+
+// ParsePortForward parses a forward in the format port[/protocol]:host:port, e.g. 8080:127.0.0.1:80 or
+// 5353/udp:localhost:53. The protocol defaults to tcp
+func ParsePortForward(s string) (PortForward, error) {
+	listen, target, ok := strings.Cut(s, ":")
+	if !ok {
+		return PortForward{}, fmt.Errorf("invalid port forward %s: missing target", s)
+	}
+
+	sPort, protocol, hasProtocol := strings.Cut(listen, "/")
+	if !hasProtocol {
+		protocol = "tcp"
+	}
+	if protocol != "tcp" && protocol != "udp" {
+		return PortForward{}, fmt.Errorf("invalid port forward %s: unsupported protocol %s", s, protocol)
+	}
+	port, err := parsePort(sPort)
+	if err != nil {
+		return PortForward{}, fmt.Errorf("invalid port forward %s: %w", s, err)
+	}
+
+	host, targetPort, err := net.SplitHostPort(target)
+	if err != nil {
+		return PortForward{}, fmt.Errorf("invalid port forward %s: %w", s, err)
+	}
+	if host == "" {
+		return PortForward{}, fmt.Errorf("invalid port forward %s: missing target host", s)
+	}
+	if _, err := parsePort(targetPort); err != nil {
+		return PortForward{}, fmt.Errorf("invalid port forward %s: %w", s, err)
+	}
+
+	return PortForward{Protocol: protocol, Port: port, Target: target}, nil
+}
+
+func (f PortForward) String() string {
+	return fmt.Sprintf("%d/%s:%s", f.Port, f.Protocol, f.Target)
+}
+
+// portForwarder serves the port forwards on the netstack address
+type portForwarder struct {
+	net      *Net
+	addr     netip.Addr
+	forwards []PortForward
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	closers []func() error
+}
+
+func newPortForwarder(n *Net, addr netip.Addr, forwards []PortForward) *portForwarder {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &portForwarder{
+		net:      n,
+		addr:     addr,
+		forwards: forwards,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start listens on the ports of the forwards, all listeners are closed if one of them can't be opened
+func (p *portForwarder) Start() error {
+	for _, f := range p.forwards {
+		addrPort := netip.AddrPortFrom(p.addr, f.Port)
+		var err error
+		switch f.Protocol {
+		case "tcp":
+			err = p.startTCP(addrPort, f.Target)
+		case "udp":
+			err = p.startUDP(addrPort, f.Target)
+		default:
+			err = fmt.Errorf("unsupported protocol %s", f.Protocol)
+		}
+		if err != nil {
+			_ = p.Close()
+			return fmt.Errorf("start port forward %s: %w", f, err)
+		}
+		log.Infof("forwarding port %s", f)
+	}
+	return nil
+}
+
+func (p *portForwarder) Close() error {
+	p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var merr error
+	for _, closeFn := range p.closers {
+		if err := closeFn(); err != nil {
+			merr = errors.Join(merr, err)
+		}
+	}
+	p.closers = nil
+	return merr
+}
+
+func (p *portForwarder) addCloser(closeFn func() error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closers = append(p.closers, closeFn)
+}
+
+func (p *portForwarder) startTCP(addrPort netip.AddrPort, target string) error {
+	listener, err := p.net.ListenTCP(addrPort)
+	if err != nil {
+		return err
+	}
+	p.addCloser(listener.Close)
+
+	go func() {
+		for {
+			inConn, err := listener.Accept()
+			if err != nil {
+				if p.ctx.Err() == nil {
+					log.Errorf("failed to accept connection forwarded to %s: %v", target, err)
+				}
+				return
+			}
+			go p.forwardTCP(inConn, target)
+		}
+	}()
+	return nil
+}
+
+func (p *portForwarder) forwardTCP(inConn net.Conn, target string) {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	outConn, err := dialer.DialContext(p.ctx, "tcp", target)
+	if err != nil {
+		log.Debugf("failed to forward TCP connection from %s to %s: %v", inConn.RemoteAddr(), target, err)
+		_ = inConn.Close()
+		return
+	}
+	relay(p.ctx, inConn, outConn, 0)
+}
+
+func (p *portForwarder) startUDP(addrPort netip.AddrPort, target string) error {
+	conn, err := p.net.ListenUDP(addrPort)
+	if err != nil {
+		return err
+	}
+	p.addCloser(conn.Close)
+
+	go p.forwardUDP(conn, target)
+	return nil
+}
+
+// udpSession is the socket on the host forwarding the datagrams of a peer
+type udpSession struct {
+	conn         net.Conn
+	lastActivity atomic.Int64
+
+	// mu guards closed, no datagram is written once the session is closed
+	mu     sync.Mutex
+	closed bool
+}
+
+// write forwards the datagram to the target, it returns false if the session is closed
+func (s *udpSession) write(b []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false, nil
+	}
+	s.lastActivity.Store(time.Now().UnixNano())
+	_, err := s.conn.Write(b)
+	return true, err
+}
+
+func (s *udpSession) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	_ = s.conn.Close()
+}
+
+// udpSessions are the sessions of a UDP forward indexed by the address of the peer
+type udpSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*udpSession
+}
+
+func (s *udpSessions) get(peer net.Addr) (*udpSession, bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[peer.String()]
+	return session, ok, len(s.sessions)
+}
+
+// add stores the session of the peer unless another one was stored in the meantime, it returns the stored session
+// and true if it was added
+func (s *udpSessions) add(peer net.Addr, session *udpSession) (*udpSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.sessions[peer.String()]; ok {
+		return existing, false
+	}
+	s.sessions[peer.String()] = session
+	return session, true
+}
+
+// remove deletes the session of the peer and closes it. It is deleted first, so the closed sessions aren't found
+func (s *udpSessions) remove(peer net.Addr, session *udpSession) {
+	s.mu.Lock()
+	if s.sessions[peer.String()] == session {
+		delete(s.sessions, peer.String())
+	}
+	s.mu.Unlock()
+
+	session.close()
+}
+
+func (s *udpSessions) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, session := range s.sessions {
+		session.close()
+	}
+}
+
+// forwardUDP forwards the datagrams of each peer through its own socket on the host, so that the replies of
+// the target can be sent back to the peer
+func (p *portForwarder) forwardUDP(conn *gonet.UDPConn, target string) {
+	sessions := &udpSessions{sessions: make(map[string]*udpSession)}
+	defer sessions.closeAll()
+
+	buf := make([]byte, udpBufferSize)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			if p.ctx.Err() == nil {
+				log.Errorf("failed to read datagram forwarded to %s: %v", target, err)
+			}
+			return
+		}
+
+		// the session may expire while the datagram is written, it is sent through a new one then
+		for attempt := 0; attempt < 2; attempt++ {
+			session, err := p.udpSession(conn, sessions, peer, target)
+			if err != nil {
+				log.Debugf("failed to forward UDP flow from %s to %s: %v", peer, target, err)
+				break
+			}
+			written, err := session.write(buf[:n])
+			if err != nil {
+				log.Debugf("failed to forward datagram from %s to %s: %v", peer, target, err)
+			}
+			if written {
+				break
+			}
+		}
+	}
+}
+
+// udpSession returns the session of the peer, a new one is dialed if the peer doesn't have one
+func (p *portForwarder) udpSession(conn *gonet.UDPConn, sessions *udpSessions, peer net.Addr, target string) (*udpSession, error) {
+	session, ok, count := sessions.get(peer)
+	if ok {
+		return session, nil
+	}
+	if count >= maxUDPSessions {
+		return nil, errors.New("too many UDP sessions")
+	}
+
+	// the sessions aren't locked while dialing, the reply goroutines of the other peers can remove theirs meanwhile
+	dialer := net.Dialer{Timeout: dialTimeout}
+	outConn, err := dialer.DialContext(p.ctx, "udp", target)
+	if err != nil {
+		return nil, err
+	}
+	session = &udpSession{conn: outConn}
+	session.lastActivity.Store(time.Now().UnixNano())
+
+	session, added := sessions.add(peer, session)
+	if !added {
+		_ = outConn.Close()
+		return session, nil
+	}
+	go func() {
+		p.replyUDP(conn, peer, session)
+		sessions.remove(peer, session)
+	}()
+	return session, nil
+}
+
+// replyUDP sends the replies of the target back to the peer until the session is idle for udpIdleTimeout
+func (p *portForwarder) replyUDP(conn *gonet.UDPConn, peer net.Addr, session *udpSession) {
+	buf := make([]byte, udpBufferSize)
+	for {
+		_ = session.conn.SetReadDeadline(time.Unix(0, session.lastActivity.Load()).Add(udpIdleTimeout))
+		n, err := session.conn.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			// the peer may have sent datagrams in the meantime
+			if errors.As(err, &netErr) && netErr.Timeout() &&
+				time.Since(time.Unix(0, session.lastActivity.Load())) < udpIdleTimeout {
+				continue
+			}
+			return
+		}
+		session.lastActivity.Store(time.Now().UnixNano())
+		if _, err := conn.WriteTo(buf[:n], peer); err != nil {
+			log.Debugf("failed to send reply of %s to %s: %v", session.conn.RemoteAddr(), peer, err)
+			return
+		}
+	}
+}
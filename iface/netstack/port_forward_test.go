@@ -0,0 +1,131 @@
+package netstack
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePortForward(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  PortForward
+		expectErr bool
+	}{
+		{input: "8080:127.0.0.1:80", expected: PortForward{Protocol: "tcp", Port: 8080, Target: "127.0.0.1:80"}},
+		{input: "8080/tcp:localhost:80", expected: PortForward{Protocol: "tcp", Port: 8080, Target: "localhost:80"}},
+		{input: "5353/udp:127.0.0.1:53", expected: PortForward{Protocol: "udp", Port: 5353, Target: "127.0.0.1:53"}},
+		{input: "8080:[::1]:80", expected: PortForward{Protocol: "tcp", Port: 8080, Target: "[::1]:80"}},
+		{input: "8080", expectErr: true},
+		{input: "8080/icmp:127.0.0.1:80", expectErr: true},
+		{input: "0:127.0.0.1:80", expectErr: true},
+		{input: "8080:127.0.0.1", expectErr: true},
+		{input: "8080::80", expectErr: true},
+		{input: "8080:127.0.0.1:70000", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			f, err := ParsePortForward(tc.input)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, f)
+		})
+	}
+}
+
+// startUDPEcho starts a UDP server echoing the received datagrams
+func startUDPEcho(t *testing.T) *net.UDPAddr {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo(buf[:n], addr)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+func TestPortForwarder(t *testing.T) {
+	addr := netip.MustParseAddr("100.64.0.1")
+	dev, tunNet, err := createNetTUN(addr, 1280)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = dev.Close() })
+
+	tcpEcho := startEcho(t)
+	udpEcho := startUDPEcho(t)
+	forwarder := newPortForwarder(tunNet, addr, []PortForward{
+		{Protocol: "tcp", Port: 8080, Target: tcpEcho.String()},
+		{Protocol: "udp", Port: 5353, Target: udpEcho.String()},
+	})
+	require.NoError(t, forwarder.Start())
+	t.Cleanup(func() { _ = forwarder.Close() })
+
+	// the stack delivers the connections to its own address locally, like the ones received from the peers
+	t.Run("tcp", func(t *testing.T) {
+		conn, err := tunNet.DialContext(context.Background(), "tcp", fmt.Sprintf("%s:8080", addr))
+		require.NoError(t, err)
+		defer conn.Close()
+		assertEcho(t, conn)
+	})
+
+	t.Run("udp", func(t *testing.T) {
+		conn, err := tunNet.DialContext(context.Background(), "udp", fmt.Sprintf("%s:5353", addr))
+		require.NoError(t, err)
+		defer conn.Close()
+		assertEcho(t, conn)
+	})
+
+	t.Run("port without forward", func(t *testing.T) {
+		_, err := tunNet.DialContext(context.Background(), "tcp", fmt.Sprintf("%s:8081", addr))
+		assert.Error(t, err)
+	})
+}
+
+func TestUDPSessions(t *testing.T) {
+	udpEcho := startUDPEcho(t)
+	peer := &net.UDPAddr{IP: net.ParseIP("100.64.0.2"), Port: 40000}
+	sessions := &udpSessions{sessions: make(map[string]*udpSession)}
+
+	outConn, err := net.Dial("udp", udpEcho.String())
+	require.NoError(t, err)
+	session, added := sessions.add(peer, &udpSession{conn: outConn})
+	require.True(t, added)
+
+	otherConn, err := net.Dial("udp", udpEcho.String())
+	require.NoError(t, err)
+	defer otherConn.Close()
+	stored, added := sessions.add(peer, &udpSession{conn: otherConn})
+	assert.False(t, added, "a peer has a single session")
+	assert.Same(t, session, stored)
+
+	written, err := session.write([]byte("ping"))
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	// the expired session is removed before it is closed, the datagrams aren't written to it afterwards
+	sessions.remove(peer, session)
+	_, ok, count := sessions.get(peer)
+	assert.False(t, ok)
+	assert.Zero(t, count)
+	written, err = session.write([]byte("ping"))
+	require.NoError(t, err)
+	assert.False(t, written, "the closed session shouldn't be written")
+}
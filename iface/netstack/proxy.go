@@ -1,7 +1,10 @@
 package netstack
 
 import (
+	"fmt"
 	"net"
+	"net/netip"
+	"sync"
 
 	"github.com/things-go/go-socks5"
 
@@ -12,21 +15,68 @@ const (
 	DefaultSocks5Port = 1080
 )
 
-// Proxy todo close server
+// ProxyConfig configures the proxies serving connections into the netstack
+type ProxyConfig struct {
+	// ListenAddr is the address of the SOCKS5 proxy
+	ListenAddr string
+	// HTTPListenAddr is the address of the HTTP CONNECT proxy, the proxy is disabled when it is empty
+	HTTPListenAddr string
+	// Username and Password authenticate the clients of both proxies, no authentication is required without Username
+	Username string
+	Password string
+	// AllowedDestinations restricts the destinations of the proxied connections, all are allowed when it is empty
+	AllowedDestinations []Destination
+}
+
+// Validate returns an error when a proxy can be reached by other hosts without authentication
+func (c ProxyConfig) Validate() error {
+	if c.Username != "" {
+		return nil
+	}
+	for _, addr := range []string{c.ListenAddr, c.HTTPListenAddr} {
+		if addr == "" || isLoopback(addr) {
+			continue
+		}
+		return fmt.Errorf("proxy listening on %s must require authentication, "+
+			"otherwise anyone reaching this address can connect to the peers of this network", addr)
+	}
+	return nil
+}
+
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
+
+// Proxy serves SOCKS5 connections into the netstack
 type Proxy struct {
 	server *socks5.Server
 
+	mu       sync.Mutex
 	listener net.Listener
 	closed   bool
 }
 
-func NewSocks5(dialer Dialer) (*Proxy, error) {
-	server := socks5.NewServer(
+// NewSocks5 creates a SOCKS5 proxy dialing the connections with the dialer. Only the connect command is supported
+func NewSocks5(dialer Dialer, config ProxyConfig) (*Proxy, error) {
+	access := newAccessControl(config)
+	opts := []socks5.Option{
 		socks5.WithDial(dialer.Dial),
-	)
+		socks5.WithRule(access),
+	}
+	if access.authRequired() {
+		opts = append(opts, socks5.WithCredential(access))
+	}
 
 	return &Proxy{
-		server: server,
+		server: socks5.NewServer(opts...),
 	}, nil
 }
 
@@ -36,12 +86,23 @@ func (s *Proxy) ListenAndServe(addr string) error {
 		log.Errorf("failed to create listener for socks5 proxy: %s", err)
 		return err
 	}
+	return s.Serve(listener)
+}
+
+// Serve accepts the connections of the listener until the proxy is closed
+func (s *Proxy) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return listener.Close()
+	}
 	s.listener = listener
+	s.mu.Unlock()
 
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
-			if s.closed {
+			if s.isClosed() {
 				return nil
 			}
 			return err
@@ -49,17 +110,25 @@ func (s *Proxy) ListenAndServe(addr string) error {
 
 		go func() {
 			if err := s.server.ServeConn(conn); err != nil {
-				log.Errorf("failed to serve a connection: %s", err)
+				log.Debugf("failed to serve a socks5 connection from %s: %s", conn.RemoteAddr(), err)
 			}
 		}()
 	}
 }
 
+func (s *Proxy) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
 func (s *Proxy) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
 	if s.listener == nil {
 		return nil
 	}
-
-	s.closed = true
 	return s.listener.Close()
 }
@@ -0,0 +1,253 @@
+package netstack
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/proxy"
+)
+
+// hostDialer dials the connections of the proxies from the host instead of a netstack
+type hostDialer struct{}
+
+func (hostDialer) Dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, network, addr)
+}
+
+func TestParseDestination(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  Destination
+		expectErr bool
+	}{
+		{input: "100.64.0.0/10", expected: Destination{Prefix: netip.MustParsePrefix("100.64.0.0/10")}},
+		{input: "10.1.2.3/8", expected: Destination{Prefix: netip.MustParsePrefix("10.0.0.0/8")}},
+		{input: "10.0.0.5", expected: Destination{Prefix: netip.MustParsePrefix("10.0.0.5/32")}},
+		{
+			input:    "10.0.0.0/8:443",
+			expected: Destination{Prefix: netip.MustParsePrefix("10.0.0.0/8"), StartPort: 443, EndPort: 443},
+		},
+		{
+			input:    "10.0.0.5:8000-8100",
+			expected: Destination{Prefix: netip.MustParsePrefix("10.0.0.5/32"), StartPort: 8000, EndPort: 8100},
+		},
+		{input: "fd00::/8", expected: Destination{Prefix: netip.MustParsePrefix("fd00::/8")}},
+		{input: "fd00::1", expected: Destination{Prefix: netip.MustParsePrefix("fd00::1/128")}},
+		{
+			input:    "fd00::/8:22",
+			expected: Destination{Prefix: netip.MustParsePrefix("fd00::/8"), StartPort: 22, EndPort: 22},
+		},
+		{
+			input:    "[fd00::1]:22",
+			expected: Destination{Prefix: netip.MustParsePrefix("fd00::1/128"), StartPort: 22, EndPort: 22},
+		},
+		{input: "example.com", expectErr: true},
+		{input: "10.0.0.0/33", expectErr: true},
+		{input: "10.0.0.0/8:0", expectErr: true},
+		{input: "10.0.0.0/8:65536", expectErr: true},
+		{input: "10.0.0.0/8:8100-8000", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			d, err := ParseDestination(tc.input)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, d)
+		})
+	}
+}
+
+func TestAccessControlAllowed(t *testing.T) {
+	access := newAccessControl(ProxyConfig{AllowedDestinations: []Destination{
+		{Prefix: netip.MustParsePrefix("100.64.0.0/10")},
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), StartPort: 8000, EndPort: 8100},
+	}})
+
+	assert.True(t, access.allowed(netip.MustParseAddr("100.64.0.1"), 22), "address of the allowed prefix")
+	assert.True(t, access.allowed(netip.MustParseAddr("::ffff:100.64.0.1"), 22), "mapped address of the allowed prefix")
+	assert.True(t, access.allowed(netip.MustParseAddr("10.0.0.1"), 8050), "port of the allowed range")
+	assert.False(t, access.allowed(netip.MustParseAddr("10.0.0.1"), 22), "port outside of the allowed range")
+	assert.False(t, access.allowed(netip.MustParseAddr("192.168.0.1"), 22), "address outside of the prefixes")
+
+	assert.True(t, newAccessControl(ProxyConfig{}).allowed(netip.MustParseAddr("192.168.0.1"), 22),
+		"all destinations are allowed without allow-list")
+}
+
+func TestProxyConfigValidate(t *testing.T) {
+	assert.NoError(t, ProxyConfig{ListenAddr: "127.0.0.1:1080", HTTPListenAddr: "[::1]:3128"}.Validate(),
+		"loopback addresses don't require authentication")
+	assert.NoError(t, ProxyConfig{ListenAddr: "localhost:1080"}.Validate(), "localhost doesn't require authentication")
+	assert.Error(t, ProxyConfig{ListenAddr: "0.0.0.0:1080"}.Validate(), "unauthenticated socks5 proxy on all addresses")
+	assert.Error(t, ProxyConfig{ListenAddr: "127.0.0.1:1080", HTTPListenAddr: "192.168.0.1:3128"}.Validate(),
+		"unauthenticated http proxy on a non-loopback address")
+	assert.NoError(t, ProxyConfig{ListenAddr: "0.0.0.0:1080", Username: "user", Password: "pass"}.Validate(),
+		"authenticated proxy on all addresses")
+}
+
+// startEcho starts a TCP server echoing the received data
+func startEcho(t *testing.T) *net.TCPAddr {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr)
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	return listener
+}
+
+func assertEcho(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	_, err := conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+}
+
+func TestSocks5Proxy(t *testing.T) {
+	echo := startEcho(t)
+	config := ProxyConfig{
+		Username: "user",
+		Password: "secret",
+		AllowedDestinations: []Destination{
+			{Prefix: netip.MustParsePrefix("127.0.0.1/32"), StartPort: uint16(echo.Port), EndPort: uint16(echo.Port)},
+		},
+	}
+
+	p, err := NewSocks5(hostDialer{}, config)
+	require.NoError(t, err)
+	listener := listen(t)
+	go func() { _ = p.Serve(listener) }()
+	t.Cleanup(func() { _ = p.Close() })
+
+	t.Run("authenticated", func(t *testing.T) {
+		dialer, err := proxy.SOCKS5("tcp", listener.Addr().String(), &proxy.Auth{User: "user", Password: "secret"}, nil)
+		require.NoError(t, err)
+		conn, err := dialer.Dial("tcp", echo.String())
+		require.NoError(t, err)
+		defer conn.Close()
+		assertEcho(t, conn)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		dialer, err := proxy.SOCKS5("tcp", listener.Addr().String(), &proxy.Auth{User: "user", Password: "wrong"}, nil)
+		require.NoError(t, err)
+		_, err = dialer.Dial("tcp", echo.String())
+		assert.Error(t, err)
+	})
+
+	t.Run("without credentials", func(t *testing.T) {
+		dialer, err := proxy.SOCKS5("tcp", listener.Addr().String(), nil, nil)
+		require.NoError(t, err)
+		_, err = dialer.Dial("tcp", echo.String())
+		assert.Error(t, err)
+	})
+
+	t.Run("destination not allowed", func(t *testing.T) {
+		dialer, err := proxy.SOCKS5("tcp", listener.Addr().String(), &proxy.Auth{User: "user", Password: "secret"}, nil)
+		require.NoError(t, err)
+		_, err = dialer.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", echo.Port+1))
+		assert.Error(t, err)
+	})
+}
+
+func TestHTTPProxy(t *testing.T) {
+	echo := startEcho(t)
+	config := ProxyConfig{
+		Username: "user",
+		Password: "secret",
+		AllowedDestinations: []Destination{
+			{Prefix: netip.MustParsePrefix("127.0.0.1/32"), StartPort: uint16(echo.Port), EndPort: uint16(echo.Port)},
+		},
+	}
+
+	p := NewHTTPProxy(hostDialer{}, config)
+	listener := listen(t)
+	go func() { _ = p.Serve(listener) }()
+	t.Cleanup(func() { _ = p.Close() })
+
+	connect := func(t *testing.T, method, dst, auth string) (net.Conn, int) {
+		t.Helper()
+
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		target := dst
+		if method != http.MethodConnect {
+			target = "http://" + dst + "/"
+		}
+		req := fmt.Sprintf("%s %s HTTP/1.1\r\nHost: %s\r\n", method, target, dst)
+		if auth != "" {
+			req += "Proxy-Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(auth)) + "\r\n"
+		}
+		_, err = conn.Write([]byte(req + "\r\n"))
+		require.NoError(t, err)
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		return conn, resp.StatusCode
+	}
+
+	t.Run("authenticated", func(t *testing.T) {
+		conn, status := connect(t, http.MethodConnect, echo.String(), "user:secret")
+		require.Equal(t, http.StatusOK, status)
+		assertEcho(t, conn)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		_, status := connect(t, http.MethodConnect, echo.String(), "user:wrong")
+		assert.Equal(t, http.StatusProxyAuthRequired, status)
+	})
+
+	t.Run("without credentials", func(t *testing.T) {
+		_, status := connect(t, http.MethodConnect, echo.String(), "")
+		assert.Equal(t, http.StatusProxyAuthRequired, status)
+	})
+
+	t.Run("destination not allowed", func(t *testing.T) {
+		_, status := connect(t, http.MethodConnect, fmt.Sprintf("127.0.0.1:%d", echo.Port+1), "user:secret")
+		assert.Equal(t, http.StatusForbidden, status)
+	})
+
+	t.Run("not a connect request", func(t *testing.T) {
+		_, status := connect(t, http.MethodGet, echo.String(), "user:secret")
+		assert.Equal(t, http.StatusMethodNotAllowed, status)
+	})
+}
@@ -5,55 +5,108 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"golang.zx2c4.com/wireguard/tun"
-	"golang.zx2c4.com/wireguard/tun/netstack"
 )
 
 type NetStackTun struct {
-	address       string
-	mtu           int
-	listenAddress string
+	address      string
+	mtu          int
+	proxyConfig  ProxyConfig
+	portForwards []PortForward
 
-	proxy  *Proxy
-	tundev tun.Device
+	net           *Net
+	proxy         *Proxy
+	httpProxy     *HTTPProxy
+	portForwarder *portForwarder
+	tundev        tun.Device
+	forwarder     *Forwarder
 }
 
-func NewNetStackTun(listenAddress string, address string, mtu int) *NetStackTun {
+func NewNetStackTun(opts Options, address string, mtu int) *NetStackTun {
 	return &NetStackTun{
-		address:       address,
-		mtu:           mtu,
-		listenAddress: listenAddress,
+		address:      address,
+		mtu:          mtu,
+		proxyConfig:  opts.Proxy,
+		portForwards: opts.PortForwards,
 	}
 }
 
 func (t *NetStackTun) Create() (tun.Device, error) {
-	nsTunDev, tunNet, err := netstack.CreateNetTUN(
-		[]netip.Addr{netip.MustParseAddr(t.address)},
-		[]netip.Addr{},
-		t.mtu)
-	if err != nil {
+	if err := t.proxyConfig.Validate(); err != nil {
 		return nil, err
 	}
-	t.tundev = nsTunDev
 
-	dialer := NewNSDialer(tunNet)
-	t.proxy, err = NewSocks5(dialer)
+	addr := netip.MustParseAddr(t.address)
+	nsTunDev, tunNet, err := createNetTUN(addr, t.mtu)
 	if err != nil {
-		_ = t.tundev.Close()
 		return nil, err
 	}
+	t.tundev = nsTunDev
+	t.net = tunNet
+	t.forwarder = newForwarder(nsTunDev.stack, addr)
+
+	if len(t.portForwards) > 0 {
+		t.portForwarder = newPortForwarder(tunNet, addr, t.portForwards)
+		if err := t.portForwarder.Start(); err != nil {
+			_ = t.tundev.Close()
+			return nil, err
+		}
+	}
 
-	go func() {
-		err := t.proxy.ListenAndServe(t.listenAddress)
+	dialer := NewNSDialer(tunNet)
+	if t.proxyConfig.ListenAddr != "" {
+		t.proxy, err = NewSocks5(dialer, t.proxyConfig)
 		if err != nil {
-			log.Errorf("error in socks5 proxy serving: %s", err)
+			_ = t.Close()
+			return nil, err
 		}
-	}()
+
+		go func() {
+			err := t.proxy.ListenAndServe(t.proxyConfig.ListenAddr)
+			if err != nil {
+				log.Errorf("error in socks5 proxy serving: %s", err)
+			}
+		}()
+	}
+
+	if t.proxyConfig.HTTPListenAddr != "" {
+		t.httpProxy = NewHTTPProxy(dialer, t.proxyConfig)
+		go func() {
+			err := t.httpProxy.ListenAndServe(t.proxyConfig.HTTPListenAddr)
+			if err != nil {
+				log.Errorf("error in http proxy serving: %s", err)
+			}
+		}()
+	}
 
 	return nsTunDev, nil
 }
 
+// Net returns the network of the netstack address to dial and listen on, it is nil before the device is created
+func (t *NetStackTun) Net() *Net {
+	return t.net
+}
+
+// Forwarder returns the forwarder of the routed traffic, it is nil before the device is created
+func (t *NetStackTun) Forwarder() *Forwarder {
+	return t.forwarder
+}
+
 func (t *NetStackTun) Close() error {
 	var err error
+	if t.forwarder != nil {
+		if fErr := t.forwarder.Disable(); fErr != nil {
+			log.Errorf("failed to disable netstack forwarder: %s", fErr)
+			err = fErr
+		}
+	}
+
+	if t.portForwarder != nil {
+		if fErr := t.portForwarder.Close(); fErr != nil {
+			log.Errorf("failed to close port forwards: %s", fErr)
+			err = fErr
+		}
+	}
+
 	if t.proxy != nil {
 		pErr := t.proxy.Close()
 		if pErr != nil {
@@ -62,6 +115,13 @@ func (t *NetStackTun) Close() error {
 		}
 	}
 
+	if t.httpProxy != nil {
+		if pErr := t.httpProxy.Close(); pErr != nil {
+			log.Errorf("failed to close http proxy: %s", pErr)
+			err = pErr
+		}
+	}
+
 	if t.tundev != nil {
 		dErr := t.tundev.Close()
 		if dErr != nil {
@@ -13,6 +13,7 @@ import (
 	"github.com/vishvananda/netlink"
 
 	"github.com/netbirdio/netbird/iface/bind"
+	"github.com/netbirdio/netbird/iface/netns"
 	"github.com/netbirdio/netbird/sharedsock"
 )
 
@@ -25,13 +26,14 @@ type tunKernelDevice struct {
 	ctx          context.Context
 	ctxCancel    context.CancelFunc
 	transportNet transport.Net
+	netNS        *netns.NetNS
 
 	link       *wgLink
 	udpMuxConn net.PacketConn
 	udpMux     *bind.UniversalUDPMuxDefault
 }
 
-func newTunDevice(name string, address WGAddress, wgPort int, key string, mtu int, transportNet transport.Net) wgTunDevice {
+func newTunDevice(name string, address WGAddress, wgPort int, key string, mtu int, transportNet transport.Net, netNS *netns.NetNS) wgTunDevice {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &tunKernelDevice{
 		ctx:          ctx,
@@ -42,55 +44,53 @@ func newTunDevice(name string, address WGAddress, wgPort int, key string, mtu in
 		key:          key,
 		mtu:          mtu,
 		transportNet: transportNet,
+		netNS:        netNS,
 	}
 }
 
 func (t *tunKernelDevice) Create() (wgConfigurer, error) {
 	link := newWGLink(t.name)
 
-	// check if interface exists
-	l, err := netlink.LinkByName(t.name)
-	if err != nil {
-		switch err.(type) {
-		case netlink.LinkNotFoundError:
-			break
-		default:
-			return nil, err
-		}
-	}
-
-	// remove if interface exists
-	if l != nil {
-		err = netlink.LinkDel(link)
-		if err != nil {
-			return nil, err
-		}
+	if err := removeLinkIfExists(link); err != nil {
+		return nil, err
 	}
 
 	log.Debugf("adding device: %s", t.name)
-	err = netlink.LinkAdd(link)
+	err := netlink.LinkAdd(link)
 	if os.IsExist(err) {
 		log.Infof("interface %s already exists. Will reuse.", t.name)
 	} else if err != nil {
 		return nil, err
 	}
 
+	if t.netNS != nil {
+		// the link is created in the namespace of the process first, so that its WireGuard socket stays there
+		link, err = moveLinkToNetNS(link, t.netNS)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	t.link = link
 
-	err = t.assignAddr()
-	if err != nil {
-		return nil, err
-	}
+	err = t.netNS.Do(func() error {
+		if err := t.assignAddr(); err != nil {
+			return err
+		}
 
-	// todo do a discovery
-	log.Debugf("setting MTU: %d interface: %s", t.mtu, t.name)
-	err = netlink.LinkSetMTU(link, t.mtu)
+		// todo do a discovery
+		log.Debugf("setting MTU: %d interface: %s", t.mtu, t.name)
+		if err := netlink.LinkSetMTU(link, t.mtu); err != nil {
+			log.Errorf("error setting MTU on interface: %s", t.name)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		log.Errorf("error setting MTU on interface: %s", t.name)
 		return nil, err
 	}
 
-	configurer := newWGConfigurer(t.name)
+	configurer := newWGConfigurer(t.name, t.netNS)
 	err = configurer.configureInterface(t.key, t.wgPort)
 	if err != nil {
 		return nil, err
@@ -108,7 +108,9 @@ func (t *tunKernelDevice) Up() (*bind.UniversalUDPMuxDefault, error) {
 	}
 
 	log.Debugf("bringing up interface: %s", t.name)
-	err := netlink.LinkSetUp(t.link)
+	err := t.netNS.Do(func() error {
+		return netlink.LinkSetUp(t.link)
+	})
 	if err != nil {
 		log.Errorf("error bringing up interface: %s", t.name)
 		return nil, err
@@ -133,7 +135,7 @@ func (t *tunKernelDevice) Up() (*bind.UniversalUDPMuxDefault, error) {
 
 func (t *tunKernelDevice) UpdateAddr(address WGAddress) error {
 	t.address = address
-	return t.assignAddr()
+	return t.netNS.Do(t.assignAddr)
 }
 
 func (t *tunKernelDevice) Close() error {
@@ -144,7 +146,7 @@ func (t *tunKernelDevice) Close() error {
 	t.ctxCancel()
 
 	var closErr error
-	if err := t.link.Close(); err != nil {
+	if err := t.netNS.Do(t.link.Close); err != nil {
 		log.Debugf("failed to close link: %s", err)
 		closErr = err
 	}
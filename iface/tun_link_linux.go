@@ -2,7 +2,15 @@
 
 package iface
 
-import "github.com/vishvananda/netlink"
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+
+	"github.com/netbirdio/netbird/iface/netns"
+)
 
 type wgLink struct {
 	attrs *netlink.LinkAttrs
@@ -31,3 +39,35 @@ func (l *wgLink) Type() string {
 func (l *wgLink) Close() error {
 	return netlink.LinkDel(l)
 }
+
+// removeLinkIfExists deletes a link left over with the same name, e.g. by a previous run
+func removeLinkIfExists(link *wgLink) error {
+	l, err := netlink.LinkByName(link.attrs.Name)
+	if err != nil {
+		var notFound netlink.LinkNotFoundError
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		return err
+	}
+	if l == nil {
+		return nil
+	}
+	return netlink.LinkDel(link)
+}
+
+// moveLinkToNetNS moves the link into the namespace, replacing the link left over there. The returned link is
+// looked up by name in the namespace, its index may differ from the one in the namespace of the process
+func moveLinkToNetNS(link *wgLink, ns *netns.NetNS) (*wgLink, error) {
+	if err := ns.Do(func() error { return removeLinkIfExists(newWGLink(link.attrs.Name)) }); err != nil {
+		_ = link.Close()
+		return nil, fmt.Errorf("remove link from network namespace %s: %w", ns, err)
+	}
+
+	log.Debugf("moving interface %s to network namespace %s", link.attrs.Name, ns)
+	if err := netlink.LinkSetNsFd(link, ns.Fd()); err != nil {
+		_ = link.Close()
+		return nil, fmt.Errorf("move link to network namespace %s: %w", ns, err)
+	}
+	return newWGLink(link.attrs.Name), nil
+}
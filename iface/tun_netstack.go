@@ -15,13 +15,13 @@ import (
 )
 
 type tunNetstackDevice struct {
-	name          string
-	address       WGAddress
-	port          int
-	key           string
-	mtu           int
-	listenAddress string
-	iceBind       *bind.ICEBind
+	name         string
+	address      WGAddress
+	port         int
+	key          string
+	mtu          int
+	netstackOpts netstack.Options
+	iceBind      *bind.ICEBind
 
 	device     *device.Device
 	wrapper    *DeviceWrapper
@@ -30,21 +30,21 @@ type tunNetstackDevice struct {
 	configurer wgConfigurer
 }
 
-func newTunNetstackDevice(name string, address WGAddress, wgPort int, key string, mtu int, transportNet transport.Net, listenAddress string) wgTunDevice {
+func newTunNetstackDevice(name string, address WGAddress, wgPort int, key string, mtu int, transportNet transport.Net, netstackOpts netstack.Options) wgTunDevice {
 	return &tunNetstackDevice{
-		name:          name,
-		address:       address,
-		port:          wgPort,
-		key:           key,
-		mtu:           mtu,
-		listenAddress: listenAddress,
-		iceBind:       bind.NewICEBind(transportNet),
+		name:         name,
+		address:      address,
+		port:         wgPort,
+		key:          key,
+		mtu:          mtu,
+		netstackOpts: netstackOpts,
+		iceBind:      bind.NewICEBind(transportNet),
 	}
 }
 
 func (t *tunNetstackDevice) Create() (wgConfigurer, error) {
 	log.Info("create netstack tun interface")
-	t.nsTun = netstack.NewNetStackTun(t.listenAddress, t.address.IP.String(), t.mtu)
+	t.nsTun = netstack.NewNetStackTun(t.netstackOpts, t.address.IP.String(), t.mtu)
 	tunIface, err := t.nsTun.Create()
 	if err != nil {
 		return nil, err
@@ -117,3 +117,19 @@ func (t *tunNetstackDevice) DeviceName() string {
 func (t *tunNetstackDevice) Wrapper() *DeviceWrapper {
 	return t.wrapper
 }
+
+// Net returns the network of the netstack to dial and listen on the interface address
+func (t *tunNetstackDevice) Net() *netstack.Net {
+	if t.nsTun == nil {
+		return nil
+	}
+	return t.nsTun.Net()
+}
+
+// Forwarder returns the forwarder of the routed traffic of the netstack
+func (t *tunNetstackDevice) Forwarder() Forwarder {
+	if t.nsTun == nil || t.nsTun.Forwarder() == nil {
+		return nil
+	}
+	return t.nsTun.Forwarder()
+}
@@ -13,6 +13,7 @@ import (
 	"golang.zx2c4.com/wireguard/tun"
 
 	"github.com/netbirdio/netbird/iface/bind"
+	"github.com/netbirdio/netbird/iface/netns"
 )
 
 type tunUSPDevice struct {
@@ -22,6 +23,7 @@ type tunUSPDevice struct {
 	key     string
 	mtu     int
 	iceBind *bind.ICEBind
+	netNS   *netns.NetNS
 
 	device     *device.Device
 	wrapper    *DeviceWrapper
@@ -29,7 +31,7 @@ type tunUSPDevice struct {
 	configurer wgConfigurer
 }
 
-func newTunUSPDevice(name string, address WGAddress, port int, key string, mtu int, transportNet transport.Net) wgTunDevice {
+func newTunUSPDevice(name string, address WGAddress, port int, key string, mtu int, transportNet transport.Net, netNS *netns.NetNS) wgTunDevice {
 	log.Infof("using userspace bind mode")
 	return &tunUSPDevice{
 		name:    name,
@@ -38,6 +40,7 @@ func newTunUSPDevice(name string, address WGAddress, port int, key string, mtu i
 		key:     key,
 		mtu:     mtu,
 		iceBind: bind.NewICEBind(transportNet),
+		netNS:   netNS,
 	}
 }
 
@@ -57,7 +60,15 @@ func (t *tunUSPDevice) Create() (wgConfigurer, error) {
 		device.NewLogger(device.LogLevelSilent, "[netbird] "),
 	)
 
-	err = t.assignAddr()
+	if t.netNS != nil {
+		// the sockets of the device are opened by the process, only the tun interface is moved
+		if _, err := moveLinkToNetNS(newWGLink(t.name), t.netNS); err != nil {
+			t.device.Close()
+			return nil, err
+		}
+	}
+
+	err = t.netNS.Do(t.assignAddr)
 	if err != nil {
 		t.device.Close()
 		return nil, err
@@ -95,7 +106,7 @@ func (t *tunUSPDevice) Up() (*bind.UniversalUDPMuxDefault, error) {
 
 func (t *tunUSPDevice) UpdateAddr(address WGAddress) error {
 	t.address = address
-	return t.assignAddr()
+	return t.netNS.Do(t.assignAddr)
 }
 
 func (t *tunUSPDevice) Close() error {
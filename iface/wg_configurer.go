@@ -7,11 +7,20 @@ import (
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
+// WGStats are the WireGuard statistics of a peer
+type WGStats struct {
+	// LastHandshake is zero if no handshake has been performed yet
+	LastHandshake time.Time
+	TxBytes       int64
+	RxBytes       int64
+}
+
 type wgConfigurer interface {
 	configureInterface(privateKey string, port int) error
 	updatePeer(peerKey string, allowedIps string, keepAlive time.Duration, endpoint *net.UDPAddr, preSharedKey *wgtypes.Key) error
 	removePeer(peerKey string) error
 	addAllowedIP(peerKey string, allowedIP string) error
 	removeAllowedIP(peerKey string, allowedIP string) error
+	getStats() (map[string]WGStats, error)
 	close()
 }
@@ -10,15 +10,19 @@ import (
 	log "github.com/sirupsen/logrus"
 	"golang.zx2c4.com/wireguard/wgctrl"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/netbirdio/netbird/iface/netns"
 )
 
 type wgKernelConfigurer struct {
 	deviceName string
+	netNS      *netns.NetNS
 }
 
-func newWGConfigurer(deviceName string) wgConfigurer {
+func newWGConfigurer(deviceName string, netNS *netns.NetNS) wgConfigurer {
 	wgc := &wgKernelConfigurer{
 		deviceName: deviceName,
+		netNS:      netNS,
 	}
 	return wgc
 }
@@ -165,7 +169,7 @@ func (c *wgKernelConfigurer) removeAllowedIP(peerKey string, allowedIP string) e
 }
 
 func (c *wgKernelConfigurer) getPeer(ifaceName, peerPubKey string) (wgtypes.Peer, error) {
-	wg, err := wgctrl.New()
+	wg, err := c.newClient()
 	if err != nil {
 		return wgtypes.Peer{}, err
 	}
@@ -189,7 +193,7 @@ func (c *wgKernelConfigurer) getPeer(ifaceName, peerPubKey string) (wgtypes.Peer
 }
 
 func (c *wgKernelConfigurer) configure(config wgtypes.Config) error {
-	wg, err := wgctrl.New()
+	wg, err := c.newClient()
 	if err != nil {
 		return err
 	}
@@ -205,5 +209,40 @@ func (c *wgKernelConfigurer) configure(config wgtypes.Config) error {
 	return wg.ConfigureDevice(c.deviceName, config)
 }
 
+// getStats returns the statistics of the peers keyed by their public key
+func (c *wgKernelConfigurer) getStats() (map[string]WGStats, error) {
+	wg, err := c.newClient()
+	if err != nil {
+		return nil, err
+	}
+	defer wg.Close()
+
+	wgDevice, err := wg.Device(c.deviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]WGStats, len(wgDevice.Peers))
+	for _, peer := range wgDevice.Peers {
+		stats[peer.PublicKey.String()] = WGStats{
+			LastHandshake: peer.LastHandshakeTime,
+			TxBytes:       peer.TransmitBytes,
+			RxBytes:       peer.ReceiveBytes,
+		}
+	}
+	return stats, nil
+}
+
+// newClient opens the WireGuard client in the namespace of the interface, its socket stays in the namespace
+func (c *wgKernelConfigurer) newClient() (*wgctrl.Client, error) {
+	var wg *wgctrl.Client
+	err := c.netNS.Do(func() error {
+		var err error
+		wg, err = wgctrl.New()
+		return err
+	})
+	return wg, err
+}
+
 func (c *wgKernelConfigurer) close() {
 }
@@ -6,6 +6,7 @@ import (
 	"net"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -168,6 +169,76 @@ func (c *wgUSPConfigurer) removeAllowedIP(peerKey string, ip string) error {
 	}
 }
 
+// getStats returns the statistics of the peers keyed by their public key
+func (c *wgUSPConfigurer) getStats() (map[string]WGStats, error) {
+	ipc, err := c.device.IpcGet()
+	if err != nil {
+		return nil, err
+	}
+	return parseStats(ipc)
+}
+
+// parseStats reads the statistics of the peers from the output of the UAPI get operation
+func parseStats(ipc string) (map[string]WGStats, error) {
+	stats := make(map[string]WGStats)
+
+	var (
+		peerKey       string
+		peerStats     WGStats
+		handshakeSec  int64
+		handshakeNsec int64
+	)
+	addPeer := func() {
+		if peerKey == "" {
+			return
+		}
+		if handshakeSec != 0 || handshakeNsec != 0 {
+			peerStats.LastHandshake = time.Unix(handshakeSec, handshakeNsec)
+		}
+		stats[peerKey] = peerStats
+	}
+
+	for _, line := range strings.Split(ipc, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch key {
+		case "public_key":
+			addPeer()
+			var wgKey wgtypes.Key
+			wgKey, err = parseHexKey(value)
+			peerKey = wgKey.String()
+			peerStats = WGStats{}
+			handshakeSec, handshakeNsec = 0, 0
+		case "last_handshake_time_sec":
+			handshakeSec, err = strconv.ParseInt(value, 10, 64)
+		case "last_handshake_time_nsec":
+			handshakeNsec, err = strconv.ParseInt(value, 10, 64)
+		case "tx_bytes":
+			peerStats.TxBytes, err = strconv.ParseInt(value, 10, 64)
+		case "rx_bytes":
+			peerStats.RxBytes, err = strconv.ParseInt(value, 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", key, err)
+		}
+	}
+	addPeer()
+
+	return stats, nil
+}
+
+func parseHexKey(value string) (wgtypes.Key, error) {
+	hexKey, err := hex.DecodeString(value)
+	if err != nil {
+		return wgtypes.Key{}, err
+	}
+	return wgtypes.NewKey(hexKey)
+}
+
 // startUAPI starts the UAPI listener for managing the WireGuard interface via external tool
 func (t *wgUSPConfigurer) startUAPI() {
 	var err error
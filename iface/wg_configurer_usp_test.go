@@ -0,0 +1,55 @@
+package iface
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func TestParseStats(t *testing.T) {
+	connectedKey, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+	idleKey, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	connectedPeer := connectedKey.PublicKey()
+	idlePeer := idleKey.PublicKey()
+
+	ipc := "private_key=" + hex.EncodeToString(connectedKey[:]) + "\n" +
+		"listen_port=51820\n" +
+		"public_key=" + hex.EncodeToString(connectedPeer[:]) + "\n" +
+		"endpoint=10.0.0.1:51820\n" +
+		"last_handshake_time_sec=1700000000\n" +
+		"last_handshake_time_nsec=500\n" +
+		"tx_bytes=1024\n" +
+		"rx_bytes=2048\n" +
+		"persistent_keepalive_interval=25\n" +
+		"allowed_ip=100.64.0.2/32\n" +
+		"public_key=" + hex.EncodeToString(idlePeer[:]) + "\n" +
+		"last_handshake_time_sec=0\n" +
+		"last_handshake_time_nsec=0\n" +
+		"tx_bytes=0\n" +
+		"rx_bytes=0\n" +
+		"errno=0\n"
+
+	stats, err := parseStats(ipc)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+
+	connected := stats[connectedPeer.String()]
+	assert.Equal(t, time.Unix(1700000000, 500), connected.LastHandshake)
+	assert.Equal(t, int64(1024), connected.TxBytes)
+	assert.Equal(t, int64(2048), connected.RxBytes)
+
+	idle := stats[idlePeer.String()]
+	assert.True(t, idle.LastHandshake.IsZero(), "a peer without a handshake shouldn't report one")
+	assert.Zero(t, idle.TxBytes)
+	assert.Zero(t, idle.RxBytes)
+
+	_, err = parseStats("public_key=invalid\n")
+	assert.Error(t, err)
+}
@@ -17,4 +17,6 @@ type Client interface {
 	GetDeviceAuthorizationFlow(serverKey wgtypes.Key) (*proto.DeviceAuthorizationFlow, error)
 	GetPKCEAuthorizationFlow(serverKey wgtypes.Key) (*proto.PKCEAuthorizationFlow, error)
 	GetNetworkMap() (*proto.NetworkMap, error)
+	ReportSSHSessions(events []*proto.SSHSessionEvent) error
+	GetDNSBlockList(blockListID string) (*proto.DNSBlockListDomains, error)
 }
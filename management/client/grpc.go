@@ -22,10 +22,15 @@ import (
 	"github.com/cenkalti/backoff/v4"
 
 	"github.com/netbirdio/netbird/client/system"
+	nbdns "github.com/netbirdio/netbird/dns"
 	"github.com/netbirdio/netbird/encryption"
 	"github.com/netbirdio/netbird/management/proto"
 )
 
+// blockListMaxRecvMsgSize is the maximum size of a DNS block list message, the domains the management service
+// sends are capped at nbdns.MaxBlockListDomainsSize
+const blockListMaxRecvMsgSize = nbdns.MaxBlockListDomainsSize + 64<<10
+
 // ConnStateNotifier is a wrapper interface of the status recorders
 type ConnStateNotifier interface {
 	MarkManagementDisconnected()
@@ -152,6 +157,11 @@ func (c *GrpcClient) Sync(msgHandler func(msg *proto.SyncResponse) error) error
 			case codes.Canceled:
 				log.Debugf("management connection context has been canceled, this usually indicates shutdown")
 				return nil
+			case codes.ResourceExhausted:
+				// the server throttles the peer, keep backing off instead of reconnecting right away
+				c.notifyDisconnected()
+				log.Warnf("disconnected from the Management service because of the rate limits, will retry later")
+				return err
 			default:
 				backOff.Reset() // reset backoff counter after successful connection
 				c.notifyDisconnected()
@@ -400,6 +410,74 @@ func (c *GrpcClient) GetPKCEAuthorizationFlow(serverKey wgtypes.Key) (*proto.PKC
 	return flowInfoResp, nil
 }
 
+// ReportSSHSessions reports the start and the end of the sessions of the peer's SSH server.
+// It also takes care of encrypting the message.
+func (c *GrpcClient) ReportSSHSessions(events []*proto.SSHSessionEvent) error {
+	if !c.ready() {
+		return fmt.Errorf("no connection to management in order to report SSH sessions")
+	}
+
+	serverPubKey, err := c.GetServerPublicKey()
+	if err != nil {
+		log.Debugf("failed getting Management Service public key: %s", err)
+		return err
+	}
+
+	mgmCtx, cancel := context.WithTimeout(c.ctx, time.Second*5)
+	defer cancel()
+
+	message := &proto.SSHSessionEvents{Events: events}
+	encryptedMSG, err := encryption.EncryptMessage(*serverPubKey, c.key, message)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.realClient.ReportSSHSessions(mgmCtx, &proto.EncryptedMessage{
+		WgPubKey: c.key.PublicKey().String(),
+		Body:     encryptedMSG,
+	})
+	return err
+}
+
+// GetDNSBlockList fetches the domains of a DNS block list distributed to the peer.
+// It also takes care of encrypting and decrypting messages.
+func (c *GrpcClient) GetDNSBlockList(blockListID string) (*proto.DNSBlockListDomains, error) {
+	if !c.ready() {
+		return nil, fmt.Errorf("no connection to management in order to get the DNS block list")
+	}
+
+	serverPubKey, err := c.GetServerPublicKey()
+	if err != nil {
+		log.Debugf("failed getting Management Service public key: %s", err)
+		return nil, err
+	}
+
+	mgmCtx, cancel := context.WithTimeout(c.ctx, time.Second*30)
+	defer cancel()
+
+	message := &proto.DNSBlockListRequest{ID: blockListID}
+	encryptedMSG, err := encryption.EncryptMessage(*serverPubKey, c.key, message)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.realClient.GetDNSBlockList(mgmCtx, &proto.EncryptedMessage{
+		WgPubKey: c.key.PublicKey().String(),
+		Body:     encryptedMSG,
+	}, grpc.MaxCallRecvMsgSize(blockListMaxRecvMsgSize))
+	if err != nil {
+		return nil, err
+	}
+
+	blockList := &proto.DNSBlockListDomains{}
+	err = encryption.DecryptMessage(*serverPubKey, c.key, resp.Body, blockList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt DNS block list message: %s", err)
+	}
+
+	return blockList, nil
+}
+
 func (c *GrpcClient) notifyDisconnected() {
 	c.connStateCallbackLock.RLock()
 	defer c.connStateCallbackLock.RUnlock()
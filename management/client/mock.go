@@ -14,6 +14,8 @@ type MockClient struct {
 	LoginFunc                      func(serverKey wgtypes.Key, info *system.Info, sshKey []byte) (*proto.LoginResponse, error)
 	GetDeviceAuthorizationFlowFunc func(serverKey wgtypes.Key) (*proto.DeviceAuthorizationFlow, error)
 	GetPKCEAuthorizationFlowFunc   func(serverKey wgtypes.Key) (*proto.PKCEAuthorizationFlow, error)
+	ReportSSHSessionsFunc          func(events []*proto.SSHSessionEvent) error
+	GetDNSBlockListFunc            func(blockListID string) (*proto.DNSBlockListDomains, error)
 }
 
 func (m *MockClient) Close() error {
@@ -69,3 +71,19 @@ func (m *MockClient) GetPKCEAuthorizationFlow(serverKey wgtypes.Key) (*proto.PKC
 func (m *MockClient) GetNetworkMap() (*proto.NetworkMap, error) {
 	return nil, nil
 }
+
+// ReportSSHSessions mock implementation of ReportSSHSessions from mgm.Client interface
+func (m *MockClient) ReportSSHSessions(events []*proto.SSHSessionEvent) error {
+	if m.ReportSSHSessionsFunc == nil {
+		return nil
+	}
+	return m.ReportSSHSessionsFunc(events)
+}
+
+// GetDNSBlockList mock implementation of GetDNSBlockList from mgm.Client interface
+func (m *MockClient) GetDNSBlockList(blockListID string) (*proto.DNSBlockListDomains, error) {
+	if m.GetDNSBlockListFunc == nil {
+		return nil, nil
+	}
+	return m.GetDNSBlockListFunc(blockListID)
+}
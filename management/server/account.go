@@ -106,6 +106,18 @@ type AccountManager interface {
 	SaveNameServerGroup(accountID, userID string, nsGroupToSave *nbdns.NameServerGroup) error
 	DeleteNameServerGroup(accountID, nsGroupID, userID string) error
 	ListNameServerGroups(accountID string) ([]*nbdns.NameServerGroup, error)
+	GetDNSBlockList(accountID, blockListID string) (*nbdns.BlockList, error)
+	CreateDNSBlockList(accountID, userID string, blockList *nbdns.BlockList) (*nbdns.BlockList, error)
+	SaveDNSBlockList(accountID, userID string, blockListToSave *nbdns.BlockList) error
+	DeleteDNSBlockList(accountID, blockListID, userID string) error
+	ListDNSBlockLists(accountID string) ([]*nbdns.BlockList, error)
+	GetSSHPolicy(accountID, sshPolicyID string) (*SSHPolicy, error)
+	CreateSSHPolicy(accountID, userID string, sshPolicy *SSHPolicy) (*SSHPolicy, error)
+	SaveSSHPolicy(accountID, userID string, sshPolicyToSave *SSHPolicy) error
+	DeleteSSHPolicy(accountID, sshPolicyID, userID string) error
+	ListSSHPolicies(accountID string) ([]*SSHPolicy, error)
+	StoreSSHSessionEvent(peerPubKey string, event SSHSessionEvent) error
+	GetPeerDNSBlockListDomains(peerPubKey, blockListID string) ([]string, string, error)
 	GetDNSDomain() string
 	StoreEvent(initiatorID, targetID, accountID string, activityID activity.Activity, meta map[string]any)
 	GetEvents(accountID, userID string) ([]*activity.Event, error)
@@ -142,6 +154,8 @@ type DefaultAccountManager struct {
 	// dnsDomain is used for peer resolution. This is appended to the peer's name
 	dnsDomain       string
 	peerLoginExpiry Scheduler
+	// blockListRefresh schedules the periodic fetch of the hosted DNS block lists
+	blockListRefresh Scheduler
 
 	// userDeleteFromIDPEnabled allows to delete user from IDP when user is deleted from account
 	userDeleteFromIDPEnabled bool
@@ -169,6 +183,10 @@ type Settings struct {
 	// JWTAllowGroups list of groups to which users are allowed access
 	JWTAllowGroups []string `gorm:"serializer:json"`
 
+	// LazyConnectionEnabled makes the peers connect to each other only when there is traffic between them and
+	// disconnect after they have been idle for a while
+	LazyConnectionEnabled bool
+
 	// Extra is a dictionary of Account settings
 	Extra *account.ExtraSettings `gorm:"embedded;embeddedPrefix:extra_"`
 }
@@ -182,6 +200,7 @@ func (s *Settings) Copy() *Settings {
 		JWTGroupsClaimName:         s.JWTGroupsClaimName,
 		GroupsPropagationEnabled:   s.GroupsPropagationEnabled,
 		JWTAllowGroups:             s.JWTAllowGroups,
+		LazyConnectionEnabled:      s.LazyConnectionEnabled,
 	}
 	if s.Extra != nil {
 		settings.Extra = s.Extra.Copy()
@@ -215,6 +234,10 @@ type Account struct {
 	RoutesG                []route.Route                     `json:"-" gorm:"foreignKey:AccountID;references:id"`
 	NameServerGroups       map[string]*nbdns.NameServerGroup `gorm:"-"`
 	NameServerGroupsG      []nbdns.NameServerGroup           `json:"-" gorm:"foreignKey:AccountID;references:id"`
+	DNSBlockLists          map[string]*nbdns.BlockList       `gorm:"-"`
+	DNSBlockListsG         []nbdns.BlockList                 `json:"-" gorm:"foreignKey:AccountID;references:id"`
+	SSHPolicies            map[string]*SSHPolicy             `gorm:"-"`
+	SSHPoliciesG           []SSHPolicy                       `json:"-" gorm:"foreignKey:AccountID;references:id"`
 	DNSSettings            DNSSettings                       `gorm:"embedded;embeddedPrefix:dns_settings_"`
 	// Settings is a dictionary of Account settings
 	Settings *Settings `gorm:"embedded;embeddedPrefix:settings_"`
@@ -402,15 +425,21 @@ func (a *Account) GetPeerNetworkMap(peerID, dnsDomain string) *NetworkMap {
 		}
 		dnsUpdate.CustomZones = zones
 		dnsUpdate.NameServerGroups = getPeerNSGroups(a, peerID)
+		dnsUpdate.BlockLists = getPeerBlockLists(a, peerID)
 	}
 
+	sshAuthorizedUsers, sshAuthorizeUsers := getPeerSSHAuthorizedUsers(a, peerID, peersToConnect)
+
 	return &NetworkMap{
-		Peers:         peersToConnect,
-		Network:       a.Network.Copy(),
-		Routes:        routesUpdate,
-		DNSConfig:     dnsUpdate,
-		OfflinePeers:  expiredPeers,
-		FirewallRules: firewallRules,
+		Peers:                 peersToConnect,
+		Network:               a.Network.Copy(),
+		Routes:                routesUpdate,
+		DNSConfig:             dnsUpdate,
+		OfflinePeers:          expiredPeers,
+		FirewallRules:         firewallRules,
+		SSHAuthorizedUsers:    sshAuthorizedUsers,
+		SSHAuthorizeUsers:     sshAuthorizeUsers,
+		LazyConnectionEnabled: a.Settings != nil && a.Settings.LazyConnectionEnabled,
 	}
 }
 
@@ -654,6 +683,16 @@ func (a *Account) Copy() *Account {
 		nsGroups[id] = nsGroup.Copy()
 	}
 
+	blockLists := map[string]*nbdns.BlockList{}
+	for id, blockList := range a.DNSBlockLists {
+		blockLists[id] = blockList.Copy()
+	}
+
+	sshPolicies := map[string]*SSHPolicy{}
+	for id, sshPolicy := range a.SSHPolicies {
+		sshPolicies[id] = sshPolicy.Copy()
+	}
+
 	dnsSettings := a.DNSSettings.Copy()
 
 	var settings *Settings
@@ -676,6 +715,8 @@ func (a *Account) Copy() *Account {
 		Policies:               policies,
 		Routes:                 routes,
 		NameServerGroups:       nsGroups,
+		DNSBlockLists:          blockLists,
+		SSHPolicies:            sshPolicies,
 		DNSSettings:            dnsSettings,
 		Settings:               settings,
 	}
@@ -815,6 +856,7 @@ func BuildManager(store Store, peersUpdateManager *PeersUpdateManager, idpManage
 		dnsDomain:                dnsDomain,
 		eventStore:               eventStore,
 		peerLoginExpiry:          NewDefaultScheduler(),
+		blockListRefresh:         NewDefaultScheduler(),
 		userDeleteFromIDPEnabled: userDeleteFromIDPEnabled,
 	}
 	allAccounts := store.GetAllAccounts()
@@ -850,6 +892,8 @@ func BuildManager(store Store, peersUpdateManager *PeersUpdateManager, idpManage
 				return nil, err
 			}
 		}
+
+		am.scheduleAccountDNSBlockListsRefresh(account)
 	}
 
 	goCacheClient := gocache.New(CacheExpirationMax, 30*time.Minute)
@@ -932,6 +976,15 @@ func (am *DefaultAccountManager) UpdateAccountSettings(accountID, userID string,
 		am.checkAndSchedulePeerLoginExpiration(account)
 	}
 
+	lazyConnectionUpdated := oldSettings.LazyConnectionEnabled != newSettings.LazyConnectionEnabled
+	if lazyConnectionUpdated {
+		event := activity.AccountLazyConnectionEnabled
+		if !newSettings.LazyConnectionEnabled {
+			event = activity.AccountLazyConnectionDisabled
+		}
+		am.StoreEvent(userID, accountID, accountID, event, nil)
+	}
+
 	updatedAccount := account.UpdateSettings(newSettings)
 
 	err = am.Store.SaveAccount(account)
@@ -939,6 +992,10 @@ func (am *DefaultAccountManager) UpdateAccountSettings(accountID, userID string,
 		return nil, err
 	}
 
+	if lazyConnectionUpdated {
+		am.updateAccountPeers(account)
+	}
+
 	return updatedAccount, nil
 }
 
@@ -1088,6 +1145,10 @@ func (am *DefaultAccountManager) DeleteAccount(accountID, userID string) error {
 	}
 	// cancel peer login expiry job
 	am.peerLoginExpiry.Cancel([]string{account.Id})
+	// cancel DNS block lists refresh jobs
+	for blockListID := range account.DNSBlockLists {
+		am.blockListRefresh.Cancel([]string{blockListID})
+	}
 
 	log.Debugf("account %s deleted", accountID)
 	return nil
@@ -1794,6 +1855,8 @@ func newAccountWithId(accountID, userID, domain string) *Account {
 	routes := make(map[string]*route.Route)
 	setupKeys := map[string]*SetupKey{}
 	nameServersGroups := make(map[string]*nbdns.NameServerGroup)
+	blockLists := make(map[string]*nbdns.BlockList)
+	sshPolicies := make(map[string]*SSHPolicy)
 	users[userID] = NewOwnerUser(userID)
 	dnsSettings := DNSSettings{
 		DisabledManagementGroups: make([]string, 0),
@@ -1810,6 +1873,8 @@ func newAccountWithId(accountID, userID, domain string) *Account {
 		Domain:           domain,
 		Routes:           routes,
 		NameServerGroups: nameServersGroups,
+		DNSBlockLists:    blockLists,
+		SSHPolicies:      sshPolicies,
 		DNSSettings:      dnsSettings,
 		Settings: &Settings{
 			PeerLoginExpirationEnabled: true,
@@ -1557,6 +1557,24 @@ func TestAccount_Copy(t *testing.T) {
 				NameServers: []nbdns.NameServer{},
 			},
 		},
+		DNSBlockLists: map[string]*nbdns.BlockList{
+			"blockList1": {
+				ID:           "blockList1",
+				Domains:      []string{},
+				URLs:         []string{},
+				AllowDomains: []string{},
+				Groups:       []string{},
+			},
+		},
+		SSHPolicies: map[string]*SSHPolicy{
+			"sshPolicy1": {
+				ID:           "sshPolicy1",
+				SourceUsers:  []string{},
+				SourceGroups: []string{},
+				Destinations: []string{},
+				LocalUsers:   []string{},
+			},
+		},
 		DNSSettings: DNSSettings{DisabledManagementGroups: []string{}},
 		Settings:    &Settings{},
 	}
@@ -1622,7 +1640,7 @@ func TestDefaultAccountManager_UpdatePeer_PeerLoginExpiration(t *testing.T) {
 	account, err := manager.GetAccountByUserOrAccountID(userID, "", "")
 	require.NoError(t, err, "unable to create an account")
 
-	key, err := wgtypes.GenerateKey()
+	key, err := wgtypes.GeneratePrivateKey()
 	require.NoError(t, err, "unable to generate WireGuard key")
 	peer, _, err := manager.AddPeer("", userID, &nbpeer.Peer{
 		Key:                    key.PublicKey().String(),
@@ -1792,6 +1810,46 @@ func TestDefaultAccountManager_UpdateAccountSettings(t *testing.T) {
 	require.Error(t, err, "expecting to fail when providing PeerLoginExpiration more than 180 days")
 }
 
+func TestDefaultAccountManager_UpdateAccountSettings_LazyConnection(t *testing.T) {
+	manager, err := createManager(t)
+	require.NoError(t, err, "unable to create account manager")
+
+	account, err := manager.GetAccountByUserOrAccountID(userID, "", "")
+	require.NoError(t, err, "unable to create an account")
+
+	key, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err, "unable to generate WireGuard key")
+	peer, _, err := manager.AddPeer("", userID, &nbpeer.Peer{
+		Key:  key.PublicKey().String(),
+		Meta: nbpeer.PeerSystemMeta{Hostname: "test-peer"},
+	})
+	require.NoError(t, err, "unable to add peer")
+
+	updMsg := manager.peersUpdateManager.CreateChannel(peer.ID)
+	t.Cleanup(func() {
+		manager.peersUpdateManager.CloseChannel(peer.ID)
+	})
+
+	updated, err := manager.UpdateAccountSettings(account.Id, userID, &Settings{
+		PeerLoginExpiration:   time.Hour,
+		LazyConnectionEnabled: true,
+	})
+	require.NoError(t, err, "expecting to update account settings successfully but got error")
+	assert.True(t, updated.Settings.LazyConnectionEnabled)
+
+	select {
+	case msg := <-updMsg:
+		assert.True(t, msg.Update.GetNetworkMap().GetPeerConfig().GetLazyConnectionEnabled(),
+			"the peers should be updated with the setting")
+	case <-time.After(time.Second):
+		t.Fatal("the peers weren't updated after the lazy connection setting changed")
+	}
+
+	account, err = manager.GetAccountByUserOrAccountID("", account.Id, "")
+	require.NoError(t, err, "unable to get account by ID")
+	assert.True(t, account.GetPeerNetworkMap(peer.ID, "netbird.io").LazyConnectionEnabled)
+}
+
 func TestAccount_GetExpiredPeers(t *testing.T) {
 	type test struct {
 		name          string
@@ -130,6 +130,30 @@ const (
 	PeerApprovalRevoked
 	// TransferredOwnerRole indicates that the user transferred the owner role of the account
 	TransferredOwnerRole
+	// DNSBlockListCreated indicates that a user created a DNS block list
+	DNSBlockListCreated
+	// DNSBlockListUpdated indicates that a user updated a DNS block list
+	DNSBlockListUpdated
+	// DNSBlockListDeleted indicates that a user deleted a DNS block list
+	DNSBlockListDeleted
+	// PeerSSHPortForwardingEnabled indicates that a user enabled SSH port forwarding on a peer
+	PeerSSHPortForwardingEnabled
+	// PeerSSHPortForwardingDisabled indicates that a user disabled SSH port forwarding on a peer
+	PeerSSHPortForwardingDisabled
+	// SSHPolicyCreated indicates that a user created an SSH policy
+	SSHPolicyCreated
+	// SSHPolicyUpdated indicates that a user updated an SSH policy
+	SSHPolicyUpdated
+	// SSHPolicyDeleted indicates that a user deleted an SSH policy
+	SSHPolicyDeleted
+	// PeerSSHSessionStarted indicates that a user started an SSH session on a peer
+	PeerSSHSessionStarted
+	// PeerSSHSessionEnded indicates that an SSH session of a user on a peer ended
+	PeerSSHSessionEnded
+	// AccountLazyConnectionEnabled indicates that a user enabled lazy peer connections for the account
+	AccountLazyConnectionEnabled
+	// AccountLazyConnectionDisabled indicates that a user disabled lazy peer connections for the account
+	AccountLazyConnectionDisabled
 )
 
 var activityMap = map[Activity]Code{
@@ -193,6 +217,18 @@ var activityMap = map[Activity]Code{
 	PeerApproved:                              {"Peer approved", "peer.approve"},
 	PeerApprovalRevoked:                       {"Peer approval revoked", "peer.approval.revoke"},
 	TransferredOwnerRole:                      {"Transferred owner role", "transferred.owner.role"},
+	DNSBlockListCreated:                       {"DNS block list created", "dns.blocklist.add"},
+	DNSBlockListUpdated:                       {"DNS block list updated", "dns.blocklist.update"},
+	DNSBlockListDeleted:                       {"DNS block list deleted", "dns.blocklist.delete"},
+	PeerSSHPortForwardingEnabled:              {"Peer SSH port forwarding enabled", "peer.ssh.port.forwarding.enable"},
+	PeerSSHPortForwardingDisabled:             {"Peer SSH port forwarding disabled", "peer.ssh.port.forwarding.disable"},
+	SSHPolicyCreated:                          {"SSH policy created", "ssh.policy.add"},
+	SSHPolicyUpdated:                          {"SSH policy updated", "ssh.policy.update"},
+	SSHPolicyDeleted:                          {"SSH policy deleted", "ssh.policy.delete"},
+	PeerSSHSessionStarted:                     {"Peer SSH session started", "peer.ssh.session.start"},
+	PeerSSHSessionEnded:                       {"Peer SSH session ended", "peer.ssh.session.end"},
+	AccountLazyConnectionEnabled:              {"Account lazy peer connections enabled", "account.setting.lazy.connection.enable"},
+	AccountLazyConnectionDisabled:             {"Account lazy peer connections disabled", "account.setting.lazy.connection.disable"},
 }
 
 // StringCode returns a string code of the activity
@@ -0,0 +1,739 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/miekg/dns"
+	"github.com/rs/xid"
+	log "github.com/sirupsen/logrus"
+
+	nbdns "github.com/netbirdio/netbird/dns"
+	"github.com/netbirdio/netbird/management/server/activity"
+	"github.com/netbirdio/netbird/management/server/status"
+)
+
+const (
+	// blockListRefreshInterval is the interval between fetches of the hosted block lists
+	blockListRefreshInterval = 24 * time.Hour
+	// blockListRetryInterval is the interval to retry a failed fetch of the hosted block lists
+	blockListRetryInterval = time.Hour
+	blockListFetchTimeout  = 30 * time.Second
+	// maxBlockListSize is the maximum size in bytes of a single hosted block list
+	maxBlockListSize = 32 << 20
+	// maxBlockListDomains is the maximum number of domains fetched for a block list
+	maxBlockListDomains = 500000
+	// maxBlockListRedirects is the maximum number of redirects followed when fetching a hosted block list
+	maxBlockListRedirects = 10
+)
+
+// blockListDeniedPrefixes are the non-public ranges not covered by the netip.Addr predicates
+var blockListDeniedPrefixes = []netip.Prefix{
+	// "this" network
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// shared address space, it holds cloud metadata endpoints too
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// newBlockListHTTPClient returns the client fetching the hosted block lists. The URLs are set by the users,
+// so the client only connects to public addresses over HTTPS, redirects included, and doesn't reach the management
+// host, its internal network or the cloud metadata endpoints
+var newBlockListHTTPClient = func() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: blockListFetchTimeout,
+		Control: blockListDialControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would make the dialer check the address of the proxy instead of the address of the list
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:       blockListFetchTimeout,
+		Transport:     transport,
+		CheckRedirect: checkBlockListRedirect,
+	}
+}
+
+// GetDNSBlockList gets a DNS block list object from account and block list IDs
+func (am *DefaultAccountManager) GetDNSBlockList(accountID, blockListID string) (*nbdns.BlockList, error) {
+
+	unlock := am.Store.AcquireAccountLock(accountID)
+	defer unlock()
+
+	account, err := am.Store.GetAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	blockList, found := account.DNSBlockLists[blockListID]
+	if found {
+		blockList = blockList.Copy()
+		blockList.InactivePeers = blockListInactivePeers(account, blockList)
+		return blockList, nil
+	}
+
+	return nil, status.Errorf(status.NotFound, "DNS block list with ID %s not found", blockListID)
+}
+
+// CreateDNSBlockList creates and saves a new DNS block list. The hosted lists are fetched in the background
+func (am *DefaultAccountManager) CreateDNSBlockList(accountID, userID string, blockList *nbdns.BlockList) (*nbdns.BlockList, error) {
+
+	unlock := am.Store.AcquireAccountLock(accountID)
+	defer unlock()
+
+	if blockList == nil {
+		return nil, status.Errorf(status.InvalidArgument, "DNS block list provided is nil")
+	}
+
+	account, err := am.Store.GetAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	newBlockList := blockList.Copy()
+	newBlockList.ID = xid.New().String()
+	newBlockList.FetchedDomainsCount = 0
+	newBlockList.LastFetched = time.Time{}
+
+	err = validateDNSBlockList(false, newBlockList, account)
+	if err != nil {
+		return nil, err
+	}
+	newBlockList.DomainsHash = blockListDomainsHash(mergeBlockListDomains(newBlockList.Domains, nil))
+
+	if account.DNSBlockLists == nil {
+		account.DNSBlockLists = make(map[string]*nbdns.BlockList)
+	}
+
+	account.DNSBlockLists[newBlockList.ID] = newBlockList
+
+	account.Network.IncSerial()
+	err = am.Store.SaveAccount(account)
+	if err != nil {
+		return nil, err
+	}
+
+	am.updateAccountPeers(account)
+
+	am.StoreEvent(userID, newBlockList.ID, accountID, activity.DNSBlockListCreated, newBlockList.EventMeta())
+
+	am.scheduleDNSBlockListRefresh(accountID, newBlockList, 0)
+
+	createdBlockList := newBlockList.Copy()
+	createdBlockList.InactivePeers = blockListInactivePeers(account, createdBlockList)
+	return createdBlockList, nil
+}
+
+// SaveDNSBlockList saves a DNS block list. The previously fetched domains are kept if the URLs didn't change
+func (am *DefaultAccountManager) SaveDNSBlockList(accountID, userID string, blockListToSave *nbdns.BlockList) error {
+
+	unlock := am.Store.AcquireAccountLock(accountID)
+	defer unlock()
+
+	if blockListToSave == nil {
+		return status.Errorf(status.InvalidArgument, "DNS block list provided is nil")
+	}
+
+	account, err := am.Store.GetAccount(accountID)
+	if err != nil {
+		return err
+	}
+
+	err = validateDNSBlockList(true, blockListToSave, account)
+	if err != nil {
+		return err
+	}
+
+	blockList := blockListToSave.Copy()
+	existing := account.DNSBlockLists[blockList.ID]
+	urlsChanged := !slices.Equal(existing.URLs, blockList.URLs)
+
+	var fetchedDomains []string
+	if urlsChanged {
+		blockList.FetchedDomainsCount = 0
+		blockList.LastFetched = time.Time{}
+	} else {
+		blockList.FetchedDomainsCount = existing.FetchedDomainsCount
+		blockList.LastFetched = existing.LastFetched
+
+		fetchedDomains, err = am.Store.GetDNSBlockListDomains(accountID, blockList.ID)
+		if err != nil {
+			return status.Errorf(status.Internal, "failed getting the fetched domains of DNS block list %s", blockList.ID)
+		}
+	}
+	blockList.DomainsHash = blockListDomainsHash(mergeBlockListDomains(blockList.Domains, fetchedDomains))
+
+	account.DNSBlockLists[blockList.ID] = blockList
+
+	account.Network.IncSerial()
+	err = am.Store.SaveAccount(account)
+	if err != nil {
+		return err
+	}
+
+	am.updateAccountPeers(account)
+
+	am.StoreEvent(userID, blockList.ID, accountID, activity.DNSBlockListUpdated, blockList.EventMeta())
+
+	if urlsChanged {
+		am.deleteDNSBlockListDomains(accountID, blockList.ID)
+		am.scheduleDNSBlockListRefresh(accountID, blockList, 0)
+	}
+
+	return nil
+}
+
+// DeleteDNSBlockList deletes the DNS block list with blockListID
+func (am *DefaultAccountManager) DeleteDNSBlockList(accountID, blockListID, userID string) error {
+
+	unlock := am.Store.AcquireAccountLock(accountID)
+	defer unlock()
+
+	account, err := am.Store.GetAccount(accountID)
+	if err != nil {
+		return err
+	}
+
+	blockList := account.DNSBlockLists[blockListID]
+	if blockList == nil {
+		return status.Errorf(status.NotFound, "DNS block list %s wasn't found", blockListID)
+	}
+	delete(account.DNSBlockLists, blockListID)
+
+	account.Network.IncSerial()
+	err = am.Store.SaveAccount(account)
+	if err != nil {
+		return err
+	}
+
+	am.updateAccountPeers(account)
+
+	am.StoreEvent(userID, blockList.ID, accountID, activity.DNSBlockListDeleted, blockList.EventMeta())
+
+	if am.blockListRefresh != nil {
+		am.blockListRefresh.Cancel([]string{blockListID})
+	}
+
+	am.deleteDNSBlockListDomains(accountID, blockListID)
+
+	return nil
+}
+
+// ListDNSBlockLists returns a list of DNS block lists from account
+func (am *DefaultAccountManager) ListDNSBlockLists(accountID string) ([]*nbdns.BlockList, error) {
+
+	unlock := am.Store.AcquireAccountLock(accountID)
+	defer unlock()
+
+	account, err := am.Store.GetAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	blockLists := make([]*nbdns.BlockList, 0, len(account.DNSBlockLists))
+	for _, item := range account.DNSBlockLists {
+		blockList := item.Copy()
+		blockList.InactivePeers = blockListInactivePeers(account, blockList)
+		blockLists = append(blockLists, blockList)
+	}
+
+	return blockLists, nil
+}
+
+// GetPeerDNSBlockListDomains returns the inline and fetched domains of a DNS block list and their hash.
+// Only the enabled block lists distributed to the peer with peerPubKey are returned
+func (am *DefaultAccountManager) GetPeerDNSBlockListDomains(peerPubKey, blockListID string) ([]string, string, error) {
+	account, err := am.Store.GetAccountByPeerPubKey(peerPubKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	peer, err := account.FindPeerByPubKey(peerPubKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var blockList *nbdns.BlockList
+	for _, peerBlockList := range getPeerBlockLists(account, peer.ID) {
+		if peerBlockList.ID == blockListID {
+			blockList = peerBlockList
+			break
+		}
+	}
+	if blockList == nil {
+		return nil, "", status.Errorf(status.NotFound, "DNS block list %s not found for peer %s", blockListID, peer.ID)
+	}
+
+	fetchedDomains, err := am.Store.GetDNSBlockListDomains(account.Id, blockListID)
+	if err != nil {
+		return nil, "", status.Errorf(status.Internal, "failed getting the fetched domains of DNS block list %s", blockListID)
+	}
+
+	domains := mergeBlockListDomains(blockList.Domains, fetchedDomains)
+	return domains, blockListDomainsHash(domains), nil
+}
+
+// deleteDNSBlockListDomains deletes the fetched domains of a block list that were replaced or whose block list was deleted
+func (am *DefaultAccountManager) deleteDNSBlockListDomains(accountID, blockListID string) {
+	err := am.Store.DeleteDNSBlockListDomains(accountID, blockListID)
+	if err != nil {
+		log.Warnf("failed deleting the fetched domains of DNS block list %s of account %s: %v", blockListID, accountID, err)
+	}
+}
+
+// scheduleDNSBlockListRefresh schedules a periodic fetch of the hosted lists of the block list.
+// A pending refresh of the block list is replaced.
+func (am *DefaultAccountManager) scheduleDNSBlockListRefresh(accountID string, blockList *nbdns.BlockList, in time.Duration) {
+	if am.blockListRefresh == nil {
+		return
+	}
+
+	am.blockListRefresh.Cancel([]string{blockList.ID})
+	if len(blockList.URLs) == 0 {
+		return
+	}
+
+	go am.blockListRefresh.Schedule(in, blockList.ID, am.dnsBlockListRefreshJob(accountID, blockList.ID))
+}
+
+// dnsBlockListRefreshJob fetches the hosted lists of a block list and distributes the fetched domains to the peers
+func (am *DefaultAccountManager) dnsBlockListRefreshJob(accountID, blockListID string) func() (time.Duration, bool) {
+	return func() (time.Duration, bool) {
+		blockList, err := am.GetDNSBlockList(accountID, blockListID)
+		if err != nil {
+			log.Debugf("stop refreshing DNS block list %s of account %s: %v", blockListID, accountID, err)
+			return 0, false
+		}
+
+		if len(blockList.URLs) == 0 {
+			return 0, false
+		}
+
+		ctx, cancel := context.WithTimeout(am.ctx, blockListFetchTimeout*time.Duration(len(blockList.URLs)))
+		defer cancel()
+
+		domains, err := fetchDNSBlockListDomains(ctx, blockList.URLs)
+		if err != nil {
+			log.Errorf("failed fetching DNS block list %s of account %s: %v", blockListID, accountID, err)
+			return blockListRetryInterval, true
+		}
+
+		unlock := am.Store.AcquireAccountLock(accountID)
+		defer unlock()
+
+		account, err := am.Store.GetAccount(accountID)
+		if err != nil {
+			log.Errorf("failed getting account %s while refreshing DNS block list %s: %v", accountID, blockListID, err)
+			return blockListRetryInterval, true
+		}
+
+		current, found := account.DNSBlockLists[blockListID]
+		if !found || !slices.Equal(current.URLs, blockList.URLs) {
+			// the block list was deleted or its URLs were changed, a new job takes over
+			return 0, false
+		}
+
+		err = am.Store.SaveDNSBlockListDomains(accountID, blockListID, domains)
+		if err != nil {
+			log.Errorf("failed saving the fetched domains of DNS block list %s of account %s: %v", blockListID, accountID, err)
+			return blockListRetryInterval, true
+		}
+
+		domainsHash := blockListDomainsHash(mergeBlockListDomains(current.Domains, domains))
+		domainsChanged := domainsHash != current.DomainsHash
+
+		current.FetchedDomainsCount = len(domains)
+		current.DomainsHash = domainsHash
+		current.LastFetched = time.Now().UTC()
+
+		if domainsChanged {
+			account.Network.IncSerial()
+		}
+		err = am.Store.SaveAccount(account)
+		if err != nil {
+			log.Errorf("failed saving account %s while refreshing DNS block list %s: %v", accountID, blockListID, err)
+			return blockListRetryInterval, true
+		}
+
+		if domainsChanged {
+			am.updateAccountPeers(account)
+		}
+
+		log.Debugf("fetched %d domains for DNS block list %s of account %s", len(domains), blockListID, accountID)
+
+		return blockListRefreshInterval, true
+	}
+}
+
+// scheduleAccountDNSBlockListsRefresh schedules the refresh of all hosted block lists of the account.
+// Lists that were never fetched or whose last fetch is older than the refresh interval are fetched right away.
+func (am *DefaultAccountManager) scheduleAccountDNSBlockListsRefresh(account *Account) {
+	for _, blockList := range account.DNSBlockLists {
+		in := blockListRefreshInterval - time.Since(blockList.LastFetched)
+		if in < 0 {
+			in = 0
+		}
+		am.scheduleDNSBlockListRefresh(account.Id, blockList, in)
+	}
+}
+
+// fetchDNSBlockListDomains downloads the hosted block lists and returns the deduplicated list of their domains.
+// The domains are capped at maxBlockListDomains and at the size the peers accept
+func fetchDNSBlockListDomains(ctx context.Context, urls []string) ([]string, error) {
+	client := newBlockListHTTPClient()
+
+	seen := make(map[string]struct{})
+	domains := make([]string, 0)
+	size := 0
+	for _, listURL := range urls {
+		fetched, err := fetchDNSBlockList(ctx, client, listURL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, domain := range fetched {
+			if _, ok := seen[domain]; ok {
+				continue
+			}
+			if len(domains) >= maxBlockListDomains {
+				log.Warnf("DNS block list exceeds %d domains, ignoring the remaining domains", maxBlockListDomains)
+				return domains, nil
+			}
+			size += nbdns.BlockListDomainsSize([]string{domain})
+			if size > nbdns.MaxBlockListDomainsSize {
+				log.Warnf("DNS block list exceeds %d bytes, ignoring the remaining domains", nbdns.MaxBlockListDomainsSize)
+				return domains, nil
+			}
+			seen[domain] = struct{}{}
+			domains = append(domains, domain)
+		}
+	}
+
+	return domains, nil
+}
+
+// blockListDialControl refuses connections to non-public addresses. It runs after the name resolution,
+// for every address a connection is attempted to
+func blockListDialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("parse address %s: %w", address, err)
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("parse address %s: %w", address, err)
+	}
+
+	if !isPublicBlockListAddr(addr) {
+		return fmt.Errorf("address %s is not public", addr)
+	}
+	return nil
+}
+
+func isPublicBlockListAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() ||
+		addr.IsMulticast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() {
+		return false
+	}
+
+	for _, prefix := range blockListDeniedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkBlockListRedirect only follows redirects to HTTPS URLs, the dialer checks the address of the target
+func checkBlockListRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxBlockListRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxBlockListRedirects)
+	}
+	if req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to %s refused, only https URLs are allowed", req.URL.Redacted())
+	}
+	return nil
+}
+
+// mergeBlockListDomains returns the inline domains followed by the fetched domains of a block list.
+// The fetched domains that don't fit in the size the peers accept next to the inline domains are left out
+func mergeBlockListDomains(inline, fetched []string) []string {
+	domains := make([]string, 0, len(inline)+len(fetched))
+	domains = append(domains, inline...)
+
+	size := nbdns.BlockListDomainsSize(inline)
+	for _, domain := range fetched {
+		size += nbdns.BlockListDomainsSize([]string{domain})
+		if size > nbdns.MaxBlockListDomainsSize {
+			break
+		}
+		domains = append(domains, domain)
+	}
+	return domains
+}
+
+// blockListDomainsHash returns the hash of the domains of a block list sent to the peers
+func blockListDomainsHash(domains []string) string {
+	hash := sha256.New()
+	for _, domain := range domains {
+		_, _ = io.WriteString(hash, domain)
+		_, _ = hash.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func fetchDNSBlockList(ctx context.Context, client *http.Client, listURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, listURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request for %s: %w", listURL, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", listURL, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: unexpected status code %d", listURL, resp.StatusCode)
+	}
+
+	return parseDNSBlockList(io.LimitReader(resp.Body, maxBlockListSize))
+}
+
+// parseDNSBlockList parses a block list in hosts file format, domain-per-line format or adblock "||domain^" format.
+// Comments and entries that are not valid domains are ignored
+func parseDNSBlockList(reader io.Reader) ([]string, error) {
+	var domains []string
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		for _, entry := range parseDNSBlockListLine(scanner.Text()) {
+			domain := nbdns.NormalizeBlockListDomain(entry)
+			if isBlockListDomainIgnored(domain) {
+				continue
+			}
+			if labels, valid := dns.IsDomainName(domain); !valid || labels < 2 {
+				continue
+			}
+			domains = append(domains, domain)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read block list: %w", err)
+	}
+
+	return domains, nil
+}
+
+func parseDNSBlockListLine(line string) []string {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "!") || strings.HasPrefix(line, "[") {
+		return nil
+	}
+
+	if i := strings.Index(line, "#"); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+
+	if strings.HasPrefix(line, "||") {
+		domain, options, found := strings.Cut(strings.TrimPrefix(line, "||"), "^")
+		if !found || options != "" {
+			return nil
+		}
+		return []string{domain}
+	}
+
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 0:
+		return nil
+	case net.ParseIP(fields[0]) != nil:
+		return fields[1:]
+	case len(fields) == 1:
+		return fields
+	default:
+		return nil
+	}
+}
+
+func isBlockListDomainIgnored(domain string) bool {
+	switch domain {
+	case "localhost", "localhost.localdomain", "local", "broadcasthost", "ip6-localhost", "ip6-loopback":
+		return true
+	default:
+		return false
+	}
+}
+
+func validateDNSBlockList(existingBlockList bool, blockList *nbdns.BlockList, account *Account) error {
+	blockListID := ""
+	if existingBlockList {
+		blockListID = blockList.ID
+		_, found := account.DNSBlockLists[blockListID]
+		if !found {
+			return status.Errorf(status.NotFound, "DNS block list with ID %s was not found", blockListID)
+		}
+	}
+
+	err := validateDNSBlockListName(blockList.Name, blockListID, account.DNSBlockLists)
+	if err != nil {
+		return err
+	}
+
+	if !blockList.Response.IsValid() {
+		return status.Errorf(status.InvalidArgument, "invalid DNS block list response %q, it should be %s or %s",
+			blockList.Response, nbdns.BlockResponseNXDomain, nbdns.BlockResponseZero)
+	}
+
+	if len(blockList.Domains) == 0 && len(blockList.URLs) == 0 {
+		return status.Errorf(status.InvalidArgument, "DNS block list should have at least one domain or URL")
+	}
+
+	blockList.Domains, err = normalizeBlockListDomains(blockList.Domains)
+	if err != nil {
+		return err
+	}
+
+	if nbdns.BlockListDomainsSize(blockList.Domains) > nbdns.MaxBlockListDomainsSize {
+		return status.Errorf(status.InvalidArgument, "DNS block list domains exceed %d bytes", nbdns.MaxBlockListDomainsSize)
+	}
+
+	blockList.AllowDomains, err = normalizeBlockListDomains(blockList.AllowDomains)
+	if err != nil {
+		return err
+	}
+
+	for _, listURL := range blockList.URLs {
+		parsed, err := url.Parse(listURL)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return status.Errorf(status.InvalidArgument, "DNS block list got an invalid URL %s, it should be an https URL", listURL)
+		}
+	}
+
+	return validateGroups(blockList.Groups, account.Groups)
+}
+
+func validateDNSBlockListName(name, blockListID string, blockLists map[string]*nbdns.BlockList) error {
+	if utf8.RuneCountInString(name) > nbdns.MaxGroupNameChar || name == "" {
+		return status.Errorf(status.InvalidArgument, "DNS block list name should be between 1 and %d", nbdns.MaxGroupNameChar)
+	}
+
+	for _, blockList := range blockLists {
+		if name == blockList.Name && blockList.ID != blockListID {
+			return status.Errorf(status.InvalidArgument, "a DNS block list with name %s already exist", name)
+		}
+	}
+
+	return nil
+}
+
+func normalizeBlockListDomains(domains []string) ([]string, error) {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = nbdns.NormalizeBlockListDomain(domain)
+		if err := validateDomain(strings.TrimPrefix(domain, nbdns.WildcardPrefix)); err != nil {
+			return nil, status.Errorf(status.InvalidArgument, "DNS block list got an invalid domain: %s %q", domain, err)
+		}
+		normalized = append(normalized, domain)
+	}
+	return normalized, nil
+}
+
+// getPeerBlockLists returns the enabled DNS block lists distributed to the peer
+func getPeerBlockLists(account *Account, peerID string) []*nbdns.BlockList {
+	groupList := account.getPeerGroups(peerID)
+
+	var peerBlockLists []*nbdns.BlockList
+
+	for _, blockList := range account.DNSBlockLists {
+		if !blockList.Enabled {
+			continue
+		}
+
+		for _, gID := range blockList.Groups {
+			_, found := groupList[gID]
+			if found {
+				peerBlockLists = append(peerBlockLists, blockList.Copy())
+				break
+			}
+		}
+	}
+
+	return peerBlockLists
+}
+
+// blockListInactivePeers returns the IDs of the peers of the block list groups which only send the queries for their
+// custom zones and nameserver groups to the NetBird DNS server: their DNS management is disabled or they don't have
+// a primary nameserver group. The host resolves the other domains and the block list doesn't apply to them. Android
+// peers send all the queries to the NetBird DNS server
+func blockListInactivePeers(account *Account, blockList *nbdns.BlockList) []string {
+	if !blockList.Enabled {
+		return nil
+	}
+
+	inactivePeers := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, groupID := range blockList.Groups {
+		group, found := account.Groups[groupID]
+		if !found {
+			continue
+		}
+
+		for _, peerID := range group.Peers {
+			if _, ok := seen[peerID]; ok {
+				continue
+			}
+			seen[peerID] = struct{}{}
+
+			peer := account.GetPeer(peerID)
+			if peer == nil {
+				continue
+			}
+			if isBlockListActive(account, peer.ID, peer.Meta.GoOS) {
+				continue
+			}
+			inactivePeers = append(inactivePeers, peer.ID)
+		}
+	}
+
+	slices.Sort(inactivePeers)
+	return inactivePeers
+}
+
+// isBlockListActive returns true if the block lists distributed to the peer apply to all of its DNS queries
+func isBlockListActive(account *Account, peerID, goOS string) bool {
+	if !account.getPeerDNSManagementStatus(peerID) {
+		return false
+	}
+	if goOS == "android" {
+		return true
+	}
+	for _, nsGroup := range getPeerNSGroups(account, peerID) {
+		if nsGroup.Primary && len(nsGroup.NameServers) > 0 {
+			return true
+		}
+	}
+	return false
+}
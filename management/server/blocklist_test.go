@@ -0,0 +1,395 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nbdns "github.com/netbirdio/netbird/dns"
+	nbpeer "github.com/netbirdio/netbird/management/server/peer"
+)
+
+func TestCreateDNSBlockList(t *testing.T) {
+	testCases := []struct {
+		name      string
+		blockList *nbdns.BlockList
+		errFunc   require.ErrorAssertionFunc
+	}{
+		{
+			name: "Create A Block List With Domains",
+			blockList: &nbdns.BlockList{
+				Name:     "ads",
+				Domains:  []string{"Ads.Example.com.", "*.tracker.example.com"},
+				Response: nbdns.BlockResponseNXDomain,
+				Groups:   []string{group1ID},
+				Enabled:  true,
+			},
+			errFunc: require.NoError,
+		},
+		{
+			name: "Should Not Create If Name Is Empty",
+			blockList: &nbdns.BlockList{
+				Domains:  []string{validDomain},
+				Response: nbdns.BlockResponseNXDomain,
+				Groups:   []string{group1ID},
+			},
+			errFunc: require.Error,
+		},
+		{
+			name: "Should Not Create Without Domains And URLs",
+			blockList: &nbdns.BlockList{
+				Name:     "ads",
+				Response: nbdns.BlockResponseNXDomain,
+				Groups:   []string{group1ID},
+			},
+			errFunc: require.Error,
+		},
+		{
+			name: "Should Not Create If Response Is Invalid",
+			blockList: &nbdns.BlockList{
+				Name:     "ads",
+				Domains:  []string{validDomain},
+				Response: "refused",
+				Groups:   []string{group1ID},
+			},
+			errFunc: require.Error,
+		},
+		{
+			name: "Should Not Create If Domain Is Invalid",
+			blockList: &nbdns.BlockList{
+				Name:     "ads",
+				Domains:  []string{invalidDomain},
+				Response: nbdns.BlockResponseZero,
+				Groups:   []string{group1ID},
+			},
+			errFunc: require.Error,
+		},
+		{
+			name: "Should Not Create If URL Is Invalid",
+			blockList: &nbdns.BlockList{
+				Name:     "ads",
+				URLs:     []string{"ftp://lists.example.com/hosts"},
+				Response: nbdns.BlockResponseZero,
+				Groups:   []string{group1ID},
+			},
+			errFunc: require.Error,
+		},
+		{
+			name: "Should Not Create If URL Is Not HTTPS",
+			blockList: &nbdns.BlockList{
+				Name:     "ads",
+				URLs:     []string{"http://lists.example.com/hosts"},
+				Response: nbdns.BlockResponseZero,
+				Groups:   []string{group1ID},
+			},
+			errFunc: require.Error,
+		},
+		{
+			name: "Should Not Create If Group Doesn't Exist",
+			blockList: &nbdns.BlockList{
+				Name:     "ads",
+				Domains:  []string{validDomain},
+				Response: nbdns.BlockResponseZero,
+				Groups:   []string{"missingGroup"},
+			},
+			errFunc: require.Error,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			am, err := createNSManager(t)
+			require.NoError(t, err, "failed to create account manager")
+
+			account, err := initTestNSAccount(t, am)
+			require.NoError(t, err, "failed to init testing account")
+
+			created, err := am.CreateDNSBlockList(account.Id, userID, testCase.blockList)
+			testCase.errFunc(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.NotEmpty(t, created.ID)
+			assert.Equal(t, []string{"ads.example.com", "*.tracker.example.com"}, created.Domains)
+
+			savedAccount, err := am.Store.GetAccount(account.Id)
+			require.NoError(t, err)
+			assert.Contains(t, savedAccount.DNSBlockLists, created.ID)
+		})
+	}
+}
+
+func TestDNSBlockListFetch(t *testing.T) {
+	listServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "# hosts list\n0.0.0.0 ads.example.com\n127.0.0.1 localhost\ntracker.example.com\n||adblock.example.com^\n")
+	}))
+	defer listServer.Close()
+
+	// the test server listens on a loopback address the block list client refuses
+	defaultHTTPClient := newBlockListHTTPClient
+	newBlockListHTTPClient = listServer.Client
+	t.Cleanup(func() {
+		newBlockListHTTPClient = defaultHTTPClient
+	})
+
+	am, err := createNSManager(t)
+	require.NoError(t, err, "failed to create account manager")
+
+	account, err := initTestNSAccount(t, am)
+	require.NoError(t, err, "failed to init testing account")
+
+	created, err := am.CreateDNSBlockList(account.Id, userID, &nbdns.BlockList{
+		Name:     "hosted",
+		URLs:     []string{listServer.URL},
+		Response: nbdns.BlockResponseZero,
+		Groups:   []string{group1ID},
+		Enabled:  true,
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		blockList, err := am.GetDNSBlockList(account.Id, created.ID)
+		return err == nil && !blockList.LastFetched.IsZero()
+	}, 5*time.Second, 50*time.Millisecond, "block list should be fetched")
+
+	blockList, err := am.GetDNSBlockList(account.Id, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, blockList.FetchedDomainsCount)
+	assert.NotEqual(t, created.DomainsHash, blockList.DomainsHash, "domains hash should change with the fetched domains")
+
+	fetchedDomains, err := am.Store.GetDNSBlockListDomains(account.Id, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ads.example.com", "tracker.example.com", "adblock.example.com"}, fetchedDomains)
+
+	err = am.DeleteDNSBlockList(account.Id, created.ID, userID)
+	require.NoError(t, err)
+
+	_, err = am.GetDNSBlockList(account.Id, created.ID)
+	require.Error(t, err, "block list shouldn't be found after delete")
+
+	fetchedDomains, err = am.Store.GetDNSBlockListDomains(account.Id, created.ID)
+	require.NoError(t, err)
+	assert.Empty(t, fetchedDomains, "fetched domains should be deleted with the block list")
+}
+
+func TestSaveDNSBlockListKeepsFetchedDomains(t *testing.T) {
+	am, err := createNSManager(t)
+	require.NoError(t, err, "failed to create account manager")
+
+	account, err := initTestNSAccount(t, am)
+	require.NoError(t, err, "failed to init testing account")
+
+	existing := &nbdns.BlockList{
+		ID:                  "existingBlockList",
+		Name:                "hosted",
+		URLs:                []string{"https://lists.example.com/hosts"},
+		Response:            nbdns.BlockResponseZero,
+		Groups:              []string{group1ID},
+		Enabled:             true,
+		FetchedDomainsCount: 1,
+		LastFetched:         time.Now().UTC(),
+	}
+	account.DNSBlockLists[existing.ID] = existing
+	require.NoError(t, am.Store.SaveAccount(account))
+	require.NoError(t, am.Store.SaveDNSBlockListDomains(account.Id, existing.ID, []string{"ads.example.com"}))
+
+	update := existing.Copy()
+	update.FetchedDomainsCount = 0
+	update.Domains = []string{"inline.example.com"}
+	update.Enabled = false
+	require.NoError(t, am.SaveDNSBlockList(account.Id, userID, update))
+
+	saved, err := am.GetDNSBlockList(account.Id, existing.ID)
+	require.NoError(t, err)
+	assert.False(t, saved.Enabled)
+	assert.Equal(t, 1, saved.FetchedDomainsCount)
+	assert.Equal(t, blockListDomainsHash([]string{"inline.example.com", "ads.example.com"}), saved.DomainsHash)
+
+	fetchedDomains, err := am.Store.GetDNSBlockListDomains(account.Id, existing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ads.example.com"}, fetchedDomains)
+}
+
+func TestGetPeerDNSBlockListDomains(t *testing.T) {
+	am, err := createNSManager(t)
+	require.NoError(t, err, "failed to create account manager")
+
+	account, err := initTestNSAccount(t, am)
+	require.NoError(t, err, "failed to init testing account")
+
+	groupAll, err := account.GetGroupAll()
+	require.NoError(t, err)
+
+	distributed, err := am.CreateDNSBlockList(account.Id, userID, &nbdns.BlockList{
+		Name:     "distributed",
+		Domains:  []string{"ads.example.com"},
+		URLs:     []string{"https://lists.example.com/hosts"},
+		Response: nbdns.BlockResponseZero,
+		Groups:   []string{groupAll.ID},
+		Enabled:  true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, am.Store.SaveDNSBlockListDomains(account.Id, distributed.ID, []string{"fetched.example.com"}))
+
+	notDistributed, err := am.CreateDNSBlockList(account.Id, userID, &nbdns.BlockList{
+		Name:     "not distributed",
+		Domains:  []string{"ads.example.com"},
+		Response: nbdns.BlockResponseZero,
+		Groups:   []string{group1ID},
+		Enabled:  true,
+	})
+	require.NoError(t, err)
+
+	domains, domainsHash, err := am.GetPeerDNSBlockListDomains(nsGroupPeer1Key, distributed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ads.example.com", "fetched.example.com"}, domains)
+	assert.Equal(t, blockListDomainsHash(domains), domainsHash)
+
+	_, _, err = am.GetPeerDNSBlockListDomains(nsGroupPeer1Key, notDistributed.ID)
+	require.Error(t, err, "block list not distributed to the peer shouldn't be returned")
+}
+
+func TestMergeBlockListDomains(t *testing.T) {
+	inline := []string{"inline.example.com"}
+	fetched := make([]string, 0, nbdns.MaxBlockListDomainsSize/20)
+	for i := 0; nbdns.BlockListDomainsSize(fetched) <= nbdns.MaxBlockListDomainsSize; i++ {
+		fetched = append(fetched, fmt.Sprintf("domain%d.example.com", i))
+	}
+
+	domains := mergeBlockListDomains(inline, fetched)
+	assert.Equal(t, inline[0], domains[0], "inline domains should come first")
+	assert.Less(t, len(domains), len(inline)+len(fetched), "domains above the size limit should be left out")
+	assert.LessOrEqual(t, nbdns.BlockListDomainsSize(domains), nbdns.MaxBlockListDomainsSize)
+}
+
+func TestGetPeerBlockLists(t *testing.T) {
+	account := &Account{
+		Peers: map[string]*nbpeer.Peer{
+			"peer1": {ID: "peer1"},
+			"peer2": {ID: "peer2"},
+		},
+		Groups: map[string]*Group{
+			group1ID: {ID: group1ID, Peers: []string{"peer1"}},
+			group2ID: {ID: group2ID, Peers: []string{"peer2"}},
+		},
+		DNSBlockLists: map[string]*nbdns.BlockList{
+			"enabled":  {ID: "enabled", Groups: []string{group1ID}, Enabled: true},
+			"disabled": {ID: "disabled", Groups: []string{group1ID, group2ID}},
+		},
+	}
+
+	peer1BlockLists := getPeerBlockLists(account, "peer1")
+	require.Len(t, peer1BlockLists, 1)
+	assert.Equal(t, "enabled", peer1BlockLists[0].ID)
+
+	assert.Empty(t, getPeerBlockLists(account, "peer2"))
+}
+
+func TestBlockListInactivePeers(t *testing.T) {
+	account := &Account{
+		Peers: map[string]*nbpeer.Peer{
+			"primary":   {ID: "primary"},
+			"hostDNS":   {ID: "hostDNS"},
+			"unmanaged": {ID: "unmanaged"},
+			"android":   {ID: "android", Meta: nbpeer.PeerSystemMeta{GoOS: "android"}},
+		},
+		Groups: map[string]*Group{
+			group1ID:    {ID: group1ID, Peers: []string{"primary", "hostDNS", "unmanaged", "android"}},
+			group2ID:    {ID: group2ID, Peers: []string{"primary", "unmanaged"}},
+			"unmanaged": {ID: "unmanaged", Peers: []string{"unmanaged"}},
+		},
+		NameServerGroups: map[string]*nbdns.NameServerGroup{
+			"primary": {
+				ID:          "primary",
+				NameServers: []nbdns.NameServer{{IP: netip.MustParseAddr("1.1.1.1"), NSType: nbdns.UDPNameServerType, Port: 53}},
+				Groups:      []string{group2ID},
+				Primary:     true,
+				Enabled:     true,
+			},
+		},
+		DNSSettings: DNSSettings{DisabledManagementGroups: []string{"unmanaged"}},
+	}
+
+	blockList := &nbdns.BlockList{ID: "blocklist", Groups: []string{group1ID, group2ID}, Enabled: true}
+	assert.Equal(t, []string{"hostDNS", "unmanaged"}, blockListInactivePeers(account, blockList))
+
+	blockList.Enabled = false
+	assert.Empty(t, blockListInactivePeers(account, blockList), "a disabled block list doesn't apply to any peer")
+}
+
+func TestFetchDNSBlockListDomains_NonPublicAddress(t *testing.T) {
+	listServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "ads.example.com\n")
+	}))
+	defer listServer.Close()
+
+	_, err := fetchDNSBlockListDomains(context.Background(), []string{listServer.URL})
+	require.Error(t, err, "block list on a loopback address shouldn't be fetched")
+	assert.Contains(t, err.Error(), "is not public")
+}
+
+func TestIsPublicBlockListAddr(t *testing.T) {
+	testCases := []struct {
+		addr     string
+		expected bool
+	}{
+		{addr: "1.1.1.1", expected: true},
+		{addr: "2606:4700:4700::1111", expected: true},
+		{addr: "127.0.0.1", expected: false},
+		{addr: "::1", expected: false},
+		{addr: "10.0.0.1", expected: false},
+		{addr: "172.16.0.1", expected: false},
+		{addr: "192.168.1.1", expected: false},
+		{addr: "169.254.169.254", expected: false},
+		{addr: "fe80::1", expected: false},
+		{addr: "fd00:ec2::254", expected: false},
+		{addr: "100.100.100.200", expected: false},
+		{addr: "0.0.0.0", expected: false},
+		{addr: "::ffff:127.0.0.1", expected: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.addr, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, isPublicBlockListAddr(netip.MustParseAddr(testCase.addr)))
+		})
+	}
+}
+
+func TestCheckBlockListRedirect(t *testing.T) {
+	httpsReq := httptest.NewRequest(http.MethodGet, "https://lists.example.com/hosts", nil)
+	assert.NoError(t, checkBlockListRedirect(httpsReq, []*http.Request{httpsReq}))
+
+	httpReq := httptest.NewRequest(http.MethodGet, "http://lists.example.com/hosts", nil)
+	assert.Error(t, checkBlockListRedirect(httpReq, []*http.Request{httpsReq}), "redirect to http should be refused")
+
+	via := make([]*http.Request, maxBlockListRedirects)
+	assert.Error(t, checkBlockListRedirect(httpsReq, via), "too many redirects should be refused")
+}
+
+func TestParseDNSBlockList(t *testing.T) {
+	list := strings.Join([]string{
+		"! adblock comment",
+		"# hosts comment",
+		"0.0.0.0 ads.example.com # inline comment",
+		":: ipv6.example.com",
+		"127.0.0.1 localhost",
+		"Upper.Example.com.",
+		"||adblock.example.com^",
+		"||options.example.com^$third-party",
+		"not a domain",
+		"nodots",
+	}, "\n")
+
+	domains, err := parseDNSBlockList(strings.NewReader(list))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ads.example.com", "ipv6.example.com", "upper.example.com", "adblock.example.com"}, domains)
+}
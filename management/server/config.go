@@ -4,6 +4,7 @@ import (
 	"net/url"
 
 	"github.com/netbirdio/netbird/management/server/idp"
+	"github.com/netbirdio/netbird/ratelimit"
 	"github.com/netbirdio/netbird/util"
 )
 
@@ -33,6 +34,7 @@ const (
 type Config struct {
 	Stuns      []*Host
 	TURNConfig *TURNConfig
+	Relay      *RelayConfig
 	Signal     *Host
 
 	Datadir                string
@@ -47,6 +49,9 @@ type Config struct {
 	PKCEAuthorizationFlow *PKCEAuthorizationFlow
 
 	StoreConfig StoreConfig
+
+	// RateLimit limits the requests of the peers, the limits are disabled if it isn't set
+	RateLimit *RateLimitConfig
 }
 
 // GetAuthAudiences returns the audience from the http config and device authorization flow config
@@ -68,6 +73,24 @@ type TURNConfig struct {
 	Turns                []*Host
 }
 
+// RateLimitConfig is a config of the rate limits of the peer requests, per WireGuard public key of the peers and per
+// IP address they connect from. A limit with a zero Rate is disabled. The IP address is the one of the gRPC connection,
+// so the per IP limits should only be set if the server sees the addresses of the clients: behind a load balancer,
+// reverse proxy or NAT all the peers share one address
+type RateLimitConfig struct {
+	Login      ratelimit.Config
+	LoginPerIP ratelimit.Config
+	Sync       ratelimit.Config
+	SyncPerIP  ratelimit.Config
+}
+
+// RelayConfig is a config of the relay servers, the credentials are generated with the secret shared with the servers
+type RelayConfig struct {
+	Addresses      []string
+	CredentialsTTL util.Duration
+	Secret         string
+}
+
 // HttpServerConfig is a config of the HTTP Management service server
 type HttpServerConfig struct {
 	LetsEncryptDomain string
@@ -146,6 +146,15 @@ func toProtocolDNSConfig(update nbdns.Config) *proto.DNSConfig {
 		protoUpdate.NameServerGroups = append(protoUpdate.NameServerGroups, protoGroup)
 	}
 
+	for _, blockList := range update.BlockLists {
+		protoUpdate.BlockLists = append(protoUpdate.BlockLists, &proto.BlockList{
+			ID:           blockList.ID,
+			DomainsHash:  blockList.DomainsHash,
+			AllowDomains: blockList.AllowDomains,
+			Response:     string(blockList.Response),
+		})
+	}
+
 	return protoUpdate
 }
 
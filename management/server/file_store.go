@@ -1,6 +1,8 @@
 package server
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,6 +22,10 @@ import (
 // storeFileName Store file name. Stored in the datadir
 const storeFileName = "store.json"
 
+// dnsBlockListsDirName is the directory of the fetched DNS block list domains, one file per block list.
+// Stored in the datadir next to the store file to keep the store file small
+const dnsBlockListsDirName = "dns_blocklists"
+
 // FileStore represents an account storage backed by a file persisted to disk
 type FileStore struct {
 	Accounts                map[string]*Account
@@ -69,6 +75,11 @@ func NewFilestoreFromSqliteStore(sqlitestore *SqliteStore, dataDir string, metri
 
 	for _, account := range sqlitestore.GetAllAccounts() {
 		store.Accounts[account.Id] = account
+
+		err = copyDNSBlockListDomains(sqlitestore, store, account)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return store, store.persist(store.storeFile)
@@ -384,6 +395,11 @@ func (s *FileStore) DeleteAccount(account *Account) error {
 
 	delete(s.Accounts, account.Id)
 
+	err := os.RemoveAll(s.dnsBlockListsDir(account.Id))
+	if err != nil {
+		log.Warnf("failed removing the DNS block list domains of account %s: %v", account.Id, err)
+	}
+
 	return s.persist(s.storeFile)
 }
 
@@ -646,6 +662,41 @@ func (s *FileStore) SaveUserLastLogin(accountID, userID string, lastLogin time.T
 	return nil
 }
 
+// SaveDNSBlockListDomains writes the fetched domains of the DNS block list to its own file
+func (s *FileStore) SaveDNSBlockListDomains(accountID, blockListID string, domains []string) error {
+	return util.WriteJson(s.dnsBlockListFile(accountID, blockListID), domains)
+}
+
+// GetDNSBlockListDomains reads the fetched domains of the DNS block list from its file
+func (s *FileStore) GetDNSBlockListDomains(accountID, blockListID string) ([]string, error) {
+	var domains []string
+	_, err := util.ReadJson(s.dnsBlockListFile(accountID, blockListID), &domains)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// DeleteDNSBlockListDomains removes the file of the fetched domains of the DNS block list
+func (s *FileStore) DeleteDNSBlockListDomains(accountID, blockListID string) error {
+	err := os.Remove(s.dnsBlockListFile(accountID, blockListID))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) dnsBlockListsDir(accountID string) string {
+	return filepath.Join(filepath.Dir(s.storeFile), dnsBlockListsDirName, accountID)
+}
+
+func (s *FileStore) dnsBlockListFile(accountID, blockListID string) string {
+	return filepath.Join(s.dnsBlockListsDir(accountID), blockListID+".json")
+}
+
 // Close the FileStore persisting data to disk
 func (s *FileStore) Close() error {
 	s.mux.Lock()
@@ -634,6 +634,42 @@ func TestFileStore_SavePeerStatus(t *testing.T) {
 	assert.Equal(t, newStatus, *actual)
 }
 
+func TestFileStore_DNSBlockListDomains(t *testing.T) {
+	store := newStore(t)
+
+	account := newAccountWithId("account_id", "testuser", "")
+	err := store.SaveAccount(account)
+	require.NoError(t, err)
+
+	domains, err := store.GetDNSBlockListDomains(account.Id, "blocklist")
+	require.NoError(t, err)
+	assert.Nil(t, domains, "domains of a block list that was never fetched should be nil")
+
+	err = store.SaveDNSBlockListDomains(account.Id, "blocklist", []string{"ads.example.com"})
+	require.NoError(t, err)
+
+	domains, err = store.GetDNSBlockListDomains(account.Id, "blocklist")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ads.example.com"}, domains)
+
+	err = store.DeleteDNSBlockListDomains(account.Id, "blocklist")
+	require.NoError(t, err)
+
+	domains, err = store.GetDNSBlockListDomains(account.Id, "blocklist")
+	require.NoError(t, err)
+	assert.Nil(t, domains)
+
+	err = store.SaveDNSBlockListDomains(account.Id, "blocklist", []string{"ads.example.com"})
+	require.NoError(t, err)
+
+	err = store.DeleteAccount(account)
+	require.NoError(t, err)
+
+	domains, err = store.GetDNSBlockListDomains(account.Id, "blocklist")
+	require.NoError(t, err)
+	assert.Nil(t, domains, "domains should be deleted with the account")
+}
+
 func newStore(t *testing.T) *FileStore {
 	t.Helper()
 	store, err := NewFileStore(t.TempDir(), nil)
@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"slices"
 
 	log "github.com/sirupsen/logrus"
 
@@ -192,6 +193,22 @@ func (am *DefaultAccountManager) DeleteGroup(accountId, userId, groupID string)
 		}
 	}
 
+	// check DNS block list links
+	for _, blockList := range account.DNSBlockLists {
+		for _, g := range blockList.Groups {
+			if g == groupID {
+				return &GroupLinkError{"DNS block list", blockList.Name}
+			}
+		}
+	}
+
+	// check SSH policy links
+	for _, sshPolicy := range account.SSHPolicies {
+		if slices.Contains(sshPolicy.SourceGroups, groupID) || slices.Contains(sshPolicy.Destinations, groupID) {
+			return &GroupLinkError{"SSH policy", sshPolicy.Name}
+		}
+	}
+
 	// check ACL links
 	for _, policy := range account.Policies {
 		for _, rule := range policy.Rules {
@@ -20,6 +20,7 @@ import (
 	nbpeer "github.com/netbirdio/netbird/management/server/peer"
 	internalStatus "github.com/netbirdio/netbird/management/server/status"
 	"github.com/netbirdio/netbird/management/server/telemetry"
+	"github.com/netbirdio/netbird/ratelimit"
 )
 
 // GRPCServer an instance of a Management gRPC API server
@@ -34,6 +35,11 @@ type GRPCServer struct {
 	jwtClaimsExtractor     *jwtclaims.ClaimsExtractor
 	appMetrics             telemetry.AppMetrics
 	ephemeralManager       *EphemeralManager
+
+	loginLimiter   *ratelimit.Limiter
+	loginIPLimiter *ratelimit.Limiter
+	syncLimiter    *ratelimit.Limiter
+	syncIPLimiter  *ratelimit.Limiter
 }
 
 // NewServer creates a new Management server
@@ -79,6 +85,11 @@ func NewServer(config *Config, accountManager AccountManager, peersUpdateManager
 		jwtclaims.WithUserIDClaim(userIDClaim),
 	)
 
+	rateLimit := &RateLimitConfig{}
+	if config.RateLimit != nil {
+		rateLimit = config.RateLimit
+	}
+
 	return &GRPCServer{
 		wgKey: key,
 		// peerKey -> event channel
@@ -90,6 +101,10 @@ func NewServer(config *Config, accountManager AccountManager, peersUpdateManager
 		jwtClaimsExtractor:     jwtClaimsExtractor,
 		appMetrics:             appMetrics,
 		ephemeralManager:       ephemeralManager,
+		loginLimiter:           ratelimit.NewLimiter(rateLimit.Login),
+		loginIPLimiter:         ratelimit.NewLimiter(rateLimit.LoginPerIP),
+		syncLimiter:            ratelimit.NewLimiter(rateLimit.Sync),
+		syncIPLimiter:          ratelimit.NewLimiter(rateLimit.SyncPerIP),
 	}, nil
 }
 
@@ -121,12 +136,21 @@ func (s *GRPCServer) Sync(req *proto.EncryptedMessage, srv proto.ManagementServi
 		log.Debugf("Sync request from peer [%s] [%s]", req.WgPubKey, p.Addr.String())
 	}
 
+	if !s.syncIPLimiter.Allow(ratelimit.PeerIP(srv.Context())) {
+		return s.syncThrottled(req.WgPubKey)
+	}
+
 	syncReq := &proto.SyncRequest{}
 	peerKey, err := s.parseRequest(req, syncReq)
 	if err != nil {
 		return err
 	}
 
+	// the key is proven once the request is decrypted, before that anybody could exhaust the limit of the peer
+	if !s.syncLimiter.Allow(peerKey.String()) {
+		return s.syncThrottled(req.WgPubKey)
+	}
+
 	peer, netMap, err := s.accountManager.SyncPeer(PeerSync{WireGuardPubKey: peerKey.String()})
 	if err != nil {
 		return mapError(err)
@@ -147,8 +171,8 @@ func (s *GRPCServer) Sync(req *proto.EncryptedMessage, srv proto.ManagementServi
 		log.Warnf("failed marking peer as connected %s %v", peerKey, err)
 	}
 
-	if s.config.TURNConfig.TimeBasedCredentials {
-		s.turnCredentialsManager.SetupRefresh(peer.ID)
+	if s.config.TURNConfig.TimeBasedCredentials || s.relayEnabled() {
+		s.turnCredentialsManager.SetupRefresh(peer.ID, peerKey.String())
 	}
 
 	if s.appMetrics != nil {
@@ -261,6 +285,22 @@ func extractPeerMeta(loginReq *proto.LoginRequest) nbpeer.PeerSystemMeta {
 	}
 }
 
+// syncThrottled counts a sync request rejected because of the rate limits and returns its error
+func (s *GRPCServer) syncThrottled(peerKey string) error {
+	if s.appMetrics != nil {
+		s.appMetrics.GRPCMetrics().CountSyncRequestThrottled()
+	}
+	return status.Errorf(codes.ResourceExhausted, "peer %s exceeded the sync rate limit", peerKey)
+}
+
+// loginThrottled counts a login request rejected because of the rate limits and returns its error
+func (s *GRPCServer) loginThrottled(peerKey string) error {
+	if s.appMetrics != nil {
+		s.appMetrics.GRPCMetrics().CountLoginRequestThrottled()
+	}
+	return status.Errorf(codes.ResourceExhausted, "peer %s exceeded the login rate limit", peerKey)
+}
+
 func (s *GRPCServer) parseRequest(req *proto.EncryptedMessage, parsed pb.Message) (wgtypes.Key, error) {
 	peerKey, err := wgtypes.ParseKey(req.GetWgPubKey())
 	if err != nil {
@@ -295,12 +335,21 @@ func (s *GRPCServer) Login(ctx context.Context, req *proto.EncryptedMessage) (*p
 		log.Debugf("Login request from peer [%s] [%s]", req.WgPubKey, p.Addr.String())
 	}
 
+	if !s.loginIPLimiter.Allow(ratelimit.PeerIP(ctx)) {
+		return nil, s.loginThrottled(req.WgPubKey)
+	}
+
 	loginReq := &proto.LoginRequest{}
 	peerKey, err := s.parseRequest(req, loginReq)
 	if err != nil {
 		return nil, err
 	}
 
+	// the key is proven once the request is decrypted, before that anybody could exhaust the limit of the peer
+	if !s.loginLimiter.Allow(peerKey.String()) {
+		return nil, s.loginThrottled(req.WgPubKey)
+	}
+
 	if loginReq.GetMeta() == nil {
 		msg := status.Errorf(codes.FailedPrecondition,
 			"peer system meta has to be provided to log in. Peer %s, remote addr %s", peerKey.String(),
@@ -344,8 +393,8 @@ func (s *GRPCServer) Login(ctx context.Context, req *proto.EncryptedMessage) (*p
 
 	// if peer has reached this point then it has logged in
 	loginResp := &proto.LoginResponse{
-		WiretrusteeConfig: toWiretrusteeConfig(s.config, nil),
-		PeerConfig:        toPeerConfig(peer, netMap.Network, s.accountManager.GetDNSDomain()),
+		WiretrusteeConfig: toWiretrusteeConfig(s.config, nil, nil),
+		PeerConfig:        toPeerConfig(peer, netMap, s.accountManager.GetDNSDomain()),
 	}
 	encryptedResp, err := encryption.EncryptMessage(peerKey, s.wgKey, loginResp)
 	if err != nil {
@@ -376,7 +425,7 @@ func ToResponseProto(configProto Protocol) proto.HostConfig_Protocol {
 	}
 }
 
-func toWiretrusteeConfig(config *Config, turnCredentials *TURNCredentials) *proto.WiretrusteeConfig {
+func toWiretrusteeConfig(config *Config, turnCredentials *TURNCredentials, relayCredentials *TURNCredentials) *proto.WiretrusteeConfig {
 	if config == nil {
 		return nil
 	}
@@ -408,6 +457,16 @@ func toWiretrusteeConfig(config *Config, turnCredentials *TURNCredentials) *prot
 		})
 	}
 
+	// the relay servers accept time based credentials only
+	var relay *proto.RelayConfig
+	if config.Relay != nil && len(config.Relay.Addresses) > 0 && relayCredentials != nil {
+		relay = &proto.RelayConfig{
+			Urls:     config.Relay.Addresses,
+			User:     relayCredentials.Username,
+			Password: relayCredentials.Password,
+		}
+	}
+
 	return &proto.WiretrusteeConfig{
 		Stuns: stuns,
 		Turns: turns,
@@ -415,45 +474,53 @@ func toWiretrusteeConfig(config *Config, turnCredentials *TURNCredentials) *prot
 			Uri:      config.Signal.URI,
 			Protocol: ToResponseProto(config.Signal.Proto),
 		},
+		Relay: relay,
 	}
 }
 
-func toPeerConfig(peer *nbpeer.Peer, network *Network, dnsName string) *proto.PeerConfig {
-	netmask, _ := network.Net.Mask.Size()
+func toPeerConfig(peer *nbpeer.Peer, networkMap *NetworkMap, dnsName string) *proto.PeerConfig {
+	netmask, _ := networkMap.Network.Net.Mask.Size()
 	fqdn := peer.FQDN(dnsName)
 	return &proto.PeerConfig{
-		Address:   fmt.Sprintf("%s/%d", peer.IP.String(), netmask), // take it from the network
-		SshConfig: &proto.SSHConfig{SshEnabled: peer.SSHEnabled},
-		Fqdn:      fqdn,
+		Address: fmt.Sprintf("%s/%d", peer.IP.String(), netmask), // take it from the network
+		SshConfig: &proto.SSHConfig{
+			SshEnabled:            peer.SSHEnabled,
+			PortForwardingEnabled: peer.SSHPortForwardingEnabled,
+			AuthorizeUsers:        networkMap.SSHAuthorizeUsers,
+		},
+		Fqdn:                  fqdn,
+		LazyConnectionEnabled: networkMap.LazyConnectionEnabled,
 	}
 }
 
-func toRemotePeerConfig(peers []*nbpeer.Peer, dnsName string) []*proto.RemotePeerConfig {
+// toRemotePeerConfig converts the remote peers. sshAuthorizedUsers are the local users the remote peers may log in as,
+// indexed by the remote peer ID
+func toRemotePeerConfig(peers []*nbpeer.Peer, dnsName string, sshAuthorizedUsers map[string][]string) []*proto.RemotePeerConfig {
 	remotePeers := []*proto.RemotePeerConfig{}
 	for _, rPeer := range peers {
 		fqdn := rPeer.FQDN(dnsName)
 		remotePeers = append(remotePeers, &proto.RemotePeerConfig{
 			WgPubKey:   rPeer.Key,
 			AllowedIps: []string{fmt.Sprintf(AllowedIPsFormat, rPeer.IP)},
-			SshConfig:  &proto.SSHConfig{SshPubKey: []byte(rPeer.SSHKey)},
+			SshConfig:  &proto.SSHConfig{SshPubKey: []byte(rPeer.SSHKey), AuthorizedUsers: sshAuthorizedUsers[rPeer.ID]},
 			Fqdn:       fqdn,
 		})
 	}
 	return remotePeers
 }
 
-func toSyncResponse(config *Config, peer *nbpeer.Peer, turnCredentials *TURNCredentials, networkMap *NetworkMap, dnsName string) *proto.SyncResponse {
-	wtConfig := toWiretrusteeConfig(config, turnCredentials)
+func toSyncResponse(config *Config, peer *nbpeer.Peer, turnCredentials *TURNCredentials, relayCredentials *TURNCredentials, networkMap *NetworkMap, dnsName string) *proto.SyncResponse {
+	wtConfig := toWiretrusteeConfig(config, turnCredentials, relayCredentials)
 
-	pConfig := toPeerConfig(peer, networkMap.Network, dnsName)
+	pConfig := toPeerConfig(peer, networkMap, dnsName)
 
-	remotePeers := toRemotePeerConfig(networkMap.Peers, dnsName)
+	remotePeers := toRemotePeerConfig(networkMap.Peers, dnsName, networkMap.SSHAuthorizedUsers)
 
 	routesUpdate := toProtocolRoutes(networkMap.Routes)
 
 	dnsUpdate := toProtocolDNSConfig(networkMap.DNSConfig)
 
-	offlinePeers := toRemotePeerConfig(networkMap.OfflinePeers, dnsName)
+	offlinePeers := toRemotePeerConfig(networkMap.OfflinePeers, dnsName, nil)
 
 	firewallRules := toProtocolFirewallRules(networkMap.FirewallRules)
 
@@ -476,6 +543,11 @@ func toSyncResponse(config *Config, peer *nbpeer.Peer, turnCredentials *TURNCred
 	}
 }
 
+// relayEnabled returns true if relay servers are configured
+func (s *GRPCServer) relayEnabled() bool {
+	return s.config.Relay != nil && len(s.config.Relay.Addresses) > 0
+}
+
 // IsHealthy indicates whether the service is healthy
 func (s *GRPCServer) IsHealthy(ctx context.Context, req *proto.Empty) (*proto.Empty, error) {
 	return &proto.Empty{}, nil
@@ -491,7 +563,13 @@ func (s *GRPCServer) sendInitialSync(peerKey wgtypes.Key, peer *nbpeer.Peer, net
 	} else {
 		turnCredentials = nil
 	}
-	plainResp := toSyncResponse(s.config, peer, turnCredentials, networkMap, s.accountManager.GetDNSDomain())
+
+	var relayCredentials *TURNCredentials
+	if s.relayEnabled() {
+		creds := s.turnCredentialsManager.GenerateRelayCredentials(peerKey.String())
+		relayCredentials = &creds
+	}
+	plainResp := toSyncResponse(s.config, peer, turnCredentials, relayCredentials, networkMap, s.accountManager.GetDNSDomain())
 
 	encryptedResp, err := encryption.EncryptMessage(peerKey, s.wgKey, plainResp)
 	if err != nil {
@@ -608,3 +686,73 @@ func (s *GRPCServer) GetPKCEAuthorizationFlow(_ context.Context, req *proto.Encr
 		Body:     encryptedResp,
 	}, nil
 }
+
+// ReportSSHSessions stores the SSH session events reported by the peer as activity events
+func (s *GRPCServer) ReportSSHSessions(_ context.Context, req *proto.EncryptedMessage) (*proto.Empty, error) {
+	sessionEvents := &proto.SSHSessionEvents{}
+	peerKey, err := s.parseRequest(req, sessionEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range sessionEvents.GetEvents() {
+		eventType := SSHSessionStarted
+		if event.GetType() == proto.SSHSessionEvent_ENDED {
+			eventType = SSHSessionEnded
+		}
+
+		err = s.accountManager.StoreSSHSessionEvent(peerKey.String(), SSHSessionEvent{
+			Type:          eventType,
+			SessionID:     event.GetSessionId(),
+			RemotePeerKey: event.GetRemotePeerKey(),
+			LocalUser:     event.GetLocalUser(),
+			Timestamp:     event.GetTimestamp().AsTime(),
+			Duration:      time.Duration(event.GetDurationSeconds()) * time.Second,
+		})
+		if err != nil && isInvalidSSHSessionEvent(err) {
+			// the peer can't report it again successfully, e.g. the remote peer has been deleted
+			log.Warnf("skipping invalid SSH session event %s of peer %s: %v", event.GetSessionId(), peerKey, err)
+			continue
+		}
+		if err != nil {
+			log.Warnf("failed storing SSH session event %s of peer %s: %v", event.GetSessionId(), peerKey, err)
+			return nil, mapError(err)
+		}
+	}
+
+	return &proto.Empty{}, nil
+}
+
+// isInvalidSSHSessionEvent returns true if the SSH session event failed to be stored because of its content
+func isInvalidSSHSessionEvent(err error) bool {
+	e, ok := internalStatus.FromError(err)
+	return ok && (e.Type() == internalStatus.NotFound || e.Type() == internalStatus.InvalidArgument)
+}
+
+// GetDNSBlockList returns the domains of a DNS block list distributed to the peer
+func (s *GRPCServer) GetDNSBlockList(_ context.Context, req *proto.EncryptedMessage) (*proto.EncryptedMessage, error) {
+	blockListReq := &proto.DNSBlockListRequest{}
+	peerKey, err := s.parseRequest(req, blockListReq)
+	if err != nil {
+		return nil, err
+	}
+
+	domains, domainsHash, err := s.accountManager.GetPeerDNSBlockListDomains(peerKey.String(), blockListReq.GetID())
+	if err != nil {
+		log.Debugf("failed getting DNS block list %s of peer %s: %v", blockListReq.GetID(), peerKey, err)
+		return nil, mapError(err)
+	}
+
+	encryptedResp, err := encryption.EncryptMessage(peerKey, s.wgKey, &proto.DNSBlockListDomains{
+		DomainsHash: domainsHash,
+		Domains:     domains,
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to encrypt DNS block list")
+	}
+
+	return &proto.EncryptedMessage{
+		WgPubKey: s.wgKey.PublicKey().String(),
+		Body:     encryptedResp,
+	}, nil
+}
@@ -12,6 +12,18 @@ const (
 	TokenAuthScopes  = "TokenAuth.Scopes"
 )
 
+// Defines values for DNSBlockListResponse.
+const (
+	DNSBlockListResponseNxdomain DNSBlockListResponse = "nxdomain"
+	DNSBlockListResponseZero     DNSBlockListResponse = "zero"
+)
+
+// Defines values for DNSBlockListRequestResponse.
+const (
+	DNSBlockListRequestResponseNxdomain DNSBlockListRequestResponse = "nxdomain"
+	DNSBlockListRequestResponseZero     DNSBlockListRequestResponse = "zero"
+)
+
 // Defines values for EventActivityCode.
 const (
 	EventActivityCodeAccountCreate                            EventActivityCode = "account.create"
@@ -31,6 +43,10 @@ const (
 	EventActivityCodePeerRename                               EventActivityCode = "peer.rename"
 	EventActivityCodePeerSshDisable                           EventActivityCode = "peer.ssh.disable"
 	EventActivityCodePeerSshEnable                            EventActivityCode = "peer.ssh.enable"
+	EventActivityCodePeerSshPortForwardingDisable             EventActivityCode = "peer.ssh.port.forwarding.disable"
+	EventActivityCodePeerSshPortForwardingEnable              EventActivityCode = "peer.ssh.port.forwarding.enable"
+	EventActivityCodePeerSshSessionEnd                        EventActivityCode = "peer.ssh.session.end"
+	EventActivityCodePeerSshSessionStart                      EventActivityCode = "peer.ssh.session.start"
 	EventActivityCodePersonalAccessTokenCreate                EventActivityCode = "personal.access.token.create"
 	EventActivityCodePersonalAccessTokenDelete                EventActivityCode = "personal.access.token.delete"
 	EventActivityCodePolicyAdd                                EventActivityCode = "policy.add"
@@ -51,6 +67,9 @@ const (
 	EventActivityCodeSetupkeyPeerAdd                          EventActivityCode = "setupkey.peer.add"
 	EventActivityCodeSetupkeyRevoke                           EventActivityCode = "setupkey.revoke"
 	EventActivityCodeSetupkeyUpdate                           EventActivityCode = "setupkey.update"
+	EventActivityCodeSshPolicyAdd                             EventActivityCode = "ssh.policy.add"
+	EventActivityCodeSshPolicyDelete                          EventActivityCode = "ssh.policy.delete"
+	EventActivityCodeSshPolicyUpdate                          EventActivityCode = "ssh.policy.update"
 	EventActivityCodeUserBlock                                EventActivityCode = "user.block"
 	EventActivityCodeUserGroupAdd                             EventActivityCode = "user.group.add"
 	EventActivityCodeUserGroupDelete                          EventActivityCode = "user.group.delete"
@@ -169,6 +188,9 @@ type AccountSettings struct {
 	// JwtGroupsEnabled Allows extract groups from JWT claim and add it to account groups.
 	JwtGroupsEnabled *bool `json:"jwt_groups_enabled,omitempty"`
 
+	// LazyConnectionEnabled Enables or disables lazy peer connections. The peers connect to each other only when there is traffic between them and disconnect after they have been idle for a while.
+	LazyConnectionEnabled *bool `json:"lazy_connection_enabled,omitempty"`
+
 	// PeerLoginExpiration Period of time after which peer login expires (seconds).
 	PeerLoginExpiration int `json:"peer_login_expiration"`
 
@@ -176,6 +198,78 @@ type AccountSettings struct {
 	PeerLoginExpirationEnabled bool `json:"peer_login_expiration_enabled"`
 }
 
+// DNSBlockList defines model for DNSBlockList.
+type DNSBlockList struct {
+	// AllowDomains Domains that are never blocked, even if they match a blocked domain. A leading "*." allows all subdomains of the domain.
+	AllowDomains []string `json:"allow_domains"`
+
+	// Description Description of the DNS block list
+	Description string `json:"description"`
+
+	// Domains Inline list of blocked domains. A leading "*." blocks all subdomains of the domain.
+	Domains []string `json:"domains"`
+
+	// Enabled Block list status
+	Enabled bool `json:"enabled"`
+
+	// FetchedDomainsCount Number of domains fetched from the hosted block lists
+	FetchedDomainsCount int `json:"fetched_domains_count"`
+
+	// Groups Distribution group IDs that defines group of peers that will enforce this block list
+	Groups []string `json:"groups"`
+
+	// Id DNS block list ID
+	Id string `json:"id"`
+
+	// InactivePeers IDs of the peers of the groups the block list doesn't apply to all the DNS queries of. Their DNS management is disabled or they don't have a primary nameserver group, so the host resolves the domains outside of their custom zones and nameserver groups without the block list
+	InactivePeers []string `json:"inactive_peers"`
+
+	// LastFetched Last time the hosted block lists were fetched
+	LastFetched *time.Time `json:"last_fetched,omitempty"`
+
+	// Name Name of the DNS block list
+	Name string `json:"name"`
+
+	// Response Answer returned for blocked domains, either an NXDOMAIN error or an unspecified address (0.0.0.0 or ::)
+	Response DNSBlockListResponse `json:"response"`
+
+	// Urls HTTPS URLs of hosted block lists in hosts file or one domain per line format. They are fetched and periodically refreshed by the management service, URLs and redirects to private, loopback or link-local addresses are refused.
+	Urls []string `json:"urls"`
+}
+
+// DNSBlockListResponse Answer returned for blocked domains, either an NXDOMAIN error or an unspecified address (0.0.0.0 or ::)
+type DNSBlockListResponse string
+
+// DNSBlockListRequest defines model for DNSBlockListRequest.
+type DNSBlockListRequest struct {
+	// AllowDomains Domains that are never blocked, even if they match a blocked domain. A leading "*." allows all subdomains of the domain.
+	AllowDomains []string `json:"allow_domains"`
+
+	// Description Description of the DNS block list
+	Description string `json:"description"`
+
+	// Domains Inline list of blocked domains. A leading "*." blocks all subdomains of the domain.
+	Domains []string `json:"domains"`
+
+	// Enabled Block list status
+	Enabled bool `json:"enabled"`
+
+	// Groups Distribution group IDs that defines group of peers that will enforce this block list
+	Groups []string `json:"groups"`
+
+	// Name Name of the DNS block list
+	Name string `json:"name"`
+
+	// Response Answer returned for blocked domains, either an NXDOMAIN error or an unspecified address (0.0.0.0 or ::)
+	Response DNSBlockListRequestResponse `json:"response"`
+
+	// Urls HTTPS URLs of hosted block lists in hosts file or one domain per line format. They are fetched and periodically refreshed by the management service, URLs and redirects to private, loopback or link-local addresses are refused.
+	Urls []string `json:"urls"`
+}
+
+// DNSBlockListRequestResponse Answer returned for blocked domains, either an NXDOMAIN error or an unspecified address (0.0.0.0 or ::)
+type DNSBlockListRequestResponse string
+
 // DNSSettings defines model for DNSSettings.
 type DNSSettings struct {
 	// DisabledManagementGroups Groups whose DNS management is disabled
@@ -376,6 +470,9 @@ type Peer struct {
 	// SshEnabled Indicates whether SSH server is enabled on this peer
 	SshEnabled bool `json:"ssh_enabled"`
 
+	// SshPortForwardingEnabled Indicates whether the SSH server of this peer allows local and remote port forwarding, the remote forwarded ports only listen on the loopback and NetBird addresses of the peer
+	SshPortForwardingEnabled bool `json:"ssh_port_forwarding_enabled"`
+
 	// UiVersion Peer's desktop UI version
 	UiVersion *string `json:"ui_version,omitempty"`
 
@@ -430,6 +527,9 @@ type PeerBase struct {
 	// SshEnabled Indicates whether SSH server is enabled on this peer
 	SshEnabled bool `json:"ssh_enabled"`
 
+	// SshPortForwardingEnabled Indicates whether the SSH server of this peer allows local and remote port forwarding, the remote forwarded ports only listen on the loopback and NetBird addresses of the peer
+	SshPortForwardingEnabled bool `json:"ssh_port_forwarding_enabled"`
+
 	// UiVersion Peer's desktop UI version
 	UiVersion *string `json:"ui_version,omitempty"`
 
@@ -487,6 +587,9 @@ type PeerBatch struct {
 	// SshEnabled Indicates whether SSH server is enabled on this peer
 	SshEnabled bool `json:"ssh_enabled"`
 
+	// SshPortForwardingEnabled Indicates whether the SSH server of this peer allows local and remote port forwarding, the remote forwarded ports only listen on the loopback and NetBird addresses of the peer
+	SshPortForwardingEnabled bool `json:"ssh_port_forwarding_enabled"`
+
 	// UiVersion Peer's desktop UI version
 	UiVersion *string `json:"ui_version,omitempty"`
 
@@ -513,6 +616,9 @@ type PeerRequest struct {
 	LoginExpirationEnabled bool   `json:"login_expiration_enabled"`
 	Name                   string `json:"name"`
 	SshEnabled             bool   `json:"ssh_enabled"`
+
+	// SshPortForwardingEnabled Indicates whether the SSH server of the peer allows local and remote port forwarding, the remote forwarded ports only listen on the loopback and NetBird addresses of the peer. Unchanged if omitted
+	SshPortForwardingEnabled *bool `json:"ssh_port_forwarding_enabled,omitempty"`
 }
 
 // PersonalAccessToken defines model for PersonalAccessToken.
@@ -850,6 +956,57 @@ type RuleRequest struct {
 	Sources *[]string `json:"sources,omitempty"`
 }
 
+// SSHPolicy defines model for SSHPolicy.
+type SSHPolicy struct {
+	// Description Description of the SSH policy
+	Description string `json:"description"`
+
+	// Destinations IDs of the peer groups the SSH access is granted to
+	Destinations []string `json:"destinations"`
+
+	// Enabled SSH policy status. Once an account has an enabled SSH policy, the SSH servers of its peers only accept the logins granted by the SSH policies.
+	Enabled bool `json:"enabled"`
+
+	// Id SSH policy ID
+	Id string `json:"id"`
+
+	// LocalUsers Local users of the destination peers the sources may log in as. "*" allows any local user.
+	LocalUsers []string `json:"local_users"`
+
+	// Name Name of the SSH policy
+	Name string `json:"name"`
+
+	// SourceGroups IDs of the peer groups granted SSH access
+	SourceGroups []string `json:"source_groups"`
+
+	// SourceUsers IDs of the users whose peers are granted SSH access
+	SourceUsers []string `json:"source_users"`
+}
+
+// SSHPolicyRequest defines model for SSHPolicyRequest.
+type SSHPolicyRequest struct {
+	// Description Description of the SSH policy
+	Description string `json:"description"`
+
+	// Destinations IDs of the peer groups the SSH access is granted to
+	Destinations []string `json:"destinations"`
+
+	// Enabled SSH policy status. Once an account has an enabled SSH policy, the SSH servers of its peers only accept the logins granted by the SSH policies.
+	Enabled bool `json:"enabled"`
+
+	// LocalUsers Local users of the destination peers the sources may log in as. "*" allows any local user.
+	LocalUsers []string `json:"local_users"`
+
+	// Name Name of the SSH policy
+	Name string `json:"name"`
+
+	// SourceGroups IDs of the peer groups granted SSH access
+	SourceGroups []string `json:"source_groups"`
+
+	// SourceUsers IDs of the users whose peers are granted SSH access
+	SourceUsers []string `json:"source_users"`
+}
+
 // SetupKey defines model for SetupKey.
 type SetupKey struct {
 	// AutoGroups List of group IDs to auto-assign to peers registered with this key
@@ -997,6 +1154,12 @@ type GetApiUsersParams struct {
 // PutApiAccountsAccountIdJSONRequestBody defines body for PutApiAccountsAccountId for application/json ContentType.
 type PutApiAccountsAccountIdJSONRequestBody = AccountRequest
 
+// PostApiDnsBlocklistsJSONRequestBody defines body for PostApiDnsBlocklists for application/json ContentType.
+type PostApiDnsBlocklistsJSONRequestBody = DNSBlockListRequest
+
+// PutApiDnsBlocklistsBlocklistIdJSONRequestBody defines body for PutApiDnsBlocklistsBlocklistId for application/json ContentType.
+type PutApiDnsBlocklistsBlocklistIdJSONRequestBody = DNSBlockListRequest
+
 // PostApiDnsNameserversJSONRequestBody defines body for PostApiDnsNameservers for application/json ContentType.
 type PostApiDnsNameserversJSONRequestBody = NameserverGroupRequest
 
@@ -1039,6 +1202,12 @@ type PostApiSetupKeysJSONRequestBody = SetupKeyRequest
 // PutApiSetupKeysKeyIdJSONRequestBody defines body for PutApiSetupKeysKeyId for application/json ContentType.
 type PutApiSetupKeysKeyIdJSONRequestBody = SetupKeyRequest
 
+// PostApiSshPoliciesJSONRequestBody defines body for PostApiSshPolicies for application/json ContentType.
+type PostApiSshPoliciesJSONRequestBody = SSHPolicyRequest
+
+// PutApiSshPoliciesSshPolicyIdJSONRequestBody defines body for PutApiSshPoliciesSshPolicyId for application/json ContentType.
+type PutApiSshPoliciesSshPolicyIdJSONRequestBody = SSHPolicyRequest
+
 // PostApiUsersJSONRequestBody defines body for PostApiUsers for application/json ContentType.
 type PostApiUsersJSONRequestBody = UserCreateRequest
 
@@ -0,0 +1,215 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	nbdns "github.com/netbirdio/netbird/dns"
+	"github.com/netbirdio/netbird/management/server"
+	"github.com/netbirdio/netbird/management/server/http/api"
+	"github.com/netbirdio/netbird/management/server/http/util"
+	"github.com/netbirdio/netbird/management/server/jwtclaims"
+	"github.com/netbirdio/netbird/management/server/status"
+)
+
+// DNSBlockListsHandler is the DNS block list handler of the account
+type DNSBlockListsHandler struct {
+	accountManager  server.AccountManager
+	claimsExtractor *jwtclaims.ClaimsExtractor
+}
+
+// NewDNSBlockListsHandler returns a new instance of DNSBlockListsHandler handler
+func NewDNSBlockListsHandler(accountManager server.AccountManager, authCfg AuthCfg) *DNSBlockListsHandler {
+	return &DNSBlockListsHandler{
+		accountManager: accountManager,
+		claimsExtractor: jwtclaims.NewClaimsExtractor(
+			jwtclaims.WithAudience(authCfg.Audience),
+			jwtclaims.WithUserIDClaim(authCfg.UserIDClaim),
+		),
+	}
+}
+
+// GetAllBlockLists returns the list of DNS block lists for the account
+func (h *DNSBlockListsHandler) GetAllBlockLists(w http.ResponseWriter, r *http.Request) {
+	claims := h.claimsExtractor.FromRequestContext(r)
+	account, _, err := h.accountManager.GetAccountFromToken(claims)
+	if err != nil {
+		log.Error(err)
+		http.Redirect(w, r, "/", http.StatusInternalServerError)
+		return
+	}
+
+	blockLists, err := h.accountManager.ListDNSBlockLists(account.Id)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	apiBlockLists := make([]*api.DNSBlockList, 0)
+	for _, b := range blockLists {
+		apiBlockLists = append(apiBlockLists, toDNSBlockListResponse(b))
+	}
+
+	util.WriteJSONObject(w, apiBlockLists)
+}
+
+// CreateBlockList handles DNS block list creation request
+func (h *DNSBlockListsHandler) CreateBlockList(w http.ResponseWriter, r *http.Request) {
+	claims := h.claimsExtractor.FromRequestContext(r)
+	account, user, err := h.accountManager.GetAccountFromToken(claims)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	var req api.PostApiDnsBlocklistsJSONRequestBody
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		util.WriteErrorResponse("couldn't parse JSON request", http.StatusBadRequest, w)
+		return
+	}
+
+	blockList, err := h.accountManager.CreateDNSBlockList(account.Id, user.Id, toServerDNSBlockList("", req))
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	resp := toDNSBlockListResponse(blockList)
+
+	util.WriteJSONObject(w, &resp)
+}
+
+// UpdateBlockList handles update to a DNS block list identified by a given ID
+func (h *DNSBlockListsHandler) UpdateBlockList(w http.ResponseWriter, r *http.Request) {
+	claims := h.claimsExtractor.FromRequestContext(r)
+	account, user, err := h.accountManager.GetAccountFromToken(claims)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	blockListID := mux.Vars(r)["blocklistId"]
+	if len(blockListID) == 0 {
+		util.WriteError(status.Errorf(status.InvalidArgument, "invalid DNS block list ID"), w)
+		return
+	}
+
+	var req api.PutApiDnsBlocklistsBlocklistIdJSONRequestBody
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		util.WriteErrorResponse("couldn't parse JSON request", http.StatusBadRequest, w)
+		return
+	}
+
+	err = h.accountManager.SaveDNSBlockList(account.Id, user.Id, toServerDNSBlockList(blockListID, req))
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	updatedBlockList, err := h.accountManager.GetDNSBlockList(account.Id, blockListID)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	resp := toDNSBlockListResponse(updatedBlockList)
+
+	util.WriteJSONObject(w, &resp)
+}
+
+// DeleteBlockList handles DNS block list deletion request
+func (h *DNSBlockListsHandler) DeleteBlockList(w http.ResponseWriter, r *http.Request) {
+	claims := h.claimsExtractor.FromRequestContext(r)
+	account, user, err := h.accountManager.GetAccountFromToken(claims)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	blockListID := mux.Vars(r)["blocklistId"]
+	if len(blockListID) == 0 {
+		util.WriteError(status.Errorf(status.InvalidArgument, "invalid DNS block list ID"), w)
+		return
+	}
+
+	err = h.accountManager.DeleteDNSBlockList(account.Id, blockListID, user.Id)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	util.WriteJSONObject(w, emptyObject{})
+}
+
+// GetBlockList handles a DNS block list Get request identified by ID
+func (h *DNSBlockListsHandler) GetBlockList(w http.ResponseWriter, r *http.Request) {
+	claims := h.claimsExtractor.FromRequestContext(r)
+	account, _, err := h.accountManager.GetAccountFromToken(claims)
+	if err != nil {
+		log.Error(err)
+		http.Redirect(w, r, "/", http.StatusInternalServerError)
+		return
+	}
+
+	blockListID := mux.Vars(r)["blocklistId"]
+	if len(blockListID) == 0 {
+		util.WriteError(status.Errorf(status.InvalidArgument, "invalid DNS block list ID"), w)
+		return
+	}
+
+	blockList, err := h.accountManager.GetDNSBlockList(account.Id, blockListID)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	resp := toDNSBlockListResponse(blockList)
+
+	util.WriteJSONObject(w, &resp)
+}
+
+func toServerDNSBlockList(blockListID string, req api.DNSBlockListRequest) *nbdns.BlockList {
+	return &nbdns.BlockList{
+		ID:           blockListID,
+		Name:         req.Name,
+		Description:  req.Description,
+		Domains:      req.Domains,
+		URLs:         req.Urls,
+		AllowDomains: req.AllowDomains,
+		Response:     nbdns.BlockResponse(req.Response),
+		Groups:       req.Groups,
+		Enabled:      req.Enabled,
+	}
+}
+
+func toDNSBlockListResponse(blockList *nbdns.BlockList) *api.DNSBlockList {
+	resp := &api.DNSBlockList{
+		Id:                  blockList.ID,
+		Name:                blockList.Name,
+		Description:         blockList.Description,
+		Domains:             blockList.Domains,
+		Urls:                blockList.URLs,
+		AllowDomains:        blockList.AllowDomains,
+		Response:            api.DNSBlockListResponse(blockList.Response),
+		Groups:              blockList.Groups,
+		Enabled:             blockList.Enabled,
+		FetchedDomainsCount: blockList.FetchedDomainsCount,
+		InactivePeers:       blockList.InactivePeers,
+	}
+
+	if resp.InactivePeers == nil {
+		resp.InactivePeers = make([]string, 0)
+	}
+
+	if !blockList.LastFetched.IsZero() {
+		lastFetched := blockList.LastFetched
+		resp.LastFetched = &lastFetched
+	}
+
+	return resp
+}
@@ -0,0 +1,190 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	nbdns "github.com/netbirdio/netbird/dns"
+	"github.com/netbirdio/netbird/management/server"
+	"github.com/netbirdio/netbird/management/server/http/api"
+	"github.com/netbirdio/netbird/management/server/jwtclaims"
+	"github.com/netbirdio/netbird/management/server/mock_server"
+	"github.com/netbirdio/netbird/management/server/status"
+)
+
+const (
+	existingBlockListID = "existingBlockListID"
+	notFoundBlockListID = "notFoundBlockListID"
+)
+
+var baseExistingBlockList = &nbdns.BlockList{
+	ID:                  existingBlockListID,
+	Name:                "ads",
+	Description:         "ads",
+	Domains:             []string{"ads.example.com", "*.tracker.example.com"},
+	URLs:                []string{"https://lists.example.com/hosts"},
+	AllowDomains:        []string{"ok.tracker.example.com"},
+	Response:            nbdns.BlockResponseNXDomain,
+	Groups:              []string{"testing"},
+	Enabled:             true,
+	FetchedDomainsCount: 1,
+	InactivePeers:       []string{"peer1"},
+}
+
+func initDNSBlockListsTestData() *DNSBlockListsHandler {
+	return &DNSBlockListsHandler{
+		accountManager: &mock_server.MockAccountManager{
+			GetDNSBlockListFunc: func(_, blockListID string) (*nbdns.BlockList, error) {
+				if blockListID == existingBlockListID {
+					return baseExistingBlockList.Copy(), nil
+				}
+				return nil, status.Errorf(status.NotFound, "DNS block list with ID %s not found", blockListID)
+			},
+			CreateDNSBlockListFunc: func(_, _ string, blockList *nbdns.BlockList) (*nbdns.BlockList, error) {
+				created := blockList.Copy()
+				created.ID = existingBlockListID
+				return created, nil
+			},
+			SaveDNSBlockListFunc: func(_, _ string, blockListToSave *nbdns.BlockList) error {
+				if blockListToSave.ID == existingBlockListID {
+					return nil
+				}
+				return status.Errorf(status.NotFound, "DNS block list with ID %s was not found", blockListToSave.ID)
+			},
+			DeleteDNSBlockListFunc: func(_, _, _ string) error {
+				return nil
+			},
+			GetAccountFromTokenFunc: func(_ jwtclaims.AuthorizationClaims) (*server.Account, *server.User, error) {
+				return testingNSAccount, testingAccount.Users["test_user"], nil
+			},
+		},
+		claimsExtractor: jwtclaims.NewClaimsExtractor(
+			jwtclaims.WithFromRequestContext(func(r *http.Request) jwtclaims.AuthorizationClaims {
+				return jwtclaims.AuthorizationClaims{
+					UserId:    "test_user",
+					Domain:    "hotmail.com",
+					AccountId: testNSGroupAccountID,
+				}
+			}),
+		),
+	}
+}
+
+func TestDNSBlockListsHandlers(t *testing.T) {
+	tt := []struct {
+		name              string
+		expectedStatus    int
+		expectedBody      bool
+		expectedBlockList *api.DNSBlockList
+		requestType       string
+		requestPath       string
+		requestBody       io.Reader
+	}{
+		{
+			name:              "Get Existing Block List",
+			requestType:       http.MethodGet,
+			requestPath:       "/api/dns/blocklists/" + existingBlockListID,
+			expectedStatus:    http.StatusOK,
+			expectedBody:      true,
+			expectedBlockList: toDNSBlockListResponse(baseExistingBlockList),
+		},
+		{
+			name:           "Get Not Existing Block List",
+			requestType:    http.MethodGet,
+			requestPath:    "/api/dns/blocklists/" + notFoundBlockListID,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:        "POST OK",
+			requestType: http.MethodPost,
+			requestPath: "/api/dns/blocklists",
+			requestBody: bytes.NewBuffer(
+				[]byte("{\"name\":\"name\",\"description\":\"Post\",\"domains\":[\"ads.example.com\"],\"urls\":[],\"allow_domains\":[],\"response\":\"zero\",\"groups\":[\"group\"],\"enabled\":true}")),
+			expectedStatus: http.StatusOK,
+			expectedBody:   true,
+			expectedBlockList: &api.DNSBlockList{
+				Id:            existingBlockListID,
+				Name:          "name",
+				Description:   "Post",
+				Domains:       []string{"ads.example.com"},
+				Urls:          []string{},
+				AllowDomains:  []string{},
+				Response:      api.DNSBlockListResponseZero,
+				Groups:        []string{"group"},
+				Enabled:       true,
+				InactivePeers: []string{},
+			},
+		},
+		{
+			name:        "PUT OK",
+			requestType: http.MethodPut,
+			requestPath: "/api/dns/blocklists/" + existingBlockListID,
+			requestBody: bytes.NewBuffer(
+				[]byte("{\"name\":\"ads\",\"description\":\"ads\",\"domains\":[\"ads.example.com\"],\"urls\":[],\"allow_domains\":[],\"response\":\"nxdomain\",\"groups\":[\"testing\"],\"enabled\":true}")),
+			expectedStatus:    http.StatusOK,
+			expectedBody:      true,
+			expectedBlockList: toDNSBlockListResponse(baseExistingBlockList),
+		},
+		{
+			name:        "PUT Not Existing Block List",
+			requestType: http.MethodPut,
+			requestPath: "/api/dns/blocklists/" + notFoundBlockListID,
+			requestBody: bytes.NewBuffer(
+				[]byte("{\"name\":\"ads\",\"description\":\"ads\",\"domains\":[\"ads.example.com\"],\"urls\":[],\"allow_domains\":[],\"response\":\"nxdomain\",\"groups\":[\"testing\"],\"enabled\":true}")),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "DELETE OK",
+			requestType:    http.MethodDelete,
+			requestPath:    "/api/dns/blocklists/" + existingBlockListID,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	p := initDNSBlockListsTestData()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.requestType, tc.requestPath, tc.requestBody)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/api/dns/blocklists/{blocklistId}", p.GetBlockList).Methods("GET")
+			router.HandleFunc("/api/dns/blocklists", p.CreateBlockList).Methods("POST")
+			router.HandleFunc("/api/dns/blocklists/{blocklistId}", p.DeleteBlockList).Methods("DELETE")
+			router.HandleFunc("/api/dns/blocklists/{blocklistId}", p.UpdateBlockList).Methods("PUT")
+			router.ServeHTTP(recorder, req)
+
+			res := recorder.Result()
+			defer res.Body.Close()
+
+			content, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("I don't know what I expected; %v", err)
+			}
+
+			if status := recorder.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v, content: %s",
+					status, tc.expectedStatus, string(content))
+				return
+			}
+
+			if !tc.expectedBody {
+				return
+			}
+
+			got := &api.DNSBlockList{}
+			if err = json.Unmarshal(content, &got); err != nil {
+				t.Fatalf("Sent content is not in correct json format; %v", err)
+			}
+			assert.Equal(t, tc.expectedBlockList, got)
+		})
+	}
+}
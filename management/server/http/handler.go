@@ -76,10 +76,12 @@ func APIHandler(accountManager s.AccountManager, jwtValidator jwtclaims.JWTValid
 	api.addSetupKeysEndpoint()
 	api.addRulesEndpoint()
 	api.addPoliciesEndpoint()
+	api.addSSHPoliciesEndpoint()
 	api.addGroupsEndpoint()
 	api.addRoutesEndpoint()
 	api.addDNSNameserversEndpoint()
 	api.addDNSSettingEndpoint()
+	api.addDNSBlockListsEndpoint()
 	api.addEventsEndpoint()
 
 	err := api.Router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
@@ -181,6 +183,15 @@ func (apiHandler *apiHandler) addRoutesEndpoint() {
 	apiHandler.Router.HandleFunc("/routes/{routeId}", routesHandler.DeleteRoute).Methods("DELETE", "OPTIONS")
 }
 
+func (apiHandler *apiHandler) addSSHPoliciesEndpoint() {
+	sshPoliciesHandler := NewSSHPoliciesHandler(apiHandler.AccountManager, apiHandler.AuthCfg)
+	apiHandler.Router.HandleFunc("/ssh/policies", sshPoliciesHandler.GetAllSSHPolicies).Methods("GET", "OPTIONS")
+	apiHandler.Router.HandleFunc("/ssh/policies", sshPoliciesHandler.CreateSSHPolicy).Methods("POST", "OPTIONS")
+	apiHandler.Router.HandleFunc("/ssh/policies/{sshPolicyId}", sshPoliciesHandler.UpdateSSHPolicy).Methods("PUT", "OPTIONS")
+	apiHandler.Router.HandleFunc("/ssh/policies/{sshPolicyId}", sshPoliciesHandler.GetSSHPolicy).Methods("GET", "OPTIONS")
+	apiHandler.Router.HandleFunc("/ssh/policies/{sshPolicyId}", sshPoliciesHandler.DeleteSSHPolicy).Methods("DELETE", "OPTIONS")
+}
+
 func (apiHandler *apiHandler) addDNSNameserversEndpoint() {
 	nameserversHandler := NewNameserversHandler(apiHandler.AccountManager, apiHandler.AuthCfg)
 	apiHandler.Router.HandleFunc("/dns/nameservers", nameserversHandler.GetAllNameservers).Methods("GET", "OPTIONS")
@@ -190,6 +201,15 @@ func (apiHandler *apiHandler) addDNSNameserversEndpoint() {
 	apiHandler.Router.HandleFunc("/dns/nameservers/{nsgroupId}", nameserversHandler.DeleteNameserverGroup).Methods("DELETE", "OPTIONS")
 }
 
+func (apiHandler *apiHandler) addDNSBlockListsEndpoint() {
+	blockListsHandler := NewDNSBlockListsHandler(apiHandler.AccountManager, apiHandler.AuthCfg)
+	apiHandler.Router.HandleFunc("/dns/blocklists", blockListsHandler.GetAllBlockLists).Methods("GET", "OPTIONS")
+	apiHandler.Router.HandleFunc("/dns/blocklists", blockListsHandler.CreateBlockList).Methods("POST", "OPTIONS")
+	apiHandler.Router.HandleFunc("/dns/blocklists/{blocklistId}", blockListsHandler.UpdateBlockList).Methods("PUT", "OPTIONS")
+	apiHandler.Router.HandleFunc("/dns/blocklists/{blocklistId}", blockListsHandler.GetBlockList).Methods("GET", "OPTIONS")
+	apiHandler.Router.HandleFunc("/dns/blocklists/{blocklistId}", blockListsHandler.DeleteBlockList).Methods("DELETE", "OPTIONS")
+}
+
 func (apiHandler *apiHandler) addDNSSettingEndpoint() {
 	dnsSettingsHandler := NewDNSSettingsHandler(apiHandler.AccountManager, apiHandler.AuthCfg)
 	apiHandler.Router.HandleFunc("/dns/settings", dnsSettingsHandler.GetDNSSettings).Methods("GET", "OPTIONS")
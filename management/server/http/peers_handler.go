@@ -78,6 +78,12 @@ func (h *PeersHandler) updatePeer(account *server.Account, user *server.User, pe
 	update := &nbpeer.Peer{ID: peerID, SSHEnabled: req.SshEnabled, Name: req.Name,
 		LoginExpirationEnabled: req.LoginExpirationEnabled}
 
+	if req.SshPortForwardingEnabled != nil {
+		update.SSHPortForwardingEnabled = *req.SshPortForwardingEnabled
+	} else if existingPeer := account.GetPeer(peerID); existingPeer != nil {
+		update.SSHPortForwardingEnabled = existingPeer.SSHPortForwardingEnabled
+	}
+
 	if req.ApprovalRequired != nil {
 		update.Status = &nbpeer.PeerStatus{RequiresApproval: *req.ApprovalRequired}
 	}
@@ -232,47 +238,49 @@ func toGroupsInfo(groups map[string]*server.Group, peerID string) []api.GroupMin
 
 func toSinglePeerResponse(peer *nbpeer.Peer, groupsInfo []api.GroupMinimum, dnsDomain string, accessiblePeer []api.AccessiblePeer) *api.Peer {
 	return &api.Peer{
-		Id:                     peer.ID,
-		Name:                   peer.Name,
-		Ip:                     peer.IP.String(),
-		Connected:              peer.Status.Connected,
-		LastSeen:               peer.Status.LastSeen,
-		Os:                     fmt.Sprintf("%s %s", peer.Meta.OS, peer.Meta.Core),
-		Version:                peer.Meta.WtVersion,
-		Groups:                 groupsInfo,
-		SshEnabled:             peer.SSHEnabled,
-		Hostname:               peer.Meta.Hostname,
-		UserId:                 &peer.UserID,
-		UiVersion:              &peer.Meta.UIVersion,
-		DnsLabel:               fqdn(peer, dnsDomain),
-		LoginExpirationEnabled: peer.LoginExpirationEnabled,
-		LastLogin:              peer.LastLogin,
-		LoginExpired:           peer.Status.LoginExpired,
-		AccessiblePeers:        accessiblePeer,
-		ApprovalRequired:       &peer.Status.RequiresApproval,
+		Id:                       peer.ID,
+		Name:                     peer.Name,
+		Ip:                       peer.IP.String(),
+		Connected:                peer.Status.Connected,
+		LastSeen:                 peer.Status.LastSeen,
+		Os:                       fmt.Sprintf("%s %s", peer.Meta.OS, peer.Meta.Core),
+		Version:                  peer.Meta.WtVersion,
+		Groups:                   groupsInfo,
+		SshEnabled:               peer.SSHEnabled,
+		SshPortForwardingEnabled: peer.SSHPortForwardingEnabled,
+		Hostname:                 peer.Meta.Hostname,
+		UserId:                   &peer.UserID,
+		UiVersion:                &peer.Meta.UIVersion,
+		DnsLabel:                 fqdn(peer, dnsDomain),
+		LoginExpirationEnabled:   peer.LoginExpirationEnabled,
+		LastLogin:                peer.LastLogin,
+		LoginExpired:             peer.Status.LoginExpired,
+		AccessiblePeers:          accessiblePeer,
+		ApprovalRequired:         &peer.Status.RequiresApproval,
 	}
 }
 
 func toPeerListItemResponse(peer *nbpeer.Peer, groupsInfo []api.GroupMinimum, dnsDomain string, accessiblePeersCount int) *api.PeerBatch {
 	return &api.PeerBatch{
-		Id:                     peer.ID,
-		Name:                   peer.Name,
-		Ip:                     peer.IP.String(),
-		Connected:              peer.Status.Connected,
-		LastSeen:               peer.Status.LastSeen,
-		Os:                     fmt.Sprintf("%s %s", peer.Meta.OS, peer.Meta.Core),
-		Version:                peer.Meta.WtVersion,
-		Groups:                 groupsInfo,
-		SshEnabled:             peer.SSHEnabled,
-		Hostname:               peer.Meta.Hostname,
-		UserId:                 &peer.UserID,
-		UiVersion:              &peer.Meta.UIVersion,
-		DnsLabel:               fqdn(peer, dnsDomain),
-		LoginExpirationEnabled: peer.LoginExpirationEnabled,
-		LastLogin:              peer.LastLogin,
-		LoginExpired:           peer.Status.LoginExpired,
-		AccessiblePeersCount:   accessiblePeersCount,
-		ApprovalRequired:       &peer.Status.RequiresApproval,
+		Id:                       peer.ID,
+		Name:                     peer.Name,
+		Ip:                       peer.IP.String(),
+		Connected:                peer.Status.Connected,
+		LastSeen:                 peer.Status.LastSeen,
+		Os:                       fmt.Sprintf("%s %s", peer.Meta.OS, peer.Meta.Core),
+		Version:                  peer.Meta.WtVersion,
+		Groups:                   groupsInfo,
+		SshEnabled:               peer.SSHEnabled,
+		SshPortForwardingEnabled: peer.SSHPortForwardingEnabled,
+		Hostname:                 peer.Meta.Hostname,
+		UserId:                   &peer.UserID,
+		UiVersion:                &peer.Meta.UIVersion,
+		DnsLabel:                 fqdn(peer, dnsDomain),
+		LoginExpirationEnabled:   peer.LoginExpirationEnabled,
+		LastLogin:                peer.LastLogin,
+		LoginExpired:             peer.Status.LoginExpired,
+		AccessiblePeersCount:     accessiblePeersCount,
+		ApprovalRequired:         &peer.Status.RequiresApproval,
 	}
 }
 
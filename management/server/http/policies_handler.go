@@ -3,7 +3,6 @@ package http
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/xid"
@@ -177,8 +176,8 @@ func (h *Policies) savePolicy(
 
 		if r.Ports != nil && len(*r.Ports) != 0 {
 			for _, v := range *r.Ports {
-				if port, err := strconv.Atoi(v); err != nil || port < 1 || port > 65535 {
-					util.WriteError(status.Errorf(status.InvalidArgument, "valid port value is in 1..65535 range"), w)
+				if _, _, err := server.ParsePortRange(v); err != nil {
+					util.WriteError(status.Errorf(status.InvalidArgument, "invalid port %s: %v", v, err), w)
 					return
 				}
 				pr.Ports = append(pr.Ports, v)
@@ -252,6 +252,57 @@ func TestPoliciesWritePolicy(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "WritePolicy POST Port Range OK",
+			requestType: http.MethodPost,
+			requestPath: "/api/policies",
+			requestBody: bytes.NewBuffer(
+				[]byte(`{
+                    "Name":"Port Range Policy",
+                    "Rules":[
+                        {
+                            "Name":"Port Range Policy",
+                            "Protocol": "tcp",
+                            "Action": "accept",
+                            "Ports": ["443", "8000-8100"],
+                            "Bidirectional":false
+                        }
+                ]}`)),
+			expectedStatus: http.StatusOK,
+			expectedBody:   true,
+			expectedPolicy: &api.Policy{
+				Id:   str("id-was-set"),
+				Name: "Port Range Policy",
+				Rules: []api.PolicyRule{
+					{
+						Id:          str("id-was-set"),
+						Name:        "Port Range Policy",
+						Description: str(""),
+						Protocol:    "tcp",
+						Action:      "accept",
+						Ports:       &[]string{"443", "8000-8100"},
+					},
+				},
+			},
+		},
+		{
+			name:        "WritePolicy POST Invalid Port Range",
+			requestType: http.MethodPost,
+			requestPath: "/api/policies",
+			requestBody: bytes.NewBuffer(
+				[]byte(`{
+                    "Name":"Port Range Policy",
+                    "Rules":[
+                        {
+                            "Name":"Port Range Policy",
+                            "Protocol": "tcp",
+                            "Action": "accept",
+                            "Ports": ["8100-8000"],
+                            "Bidirectional":false
+                        }
+                ]}`)),
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
 		{
 			name:        "WritePolicy PUT Invalid Name",
 			requestType: http.MethodPut,
@@ -0,0 +1,199 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/netbirdio/netbird/management/server"
+	"github.com/netbirdio/netbird/management/server/http/api"
+	"github.com/netbirdio/netbird/management/server/http/util"
+	"github.com/netbirdio/netbird/management/server/jwtclaims"
+	"github.com/netbirdio/netbird/management/server/status"
+)
+
+// SSHPoliciesHandler is the SSH policy handler of the account
+type SSHPoliciesHandler struct {
+	accountManager  server.AccountManager
+	claimsExtractor *jwtclaims.ClaimsExtractor
+}
+
+// NewSSHPoliciesHandler returns a new instance of SSHPoliciesHandler handler
+func NewSSHPoliciesHandler(accountManager server.AccountManager, authCfg AuthCfg) *SSHPoliciesHandler {
+	return &SSHPoliciesHandler{
+		accountManager: accountManager,
+		claimsExtractor: jwtclaims.NewClaimsExtractor(
+			jwtclaims.WithAudience(authCfg.Audience),
+			jwtclaims.WithUserIDClaim(authCfg.UserIDClaim),
+		),
+	}
+}
+
+// GetAllSSHPolicies returns the list of SSH policies for the account
+func (h *SSHPoliciesHandler) GetAllSSHPolicies(w http.ResponseWriter, r *http.Request) {
+	claims := h.claimsExtractor.FromRequestContext(r)
+	account, _, err := h.accountManager.GetAccountFromToken(claims)
+	if err != nil {
+		log.Error(err)
+		http.Redirect(w, r, "/", http.StatusInternalServerError)
+		return
+	}
+
+	sshPolicies, err := h.accountManager.ListSSHPolicies(account.Id)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	apiSSHPolicies := make([]*api.SSHPolicy, 0)
+	for _, p := range sshPolicies {
+		apiSSHPolicies = append(apiSSHPolicies, toSSHPolicyResponse(p))
+	}
+
+	util.WriteJSONObject(w, apiSSHPolicies)
+}
+
+// CreateSSHPolicy handles SSH policy creation request
+func (h *SSHPoliciesHandler) CreateSSHPolicy(w http.ResponseWriter, r *http.Request) {
+	claims := h.claimsExtractor.FromRequestContext(r)
+	account, user, err := h.accountManager.GetAccountFromToken(claims)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	var req api.PostApiSshPoliciesJSONRequestBody
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		util.WriteErrorResponse("couldn't parse JSON request", http.StatusBadRequest, w)
+		return
+	}
+
+	sshPolicy, err := h.accountManager.CreateSSHPolicy(account.Id, user.Id, toServerSSHPolicy("", req))
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	resp := toSSHPolicyResponse(sshPolicy)
+
+	util.WriteJSONObject(w, &resp)
+}
+
+// UpdateSSHPolicy handles update to an SSH policy identified by a given ID
+func (h *SSHPoliciesHandler) UpdateSSHPolicy(w http.ResponseWriter, r *http.Request) {
+	claims := h.claimsExtractor.FromRequestContext(r)
+	account, user, err := h.accountManager.GetAccountFromToken(claims)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	sshPolicyID := mux.Vars(r)["sshPolicyId"]
+	if len(sshPolicyID) == 0 {
+		util.WriteError(status.Errorf(status.InvalidArgument, "invalid SSH policy ID"), w)
+		return
+	}
+
+	var req api.PutApiSshPoliciesSshPolicyIdJSONRequestBody
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		util.WriteErrorResponse("couldn't parse JSON request", http.StatusBadRequest, w)
+		return
+	}
+
+	err = h.accountManager.SaveSSHPolicy(account.Id, user.Id, toServerSSHPolicy(sshPolicyID, req))
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	updatedSSHPolicy, err := h.accountManager.GetSSHPolicy(account.Id, sshPolicyID)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	resp := toSSHPolicyResponse(updatedSSHPolicy)
+
+	util.WriteJSONObject(w, &resp)
+}
+
+// DeleteSSHPolicy handles SSH policy deletion request
+func (h *SSHPoliciesHandler) DeleteSSHPolicy(w http.ResponseWriter, r *http.Request) {
+	claims := h.claimsExtractor.FromRequestContext(r)
+	account, user, err := h.accountManager.GetAccountFromToken(claims)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	sshPolicyID := mux.Vars(r)["sshPolicyId"]
+	if len(sshPolicyID) == 0 {
+		util.WriteError(status.Errorf(status.InvalidArgument, "invalid SSH policy ID"), w)
+		return
+	}
+
+	err = h.accountManager.DeleteSSHPolicy(account.Id, sshPolicyID, user.Id)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	util.WriteJSONObject(w, emptyObject{})
+}
+
+// GetSSHPolicy handles an SSH policy Get request identified by ID
+func (h *SSHPoliciesHandler) GetSSHPolicy(w http.ResponseWriter, r *http.Request) {
+	claims := h.claimsExtractor.FromRequestContext(r)
+	account, _, err := h.accountManager.GetAccountFromToken(claims)
+	if err != nil {
+		log.Error(err)
+		http.Redirect(w, r, "/", http.StatusInternalServerError)
+		return
+	}
+
+	sshPolicyID := mux.Vars(r)["sshPolicyId"]
+	if len(sshPolicyID) == 0 {
+		util.WriteError(status.Errorf(status.InvalidArgument, "invalid SSH policy ID"), w)
+		return
+	}
+
+	sshPolicy, err := h.accountManager.GetSSHPolicy(account.Id, sshPolicyID)
+	if err != nil {
+		util.WriteError(err, w)
+		return
+	}
+
+	resp := toSSHPolicyResponse(sshPolicy)
+
+	util.WriteJSONObject(w, &resp)
+}
+
+func toServerSSHPolicy(sshPolicyID string, req api.SSHPolicyRequest) *server.SSHPolicy {
+	return &server.SSHPolicy{
+		ID:           sshPolicyID,
+		Name:         req.Name,
+		Description:  req.Description,
+		Enabled:      req.Enabled,
+		SourceUsers:  req.SourceUsers,
+		SourceGroups: req.SourceGroups,
+		Destinations: req.Destinations,
+		LocalUsers:   req.LocalUsers,
+	}
+}
+
+func toSSHPolicyResponse(sshPolicy *server.SSHPolicy) *api.SSHPolicy {
+	return &api.SSHPolicy{
+		Id:           sshPolicy.ID,
+		Name:         sshPolicy.Name,
+		Description:  sshPolicy.Description,
+		Enabled:      sshPolicy.Enabled,
+		SourceUsers:  sshPolicy.SourceUsers,
+		SourceGroups: sshPolicy.SourceGroups,
+		Destinations: sshPolicy.Destinations,
+		LocalUsers:   sshPolicy.LocalUsers,
+	}
+}
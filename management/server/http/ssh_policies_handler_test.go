@@ -0,0 +1,187 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/netbirdio/netbird/management/server"
+	"github.com/netbirdio/netbird/management/server/http/api"
+	"github.com/netbirdio/netbird/management/server/jwtclaims"
+	"github.com/netbirdio/netbird/management/server/mock_server"
+	"github.com/netbirdio/netbird/management/server/status"
+)
+
+const (
+	existingSSHPolicyID = "existingSSHPolicyID"
+	notFoundSSHPolicyID = "notFoundSSHPolicyID"
+)
+
+var baseExistingSSHPolicy = &server.SSHPolicy{
+	ID:           existingSSHPolicyID,
+	Name:         "admins",
+	Description:  "admins",
+	Enabled:      true,
+	SourceUsers:  []string{"test_user"},
+	SourceGroups: []string{},
+	Destinations: []string{"testing"},
+	LocalUsers:   []string{"root"},
+}
+
+func initSSHPoliciesTestData() *SSHPoliciesHandler {
+	return &SSHPoliciesHandler{
+		accountManager: &mock_server.MockAccountManager{
+			GetSSHPolicyFunc: func(_, sshPolicyID string) (*server.SSHPolicy, error) {
+				if sshPolicyID == existingSSHPolicyID {
+					return baseExistingSSHPolicy.Copy(), nil
+				}
+				return nil, status.Errorf(status.NotFound, "SSH policy with ID %s not found", sshPolicyID)
+			},
+			CreateSSHPolicyFunc: func(_, _ string, sshPolicy *server.SSHPolicy) (*server.SSHPolicy, error) {
+				created := sshPolicy.Copy()
+				created.ID = existingSSHPolicyID
+				return created, nil
+			},
+			SaveSSHPolicyFunc: func(_, _ string, sshPolicyToSave *server.SSHPolicy) error {
+				if sshPolicyToSave.ID == existingSSHPolicyID {
+					return nil
+				}
+				return status.Errorf(status.NotFound, "SSH policy with ID %s was not found", sshPolicyToSave.ID)
+			},
+			DeleteSSHPolicyFunc: func(_, _, _ string) error {
+				return nil
+			},
+			ListSSHPoliciesFunc: func(_ string) ([]*server.SSHPolicy, error) {
+				return []*server.SSHPolicy{baseExistingSSHPolicy.Copy()}, nil
+			},
+			GetAccountFromTokenFunc: func(_ jwtclaims.AuthorizationClaims) (*server.Account, *server.User, error) {
+				return testingNSAccount, testingAccount.Users["test_user"], nil
+			},
+		},
+		claimsExtractor: jwtclaims.NewClaimsExtractor(
+			jwtclaims.WithFromRequestContext(func(r *http.Request) jwtclaims.AuthorizationClaims {
+				return jwtclaims.AuthorizationClaims{
+					UserId:    "test_user",
+					Domain:    "hotmail.com",
+					AccountId: testNSGroupAccountID,
+				}
+			}),
+		),
+	}
+}
+
+func TestSSHPoliciesHandlers(t *testing.T) {
+	tt := []struct {
+		name              string
+		expectedStatus    int
+		expectedBody      bool
+		expectedSSHPolicy *api.SSHPolicy
+		requestType       string
+		requestPath       string
+		requestBody       io.Reader
+	}{
+		{
+			name:              "Get Existing SSH Policy",
+			requestType:       http.MethodGet,
+			requestPath:       "/api/ssh/policies/" + existingSSHPolicyID,
+			expectedStatus:    http.StatusOK,
+			expectedBody:      true,
+			expectedSSHPolicy: toSSHPolicyResponse(baseExistingSSHPolicy),
+		},
+		{
+			name:           "Get Not Existing SSH Policy",
+			requestType:    http.MethodGet,
+			requestPath:    "/api/ssh/policies/" + notFoundSSHPolicyID,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:        "POST OK",
+			requestType: http.MethodPost,
+			requestPath: "/api/ssh/policies",
+			requestBody: bytes.NewBuffer(
+				[]byte("{\"name\":\"devs\",\"description\":\"Post\",\"source_users\":[],\"source_groups\":[\"devs\"],\"destinations\":[\"servers\"],\"local_users\":[\"ubuntu\"],\"enabled\":true}")),
+			expectedStatus: http.StatusOK,
+			expectedBody:   true,
+			expectedSSHPolicy: &api.SSHPolicy{
+				Id:           existingSSHPolicyID,
+				Name:         "devs",
+				Description:  "Post",
+				SourceUsers:  []string{},
+				SourceGroups: []string{"devs"},
+				Destinations: []string{"servers"},
+				LocalUsers:   []string{"ubuntu"},
+				Enabled:      true,
+			},
+		},
+		{
+			name:        "PUT OK",
+			requestType: http.MethodPut,
+			requestPath: "/api/ssh/policies/" + existingSSHPolicyID,
+			requestBody: bytes.NewBuffer(
+				[]byte("{\"name\":\"admins\",\"description\":\"admins\",\"source_users\":[\"test_user\"],\"source_groups\":[],\"destinations\":[\"testing\"],\"local_users\":[\"root\"],\"enabled\":true}")),
+			expectedStatus:    http.StatusOK,
+			expectedBody:      true,
+			expectedSSHPolicy: toSSHPolicyResponse(baseExistingSSHPolicy),
+		},
+		{
+			name:        "PUT Not Existing SSH Policy",
+			requestType: http.MethodPut,
+			requestPath: "/api/ssh/policies/" + notFoundSSHPolicyID,
+			requestBody: bytes.NewBuffer(
+				[]byte("{\"name\":\"admins\",\"description\":\"admins\",\"source_users\":[\"test_user\"],\"source_groups\":[],\"destinations\":[\"testing\"],\"local_users\":[\"root\"],\"enabled\":true}")),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "DELETE OK",
+			requestType:    http.MethodDelete,
+			requestPath:    "/api/ssh/policies/" + existingSSHPolicyID,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	p := initSSHPoliciesTestData()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.requestType, tc.requestPath, tc.requestBody)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/api/ssh/policies/{sshPolicyId}", p.GetSSHPolicy).Methods("GET")
+			router.HandleFunc("/api/ssh/policies", p.CreateSSHPolicy).Methods("POST")
+			router.HandleFunc("/api/ssh/policies/{sshPolicyId}", p.DeleteSSHPolicy).Methods("DELETE")
+			router.HandleFunc("/api/ssh/policies/{sshPolicyId}", p.UpdateSSHPolicy).Methods("PUT")
+			router.ServeHTTP(recorder, req)
+
+			res := recorder.Result()
+			defer res.Body.Close()
+
+			content, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("I don't know what I expected; %v", err)
+			}
+
+			if status := recorder.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v, content: %s",
+					status, tc.expectedStatus, string(content))
+				return
+			}
+
+			if !tc.expectedBody {
+				return
+			}
+
+			got := &api.SSHPolicy{}
+			if err = json.Unmarshal(content, &got); err != nil {
+				t.Fatalf("Sent content is not in correct json format; %v", err)
+			}
+			assert.Equal(t, tc.expectedSSHPolicy, got)
+		})
+	}
+}
@@ -14,11 +14,14 @@ import (
 	"github.com/stretchr/testify/require"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 
 	"github.com/netbirdio/netbird/encryption"
 	mgmtProto "github.com/netbirdio/netbird/management/proto"
+	"github.com/netbirdio/netbird/ratelimit"
 	"github.com/netbirdio/netbird/util"
 )
 
@@ -417,7 +420,7 @@ func startManagement(t *testing.T, config *Config) (*grpc.Server, string, error)
 	if err != nil {
 		return nil, "", err
 	}
-	turnManager := NewTimeBasedAuthSecretsManager(peersUpdateManager, config.TURNConfig)
+	turnManager := NewTimeBasedAuthSecretsManager(peersUpdateManager, config.TURNConfig, config.Relay)
 
 	ephemeralMgr := NewEphemeralManager(store, accountManager)
 	mgmtServer, err := NewServer(config, accountManager, peersUpdateManager, turnManager, nil, ephemeralMgr)
@@ -452,3 +455,126 @@ func createRawClient(addr string) (mgmtProto.ManagementServiceClient, *grpc.Clie
 
 	return mgmtProto.NewManagementServiceClient(conn), conn, nil
 }
+
+func Test_RateLimits(t *testing.T) {
+	dir := t.TempDir()
+	err := util.CopyFileContents("testdata/store_with_expired_peers.json", filepath.Join(dir, "store.json"))
+	require.NoError(t, err)
+
+	mgmtServer, mgmtAddr, err := startManagement(t, &Config{
+		Stuns: []*Host{{
+			Proto: "udp",
+			URI:   "stun:stun.wiretrustee.com:3468",
+		}},
+		TURNConfig: &TURNConfig{
+			Secret: "whatever",
+			Turns: []*Host{{
+				Proto: "udp",
+				URI:   "turn:stun.wiretrustee.com:3468",
+			}},
+		},
+		Signal: &Host{
+			Proto: "http",
+			URI:   "signal.wiretrustee.com:10000",
+		},
+		Datadir: dir,
+		RateLimit: &RateLimitConfig{
+			Login: ratelimit.Config{Rate: 0.001, Burst: 1},
+			Sync:  ratelimit.Config{Rate: 0.001, Burst: 1},
+		},
+	})
+	require.NoError(t, err)
+	defer mgmtServer.GracefulStop()
+
+	client, clientConn, err := createRawClient(mgmtAddr)
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	key, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	// requests that can't be decrypted with the key don't use the limit of the peer
+	for i := 0; i < 3; i++ {
+		_, err = client.Login(context.Background(), &mgmtProto.EncryptedMessage{WgPubKey: key.PublicKey().String(), Body: []byte("forged")})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	_, err = loginPeerWithValidSetupKey(key, client)
+	require.NoError(t, err)
+
+	_, err = loginPeerWithValidSetupKey(key, client)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err), "login rate of the peer exceeded")
+
+	// other peers aren't affected
+	otherKey, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+	_, err = loginPeerWithValidSetupKey(otherKey, client)
+	require.NoError(t, err)
+
+	serverKey, err := getServerKey(client)
+	require.NoError(t, err)
+	message, err := encryption.EncryptMessage(*serverKey, key, &mgmtProto.SyncRequest{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sync, err := client.Sync(ctx, &mgmtProto.EncryptedMessage{WgPubKey: key.PublicKey().String(), Body: message})
+	require.NoError(t, err)
+	require.NoError(t, sync.RecvMsg(&mgmtProto.EncryptedMessage{}))
+
+	sync, err = client.Sync(ctx, &mgmtProto.EncryptedMessage{WgPubKey: key.PublicKey().String(), Body: message})
+	require.NoError(t, err)
+	err = sync.RecvMsg(&mgmtProto.EncryptedMessage{})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err), "sync rate of the peer exceeded")
+}
+
+func Test_ReportSSHSessions(t *testing.T) {
+	dir := t.TempDir()
+	err := util.CopyFileContents("testdata/store_with_expired_peers.json", filepath.Join(dir, "store.json"))
+	require.NoError(t, err)
+
+	mgmtServer, mgmtAddr, err := startManagement(t, &Config{
+		Stuns: []*Host{{
+			Proto: "udp",
+			URI:   "stun:stun.wiretrustee.com:3468",
+		}},
+		TURNConfig: &TURNConfig{
+			Secret: "whatever",
+			Turns: []*Host{{
+				Proto: "udp",
+				URI:   "turn:stun.wiretrustee.com:3468",
+			}},
+		},
+		Signal: &Host{
+			Proto: "http",
+			URI:   "signal.wiretrustee.com:10000",
+		},
+		Datadir: dir,
+	})
+	require.NoError(t, err)
+	defer mgmtServer.GracefulStop()
+
+	client, clientConn, err := createRawClient(mgmtAddr)
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	peers, err := registerPeers(2, client)
+	require.NoError(t, err)
+	serverKey, err := getServerKey(client)
+	require.NoError(t, err)
+
+	deletedKey, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+	message, err := encryption.EncryptMessage(*serverKey, *peers[0], &mgmtProto.SSHSessionEvents{Events: []*mgmtProto.SSHSessionEvent{
+		{Type: mgmtProto.SSHSessionEvent_STARTED, SessionId: "deleted", RemotePeerKey: deletedKey.PublicKey().String()},
+		{Type: mgmtProto.SSHSessionEvent_STARTED, SessionId: "valid", RemotePeerKey: peers[1].PublicKey().String()},
+	}})
+	require.NoError(t, err)
+
+	// the event of the unknown remote peer is skipped, the rest of the batch is stored
+	_, err = client.ReportSSHSessions(context.Background(), &mgmtProto.EncryptedMessage{
+		WgPubKey: peers[0].PublicKey().String(),
+		Body:     message,
+	})
+	require.NoError(t, err)
+}
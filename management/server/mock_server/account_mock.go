@@ -67,6 +67,18 @@ type MockAccountManager struct {
 	SaveNameServerGroupFunc         func(accountID, userID string, nsGroupToSave *nbdns.NameServerGroup) error
 	DeleteNameServerGroupFunc       func(accountID, nsGroupID, userID string) error
 	ListNameServerGroupsFunc        func(accountID string) ([]*nbdns.NameServerGroup, error)
+	GetDNSBlockListFunc             func(accountID, blockListID string) (*nbdns.BlockList, error)
+	CreateDNSBlockListFunc          func(accountID, userID string, blockList *nbdns.BlockList) (*nbdns.BlockList, error)
+	SaveDNSBlockListFunc            func(accountID, userID string, blockListToSave *nbdns.BlockList) error
+	DeleteDNSBlockListFunc          func(accountID, blockListID, userID string) error
+	ListDNSBlockListsFunc           func(accountID string) ([]*nbdns.BlockList, error)
+	GetSSHPolicyFunc                func(accountID, sshPolicyID string) (*server.SSHPolicy, error)
+	CreateSSHPolicyFunc             func(accountID, userID string, sshPolicy *server.SSHPolicy) (*server.SSHPolicy, error)
+	SaveSSHPolicyFunc               func(accountID, userID string, sshPolicyToSave *server.SSHPolicy) error
+	DeleteSSHPolicyFunc             func(accountID, sshPolicyID, userID string) error
+	ListSSHPoliciesFunc             func(accountID string) ([]*server.SSHPolicy, error)
+	StoreSSHSessionEventFunc        func(peerPubKey string, event server.SSHSessionEvent) error
+	GetPeerDNSBlockListDomainsFunc  func(peerPubKey, blockListID string) ([]string, string, error)
 	CreateUserFunc                  func(accountID, userID string, key *server.UserInfo) (*server.UserInfo, error)
 	GetAccountFromTokenFunc         func(claims jwtclaims.AuthorizationClaims) (*server.Account, *server.User, error)
 	CheckUserAccessByJWTGroupsFunc  func(claims jwtclaims.AuthorizationClaims) error
@@ -526,6 +538,102 @@ func (am *MockAccountManager) ListNameServerGroups(accountID string) ([]*nbdns.N
 	return nil, nil
 }
 
+// GetDNSBlockList mocks GetDNSBlockList of the AccountManager interface
+func (am *MockAccountManager) GetDNSBlockList(accountID, blockListID string) (*nbdns.BlockList, error) {
+	if am.GetDNSBlockListFunc != nil {
+		return am.GetDNSBlockListFunc(accountID, blockListID)
+	}
+	return nil, nil
+}
+
+// CreateDNSBlockList mocks CreateDNSBlockList of the AccountManager interface
+func (am *MockAccountManager) CreateDNSBlockList(accountID, userID string, blockList *nbdns.BlockList) (*nbdns.BlockList, error) {
+	if am.CreateDNSBlockListFunc != nil {
+		return am.CreateDNSBlockListFunc(accountID, userID, blockList)
+	}
+	return nil, nil
+}
+
+// SaveDNSBlockList mocks SaveDNSBlockList of the AccountManager interface
+func (am *MockAccountManager) SaveDNSBlockList(accountID, userID string, blockListToSave *nbdns.BlockList) error {
+	if am.SaveDNSBlockListFunc != nil {
+		return am.SaveDNSBlockListFunc(accountID, userID, blockListToSave)
+	}
+	return nil
+}
+
+// DeleteDNSBlockList mocks DeleteDNSBlockList of the AccountManager interface
+func (am *MockAccountManager) DeleteDNSBlockList(accountID, blockListID, userID string) error {
+	if am.DeleteDNSBlockListFunc != nil {
+		return am.DeleteDNSBlockListFunc(accountID, blockListID, userID)
+	}
+	return nil
+}
+
+// ListDNSBlockLists mocks ListDNSBlockLists of the AccountManager interface
+func (am *MockAccountManager) ListDNSBlockLists(accountID string) ([]*nbdns.BlockList, error) {
+	if am.ListDNSBlockListsFunc != nil {
+		return am.ListDNSBlockListsFunc(accountID)
+	}
+	return nil, nil
+}
+
+// GetSSHPolicy mocks GetSSHPolicy of the AccountManager interface
+func (am *MockAccountManager) GetSSHPolicy(accountID, sshPolicyID string) (*server.SSHPolicy, error) {
+	if am.GetSSHPolicyFunc != nil {
+		return am.GetSSHPolicyFunc(accountID, sshPolicyID)
+	}
+	return nil, nil
+}
+
+// CreateSSHPolicy mocks CreateSSHPolicy of the AccountManager interface
+func (am *MockAccountManager) CreateSSHPolicy(accountID, userID string, sshPolicy *server.SSHPolicy) (*server.SSHPolicy, error) {
+	if am.CreateSSHPolicyFunc != nil {
+		return am.CreateSSHPolicyFunc(accountID, userID, sshPolicy)
+	}
+	return nil, nil
+}
+
+// SaveSSHPolicy mocks SaveSSHPolicy of the AccountManager interface
+func (am *MockAccountManager) SaveSSHPolicy(accountID, userID string, sshPolicyToSave *server.SSHPolicy) error {
+	if am.SaveSSHPolicyFunc != nil {
+		return am.SaveSSHPolicyFunc(accountID, userID, sshPolicyToSave)
+	}
+	return nil
+}
+
+// DeleteSSHPolicy mocks DeleteSSHPolicy of the AccountManager interface
+func (am *MockAccountManager) DeleteSSHPolicy(accountID, sshPolicyID, userID string) error {
+	if am.DeleteSSHPolicyFunc != nil {
+		return am.DeleteSSHPolicyFunc(accountID, sshPolicyID, userID)
+	}
+	return nil
+}
+
+// ListSSHPolicies mocks ListSSHPolicies of the AccountManager interface
+func (am *MockAccountManager) ListSSHPolicies(accountID string) ([]*server.SSHPolicy, error) {
+	if am.ListSSHPoliciesFunc != nil {
+		return am.ListSSHPoliciesFunc(accountID)
+	}
+	return nil, nil
+}
+
+// StoreSSHSessionEvent mocks StoreSSHSessionEvent of the AccountManager interface
+func (am *MockAccountManager) StoreSSHSessionEvent(peerPubKey string, event server.SSHSessionEvent) error {
+	if am.StoreSSHSessionEventFunc != nil {
+		return am.StoreSSHSessionEventFunc(peerPubKey, event)
+	}
+	return nil
+}
+
+// GetPeerDNSBlockListDomains mocks GetPeerDNSBlockListDomains of the AccountManager interface
+func (am *MockAccountManager) GetPeerDNSBlockListDomains(peerPubKey, blockListID string) ([]string, string, error) {
+	if am.GetPeerDNSBlockListDomainsFunc != nil {
+		return am.GetPeerDNSBlockListDomainsFunc(peerPubKey, blockListID)
+	}
+	return nil, "", status.Errorf(codes.Unimplemented, "method GetPeerDNSBlockListDomains is not implemented")
+}
+
 // CreateUser mocks CreateUser of the AccountManager interface
 func (am *MockAccountManager) CreateUser(accountID, userID string, invite *server.UserInfo) (*server.UserInfo, error) {
 	if am.CreateUserFunc != nil {
@@ -32,6 +32,13 @@ type NetworkMap struct {
 	DNSConfig     nbdns.Config
 	OfflinePeers  []*nbpeer.Peer
 	FirewallRules []*FirewallRule
+	// SSHAuthorizedUsers are the local users the remote peers may log in as on the SSH server of the peer, indexed by
+	// the remote peer ID
+	SSHAuthorizedUsers map[string][]string
+	// SSHAuthorizeUsers indicates whether the SSH server of the peer restricts the local users to SSHAuthorizedUsers
+	SSHAuthorizeUsers bool
+	// LazyConnectionEnabled indicates whether the peer connects to the remote peers only when there is traffic
+	LazyConnectionEnabled bool
 }
 
 type Network struct {
@@ -129,7 +129,7 @@ func (am *DefaultAccountManager) MarkPeerConnected(peerPubKey string, connected
 	return nil
 }
 
-// UpdatePeer updates peer. Only Peer.Name, Peer.SSHEnabled, and Peer.LoginExpirationEnabled can be updated.
+// UpdatePeer updates peer. Only Peer.Name, Peer.SSHEnabled, Peer.SSHPortForwardingEnabled, and Peer.LoginExpirationEnabled can be updated.
 func (am *DefaultAccountManager) UpdatePeer(accountID, userID string, update *nbpeer.Peer) (*nbpeer.Peer, error) {
 	unlock := am.Store.AcquireAccountLock(accountID)
 	defer unlock()
@@ -158,6 +158,15 @@ func (am *DefaultAccountManager) UpdatePeer(accountID, userID string, update *nb
 		am.StoreEvent(userID, peer.IP.String(), accountID, event, peer.EventMeta(am.GetDNSDomain()))
 	}
 
+	if peer.SSHPortForwardingEnabled != update.SSHPortForwardingEnabled {
+		peer.SSHPortForwardingEnabled = update.SSHPortForwardingEnabled
+		event := activity.PeerSSHPortForwardingEnabled
+		if !update.SSHPortForwardingEnabled {
+			event = activity.PeerSSHPortForwardingDisabled
+		}
+		am.StoreEvent(userID, peer.IP.String(), accountID, event, peer.EventMeta(am.GetDNSDomain()))
+	}
+
 	if peer.Name != update.Name {
 		peer.Name = update.Name
 
@@ -761,7 +770,7 @@ func (am *DefaultAccountManager) updateAccountPeers(account *Account) {
 
 	for _, peer := range peers {
 		remotePeerNetworkMap := account.GetPeerNetworkMap(peer.ID, am.dnsDomain)
-		update := toSyncResponse(nil, peer, nil, remotePeerNetworkMap, am.GetDNSDomain())
+		update := toSyncResponse(nil, peer, nil, nil, remotePeerNetworkMap, am.GetDNSDomain())
 		am.peersUpdateManager.SendUpdate(peer.ID, &UpdateMessage{Update: update})
 	}
 }
@@ -34,6 +34,8 @@ type Peer struct {
 	SSHKey string
 	// SSHEnabled indicates whether SSH server is enabled on the peer
 	SSHEnabled bool
+	// SSHPortForwardingEnabled indicates whether the SSH server of the peer allows local and remote port forwarding
+	SSHPortForwardingEnabled bool
 	// LoginExpirationEnabled indicates whether peer's login expiration is enabled and once expired the peer has to re-login.
 	// Works with LastLogin
 	LoginExpirationEnabled bool
@@ -89,21 +91,22 @@ func (p *Peer) Copy() *Peer {
 		peerStatus = p.Status.Copy()
 	}
 	return &Peer{
-		ID:                     p.ID,
-		AccountID:              p.AccountID,
-		Key:                    p.Key,
-		SetupKey:               p.SetupKey,
-		IP:                     p.IP,
-		Meta:                   p.Meta,
-		Name:                   p.Name,
-		DNSLabel:               p.DNSLabel,
-		Status:                 peerStatus,
-		UserID:                 p.UserID,
-		SSHKey:                 p.SSHKey,
-		SSHEnabled:             p.SSHEnabled,
-		LoginExpirationEnabled: p.LoginExpirationEnabled,
-		LastLogin:              p.LastLogin,
-		Ephemeral:              p.Ephemeral,
+		ID:                       p.ID,
+		AccountID:                p.AccountID,
+		Key:                      p.Key,
+		SetupKey:                 p.SetupKey,
+		IP:                       p.IP,
+		Meta:                     p.Meta,
+		Name:                     p.Name,
+		DNSLabel:                 p.DNSLabel,
+		Status:                   peerStatus,
+		UserID:                   p.UserID,
+		SSHKey:                   p.SSHKey,
+		SSHEnabled:               p.SSHEnabled,
+		SSHPortForwardingEnabled: p.SSHPortForwardingEnabled,
+		LoginExpirationEnabled:   p.LoginExpirationEnabled,
+		LastLogin:                p.LastLogin,
+		Ephemeral:                p.Ephemeral,
 	}
 }
 
@@ -2,6 +2,7 @@ package server
 
 import (
 	_ "embed"
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -94,10 +95,32 @@ type PolicyRule struct {
 	// Protocol type of the traffic
 	Protocol PolicyRuleProtocolType
 
-	// Ports or it ranges list
+	// Ports or it ranges list, a range is written like 8000-8100
 	Ports []string `gorm:"serializer:json"`
 }
 
+// ParsePortRange parses a port or an inclusive range of ports like 8000-8100, a single port is returned
+// as a range with the same start and end
+func ParsePortRange(port string) (int, int, error) {
+	startValue, endValue, isRange := strings.Cut(port, "-")
+	start, err := strconv.Atoi(startValue)
+	if err != nil || start < 1 || start > 65535 {
+		return 0, 0, fmt.Errorf("valid port value is in 1..65535 range")
+	}
+	if !isRange {
+		return start, start, nil
+	}
+
+	end, err := strconv.Atoi(endValue)
+	if err != nil || end < 1 || end > 65535 {
+		return 0, 0, fmt.Errorf("valid port value is in 1..65535 range")
+	}
+	if start > end {
+		return 0, 0, fmt.Errorf("port range start %d is greater than its end %d", start, end)
+	}
+	return start, end, nil
+}
+
 // Copy returns a copy of a policy rule
 func (pm *PolicyRule) Copy() *PolicyRule {
 	rule := &PolicyRule{
@@ -200,10 +223,13 @@ type FirewallRule struct {
 	// Protocol of the traffic
 	Protocol string
 
-	// Port of the traffic
+	// Port of the traffic, a range of ports like 8000-8100 or a list of ports like 80,443
 	Port string
 }
 
+// maxPortsPerRule is the largest number of ports in the list of a firewall rule, iptables matches up to 15 ports
+const maxPortsPerRule = 15
+
 // getPeerConnectionResources for a given peer
 //
 // This function returns the list of peers and firewall rules that are applicable to a given peer.
@@ -297,7 +323,7 @@ func (a *Account) connResourcesGenerator() (func(*PolicyRule, []*nbpeer.Peer, in
 					continue
 				}
 
-				for _, port := range rule.Ports {
+				for _, port := range firewallRulePorts(rule.Ports) {
 					pr := fr // clone rule and add set new port
 					pr.Port = port
 					rules = append(rules, &pr)
@@ -308,6 +334,26 @@ func (a *Account) connResourcesGenerator() (func(*PolicyRule, []*nbpeer.Peer, in
 		}
 }
 
+// firewallRulePorts returns the ports of the firewall rules of a policy rule: the single ports are grouped into lists,
+// each range gets a rule of its own
+func firewallRulePorts(ports []string) []string {
+	var singlePorts, rulePorts []string
+	for _, port := range ports {
+		if strings.Contains(port, "-") {
+			rulePorts = append(rulePorts, port)
+		} else {
+			singlePorts = append(singlePorts, port)
+		}
+	}
+
+	for len(singlePorts) > 0 {
+		n := min(len(singlePorts), maxPortsPerRule)
+		rulePorts = append(rulePorts, strings.Join(singlePorts[:n], ","))
+		singlePorts = singlePorts[n:]
+	}
+	return rulePorts
+}
+
 // GetPolicy from the store
 func (am *DefaultAccountManager) GetPolicy(accountID, policyID, userID string) (*Policy, error) {
 	unlock := am.Store.AcquireAccountLock(accountID)
@@ -473,12 +519,55 @@ func toProtocolFirewallRules(update []*FirewallRule) []*proto.FirewallRule {
 			Direction: direction,
 			Action:    action,
 			Protocol:  protocol,
-			Port:      update[i].Port,
+			Port:      toProtocolLegacyPort(update[i].Port),
+			PortInfo:  toProtocolPortInfo(update[i].Port),
 		}
 	}
 	return result
 }
 
+// toProtocolLegacyPort returns the single port of the rule for the clients which don't support PortInfo, the first
+// port of a range or a list. These clients fail on anything but a single port and would drop all the rules, with the
+// first port they only allow a part of the traffic of the rule
+func toProtocolLegacyPort(port string) string {
+	first, _, _ := strings.Cut(port, ",")
+	first, _, _ = strings.Cut(first, "-")
+	return first
+}
+
+// toProtocolPortInfo returns the structured form of the port, the range or the list of ports, nil for the rules of all
+// ports
+func toProtocolPortInfo(port string) *proto.PortInfo {
+	if port == "" {
+		return nil
+	}
+
+	if strings.Contains(port, ",") {
+		list := &proto.PortInfo_List{}
+		for _, value := range strings.Split(port, ",") {
+			start, end, err := ParsePortRange(value)
+			if err != nil || start != end {
+				log.Errorf("invalid port %s in the ports %s of firewall rule: %v", value, port, err)
+				return nil
+			}
+			list.Ports = append(list.Ports, uint32(start))
+		}
+		return &proto.PortInfo{PortSelection: &proto.PortInfo_List_{List: list}}
+	}
+
+	start, end, err := ParsePortRange(port)
+	if err != nil {
+		log.Errorf("invalid port %s of firewall rule: %v", port, err)
+		return nil
+	}
+	if start == end {
+		return &proto.PortInfo{PortSelection: &proto.PortInfo_Port{Port: uint32(start)}}
+	}
+	return &proto.PortInfo{PortSelection: &proto.PortInfo_Range_{
+		Range: &proto.PortInfo_Range{Start: uint32(start), End: uint32(end)},
+	}}
+}
+
 // getAllPeersFromGroups for given peer ID and list of groups
 //
 // Returns list of peers and boolean indicating if peer is in any of the groups
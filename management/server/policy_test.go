@@ -3,6 +3,7 @@ package server
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -459,3 +460,66 @@ func sortFunc() func(a *FirewallRule, b *FirewallRule) int {
 		return 0 // a is equal to b
 	}
 }
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		port          string
+		expectedStart int
+		expectedEnd   int
+		expectErr     bool
+	}{
+		{port: "80", expectedStart: 80, expectedEnd: 80},
+		{port: "8000-8100", expectedStart: 8000, expectedEnd: 8100},
+		{port: "1-65535", expectedStart: 1, expectedEnd: 65535},
+		{port: "8100-8000", expectErr: true},
+		{port: "0", expectErr: true},
+		{port: "80-70000", expectErr: true},
+		{port: "http", expectErr: true},
+		{port: "80-", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.port, func(t *testing.T) {
+			start, end, err := ParsePortRange(tc.port)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStart, start)
+			assert.Equal(t, tc.expectedEnd, end)
+		})
+	}
+}
+
+func TestToProtocolFirewallRulesPortInfo(t *testing.T) {
+	rules := toProtocolFirewallRules([]*FirewallRule{
+		{PeerIP: "100.65.1.1", Protocol: string(PolicyRuleProtocolTCP), Port: "443"},
+		{PeerIP: "100.65.1.1", Protocol: string(PolicyRuleProtocolTCP), Port: "8000-8100"},
+		{PeerIP: "100.65.1.1", Protocol: string(PolicyRuleProtocolALL)},
+		{PeerIP: "100.65.1.1", Protocol: string(PolicyRuleProtocolUDP), Port: "53,5353"},
+	})
+
+	assert.Equal(t, uint32(443), rules[0].PortInfo.GetPort())
+	assert.Equal(t, "443", rules[0].Port)
+	assert.Equal(t, uint32(8000), rules[1].PortInfo.GetRange().GetStart())
+	assert.Equal(t, uint32(8100), rules[1].PortInfo.GetRange().GetEnd())
+	assert.Equal(t, "8000", rules[1].Port, "older clients should get a single port of the range")
+	assert.Nil(t, rules[2].PortInfo)
+	assert.Empty(t, rules[2].Port)
+	assert.Equal(t, []uint32{53, 5353}, rules[3].PortInfo.GetList().GetPorts())
+	assert.Equal(t, "53", rules[3].Port, "older clients should get a single port of the list")
+}
+
+func TestFirewallRulePorts(t *testing.T) {
+	assert.Empty(t, firewallRulePorts(nil))
+	assert.Equal(t, []string{"8000-8100", "80,443"}, firewallRulePorts([]string{"80", "8000-8100", "443"}),
+		"single ports should be grouped into a list and ranges kept apart")
+
+	ports := make([]string, 0, 20)
+	for port := 1; port <= 20; port++ {
+		ports = append(ports, strconv.Itoa(port))
+	}
+	assert.Equal(t, []string{"1,2,3,4,5,6,7,8,9,10,11,12,13,14,15", "16,17,18,19,20"}, firewallRulePorts(ports),
+		"lists should be split at the multiport limit")
+}
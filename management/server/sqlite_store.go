@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -36,6 +37,13 @@ type installation struct {
 	InstallationIDValue string
 }
 
+// dnsBlockListDomains holds the fetched domains of a DNS block list in their own table
+type dnsBlockListDomains struct {
+	BlockListID string   `gorm:"primaryKey"`
+	AccountID   string   `gorm:"index"`
+	Domains     []string `gorm:"serializer:json"`
+}
+
 // NewSqliteStore restores a store from the file located in the datadir
 func NewSqliteStore(dataDir string, metrics telemetry.AppMetrics) (*SqliteStore, error) {
 	storeStr := "store.db?cache=shared"
@@ -63,7 +71,7 @@ func NewSqliteStore(dataDir string, metrics telemetry.AppMetrics) (*SqliteStore,
 	err = db.AutoMigrate(
 		&SetupKey{}, &nbpeer.Peer{}, &User{}, &PersonalAccessToken{}, &Group{}, &Rule{},
 		&Account{}, &Policy{}, &PolicyRule{}, &route.Route{}, &nbdns.NameServerGroup{},
-		&installation{}, &account.ExtraSettings{},
+		&nbdns.BlockList{}, &dnsBlockListDomains{}, &SSHPolicy{}, &installation{}, &account.ExtraSettings{},
 	)
 	if err != nil {
 		return nil, err
@@ -89,6 +97,11 @@ func NewSqliteStoreFromFileStore(filestore *FileStore, dataDir string, metrics t
 		if err != nil {
 			return nil, err
 		}
+
+		err = copyDNSBlockListDomains(filestore, store, account)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return store, nil
@@ -171,6 +184,16 @@ func (s *SqliteStore) SaveAccount(account *Account) error {
 		account.NameServerGroupsG = append(account.NameServerGroupsG, *ns)
 	}
 
+	for id, blockList := range account.DNSBlockLists {
+		blockList.ID = id
+		account.DNSBlockListsG = append(account.DNSBlockListsG, *blockList)
+	}
+
+	for id, sshPolicy := range account.SSHPolicies {
+		sshPolicy.ID = id
+		account.SSHPoliciesG = append(account.SSHPoliciesG, *sshPolicy)
+	}
+
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		result := tx.Select(clause.Associations).Delete(account.Policies, "account_id = ?", account.Id)
 		if result.Error != nil {
@@ -224,6 +247,11 @@ func (s *SqliteStore) DeleteAccount(account *Account) error {
 			return result.Error
 		}
 
+		result = tx.Delete(&dnsBlockListDomains{}, "account_id = ?", account.Id)
+		if result.Error != nil {
+			return result.Error
+		}
+
 		return nil
 	})
 
@@ -253,6 +281,30 @@ func (s *SqliteStore) GetInstallationID() string {
 	return installation.InstallationIDValue
 }
 
+// SaveDNSBlockListDomains stores the fetched domains of the DNS block list in their own table
+func (s *SqliteStore) SaveDNSBlockListDomains(accountID, blockListID string, domains []string) error {
+	blockListDomains := dnsBlockListDomains{BlockListID: blockListID, AccountID: accountID, Domains: domains}
+	return s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&blockListDomains).Error
+}
+
+// GetDNSBlockListDomains returns the fetched domains of the DNS block list
+func (s *SqliteStore) GetDNSBlockListDomains(accountID, blockListID string) ([]string, error) {
+	var blockListDomains dnsBlockListDomains
+	result := s.db.First(&blockListDomains, "account_id = ? and block_list_id = ?", accountID, blockListID)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return blockListDomains.Domains, nil
+}
+
+// DeleteDNSBlockListDomains deletes the fetched domains of the DNS block list
+func (s *SqliteStore) DeleteDNSBlockListDomains(accountID, blockListID string) error {
+	return s.db.Delete(&dnsBlockListDomains{}, "account_id = ? and block_list_id = ?", accountID, blockListID).Error
+}
+
 func (s *SqliteStore) SavePeerStatus(accountID, peerID string, peerStatus nbpeer.PeerStatus) error {
 	var peer nbpeer.Peer
 
@@ -421,6 +473,18 @@ func (s *SqliteStore) GetAccount(accountID string) (*Account, error) {
 	}
 	account.NameServerGroupsG = nil
 
+	account.DNSBlockLists = make(map[string]*nbdns.BlockList, len(account.DNSBlockListsG))
+	for _, blockList := range account.DNSBlockListsG {
+		account.DNSBlockLists[blockList.ID] = blockList.Copy()
+	}
+	account.DNSBlockListsG = nil
+
+	account.SSHPolicies = make(map[string]*SSHPolicy, len(account.SSHPoliciesG))
+	for _, sshPolicy := range account.SSHPoliciesG {
+		account.SSHPolicies[sshPolicy.ID] = sshPolicy.Copy()
+	}
+	account.SSHPoliciesG = nil
+
 	return &account, nil
 }
 
@@ -213,6 +213,49 @@ func TestSqlite_SavePeerStatus(t *testing.T) {
 	assert.Equal(t, newStatus, *actual)
 }
 
+func TestSqlite_DNSBlockListDomains(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The SQLite store is not properly supported by Windows yet")
+	}
+
+	store := newSqliteStore(t)
+
+	account := newAccountWithId("account_id", "testuser", "")
+	err := store.SaveAccount(account)
+	require.NoError(t, err)
+
+	domains, err := store.GetDNSBlockListDomains(account.Id, "blocklist")
+	require.NoError(t, err)
+	assert.Nil(t, domains, "domains of a block list that was never fetched should be nil")
+
+	err = store.SaveDNSBlockListDomains(account.Id, "blocklist", []string{"ads.example.com"})
+	require.NoError(t, err)
+
+	err = store.SaveDNSBlockListDomains(account.Id, "blocklist", []string{"ads.example.com", "tracker.example.com"})
+	require.NoError(t, err)
+
+	domains, err = store.GetDNSBlockListDomains(account.Id, "blocklist")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ads.example.com", "tracker.example.com"}, domains)
+
+	err = store.DeleteDNSBlockListDomains(account.Id, "blocklist")
+	require.NoError(t, err)
+
+	domains, err = store.GetDNSBlockListDomains(account.Id, "blocklist")
+	require.NoError(t, err)
+	assert.Nil(t, domains)
+
+	err = store.SaveDNSBlockListDomains(account.Id, "blocklist", []string{"ads.example.com"})
+	require.NoError(t, err)
+
+	err = store.DeleteAccount(account)
+	require.NoError(t, err)
+
+	domains, err = store.GetDNSBlockListDomains(account.Id, "blocklist")
+	require.NoError(t, err)
+	assert.Nil(t, domains, "domains should be deleted with the account")
+}
+
 func TestSqlite_TestGetAccountByPrivateDomain(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("The SQLite store is not properly supported by Windows yet")
@@ -0,0 +1,416 @@
+package server
+
+import (
+	"regexp"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/xid"
+
+	nbdns "github.com/netbirdio/netbird/dns"
+	"github.com/netbirdio/netbird/management/server/activity"
+	nbpeer "github.com/netbirdio/netbird/management/server/peer"
+	"github.com/netbirdio/netbird/management/server/status"
+)
+
+const (
+	// SSHLocalUserWildcard allows the sources of an SSH policy to log in as any local user
+	SSHLocalUserWildcard = "*"
+
+	// SSHSessionStarted is the type of the event reported when an SSH session starts
+	SSHSessionStarted SSHSessionEventType = "started"
+	// SSHSessionEnded is the type of the event reported when an SSH session ends
+	SSHSessionEnded SSHSessionEventType = "ended"
+)
+
+// sshLocalUserRegexp matches the names of the local users supported by the common operating systems
+var sshLocalUserRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.][a-zA-Z0-9_.@\\-]{0,63}\$?$`)
+
+// SSHPolicy grants NetBird users and peer groups SSH access as local users to the peers of the destination groups.
+// Once an account has an enabled SSH policy, the SSH servers of its peers only accept logins granted by the policies
+type SSHPolicy struct {
+	// ID of the SSH policy
+	ID string `gorm:"primaryKey"`
+	// AccountID is a reference to Account that this object belongs
+	AccountID string `json:"-" gorm:"index"`
+	// Name of the SSH policy
+	Name string
+	// Description of the SSH policy visible in the UI
+	Description string
+	// Enabled status of the SSH policy
+	Enabled bool
+	// SourceUsers list of IDs of NetBird users whose peers are granted access
+	SourceUsers []string `gorm:"serializer:json"`
+	// SourceGroups list of peer group IDs granted access
+	SourceGroups []string `gorm:"serializer:json"`
+	// Destinations list of peer group IDs the access is granted to
+	Destinations []string `gorm:"serializer:json"`
+	// LocalUsers list of local users the sources may log in as. "*" allows any local user
+	LocalUsers []string `gorm:"serializer:json"`
+}
+
+// EventMeta returns activity event meta related to the SSH policy
+func (p *SSHPolicy) EventMeta() map[string]any {
+	return map[string]any{"name": p.Name}
+}
+
+// Copy copies an SSH policy object
+func (p *SSHPolicy) Copy() *SSHPolicy {
+	return &SSHPolicy{
+		ID:           p.ID,
+		AccountID:    p.AccountID,
+		Name:         p.Name,
+		Description:  p.Description,
+		Enabled:      p.Enabled,
+		SourceUsers:  slices.Clone(p.SourceUsers),
+		SourceGroups: slices.Clone(p.SourceGroups),
+		Destinations: slices.Clone(p.Destinations),
+		LocalUsers:   slices.Clone(p.LocalUsers),
+	}
+}
+
+// SSHSessionEventType is the type of an SSH session event
+type SSHSessionEventType string
+
+// SSHSessionEvent is the start or the end of a session of a peer's SSH server reported by the peer
+type SSHSessionEvent struct {
+	Type SSHSessionEventType
+	// SessionID identifies the session in the events of its start and its end
+	SessionID string
+	// RemotePeerKey is the WireGuard public key of the peer that opened the session
+	RemotePeerKey string
+	// LocalUser is the local user the remote peer logged in as
+	LocalUser string
+	// Timestamp of the event on the reporting peer
+	Timestamp time.Time
+	// Duration of the session, set for ended sessions
+	Duration time.Duration
+}
+
+// GetSSHPolicy gets an SSH policy object from account and SSH policy IDs
+func (am *DefaultAccountManager) GetSSHPolicy(accountID, sshPolicyID string) (*SSHPolicy, error) {
+
+	unlock := am.Store.AcquireAccountLock(accountID)
+	defer unlock()
+
+	account, err := am.Store.GetAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	sshPolicy, found := account.SSHPolicies[sshPolicyID]
+	if found {
+		return sshPolicy.Copy(), nil
+	}
+
+	return nil, status.Errorf(status.NotFound, "SSH policy with ID %s not found", sshPolicyID)
+}
+
+// CreateSSHPolicy creates and saves a new SSH policy
+func (am *DefaultAccountManager) CreateSSHPolicy(accountID, userID string, sshPolicy *SSHPolicy) (*SSHPolicy, error) {
+
+	unlock := am.Store.AcquireAccountLock(accountID)
+	defer unlock()
+
+	if sshPolicy == nil {
+		return nil, status.Errorf(status.InvalidArgument, "SSH policy provided is nil")
+	}
+
+	account, err := am.Store.GetAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	newSSHPolicy := sshPolicy.Copy()
+	newSSHPolicy.ID = xid.New().String()
+	newSSHPolicy.AccountID = accountID
+
+	err = validateSSHPolicy(false, newSSHPolicy, account)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.SSHPolicies == nil {
+		account.SSHPolicies = make(map[string]*SSHPolicy)
+	}
+
+	account.SSHPolicies[newSSHPolicy.ID] = newSSHPolicy
+
+	account.Network.IncSerial()
+	err = am.Store.SaveAccount(account)
+	if err != nil {
+		return nil, err
+	}
+
+	am.updateAccountPeers(account)
+
+	am.StoreEvent(userID, newSSHPolicy.ID, accountID, activity.SSHPolicyCreated, newSSHPolicy.EventMeta())
+
+	return newSSHPolicy.Copy(), nil
+}
+
+// SaveSSHPolicy saves an SSH policy
+func (am *DefaultAccountManager) SaveSSHPolicy(accountID, userID string, sshPolicyToSave *SSHPolicy) error {
+
+	unlock := am.Store.AcquireAccountLock(accountID)
+	defer unlock()
+
+	if sshPolicyToSave == nil {
+		return status.Errorf(status.InvalidArgument, "SSH policy provided is nil")
+	}
+
+	account, err := am.Store.GetAccount(accountID)
+	if err != nil {
+		return err
+	}
+
+	sshPolicy := sshPolicyToSave.Copy()
+	sshPolicy.AccountID = accountID
+
+	err = validateSSHPolicy(true, sshPolicy, account)
+	if err != nil {
+		return err
+	}
+
+	account.SSHPolicies[sshPolicy.ID] = sshPolicy
+
+	account.Network.IncSerial()
+	err = am.Store.SaveAccount(account)
+	if err != nil {
+		return err
+	}
+
+	am.updateAccountPeers(account)
+
+	am.StoreEvent(userID, sshPolicy.ID, accountID, activity.SSHPolicyUpdated, sshPolicy.EventMeta())
+
+	return nil
+}
+
+// DeleteSSHPolicy deletes the SSH policy with sshPolicyID
+func (am *DefaultAccountManager) DeleteSSHPolicy(accountID, sshPolicyID, userID string) error {
+
+	unlock := am.Store.AcquireAccountLock(accountID)
+	defer unlock()
+
+	account, err := am.Store.GetAccount(accountID)
+	if err != nil {
+		return err
+	}
+
+	sshPolicy := account.SSHPolicies[sshPolicyID]
+	if sshPolicy == nil {
+		return status.Errorf(status.NotFound, "SSH policy %s wasn't found", sshPolicyID)
+	}
+	delete(account.SSHPolicies, sshPolicyID)
+
+	account.Network.IncSerial()
+	err = am.Store.SaveAccount(account)
+	if err != nil {
+		return err
+	}
+
+	am.updateAccountPeers(account)
+
+	am.StoreEvent(userID, sshPolicy.ID, accountID, activity.SSHPolicyDeleted, sshPolicy.EventMeta())
+
+	return nil
+}
+
+// ListSSHPolicies returns a list of SSH policies from account
+func (am *DefaultAccountManager) ListSSHPolicies(accountID string) ([]*SSHPolicy, error) {
+
+	unlock := am.Store.AcquireAccountLock(accountID)
+	defer unlock()
+
+	account, err := am.Store.GetAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	sshPolicies := make([]*SSHPolicy, 0, len(account.SSHPolicies))
+	for _, item := range account.SSHPolicies {
+		sshPolicies = append(sshPolicies, item.Copy())
+	}
+
+	return sshPolicies, nil
+}
+
+// StoreSSHSessionEvent stores the SSH session event reported by the peer with peerPubKey as an activity event.
+// The initiator of the event is the user of the remote peer that opened the session
+func (am *DefaultAccountManager) StoreSSHSessionEvent(peerPubKey string, event SSHSessionEvent) error {
+	account, err := am.Store.GetAccountByPeerPubKey(peerPubKey)
+	if err != nil {
+		return err
+	}
+
+	peer, err := account.FindPeerByPubKey(peerPubKey)
+	if err != nil {
+		return err
+	}
+
+	remotePeer, err := account.FindPeerByPubKey(event.RemotePeerKey)
+	if err != nil {
+		return status.Errorf(status.NotFound, "remote peer %s of SSH session %s not found", event.RemotePeerKey, event.SessionID)
+	}
+
+	var activityID activity.Activity
+	switch event.Type {
+	case SSHSessionStarted:
+		activityID = activity.PeerSSHSessionStarted
+	case SSHSessionEnded:
+		activityID = activity.PeerSSHSessionEnded
+	default:
+		return status.Errorf(status.InvalidArgument, "unknown SSH session event type %s", event.Type)
+	}
+
+	meta := peer.EventMeta(am.GetDNSDomain())
+	meta["session_id"] = event.SessionID
+	meta["local_user"] = event.LocalUser
+	meta["source_peer_name"] = remotePeer.Name
+	meta["source_peer_ip"] = remotePeer.IP
+	meta["time"] = event.Timestamp.UTC().Format(time.RFC3339)
+	if remotePeer.UserID != "" {
+		meta["user_id"] = remotePeer.UserID
+	}
+	if event.Type == SSHSessionEnded {
+		meta["duration"] = event.Duration.Round(time.Second).String()
+	}
+
+	initiatorID := remotePeer.UserID
+	if initiatorID == "" {
+		initiatorID = remotePeer.ID
+	}
+
+	am.StoreEvent(initiatorID, peer.ID, account.Id, activityID, meta)
+
+	return nil
+}
+
+// getPeerSSHAuthorizedUsers returns the local users each remote peer is allowed to log in as on the peer, indexed by
+// the remote peer ID, and whether the SSH server of the peer has to restrict the local users to them
+func getPeerSSHAuthorizedUsers(account *Account, peerID string, remotePeers []*nbpeer.Peer) (map[string][]string, bool) {
+	enforced := false
+	destinationGroups := account.getPeerGroups(peerID)
+
+	var peerPolicies []*SSHPolicy
+	for _, sshPolicy := range account.SSHPolicies {
+		if !sshPolicy.Enabled {
+			continue
+		}
+		enforced = true
+
+		for _, gID := range sshPolicy.Destinations {
+			if _, found := destinationGroups[gID]; found {
+				peerPolicies = append(peerPolicies, sshPolicy)
+				break
+			}
+		}
+	}
+
+	if len(peerPolicies) == 0 {
+		return nil, enforced
+	}
+
+	authorizedUsers := make(map[string][]string)
+	for _, remotePeer := range remotePeers {
+		sourceGroups := account.getPeerGroups(remotePeer.ID)
+
+		var localUsers []string
+		for _, sshPolicy := range peerPolicies {
+			if !sshPolicySourceMatches(sshPolicy, remotePeer, sourceGroups) {
+				continue
+			}
+			for _, localUser := range sshPolicy.LocalUsers {
+				if !slices.Contains(localUsers, localUser) {
+					localUsers = append(localUsers, localUser)
+				}
+			}
+		}
+
+		if len(localUsers) > 0 {
+			authorizedUsers[remotePeer.ID] = localUsers
+		}
+	}
+
+	return authorizedUsers, enforced
+}
+
+func sshPolicySourceMatches(sshPolicy *SSHPolicy, remotePeer *nbpeer.Peer, sourceGroups lookupMap) bool {
+	if remotePeer.UserID != "" && slices.Contains(sshPolicy.SourceUsers, remotePeer.UserID) {
+		return true
+	}
+
+	for _, gID := range sshPolicy.SourceGroups {
+		if _, found := sourceGroups[gID]; found {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateSSHPolicy(existingSSHPolicy bool, sshPolicy *SSHPolicy, account *Account) error {
+	sshPolicyID := ""
+	if existingSSHPolicy {
+		sshPolicyID = sshPolicy.ID
+		_, found := account.SSHPolicies[sshPolicyID]
+		if !found {
+			return status.Errorf(status.NotFound, "SSH policy with ID %s was not found", sshPolicyID)
+		}
+	}
+
+	err := validateSSHPolicyName(sshPolicy.Name, sshPolicyID, account.SSHPolicies)
+	if err != nil {
+		return err
+	}
+
+	if len(sshPolicy.SourceUsers) == 0 && len(sshPolicy.SourceGroups) == 0 {
+		return status.Errorf(status.InvalidArgument, "SSH policy should have at least one source user or group")
+	}
+
+	for _, userID := range sshPolicy.SourceUsers {
+		if _, found := account.Users[userID]; !found {
+			return status.Errorf(status.InvalidArgument, "user %s not found", userID)
+		}
+	}
+
+	if len(sshPolicy.SourceGroups) > 0 {
+		err = validateGroups(sshPolicy.SourceGroups, account.Groups)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = validateGroups(sshPolicy.Destinations, account.Groups)
+	if err != nil {
+		return err
+	}
+
+	if len(sshPolicy.LocalUsers) == 0 {
+		return status.Errorf(status.InvalidArgument, "SSH policy should have at least one local user")
+	}
+
+	for _, localUser := range sshPolicy.LocalUsers {
+		if localUser != SSHLocalUserWildcard && !sshLocalUserRegexp.MatchString(localUser) {
+			return status.Errorf(status.InvalidArgument, "SSH policy got an invalid local user %q", localUser)
+		}
+	}
+
+	return nil
+}
+
+func validateSSHPolicyName(name, sshPolicyID string, sshPolicies map[string]*SSHPolicy) error {
+	if utf8.RuneCountInString(name) > nbdns.MaxGroupNameChar || name == "" {
+		return status.Errorf(status.InvalidArgument, "SSH policy name should be between 1 and %d", nbdns.MaxGroupNameChar)
+	}
+
+	for _, sshPolicy := range sshPolicies {
+		if name == sshPolicy.Name && sshPolicy.ID != sshPolicyID {
+			return status.Errorf(status.InvalidArgument, "an SSH policy with name %s already exist", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/netbirdio/netbird/management/server/activity"
+	nbpeer "github.com/netbirdio/netbird/management/server/peer"
+)
+
+func TestCreateSSHPolicy(t *testing.T) {
+	testCases := []struct {
+		name      string
+		sshPolicy *SSHPolicy
+		errFunc   require.ErrorAssertionFunc
+	}{
+		{
+			name: "Create An SSH Policy With Source Users",
+			sshPolicy: &SSHPolicy{
+				Name:         "admins",
+				Enabled:      true,
+				SourceUsers:  []string{userID},
+				Destinations: []string{group1ID},
+				LocalUsers:   []string{"root", "ubuntu"},
+			},
+			errFunc: require.NoError,
+		},
+		{
+			name: "Create An SSH Policy With Source Groups And Any Local User",
+			sshPolicy: &SSHPolicy{
+				Name:         "devs",
+				Enabled:      true,
+				SourceGroups: []string{group2ID},
+				Destinations: []string{group1ID},
+				LocalUsers:   []string{SSHLocalUserWildcard},
+			},
+			errFunc: require.NoError,
+		},
+		{
+			name: "Should Not Create If Name Is Empty",
+			sshPolicy: &SSHPolicy{
+				SourceUsers:  []string{userID},
+				Destinations: []string{group1ID},
+				LocalUsers:   []string{"root"},
+			},
+			errFunc: require.Error,
+		},
+		{
+			name: "Should Not Create Without Sources",
+			sshPolicy: &SSHPolicy{
+				Name:         "admins",
+				Destinations: []string{group1ID},
+				LocalUsers:   []string{"root"},
+			},
+			errFunc: require.Error,
+		},
+		{
+			name: "Should Not Create If Source User Doesn't Exist",
+			sshPolicy: &SSHPolicy{
+				Name:         "admins",
+				SourceUsers:  []string{"missingUser"},
+				Destinations: []string{group1ID},
+				LocalUsers:   []string{"root"},
+			},
+			errFunc: require.Error,
+		},
+		{
+			name: "Should Not Create If Destination Group Doesn't Exist",
+			sshPolicy: &SSHPolicy{
+				Name:         "admins",
+				SourceUsers:  []string{userID},
+				Destinations: []string{"missingGroup"},
+				LocalUsers:   []string{"root"},
+			},
+			errFunc: require.Error,
+		},
+		{
+			name: "Should Not Create Without Local Users",
+			sshPolicy: &SSHPolicy{
+				Name:         "admins",
+				SourceUsers:  []string{userID},
+				Destinations: []string{group1ID},
+			},
+			errFunc: require.Error,
+		},
+		{
+			name: "Should Not Create If Local User Is Invalid",
+			sshPolicy: &SSHPolicy{
+				Name:         "admins",
+				SourceUsers:  []string{userID},
+				Destinations: []string{group1ID},
+				LocalUsers:   []string{"root; rm -rf /"},
+			},
+			errFunc: require.Error,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			am, err := createNSManager(t)
+			require.NoError(t, err, "failed to create account manager")
+
+			account, err := initTestNSAccount(t, am)
+			require.NoError(t, err, "failed to init testing account")
+
+			created, err := am.CreateSSHPolicy(account.Id, userID, testCase.sshPolicy)
+			testCase.errFunc(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.NotEmpty(t, created.ID)
+			assert.Equal(t, testCase.sshPolicy.LocalUsers, created.LocalUsers)
+
+			savedAccount, err := am.Store.GetAccount(account.Id)
+			require.NoError(t, err)
+			assert.Contains(t, savedAccount.SSHPolicies, created.ID)
+		})
+	}
+}
+
+func TestSSHPolicyLifecycle(t *testing.T) {
+	am, err := createNSManager(t)
+	require.NoError(t, err, "failed to create account manager")
+
+	account, err := initTestNSAccount(t, am)
+	require.NoError(t, err, "failed to init testing account")
+
+	created, err := am.CreateSSHPolicy(account.Id, userID, &SSHPolicy{
+		Name:         "admins",
+		Enabled:      true,
+		SourceUsers:  []string{userID},
+		Destinations: []string{group1ID},
+		LocalUsers:   []string{"root"},
+	})
+	require.NoError(t, err)
+
+	_, err = am.CreateSSHPolicy(account.Id, userID, &SSHPolicy{
+		Name:         "admins",
+		SourceUsers:  []string{userID},
+		Destinations: []string{group1ID},
+		LocalUsers:   []string{"root"},
+	})
+	require.Error(t, err, "SSH policy names should be unique")
+
+	update := created.Copy()
+	update.LocalUsers = []string{"ubuntu"}
+	require.NoError(t, am.SaveSSHPolicy(account.Id, userID, update))
+
+	saved, err := am.GetSSHPolicy(account.Id, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ubuntu"}, saved.LocalUsers)
+
+	err = am.DeleteGroup(account.Id, userID, group1ID)
+	require.Error(t, err, "group linked to an SSH policy shouldn't be deleted")
+
+	sshPolicies, err := am.ListSSHPolicies(account.Id)
+	require.NoError(t, err)
+	require.Len(t, sshPolicies, 1)
+
+	require.NoError(t, am.DeleteSSHPolicy(account.Id, created.ID, userID))
+
+	_, err = am.GetSSHPolicy(account.Id, created.ID)
+	require.Error(t, err, "SSH policy shouldn't be found after delete")
+}
+
+func TestGetPeerSSHAuthorizedUsers(t *testing.T) {
+	peers := map[string]*nbpeer.Peer{
+		"destination": {ID: "destination"},
+		"admin":       {ID: "admin", UserID: "adminUser"},
+		"dev":         {ID: "dev", UserID: "devUser"},
+		"server":      {ID: "server"},
+	}
+	account := &Account{
+		Peers: peers,
+		Groups: map[string]*Group{
+			"servers": {ID: "servers", Peers: []string{"destination", "server"}},
+			"devs":    {ID: "devs", Peers: []string{"dev"}},
+		},
+		SSHPolicies: map[string]*SSHPolicy{
+			"admins": {
+				ID:           "admins",
+				Enabled:      true,
+				SourceUsers:  []string{"adminUser"},
+				Destinations: []string{"servers"},
+				LocalUsers:   []string{SSHLocalUserWildcard},
+			},
+			"devs": {
+				ID:           "devs",
+				Enabled:      true,
+				SourceGroups: []string{"devs"},
+				Destinations: []string{"servers"},
+				LocalUsers:   []string{"ubuntu", "deploy"},
+			},
+			"disabled": {
+				ID:           "disabled",
+				SourceGroups: []string{"devs"},
+				Destinations: []string{"servers"},
+				LocalUsers:   []string{"root"},
+			},
+		},
+	}
+
+	remotePeers := []*nbpeer.Peer{peers["admin"], peers["dev"], peers["server"]}
+
+	authorizedUsers, enforced := getPeerSSHAuthorizedUsers(account, "destination", remotePeers)
+	assert.True(t, enforced)
+	assert.Equal(t, map[string][]string{
+		"admin": {SSHLocalUserWildcard},
+		"dev":   {"ubuntu", "deploy"},
+	}, authorizedUsers)
+
+	authorizedUsers, enforced = getPeerSSHAuthorizedUsers(account, "dev", []*nbpeer.Peer{peers["admin"]})
+	assert.True(t, enforced, "peers outside of the destinations should deny all logins once a policy is enabled")
+	assert.Empty(t, authorizedUsers)
+
+	for _, sshPolicy := range account.SSHPolicies {
+		sshPolicy.Enabled = false
+	}
+	authorizedUsers, enforced = getPeerSSHAuthorizedUsers(account, "destination", remotePeers)
+	assert.False(t, enforced, "logins shouldn't be restricted without enabled policies")
+	assert.Empty(t, authorizedUsers)
+}
+
+func TestStoreSSHSessionEvent(t *testing.T) {
+	am, err := createNSManager(t)
+	require.NoError(t, err, "failed to create account manager")
+
+	account, err := initTestNSAccount(t, am)
+	require.NoError(t, err, "failed to init testing account")
+
+	err = am.StoreSSHSessionEvent(nsGroupPeer1Key, SSHSessionEvent{
+		Type:          SSHSessionEnded,
+		SessionID:     "session1",
+		RemotePeerKey: nsGroupPeer2Key,
+		LocalUser:     "root",
+		Timestamp:     time.Now(),
+		Duration:      90 * time.Second,
+	})
+	require.NoError(t, err)
+
+	var event *activity.Event
+	require.Eventually(t, func() bool {
+		events, err := am.eventStore.Get(account.Id, 0, 100, false)
+		if err != nil {
+			return false
+		}
+		for _, e := range events {
+			if e.Activity == activity.PeerSSHSessionEnded {
+				event = e
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 50*time.Millisecond, "SSH session event should be stored")
+
+	assert.Equal(t, userID, event.InitiatorID)
+	assert.Equal(t, "root", event.Meta["local_user"])
+	assert.Equal(t, "1m30s", event.Meta["duration"])
+
+	err = am.StoreSSHSessionEvent(nsGroupPeer1Key, SSHSessionEvent{
+		Type:          SSHSessionStarted,
+		SessionID:     "session2",
+		RemotePeerKey: "unknownPeerKey",
+		LocalUser:     "root",
+		Timestamp:     time.Now(),
+	})
+	require.Error(t, err, "sessions of unknown peers shouldn't be stored")
+}
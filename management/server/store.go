@@ -34,6 +34,11 @@ type Store interface {
 	AcquireGlobalLock() func()
 	SavePeerStatus(accountID, peerID string, status nbpeer.PeerStatus) error
 	SaveUserLastLogin(accountID, userID string, lastLogin time.Time) error
+	// SaveDNSBlockListDomains stores the domains fetched for a DNS block list apart from the account
+	SaveDNSBlockListDomains(accountID, blockListID string, domains []string) error
+	// GetDNSBlockListDomains returns the domains fetched for a DNS block list, nil if they were never fetched
+	GetDNSBlockListDomains(accountID, blockListID string) ([]string, error)
+	DeleteDNSBlockListDomains(accountID, blockListID string) error
 	// Close should close the store persisting all unsaved data.
 	Close() error
 	// GetStoreEngine should return StoreEngine of the current store implementation.
@@ -41,6 +46,25 @@ type Store interface {
 	GetStoreEngine() StoreEngine
 }
 
+// copyDNSBlockListDomains copies the fetched domains of the DNS block lists of the account between stores
+func copyDNSBlockListDomains(from, to Store, account *Account) error {
+	for blockListID := range account.DNSBlockLists {
+		domains, err := from.GetDNSBlockListDomains(account.Id, blockListID)
+		if err != nil {
+			return fmt.Errorf("get domains of DNS block list %s: %w", blockListID, err)
+		}
+		if domains == nil {
+			continue
+		}
+
+		err = to.SaveDNSBlockListDomains(account.Id, blockListID, domains)
+		if err != nil {
+			return fmt.Errorf("save domains of DNS block list %s: %w", blockListID, err)
+		}
+	}
+	return nil
+}
+
 type StoreEngine string
 
 const (
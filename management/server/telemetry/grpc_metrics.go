@@ -16,6 +16,8 @@ type GRPCMetrics struct {
 	syncRequestsCounter   syncint64.Counter
 	loginRequestsCounter  syncint64.Counter
 	getKeyRequestsCounter syncint64.Counter
+	syncThrottledCounter  syncint64.Counter
+	loginThrottledCounter syncint64.Counter
 	activeStreamsGauge    asyncint64.Gauge
 	syncRequestDuration   syncint64.Histogram
 	loginRequestDuration  syncint64.Histogram
@@ -38,6 +40,15 @@ func NewGRPCMetrics(ctx context.Context, meter metric.Meter) (*GRPCMetrics, erro
 		return nil, err
 	}
 
+	syncThrottledCounter, err := meter.SyncInt64().Counter("management.grpc.sync.throttled.counter", instrument.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+	loginThrottledCounter, err := meter.SyncInt64().Counter("management.grpc.login.throttled.counter", instrument.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+
 	activeStreamsGauge, err := meter.AsyncInt64().Gauge("management.grpc.connected.streams", instrument.WithUnit("1"))
 	if err != nil {
 		return nil, err
@@ -70,6 +81,8 @@ func NewGRPCMetrics(ctx context.Context, meter metric.Meter) (*GRPCMetrics, erro
 		syncRequestsCounter:   syncRequestsCounter,
 		loginRequestsCounter:  loginRequestsCounter,
 		getKeyRequestsCounter: getKeyRequestsCounter,
+		syncThrottledCounter:  syncThrottledCounter,
+		loginThrottledCounter: loginThrottledCounter,
 		activeStreamsGauge:    activeStreamsGauge,
 		syncRequestDuration:   syncRequestDuration,
 		loginRequestDuration:  loginRequestDuration,
@@ -93,6 +106,16 @@ func (grpcMetrics *GRPCMetrics) CountLoginRequest() {
 	grpcMetrics.loginRequestsCounter.Add(grpcMetrics.ctx, 1)
 }
 
+// CountSyncRequestThrottled counts the number of gRPC sync requests rejected because of the rate limits
+func (grpcMetrics *GRPCMetrics) CountSyncRequestThrottled() {
+	grpcMetrics.syncThrottledCounter.Add(grpcMetrics.ctx, 1)
+}
+
+// CountLoginRequestThrottled counts the number of gRPC login requests rejected because of the rate limits
+func (grpcMetrics *GRPCMetrics) CountLoginRequestThrottled() {
+	grpcMetrics.loginThrottledCounter.Add(grpcMetrics.ctx, 1)
+}
+
 // CountLoginRequestDuration counts the duration of the login gRPC requests
 func (grpcMetrics *GRPCMetrics) CountLoginRequestDuration(duration time.Duration) {
 	grpcMetrics.loginRequestDuration.Record(grpcMetrics.ctx, duration.Milliseconds())
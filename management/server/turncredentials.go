@@ -11,19 +11,25 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/netbirdio/netbird/management/proto"
+	relayAuth "github.com/netbirdio/netbird/relay/auth"
 )
 
-// TURNCredentialsManager used to manage TURN credentials
+// defaultRelayCredentialsTTL is used when the relay config doesn't set the TTL of the credentials
+const defaultRelayCredentialsTTL = 24 * time.Hour
+
+// TURNCredentialsManager used to manage TURN and relay credentials
 type TURNCredentialsManager interface {
 	GenerateCredentials() TURNCredentials
-	SetupRefresh(peerKey string)
-	CancelRefresh(peerKey string)
+	GenerateRelayCredentials(peerKey string) TURNCredentials
+	SetupRefresh(peerID, peerKey string)
+	CancelRefresh(peerID string)
 }
 
-// TimeBasedAuthSecretsManager generates credentials with TTL and using pre-shared secret known to TURN server
+// TimeBasedAuthSecretsManager generates credentials with TTL and using pre-shared secret known to TURN and relay servers
 type TimeBasedAuthSecretsManager struct {
 	mux           sync.Mutex
 	config        *TURNConfig
+	relayConfig   *RelayConfig
 	updateManager *PeersUpdateManager
 	cancelMap     map[string]chan struct{}
 }
@@ -33,10 +39,11 @@ type TURNCredentials struct {
 	Password string
 }
 
-func NewTimeBasedAuthSecretsManager(updateManager *PeersUpdateManager, config *TURNConfig) *TimeBasedAuthSecretsManager {
+func NewTimeBasedAuthSecretsManager(updateManager *PeersUpdateManager, config *TURNConfig, relayConfig *RelayConfig) *TimeBasedAuthSecretsManager {
 	return &TimeBasedAuthSecretsManager{
 		mux:           sync.Mutex{},
 		config:        config,
+		relayConfig:   relayConfig,
 		updateManager: updateManager,
 		cancelMap:     make(map[string]chan struct{}),
 	}
@@ -65,6 +72,23 @@ func (m *TimeBasedAuthSecretsManager) GenerateCredentials() TURNCredentials {
 
 }
 
+// GenerateRelayCredentials generates new time-based credentials for the relay servers, the same way as for TURN but
+// with the secret and TTL of the relay config. The credentials only authenticate the peer with the WireGuard public key
+func (m *TimeBasedAuthSecretsManager) GenerateRelayCredentials(peerKey string) TURNCredentials {
+	username, password := relayAuth.GenerateCredentials(m.relayConfig.Secret, peerKey, m.relayCredentialsTTL())
+	return TURNCredentials{
+		Username: username,
+		Password: password,
+	}
+}
+
+func (m *TimeBasedAuthSecretsManager) relayCredentialsTTL() time.Duration {
+	if m.relayConfig.CredentialsTTL.Duration <= 0 {
+		return defaultRelayCredentialsTTL
+	}
+	return m.relayConfig.CredentialsTTL.Duration
+}
+
 func (m *TimeBasedAuthSecretsManager) cancel(peerID string) {
 	if channel, ok := m.cancelMap[peerID]; ok {
 		close(channel)
@@ -80,46 +104,83 @@ func (m *TimeBasedAuthSecretsManager) CancelRefresh(peerID string) {
 }
 
 // SetupRefresh starts peer credentials refresh. Since credentials are expiring (TTL) it is necessary to always generate them and send to the peer.
-// A goroutine is created and put into TimeBasedAuthSecretsManager.cancelMap. This routine should be cancelled if peer is gone.
-func (m *TimeBasedAuthSecretsManager) SetupRefresh(peerID string) {
+// Goroutines are created for the TURN and the relay credentials and their cancel channel is put into TimeBasedAuthSecretsManager.cancelMap.
+// These routines should be cancelled if peer is gone. The relay credentials are bound to the WireGuard public key of the peer.
+func (m *TimeBasedAuthSecretsManager) SetupRefresh(peerID, peerKey string) {
 	m.mux.Lock()
 	defer m.mux.Unlock()
 	m.cancel(peerID)
 	cancel := make(chan struct{}, 1)
 	m.cancelMap[peerID] = cancel
-	log.Debugf("starting turn refresh for %s", peerID)
-
-	go func() {
-		// we don't want to regenerate credentials right on expiration, so we do it slightly before (at 3/4 of TTL)
-		ticker := time.NewTicker(m.config.CredentialsTTL.Duration / 4 * 3)
-
-		for {
-			select {
-			case <-cancel:
-				log.Debugf("stopping turn refresh for %s", peerID)
-				return
-			case <-ticker.C:
-				c := m.GenerateCredentials()
-				var turns []*proto.ProtectedHostConfig
-				for _, host := range m.config.Turns {
-					turns = append(turns, &proto.ProtectedHostConfig{
-						HostConfig: &proto.HostConfig{
-							Uri:      host.URI,
-							Protocol: ToResponseProto(host.Proto),
-						},
+
+	if m.config != nil && m.config.TimeBasedCredentials {
+		log.Debugf("starting turn refresh for %s", peerID)
+		go m.refreshTURNCredentials(peerID, cancel)
+	}
+
+	if m.relayConfig != nil && len(m.relayConfig.Addresses) > 0 {
+		log.Debugf("starting relay credentials refresh for %s", peerID)
+		go m.refreshRelayCredentials(peerID, peerKey, cancel)
+	}
+}
+
+func (m *TimeBasedAuthSecretsManager) refreshTURNCredentials(peerID string, cancel chan struct{}) {
+	// we don't want to regenerate credentials right on expiration, so we do it slightly before (at 3/4 of TTL)
+	ticker := time.NewTicker(m.config.CredentialsTTL.Duration / 4 * 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cancel:
+			log.Debugf("stopping turn refresh for %s", peerID)
+			return
+		case <-ticker.C:
+			c := m.GenerateCredentials()
+			var turns []*proto.ProtectedHostConfig
+			for _, host := range m.config.Turns {
+				turns = append(turns, &proto.ProtectedHostConfig{
+					HostConfig: &proto.HostConfig{
+						Uri:      host.URI,
+						Protocol: ToResponseProto(host.Proto),
+					},
+					User:     c.Username,
+					Password: c.Password,
+				})
+			}
+
+			update := &proto.SyncResponse{
+				WiretrusteeConfig: &proto.WiretrusteeConfig{
+					Turns: turns,
+				},
+			}
+			log.Debugf("sending new TURN credentials to peer %s", peerID)
+			m.updateManager.SendUpdate(peerID, &UpdateMessage{Update: update})
+		}
+	}
+}
+
+func (m *TimeBasedAuthSecretsManager) refreshRelayCredentials(peerID, peerKey string, cancel chan struct{}) {
+	ticker := time.NewTicker(m.relayCredentialsTTL() / 4 * 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cancel:
+			log.Debugf("stopping relay credentials refresh for %s", peerID)
+			return
+		case <-ticker.C:
+			c := m.GenerateRelayCredentials(peerKey)
+			update := &proto.SyncResponse{
+				WiretrusteeConfig: &proto.WiretrusteeConfig{
+					Relay: &proto.RelayConfig{
+						Urls:     m.relayConfig.Addresses,
 						User:     c.Username,
 						Password: c.Password,
-					})
-				}
-
-				update := &proto.SyncResponse{
-					WiretrusteeConfig: &proto.WiretrusteeConfig{
-						Turns: turns,
 					},
-				}
-				log.Debugf("sending new TURN credentials to peer %s", peerID)
-				m.updateManager.SendUpdate(peerID, &UpdateMessage{Update: update})
+				},
 			}
+			log.Debugf("sending new relay credentials to peer %s", peerID)
+			m.updateManager.SendUpdate(peerID, &UpdateMessage{Update: update})
 		}
-	}()
+	}
 }
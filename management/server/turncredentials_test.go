@@ -4,12 +4,17 @@ import (
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
+	"strings"
 	"testing"
 	"time"
 
+	relayAuth "github.com/netbirdio/netbird/relay/auth"
 	"github.com/netbirdio/netbird/util"
 )
 
+// relayTestPeerKey is the WireGuard public key the relay credentials are issued for
+const relayTestPeerKey = "RlSy2vzoG2HyMBTUImXOiVhCBiiBa5qD5xzMxkiFDW4="
+
 var TurnTestHost = &Host{
 	Proto:    UDP,
 	URI:      "turn:turn.wiretrustee.com:77777",
@@ -23,10 +28,11 @@ func TestTimeBasedAuthSecretsManager_GenerateCredentials(t *testing.T) {
 	peersManager := NewPeersUpdateManager(nil)
 
 	tested := NewTimeBasedAuthSecretsManager(peersManager, &TURNConfig{
-		CredentialsTTL: ttl,
-		Secret:         secret,
-		Turns:          []*Host{TurnTestHost},
-	})
+		TimeBasedCredentials: true,
+		CredentialsTTL:       ttl,
+		Secret:               secret,
+		Turns:                []*Host{TurnTestHost},
+	}, nil)
 
 	credentials := tested.GenerateCredentials()
 
@@ -49,12 +55,13 @@ func TestTimeBasedAuthSecretsManager_SetupRefresh(t *testing.T) {
 	updateChannel := peersManager.CreateChannel(peer)
 
 	tested := NewTimeBasedAuthSecretsManager(peersManager, &TURNConfig{
-		CredentialsTTL: ttl,
-		Secret:         secret,
-		Turns:          []*Host{TurnTestHost},
-	})
+		TimeBasedCredentials: true,
+		CredentialsTTL:       ttl,
+		Secret:               secret,
+		Turns:                []*Host{TurnTestHost},
+	}, nil)
 
-	tested.SetupRefresh(peer)
+	tested.SetupRefresh(peer, relayTestPeerKey)
 
 	if _, ok := tested.cancelMap[peer]; !ok {
 		t.Errorf("expecting peer to be present in a cancel map, got not present")
@@ -97,12 +104,13 @@ func TestTimeBasedAuthSecretsManager_CancelRefresh(t *testing.T) {
 	peer := "some_peer"
 
 	tested := NewTimeBasedAuthSecretsManager(peersManager, &TURNConfig{
-		CredentialsTTL: ttl,
-		Secret:         secret,
-		Turns:          []*Host{TurnTestHost},
-	})
+		TimeBasedCredentials: true,
+		CredentialsTTL:       ttl,
+		Secret:               secret,
+		Turns:                []*Host{TurnTestHost},
+	}, nil)
 
-	tested.SetupRefresh(peer)
+	tested.SetupRefresh(peer, relayTestPeerKey)
 	if _, ok := tested.cancelMap[peer]; !ok {
 		t.Errorf("expecting peer to be present in a cancel map, got not present")
 	}
@@ -113,6 +121,67 @@ func TestTimeBasedAuthSecretsManager_CancelRefresh(t *testing.T) {
 	}
 }
 
+func TestTimeBasedAuthSecretsManager_GenerateRelayCredentials(t *testing.T) {
+	secret := "some_secret"
+	peersManager := NewPeersUpdateManager(nil)
+
+	tested := NewTimeBasedAuthSecretsManager(peersManager, &TURNConfig{}, &RelayConfig{
+		Addresses:      []string{"wss://relay.netbird.io:443/relay"},
+		CredentialsTTL: util.Duration{Duration: time.Hour},
+		Secret:         secret,
+	})
+
+	credentials := tested.GenerateRelayCredentials(relayTestPeerKey)
+
+	credentialsKey, err := relayAuth.NewValidator(secret).Validate(credentials.Username, credentials.Password)
+	if err != nil {
+		t.Errorf("expected generated relay credentials to be valid, got %v", err)
+	}
+	if credentialsKey != relayTestPeerKey {
+		t.Errorf("expected relay credentials bound to peer key %s, got %s", relayTestPeerKey, credentialsKey)
+	}
+	validateMAC(t, credentials.Username, credentials.Password, []byte(secret))
+}
+
+func TestTimeBasedAuthSecretsManager_SetupRelayRefresh(t *testing.T) {
+	peersManager := NewPeersUpdateManager(nil)
+	peer := "some_peer"
+	updateChannel := peersManager.CreateChannel(peer)
+	relayAddresses := []string{"wss://relay.netbird.io:443/relay"}
+
+	tested := NewTimeBasedAuthSecretsManager(peersManager, &TURNConfig{}, &RelayConfig{
+		Addresses:      relayAddresses,
+		CredentialsTTL: util.Duration{Duration: 2 * time.Second},
+		Secret:         "some_secret",
+	})
+
+	tested.SetupRefresh(peer, relayTestPeerKey)
+	defer tested.CancelRefresh(peer)
+
+	select {
+	case update := <-updateChannel:
+		config := update.Update.GetWiretrusteeConfig()
+		if len(config.GetTurns()) != 0 {
+			t.Errorf("expecting no TURN credentials update, got %v", config.GetTurns())
+		}
+		relay := config.GetRelay()
+		if relay == nil {
+			t.Fatal("expecting relay credentials update, got none")
+		}
+		if len(relay.Urls) != 1 || relay.Urls[0] != relayAddresses[0] {
+			t.Errorf("expecting relay URLs %v, got %v", relayAddresses, relay.Urls)
+		}
+		if relay.User == "" || relay.Password == "" {
+			t.Errorf("expecting relay credentials, got empty")
+		}
+		if !strings.HasSuffix(relay.User, ":"+relayTestPeerKey) {
+			t.Errorf("expecting relay credentials bound to peer key %s, got username %s", relayTestPeerKey, relay.User)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expecting relay credentials update, got none")
+	}
+}
+
 func validateMAC(t *testing.T, username string, actualMAC string, key []byte) {
 	t.Helper()
 	mac := hmac.New(sha1.New, key)
@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -454,6 +455,13 @@ func (am *DefaultAccountManager) DeleteUser(accountID, initiatorUserID string, t
 		return status.Errorf(status.PermissionDenied, "only integration service user can delete this user")
 	}
 
+	// the SSH policies granting access to the user would reference a missing user
+	for _, sshPolicy := range account.SSHPolicies {
+		if slices.Contains(sshPolicy.SourceUsers, targetUserID) {
+			return status.Errorf(status.PreconditionFailed, "user is linked to SSH policy %s, remove it from the policy first", sshPolicy.Name)
+		}
+	}
+
 	// handle service user first and exit, no need to fetch extra data from IDP, etc
 	if targetUser.IsServiceUser {
 		if targetUser.NonDeletable {
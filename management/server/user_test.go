@@ -645,6 +645,50 @@ func TestUser_DeleteUser_regularUser(t *testing.T) {
 
 }
 
+func TestUser_DeleteUser_LinkedToSSHPolicy(t *testing.T) {
+	store := newStore(t)
+	account := newAccountWithId(mockAccountID, mockUserID, "")
+	account.Users[mockServiceUserID] = &User{
+		Id:              mockServiceUserID,
+		IsServiceUser:   true,
+		ServiceUserName: mockServiceUserName,
+	}
+	account.SSHPolicies = map[string]*SSHPolicy{
+		"sshPolicy": {
+			ID:          "sshPolicy",
+			Name:        "ssh",
+			Enabled:     true,
+			SourceUsers: []string{mockServiceUserID},
+			LocalUsers:  []string{"root"},
+		},
+	}
+
+	err := store.SaveAccount(account)
+	if err != nil {
+		t.Fatalf("Error when saving account: %s", err)
+	}
+
+	am := DefaultAccountManager{
+		Store:      store,
+		eventStore: &activity.InMemoryEventStore{},
+	}
+
+	err = am.DeleteUser(mockAccountID, mockUserID, mockServiceUserID)
+	assert.Error(t, err, "deleting a user linked to an SSH policy should fail")
+	assert.NotNil(t, store.Accounts[mockAccountID].Users[mockServiceUserID])
+
+	account.SSHPolicies["sshPolicy"].SourceUsers = nil
+	account.SSHPolicies["sshPolicy"].SourceGroups = []string{"group"}
+	err = store.SaveAccount(account)
+	if err != nil {
+		t.Fatalf("Error when saving account: %s", err)
+	}
+
+	err = am.DeleteUser(mockAccountID, mockUserID, mockServiceUserID)
+	assert.NoError(t, err, "a user unlinked from the SSH policy should be deleted")
+	assert.Nil(t, store.Accounts[mockAccountID].Users[mockServiceUserID])
+}
+
 func TestDefaultAccountManager_GetUser(t *testing.T) {
 	store := newStore(t)
 	account := newAccountWithId(mockAccountID, mockUserID, "")
@@ -0,0 +1,102 @@
+// Package ratelimit limits the rate of the requests of the clients with a token bucket per client key, e.g. the
+// WireGuard public key of a peer or its IP address
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/peer"
+)
+
+// cleanupInterval is the interval of removing the buckets of the keys which haven't sent requests recently
+const cleanupInterval = time.Minute
+
+// Config is a rate limit of Rate requests per second with bursts of up to Burst requests. A Rate of zero disables
+// the limit
+type Config struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled returns true if the config limits the requests
+func (c Config) Enabled() bool {
+	return c.Rate > 0
+}
+
+// Limiter limits the rate of the requests per key
+type Limiter struct {
+	config Config
+
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewLimiter creates a Limiter allowing every key the requests of the config
+func NewLimiter(config Config) *Limiter {
+	if config.Burst < 1 {
+		config.Burst = 1
+	}
+	return &Limiter{
+		config:      config,
+		buckets:     make(map[string]*bucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+// Allow consumes a token of the key and returns false if the key has exceeded its rate
+func (l *Limiter) Allow(key string) bool {
+	if l == nil || !l.config.Enabled() {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastCleanup) > cleanupInterval {
+		l.cleanup(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(l.config.Rate), l.config.Burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+	return b.limiter.AllowN(now, 1)
+}
+
+// cleanup removes the buckets which have been refilled since their last request, they behave like new ones
+func (l *Limiter) cleanup(now time.Time) {
+	refill := time.Duration(float64(l.config.Burst) / l.config.Rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > refill {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastCleanup = now
+}
+
+// PeerIP returns the IP address of the gRPC client of the context, empty if it is unknown
+func PeerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/peer"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	limiter := NewLimiter(Config{Rate: 1, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, limiter.Allow("peerA"), "request %d within the burst", i)
+	}
+	assert.False(t, limiter.Allow("peerA"), "request exceeding the burst")
+	assert.True(t, limiter.Allow("peerB"), "other keys have their own bucket")
+}
+
+func TestLimiter_Disabled(t *testing.T) {
+	limiter := NewLimiter(Config{})
+	for i := 0; i < 100; i++ {
+		assert.True(t, limiter.Allow("peerA"))
+	}
+
+	var nilLimiter *Limiter
+	assert.True(t, nilLimiter.Allow("peerA"))
+}
+
+func TestLimiter_Cleanup(t *testing.T) {
+	limiter := NewLimiter(Config{Rate: 1000, Burst: 1})
+	assert.True(t, limiter.Allow("peerA"))
+
+	limiter.cleanup(time.Now().Add(time.Second))
+	assert.Empty(t, limiter.buckets, "refilled buckets are removed")
+}
+
+func TestPeerIP(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("100.64.0.1"), Port: 51820},
+	})
+	assert.Equal(t, "100.64.0.1", PeerIP(ctx))
+	assert.Empty(t, PeerIP(context.Background()))
+}
@@ -0,0 +1,72 @@
+// Package auth validates the time based credentials the Management service issues for the relay servers.
+//
+// The username is the unix time the credentials expire at and the WireGuard public key of the peer they are issued
+// for, separated by a colon, and the password the base64 encoded HMAC-SHA1 of the username with a secret shared by the
+// Management service and the relay servers, the same scheme as the TURN REST API
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the password doesn't match the username
+	ErrInvalidCredentials = errors.New("invalid relay credentials")
+	// ErrExpiredCredentials is returned when the credentials have expired
+	ErrExpiredCredentials = errors.New("expired relay credentials")
+)
+
+// Validator validates the credentials of the relay clients
+type Validator struct {
+	secret []byte
+}
+
+// NewValidator creates a Validator for the credentials generated with the secret
+func NewValidator(secret string) *Validator {
+	return &Validator{secret: []byte(secret)}
+}
+
+// Validate returns the WireGuard public key of the peer the credentials are issued for, or an error if the credentials
+// are invalid or have expired
+func (v *Validator) Validate(username, password string) (string, error) {
+	timestamp, peerKey, ok := strings.Cut(username, ":")
+	if !ok || peerKey == "" {
+		return "", fmt.Errorf("%w: username isn't formatted as timestamp:peerKey", ErrInvalidCredentials)
+	}
+	expiry, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: username doesn't start with a timestamp", ErrInvalidCredentials)
+	}
+
+	expected := sign(v.secret, username)
+	received, err := base64.StdEncoding.DecodeString(password)
+	if err != nil || !hmac.Equal(expected, received) {
+		return "", ErrInvalidCredentials
+	}
+
+	if time.Now().Unix() > expiry {
+		return "", ErrExpiredCredentials
+	}
+	return peerKey, nil
+}
+
+// GenerateCredentials returns credentials of the peer with the WireGuard public key valid for the ttl
+func GenerateCredentials(secret, peerKey string, ttl time.Duration) (string, string) {
+	username := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10) + ":" + peerKey
+	password := base64.StdEncoding.EncodeToString(sign([]byte(secret), username))
+	return username, password
+}
+
+func sign(secret []byte, username string) []byte {
+	mac := hmac.New(sha1.New, secret)
+	// writing to a hash never fails
+	_, _ = mac.Write([]byte(username))
+	return mac.Sum(nil)
+}
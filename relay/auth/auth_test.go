@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator_Validate(t *testing.T) {
+	validator := NewValidator("secret")
+
+	username, password := GenerateCredentials("secret", "peerA", time.Hour)
+	peerKey, err := validator.Validate(username, password)
+	assert.NoError(t, err, "valid credentials")
+	assert.Equal(t, "peerA", peerKey, "the credentials are bound to the peer key")
+
+	_, otherPassword := GenerateCredentials("other-secret", "peerA", time.Hour)
+	_, err = validator.Validate(username, otherPassword)
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "credentials of another secret")
+
+	otherUsername, _ := GenerateCredentials("secret", "peerB", time.Hour)
+	_, err = validator.Validate(otherUsername, password)
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "password of another peer")
+
+	_, err = validator.Validate(username, "not base64")
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "invalid password")
+
+	timestamp := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	_, err = validator.Validate(timestamp, signedPassword("secret", timestamp))
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "username without peer key")
+
+	_, err = validator.Validate("user:peerA", signedPassword("secret", "user:peerA"))
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "username isn't a timestamp")
+
+	expired := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10) + ":peerA"
+	_, err = validator.Validate(expired, signedPassword("secret", expired))
+	assert.ErrorIs(t, err, ErrExpiredCredentials, "expired credentials")
+}
+
+func signedPassword(secret, username string) string {
+	return base64.StdEncoding.EncodeToString(sign([]byte(secret), username))
+}
@@ -0,0 +1,226 @@
+// Package client connects to the relay servers, it multiplexes the connections to the remote peers over one
+// WebSocket connection per relay server.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"nhooyr.io/websocket"
+
+	"github.com/netbirdio/netbird/relay/messages"
+)
+
+const (
+	writeTimeout = 5 * time.Second
+	// keepAliveInterval keeps the connection through proxies and NATs which drop idle connections
+	keepAliveInterval = 25 * time.Second
+)
+
+// ErrClientClosed is returned when the connection to the relay server has been closed
+var ErrClientClosed = errors.New("relay client is closed")
+
+// Client is a connection to a relay server
+type Client struct {
+	serverURL string
+	peerKey   string
+	ws        *websocket.Conn
+
+	mu     sync.Mutex
+	conns  map[string]*Conn
+	closed bool
+	done   chan struct{}
+	cancel context.CancelFunc
+}
+
+// Connect connects to the relay server as the peer with the WireGuard public key. The server URL has the ws or wss
+// scheme
+func Connect(ctx context.Context, serverURL, peerKey, username, password string) (*Client, error) {
+	return connect(ctx, serverURL, peerKey, username, password, nil)
+}
+
+// connect connects to the relay server with the HTTP client, the default one if nil
+func connect(ctx context.Context, serverURL, peerKey, username, password string, httpClient *http.Client) (*Client, error) {
+	req, err := http.NewRequest(http.MethodGet, serverURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parse relay server URL: %w", err)
+	}
+	req.SetBasicAuth(username, password)
+	req.Header.Set(messages.HeaderPeerKey, peerKey)
+
+	ws, _, err := websocket.Dial(ctx, serverURL, &websocket.DialOptions{HTTPClient: httpClient, HTTPHeader: req.Header})
+	if err != nil {
+		return nil, fmt.Errorf("connect to relay server %s: %w", serverURL, err)
+	}
+	ws.SetReadLimit(messages.MaxMessageSize)
+
+	clientCtx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		serverURL: serverURL,
+		peerKey:   peerKey,
+		ws:        ws,
+		conns:     make(map[string]*Conn),
+		done:      make(chan struct{}),
+		cancel:    cancel,
+	}
+	go c.readLoop(clientCtx)
+	go c.keepAlive(clientCtx)
+
+	log.Infof("connected to relay server %s", serverURL)
+	return c, nil
+}
+
+// OpenConn opens a connection to the remote peer, it replaces a previous connection to the same peer
+func (c *Client) OpenConn(peerKey string) (*Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrClientClosed
+	}
+
+	if previous, ok := c.conns[peerKey]; ok {
+		previous.close()
+	}
+	conn := newConn(c, peerKey)
+	c.conns[peerKey] = conn
+	return conn, nil
+}
+
+// Done returns a channel which is closed once the connection to the relay server has been closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the connection to the relay server and all the connections to the remote peers
+func (c *Client) Close() error {
+	err := c.ws.Close(websocket.StatusNormalClosure, "")
+	c.shutdown()
+	return err
+}
+
+func (c *Client) shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.cancel()
+
+	for peerKey, conn := range c.conns {
+		conn.close()
+		delete(c.conns, peerKey)
+	}
+	close(c.done)
+}
+
+func (c *Client) send(peerKey string, payload []byte) error {
+	msg, err := messages.MarshalTransport(peerKey, payload)
+	if err != nil {
+		return err
+	}
+	return c.write(msg)
+}
+
+func (c *Client) write(msg []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	return c.ws.Write(ctx, websocket.MessageBinary, msg)
+}
+
+// removeConn removes the connection to the remote peer and tells the peer about it
+func (c *Client) removeConn(conn *Conn) {
+	c.mu.Lock()
+	current := c.conns[conn.peerKey] == conn
+	if current {
+		delete(c.conns, conn.peerKey)
+	}
+	closed := c.closed
+	c.mu.Unlock()
+
+	if !current || closed {
+		return
+	}
+
+	msg, err := messages.MarshalClose(conn.peerKey)
+	if err != nil {
+		return
+	}
+	if err := c.write(msg); err != nil {
+		log.Debugf("failed to notify peer %s about the closed relay connection: %v", conn.peerKey, err)
+	}
+}
+
+func (c *Client) readLoop(ctx context.Context) {
+	defer c.shutdown()
+
+	for {
+		msgType, msg, err := c.ws.Read(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warnf("lost the connection to relay server %s: %v", c.serverURL, err)
+			}
+			return
+		}
+		if msgType != websocket.MessageBinary {
+			continue
+		}
+
+		relayMsgType, peerKey, payload, err := messages.Unmarshal(msg)
+		if err != nil {
+			log.Debugf("dropping invalid message from relay server %s: %v", c.serverURL, err)
+			continue
+		}
+
+		c.mu.Lock()
+		conn, ok := c.conns[peerKey]
+		if ok && relayMsgType == messages.MsgClose {
+			delete(c.conns, peerKey)
+		}
+		c.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		switch relayMsgType {
+		case messages.MsgTransport:
+			conn.receive(payload)
+		case messages.MsgClose:
+			log.Debugf("peer %s has closed its relay connection", peerKey)
+			conn.close()
+		}
+	}
+}
+
+func (c *Client) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, writeTimeout)
+			err := c.ws.Ping(pingCtx)
+			cancel()
+			if err != nil && ctx.Err() == nil {
+				log.Warnf("relay server %s doesn't respond: %v", c.serverURL, err)
+				_ = c.ws.CloseNow()
+				return
+			}
+		}
+	}
+}
+
+// compile time check that Conn can be used by the WireGuard proxies
+var _ net.Conn = (*Conn)(nil)
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/netbirdio/netbird/relay/auth"
+	"github.com/netbirdio/netbird/relay/server"
+)
+
+const testSecret = "secret"
+
+func startRelayServer(t *testing.T) string {
+	t.Helper()
+
+	relayServer := server.NewServer(testSecret)
+	httpServer := httptest.NewServer(relayServer)
+	t.Cleanup(func() {
+		relayServer.Close()
+		httpServer.Close()
+	})
+	return "ws" + strings.TrimPrefix(httpServer.URL, "http")
+}
+
+func connectPeer(t *testing.T, serverURL, peerKey string) *Client {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	username, password := auth.GenerateCredentials(testSecret, peerKey, time.Hour)
+	client, err := Connect(ctx, serverURL, peerKey, username, password)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return client
+}
+
+func readWithTimeout(t *testing.T, conn *Conn) []byte {
+	t.Helper()
+
+	type result struct {
+		packet []byte
+		err    error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		buf := make([]byte, 1500)
+		n, err := conn.Read(buf)
+		resultCh <- result{buf[:n], err}
+	}()
+
+	select {
+	case r := <-resultCh:
+		require.NoError(t, r.err)
+		return r.packet
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a packet")
+		return nil
+	}
+}
+
+func TestClient_RelayPackets(t *testing.T) {
+	serverURL := startRelayServer(t)
+
+	clientA := connectPeer(t, serverURL, "peerA")
+	clientB := connectPeer(t, serverURL, "peerB")
+
+	connA, err := clientA.OpenConn("peerB")
+	require.NoError(t, err)
+	connB, err := clientB.OpenConn("peerA")
+	require.NoError(t, err)
+
+	_, err = connA.Write([]byte("ping"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ping"), readWithTimeout(t, connB))
+
+	_, err = connB.Write([]byte("pong"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("pong"), readWithTimeout(t, connA))
+
+	require.NoError(t, connA.Close())
+	select {
+	case <-connB.Closed():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the remote peer to close the connection")
+	}
+}
+
+func TestClient_RemotePeerDisconnected(t *testing.T) {
+	serverURL := startRelayServer(t)
+
+	clientA := connectPeer(t, serverURL, "peerA")
+	clientB := connectPeer(t, serverURL, "peerB")
+
+	connA, err := clientA.OpenConn("peerB")
+	require.NoError(t, err)
+	connB, err := clientB.OpenConn("peerA")
+	require.NoError(t, err)
+
+	// the server tells the contacts of a peer about its disconnection
+	_, err = connA.Write([]byte("ping"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ping"), readWithTimeout(t, connB))
+	require.NoError(t, clientB.Close())
+
+	select {
+	case <-connA.Closed():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the connection to the disconnected peer to be closed")
+	}
+}
+
+func TestConnect_InvalidCredentials(t *testing.T) {
+	serverURL := startRelayServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	username, password := auth.GenerateCredentials("other-secret", "peerA", time.Hour)
+	_, err := Connect(ctx, serverURL, "peerA", username, password)
+	assert.Error(t, err)
+}
+
+func TestConnect_CredentialsOfAnotherPeer(t *testing.T) {
+	serverURL := startRelayServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	username, password := auth.GenerateCredentials(testSecret, "peerA", time.Hour)
+	_, err := Connect(ctx, serverURL, "peerB", username, password)
+	assert.Error(t, err, "the credentials of a peer shouldn't authenticate another one")
+}
+
+func newTestManager(t *testing.T, peerKey string, httpServer *httptest.Server) *Manager {
+	t.Helper()
+
+	manager := NewManager(peerKey)
+	manager.httpClient = httpServer.Client()
+	t.Cleanup(manager.Close)
+	return manager
+}
+
+func TestManager_OpenConn(t *testing.T) {
+	relayServer := server.NewServer(testSecret)
+	httpServer := httptest.NewTLSServer(relayServer)
+	t.Cleanup(func() {
+		relayServer.Close()
+		httpServer.Close()
+	})
+	serverURL := "wss" + strings.TrimPrefix(httpServer.URL, "https")
+
+	managerA := newTestManager(t, "peerA", httpServer)
+	usernameA, passwordA := auth.GenerateCredentials(testSecret, "peerA", time.Hour)
+	managerA.UpdateServers([]string{serverURL}, usernameA, passwordA)
+	assert.Equal(t, serverURL, managerA.ServerURL())
+
+	managerB := newTestManager(t, "peerB", httpServer)
+	usernameB, passwordB := auth.GenerateCredentials(testSecret, "peerB", time.Hour)
+	managerB.UpdateServers([]string{serverURL}, usernameB, passwordB)
+
+	connA, err := managerA.OpenConn(serverURL, "peerB")
+	require.NoError(t, err)
+	connB, err := managerB.OpenConn(managerA.ServerURL(), "peerA")
+	require.NoError(t, err)
+
+	_, err = connB.Write([]byte("ping"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ping"), readWithTimeout(t, connA))
+
+	// the connections to the same server share the client
+	connC, err := managerA.OpenConn(serverURL, "peerC")
+	require.NoError(t, err)
+	assert.Same(t, connA.client, connC.client)
+}
+
+func TestManager_ServerNotAllowed(t *testing.T) {
+	serverURL := startRelayServer(t)
+
+	manager := NewManager("peerA")
+	defer manager.Close()
+
+	username, password := auth.GenerateCredentials(testSecret, "peerA", time.Hour)
+	manager.UpdateServers([]string{serverURL, "wss://relay.netbird.io:443/relay"}, username, password)
+	assert.Equal(t, "wss://relay.netbird.io:443/relay", manager.ServerURL(), "the servers without TLS should be ignored")
+	assert.False(t, manager.IsAllowed(serverURL))
+
+	_, err := manager.OpenConn(serverURL, "peerB")
+	assert.ErrorIs(t, err, ErrServerNotAllowed, "a server without TLS shouldn't be used")
+
+	_, err = manager.OpenConn("wss://attacker.example.com/relay", "peerB")
+	assert.ErrorIs(t, err, ErrServerNotAllowed, "a server of the remote peer shouldn't be used")
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// receiveQueueSize is the number of packets a Conn buffers until they are read, later packets are dropped
+const receiveQueueSize = 256
+
+// Addr is the address of a peer reachable through a relay server
+type Addr struct {
+	ServerURL string
+	PeerKey   string
+}
+
+// Network returns the network name of the relayed connections
+func (a Addr) Network() string {
+	return "relay"
+}
+
+func (a Addr) String() string {
+	return a.ServerURL + "/" + a.PeerKey
+}
+
+// Conn is a connection to a remote peer through a relay server. Every Write sends a packet and every Read returns one
+type Conn struct {
+	client  *Client
+	peerKey string
+
+	packets   chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newConn(client *Client, peerKey string) *Conn {
+	return &Conn{
+		client:  client,
+		peerKey: peerKey,
+		packets: make(chan []byte, receiveQueueSize),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Read reads the next packet from the remote peer
+func (c *Conn) Read(b []byte) (int, error) {
+	select {
+	case packet := <-c.packets:
+		return copy(b, packet), nil
+	case <-c.closed:
+		return 0, net.ErrClosed
+	}
+}
+
+// Write sends the packet to the remote peer
+func (c *Conn) Write(b []byte) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+
+	if err := c.client.send(c.peerKey, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close closes the connection and tells the remote peer about it
+func (c *Conn) Close() error {
+	if c.close() {
+		c.client.removeConn(c)
+	}
+	return nil
+}
+
+// Closed returns a channel which is closed once the connection has been closed, either locally, by the remote peer or
+// because the connection to the relay server has been lost
+func (c *Conn) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// LocalAddr returns the address of the relay server
+func (c *Conn) LocalAddr() net.Addr {
+	return Addr{ServerURL: c.client.serverURL, PeerKey: c.client.peerKey}
+}
+
+// RemoteAddr returns the address of the remote peer on the relay server
+func (c *Conn) RemoteAddr() net.Addr {
+	return Addr{ServerURL: c.client.serverURL, PeerKey: c.peerKey}
+}
+
+// SetDeadline is not supported
+func (c *Conn) SetDeadline(time.Time) error {
+	return nil
+}
+
+// SetReadDeadline is not supported
+func (c *Conn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline is not supported
+func (c *Conn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// receive queues a packet of the remote peer, it is dropped if the reader falls behind
+func (c *Conn) receive(packet []byte) {
+	select {
+	case c.packets <- packet:
+	case <-c.closed:
+	default:
+	}
+}
+
+// close returns true if the connection was open
+func (c *Conn) close() bool {
+	closed := false
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		closed = true
+	})
+	return closed
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const connectTimeout = 10 * time.Second
+
+// ErrServerNotAllowed is returned when opening a connection through a relay server the Management service didn't
+// provide
+var ErrServerNotAllowed = errors.New("relay server isn't provided by the Management service")
+
+// Manager keeps the connections to the relay servers. It connects to a server once the first connection to a remote
+// peer is opened through it, so the peers which reach each other directly don't use a relay server at all
+type Manager struct {
+	peerKey string
+	// httpClient connects to the relay servers, the default client if nil
+	httpClient *http.Client
+
+	mu         sync.Mutex
+	serverURLs []string
+	username   string
+	password   string
+	clients    map[string]*Client
+}
+
+// NewManager creates a Manager for the peer with the WireGuard public key
+func NewManager(peerKey string) *Manager {
+	return &Manager{
+		peerKey: peerKey,
+		clients: make(map[string]*Client),
+	}
+}
+
+// UpdateServers sets the relay servers of the Management service and the credentials for them. Only the servers
+// with the wss scheme are used, the first one is the relay server of this peer
+func (m *Manager) UpdateServers(serverURLs []string, username, password string) {
+	var allowed []string
+	for _, serverURL := range serverURLs {
+		if err := validateServerURL(serverURL); err != nil {
+			log.Warnf("ignoring relay server %s: %v", serverURL, err)
+			continue
+		}
+		allowed = append(allowed, serverURL)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(allowed) > 0 && (len(m.serverURLs) == 0 || allowed[0] != m.serverURLs[0]) {
+		log.Infof("relay server set to %s", allowed[0])
+	}
+	m.serverURLs = allowed
+	m.username = username
+	m.password = password
+}
+
+// ServerURL returns the URL of the relay server of this peer, it is empty if no relay server is configured
+func (m *Manager) ServerURL() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.serverURLs) == 0 {
+		return ""
+	}
+	return m.serverURLs[0]
+}
+
+// IsAllowed returns true if the relay server is one of the servers of the Management service
+func (m *Manager) IsAllowed(serverURL string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Contains(m.serverURLs, serverURL)
+}
+
+// OpenConn opens a connection to the remote peer through the relay server. The server can be the one of the remote
+// peer, both peers have to use the same one. It has to be one of the servers of the Management service
+func (m *Manager) OpenConn(serverURL, peerKey string) (*Conn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !slices.Contains(m.serverURLs, serverURL) {
+		return nil, fmt.Errorf("%w: %s", ErrServerNotAllowed, serverURL)
+	}
+
+	client, ok := m.clients[serverURL]
+	if ok {
+		select {
+		case <-client.Done():
+			ok = false
+		default:
+		}
+	}
+
+	if !ok {
+		ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+		defer cancel()
+
+		var err error
+		client, err = connect(ctx, serverURL, m.peerKey, m.username, m.password, m.httpClient)
+		if err != nil {
+			return nil, err
+		}
+		m.clients[serverURL] = client
+	}
+
+	return client.OpenConn(peerKey)
+}
+
+// Close closes the connections to the relay servers
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for serverURL, client := range m.clients {
+		if err := client.Close(); err != nil {
+			log.Debugf("failed to close the connection to relay server %s: %v", serverURL, err)
+		}
+		delete(m.clients, serverURL)
+	}
+}
+
+// validateServerURL checks the relay server is reached over TLS
+func validateServerURL(serverURL string) error {
+	parsedURL, err := url.Parse(serverURL)
+	if err != nil {
+		return err
+	}
+	if parsedURL.Scheme != "wss" {
+		return fmt.Errorf("the scheme %q isn't wss", parsedURL.Scheme)
+	}
+	if parsedURL.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/acme"
+
+	"github.com/netbirdio/netbird/encryption"
+	"github.com/netbirdio/netbird/relay/server"
+	"github.com/netbirdio/netbird/util"
+)
+
+// relayPath is the HTTP path of the WebSocket endpoint of the relay server
+const relayPath = "/relay"
+
+var (
+	relayPort              int
+	relayLetsencryptDomain string
+	relaySSLDir            string
+	defaultRelaySSLDir     string
+	relayCertFile          string
+	relayCertKey           string
+	relayAuthSecret        string
+
+	runCmd = &cobra.Command{
+		Use:   "run",
+		Short: "start NetBird Relay Server daemon",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			// detect whether user specified a port
+			if !cmd.Flag("port").Changed && tlsEnabled() {
+				relayPort = 443
+			}
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := util.InitLog(logLevel, logFile)
+			if err != nil {
+				log.Fatalf("failed initializing log %v", err)
+			}
+
+			if relayAuthSecret == "" {
+				return errors.New("the auth secret shared with the Management service is required")
+			}
+
+			tlsConfig, err := loadTLSConfig()
+			if err != nil {
+				return err
+			}
+
+			listener, err := net.Listen("tcp", fmt.Sprintf(":%d", relayPort))
+			if err != nil {
+				return err
+			}
+			if tlsConfig != nil {
+				listener = tls.NewListener(listener, tlsConfig)
+			}
+
+			relayServer := server.NewServer(relayAuthSecret)
+			mux := http.NewServeMux()
+			mux.Handle(relayPath, relayServer)
+			httpServer := &http.Server{Handler: mux}
+
+			go func() {
+				err := httpServer.Serve(listener)
+				if err != nil && !errors.Is(err, http.ErrServerClosed) {
+					notifyStop(fmt.Sprintf("failed running HTTP server %v", err))
+				}
+			}()
+			log.Infof("started Relay Service: %s, TLS enabled: %t", listener.Addr().String(), tlsConfig != nil)
+
+			SetupCloseHandler()
+
+			<-stopCh
+			_ = httpServer.Close()
+			relayServer.Close()
+			log.Infof("stopped Relay Service")
+
+			return nil
+		},
+	}
+)
+
+func tlsEnabled() bool {
+	return relayLetsencryptDomain != "" || (relayCertFile != "" && relayCertKey != "")
+}
+
+// loadTLSConfig returns the TLS config of the server, nil if TLS is disabled. The WebSocket connections require
+// HTTP/1.1, HTTP/2 is not offered
+func loadTLSConfig() (*tls.Config, error) {
+	if relayLetsencryptDomain != "" {
+		certManager, err := encryption.CreateCertManager(relaySSLDir, relayLetsencryptDomain)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig := certManager.TLSConfig()
+		tlsConfig.NextProtos = []string{"http/1.1", acme.ALPNProto}
+		return tlsConfig, nil
+	}
+
+	if relayCertFile != "" && relayCertKey != "" {
+		cert, err := tls.LoadX509KeyPair(relayCertFile, relayCertKey)
+		if err != nil {
+			return nil, err
+		}
+		return &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			NextProtos:   []string{"http/1.1"},
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func notifyStop(msg string) {
+	select {
+	case stopCh <- 1:
+		log.Error(msg)
+	default:
+		// stop has been already called, nothing to report
+	}
+}
+
+func init() {
+	runCmd.PersistentFlags().IntVar(&relayPort, "port", 80, "Server port to listen on (defaults to 443 if TLS is enabled, 80 otherwise")
+	runCmd.Flags().StringVar(&relaySSLDir, "ssl-dir", defaultRelaySSLDir, "server ssl directory location. *Required only for Let's Encrypt certificates.")
+	runCmd.Flags().StringVar(&relayLetsencryptDomain, "letsencrypt-domain", "", "a domain to issue Let's Encrypt certificate for. Enables TLS using Let's Encrypt. Will fetch and renew certificate, and run the server with TLS")
+	runCmd.Flags().StringVar(&relayCertFile, "cert-file", "", "Location of your SSL certificate. Can be used when you have an existing certificate and don't want a new certificate be generated automatically. If letsencrypt-domain is specified this property has no effect")
+	runCmd.Flags().StringVar(&relayCertKey, "cert-key", "", "Location of your SSL certificate private key. Can be used when you have an existing certificate and don't want a new certificate be generated automatically. If letsencrypt-domain is specified this property has no effect")
+	runCmd.Flags().StringVar(&relayAuthSecret, "auth-secret", "", "secret shared with the Management service to validate the credentials of the peers")
+}
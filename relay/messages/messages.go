@@ -0,0 +1,87 @@
+// Package messages defines the messages the relay server and its clients exchange over WebSocket.
+//
+// Every message is a binary WebSocket message starting with the message type and the length prefixed public key of a
+// peer. A client addresses the messages to the destination peer, the server replaces the key with the one of the
+// source peer before forwarding them.
+package messages
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// HeaderPeerKey is the HTTP header of the WebSocket handshake carrying the WireGuard public key of the client
+	HeaderPeerKey = "X-NetBird-Peer-Key"
+
+	// MaxMessageSize is the size of the largest message, a WireGuard packet with the header
+	MaxMessageSize = 65535
+
+	maxPeerKeyLen = 255
+)
+
+// MsgType is the type of a relay message
+type MsgType byte
+
+const (
+	// MsgTransport carries a packet from or to a peer
+	MsgTransport MsgType = 1
+	// MsgClose tells that the connection to a peer has been closed, the server sends it when a peer has disconnected
+	MsgClose MsgType = 2
+)
+
+// ErrInvalidMessage is returned when a message can't be parsed
+var ErrInvalidMessage = errors.New("invalid relay message")
+
+// MarshalTransport returns a transport message carrying the payload from or to the peer
+func MarshalTransport(peerKey string, payload []byte) ([]byte, error) {
+	return marshal(MsgTransport, peerKey, payload)
+}
+
+// MarshalClose returns a message telling that the connection from or to the peer has been closed
+func MarshalClose(peerKey string) ([]byte, error) {
+	return marshal(MsgClose, peerKey, nil)
+}
+
+// Unmarshal parses a message, the payload references the message
+func Unmarshal(msg []byte) (MsgType, string, []byte, error) {
+	if len(msg) < 2 {
+		return 0, "", nil, ErrInvalidMessage
+	}
+
+	msgType := MsgType(msg[0])
+	if msgType != MsgTransport && msgType != MsgClose {
+		return 0, "", nil, fmt.Errorf("%w: unknown type %d", ErrInvalidMessage, msgType)
+	}
+
+	keyLen := int(msg[1])
+	if keyLen == 0 || len(msg) < 2+keyLen {
+		return 0, "", nil, ErrInvalidMessage
+	}
+
+	return msgType, string(msg[2 : 2+keyLen]), msg[2+keyLen:], nil
+}
+
+// ReplacePeerKey returns the message with the peer key replaced, the server uses it to set the source of the messages
+func ReplacePeerKey(msg []byte, peerKey string) ([]byte, error) {
+	msgType, _, payload, err := Unmarshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return marshal(msgType, peerKey, payload)
+}
+
+func marshal(msgType MsgType, peerKey string, payload []byte) ([]byte, error) {
+	if peerKey == "" || len(peerKey) > maxPeerKeyLen {
+		return nil, fmt.Errorf("invalid peer key length %d", len(peerKey))
+	}
+
+	msg := make([]byte, 0, 2+len(peerKey)+len(payload))
+	msg = append(msg, byte(msgType), byte(len(peerKey)))
+	msg = append(msg, peerKey...)
+	msg = append(msg, payload...)
+	if len(msg) > MaxMessageSize {
+		return nil, fmt.Errorf("relay message of %d bytes exceeds the maximum size", len(msg))
+	}
+	return msg, nil
+}
@@ -0,0 +1,93 @@
+package messages
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalUnmarshal(t *testing.T) {
+	testCases := []struct {
+		name    string
+		marshal func() ([]byte, error)
+		msgType MsgType
+		payload []byte
+	}{
+		{
+			name: "transport",
+			marshal: func() ([]byte, error) {
+				return MarshalTransport("peerA", []byte("packet"))
+			},
+			msgType: MsgTransport,
+			payload: []byte("packet"),
+		},
+		{
+			name: "close",
+			marshal: func() ([]byte, error) {
+				return MarshalClose("peerA")
+			},
+			msgType: MsgClose,
+			payload: []byte{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			msg, err := testCase.marshal()
+			require.NoError(t, err)
+
+			msgType, peerKey, payload, err := Unmarshal(msg)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.msgType, msgType)
+			assert.Equal(t, "peerA", peerKey)
+			assert.Equal(t, testCase.payload, payload)
+		})
+	}
+}
+
+func TestMarshalInvalid(t *testing.T) {
+	_, err := MarshalTransport("", []byte("packet"))
+	assert.Error(t, err, "empty peer key")
+
+	_, err = MarshalTransport(strings.Repeat("a", maxPeerKeyLen+1), []byte("packet"))
+	assert.Error(t, err, "too long peer key")
+
+	_, err = MarshalTransport("peerA", make([]byte, MaxMessageSize))
+	assert.Error(t, err, "too large payload")
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		msg  []byte
+	}{
+		{name: "empty", msg: []byte{}},
+		{name: "unknown type", msg: []byte{9, 1, 'a'}},
+		{name: "empty key", msg: []byte{byte(MsgTransport), 0}},
+		{name: "truncated key", msg: []byte{byte(MsgTransport), 5, 'a'}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, _, _, err := Unmarshal(testCase.msg)
+			assert.True(t, errors.Is(err, ErrInvalidMessage), "expected an invalid message error, got %v", err)
+		})
+	}
+}
+
+func TestReplacePeerKey(t *testing.T) {
+	msg, err := MarshalTransport("destination", []byte("packet"))
+	require.NoError(t, err)
+
+	forwarded, err := ReplacePeerKey(msg, "source")
+	require.NoError(t, err)
+
+	msgType, peerKey, payload, err := Unmarshal(forwarded)
+	require.NoError(t, err)
+	assert.Equal(t, MsgTransport, msgType)
+	assert.Equal(t, "source", peerKey)
+	assert.Equal(t, []byte("packet"), payload)
+}
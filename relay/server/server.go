@@ -0,0 +1,209 @@
+// Package server implements the relay server. It forwards the packets of the peers which can't reach each other
+// directly, e.g. because UDP is blocked, over WebSocket connections which pass firewalls allowing HTTPS only.
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"nhooyr.io/websocket"
+
+	"github.com/netbirdio/netbird/relay/auth"
+	"github.com/netbirdio/netbird/relay/messages"
+)
+
+// writeTimeout limits the time a slow peer can hold up the peers sending to it
+const writeTimeout = 5 * time.Second
+
+// Server accepts the WebSocket connections of the peers and forwards the messages between them
+type Server struct {
+	validator *auth.Validator
+
+	mu    sync.RWMutex
+	peers map[string]*peer
+}
+
+type peer struct {
+	key  string
+	conn *websocket.Conn
+
+	mu sync.Mutex
+	// contacts are the peers this peer has exchanged messages with, they are notified once it disconnects
+	contacts map[string]struct{}
+}
+
+// NewServer creates a Server accepting the clients with credentials generated with the secret
+func NewServer(secret string) *Server {
+	return &Server{
+		validator: auth.NewValidator(secret),
+		peers:     make(map[string]*peer),
+	}
+}
+
+// ServeHTTP authenticates the client and relays its messages until it disconnects
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	peerKey := r.Header.Get(messages.HeaderPeerKey)
+	if peerKey == "" {
+		http.Error(w, "missing peer key", http.StatusBadRequest)
+		return
+	}
+
+	username, password, ok := basicAuth(r)
+	if !ok {
+		http.Error(w, "missing credentials", http.StatusUnauthorized)
+		return
+	}
+	credentialsKey, err := s.validator.Validate(username, password)
+	if err != nil {
+		log.Debugf("rejected relay client %s: %v", peerKey, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if credentialsKey != peerKey {
+		log.Debugf("rejected relay client %s: credentials issued for peer %s", peerKey, credentialsKey)
+		http.Error(w, "credentials issued for another peer", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		log.Debugf("failed to accept the WebSocket connection of peer %s: %v", peerKey, err)
+		return
+	}
+	conn.SetReadLimit(messages.MaxMessageSize)
+
+	p := &peer{
+		key:      peerKey,
+		conn:     conn,
+		contacts: make(map[string]struct{}),
+	}
+	s.register(p)
+	defer s.unregister(p)
+
+	s.relay(r.Context(), p)
+}
+
+// basicAuth returns the credentials of the Basic authorization header. The username contains a colon, unlike RFC 7617
+// allows, but the base64 encoded password doesn't: the credentials are split at the last colon
+func basicAuth(r *http.Request) (string, string, bool) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", "", false
+	}
+	credentials := username + ":" + password
+	i := strings.LastIndex(credentials, ":")
+	return credentials[:i], credentials[i+1:], true
+}
+
+// Close disconnects all the peers
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.peers {
+		_ = p.conn.CloseNow()
+	}
+}
+
+func (s *Server) register(p *peer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a peer reconnecting replaces its previous connection
+	if previous, ok := s.peers[p.key]; ok {
+		_ = previous.conn.CloseNow()
+	}
+	s.peers[p.key] = p
+	log.Debugf("relay client %s connected, %d peers connected", p.key, len(s.peers))
+}
+
+func (s *Server) unregister(p *peer) {
+	s.mu.Lock()
+	registered := s.peers[p.key] == p
+	if registered {
+		delete(s.peers, p.key)
+	}
+	s.mu.Unlock()
+
+	_ = p.conn.CloseNow()
+
+	p.mu.Lock()
+	contacts := p.contacts
+	p.contacts = nil
+	p.mu.Unlock()
+
+	// the contacts of a peer which has reconnected keep talking to its new connection
+	if !registered {
+		log.Debugf("previous connection of relay client %s closed", p.key)
+		return
+	}
+
+	for contact := range contacts {
+		msg, err := messages.MarshalClose(p.key)
+		if err != nil {
+			continue
+		}
+		s.forward(p, contact, msg)
+	}
+	log.Debugf("relay client %s disconnected", p.key)
+}
+
+func (s *Server) relay(ctx context.Context, p *peer) {
+	for {
+		msgType, msg, err := p.conn.Read(ctx)
+		if err != nil {
+			return
+		}
+		if msgType != websocket.MessageBinary {
+			continue
+		}
+
+		_, dst, _, err := messages.Unmarshal(msg)
+		if err != nil {
+			log.Debugf("dropping invalid message of relay client %s: %v", p.key, err)
+			continue
+		}
+
+		forwarded, err := messages.ReplacePeerKey(msg, p.key)
+		if err != nil {
+			log.Debugf("dropping message of relay client %s: %v", p.key, err)
+			continue
+		}
+
+		s.forward(p, dst, forwarded)
+	}
+}
+
+// forward sends the message of the source peer to the destination peer, it is dropped if the destination isn't
+// connected. Only connected peers become contacts, a peer can't grow its contacts by sending to arbitrary keys
+func (s *Server) forward(src *peer, dst string, msg []byte) {
+	s.mu.RLock()
+	p, ok := s.peers[dst]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	src.addContact(dst)
+	p.addContact(src.key)
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	if err := p.conn.Write(ctx, websocket.MessageBinary, msg); err != nil {
+		log.Debugf("failed to forward a message to relay client %s: %v", dst, err)
+	}
+}
+
+func (p *peer) addContact(peerKey string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// the contacts are nil once the peer has disconnected
+	if p.contacts != nil {
+		p.contacts[peerKey] = struct{}{}
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"nhooyr.io/websocket"
+
+	"github.com/netbirdio/netbird/relay/auth"
+	"github.com/netbirdio/netbird/relay/messages"
+)
+
+const testSecret = "secret"
+
+func startServer(t *testing.T) string {
+	t.Helper()
+
+	relayServer := NewServer(testSecret)
+	httpServer := httptest.NewServer(relayServer)
+	t.Cleanup(func() {
+		relayServer.Close()
+		httpServer.Close()
+	})
+	return "ws" + strings.TrimPrefix(httpServer.URL, "http")
+}
+
+// dial connects as the peer with credentials issued for the credentials key
+func dial(t *testing.T, serverURL, peerKey, credentialsKey string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	username, password := auth.GenerateCredentials(testSecret, credentialsKey, time.Hour)
+	req, err := http.NewRequest(http.MethodGet, serverURL, nil)
+	require.NoError(t, err)
+	req.SetBasicAuth(username, password)
+	req.Header.Set(messages.HeaderPeerKey, peerKey)
+
+	conn, resp, err := websocket.Dial(ctx, serverURL, &websocket.DialOptions{HTTPHeader: req.Header})
+	if conn != nil {
+		t.Cleanup(func() {
+			_ = conn.CloseNow()
+		})
+	}
+	return conn, resp, err
+}
+
+func connect(t *testing.T, serverURL, peerKey string) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := dial(t, serverURL, peerKey, peerKey)
+	require.NoError(t, err)
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, dst string, payload []byte) {
+	t.Helper()
+
+	msg, err := messages.MarshalTransport(dst, payload)
+	require.NoError(t, err)
+	require.NoError(t, conn.Write(context.Background(), websocket.MessageBinary, msg))
+}
+
+// receive reads the next message, it fails the test if none arrives within the timeout
+func receive(t *testing.T, conn *websocket.Conn, timeout time.Duration) (messages.MsgType, string, []byte) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_, msg, err := conn.Read(ctx)
+	require.NoError(t, err)
+	msgType, peerKey, payload, err := messages.Unmarshal(msg)
+	require.NoError(t, err)
+	return msgType, peerKey, payload
+}
+
+func TestServer_CredentialsOfAnotherPeer(t *testing.T) {
+	serverURL := startServer(t)
+
+	_, resp, err := dial(t, serverURL, "peerA", "peerB")
+	require.Error(t, err, "credentials issued for another peer should be rejected")
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestServer_Forward(t *testing.T) {
+	serverURL := startServer(t)
+
+	connA := connect(t, serverURL, "peerA")
+	connB := connect(t, serverURL, "peerB")
+
+	send(t, connA, "peerB", []byte("ping"))
+	msgType, src, payload := receive(t, connB, 5*time.Second)
+	assert.Equal(t, messages.MsgTransport, msgType)
+	assert.Equal(t, "peerA", src, "the server should set the source peer")
+	assert.Equal(t, []byte("ping"), payload)
+}
+
+func TestServer_DisconnectNotifiesContacts(t *testing.T) {
+	serverURL := startServer(t)
+
+	connA := connect(t, serverURL, "peerA")
+	connB := connect(t, serverURL, "peerB")
+
+	send(t, connA, "peerB", []byte("ping"))
+	_, _, _ = receive(t, connB, 5*time.Second)
+
+	require.NoError(t, connA.Close(websocket.StatusNormalClosure, ""))
+
+	msgType, src, _ := receive(t, connB, 5*time.Second)
+	assert.Equal(t, messages.MsgClose, msgType)
+	assert.Equal(t, "peerA", src)
+}
+
+func TestServer_ReconnectDoesNotNotifyContacts(t *testing.T) {
+	serverURL := startServer(t)
+
+	connA := connect(t, serverURL, "peerA")
+	connB := connect(t, serverURL, "peerB")
+
+	send(t, connA, "peerB", []byte("ping"))
+	_, _, _ = receive(t, connB, 5*time.Second)
+
+	reconnectedA := connect(t, serverURL, "peerA")
+
+	// the server closes the previous connection once the peer reconnects
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _, err := connA.Read(ctx)
+	require.Error(t, err, "the previous connection should be closed")
+
+	send(t, reconnectedA, "peerB", []byte("pong"))
+	msgType, src, payload := receive(t, connB, 5*time.Second)
+	assert.Equal(t, messages.MsgTransport, msgType, "the previous connection shouldn't close the relayed path")
+	assert.Equal(t, "peerA", src)
+	assert.Equal(t, []byte("pong"), payload)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_, msg, err := connB.Read(ctx)
+	assert.Error(t, err, "unexpected message %v after the reconnection", msg)
+}
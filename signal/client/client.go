@@ -22,11 +22,14 @@ const StreamDisconnected Status = "Disconnected"
 const (
 	// DirectCheck indicates support to direct mode checks
 	DirectCheck uint32 = 1
+	// RelayUpgrade indicates support to upgrade a relayed connection to a direct one
+	RelayUpgrade uint32 = 2
 )
 
 // FeaturesSupport register protocol supported features
 type FeaturesSupport struct {
-	DirectCheck bool
+	DirectCheck  bool
+	RelayUpgrade bool
 }
 
 type Client interface {
@@ -55,19 +58,21 @@ func UnMarshalCredential(msg *proto.Message) (*Credential, error) {
 
 // MarshalCredential marshal a Credential instance and returns a Message object
 func MarshalCredential(myKey wgtypes.Key, myPort int, remoteKey wgtypes.Key, credential *Credential, t proto.Body_Type,
-	rosenpassPubKey []byte, rosenpassAddr string) (*proto.Message, error) {
+	rosenpassPubKey []byte, rosenpassAddr string, relayServerAddress string) (*proto.Message, error) {
 	return &proto.Message{
 		Key:       myKey.PublicKey().String(),
 		RemoteKey: remoteKey.String(),
 		Body: &proto.Body{
-			Type:           t,
-			Payload:        fmt.Sprintf("%s:%s", credential.UFrag, credential.Pwd),
-			WgListenPort:   uint32(myPort),
-			NetBirdVersion: version.NetbirdVersion(),
+			Type:              t,
+			Payload:           fmt.Sprintf("%s:%s", credential.UFrag, credential.Pwd),
+			WgListenPort:      uint32(myPort),
+			NetBirdVersion:    version.NetbirdVersion(),
+			FeaturesSupported: []uint32{RelayUpgrade},
 			RosenpassConfig: &proto.RosenpassConfig{
 				RosenpassPubKey:     rosenpassPubKey,
 				RosenpassServerAddr: rosenpassAddr,
 			},
+			RelayServerAddress: relayServerAddress,
 		},
 	}, nil
 }
@@ -82,9 +87,11 @@ type Credential struct {
 func ParseFeaturesSupported(featuresMessage []uint32) FeaturesSupport {
 	var protoSupport FeaturesSupport
 	for _, feature := range featuresMessage {
-		if feature == DirectCheck {
+		switch feature {
+		case DirectCheck:
 			protoSupport.DirectCheck = true
-			return protoSupport
+		case RelayUpgrade:
+			protoSupport.RelayUpgrade = true
 		}
 	}
 	return protoSupport
@@ -113,6 +113,32 @@ var _ = Describe("GrpcClient", func() {
 				Expect(featuresSupportedReceivedOnB).To(ContainElements([]uint32{DirectCheck}))
 			})
 		})
+
+		Context("with a peer not connected to Signal", func() {
+			It("should fail with ErrPeerNotConnected", func() {
+
+				keyA, _ := wgtypes.GenerateKey()
+				clientA := createSignalClient(addr, keyA)
+				go func() {
+					err := clientA.Receive(func(msg *sigProto.Message) error {
+						return nil
+					})
+					if err != nil {
+						return
+					}
+				}()
+				clientA.WaitStreamConnected()
+
+				keyB, _ := wgtypes.GenerateKey()
+				err := clientA.Send(&sigProto.Message{
+					Key:       keyA.PublicKey().String(),
+					RemoteKey: keyB.PublicKey().String(),
+					Body:      &sigProto.Body{Payload: "ping"},
+				})
+
+				Expect(err).To(Equal(ErrPeerNotConnected))
+			})
+		})
 	})
 
 	Describe("Connecting to the Signal stream channel", func() {
@@ -171,6 +197,7 @@ var _ = Describe("GrpcClient", func() {
 func TestParseFeaturesSupported(t *testing.T) {
 	expectedOnEmptyOrUnsupported := FeaturesSupport{DirectCheck: false}
 	expectedWithDirectCheck := FeaturesSupport{DirectCheck: true}
+	expectedWithAll := FeaturesSupport{DirectCheck: true, RelayUpgrade: true}
 	testCases := []struct {
 		name     string
 		input    []uint32
@@ -181,6 +208,11 @@ func TestParseFeaturesSupported(t *testing.T) {
 			input:    []uint32{DirectCheck},
 			expected: expectedWithDirectCheck,
 		},
+		{
+			name:     "Should Return All Supported Features",
+			input:    []uint32{RelayUpgrade, DirectCheck},
+			expected: expectedWithAll,
+		},
 		{
 			name:     "Should Return DirectCheck Unsupported When Nil",
 			input:    nil,
@@ -199,6 +231,9 @@ func TestParseFeaturesSupported(t *testing.T) {
 			if result.DirectCheck != testCase.expected.DirectCheck {
 				t.Errorf("Direct check feature should match: Expected: %t, Got: %t", testCase.expected.DirectCheck, result.DirectCheck)
 			}
+			if result.RelayUpgrade != testCase.expected.RelayUpgrade {
+				t.Errorf("Relay upgrade feature should match: Expected: %t, Got: %t", testCase.expected.RelayUpgrade, result.RelayUpgrade)
+			}
 		})
 	}
 }
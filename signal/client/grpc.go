@@ -1,8 +1,11 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
@@ -26,6 +29,12 @@ import (
 
 const defaultSendTimeout = 5 * time.Second
 
+// sendAttempts is the number of attempts to send a message before giving up
+const sendAttempts = 4
+
+// ErrPeerNotConnected is returned by Send when the remote peer isn't connected to the Signal server
+var ErrPeerNotConnected = errors.New("remote peer is not connected to the Signal server")
+
 // ConnStateNotifier is a wrapper interface of the status recorder
 type ConnStateNotifier interface {
 	MarkSignalDisconnected()
@@ -212,7 +221,7 @@ func (c *GrpcClient) connect(ctx context.Context, key string) (proto.SignalExcha
 	c.stream = nil
 
 	// add key fingerprint to the request header to be identified on the server side
-	md := metadata.New(map[string]string{proto.HeaderId: key})
+	md := metadata.New(map[string]string{proto.HeaderId: key, proto.HeaderDeliveryFeedback: "1", proto.HeaderAuth: "1"})
 	metaCtx := metadata.NewOutgoingContext(ctx, md)
 	stream, err := c.realClient.ConnectStream(metaCtx, grpc.WaitForReady(true))
 	c.stream = stream
@@ -224,14 +233,65 @@ func (c *GrpcClient) connect(ctx context.Context, key string) (proto.SignalExcha
 	if err != nil {
 		return nil, err
 	}
+	// servers not supporting the authentication of the peers register them right away
 	registered := header.Get(proto.HeaderRegistered)
-	if len(registered) == 0 {
+	if len(registered) > 0 {
+		return stream, nil
+	}
+
+	challenge := header.Get(proto.HeaderAuthChallenge)
+	serverKey := header.Get(proto.HeaderAuthKey)
+	if len(challenge) == 0 || len(serverKey) == 0 {
 		return nil, fmt.Errorf("didn't receive a registration header from the Signal server whille connecting to the streams")
 	}
 
+	err = c.authenticate(stream, challenge[0], serverKey[0])
+	if err != nil {
+		return nil, fmt.Errorf("authenticate to the Signal server: %w", err)
+	}
+
 	return stream, nil
 }
 
+// authenticate proves that the client holds the private key of its Id by answering the challenge of the Signal
+// server, then waits for the server to acknowledge the registration
+func (c *GrpcClient) authenticate(stream proto.SignalExchange_ConnectStreamClient, encodedChallenge, encodedServerKey string) error {
+	challenge, err := base64.StdEncoding.DecodeString(encodedChallenge)
+	if err != nil {
+		return fmt.Errorf("decode challenge: %w", err)
+	}
+	serverKey, err := wgtypes.ParseKey(encodedServerKey)
+	if err != nil {
+		return fmt.Errorf("parse server key: %w", err)
+	}
+
+	response, err := encryption.Encrypt(challenge, serverKey, c.key)
+	if err != nil {
+		return err
+	}
+	err = stream.Send(&proto.EncryptedMessage{
+		Key:       c.key.PublicKey().String(),
+		RemoteKey: serverKey.String(),
+		Body:      response,
+	})
+	if err != nil {
+		return err
+	}
+
+	ack, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	decrypted, err := encryption.Decrypt(ack.GetBody(), serverKey, c.key)
+	if err != nil {
+		return fmt.Errorf("decrypt acknowledgement: %w", err)
+	}
+	if !bytes.Equal(decrypted, challenge) {
+		return fmt.Errorf("acknowledgement doesn't match the challenge")
+	}
+	return nil
+}
+
 // Ready indicates whether the client is okay and Ready to be used
 // for now it just checks whether gRPC connection to the service is in state Ready
 func (c *GrpcClient) Ready() bool {
@@ -326,11 +386,13 @@ func (c *GrpcClient) Send(msg *proto.Message) error {
 
 	attemptTimeout := defaultSendTimeout
 
-	for attempt := 0; attempt < 4; attempt++ {
+	for attempt := 0; attempt < sendAttempts; attempt++ {
 		if attempt > 1 {
 			attemptTimeout = time.Duration(attempt) * 5 * time.Second
 		}
 		ctx, cancel := context.WithTimeout(c.ctx, attemptTimeout)
+		// opts in to the NotFound error for the messages to peers which aren't connected
+		ctx = metadata.AppendToOutgoingContext(ctx, proto.HeaderDeliveryFeedback, "1")
 
 		_, err = c.realClient.Send(ctx, encryptedMessage)
 
@@ -340,6 +402,21 @@ func (c *GrpcClient) Send(msg *proto.Message) error {
 			return err
 		}
 
+		// retrying doesn't help until the remote peer connects
+		if s, ok := status.FromError(err); ok && s.Code() == codes.NotFound {
+			return ErrPeerNotConnected
+		}
+
+		// the server throttles this peer, retrying right away would be throttled as well
+		if s, ok := status.FromError(err); ok && s.Code() == codes.ResourceExhausted && attempt < sendAttempts-1 {
+			log.Debugf("sending to peer %s has been throttled by the Signal server, retrying in %s", msg.RemoteKey, attemptTimeout)
+			select {
+			case <-c.ctx.Done():
+				return err
+			case <-time.After(attemptTimeout):
+			}
+		}
+
 		if err == nil {
 			return nil
 		}
@@ -369,6 +446,12 @@ func (c *GrpcClient) receive(stream proto.SignalExchange_ConnectStreamClient,
 		}
 		log.Tracef("received a new message from Peer [fingerprint: %s]", msg.Key)
 
+		// the messages are sent with Send which reports the undelivered ones itself
+		if msg.GetDeliveryStatus() == proto.DeliveryStatus_NOT_CONNECTED {
+			log.Debugf("message to Peer [key: %s] sent on the stream wasn't delivered because it isn't connected", msg.Key)
+			continue
+		}
+
 		decryptedMessage, err := c.decryptMessage(msg)
 		if err != nil {
 			log.Errorf("failed decrypting message of Peer [key: %s] error: [%s]", msg.Key, err.Error())
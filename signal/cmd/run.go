@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -15,6 +16,8 @@ import (
 	"time"
 
 	"github.com/netbirdio/netbird/encryption"
+	"github.com/netbirdio/netbird/signal/forwarder"
+	"github.com/netbirdio/netbird/signal/metrics"
 	"github.com/netbirdio/netbird/signal/proto"
 	"github.com/netbirdio/netbird/signal/server"
 	"github.com/netbirdio/netbird/util"
@@ -22,6 +25,8 @@ import (
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 )
 
@@ -31,6 +36,10 @@ var (
 	signalSSLDir            string
 	defaultSignalSSLDir     string
 	tlsEnabled              bool
+	redisURL                string
+	requireAuth             bool
+	metricsPort             int
+	rateLimits              server.RateLimits
 
 	signalKaep = grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
 		MinTime:             5 * time.Second,
@@ -92,9 +101,32 @@ var (
 				opts = append(opts, grpc.Creds(transportCredentials))
 			}
 
+			var fwd forwarder.Forwarder = forwarder.NewLocal()
+			if redisURL != "" {
+				// replicas behind a load balancer exchange the messages of their peers through Redis
+				fwd, err = forwarder.NewRedis(context.Background(), redisURL)
+				if err != nil {
+					return err
+				}
+				log.Infof("forwarding messages to the peers of other replicas through Redis")
+			}
+
+			appMetrics, err := metrics.NewAppMetrics(cmd.Context())
+			if err != nil {
+				return err
+			}
+			err = appMetrics.Expose(metricsPort, "/metrics")
+			if err != nil {
+				return err
+			}
+
 			opts = append(opts, signalKaep, signalKasp)
 			grpcServer := grpc.NewServer(opts...)
-			proto.RegisterSignalExchangeServer(grpcServer, server.NewServer())
+			proto.RegisterSignalExchangeServer(grpcServer, server.NewServerWithForwarder(fwd, appMetrics, rateLimits, requireAuth))
+
+			// the health service reports serving until the server stops
+			healthServer := health.NewServer()
+			grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
 
 			var compatListener net.Listener
 			if signalPort != 10000 {
@@ -134,6 +166,7 @@ var (
 			SetupCloseHandler()
 
 			<-stopCh
+			healthServer.Shutdown()
 			if grpcListener != nil {
 				_ = grpcListener.Close()
 				log.Infof("stopped gRPC server")
@@ -146,6 +179,12 @@ var (
 				_ = compatListener.Close()
 				log.Infof("stopped gRPC backward compatibility server")
 			}
+			if err := fwd.Close(); err != nil {
+				log.Warnf("failed closing the message forwarder: %v", err)
+			}
+			if err := appMetrics.Close(); err != nil {
+				log.Warnf("failed closing the metrics endpoint: %v", err)
+			}
 			log.Infof("stopped Signal Service")
 
 			return nil
@@ -286,4 +325,13 @@ func init() {
 	runCmd.PersistentFlags().IntVar(&signalPort, "port", 80, "Server port to listen on (defaults to 443 if TLS is enabled, 80 otherwise")
 	runCmd.Flags().StringVar(&signalSSLDir, "ssl-dir", defaultSignalSSLDir, "server ssl directory location. *Required only for Let's Encrypt certificates.")
 	runCmd.Flags().StringVar(&signalLetsencryptDomain, "letsencrypt-domain", "", "a domain to issue Let's Encrypt certificate for. Enables TLS using Let's Encrypt. Will fetch and renew certificate, and run the server with TLS")
+	runCmd.Flags().IntVar(&metricsPort, "metrics-port", 9090, "metrics endpoint http port. Metrics are accessible under host:metrics-port/metrics")
+	runCmd.Flags().Float64Var(&rateLimits.Messages.Rate, "message-rate-limit", 50, "messages per second a peer may send, 0 disables the limit")
+	runCmd.Flags().IntVar(&rateLimits.Messages.Burst, "message-burst-limit", 200, "messages a peer may send at once before the message rate limit applies")
+	runCmd.Flags().Float64Var(&rateLimits.Streams.Rate, "stream-rate-limit", 0.2, "stream connections per second of a peer, 0 disables the limit")
+	runCmd.Flags().IntVar(&rateLimits.Streams.Burst, "stream-burst-limit", 10, "stream connections of a peer at once before the stream rate limit applies")
+	runCmd.Flags().Float64Var(&rateLimits.StreamsPerIP.Rate, "ip-stream-rate-limit", 0, "stream connections per second from an IP address, 0 disables the limit. Only enable it if the server sees the addresses of the clients, behind a proxy or NAT all the peers share its address")
+	runCmd.Flags().IntVar(&rateLimits.StreamsPerIP.Burst, "ip-stream-burst-limit", 200, "stream connections from an IP address at once before the IP stream rate limit applies")
+	runCmd.Flags().BoolVar(&requireAuth, "require-auth", false, "rejects the peers which don't prove that they hold the key of their ID, clients older than the peer authentication can't connect then")
+	runCmd.Flags().StringVar(&redisURL, "redis-url", "", "Redis URL (e.g. redis://localhost:6379/0) used to forward messages between multiple Signal replicas behind a load balancer. Runs a standalone server if not set")
 }
@@ -0,0 +1,36 @@
+// Package forwarder delivers the messages of the peers to the peers connected to other Signal replicas, so that
+// several replicas can run behind a load balancer.
+package forwarder
+
+import (
+	"context"
+	"errors"
+
+	"github.com/netbirdio/netbird/signal/proto"
+)
+
+// ErrPeerNotConnected is returned when the destination peer isn't connected to any replica
+var ErrPeerNotConnected = errors.New("peer is not connected to any signal replica")
+
+// MessageHandler delivers a message forwarded by another replica to the peer connected to this replica
+type MessageHandler func(msg *proto.EncryptedMessage)
+
+// Forwarder exchanges the messages of the peers between the Signal replicas
+type Forwarder interface {
+	// Start starts passing the messages for the peers registered on this replica to the handler
+	Start(handler MessageHandler)
+	// Register announces that the peer is connected to this replica
+	Register(ctx context.Context, peerID string) error
+	// Deregister announces that the peer is not connected to this replica anymore
+	Deregister(ctx context.Context, peerID string) error
+	// Forward delivers the message to the replica the destination peer is connected to, it returns
+	// ErrPeerNotConnected if the peer isn't connected to any replica
+	Forward(ctx context.Context, msg *proto.EncryptedMessage) error
+	// SetAuthenticated records on all the replicas that the peer proved that it holds the key of its ID
+	SetAuthenticated(ctx context.Context, peerID string) error
+	// IsAuthenticated returns true if the peer authenticated before on any replica, its ID must then only be accepted
+	// from authenticated streams, also while it is offline
+	IsAuthenticated(ctx context.Context, peerID string) (bool, error)
+	// Close stops forwarding messages
+	Close() error
+}
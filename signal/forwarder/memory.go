@@ -0,0 +1,123 @@
+package forwarder
+
+import (
+	"context"
+	"sync"
+
+	"github.com/netbirdio/netbird/signal/proto"
+)
+
+// MemoryHub connects the replicas running in the same process. A hub with a single replica is the forwarder of a
+// standalone Signal server, it never finds the peers which aren't connected to the server itself
+type MemoryHub struct {
+	mu sync.RWMutex
+	// peers maps the peers to the forwarders of the replicas they are connected to
+	peers map[string]*Memory
+	// authenticated contains the peers which authenticated on any replica
+	authenticated map[string]struct{}
+}
+
+// NewMemoryHub creates a hub without replicas
+func NewMemoryHub() *MemoryHub {
+	return &MemoryHub{
+		peers:         make(map[string]*Memory),
+		authenticated: make(map[string]struct{}),
+	}
+}
+
+// NewForwarder creates the forwarder of a replica connected to the hub
+func (h *MemoryHub) NewForwarder() *Memory {
+	return &Memory{hub: h}
+}
+
+// Memory is the Forwarder of a replica connected to a MemoryHub
+type Memory struct {
+	hub *MemoryHub
+
+	mu      sync.RWMutex
+	handler MessageHandler
+}
+
+// NewLocal creates the forwarder of a standalone Signal server
+func NewLocal() *Memory {
+	return NewMemoryHub().NewForwarder()
+}
+
+// Start sets the handler of the messages for the peers registered on this replica
+func (m *Memory) Start(handler MessageHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handler = handler
+}
+
+// Register routes the messages for the peer to this replica
+func (m *Memory) Register(_ context.Context, peerID string) error {
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+
+	m.hub.peers[peerID] = m
+	return nil
+}
+
+// Deregister stops routing the messages for the peer to this replica, unless it has reconnected to another one
+func (m *Memory) Deregister(_ context.Context, peerID string) error {
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+
+	if m.hub.peers[peerID] == m {
+		delete(m.hub.peers, peerID)
+	}
+	return nil
+}
+
+// Forward passes the message to the handler of the replica the destination peer is registered on
+func (m *Memory) Forward(_ context.Context, msg *proto.EncryptedMessage) error {
+	m.hub.mu.RLock()
+	dst, ok := m.hub.peers[msg.RemoteKey]
+	m.hub.mu.RUnlock()
+	if !ok {
+		return ErrPeerNotConnected
+	}
+
+	dst.mu.RLock()
+	handler := dst.handler
+	dst.mu.RUnlock()
+	if handler == nil {
+		return ErrPeerNotConnected
+	}
+
+	handler(msg)
+	return nil
+}
+
+// SetAuthenticated records that the peer authenticated
+func (m *Memory) SetAuthenticated(_ context.Context, peerID string) error {
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+
+	m.hub.authenticated[peerID] = struct{}{}
+	return nil
+}
+
+// IsAuthenticated returns true if the peer authenticated on any replica of the hub
+func (m *Memory) IsAuthenticated(_ context.Context, peerID string) (bool, error) {
+	m.hub.mu.RLock()
+	defer m.hub.mu.RUnlock()
+
+	_, ok := m.hub.authenticated[peerID]
+	return ok, nil
+}
+
+// Close deregisters all the peers of this replica
+func (m *Memory) Close() error {
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+
+	for peerID, replica := range m.hub.peers {
+		if replica == m {
+			delete(m.hub.peers, peerID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package forwarder
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/netbirdio/netbird/signal/proto"
+)
+
+func TestMemory_Forward(t *testing.T) {
+	hub := NewMemoryHub()
+	replicaA := hub.NewForwarder()
+	replicaB := hub.NewForwarder()
+
+	var received []*proto.EncryptedMessage
+	replicaB.Start(func(msg *proto.EncryptedMessage) {
+		received = append(received, msg)
+	})
+	replicaA.Start(func(msg *proto.EncryptedMessage) {
+		t.Errorf("unexpected message for replica A: %v", msg)
+	})
+
+	ctx := context.Background()
+	require.NoError(t, replicaB.Register(ctx, "peerB"))
+
+	msg := &proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerB", Body: []byte("body")}
+	require.NoError(t, replicaA.Forward(ctx, msg))
+	require.Len(t, received, 1)
+	assert.Equal(t, msg, received[0])
+
+	err := replicaA.Forward(ctx, &proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerC"})
+	assert.ErrorIs(t, err, ErrPeerNotConnected, "unknown peer")
+
+	require.NoError(t, replicaB.Deregister(ctx, "peerB"))
+	assert.ErrorIs(t, replicaA.Forward(ctx, msg), ErrPeerNotConnected, "deregistered peer")
+}
+
+func TestMemory_DeregisterReconnectedPeer(t *testing.T) {
+	hub := NewMemoryHub()
+	replicaA := hub.NewForwarder()
+	replicaB := hub.NewForwarder()
+
+	delivered := false
+	replicaB.Start(func(msg *proto.EncryptedMessage) {
+		delivered = true
+	})
+
+	ctx := context.Background()
+	require.NoError(t, replicaA.Register(ctx, "peer"))
+	// the peer reconnects to replica B before replica A notices the disconnection
+	require.NoError(t, replicaB.Register(ctx, "peer"))
+	require.NoError(t, replicaA.Deregister(ctx, "peer"))
+
+	require.NoError(t, replicaA.Forward(ctx, &proto.EncryptedMessage{Key: "other", RemoteKey: "peer"}))
+	assert.True(t, delivered, "expected the message to be delivered to the replica the peer has reconnected to")
+}
+
+func TestMemory_Close(t *testing.T) {
+	hub := NewMemoryHub()
+	replicaA := hub.NewForwarder()
+	replicaB := hub.NewForwarder()
+	replicaA.Start(func(msg *proto.EncryptedMessage) {})
+
+	ctx := context.Background()
+	require.NoError(t, replicaA.Register(ctx, "peer"))
+	require.NoError(t, replicaA.Close())
+
+	err := replicaB.Forward(ctx, &proto.EncryptedMessage{Key: "other", RemoteKey: "peer"})
+	assert.ErrorIs(t, err, ErrPeerNotConnected)
+}
+
+func TestMemory_Authenticated(t *testing.T) {
+	hub := NewMemoryHub()
+	replicaA := hub.NewForwarder()
+	replicaB := hub.NewForwarder()
+
+	ctx := context.Background()
+	authenticated, err := replicaB.IsAuthenticated(ctx, "peerA")
+	require.NoError(t, err)
+	assert.False(t, authenticated)
+
+	require.NoError(t, replicaA.SetAuthenticated(ctx, "peerA"))
+	authenticated, err = replicaB.IsAuthenticated(ctx, "peerA")
+	require.NoError(t, err)
+	assert.True(t, authenticated, "authentication should be shared by the replicas")
+}
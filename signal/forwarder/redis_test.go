@@ -0,0 +1,254 @@
+package forwarder
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/netbirdio/netbird/signal/proto"
+)
+
+// redisStandIn is a Redis server supporting the Pub/Sub commands the Redis forwarder uses
+type redisStandIn struct {
+	listener net.Listener
+
+	mu sync.Mutex
+	// subscribers maps the channels to their subscribed connections
+	subscribers map[string]map[*redisStandInConn]struct{}
+	// keys are the keys set, without their expiration
+	keys map[string]string
+}
+
+type redisStandInConn struct {
+	conn     net.Conn
+	writeMu  sync.Mutex
+	channels map[string]struct{}
+}
+
+func startRedisStandIn(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &redisStandIn{
+		listener:    listener,
+		subscribers: make(map[string]map[*redisStandInConn]struct{}),
+		keys:        make(map[string]string),
+	}
+	go s.serve()
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	return "redis://" + listener.Addr().String()
+}
+
+func (s *redisStandIn) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(&redisStandInConn{conn: conn, channels: make(map[string]struct{})})
+	}
+}
+
+func (s *redisStandIn) handle(c *redisStandInConn) {
+	defer func() {
+		s.mu.Lock()
+		for channel := range c.channels {
+			delete(s.subscribers[channel], c)
+		}
+		s.mu.Unlock()
+		_ = c.conn.Close()
+	}()
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		switch strings.ToLower(args[0]) {
+		case "ping":
+			if len(c.channels) > 0 {
+				c.write("*2\r\n" + bulk("pong") + bulk(""))
+			} else {
+				c.write("+PONG\r\n")
+			}
+		case "subscribe":
+			for _, channel := range args[1:] {
+				s.mu.Lock()
+				if s.subscribers[channel] == nil {
+					s.subscribers[channel] = make(map[*redisStandInConn]struct{})
+				}
+				s.subscribers[channel][c] = struct{}{}
+				c.channels[channel] = struct{}{}
+				count := len(c.channels)
+				s.mu.Unlock()
+				c.write(fmt.Sprintf("*3\r\n%s%s:%d\r\n", bulk("subscribe"), bulk(channel), count))
+			}
+		case "unsubscribe":
+			for _, channel := range args[1:] {
+				s.mu.Lock()
+				delete(s.subscribers[channel], c)
+				delete(c.channels, channel)
+				count := len(c.channels)
+				s.mu.Unlock()
+				c.write(fmt.Sprintf("*3\r\n%s%s:%d\r\n", bulk("unsubscribe"), bulk(channel), count))
+			}
+		case "publish":
+			s.mu.Lock()
+			var receivers []*redisStandInConn
+			for subscriber := range s.subscribers[args[1]] {
+				receivers = append(receivers, subscriber)
+			}
+			s.mu.Unlock()
+			for _, receiver := range receivers {
+				receiver.write("*3\r\n" + bulk("message") + bulk(args[1]) + bulk(args[2]))
+			}
+			c.write(fmt.Sprintf(":%d\r\n", len(receivers)))
+		case "set":
+			s.mu.Lock()
+			s.keys[args[1]] = args[2]
+			s.mu.Unlock()
+			c.write("+OK\r\n")
+		case "exists":
+			s.mu.Lock()
+			count := 0
+			for _, key := range args[1:] {
+				if _, ok := s.keys[key]; ok {
+					count++
+				}
+			}
+			s.mu.Unlock()
+			c.write(fmt.Sprintf(":%d\r\n", count))
+		default:
+			c.write(fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0]))
+		}
+	}
+}
+
+func (c *redisStandInConn) write(reply string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, _ = io.WriteString(c.conn, reply)
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected command %q", line)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		arg := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, arg); err != nil {
+			return nil, err
+		}
+		args = append(args, string(arg[:length]))
+	}
+	return args, nil
+}
+
+func newRedisReplica(t *testing.T, url string) (*Redis, chan *proto.EncryptedMessage) {
+	t.Helper()
+
+	replica, err := NewRedis(context.Background(), url)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = replica.Close()
+	})
+
+	received := make(chan *proto.EncryptedMessage, 1)
+	replica.Start(func(msg *proto.EncryptedMessage) {
+		received <- msg
+	})
+	return replica, received
+}
+
+func TestRedis_Forward(t *testing.T) {
+	url := startRedisStandIn(t)
+	replicaA, _ := newRedisReplica(t, url)
+	replicaB, receivedB := newRedisReplica(t, url)
+
+	ctx := context.Background()
+	require.NoError(t, replicaB.Register(ctx, "peerB"))
+
+	msg := &proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerB", Body: []byte("body")}
+	require.Eventually(t, func() bool {
+		// the subscription is confirmed asynchronously
+		return replicaA.Forward(ctx, msg) == nil
+	}, 5*time.Second, 50*time.Millisecond)
+
+	select {
+	case received := <-receivedB:
+		assert.Equal(t, msg.Key, received.Key)
+		assert.Equal(t, msg.RemoteKey, received.RemoteKey)
+		assert.Equal(t, msg.Body, received.Body)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the message to be forwarded to replica B")
+	}
+
+	err := replicaA.Forward(ctx, &proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerC"})
+	assert.ErrorIs(t, err, ErrPeerNotConnected, "unknown peer")
+
+	require.NoError(t, replicaB.Deregister(ctx, "peerB"))
+	require.Eventually(t, func() bool {
+		return replicaA.Forward(ctx, msg) == ErrPeerNotConnected
+	}, 5*time.Second, 50*time.Millisecond, "deregistered peer")
+}
+
+func TestRedis_Authenticated(t *testing.T) {
+	url := startRedisStandIn(t)
+	replicaA, _ := newRedisReplica(t, url)
+	replicaB, _ := newRedisReplica(t, url)
+
+	ctx := context.Background()
+	authenticated, err := replicaB.IsAuthenticated(ctx, "peerA")
+	require.NoError(t, err)
+	assert.False(t, authenticated)
+
+	require.NoError(t, replicaA.SetAuthenticated(ctx, "peerA"))
+	authenticated, err = replicaB.IsAuthenticated(ctx, "peerA")
+	require.NoError(t, err)
+	assert.True(t, authenticated, "authentication should be shared by the replicas")
+}
+
+func TestNewRedis_InvalidURL(t *testing.T) {
+	_, err := NewRedis(context.Background(), "invalid://localhost")
+	assert.Error(t, err)
+}
@@ -0,0 +1,246 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"time"
+
+	prometheus2 "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	metric2 "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+const defaultEndpoint = "/metrics"
+
+const (
+	// MessageTypeLocal is a message delivered to a peer connected to the same replica
+	MessageTypeLocal = "local"
+	// MessageTypeReplica is a message forwarded to a peer connected to another replica
+	MessageTypeReplica = "replica"
+	// MessageTypeForwarded is a message forwarded by another replica to a peer connected to this one
+	MessageTypeForwarded = "forwarded"
+)
+
+var messageTypes = []string{MessageTypeLocal, MessageTypeReplica, MessageTypeForwarded}
+
+// AppMetrics are the metrics of the Signal server based on OpenTelemetry https://opentelemetry.io/
+type AppMetrics struct {
+	// Meter can be used by different application parts to create counters and measure things
+	Meter    metric2.Meter
+	registry *prometheus2.Registry
+	listener net.Listener
+	ctx      context.Context
+
+	activePeers          syncint64.UpDownCounter
+	connectedStreams     syncint64.Counter
+	authenticatedStreams syncint64.Counter
+	disconnectedStreams  syncint64.Counter
+	rejectedStreams      syncint64.Counter
+	forwardedMessages    syncint64.Counter
+	// notConnectedMessages and failedMessages have one counter per message type
+	notConnectedMessages map[string]syncint64.Counter
+	failedMessages       map[string]syncint64.Counter
+	throttledMessages    syncint64.Counter
+	throttledStreams     syncint64.Counter
+	// forwardDurationMicro has the type of the forwarded messages as attribute, the counters have none as the
+	// Prometheus exporter doesn't support counters with several attribute sets yet, the dropped messages are counted
+	// by type with a counter per type instead
+	forwardDurationMicro syncint64.Histogram
+}
+
+// NewAppMetrics creates the metrics of the Signal server, they are exposed with Expose
+func NewAppMetrics(ctx context.Context) (*AppMetrics, error) {
+	registry := prometheus2.NewRegistry()
+	exporter, err := prometheus.New(prometheus.WithRegisterer(registry), prometheus.WithoutUnits())
+	if err != nil {
+		return nil, err
+	}
+
+	provider := metric.NewMeterProvider(metric.WithReader(exporter))
+	pkg := reflect.TypeOf(defaultEndpoint).PkgPath()
+	meter := provider.Meter(pkg)
+
+	activePeers, err := meter.SyncInt64().UpDownCounter("signal.peers.active",
+		instrument.WithDescription("Number of peers connected to the Signal server"), instrument.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+
+	connectedStreams, err := meter.SyncInt64().Counter("signal.streams.connected",
+		instrument.WithDescription("Number of peer streams registered on the Signal server"), instrument.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+
+	authenticatedStreams, err := meter.SyncInt64().Counter("signal.streams.authenticated",
+		instrument.WithDescription("Number of peer streams registered on the Signal server after authenticating the peer"), instrument.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+
+	disconnectedStreams, err := meter.SyncInt64().Counter("signal.streams.disconnected",
+		instrument.WithDescription("Number of peer streams which disconnected from the Signal server"), instrument.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+
+	rejectedStreams, err := meter.SyncInt64().Counter("signal.streams.rejected",
+		instrument.WithDescription("Number of peer streams the Signal server refused to register"), instrument.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+
+	forwardedMessages, err := meter.SyncInt64().Counter("signal.messages.forwarded",
+		instrument.WithDescription("Number of messages forwarded to the peers"), instrument.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+
+	notConnectedMessages := make(map[string]syncint64.Counter, len(messageTypes))
+	failedMessages := make(map[string]syncint64.Counter, len(messageTypes))
+	for _, messageType := range messageTypes {
+		notConnectedMessages[messageType], err = meter.SyncInt64().Counter("signal.messages.dropped.not_connected."+messageType,
+			instrument.WithDescription("Number of "+messageType+" messages to peers which aren't connected to the Signal server"), instrument.WithUnit("1"))
+		if err != nil {
+			return nil, err
+		}
+
+		failedMessages[messageType], err = meter.SyncInt64().Counter("signal.messages.dropped.failed."+messageType,
+			instrument.WithDescription("Number of "+messageType+" messages which couldn't be sent to the connected peers"), instrument.WithUnit("1"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	throttledMessages, err := meter.SyncInt64().Counter("signal.messages.throttled",
+		instrument.WithDescription("Number of messages of the peers rejected because of the rate limits"), instrument.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+
+	throttledStreams, err := meter.SyncInt64().Counter("signal.streams.throttled",
+		instrument.WithDescription("Number of peer streams rejected because of the rate limits"), instrument.WithUnit("1"))
+	if err != nil {
+		return nil, err
+	}
+
+	forwardDuration, err := meter.SyncInt64().Histogram("signal.messages.forward.duration.micro",
+		instrument.WithDescription("Duration of forwarding a message to a peer"), instrument.WithUnit("microseconds"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &AppMetrics{
+		Meter:                meter,
+		registry:             registry,
+		ctx:                  ctx,
+		activePeers:          activePeers,
+		connectedStreams:     connectedStreams,
+		authenticatedStreams: authenticatedStreams,
+		disconnectedStreams:  disconnectedStreams,
+		rejectedStreams:      rejectedStreams,
+		forwardedMessages:    forwardedMessages,
+		notConnectedMessages: notConnectedMessages,
+		failedMessages:       failedMessages,
+		throttledMessages:    throttledMessages,
+		throttledStreams:     throttledStreams,
+		forwardDurationMicro: forwardDuration,
+	}, nil
+}
+
+// CountStreamConnected counts a registered peer stream, authenticated is true if the peer proved its identity
+func (appMetrics *AppMetrics) CountStreamConnected(authenticated bool) {
+	appMetrics.connectedStreams.Add(appMetrics.ctx, 1)
+	if authenticated {
+		appMetrics.authenticatedStreams.Add(appMetrics.ctx, 1)
+	}
+	appMetrics.activePeers.Add(appMetrics.ctx, 1)
+}
+
+// CountStreamDisconnected counts a registered peer stream which disconnected
+func (appMetrics *AppMetrics) CountStreamDisconnected() {
+	appMetrics.disconnectedStreams.Add(appMetrics.ctx, 1)
+	appMetrics.activePeers.Add(appMetrics.ctx, -1)
+}
+
+// CountStreamRejected counts a peer stream which wasn't registered
+func (appMetrics *AppMetrics) CountStreamRejected() {
+	appMetrics.rejectedStreams.Add(appMetrics.ctx, 1)
+}
+
+// CountMessageForwarded counts a message forwarded to a peer and records how long forwarding took
+func (appMetrics *AppMetrics) CountMessageForwarded(messageType string, duration time.Duration) {
+	appMetrics.forwardedMessages.Add(appMetrics.ctx, 1)
+	appMetrics.forwardDurationMicro.Record(appMetrics.ctx, duration.Microseconds(), attribute.String("type", messageType))
+}
+
+// CountMessageNotConnected counts a message of the type to a peer which isn't connected
+func (appMetrics *AppMetrics) CountMessageNotConnected(messageType string) {
+	if counter, ok := appMetrics.notConnectedMessages[messageType]; ok {
+		counter.Add(appMetrics.ctx, 1)
+	}
+}
+
+// CountMessageFailed counts a message of the type which couldn't be sent to the connected peer
+func (appMetrics *AppMetrics) CountMessageFailed(messageType string) {
+	if counter, ok := appMetrics.failedMessages[messageType]; ok {
+		counter.Add(appMetrics.ctx, 1)
+	}
+}
+
+// CountMessageThrottled counts a message of a peer rejected because of the rate limits
+func (appMetrics *AppMetrics) CountMessageThrottled() {
+	appMetrics.throttledMessages.Add(appMetrics.ctx, 1)
+}
+
+// CountStreamThrottled counts a peer stream rejected because of the rate limits
+func (appMetrics *AppMetrics) CountStreamThrottled() {
+	appMetrics.throttledStreams.Add(appMetrics.ctx, 1)
+}
+
+// Handler returns the HTTP handler serving the metrics in the Prometheus format https://prometheus.io/
+func (appMetrics *AppMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(appMetrics.registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// Expose metrics on a given port and endpoint. If endpoint is empty a defaultEndpoint one will be used.
+func (appMetrics *AppMetrics) Expose(port int, endpoint string) error {
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	mux := http.NewServeMux()
+	mux.Handle(endpoint, appMetrics.Handler())
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	appMetrics.listener = listener
+	go func() {
+		err := http.Serve(listener, mux)
+		if err != nil {
+			return
+		}
+	}()
+
+	log.Infof("enabled application metrics and exposing on http://%s", listener.Addr().String())
+
+	return nil
+}
+
+// Close stops the metrics HTTP handler and closes the listener
+func (appMetrics *AppMetrics) Close() error {
+	if appMetrics.listener == nil {
+		return nil
+	}
+	return appMetrics.listener.Close()
+}
@@ -1,12 +1,16 @@
 package peer
 
 import (
+	"errors"
 	"github.com/netbirdio/netbird/signal/proto"
 	log "github.com/sirupsen/logrus"
 	"sync"
 	"time"
 )
 
+// ErrPeerAuthenticated is returned when an unauthenticated stream tries to override the authenticated stream of a peer
+var ErrPeerAuthenticated = errors.New("peer is registered with an authenticated stream")
+
 // Peer representation of a connected Peer
 type Peer struct {
 	// a unique id of the Peer (e.g. sha256 fingerprint of the Wireguard public key)
@@ -16,29 +20,99 @@ type Peer struct {
 
 	//a gRpc connection stream to the Peer
 	Stream proto.SignalExchange_ConnectStreamServer
+
+	// Authenticated is true if the Peer proved that it holds the WireGuard private key of its Id
+	Authenticated bool
+
+	// acknowledged is closed once the registration is acknowledged on the stream, the messages to the Peer wait for it
+	acknowledged chan struct{}
+	// sendMu serializes the messages sent to the Peer, the gRPC stream doesn't support concurrent sends
+	sendMu sync.Mutex
 }
 
 // NewPeer creates a new instance of a connected Peer
 func NewPeer(id string, stream proto.SignalExchange_ConnectStreamServer) *Peer {
 	return &Peer{
-		Id:       id,
-		Stream:   stream,
-		StreamID: time.Now().UnixNano(),
+		Id:           id,
+		Stream:       stream,
+		StreamID:     time.Now().UnixNano(),
+		acknowledged: make(chan struct{}),
 	}
 }
 
+// Acknowledge sends the acknowledgement of the registration with ack, it has to be the first message the Peer receives.
+// The messages sent to the Peer are held back until then
+func (p *Peer) Acknowledge(ack func() error) error {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+
+	if err := ack(); err != nil {
+		return err
+	}
+	close(p.acknowledged)
+	return nil
+}
+
+// Send sends the message to the Peer once its registration is acknowledged
+func (p *Peer) Send(msg *proto.EncryptedMessage) error {
+	select {
+	case <-p.acknowledged:
+	case <-p.Stream.Context().Done():
+		return p.Stream.Context().Err()
+	}
+
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	return p.Stream.Send(msg)
+}
+
 // Registry that holds all currently connected Peers
 type Registry struct {
 	// Peer.key -> Peer
 	Peers sync.Map
 	// regMutex ensures that registration and de-registrations are safe
 	regMutex sync.Mutex
+	// peerLocks serialize the connections and disconnections of the streams of a peer, they are removed once unused
+	peerLocks   map[string]*peerLock
+	peerLocksMu sync.Mutex
+}
+
+// peerLock is the lock of a peer ID with the number of goroutines holding or waiting for it
+type peerLock struct {
+	sync.Mutex
+	refs int
 }
 
 // NewRegistry creates a new connected Peer registry
 func NewRegistry() *Registry {
 	return &Registry{
-		regMutex: sync.Mutex{},
+		regMutex:  sync.Mutex{},
+		peerLocks: make(map[string]*peerLock),
+	}
+}
+
+// LockPeer locks the peer ID until the returned function is called. It serializes the registration of the streams of
+// a peer with the state kept elsewhere, e.g. the subscriptions of the message forwarder
+func (registry *Registry) LockPeer(peerId string) (unlock func()) {
+	registry.peerLocksMu.Lock()
+	lock, ok := registry.peerLocks[peerId]
+	if !ok {
+		lock = &peerLock{}
+		registry.peerLocks[peerId] = lock
+	}
+	lock.refs++
+	registry.peerLocksMu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		registry.peerLocksMu.Lock()
+		defer registry.peerLocksMu.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(registry.peerLocks, peerId)
+		}
 	}
 }
 
@@ -58,8 +132,9 @@ func (registry *Registry) IsPeerRegistered(peerId string) bool {
 	return false
 }
 
-// Register registers peer in the registry
-func (registry *Registry) Register(peer *Peer) {
+// Register registers peer in the registry. An authenticated stream of the peer can only be overridden by another
+// authenticated one
+func (registry *Registry) Register(peer *Peer) error {
 	registry.regMutex.Lock()
 	defer registry.regMutex.Unlock()
 
@@ -67,11 +142,17 @@ func (registry *Registry) Register(peer *Peer) {
 	p, loaded := registry.Peers.LoadOrStore(peer.Id, peer)
 	if loaded {
 		pp := p.(*Peer)
+		if pp.Authenticated && !peer.Authenticated {
+			log.Warnf("rejected unauthenticated stream of peer [%s] [new streamID %d, authenticated StreamID %d]",
+				peer.Id, peer.StreamID, pp.StreamID)
+			return ErrPeerAuthenticated
+		}
 		log.Warnf("peer [%s] is already registered [new streamID %d, previous StreamID %d]. Will override stream.",
 			peer.Id, peer.StreamID, pp.StreamID)
 		registry.Peers.Store(peer.Id, peer)
 	}
 	log.Debugf("peer registered [%s]", peer.Id)
+	return nil
 }
 
 // Deregister Peer from the Registry (usually once it disconnects)
@@ -1,11 +1,41 @@
 package peer
 
 import (
+	"context"
+	"github.com/netbirdio/netbird/signal/proto"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
 	"testing"
 	"time"
 )
 
+// recordingStream records the messages sent on the stream
+type recordingStream struct {
+	proto.SignalExchange_ConnectStreamServer
+	ctx context.Context
+
+	mu   sync.Mutex
+	sent []string
+}
+
+func (s *recordingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *recordingStream) Send(msg *proto.EncryptedMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, msg.Key)
+	return nil
+}
+
+func (s *recordingStream) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.sent...)
+}
+
 func TestRegistry_ShouldNotDeregisterWhenHasNewerStreamRegistered(t *testing.T) {
 	r := NewRegistry()
 
@@ -77,3 +107,93 @@ func TestRegistry_Deregister(t *testing.T) {
 	}
 
 }
+
+func TestRegistry_ShouldNotOverrideAuthenticatedStream(t *testing.T) {
+	r := NewRegistry()
+
+	authenticated := NewPeer("peer", nil)
+	authenticated.Authenticated = true
+	assert.NoError(t, r.Register(authenticated))
+
+	assert.ErrorIs(t, r.Register(NewPeer("peer", nil)), ErrPeerAuthenticated)
+	registered, _ := r.Get("peer")
+	assert.Equal(t, authenticated, registered)
+
+	time.Sleep(time.Nanosecond)
+	reconnected := NewPeer("peer", nil)
+	reconnected.Authenticated = true
+	assert.NoError(t, r.Register(reconnected))
+	registered, _ = r.Get("peer")
+	assert.Equal(t, reconnected, registered)
+}
+
+func TestRegistry_LockPeer(t *testing.T) {
+	r := NewRegistry()
+
+	unlock := r.LockPeer("peer")
+	// the locks of other peers are independent
+	r.LockPeer("other")()
+
+	locked := make(chan struct{})
+	go func() {
+		r.LockPeer("peer")()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("the peer should stay locked until it is unlocked")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("the peer should be locked once it is unlocked")
+	}
+	assert.Empty(t, r.peerLocks, "the unused locks should be removed")
+}
+
+func TestPeer_SendAfterAcknowledgement(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &recordingStream{ctx: ctx}
+	p := NewPeer("peer", stream)
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- p.Send(&proto.EncryptedMessage{Key: "forwarded"})
+	}()
+
+	select {
+	case <-sent:
+		t.Fatal("the message shouldn't be sent before the acknowledgement")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, p.Acknowledge(func() error {
+		return stream.Send(&proto.EncryptedMessage{Key: "ack"})
+	}))
+	require.NoError(t, <-sent)
+	assert.Equal(t, []string{"ack", "forwarded"}, stream.messages())
+}
+
+func TestPeer_SendWithoutAcknowledgement(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewPeer("peer", &recordingStream{ctx: ctx})
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- p.Send(&proto.EncryptedMessage{Key: "forwarded"})
+	}()
+
+	// the stream failed before it was acknowledged
+	cancel()
+	select {
+	case err := <-sent:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("the message should be dropped once the stream is closed")
+	}
+}
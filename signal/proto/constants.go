@@ -3,3 +3,15 @@ package proto
 // protocol constants, field names that can be used by both client and server
 const HeaderId = "x-wiretrustee-peer-id"
 const HeaderRegistered = "x-wiretrustee-peer-registered"
+
+// HeaderDeliveryFeedback opts in to the reports of the messages which couldn't be delivered: the control messages for
+// the messages sent on the stream and the NotFound error for the messages sent with Send
+const HeaderDeliveryFeedback = "x-netbird-delivery-feedback"
+
+// HeaderAuth opts in to the authentication of the peer. Instead of HeaderRegistered the server responds with the
+// HeaderAuthChallenge and HeaderAuthKey headers, the peer proves that it holds the WireGuard private key of its Id by
+// sending the challenge encrypted for the server key as first message. The server acknowledges the registration by
+// sending the challenge encrypted for the peer key back
+const HeaderAuth = "x-netbird-peer-auth"
+const HeaderAuthChallenge = "x-netbird-peer-auth-challenge"
+const HeaderAuthKey = "x-netbird-peer-auth-key"
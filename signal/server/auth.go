@@ -0,0 +1,88 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/netbirdio/netbird/encryption"
+	"github.com/netbirdio/netbird/signal/proto"
+)
+
+const authChallengeSize = 32
+
+// authChallenge is the challenge a peer has to answer to prove that it holds the WireGuard private key of its Id.
+// Every stream gets its own challenge and server key
+type authChallenge struct {
+	key       wgtypes.Key
+	challenge []byte
+}
+
+func newAuthChallenge() (*authChallenge, error) {
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := make([]byte, authChallengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+
+	return &authChallenge{
+		key:       key,
+		challenge: challenge,
+	}, nil
+}
+
+// header returns the stream header sending the challenge to the peer
+func (c *authChallenge) header() metadata.MD {
+	return metadata.Pairs(
+		proto.HeaderAuthChallenge, base64.StdEncoding.EncodeToString(c.challenge),
+		proto.HeaderAuthKey, c.key.PublicKey().String(),
+	)
+}
+
+// verify checks that the response has been encrypted with the private key of the peer
+func (c *authChallenge) verify(peerID string, response *proto.EncryptedMessage) error {
+	if response.GetKey() != peerID {
+		return fmt.Errorf("response sent for peer %s", response.GetKey())
+	}
+
+	peerKey, err := wgtypes.ParseKey(peerID)
+	if err != nil {
+		return fmt.Errorf("peer Id isn't a WireGuard public key: %w", err)
+	}
+
+	decrypted, err := encryption.Decrypt(response.GetBody(), peerKey, c.key)
+	if err != nil {
+		return fmt.Errorf("decrypt response: %w", err)
+	}
+	if !bytes.Equal(decrypted, c.challenge) {
+		return fmt.Errorf("response doesn't match the challenge")
+	}
+	return nil
+}
+
+// acknowledgement returns the message confirming the registration of the authenticated peer
+func (c *authChallenge) acknowledgement(peerID string) (*proto.EncryptedMessage, error) {
+	peerKey, err := wgtypes.ParseKey(peerID)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := encryption.Encrypt(c.challenge, peerKey, c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.EncryptedMessage{
+		Key:       c.key.PublicKey().String(),
+		RemoteKey: peerID,
+		Body:      encrypted,
+	}, nil
+}
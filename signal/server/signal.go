@@ -2,7 +2,11 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/netbirdio/netbird/ratelimit"
+	"github.com/netbirdio/netbird/signal/forwarder"
+	"github.com/netbirdio/netbird/signal/metrics"
 	"github.com/netbirdio/netbird/signal/peer"
 	"github.com/netbirdio/netbird/signal/proto"
 	log "github.com/sirupsen/logrus"
@@ -10,19 +14,55 @@ import (
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"io"
+	"time"
 )
 
 // Server an instance of a Signal server
 type Server struct {
 	registry *peer.Registry
+	// forwarder delivers the messages to the peers connected to other replicas
+	forwarder forwarder.Forwarder
+	// metrics are nil if the metrics are disabled
+	metrics *metrics.AppMetrics
+
+	messageLimiter  *ratelimit.Limiter
+	streamLimiter   *ratelimit.Limiter
+	ipStreamLimiter *ratelimit.Limiter
+	// requireAuth rejects the peers which don't authenticate
+	requireAuth bool
 	proto.UnimplementedSignalExchangeServer
 }
 
-// NewServer creates a new Signal server
+// RateLimits are the limits of the requests of the peers, the zero value disables them
+type RateLimits struct {
+	// Messages limits the messages a peer sends
+	Messages ratelimit.Config
+	// Streams limits how often a peer connects to the stream
+	Streams ratelimit.Config
+	// StreamsPerIP limits how often the peers behind an IP address connect to the stream. The address is the one of
+	// the gRPC connection, the peers behind a proxy share it
+	StreamsPerIP ratelimit.Config
+}
+
+// NewServer creates a new standalone Signal server
 func NewServer() *Server {
-	return &Server{
-		registry: peer.NewRegistry(),
+	return NewServerWithForwarder(forwarder.NewLocal(), nil, RateLimits{}, false)
+}
+
+// NewServerWithForwarder creates a new Signal server replica exchanging the messages with the other replicas
+// through the forwarder. With requireAuth the peers which don't authenticate, i.e. older clients, are rejected
+func NewServerWithForwarder(fwd forwarder.Forwarder, appMetrics *metrics.AppMetrics, limits RateLimits, requireAuth bool) *Server {
+	s := &Server{
+		registry:        peer.NewRegistry(),
+		forwarder:       fwd,
+		metrics:         appMetrics,
+		messageLimiter:  ratelimit.NewLimiter(limits.Messages),
+		streamLimiter:   ratelimit.NewLimiter(limits.Streams),
+		ipStreamLimiter: ratelimit.NewLimiter(limits.StreamsPerIP),
+		requireAuth:     requireAuth,
 	}
+	fwd.Start(s.deliverForwarded)
+	return s
 }
 
 // Send forwards a message to the signal peer
@@ -32,16 +72,16 @@ func (s *Server) Send(ctx context.Context, msg *proto.EncryptedMessage) (*proto.
 		return nil, fmt.Errorf("peer %s is not registered", msg.Key)
 	}
 
-	if dstPeer, found := s.registry.Get(msg.RemoteKey); found {
-		//forward the message to the target peer
-		err := dstPeer.Stream.Send(msg)
-		if err != nil {
-			log.Errorf("error while forwarding message from peer [%s] to peer [%s] %v", msg.Key, msg.RemoteKey, err)
-			//todo respond to the sender?
-		}
-	} else {
-		log.Debugf("message from peer [%s] can't be forwarded to peer [%s] because destination peer is not connected", msg.Key, msg.RemoteKey)
-		//todo respond to the sender?
+	// the sender of a unary message isn't authenticated
+	if !s.messageLimiter.Allow(peerLimitKey(ctx, msg.Key, false)) {
+		s.countMessageThrottled()
+		return nil, status.Errorf(codes.ResourceExhausted, "peer %s exceeded the message rate limit", msg.Key)
+	}
+
+	err := s.forward(ctx, msg)
+	// older clients retry the messages failing with an error
+	if errors.Is(err, forwarder.ErrPeerNotConnected) && wantsDeliveryFeedback(ctx) {
+		return nil, status.Errorf(codes.NotFound, "peer %s is not connected", msg.RemoteKey)
 	}
 	return &proto.EncryptedMessage{}, nil
 }
@@ -49,25 +89,44 @@ func (s *Server) Send(ctx context.Context, msg *proto.EncryptedMessage) (*proto.
 // ConnectStream connects to the exchange stream
 func (s *Server) ConnectStream(stream proto.SignalExchange_ConnectStreamServer) error {
 
-	p, err := s.connectPeer(stream)
+	p, challenge, err := s.connectPeer(stream)
 	if err != nil {
+		if s.metrics != nil {
+			s.metrics.CountStreamRejected()
+		}
 		return err
 	}
+	if s.metrics != nil {
+		s.metrics.CountStreamConnected(p.Authenticated)
+	}
 
 	defer func() {
 		log.Infof("peer disconnected [%s] [streamID %d] ", p.Id, p.StreamID)
-		s.registry.Deregister(p)
+		if s.metrics != nil {
+			s.metrics.CountStreamDisconnected()
+		}
+		s.disconnectPeer(p)
 	}()
 
-	//needed to confirm that the peer has been registered so that the client can proceed
-	header := metadata.Pairs(proto.HeaderRegistered, "1")
-	err = stream.SendHeader(header)
+	//needed to confirm that the peer has been registered so that the client can proceed. The messages forwarded to
+	//the peer in the meantime are sent after it
+	err = p.Acknowledge(func() error {
+		if challenge != nil {
+			ack, err := challenge.acknowledgement(p.Id)
+			if err != nil {
+				return err
+			}
+			return stream.Send(ack)
+		}
+		return stream.SendHeader(metadata.Pairs(proto.HeaderRegistered, "1"))
+	})
 	if err != nil {
 		return err
 	}
 
-	log.Infof("peer connected [%s] [streamID %d] ", p.Id, p.StreamID)
+	log.Infof("peer connected [%s] [streamID %d] [authenticated %t]", p.Id, p.StreamID, p.Authenticated)
 
+	deliveryFeedback := wantsDeliveryFeedback(stream.Context())
 	for {
 		//read incoming messages
 		msg, err := stream.Recv()
@@ -77,36 +136,240 @@ func (s *Server) ConnectStream(stream proto.SignalExchange_ConnectStreamServer)
 			return err
 		}
 		log.Debugf("received a new message from peer [%s] to peer [%s]", p.Id, msg.RemoteKey)
-		// lookup the target peer where the message is going to
-		if dstPeer, found := s.registry.Get(msg.RemoteKey); found {
-			//forward the message to the target peer
-			err := dstPeer.Stream.Send(msg)
-			if err != nil {
-				log.Errorf("error while forwarding message from peer [%s] to peer [%s] %v", p.Id, msg.RemoteKey, err)
-				//todo respond to the sender?
-			}
-		} else {
-			log.Debugf("message from peer [%s] can't be forwarded to peer [%s] because destination peer is not connected", p.Id, msg.RemoteKey)
-			//todo respond to the sender?
+		// the receivers trust the sender of a message to be its Key
+		if msg.Key != p.Id {
+			log.Warnf("dropping message from peer [%s] claiming to be sent by peer [%s]", p.Id, msg.Key)
+			continue
+		}
+		if !s.messageLimiter.Allow(peerLimitKey(stream.Context(), p.Id, p.Authenticated)) {
+			log.Debugf("dropping message from peer [%s] to peer [%s] exceeding the message rate limit", p.Id, msg.RemoteKey)
+			s.countMessageThrottled()
+			continue
+		}
+		err = s.forward(stream.Context(), msg)
+		if deliveryFeedback && errors.Is(err, forwarder.ErrPeerNotConnected) {
+			s.notifyNotConnected(p, msg)
 		}
 	}
 	<-stream.Context().Done()
 	return stream.Context().Err()
 }
 
+// forward sends the message to the target peer if it is connected to this replica, otherwise the forwarder delivers
+// it to the replica the peer is connected to. It returns forwarder.ErrPeerNotConnected if the target peer isn't
+// connected to any replica
+func (s *Server) forward(ctx context.Context, msg *proto.EncryptedMessage) error {
+	start := time.Now()
+
+	// lookup the target peer where the message is going to
+	if dstPeer, found := s.registry.Get(msg.RemoteKey); found {
+		//forward the message to the target peer
+		err := dstPeer.Send(msg)
+		if err != nil {
+			log.Errorf("error while forwarding message from peer [%s] to peer [%s] %v", msg.Key, msg.RemoteKey, err)
+		}
+		s.countForward(metrics.MessageTypeLocal, start, err)
+		return err
+	}
+
+	err := s.forwarder.Forward(ctx, msg)
+	switch {
+	case errors.Is(err, forwarder.ErrPeerNotConnected):
+		log.Debugf("message from peer [%s] can't be forwarded to peer [%s] because destination peer is not connected", msg.Key, msg.RemoteKey)
+	case err != nil:
+		log.Errorf("error while forwarding message from peer [%s] to peer [%s] through another replica %v", msg.Key, msg.RemoteKey, err)
+	}
+	s.countForward(metrics.MessageTypeReplica, start, err)
+	return err
+}
+
+// countForward records the outcome of forwarding a message started at start
+func (s *Server) countForward(messageType string, start time.Time, err error) {
+	if s.metrics == nil {
+		return
+	}
+
+	switch {
+	case err == nil:
+		s.metrics.CountMessageForwarded(messageType, time.Since(start))
+	case errors.Is(err, forwarder.ErrPeerNotConnected):
+		s.metrics.CountMessageNotConnected(messageType)
+	default:
+		s.metrics.CountMessageFailed(messageType)
+	}
+}
+
+// countMessageThrottled counts a message rejected because of the rate limits
+func (s *Server) countMessageThrottled() {
+	if s.metrics != nil {
+		s.metrics.CountMessageThrottled()
+	}
+}
+
+// countStreamThrottled counts a stream rejected because of the rate limits
+func (s *Server) countStreamThrottled() {
+	if s.metrics != nil {
+		s.metrics.CountStreamThrottled()
+	}
+}
+
+// notifyNotConnected sends a control message back to the sender of a message whose destination peer isn't connected
+func (s *Server) notifyNotConnected(sender *peer.Peer, msg *proto.EncryptedMessage) {
+	err := sender.Send(&proto.EncryptedMessage{
+		Key:            msg.RemoteKey,
+		RemoteKey:      sender.Id,
+		DeliveryStatus: proto.DeliveryStatus_NOT_CONNECTED,
+	})
+	if err != nil {
+		log.Errorf("error while notifying peer [%s] that peer [%s] is not connected %v", sender.Id, msg.RemoteKey, err)
+	}
+}
+
+// deliverForwarded sends a message forwarded by another replica to the target peer connected to this replica
+func (s *Server) deliverForwarded(msg *proto.EncryptedMessage) {
+	start := time.Now()
+
+	dstPeer, found := s.registry.Get(msg.RemoteKey)
+	if !found {
+		log.Debugf("forwarded message from peer [%s] can't be delivered to peer [%s] because destination peer has disconnected", msg.Key, msg.RemoteKey)
+		s.countForward(metrics.MessageTypeForwarded, start, forwarder.ErrPeerNotConnected)
+		return
+	}
+
+	err := dstPeer.Send(msg)
+	if err != nil {
+		log.Errorf("error while delivering forwarded message from peer [%s] to peer [%s] %v", msg.Key, msg.RemoteKey, err)
+	}
+	s.countForward(metrics.MessageTypeForwarded, start, err)
+}
+
 // Handles initial Peer connection.
-// Each connection must provide an Id header.
-// At this moment the connecting Peer will be registered in the peer.Registry
-func (s Server) connectPeer(stream proto.SignalExchange_ConnectStreamServer) (*peer.Peer, error) {
-	if meta, hasMeta := metadata.FromIncomingContext(stream.Context()); hasMeta {
-		if id, found := meta[proto.HeaderId]; found {
-			p := peer.NewPeer(id[0], stream)
-			s.registry.Register(p)
-			return p, nil
-		} else {
-			return nil, status.Errorf(codes.FailedPrecondition, "missing connection header: "+proto.HeaderId)
+// Each connection must provide an Id header. Peers sending the auth header have to answer the challenge of the
+// returned authChallenge before they are registered, their registration is acknowledged with it.
+// At this moment the connecting Peer will be registered in the peer.Registry and announced to the other replicas
+func (s Server) connectPeer(stream proto.SignalExchange_ConnectStreamServer) (*peer.Peer, *authChallenge, error) {
+	meta, hasMeta := metadata.FromIncomingContext(stream.Context())
+	if !hasMeta {
+		return nil, nil, status.Errorf(codes.FailedPrecondition, "missing connection stream meta")
+	}
+	id, found := meta[proto.HeaderId]
+	if !found {
+		return nil, nil, status.Errorf(codes.FailedPrecondition, "missing connection header: "+proto.HeaderId)
+	}
+
+	if !s.ipStreamLimiter.Allow(ratelimit.PeerIP(stream.Context())) {
+		s.countStreamThrottled()
+		return nil, nil, status.Errorf(codes.ResourceExhausted, "peer %s exceeded the stream rate limit", id[0])
+	}
+
+	p := peer.NewPeer(id[0], stream)
+
+	var challenge *authChallenge
+	if len(meta.Get(proto.HeaderAuth)) > 0 {
+		var err error
+		challenge, err = authenticatePeer(stream, p.Id)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.Authenticated = true
+		if err := s.forwarder.SetAuthenticated(stream.Context(), p.Id); err != nil {
+			return nil, nil, status.Errorf(codes.Unavailable, "failed recording the authentication of the peer: %v", err)
 		}
-	} else {
-		return nil, status.Errorf(codes.FailedPrecondition, "missing connection stream meta")
+	} else if err := s.checkUnauthenticated(stream.Context(), p.Id); err != nil {
+		return nil, nil, err
+	}
+
+	if !s.streamLimiter.Allow(peerLimitKey(stream.Context(), p.Id, p.Authenticated)) {
+		s.countStreamThrottled()
+		return nil, nil, status.Errorf(codes.ResourceExhausted, "peer %s exceeded the stream rate limit", id[0])
+	}
+
+	// the subscriptions of the forwarder aren't reference counted, a disconnecting stream of the peer mustn't remove
+	// the one of its new stream
+	unlock := s.registry.LockPeer(p.Id)
+	defer unlock()
+
+	if err := s.registry.Register(p); err != nil {
+		return nil, nil, status.Errorf(codes.PermissionDenied, "failed registering peer: %v", err)
+	}
+	if err := s.forwarder.Register(stream.Context(), p.Id); err != nil {
+		s.registry.Deregister(p)
+		return nil, nil, status.Errorf(codes.Unavailable, "failed registering peer with the message forwarder: %v", err)
+	}
+	return p, challenge, nil
+}
+
+// disconnectPeer removes the stream of the peer from the registry. The peer is deregistered from the forwarder unless
+// it has reconnected to this replica in the meantime
+func (s *Server) disconnectPeer(p *peer.Peer) {
+	unlock := s.registry.LockPeer(p.Id)
+	defer unlock()
+
+	s.registry.Deregister(p)
+	if s.registry.IsPeerRegistered(p.Id) {
+		return
 	}
+	if err := s.forwarder.Deregister(context.Background(), p.Id); err != nil {
+		log.Errorf("failed deregistering peer [%s] from the message forwarder: %v", p.Id, err)
+	}
+}
+
+// checkUnauthenticated returns an error if a peer which doesn't authenticate can't be registered: the server requires
+// the authentication, or the peer of the ID authenticated before on any replica and the stream may hijack its ID
+func (s Server) checkUnauthenticated(ctx context.Context, peerID string) error {
+	if s.requireAuth {
+		return status.Errorf(codes.Unauthenticated, "peer authentication is required")
+	}
+
+	authenticated, err := s.forwarder.IsAuthenticated(ctx, peerID)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed checking the authentication of the peer: %v", err)
+	}
+	if authenticated {
+		log.Warnf("rejected unauthenticated stream of peer [%s] which authenticated before", peerID)
+		return status.Errorf(codes.PermissionDenied, "peer %s authenticated before and has to authenticate", peerID)
+	}
+	return nil
+}
+
+// peerLimitKey returns the key of the rate limit buckets of a peer. The ID of a peer which didn't authenticate
+// isn't proven, so its buckets are bound to the IP address it connects from as well: clients claiming its ID from
+// other addresses can't exhaust them
+func peerLimitKey(ctx context.Context, peerID string, authenticated bool) string {
+	if authenticated {
+		return peerID
+	}
+	return ratelimit.PeerIP(ctx) + "/" + peerID
+}
+
+// authenticatePeer sends a challenge to the peer and verifies its response
+func authenticatePeer(stream proto.SignalExchange_ConnectStreamServer, peerID string) (*authChallenge, error) {
+	challenge, err := newAuthChallenge()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed creating authentication challenge: %v", err)
+	}
+
+	err = stream.SendHeader(challenge.header())
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	err = challenge.verify(peerID, response)
+	if err != nil {
+		log.Warnf("failed authenticating peer [%s]: %v", peerID, err)
+		return nil, status.Errorf(codes.Unauthenticated, "failed authenticating peer: %v", err)
+	}
+	return challenge, nil
+}
+
+// wantsDeliveryFeedback checks whether the peer opted in to the reports of undelivered messages, the control messages
+// on the stream and the errors of Send. Older clients don't expect them
+func wantsDeliveryFeedback(ctx context.Context) bool {
+	meta, hasMeta := metadata.FromIncomingContext(ctx)
+	return hasMeta && len(meta.Get(proto.HeaderDeliveryFeedback)) > 0
 }
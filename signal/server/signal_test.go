@@ -0,0 +1,378 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/netbirdio/netbird/encryption"
+	"github.com/netbirdio/netbird/ratelimit"
+	"github.com/netbirdio/netbird/signal/forwarder"
+	"github.com/netbirdio/netbird/signal/metrics"
+	"github.com/netbirdio/netbird/signal/proto"
+)
+
+func startReplica(t *testing.T, fwd forwarder.Forwarder) proto.SignalExchangeClient {
+	t.Helper()
+	return startReplicaWithMetrics(t, fwd, nil, RateLimits{})
+}
+
+func startReplicaWithMetrics(t *testing.T, fwd forwarder.Forwarder, appMetrics *metrics.AppMetrics, limits RateLimits) proto.SignalExchangeClient {
+	t.Helper()
+	return serveReplica(t, NewServerWithForwarder(fwd, appMetrics, limits, false))
+}
+
+func serveReplica(t *testing.T, server *Server) proto.SignalExchangeClient {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer()
+	proto.RegisterSignalExchangeServer(s, server)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return proto.NewSignalExchangeClient(conn)
+}
+
+func connectStream(t *testing.T, client proto.SignalExchangeClient, peerID string, kv ...string) proto.SignalExchange_ConnectStreamClient {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ctx = metadata.AppendToOutgoingContext(ctx, append([]string{proto.HeaderId, peerID}, kv...)...)
+	stream, err := client.ConnectStream(ctx)
+	require.NoError(t, err)
+
+	// the header confirms the registration of the peer
+	_, err = stream.Header()
+	require.NoError(t, err)
+	return stream
+}
+
+func receive(t *testing.T, stream proto.SignalExchange_ConnectStreamClient) *proto.EncryptedMessage {
+	t.Helper()
+
+	received := make(chan *proto.EncryptedMessage, 1)
+	go func() {
+		msg, err := stream.Recv()
+		if err == nil {
+			received <- msg
+		}
+	}()
+
+	select {
+	case msg := <-received:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a message")
+		return nil
+	}
+}
+
+func TestServer_ForwardToOtherReplica(t *testing.T) {
+	hub := forwarder.NewMemoryHub()
+	clientA := startReplica(t, hub.NewForwarder())
+	clientB := startReplica(t, hub.NewForwarder())
+
+	streamA := connectStream(t, clientA, "peerA")
+	streamB := connectStream(t, clientB, "peerB")
+
+	// unary Send to a peer of the other replica
+	_, err := clientA.Send(context.Background(), &proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerB", Body: []byte("offer")})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("offer"), receive(t, streamB).Body)
+
+	// stream message to a peer of the other replica
+	err = streamB.Send(&proto.EncryptedMessage{Key: "peerB", RemoteKey: "peerA", Body: []byte("answer")})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("answer"), receive(t, streamA).Body)
+}
+
+func TestServer_ForwardWithinReplica(t *testing.T) {
+	client := startReplica(t, forwarder.NewLocal())
+
+	connectStream(t, client, "peerA")
+	streamB := connectStream(t, client, "peerB")
+
+	_, err := client.Send(context.Background(), &proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerB", Body: []byte("offer")})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("offer"), receive(t, streamB).Body)
+}
+
+func TestServer_SendToNotConnectedPeer(t *testing.T) {
+	hub := forwarder.NewMemoryHub()
+	client := startReplica(t, hub.NewForwarder())
+	startReplica(t, hub.NewForwarder())
+
+	connectStream(t, client, "peerA")
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), proto.HeaderDeliveryFeedback, "1")
+	_, err := client.Send(ctx, &proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerB", Body: []byte("offer")})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_SendToNotConnectedPeerLegacyClient(t *testing.T) {
+	hub := forwarder.NewMemoryHub()
+	client := startReplica(t, hub.NewForwarder())
+	startReplica(t, hub.NewForwarder())
+
+	connectStream(t, client, "peerA")
+
+	// clients without delivery feedback retry the failed messages
+	reply, err := client.Send(context.Background(), &proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerB", Body: []byte("offer")})
+	require.NoError(t, err)
+	assert.Empty(t, reply.Body)
+}
+
+func TestServer_StreamDeliveryFeedback(t *testing.T) {
+	client := startReplica(t, forwarder.NewLocal())
+
+	streamA := connectStream(t, client, "peerA", proto.HeaderDeliveryFeedback, "1")
+
+	err := streamA.Send(&proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerB", Body: []byte("offer")})
+	require.NoError(t, err)
+
+	feedback := receive(t, streamA)
+	assert.Equal(t, proto.DeliveryStatus_NOT_CONNECTED, feedback.DeliveryStatus)
+	assert.Equal(t, "peerB", feedback.Key)
+	assert.Equal(t, "peerA", feedback.RemoteKey)
+	assert.Empty(t, feedback.Body)
+}
+
+// connectAuthenticatedStream connects a stream answering the challenge of the server with the key
+func connectAuthenticatedStream(t *testing.T, client proto.SignalExchangeClient, key wgtypes.Key, answerKey wgtypes.Key) (proto.SignalExchange_ConnectStreamClient, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ctx = metadata.AppendToOutgoingContext(ctx, proto.HeaderId, key.PublicKey().String(), proto.HeaderAuth, "1")
+	stream, err := client.ConnectStream(ctx)
+	require.NoError(t, err)
+
+	header, err := stream.Header()
+	require.NoError(t, err)
+	challenge, err := base64.StdEncoding.DecodeString(header.Get(proto.HeaderAuthChallenge)[0])
+	require.NoError(t, err)
+	serverKey, err := wgtypes.ParseKey(header.Get(proto.HeaderAuthKey)[0])
+	require.NoError(t, err)
+
+	response, err := encryption.Encrypt(challenge, serverKey, answerKey)
+	require.NoError(t, err)
+	err = stream.Send(&proto.EncryptedMessage{Key: key.PublicKey().String(), RemoteKey: serverKey.String(), Body: response})
+	require.NoError(t, err)
+
+	ack, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := encryption.Decrypt(ack.Body, serverKey, key)
+	require.NoError(t, err)
+	require.Equal(t, challenge, decrypted)
+	return stream, nil
+}
+
+func TestServer_AuthenticatedPeer(t *testing.T) {
+	client := startReplica(t, forwarder.NewLocal())
+
+	key, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+	_, err = connectAuthenticatedStream(t, client, key, key)
+	require.NoError(t, err)
+
+	// a stream without authentication can't take over the peer
+	ctx := metadata.AppendToOutgoingContext(context.Background(), proto.HeaderId, key.PublicKey().String())
+	hijacker, err := client.ConnectStream(ctx)
+	require.NoError(t, err)
+	_, err = hijacker.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// the peer itself can reconnect
+	_, err = connectAuthenticatedStream(t, client, key, key)
+	require.NoError(t, err)
+}
+
+func TestServer_AuthenticatedPeerOnOtherReplica(t *testing.T) {
+	hub := forwarder.NewMemoryHub()
+	clientA := startReplica(t, hub.NewForwarder())
+	clientB := startReplica(t, hub.NewForwarder())
+
+	key, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+	stream, err := connectAuthenticatedStream(t, clientA, key, key)
+	require.NoError(t, err)
+	require.NoError(t, stream.CloseSend())
+
+	// neither on another replica nor while the peer is offline
+	ctx := metadata.AppendToOutgoingContext(context.Background(), proto.HeaderId, key.PublicKey().String())
+	hijacker, err := clientB.ConnectStream(ctx)
+	require.NoError(t, err)
+	_, err = hijacker.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestServer_RequireAuth(t *testing.T) {
+	client := serveReplica(t, NewServerWithForwarder(forwarder.NewLocal(), nil, RateLimits{}, true))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), proto.HeaderId, "peerA")
+	stream, err := client.ConnectStream(ctx)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	key, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+	_, err = connectAuthenticatedStream(t, client, key, key)
+	require.NoError(t, err)
+}
+
+func TestServer_DropMessagesOfOtherSender(t *testing.T) {
+	client := startReplica(t, forwarder.NewLocal())
+
+	streamA := connectStream(t, client, "peerA")
+	streamB := connectStream(t, client, "peerB")
+
+	require.NoError(t, streamA.Send(&proto.EncryptedMessage{Key: "peerC", RemoteKey: "peerB", Body: []byte("forged")}))
+	require.NoError(t, streamA.Send(&proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerB", Body: []byte("offer")}))
+
+	msg := receive(t, streamB)
+	assert.Equal(t, "peerA", msg.Key, "message claiming another sender should be dropped")
+	assert.Equal(t, []byte("offer"), msg.Body)
+}
+
+func TestServer_AuthenticationWithWrongKey(t *testing.T) {
+	client := startReplica(t, forwarder.NewLocal())
+
+	key, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+	otherKey, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	_, err = connectAuthenticatedStream(t, client, key, otherKey)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_Metrics(t *testing.T) {
+	appMetrics, err := metrics.NewAppMetrics(context.Background())
+	require.NoError(t, err)
+	client := startReplicaWithMetrics(t, forwarder.NewLocal(), appMetrics, RateLimits{})
+
+	connectStream(t, client, "peerA")
+	streamB := connectStream(t, client, "peerB")
+
+	_, err = client.Send(context.Background(), &proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerB", Body: []byte("offer")})
+	require.NoError(t, err)
+	receive(t, streamB)
+
+	_, err = client.Send(context.Background(), &proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerC", Body: []byte("offer")})
+	require.NoError(t, err, "message dropped for a legacy client shouldn't fail")
+
+	recorder := httptest.NewRecorder()
+	appMetrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), "signal_peers_active 2")
+	assert.Contains(t, string(body), "signal_streams_connected_total 2")
+	assert.Contains(t, string(body), "signal_messages_forwarded_total 1")
+	assert.Contains(t, string(body), "signal_messages_dropped_not_connected_replica_total 1")
+	assert.Contains(t, string(body), `signal_messages_forward_duration_micro_count{type="local"} 1`)
+}
+
+func TestServer_RateLimits(t *testing.T) {
+	appMetrics, err := metrics.NewAppMetrics(context.Background())
+	require.NoError(t, err)
+	client := startReplicaWithMetrics(t, forwarder.NewLocal(), appMetrics, RateLimits{
+		Messages:     ratelimit.Config{Rate: 0.001, Burst: 1},
+		Streams:      ratelimit.Config{Rate: 0.001, Burst: 1},
+		StreamsPerIP: ratelimit.Config{Rate: 0.001, Burst: 3},
+	})
+
+	connectStream(t, client, "peerA")
+	streamB := connectStream(t, client, "peerB")
+
+	_, err = client.Send(context.Background(), &proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerB", Body: []byte("offer")})
+	require.NoError(t, err)
+	receive(t, streamB)
+
+	_, err = client.Send(context.Background(), &proto.EncryptedMessage{Key: "peerA", RemoteKey: "peerB", Body: []byte("offer")})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "message rate of the peer exceeded")
+
+	// a reconnecting peer exceeds its stream rate
+	ctx := metadata.AppendToOutgoingContext(context.Background(), proto.HeaderId, "peerA")
+	stream, err := client.ConnectStream(ctx)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "stream rate of the peer exceeded")
+
+	// the IP address has used its burst of streams
+	ctx = metadata.AppendToOutgoingContext(context.Background(), proto.HeaderId, "peerC")
+	stream, err = client.ConnectStream(ctx)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "stream rate of the IP address exceeded")
+
+	recorder := httptest.NewRecorder()
+	appMetrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "signal_messages_throttled_total 1")
+	assert.Contains(t, string(body), "signal_streams_throttled_total 2")
+}
+
+func TestServer_RateLimitsAfterAuthentication(t *testing.T) {
+	client := startReplicaWithMetrics(t, forwarder.NewLocal(), nil, RateLimits{
+		Streams: ratelimit.Config{Rate: 0.001, Burst: 2},
+	})
+
+	key, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+	otherKey, err := wgtypes.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	_, err = connectAuthenticatedStream(t, client, key, key)
+	require.NoError(t, err)
+
+	// clients claiming the ID of the peer don't use its stream limit
+	for i := 0; i < 3; i++ {
+		_, err = connectAuthenticatedStream(t, client, key, otherKey)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), proto.HeaderId, key.PublicKey().String())
+		hijacker, err := client.ConnectStream(ctx)
+		require.NoError(t, err)
+		_, err = hijacker.Recv()
+		assert.NotEqual(t, codes.OK, status.Code(err))
+	}
+
+	_, err = connectAuthenticatedStream(t, client, key, key)
+	require.NoError(t, err, "the peer should be able to reconnect within its limit")
+
+	_, err = connectAuthenticatedStream(t, client, key, key)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "stream rate of the peer exceeded")
+}